@@ -29,7 +29,8 @@ USAGE=$(cat <<-END
        such as pull requests, 'develop:master' indicates merge 'develop' branch to 'master'.
        For GitHub and Bitbucket, pull requests can use the single revision form, such as
        'refs/pull/1/merge' for GitHub and 'refs/pull-requests/1/merge' for Bitbucket; but for
-       Gitlab, composite revision is necessary, such as 'refs/merge-requests/1/head:master'.
+       Gitlab and Gitea, composite revision is necessary, such as 'refs/merge-requests/1/head:master'
+       for Gitlab and 'refs/pull/1/head:master' for Gitea.
      - SCM_AUTH [Optional] For public repository, no need provide auth, but for
        private repository, this should be provided. Auth here supports 2 different formats:
        a. <user>:<password>
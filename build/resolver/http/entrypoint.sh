@@ -34,6 +34,8 @@ USAGE=$(cat <<-END
       - FORM_FILE_KEY [Optional] The key of curl '-F' option (Specify HTTP multipart POST data) used to 
         upload files, by default is 'file'.
       - CURL_EXTENTION [Optional] Some other extent options of cURL
+      - ARTIFACT_TOKEN [Optional] Token to upload artifacts to cyclone server, it's set by workflow controller
+        when URL is the artifacts API of cyclone server.
       - FIND_OPTIONS [Optional] is only used in output http resources. We will pass the FIND_OPTIONS to Linux
         command "find" to find files in the ${WORKDIR}/data/${DATA_SUBDIR} folder, and then we will tar and push them.
         E.g. ". -path './output' -name '*.jar'" will populate the command "find . -path './output' -name *.jar".
@@ -93,10 +95,16 @@ wrapPush() {
         headerString="-H ${header} ${headerString}"
     done
 
+    # Artifact token is set by workflow controller when files are uploaded to cyclone server as artifacts.
+    authHeader=()
+    if [ -n "${ARTIFACT_TOKEN}" ]; then
+        authHeader=(-H "Authorization: Bearer ${ARTIFACT_TOKEN}")
+    fi
+
     echo "Start to upload file"
     echo "curl -v ${URL} ${headerString} -X ${METHOD} -F \"${FORM_FILE_KEY}=@${COMPRESS_FILE_NAME}\" ${CURL_EXTENSION}"
     
-    status_code=$(curl --write-out %{http_code} --silent --output /dev/null ${URL} ${headerString} -X ${METHOD} -F "${FORM_FILE_KEY}=@${COMPRESS_FILE_NAME}" ${CURL_EXTENSION})
+    status_code=$(curl --write-out %{http_code} --silent --output /dev/null ${URL} ${headerString} "${authHeader[@]}" -X ${METHOD} -F "${FORM_FILE_KEY}=@${COMPRESS_FILE_NAME}" ${CURL_EXTENSION})
     if [[ "$status_code" -ne 201 ]] ; then
         echo "Upload files error, status code: $status_code"
         exit 1
@@ -35,11 +35,14 @@ import (
 	"github.com/caicloud/cyclone/pkg/server/apis/modifiers"
 	"github.com/caicloud/cyclone/pkg/server/biz/accelerator/cleaner"
 	"github.com/caicloud/cyclone/pkg/server/biz/artifact"
+	"github.com/caicloud/cyclone/pkg/server/biz/logstore"
 	_ "github.com/caicloud/cyclone/pkg/server/biz/scm/bitbucket"
+	_ "github.com/caicloud/cyclone/pkg/server/biz/scm/gitea"
 	_ "github.com/caicloud/cyclone/pkg/server/biz/scm/github"
 	_ "github.com/caicloud/cyclone/pkg/server/biz/scm/gitlab"
 	_ "github.com/caicloud/cyclone/pkg/server/biz/scm/svn"
 	"github.com/caicloud/cyclone/pkg/server/biz/templates"
+	svrcommon "github.com/caicloud/cyclone/pkg/server/common"
 	"github.com/caicloud/cyclone/pkg/server/config"
 	"github.com/caicloud/cyclone/pkg/server/handler"
 	"github.com/caicloud/cyclone/pkg/server/handler/v1alpha1"
@@ -114,6 +117,15 @@ func initialize(opts *Options) {
 		log.Warningf("Init cache cleanup status error: %v", err)
 	}
 
+	// Start workers to handle SCM webhook deliveries, including those pending before restart.
+	v1alpha1.StartWebhookDeliveryWorkers()
+
+	// Start to poll tags of registries for image triggers.
+	v1alpha1.StartImageTriggerPoller()
+
+	// Start to poll repos for SCM triggers in poll mode.
+	v1alpha1.StartSCMTriggerPoller()
+
 }
 
 func main() {
@@ -125,9 +137,12 @@ func main() {
 
 	initialize(opts)
 
-	artifactManager := artifact.NewManager()
+	artifactManager := artifact.NewManager(v1alpha1.ListArtifactStores)
 	go artifactManager.CleanPeriodically(config.Config.Artifact.RetentionSeconds * time.Second)
 
+	logCleaner := logstore.NewCleaner(logstore.NewFileStore(svrcommon.CycloneHome), v1alpha1.GetLogRetention)
+	go logCleaner.CleanPeriodically()
+
 	// Create nirvana command.
 	cmd := nconfig.NewNamedNirvanaCommand("cyclone-server", &nconfig.Option{
 		IP:   config.Config.CycloneServerHost,
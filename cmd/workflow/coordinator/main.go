@@ -62,6 +62,13 @@ func main() {
 		return
 	}
 
+	// Wait services to be ready, workload containers are held until then.
+	log.Info("Wait services ready ... ")
+	if err = c.WaitServicesReady(); err != nil {
+		message = fmt.Sprintf("Stage %s failed to wait services ready, error: %v", c.Stage.Name, err)
+		return
+	}
+
 	// Wait all containers running, so we can start to collect logs.
 	log.Info("Wait all containers running ... ")
 	err = c.WaitRunning()
@@ -89,6 +96,12 @@ func main() {
 		return
 	}
 
+	// Kill service containers since workload containers they serve have finished.
+	if err = c.StopServices(); err != nil {
+		message = fmt.Sprintf("Stage %s failed to stop service containers, error: %v", c.Stage.Name, err)
+		return
+	}
+
 	// Collect execution result from the workload container, results are key-value pairs in a
 	// specified file, /workspace/results/*/__result__
 	if err = c.CollectExecutionResults(); err != nil {
@@ -118,6 +131,12 @@ func main() {
 		return
 	}
 
+	// Upload artifacts to cyclone server if there is no PVC to share them with downstream stages.
+	if err = c.UploadArtifacts(); err != nil {
+		message = fmt.Sprintf("Stage %s failed to upload artifacts, error: %v", c.Stage.Name, err)
+		return
+	}
+
 	// Wait all others container completion. Coordinator will be the last one
 	// to quit since it need to collect other containers' logs.
 	log.Info("Wait for all other containers completion ... ")
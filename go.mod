@@ -3,6 +3,7 @@ module github.com/caicloud/cyclone
 go 1.13
 
 require (
+	github.com/PaesslerAG/gval v0.1.0
 	github.com/PaesslerAG/jsonpath v0.0.0-20181129101437-13fe51c7d940
 	github.com/caicloud/nirvana v0.2.4
 	github.com/cbroglie/mustache v1.0.1
@@ -25,6 +25,10 @@ type ArtifactItem struct {
 	// It's in the format of: <stage name>/<artifact name>
 	// +Optional
 	Source string `json:"source"`
+	// Container is name of the workload container that produces the artifact, it's only used for output
+	// artifacts. If not set, the first workload container is used.
+	// +Optional
+	Container string `json:"container,omitempty"`
 }
 
 // ParameterItem defines a parameter
@@ -26,6 +26,8 @@ type StageSpec struct {
 	Pod *PodWorkload `json:"pod,omitempty"`
 	// Delegation kind workload, this stage would be executed externally.
 	Delegation *DelegationWorkload `json:"delegation,omitempty"`
+	// Approval kind workload, this stage would wait for approvers to approve or reject it.
+	Approval *ApprovalWorkload `json:"approval,omitempty"`
 }
 
 // PodWorkload describes pod type workload, a complete pod spec is included.
@@ -38,6 +40,16 @@ type PodWorkload struct {
 	Spec corev1.PodSpec `json:"spec"`
 	// Stage workload metadata
 	Meta *PodWorkloadMeta `json:"metadata,omitempty"`
+	// ServiceContainers are names of workload containers that serve other workload containers, for example
+	// an application tested by a test runner. They are killed when other workload containers finish, and
+	// their exit codes don't affect the stage status.
+	// +optional
+	ServiceContainers []string `json:"serviceContainers,omitempty"`
+	// Services are containers that run alongside workload containers, for example databases used by integration
+	// tests. Workload containers start after all services are ready according to their readiness probes, and
+	// services are killed when workload containers finish. Exit codes of services don't affect the stage status.
+	// +optional
+	Services []corev1.Container `json:"services,omitempty"`
 }
 
 // PodWorkloadMeta describes extra labels or annotations that should be added to the PodWorkload.
@@ -59,6 +71,51 @@ type DelegationWorkload struct {
 	URL string `json:"url"`
 	// Config is a json string that configure how to run this workload, it's interpreted by external services.
 	Config string `json:"config"`
+	// Timeout is the maximum time to wait for the external service to report the stage finished, for
+	// example '30m'. When exceeded, the stage would fail. If not set, it waits until the WorkflowRun timeout.
+	// +optional
+	Timeout string `json:"timeout,omitempty"`
+	// Auth configures how Cyclone authenticates itself to the external service.
+	// +optional
+	Auth *DelegationAuth `json:"auth,omitempty"`
+}
+
+// DelegationAuthType is type of authentication used in delegation requests.
+type DelegationAuthType string
+
+const (
+	// DelegationAuthHMAC signs delegation request body with HMAC-SHA256, the signature is sent in
+	// header 'X-Cyclone-Signature' in format 'sha256=<hex>'.
+	DelegationAuthHMAC DelegationAuthType = "HMAC"
+	// DelegationAuthBearer sends the secret as bearer token in 'Authorization' header.
+	DelegationAuthBearer DelegationAuthType = "Bearer"
+)
+
+// DelegationAuth describes authentication of delegation requests.
+type DelegationAuth struct {
+	// Type of the authentication, 'HMAC' or 'Bearer'.
+	Type DelegationAuthType `json:"type"`
+	// Secret is the HMAC key or the bearer token. It's recommended to refer a value in Kubernetes secret
+	// with '${secrets.<namespace>:<secret>/<jsonpath>}'.
+	Secret string `json:"secret"`
+}
+
+// ApprovalWorkload describes approval type workload. The WorkflowRun would wait at this stage until an approver
+// approves or rejects it. If neither users nor groups are specified, anyone can approve it.
+type ApprovalWorkload struct {
+	// Users who are allowed to approve or reject the stage.
+	// +optional
+	Users []string `json:"users,omitempty"`
+	// Groups whose members are allowed to approve or reject the stage.
+	// +optional
+	Groups []string `json:"groups,omitempty"`
+	// Message is a description for approvers about what to approve.
+	// +optional
+	Message string `json:"message,omitempty"`
+	// Timeout is the maximum time to wait for approval, for example '24h'. When exceeded, the stage would fail.
+	// If not set, it waits until the WorkflowRun timeout.
+	// +optional
+	Timeout string `json:"timeout,omitempty"`
 }
 
 // Argument defines a argument.
@@ -31,6 +31,33 @@ type WorkflowSpec struct {
 	// global variable 'IMAGE_TAG' set here can be used in resource parameters as '${variables.IMAGE_TAG}. Format
 	// for the variable reference is ${variables.<variable_name>}
 	GlobalVariables []GlobalVariable `json:"globalVariables,omitempty"`
+
+	// Concurrency limits WorkflowRuns of this workflow in the same concurrency group. It can be overridden
+	// by the WorkflowRun (or WorkflowTrigger that creates the WorkflowRun).
+	Concurrency *Concurrency `json:"concurrency,omitempty"`
+}
+
+// ConcurrencyMode represents how to handle a new WorkflowRun when there are other WorkflowRuns in its
+// concurrency group.
+type ConcurrencyMode string
+
+const (
+	// ConcurrencyCancelInProgress cancels running and queued WorkflowRuns in the group, and starts the new one.
+	ConcurrencyCancelInProgress ConcurrencyMode = "cancel-in-progress"
+	// ConcurrencyQueue queues the new WorkflowRun until WorkflowRuns in the group before it finished.
+	ConcurrencyQueue ConcurrencyMode = "queue"
+	// ConcurrencySkipIfRunning skips (cancels) the new WorkflowRun if there is a WorkflowRun running in the group.
+	ConcurrencySkipIfRunning ConcurrencyMode = "skip-if-running"
+)
+
+// Concurrency groups WorkflowRuns, so that at most one WorkflowRun runs in a group at the same time.
+type Concurrency struct {
+	// Group is a Go template to render the concurrency group of a WorkflowRun, for example,
+	// '{{ .Event.Repo }}-{{ .Event.Branch }}'. WorkflowRuns with the same rendered group in the same
+	// namespace belong to a group, empty rendered group means no concurrency constraint.
+	Group string `json:"group"`
+	// Mode is how to handle a new WorkflowRun in the group, defaults to 'queue'.
+	Mode ConcurrencyMode `json:"mode,omitempty"`
 }
 
 // GlobalVariable defines a global variable, For the moment we support three kinds of value:
@@ -67,19 +94,29 @@ type NotificationType string
 
 const (
 	// NotificationTypeEmail represents sending notifications by email.
-	NotificationTypeEmail NotificationPolicy = "Email"
+	NotificationTypeEmail NotificationType = "Email"
 	// NotificationTypeSlack represents sending notifications by Slack.
-	NotificationTypeSlack NotificationPolicy = "Slack"
+	NotificationTypeSlack NotificationType = "Slack"
 	// NotificationTypeWebhook represents sending notifications by webhook.
-	NotificationTypeWebhook NotificationPolicy = "Webhook"
+	NotificationTypeWebhook NotificationType = "Webhook"
 )
 
 // NotificationReceiver represents the receiver of notifications.
 type NotificationReceiver struct {
+	// Name of the receiver, it's used as key of the receiver's result in WorkflowRun notification status.
+	// If not set, '<type>-<index>' is used, for example 'slack-0'.
+	// +optional
+	Name string `json:"name,omitempty"`
 	// Type represents the way to send notifications.
 	Type NotificationType `json:"type"`
-	// Addresses represents the addresses to receive notifications.
+	// Addresses represents the addresses to receive notifications. They are email addresses for Email type,
+	// incoming webhook URLs for Slack type and URLs for Webhook type. Values in secret can be referred with
+	// '${secrets.<namespace>:<secret>/<jsonpath>}'.
 	Addresses []string `json:"addresses"`
+	// Template is a Go template to customize the notification content, it's the email body for Email type,
+	// message text for Slack type and payload for Webhook type.
+	// +optional
+	Template string `json:"template,omitempty"`
 }
 
 // StageItem describes a stage in a workflow.
@@ -94,6 +131,46 @@ type StageItem struct {
 	// can tolerate failure of this stage. In this case, all other stages can continue to execute and the overall
 	// status of the workflow execution can still be succeed.
 	Trivial bool `json:"trivial"`
+	// When is a condition expression to decide whether this stage should be executed, the stage would be skipped
+	// if it's evaluated to false. Global variables, SCM event data and upstream stage outputs can be used in the
+	// expression, for example: 'scm.type == "scm-tag-release" && variables.ENV == "prod"'. Refer to
+	// docs/concepts/conditions.md for details. If not set, the stage would always be executed.
+	// +optional
+	When string `json:"when,omitempty"`
+	// Retry is the policy to retry this stage when it failed. If not set, the stage would not be retried.
+	// +optional
+	Retry *RetryPolicy `json:"retry,omitempty"`
+	// Timeout is the maximum time this stage can run, for example '30m', '1h'. When exceeded, the stage pod would
+	// be killed and the stage would fail. It can be overridden in WorkflowRun stage parameters. If not set, only
+	// timeout of the WorkflowRun applies.
+	// +optional
+	Timeout string `json:"timeout,omitempty"`
+	// Matrix defines axes (name to values) to expand this stage into parallel instances, one instance for each
+	// combination of axis values, for example: {"GO_VERSION": ["1.13", "1.14"], "GOARCH": ["amd64", "arm64"]}.
+	// Axis values are injected as stage arguments with the axis names. Status of the stage is aggregated from
+	// all instances, and stages depending on it would wait for all instances. Only pod workload supported.
+	// +optional
+	Matrix map[string][]string `json:"matrix,omitempty"`
+}
+
+// RetryPolicy describes how to retry a failed stage. If both ExitCodes and Reasons are empty, the stage
+// would be retried on any failure.
+type RetryPolicy struct {
+	// Limit is the maximum number of retries, the first execution is not counted.
+	Limit int `json:"limit"`
+	// Backoff is the duration to wait before the first retry, for example '10s'. It doubles for every following
+	// retry, but never exceeds MaxBackoff. Default to 10s.
+	// +optional
+	Backoff string `json:"backoff,omitempty"`
+	// MaxBackoff is the maximum duration to wait between two retries. Default to 5m.
+	// +optional
+	MaxBackoff string `json:"maxBackoff,omitempty"`
+	// ExitCodes are exit codes of workload container that should be retried.
+	// +optional
+	ExitCodes []int32 `json:"exitCodes,omitempty"`
+	// Reasons are failure reasons that should be retried, for example 'Evicted', 'OOMKilled', 'PodDeleted'.
+	// +optional
+	Reasons []string `json:"reasons,omitempty"`
 }
 
 // +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
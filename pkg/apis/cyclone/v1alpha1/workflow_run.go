@@ -25,7 +25,8 @@ type WorkflowRun struct {
 type WorkflowRunSpec struct {
 	// Reference to a Workflow
 	WorkflowRef *corev1.ObjectReference `json:"workflowRef"`
-	// Stages in the workflow to start execution
+	// Stages in the workflow to start execution, stages that neither are start stages nor depend on them
+	// would be skipped. If it's empty, all stages in the workflow would be executed.
 	StartStages []string `json:"startStages"`
 	// Stages in the workflow to end execution
 	EndStages []string `json:"endStages"`
@@ -50,6 +51,8 @@ type WorkflowRunSpec struct {
 	// Values defined here will override those defined in the workflow. If a variable is defined in workflow but not here,
 	// it would be populated (final value generated) here when workflowrun created by workflowrun controller.
 	GlobalVariables []GlobalVariable `json:"globalVariables,omitempty"`
+	// Concurrency overrides the concurrency config of the workflow.
+	Concurrency *Concurrency `json:"concurrency,omitempty"`
 }
 
 // PresetVolume defines a preset volume
@@ -101,6 +104,9 @@ type ParameterConfig struct {
 	Name string `json:"name"`
 	// Parameters ...
 	Parameters []ParameterItem `json:"parameters"`
+	// Timeout overrides timeout of the stage defined in workflow, only used in stage parameters.
+	// +optional
+	Timeout string `json:"timeout,omitempty"`
 }
 
 // ExecutionContext is execution context of a workflow. Namespace, pvc
@@ -140,8 +146,93 @@ type StageStatus struct {
 	// can tolerate failure of this stage. In this case, all other stages can continue to execute and the overall
 	// status of the workflow execution can still be succeed.
 	Trivial bool `json:"trivial"`
+	// Timeout of the stage, it's resolved from workflow and workflowrun stage parameters when workflowrun starts.
+	// +optional
+	Timeout string `json:"timeout,omitempty"`
 	// Events of the stage
 	Events []StageEvent `json:"events"`
+	// Attempts records failed executions of the stage, only recorded when retry policy is configured for the stage.
+	// +optional
+	Attempts []StageAttempt `json:"attempts,omitempty"`
+	// Instances are names of expanded instances if this is a matrix stage, status of the stage is aggregated
+	// from status of all instances.
+	// +optional
+	Instances []string `json:"instances,omitempty"`
+	// Parent is name of the matrix stage if this is an instance expanded from it.
+	// +optional
+	Parent string `json:"parent,omitempty"`
+	// Matrix is values of matrix axes for this instance, only set for instances of matrix stage.
+	// +optional
+	Matrix map[string]string `json:"matrix,omitempty"`
+	// Approval is status of the approval, only set for stages with approval workload.
+	// +optional
+	Approval *ApprovalStatus `json:"approval,omitempty"`
+	// Delegation is status of the delegation, only set for stages with delegation workload.
+	// +optional
+	Delegation *DelegationStatus `json:"delegation,omitempty"`
+	// Containers are status of workload containers in the stage pod.
+	// +optional
+	Containers []WorkloadContainerStatus `json:"containers,omitempty"`
+}
+
+// WorkloadContainerStatus describes status of a workload container in the stage pod.
+type WorkloadContainerStatus struct {
+	// Name of the container
+	Name string `json:"name"`
+	// Service indicates whether the container is a service container, service containers are killed
+	// when other workload containers finish.
+	Service bool `json:"service,omitempty"`
+	// ExitCode is exit code of the container, nil if the container hasn't terminated.
+	ExitCode *int32 `json:"exitCode,omitempty"`
+	// Reason of the termination, for example 'Completed', 'Error', 'OOMKilled'
+	Reason string `json:"reason,omitempty"`
+}
+
+// DelegationStatus describes status of a delegated stage.
+type DelegationStatus struct {
+	// TokenDigest is SHA-256 digest of the callback token sent to the external service, the external
+	// service should report stage status with the token.
+	TokenDigest string `json:"tokenDigest"`
+	// Deadline is the time when the delegation expires, nil means no delegation timeout.
+	Deadline *metav1.Time `json:"deadline,omitempty"`
+	// LastReportTime is the time when the external service reported stage status last time.
+	LastReportTime *metav1.Time `json:"lastReportTime,omitempty"`
+}
+
+// ApprovalStatus describes status of an approval stage.
+type ApprovalStatus struct {
+	// Users who are allowed to approve or reject the stage.
+	Users []string `json:"users,omitempty"`
+	// Groups whose members are allowed to approve or reject the stage.
+	Groups []string `json:"groups,omitempty"`
+	// Message is a description for approvers about what to approve.
+	Message string `json:"message,omitempty"`
+	// Deadline is the time when the approval expires, nil means no approval timeout.
+	Deadline *metav1.Time `json:"deadline,omitempty"`
+	// Approver is the user who approved or rejected the stage, empty if it hasn't been decided yet.
+	Approver string `json:"approver,omitempty"`
+	// Approved indicates whether the stage is approved or rejected by the approver.
+	Approved bool `json:"approved"`
+	// Comment given by the approver.
+	Comment string `json:"comment,omitempty"`
+	// Time when the stage is approved or rejected.
+	Time *metav1.Time `json:"time,omitempty"`
+}
+
+// StageAttempt describes one failed execution of a stage.
+type StageAttempt struct {
+	// Pod is name of the pod that executed the stage
+	Pod string `json:"pod"`
+	// ExitCode is exit code of the workload container, nil if the workload container hasn't terminated.
+	ExitCode *int32 `json:"exitCode,omitempty"`
+	// Reason of the failure, for example 'Evicted', 'OOMKilled', 'PodDeleted'
+	Reason string `json:"reason,omitempty"`
+	// A human readable message about the failure
+	Message string `json:"message,omitempty"`
+	// StartTime is the time when the execution started
+	StartTime metav1.Time `json:"startTime,omitempty"`
+	// FinishTime is the time when the execution failed
+	FinishTime metav1.Time `json:"finishTime,omitempty"`
 }
 
 // StageEvent describes pod warning events for a stage
@@ -179,6 +270,9 @@ const (
 	StatusFailed StatusPhase = "Failed"
 	// StatusCancelled indicates WorkflowRun have been cancelled.
 	StatusCancelled StatusPhase = "Cancelled"
+	// StatusSkipped means Stage is not executed because its 'when' condition is not met. It's only
+	// used for stage, and stages depend on it can still be executed.
+	StatusSkipped StatusPhase = "Skipped"
 )
 
 // PodInfo describes the pod a stage created.
@@ -188,14 +282,47 @@ type PodInfo struct {
 }
 
 const (
-	// ReasonAutoCancelPreviousBuild means this WorkflowRun is terminated because there is new WorkflowRun for the same PR.
+	// ReasonAutoCancelPreviousBuild means this WorkflowRun is terminated because there is new WorkflowRun for the same PR
+	// or in the same 'cancel-in-progress' concurrency group.
 	ReasonAutoCancelPreviousBuild = "AutoCancelPreviousBuild"
+	// ReasonConcurrencySkipped means this WorkflowRun is cancelled before started because there is WorkflowRun running
+	// in the same 'skip-if-running' concurrency group.
+	ReasonConcurrencySkipped = "ConcurrencySkipped"
+	// ReasonReplacedByCronSchedule means this WorkflowRun is cancelled because a new WorkflowRun is scheduled by its
+	// cron trigger with the 'Replace' concurrency policy.
+	ReasonReplacedByCronSchedule = "ReplacedByCronSchedule"
 	// ReasonManuallyStop means this WorkflowRun is stopped manually.
 	ReasonManuallyStop = "ManuallyStop"
 	// ReasonManuallyPause means this WorkflowRun is paused manually.
 	ReasonManuallyPause = "ManuallyPause"
 	// ReasonManuallyResume means this WorkflowRun is resumed manually.
 	ReasonManuallyResume = "ManuallyResume"
+	// ReasonConditionNotMet means the stage is skipped because its 'when' condition is evaluated to false.
+	ReasonConditionNotMet = "ConditionNotMet"
+	// ReasonInvalidCondition means the stage failed because its 'when' condition can't be evaluated.
+	ReasonInvalidCondition = "InvalidCondition"
+	// ReasonRetrying means the stage failed and is waiting to be retried according to its retry policy.
+	ReasonRetrying = "Retrying"
+	// ReasonStageTimeout means the stage is killed because it runs longer than its timeout.
+	ReasonStageTimeout = "StageTimeout"
+	// ReasonNotStartStage means the stage is skipped because it's neither a start stage nor depends on any start stage.
+	ReasonNotStartStage = "NotStartStage"
+	// ReasonWaitingApproval means the stage is waiting for approvers to approve or reject it.
+	ReasonWaitingApproval = "WaitingApproval"
+	// ReasonApproved means the stage is approved.
+	ReasonApproved = "Approved"
+	// ReasonRejected means the stage is rejected.
+	ReasonRejected = "Rejected"
+	// ReasonApprovalTimeout means the stage failed because it's not approved or rejected within the approval timeout.
+	ReasonApprovalTimeout = "ApprovalTimeout"
+	// ReasonWaitingDelegation means the stage is delegated and is waiting for the external service to report its status.
+	ReasonWaitingDelegation = "WaitingDelegation"
+	// ReasonDelegationReported means the stage status is reported by the external service.
+	ReasonDelegationReported = "DelegationReported"
+	// ReasonDelegationTimeout means the stage failed because the external service doesn't report it finished within the delegation timeout.
+	ReasonDelegationTimeout = "DelegationTimeout"
+	// ReasonRerun means the stage status is inherited from the WorkflowRun that is re-run.
+	ReasonRerun = "Rerun"
 )
 
 // Status of a Stage in a WorkflowRun or the whole WorkflowRun.
@@ -31,6 +31,12 @@ const (
 
 	// TriggerTypeWebhook indicates webhook trigger
 	TriggerTypeWebhook TriggerType = "Webhook"
+
+	// TriggerTypeWorkflowRun indicates WorkflowRun completion trigger
+	TriggerTypeWorkflowRun TriggerType = "WorkflowRun"
+
+	// TriggerTypeImage indicates container image push trigger
+	TriggerTypeImage TriggerType = "Image"
 )
 
 // WorkflowTriggerSpec defines workflow trigger definition.
@@ -43,6 +49,12 @@ type WorkflowTriggerSpec struct {
 	Cron CronTrigger `json:"cron,omitempty"`
 	// SCM represents webhook trigger config.
 	SCM SCMTrigger `json:"scm,omitempty"`
+	// Webhook represents generic webhook trigger config.
+	Webhook WebhookTrigger `json:"webhook,omitempty"`
+	// WorkflowRun represents WorkflowRun completion trigger config.
+	WorkflowRun WorkflowRunTrigger `json:"workflowRun,omitempty"`
+	// Image represents container image push trigger config.
+	Image ImageTrigger `json:"image,omitempty"`
 	// Whether this trigger is disabled, if set to true, no workflow will be triggered
 	Disabled bool `json:"disabled"`
 	// Spec to run the workflow
@@ -52,12 +64,157 @@ type WorkflowTriggerSpec struct {
 // CronTrigger represents the cron trigger policy.
 type CronTrigger struct {
 	Schedule string `json:"schedule"`
+	// Timezone is the IANA name of the timezone to interpret the schedule in, for example, 'Asia/Shanghai'.
+	// Defaults to the local timezone of the workflow controller.
+	// +optional
+	Timezone string `json:"timezone,omitempty"`
+	// StartingDeadlineSeconds is the deadline in seconds for starting a WorkflowRun if it misses the scheduled
+	// time, for example, because the workflow controller is down. Only the latest missed schedule within the
+	// deadline is caught up. If it's not set, missed schedules are not caught up.
+	// +optional
+	StartingDeadlineSeconds *int64 `json:"startingDeadlineSeconds,omitempty"`
+	// ConcurrencyPolicy specifies how to treat concurrent WorkflowRuns created by the trigger, defaults to Allow.
+	// +optional
+	ConcurrencyPolicy CronConcurrencyPolicy `json:"concurrencyPolicy,omitempty"`
 }
 
+// CronConcurrencyPolicy describes how WorkflowRuns created by a cron trigger are handled if the previous ones are
+// still running.
+type CronConcurrencyPolicy string
+
+const (
+	// CronConcurrencyAllow allows WorkflowRuns to run concurrently.
+	CronConcurrencyAllow CronConcurrencyPolicy = "Allow"
+	// CronConcurrencyForbid skips the new WorkflowRun if the previous ones are still running.
+	CronConcurrencyForbid CronConcurrencyPolicy = "Forbid"
+	// CronConcurrencyReplace cancels the running WorkflowRuns and creates the new one.
+	CronConcurrencyReplace CronConcurrencyPolicy = "Replace"
+)
+
 // WorkflowTriggerStatus describes status of a workflow trigger.
 type WorkflowTriggerStatus struct {
 	// Count represents triggered times.
 	Count int `json:"count"`
+	// WebhookURL is the URL to send payloads to, only for Webhook type triggers.
+	WebhookURL string `json:"webhookURL,omitempty"`
+	// Cron is the status of Cron type triggers.
+	// +optional
+	Cron *CronTriggerStatus `json:"cron,omitempty"`
+	// Image is the status of Image type triggers.
+	// +optional
+	Image *ImageTriggerStatus `json:"image,omitempty"`
+	// SCM is the status of SCM type triggers polling the repo.
+	// +optional
+	SCM *SCMTriggerStatus `json:"scm,omitempty"`
+}
+
+// SCMTriggerStatus describes the state of the repo seen by the last poll of an SCM trigger.
+type SCMTriggerStatus struct {
+	// Refs are commit SHAs of branches and tags, keys are full refs, for example, 'refs/heads/master'. It's null if
+	// refs are not polled.
+	// +optional
+	Refs map[string]string `json:"refs"`
+	// PullRequests are head commit SHAs of open pull requests, keys are numbers of the pull requests. It's null if
+	// pull requests are not polled.
+	// +optional
+	PullRequests map[string]string `json:"pullRequests"`
+	// LastPollTime is the last time the repo was polled.
+	// +optional
+	LastPollTime *metav1.Time `json:"lastPollTime,omitempty"`
+}
+
+// CronTriggerStatus describes status of a cron trigger.
+type CronTriggerStatus struct {
+	// LastScheduleTime is the last time a WorkflowRun was scheduled, including skipped ones.
+	// +optional
+	LastScheduleTime *metav1.Time `json:"lastScheduleTime,omitempty"`
+	// SuccCount is the number of WorkflowRuns created successfully.
+	SuccCount int `json:"succCount"`
+	// FailCount is the number of WorkflowRuns failed to create.
+	FailCount int `json:"failCount"`
+	// SkipCount is the number of WorkflowRuns skipped by the Forbid concurrency policy.
+	SkipCount int `json:"skipCount"`
+}
+
+// WebhookTrigger represents the generic webhook trigger policy. Arbitrary systems can send JSON payloads to URL of
+// the trigger, values extracted from the payloads are used to run the workflow.
+type WebhookTrigger struct {
+	// Secret is the key to verify HMAC-SHA256 signatures of payloads, payloads are rejected if it's empty. It can be
+	// a plain string or a secret reference, for example, '${secrets.<namespace>:<secret>/<jsonpath>}'.
+	Secret string `json:"secret"`
+	// SignatureHeader is the header carrying the signature, default is 'X-Cyclone-Signature'. Value of the header
+	// is hex encoded HMAC-SHA256 digest of the payload, with optional prefix 'sha256='.
+	SignatureHeader string `json:"signatureHeader,omitempty"`
+	// Rules extract values from the payload.
+	Rules []WebhookRule `json:"rules,omitempty"`
+}
+
+// WebhookRule extracts a value from the webhook payload by JSONPath, and sets it to a global variable or a stage
+// parameter of the WorkflowRun. Non-string values are set in JSON format.
+type WebhookRule struct {
+	// JSONPath of the value in the payload, for example, '$.repository.tag'.
+	JSONPath string `json:"jsonPath"`
+	// Variable is name of the global variable to set.
+	Variable string `json:"variable,omitempty"`
+	// Stage and Parameter are names of the stage and its parameter to set.
+	Stage     string `json:"stage,omitempty"`
+	Parameter string `json:"parameter,omitempty"`
+	// Required indicates the payload is ignored if the value is not found.
+	Required bool `json:"required,omitempty"`
+}
+
+// WorkflowRunTrigger represents the WorkflowRun completion trigger policy. It fires when a WorkflowRun of the
+// upstream workflow finishes in one of the phases. Name, workflow, project, phase and stage outputs of the upstream
+// WorkflowRun are passed to the triggered WorkflowRun as global variables 'upstream.workflowrun', 'upstream.workflow',
+// 'upstream.project', 'upstream.phase' and 'upstream.stages.<stage>.outputs.<key>'.
+// Artifacts of the upstream WorkflowRun can be bound to stages with source 'upstream/<stage>/<artifact>' if both
+// WorkflowRuns run in the same execution context.
+type WorkflowRunTrigger struct {
+	// Project is the project of the upstream workflow, defaults to project of the trigger.
+	// +optional
+	Project string `json:"project,omitempty"`
+	// Workflow is name of the upstream workflow.
+	Workflow string `json:"workflow"`
+	// Phases are the phases of upstream WorkflowRuns to fire the trigger, they can be Succeeded, Failed and
+	// Cancelled. Defaults to Succeeded.
+	// +optional
+	Phases []StatusPhase `json:"phases,omitempty"`
+}
+
+// ImageTrigger represents the container image push trigger policy. It fires when a tag of the repository is pushed
+// to the DockerRegistry integration, which is notified by Docker Registry v2 or Harbor webhooks sent to URL of the
+// trigger, or found by polling tags of the repository. Repository, tag and digest of the pushed image are bound to
+// parameters 'REPOSITORY', 'TAG' and 'DIGEST' of the image resource, and passed as global variables 'image.registry',
+// 'image.repository', 'image.tag' and 'image.digest'.
+type ImageTrigger struct {
+	// Integration is name of the DockerRegistry integration.
+	Integration string `json:"integration"`
+	// Repository is the repository of images without registry, for example, 'library/alpine'.
+	Repository string `json:"repository"`
+	// Tags represents patterns to filter tags, patterns are the same as those of SCM tag filters.
+	// +optional
+	Tags *RefFilter `json:"tags,omitempty"`
+	// Resource is name of the image resource to bind the pushed image to.
+	// +optional
+	Resource string `json:"resource,omitempty"`
+	// Secret is the token to authenticate webhooks, it's compared with the 'Authorization' header of requests, with
+	// optional prefix 'Bearer '. It can be a plain string or a secret reference. Webhooks are rejected if it's empty.
+	// +optional
+	Secret string `json:"secret,omitempty"`
+	// PollInterval is the interval to poll tags of the repository, for example, '10m'. Tags are not polled if it's
+	// empty, and the minimum interval is 1 minute.
+	// +optional
+	PollInterval string `json:"pollInterval,omitempty"`
+}
+
+// ImageTriggerStatus describes status of an image trigger.
+type ImageTriggerStatus struct {
+	// Tags are digests of the tags that have been seen, it's used to skip tags not changed.
+	// +optional
+	Tags map[string]string `json:"tags,omitempty"`
+	// LastPollTime is the last time tags of the repository were polled.
+	// +optional
+	LastPollTime *metav1.Time `json:"lastPollTime,omitempty"`
 }
 
 // SCMTrigger represents the SCM trigger policy.
@@ -75,6 +232,44 @@ type SCMTrigger struct {
 	Repo string `json:"repo"`
 	// SCMTriggerPolicy represents trigger policies for SCM events.
 	SCMTriggerPolicy `json:",inline"`
+	// Feedback configures how results of WorkflowRuns triggered by pull requests are reported to the SCM. If it's
+	// not set, only one commit status is created for each WorkflowRun.
+	// +optional
+	Feedback *SCMFeedback `json:"feedback,omitempty"`
+	// PollInterval is the interval to poll branches, tags and pull requests of the repo, for example, '5m'. It's for
+	// SCM servers that can't send webhooks to Cyclone. If it's set, no webhook is registered in the SCM, and events
+	// are synthesized from changes found by polling. Pull request comment and post commit events can't be polled.
+	// The minimum interval is 1 minute.
+	// +optional
+	PollInterval string `json:"pollInterval,omitempty"`
+}
+
+// SCMFeedback configures how results of WorkflowRuns triggered by pull requests are reported to the SCM.
+type SCMFeedback struct {
+	// StageStatuses creates a commit status for each stage besides the overall one, contexts of stage statuses
+	// are 'continuous-integration/cyclone/<stage>'.
+	// +optional
+	StageStatuses bool `json:"stageStatuses,omitempty"`
+	// Comment posts a summary comment to the pull request when the WorkflowRun finishes. The comment contains
+	// results of stages, test counts and artifacts.
+	// +optional
+	Comment bool `json:"comment,omitempty"`
+	// Checks reports the WorkflowRun as a check run with annotations instead of the overall commit status. It's
+	// only supported by GitHub, commit status is created if check runs can't be created.
+	// +optional
+	Checks bool `json:"checks,omitempty"`
+	// Deployment creates deployment statuses of the commit for an environment. It's only supported by GitHub.
+	// +optional
+	Deployment *SCMDeploymentFeedback `json:"deployment,omitempty"`
+}
+
+// SCMDeploymentFeedback configures deployment statuses reported to the SCM.
+type SCMDeploymentFeedback struct {
+	// Environment is name of the environment that the WorkflowRun deploys to, for example, 'staging'.
+	Environment string `json:"environment"`
+	// URL is the URL of the deployed environment.
+	// +optional
+	URL string `json:"url,omitempty"`
 }
 
 // SCMTriggerPolicy represents trigger policies for SCM events.
@@ -98,9 +293,32 @@ type SCMTriggerBasic struct {
 	Enabled bool `json:"enabled"`
 }
 
+// RefFilter filters branches or tags by patterns. A pattern is a glob by default, in which '*' matches any characters
+// except '/', '**' matches any characters, and '?' matches one character except '/'. Patterns with prefix 'regex:'
+// are regular expressions that must match the whole ref. A ref matches a pattern if either its full ref, for example
+// 'refs/heads/release/1.0', or its short name, for example 'release/1.0', matches the pattern.
+type RefFilter struct {
+	// Include represents patterns to include refs, empty means all refs are included.
+	Include []string `json:"include,omitempty"`
+	// Exclude represents patterns to exclude refs, it takes precedence over Include.
+	Exclude []string `json:"exclude,omitempty"`
+}
+
+// PathFilter filters events by files they changed, patterns are the same as those in RefFilter, and they match file
+// paths relative to the repository root, for example 'services/api/**'. An event passes the filter if any changed
+// file matches Paths, and doesn't match PathsIgnore. Events whose changed files can't be got always pass the filter.
+type PathFilter struct {
+	// Paths represents patterns of files that trigger the event, empty means all files.
+	Paths []string `json:"paths,omitempty"`
+	// PathsIgnore represents patterns of files that are ignored, it takes precedence over Paths.
+	PathsIgnore []string `json:"pathsIgnore,omitempty"`
+}
+
 // SCMTriggerTagRelease represents trigger policy for tag release events.
 type SCMTriggerTagRelease struct {
 	SCMTriggerBasic `json:",inline"`
+	// Filter represents patterns to filter tags.
+	Filter *RefFilter `json:"filter,omitempty"`
 }
 
 // SCMTriggerPush represents trigger policy for push events.
@@ -108,6 +326,10 @@ type SCMTriggerPush struct {
 	SCMTriggerBasic `json:",inline"`
 	// Branches represents the branch lists to filter push events.
 	Branches []string `json:"branches"`
+	// Filter represents patterns to filter branches, it works together with Branches if both are set.
+	Filter *RefFilter `json:"filter,omitempty"`
+	// PathFilter represents patterns to filter push events by changed files.
+	PathFilter `json:",inline"`
 }
 
 // SCMTriggerPullRequest represents trigger policy for pull request events.
@@ -116,6 +338,10 @@ type SCMTriggerPullRequest struct {
 
 	// Branches represents the pr target branches list to filter PullRequest events.
 	Branches []string `json:"branches"`
+	// Filter represents patterns to filter pr target branches, it works together with Branches if both are set.
+	Filter *RefFilter `json:"filter,omitempty"`
+	// PathFilter represents patterns to filter pull requests by changed files.
+	PathFilter `json:",inline"`
 }
 
 // SCMTriggerPullRequestComment represents trigger policy for pull request comment events.
@@ -14,6 +14,66 @@ import (
 	api "k8s.io/client-go/tools/clientcmd/api"
 )
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ApprovalStatus) DeepCopyInto(out *ApprovalStatus) {
+	*out = *in
+	if in.Users != nil {
+		in, out := &in.Users, &out.Users
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.Groups != nil {
+		in, out := &in.Groups, &out.Groups
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.Deadline != nil {
+		in, out := &in.Deadline, &out.Deadline
+		*out = (*in).DeepCopy()
+	}
+	if in.Time != nil {
+		in, out := &in.Time, &out.Time
+		*out = (*in).DeepCopy()
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ApprovalStatus.
+func (in *ApprovalStatus) DeepCopy() *ApprovalStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(ApprovalStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ApprovalWorkload) DeepCopyInto(out *ApprovalWorkload) {
+	*out = *in
+	if in.Users != nil {
+		in, out := &in.Users, &out.Users
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.Groups != nil {
+		in, out := &in.Groups, &out.Groups
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ApprovalWorkload.
+func (in *ApprovalWorkload) DeepCopy() *ApprovalWorkload {
+	if in == nil {
+		return nil
+	}
+	out := new(ApprovalWorkload)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *Argument) DeepCopyInto(out *Argument) {
 	*out = *in
@@ -93,9 +153,30 @@ func (in *ClusterCredential) DeepCopy() *ClusterCredential {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *Concurrency) DeepCopyInto(out *Concurrency) {
+	*out = *in
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new Concurrency.
+func (in *Concurrency) DeepCopy() *Concurrency {
+	if in == nil {
+		return nil
+	}
+	out := new(Concurrency)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *CronTrigger) DeepCopyInto(out *CronTrigger) {
 	*out = *in
+	if in.StartingDeadlineSeconds != nil {
+		in, out := &in.StartingDeadlineSeconds, &out.StartingDeadlineSeconds
+		*out = new(int64)
+		**out = **in
+	}
 	return
 }
 
@@ -109,9 +190,74 @@ func (in *CronTrigger) DeepCopy() *CronTrigger {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *CronTriggerStatus) DeepCopyInto(out *CronTriggerStatus) {
+	*out = *in
+	if in.LastScheduleTime != nil {
+		in, out := &in.LastScheduleTime, &out.LastScheduleTime
+		*out = (*in).DeepCopy()
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new CronTriggerStatus.
+func (in *CronTriggerStatus) DeepCopy() *CronTriggerStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(CronTriggerStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *DelegationAuth) DeepCopyInto(out *DelegationAuth) {
+	*out = *in
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new DelegationAuth.
+func (in *DelegationAuth) DeepCopy() *DelegationAuth {
+	if in == nil {
+		return nil
+	}
+	out := new(DelegationAuth)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *DelegationStatus) DeepCopyInto(out *DelegationStatus) {
+	*out = *in
+	if in.Deadline != nil {
+		in, out := &in.Deadline, &out.Deadline
+		*out = (*in).DeepCopy()
+	}
+	if in.LastReportTime != nil {
+		in, out := &in.LastReportTime, &out.LastReportTime
+		*out = (*in).DeepCopy()
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new DelegationStatus.
+func (in *DelegationStatus) DeepCopy() *DelegationStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(DelegationStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *DelegationWorkload) DeepCopyInto(out *DelegationWorkload) {
 	*out = *in
+	if in.Auth != nil {
+		in, out := &in.Auth, &out.Auth
+		*out = new(DelegationAuth)
+		**out = **in
+	}
 	return
 }
 
@@ -234,6 +380,54 @@ func (in *GlobalVariable) DeepCopy() *GlobalVariable {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ImageTrigger) DeepCopyInto(out *ImageTrigger) {
+	*out = *in
+	if in.Tags != nil {
+		in, out := &in.Tags, &out.Tags
+		*out = new(RefFilter)
+		(*in).DeepCopyInto(*out)
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ImageTrigger.
+func (in *ImageTrigger) DeepCopy() *ImageTrigger {
+	if in == nil {
+		return nil
+	}
+	out := new(ImageTrigger)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ImageTriggerStatus) DeepCopyInto(out *ImageTriggerStatus) {
+	*out = *in
+	if in.Tags != nil {
+		in, out := &in.Tags, &out.Tags
+		*out = make(map[string]string, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
+	if in.LastPollTime != nil {
+		in, out := &in.LastPollTime, &out.LastPollTime
+		*out = (*in).DeepCopy()
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ImageTriggerStatus.
+func (in *ImageTriggerStatus) DeepCopy() *ImageTriggerStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(ImageTriggerStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *Inputs) DeepCopyInto(out *Inputs) {
 	*out = *in
@@ -452,6 +646,32 @@ func (in *ParameterItem) DeepCopy() *ParameterItem {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *PathFilter) DeepCopyInto(out *PathFilter) {
+	*out = *in
+	if in.Paths != nil {
+		in, out := &in.Paths, &out.Paths
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.PathsIgnore != nil {
+		in, out := &in.PathsIgnore, &out.PathsIgnore
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new PathFilter.
+func (in *PathFilter) DeepCopy() *PathFilter {
+	if in == nil {
+		return nil
+	}
+	out := new(PathFilter)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *Persistent) DeepCopyInto(out *Persistent) {
 	*out = *in
@@ -495,6 +715,18 @@ func (in *PodWorkload) DeepCopyInto(out *PodWorkload) {
 		*out = new(PodWorkloadMeta)
 		(*in).DeepCopyInto(*out)
 	}
+	if in.ServiceContainers != nil {
+		in, out := &in.ServiceContainers, &out.ServiceContainers
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.Services != nil {
+		in, out := &in.Services, &out.Services
+		*out = make([]v1.Container, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
 	return
 }
 
@@ -668,6 +900,32 @@ func (in *ProjectStatus) DeepCopy() *ProjectStatus {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *RefFilter) DeepCopyInto(out *RefFilter) {
+	*out = *in
+	if in.Include != nil {
+		in, out := &in.Include, &out.Include
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.Exclude != nil {
+		in, out := &in.Exclude, &out.Exclude
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new RefFilter.
+func (in *RefFilter) DeepCopy() *RefFilter {
+	if in == nil {
+		return nil
+	}
+	out := new(RefFilter)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *Resource) DeepCopyInto(out *Resource) {
 	*out = *in
@@ -782,10 +1040,78 @@ func (in *ResourceSpec) DeepCopy() *ResourceSpec {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *RetryPolicy) DeepCopyInto(out *RetryPolicy) {
+	*out = *in
+	if in.ExitCodes != nil {
+		in, out := &in.ExitCodes, &out.ExitCodes
+		*out = make([]int32, len(*in))
+		copy(*out, *in)
+	}
+	if in.Reasons != nil {
+		in, out := &in.Reasons, &out.Reasons
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new RetryPolicy.
+func (in *RetryPolicy) DeepCopy() *RetryPolicy {
+	if in == nil {
+		return nil
+	}
+	out := new(RetryPolicy)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *SCMDeploymentFeedback) DeepCopyInto(out *SCMDeploymentFeedback) {
+	*out = *in
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new SCMDeploymentFeedback.
+func (in *SCMDeploymentFeedback) DeepCopy() *SCMDeploymentFeedback {
+	if in == nil {
+		return nil
+	}
+	out := new(SCMDeploymentFeedback)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *SCMFeedback) DeepCopyInto(out *SCMFeedback) {
+	*out = *in
+	if in.Deployment != nil {
+		in, out := &in.Deployment, &out.Deployment
+		*out = new(SCMDeploymentFeedback)
+		**out = **in
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new SCMFeedback.
+func (in *SCMFeedback) DeepCopy() *SCMFeedback {
+	if in == nil {
+		return nil
+	}
+	out := new(SCMFeedback)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *SCMTrigger) DeepCopyInto(out *SCMTrigger) {
 	*out = *in
 	in.SCMTriggerPolicy.DeepCopyInto(&out.SCMTriggerPolicy)
+	if in.Feedback != nil {
+		in, out := &in.Feedback, &out.Feedback
+		*out = new(SCMFeedback)
+		(*in).DeepCopyInto(*out)
+	}
 	return
 }
 
@@ -819,7 +1145,7 @@ func (in *SCMTriggerBasic) DeepCopy() *SCMTriggerBasic {
 func (in *SCMTriggerPolicy) DeepCopyInto(out *SCMTriggerPolicy) {
 	*out = *in
 	in.Push.DeepCopyInto(&out.Push)
-	out.TagRelease = in.TagRelease
+	in.TagRelease.DeepCopyInto(&out.TagRelease)
 	in.PullRequest.DeepCopyInto(&out.PullRequest)
 	in.PullRequestComment.DeepCopyInto(&out.PullRequestComment)
 	out.PostCommit = in.PostCommit
@@ -862,6 +1188,12 @@ func (in *SCMTriggerPullRequest) DeepCopyInto(out *SCMTriggerPullRequest) {
 		*out = make([]string, len(*in))
 		copy(*out, *in)
 	}
+	if in.Filter != nil {
+		in, out := &in.Filter, &out.Filter
+		*out = new(RefFilter)
+		(*in).DeepCopyInto(*out)
+	}
+	in.PathFilter.DeepCopyInto(&out.PathFilter)
 	return
 }
 
@@ -906,6 +1238,12 @@ func (in *SCMTriggerPush) DeepCopyInto(out *SCMTriggerPush) {
 		*out = make([]string, len(*in))
 		copy(*out, *in)
 	}
+	if in.Filter != nil {
+		in, out := &in.Filter, &out.Filter
+		*out = new(RefFilter)
+		(*in).DeepCopyInto(*out)
+	}
+	in.PathFilter.DeepCopyInto(&out.PathFilter)
 	return
 }
 
@@ -919,10 +1257,49 @@ func (in *SCMTriggerPush) DeepCopy() *SCMTriggerPush {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *SCMTriggerStatus) DeepCopyInto(out *SCMTriggerStatus) {
+	*out = *in
+	if in.Refs != nil {
+		in, out := &in.Refs, &out.Refs
+		*out = make(map[string]string, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
+	if in.PullRequests != nil {
+		in, out := &in.PullRequests, &out.PullRequests
+		*out = make(map[string]string, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
+	if in.LastPollTime != nil {
+		in, out := &in.LastPollTime, &out.LastPollTime
+		*out = (*in).DeepCopy()
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new SCMTriggerStatus.
+func (in *SCMTriggerStatus) DeepCopy() *SCMTriggerStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(SCMTriggerStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *SCMTriggerTagRelease) DeepCopyInto(out *SCMTriggerTagRelease) {
 	*out = *in
 	out.SCMTriggerBasic = in.SCMTriggerBasic
+	if in.Filter != nil {
+		in, out := &in.Filter, &out.Filter
+		*out = new(RefFilter)
+		(*in).DeepCopyInto(*out)
+	}
 	return
 }
 
@@ -963,6 +1340,29 @@ func (in *Stage) DeepCopyObject() runtime.Object {
 	return nil
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *StageAttempt) DeepCopyInto(out *StageAttempt) {
+	*out = *in
+	if in.ExitCode != nil {
+		in, out := &in.ExitCode, &out.ExitCode
+		*out = new(int32)
+		**out = **in
+	}
+	in.StartTime.DeepCopyInto(&out.StartTime)
+	in.FinishTime.DeepCopyInto(&out.FinishTime)
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new StageAttempt.
+func (in *StageAttempt) DeepCopy() *StageAttempt {
+	if in == nil {
+		return nil
+	}
+	out := new(StageAttempt)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *StageEvent) DeepCopyInto(out *StageEvent) {
 	*out = *in
@@ -993,6 +1393,26 @@ func (in *StageItem) DeepCopyInto(out *StageItem) {
 		*out = make([]string, len(*in))
 		copy(*out, *in)
 	}
+	if in.Retry != nil {
+		in, out := &in.Retry, &out.Retry
+		*out = new(RetryPolicy)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.Matrix != nil {
+		in, out := &in.Matrix, &out.Matrix
+		*out = make(map[string][]string, len(*in))
+		for key, val := range *in {
+			var outVal []string
+			if val == nil {
+				(*out)[key] = nil
+			} else {
+				in, out := &val, &outVal
+				*out = make([]string, len(*in))
+				copy(*out, *in)
+			}
+			(*out)[key] = outVal
+		}
+	}
 	return
 }
 
@@ -1050,7 +1470,12 @@ func (in *StageSpec) DeepCopyInto(out *StageSpec) {
 	if in.Delegation != nil {
 		in, out := &in.Delegation, &out.Delegation
 		*out = new(DelegationWorkload)
-		**out = **in
+		(*in).DeepCopyInto(*out)
+	}
+	if in.Approval != nil {
+		in, out := &in.Approval, &out.Approval
+		*out = new(ApprovalWorkload)
+		(*in).DeepCopyInto(*out)
 	}
 	return
 }
@@ -1091,6 +1516,42 @@ func (in *StageStatus) DeepCopyInto(out *StageStatus) {
 			(*in)[i].DeepCopyInto(&(*out)[i])
 		}
 	}
+	if in.Attempts != nil {
+		in, out := &in.Attempts, &out.Attempts
+		*out = make([]StageAttempt, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.Instances != nil {
+		in, out := &in.Instances, &out.Instances
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.Matrix != nil {
+		in, out := &in.Matrix, &out.Matrix
+		*out = make(map[string]string, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
+	if in.Approval != nil {
+		in, out := &in.Approval, &out.Approval
+		*out = new(ApprovalStatus)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.Delegation != nil {
+		in, out := &in.Delegation, &out.Delegation
+		*out = new(DelegationStatus)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.Containers != nil {
+		in, out := &in.Containers, &out.Containers
+		*out = make([]WorkloadContainerStatus, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
 	return
 }
 
@@ -1143,6 +1604,43 @@ func (in *TLSClientConfig) DeepCopy() *TLSClientConfig {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *WebhookRule) DeepCopyInto(out *WebhookRule) {
+	*out = *in
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new WebhookRule.
+func (in *WebhookRule) DeepCopy() *WebhookRule {
+	if in == nil {
+		return nil
+	}
+	out := new(WebhookRule)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *WebhookTrigger) DeepCopyInto(out *WebhookTrigger) {
+	*out = *in
+	if in.Rules != nil {
+		in, out := &in.Rules, &out.Rules
+		*out = make([]WebhookRule, len(*in))
+		copy(*out, *in)
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new WebhookTrigger.
+func (in *WebhookTrigger) DeepCopy() *WebhookTrigger {
+	if in == nil {
+		return nil
+	}
+	out := new(WebhookTrigger)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *Workflow) DeepCopyInto(out *Workflow) {
 	*out = *in
@@ -1313,6 +1811,11 @@ func (in *WorkflowRunSpec) DeepCopyInto(out *WorkflowRunSpec) {
 		*out = make([]GlobalVariable, len(*in))
 		copy(*out, *in)
 	}
+	if in.Concurrency != nil {
+		in, out := &in.Concurrency, &out.Concurrency
+		*out = new(Concurrency)
+		**out = **in
+	}
 	return
 }
 
@@ -1365,6 +1868,27 @@ func (in *WorkflowRunStatus) DeepCopy() *WorkflowRunStatus {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *WorkflowRunTrigger) DeepCopyInto(out *WorkflowRunTrigger) {
+	*out = *in
+	if in.Phases != nil {
+		in, out := &in.Phases, &out.Phases
+		*out = make([]StatusPhase, len(*in))
+		copy(*out, *in)
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new WorkflowRunTrigger.
+func (in *WorkflowRunTrigger) DeepCopy() *WorkflowRunTrigger {
+	if in == nil {
+		return nil
+	}
+	out := new(WorkflowRunTrigger)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *WorkflowSpec) DeepCopyInto(out *WorkflowSpec) {
 	*out = *in
@@ -1386,6 +1910,11 @@ func (in *WorkflowSpec) DeepCopyInto(out *WorkflowSpec) {
 		*out = make([]GlobalVariable, len(*in))
 		copy(*out, *in)
 	}
+	if in.Concurrency != nil {
+		in, out := &in.Concurrency, &out.Concurrency
+		*out = new(Concurrency)
+		**out = **in
+	}
 	return
 }
 
@@ -1405,7 +1934,7 @@ func (in *WorkflowTrigger) DeepCopyInto(out *WorkflowTrigger) {
 	out.TypeMeta = in.TypeMeta
 	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
 	in.Spec.DeepCopyInto(&out.Spec)
-	out.Status = in.Status
+	in.Status.DeepCopyInto(&out.Status)
 	return
 }
 
@@ -1470,8 +1999,11 @@ func (in *WorkflowTriggerSpec) DeepCopyInto(out *WorkflowTriggerSpec) {
 			(*in)[i].DeepCopyInto(&(*out)[i])
 		}
 	}
-	out.Cron = in.Cron
+	in.Cron.DeepCopyInto(&out.Cron)
 	in.SCM.DeepCopyInto(&out.SCM)
+	in.Webhook.DeepCopyInto(&out.Webhook)
+	in.WorkflowRun.DeepCopyInto(&out.WorkflowRun)
+	in.Image.DeepCopyInto(&out.Image)
 	in.WorkflowRunSpec.DeepCopyInto(&out.WorkflowRunSpec)
 	return
 }
@@ -1489,6 +2021,21 @@ func (in *WorkflowTriggerSpec) DeepCopy() *WorkflowTriggerSpec {
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *WorkflowTriggerStatus) DeepCopyInto(out *WorkflowTriggerStatus) {
 	*out = *in
+	if in.Cron != nil {
+		in, out := &in.Cron, &out.Cron
+		*out = new(CronTriggerStatus)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.Image != nil {
+		in, out := &in.Image, &out.Image
+		*out = new(ImageTriggerStatus)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.SCM != nil {
+		in, out := &in.SCM, &out.SCM
+		*out = new(SCMTriggerStatus)
+		(*in).DeepCopyInto(*out)
+	}
 	return
 }
 
@@ -1501,3 +2048,24 @@ func (in *WorkflowTriggerStatus) DeepCopy() *WorkflowTriggerStatus {
 	in.DeepCopyInto(out)
 	return out
 }
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *WorkloadContainerStatus) DeepCopyInto(out *WorkloadContainerStatus) {
+	*out = *in
+	if in.ExitCode != nil {
+		in, out := &in.ExitCode, &out.ExitCode
+		*out = new(int32)
+		**out = **in
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new WorkloadContainerStatus.
+func (in *WorkloadContainerStatus) DeepCopy() *WorkloadContainerStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(WorkloadContainerStatus)
+	in.DeepCopyInto(out)
+	return out
+}
@@ -22,9 +22,31 @@ const (
 	// AnnotationWorkflowRunSCMEvent is the annotation key used to indicate the SCM event data to trigger workflowruns.
 	AnnotationWorkflowRunSCMEvent = "workflowrun.cyclone.dev/scm-event"
 
+	// AnnotationWorkflowRunSCMEventID is the annotation key used to indicate ID of the SCM event that triggered
+	// workflowruns, for example, the webhook delivery of the event.
+	AnnotationWorkflowRunSCMEventID = "workflowrun.cyclone.dev/scm-event-id"
+
+	// AnnotationWorkflowRunSCMFeedback is the annotation key used to indicate how results of workflowruns are
+	// reported to the SCM, it's the JSON of SCMFeedback of the trigger.
+	AnnotationWorkflowRunSCMFeedback = "workflowrun.cyclone.dev/scm-feedback"
+
 	// AnnotationWorkflowRunPRUpdatedAt is the annotation key used to indicate the time that SCM event gets triggered.
 	AnnotationWorkflowRunPRUpdatedAt = "workflowrun.cyclone.dev/scm-pr-updated-at"
 
+	// AnnotationWorkflowRunRerunFrom is the annotation key used to indicate the WorkflowRun that a workflowrun is re-run from.
+	AnnotationWorkflowRunRerunFrom = "workflowrun.cyclone.dev/rerun-from"
+
+	// AnnotationWorkflowRunUpstream is the annotation key used to indicate the upstream WorkflowRun that triggered a workflowrun.
+	AnnotationWorkflowRunUpstream = "workflowrun.cyclone.dev/upstream"
+
+	// AnnotationWorkflowRunUpstreamChain is the annotation key used to indicate workflows of upstream WorkflowRuns in the
+	// chain of WorkflowRun completion triggers, in format '<project>/<workflow>' separated by comma.
+	AnnotationWorkflowRunUpstreamChain = "workflowrun.cyclone.dev/upstream-chain"
+
+	// AnnotationWorkflowRunDownstreamTriggered is the annotation key used to indicate WorkflowRun completion triggers
+	// have been processed for a terminated workflowrun.
+	AnnotationWorkflowRunDownstreamTriggered = "workflowrun.cyclone.dev/downstream-triggered"
+
 	// AnnotationTenantInfo is the annotation key used for namespace to relate tenant information
 	AnnotationTenantInfo = "tenant.cyclone.dev/info"
 
@@ -37,6 +59,9 @@ const (
 	// AnnotationStageResult is annotation to hold execution results (JSON format) of a stage.
 	AnnotationStageResult = "stage.cyclone.dev/execution-results"
 
+	// AnnotationServiceContainers is annotation of stage pods to hold names of service containers, separated by comma.
+	AnnotationServiceContainers = "stage.cyclone.dev/service-containers"
+
 	// AnnotationIstioInject is annotation to decide whether to inject istio sidecar
 	AnnotationIstioInject = "sidecar.istio.io/inject"
 
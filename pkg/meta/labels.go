@@ -29,6 +29,18 @@ const (
 	// LabelWorkflowRunName is the label key used to indicate the workflowrun which the resources belongs to
 	LabelWorkflowRunName = "workflowrun.cyclone.dev/name"
 
+	// LabelWorkflowRunWorkspace is the label key used to indicate the workspace (data folder in PV) shared from another workflowrun,
+	// workflowruns without this label use their own names as workspaces.
+	LabelWorkflowRunWorkspace = "workflowrun.cyclone.dev/workspace"
+
+	// LabelWorkflowRunTrigger is the label key used to indicate the cron or workflowrun workflowtrigger which created the workflowrun,
+	// it differs from the annotation AnnotationWorkflowRunTrigger, which indicates the type of the trigger event.
+	LabelWorkflowRunTrigger = "workflowrun.cyclone.dev/workflowtrigger"
+
+	// LabelWorkflowRunUpstreamWorkspace is the label key used to indicate the workspace of the upstream workflowrun
+	// that triggered the workflowrun, artifacts of the upstream workflowrun are read from it.
+	LabelWorkflowRunUpstreamWorkspace = "workflowrun.cyclone.dev/upstream-workspace"
+
 	// LabelWorkflowRunAcceleration is the label key used to indicate a workflowrun turned on acceleration
 	LabelWorkflowRunAcceleration = "workflowrun.cyclone.dev/acceleration"
 
@@ -55,6 +67,13 @@ const (
 	// this label is useful for SCM type event source triggers to determine a repository name.
 	LabelWftEventRepo = "workflowtrigger.cyclone.dev/event-repo"
 
+	// LabelWebhookDeliveryState is the label key used to indicate state of a webhook delivery, it also marks
+	// ConfigMaps that store webhook deliveries.
+	LabelWebhookDeliveryState = "webhookdelivery.cyclone.dev/state"
+
+	// LabelWebhookDeliveryIntegration is the label key used to indicate the SCM integration that received a webhook delivery.
+	LabelWebhookDeliveryIntegration = "webhookdelivery.cyclone.dev/integration"
+
 	// LabelPodKind is the label key applied to pod to indicate whether the pod is used for GC purpose.
 	LabelPodKind = "pod.kubernetes.io/kind"
 
@@ -115,6 +134,11 @@ func WorkflowSelector(workflow string) string {
 	return LabelWorkflowName + "=" + workflow
 }
 
+// TriggeredWorkflowRunSelector is a selector for workflowruns created by the cron workflowtrigger
+func TriggeredWorkflowRunSelector(wft string) string {
+	return LabelWorkflowRunTrigger + "=" + wft
+}
+
 // SchedulableClusterSelector is a selector for clusters which are use to perform workload
 func SchedulableClusterSelector() string {
 	return fmt.Sprintf("%s=%s", LabelIntegrationSchedulableCluster, LabelValueTrue)
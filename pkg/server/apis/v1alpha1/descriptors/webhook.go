@@ -4,6 +4,7 @@ import (
 	"github.com/caicloud/nirvana/definition"
 
 	handler "github.com/caicloud/cyclone/pkg/server/handler/v1alpha1"
+	httputil "github.com/caicloud/cyclone/pkg/util/http"
 )
 
 func init() {
@@ -28,15 +29,145 @@ var webhook = []definition.Descriptor{
 					{
 						Source:      definition.Query,
 						Name:        "sourceType",
-						Description: "the webhook sourceType, support SCM for now",
+						Description: "the webhook sourceType, support SCM and Webhook for now",
 					},
 					{
-						Source: definition.Query,
-						Name:   "integration",
+						Source:      definition.Query,
+						Name:        "integration",
+						Description: "integration of SCM webhooks, or workflow trigger of generic webhooks",
 					},
 				},
 				Results: definition.DataErrorResults("webhook"),
 			},
 		},
 	},
+	{
+		Path:        "/tenants/{tenant}/webhook/dryrun",
+		Description: "Webhook dry run APIs",
+		Definitions: []definition.Definition{
+			{
+				Method:      definition.Get,
+				Function:    handler.DryRunWebhook,
+				Description: "Report which SCM workflow triggers would be fired by an event of the ref",
+				Parameters: []definition.Parameter{
+					{
+						Source:      definition.Path,
+						Name:        "tenant",
+						Description: "tenant",
+					},
+					{
+						Source:      definition.Query,
+						Name:        "repo",
+						Description: "full repo name, for example 'caicloud/cyclone'",
+					},
+					{
+						Source:      definition.Query,
+						Name:        "ref",
+						Description: "branch or tag ref, target branch for pull request events",
+					},
+					{
+						Source:      definition.Query,
+						Name:        "eventType",
+						Description: "scm-push, scm-tag-release or scm-pull-request, inferred from the ref if empty",
+						Default:     "",
+					},
+					{
+						Source:      definition.Query,
+						Name:        "integration",
+						Description: "SCM integration of the workflow triggers",
+						Default:     "",
+					},
+					{
+						Source:      definition.Query,
+						Name:        "files",
+						Description: "comma separated changed files to match path filters, path filters pass if it's empty",
+						Default:     "",
+					},
+				},
+				Results: definition.DataErrorResults("dry run results"),
+			},
+		},
+	},
+	{
+		Path:        "/webhookdeliveries",
+		Description: "Webhook delivery APIs",
+		Tags:        []string{"webhook"},
+		Definitions: []definition.Definition{
+			{
+				Method:      definition.List,
+				Function:    handler.ListWebhookDeliveries,
+				Description: "List webhook deliveries from the newest to the oldest",
+				Parameters: []definition.Parameter{
+					{
+						Source: definition.Header,
+						Name:   httputil.TenantHeaderName,
+					},
+					{
+						Source:      definition.Query,
+						Name:        "integration",
+						Description: "SCM integration that received the deliveries",
+						Default:     "",
+					},
+					{
+						Source:      definition.Query,
+						Name:        "state",
+						Description: "state of the deliveries, Pending, Succeeded or Failed",
+						Default:     "",
+					},
+					{
+						Source:      definition.Auto,
+						Name:        httputil.PaginationAutoParameter,
+						Description: "pagination",
+					},
+				},
+				Results: definition.DataErrorResults("webhook deliveries"),
+			},
+		},
+	},
+	{
+		Path:        "/webhookdeliveries/{webhookdelivery}",
+		Description: "Webhook delivery APIs",
+		Tags:        []string{"webhook"},
+		Definitions: []definition.Definition{
+			{
+				Method:      definition.Get,
+				Function:    handler.GetWebhookDelivery,
+				Description: "Get webhook delivery",
+				Parameters: []definition.Parameter{
+					{
+						Source: definition.Header,
+						Name:   httputil.TenantHeaderName,
+					},
+					{
+						Source: definition.Path,
+						Name:   httputil.WebhookDeliveryNamePathParameterName,
+					},
+				},
+				Results: definition.DataErrorResults("webhook delivery"),
+			},
+		},
+		Children: []definition.Descriptor{
+			{
+				Path: "/redeliver",
+				Definitions: []definition.Definition{
+					{
+						Method:      definition.Create,
+						Function:    handler.RedeliverWebhookDelivery,
+						Description: "Re-deliver the event of a webhook delivery as a new delivery",
+						Parameters: []definition.Parameter{
+							{
+								Source: definition.Header,
+								Name:   httputil.TenantHeaderName,
+							},
+							{
+								Source: definition.Path,
+								Name:   httputil.WebhookDeliveryNamePathParameterName,
+							},
+						},
+						Results: definition.DataErrorResults("webhook delivery"),
+					},
+				},
+			},
+		},
+	},
 }
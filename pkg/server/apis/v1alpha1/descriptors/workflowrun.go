@@ -261,6 +261,113 @@ var workflowrun = []definition.Descriptor{
 					},
 				},
 			},
+			{
+				Path: "/rerun",
+				Definitions: []definition.Definition{
+					{
+						Method:      definition.Create,
+						Function:    handler.RerunWorkflowRun,
+						Description: "Re-run a workflowrun from its failed stages",
+						Parameters: []definition.Parameter{
+							{
+								Source: definition.Path,
+								Name:   httputil.ProjectNamePathParameterName,
+							},
+							{
+								Source: definition.Path,
+								Name:   httputil.WorkflowNamePathParameterName,
+							},
+							{
+								Source: definition.Path,
+								Name:   httputil.WorkflowRunNamePathParameterName,
+							},
+							{
+								Source: definition.Header,
+								Name:   httputil.TenantHeaderName,
+							},
+						},
+						Results: definition.DataErrorResults("workflowrun"),
+					},
+				},
+			},
+			{
+				Path: "/stages/{stage}/approve",
+				Definitions: []definition.Definition{
+					{
+						Method:      definition.Update,
+						Function:    handler.ApproveStage,
+						Description: "Approve an approval stage of a workflowrun",
+						Parameters: []definition.Parameter{
+							{
+								Source: definition.Path,
+								Name:   httputil.ProjectNamePathParameterName,
+							},
+							{
+								Source: definition.Path,
+								Name:   httputil.WorkflowNamePathParameterName,
+							},
+							{
+								Source: definition.Path,
+								Name:   httputil.WorkflowRunNamePathParameterName,
+							},
+							{
+								Source: definition.Path,
+								Name:   httputil.StageNamePathParameterName,
+							},
+							{
+								Source: definition.Header,
+								Name:   httputil.TenantHeaderName,
+							},
+							{
+								Source:      definition.Query,
+								Name:        httputil.CommentQueryParameter,
+								Default:     "",
+								Description: "comment of the approver",
+							},
+						},
+						Results: definition.DataErrorResults("workflowrun"),
+					},
+				},
+			},
+			{
+				Path: "/stages/{stage}/reject",
+				Definitions: []definition.Definition{
+					{
+						Method:      definition.Update,
+						Function:    handler.RejectStage,
+						Description: "Reject an approval stage of a workflowrun",
+						Parameters: []definition.Parameter{
+							{
+								Source: definition.Path,
+								Name:   httputil.ProjectNamePathParameterName,
+							},
+							{
+								Source: definition.Path,
+								Name:   httputil.WorkflowNamePathParameterName,
+							},
+							{
+								Source: definition.Path,
+								Name:   httputil.WorkflowRunNamePathParameterName,
+							},
+							{
+								Source: definition.Path,
+								Name:   httputil.StageNamePathParameterName,
+							},
+							{
+								Source: definition.Header,
+								Name:   httputil.TenantHeaderName,
+							},
+							{
+								Source:      definition.Query,
+								Name:        httputil.CommentQueryParameter,
+								Default:     "",
+								Description: "comment of the approver",
+							},
+						},
+						Results: definition.DataErrorResults("workflowrun"),
+					},
+				},
+			},
 			{
 				Path: "/logstream",
 				Definitions: []definition.Definition{
@@ -352,6 +459,106 @@ var workflowrun = []definition.Descriptor{
 					},
 				},
 			},
+			{
+				Path: "/logs/search",
+				Definitions: []definition.Definition{
+					{
+						Method:      definition.Get,
+						Function:    handler.SearchLogs,
+						Description: "Search logs of containers in the workflowRun",
+						Parameters: []definition.Parameter{
+							{
+								Source: definition.Path,
+								Name:   httputil.ProjectNamePathParameterName,
+							},
+							{
+								Source: definition.Path,
+								Name:   httputil.WorkflowNamePathParameterName,
+							},
+							{
+								Source: definition.Path,
+								Name:   httputil.WorkflowRunNamePathParameterName,
+							},
+							{
+								Source: definition.Header,
+								Name:   httputil.TenantHeaderName,
+							},
+							{
+								Source:      definition.Query,
+								Name:        httputil.StagesQueryParameter,
+								Description: "comma separated stages to search, all stages are searched if it's empty",
+							},
+							{
+								Source:      definition.Query,
+								Name:        httputil.ContainerNameQueryParameter,
+								Description: "container to search, all containers except sidecars are searched if it's empty",
+							},
+							{
+								Source:      definition.Query,
+								Name:        httputil.StartTimeQueryParameter,
+								Description: "select lines received at or after the time in unix seconds",
+							},
+							{
+								Source:      definition.Query,
+								Name:        httputil.EndTimeQueryParameter,
+								Description: "select lines received before the time in unix seconds",
+							},
+							{
+								Source:      definition.Query,
+								Name:        httputil.PatternQueryParameter,
+								Description: "regular expression to match content of lines",
+							},
+							{
+								Source:      definition.Auto,
+								Name:        httputil.PaginationAutoParameter,
+								Description: "pagination",
+							},
+						},
+						Results: definition.DataErrorResults("log lines"),
+					},
+				},
+			},
+			{
+				Path: "/logs/export",
+				Definitions: []definition.Definition{
+					{
+						Method:      definition.Get,
+						Produces:    []string{definition.MIMEOctetStream},
+						Function:    handler.ExportLogs,
+						Description: "Export logs of all containers in the workflowRun as a gzipped tar",
+						Parameters: []definition.Parameter{
+							{
+								Source: definition.Path,
+								Name:   httputil.ProjectNamePathParameterName,
+							},
+							{
+								Source: definition.Path,
+								Name:   httputil.WorkflowNamePathParameterName,
+							},
+							{
+								Source: definition.Path,
+								Name:   httputil.WorkflowRunNamePathParameterName,
+							},
+							{
+								Source: definition.Header,
+								Name:   httputil.TenantHeaderName,
+							},
+						},
+						Results: []definition.Result{
+							{
+								Destination: definition.Data,
+								Description: "logs archive",
+							},
+							{
+								Destination: definition.Meta,
+							},
+							{
+								Destination: definition.Error,
+							},
+						},
+					},
+				},
+			},
 			{
 				Path: "/artifacts",
 				Definitions: []definition.Definition{
@@ -530,4 +737,83 @@ var workflowrun = []definition.Descriptor{
 			},
 		},
 	},
+	{
+		Path: "/workflowruns/{workflowrun}/artifacts/{artifact}",
+		Tags: []string{"workflowrun"},
+		Definitions: []definition.Definition{
+			{
+				Method:      definition.Get,
+				Produces:    []string{definition.MIMEOctetStream},
+				Function:    handler.DownloadStageArtifact,
+				Description: "Download stage artifacts as input artifacts of other stages",
+				Parameters: []definition.Parameter{
+					{
+						Source: definition.Path,
+						Name:   httputil.WorkflowRunNamePathParameterName,
+					},
+					{
+						Source: definition.Path,
+						Name:   httputil.ArtifactNamePathParameterName,
+					},
+					{
+						Source: definition.Query,
+						Name:   httputil.NamespaceQueryParameter,
+					},
+					{
+						Source:    definition.Query,
+						Name:      httputil.StageNameQueryParameter,
+						Operators: []definition.Operator{validator.String("required")},
+					},
+				},
+				Results: []definition.Result{
+					{
+						Destination: definition.Data,
+						Description: "artifact",
+					},
+					{
+						Destination: definition.Meta,
+					},
+					{
+						Destination: definition.Error,
+					},
+				},
+			},
+		},
+	},
+	{
+		Path: "/workflowruns/{workflowrun}/delegationreports",
+		Tags: []string{"workflowrun"},
+		Definitions: []definition.Definition{
+			{
+				Method:      definition.Create,
+				Function:    handler.ReportDelegation,
+				Description: "Report status of delegated stage",
+				Parameters: []definition.Parameter{
+					{
+						Source: definition.Path,
+						Name:   httputil.WorkflowRunNamePathParameterName,
+					},
+					{
+						Source: definition.Query,
+						Name:   httputil.NamespaceQueryParameter,
+					},
+					{
+						Source:    definition.Query,
+						Name:      httputil.StageNameQueryParameter,
+						Operators: []definition.Operator{validator.String("required")},
+					},
+					{
+						Source:      definition.Body,
+						Name:        "report",
+						Description: "status of the delegated stage",
+					},
+				},
+				Results: []definition.Result{
+					{
+						Destination: definition.Error,
+					},
+				},
+			},
+		},
+	},
 }
@@ -167,4 +167,41 @@ var workflowtrigger = []definition.Descriptor{
 			},
 		},
 	},
+	{
+		Path:        "/projects/{project}/workflows/{workflow}/workflowtriggers/{workflowtrigger}/schedule",
+		Description: "workflowtrigger APIs",
+		Tags:        []string{"workflowtrigger"},
+		Definitions: []definition.Definition{
+			{
+				Method:      definition.Get,
+				Function:    handler.GetWorkflowTriggerSchedule,
+				Description: "Get last schedule time and next fire times of Cron type workflowtrigger",
+				Parameters: []definition.Parameter{
+					{
+						Source: definition.Header,
+						Name:   httputil.TenantHeaderName,
+					},
+					{
+						Source: definition.Path,
+						Name:   httputil.ProjectNamePathParameterName,
+					},
+					{
+						Source: definition.Path,
+						Name:   httputil.WorkflowNamePathParameterName,
+					},
+					{
+						Source: definition.Path,
+						Name:   httputil.WorkflowTriggerNamePathParameterName,
+					},
+					{
+						Source:      definition.Query,
+						Name:        httputil.CountQueryParameter,
+						Default:     5,
+						Description: "number of next fire times to return",
+					},
+				},
+				Results: definition.DataErrorResults("cron schedule"),
+			},
+		},
+	},
 }
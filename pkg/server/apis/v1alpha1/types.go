@@ -1,6 +1,7 @@
 package v1alpha1
 
 import (
+	"encoding/json"
 	"time"
 
 	core_v1 "k8s.io/api/core/v1"
@@ -30,6 +31,72 @@ type TenantSpec struct {
 	// ResourceQuota describes the resource quota of the namespace,
 	// eg map[core_v1.ResourceName]string{"cpu": "2", "memory": "4Gi"}
 	ResourceQuota map[core_v1.ResourceName]string `json:"resourceQuota"`
+
+	// ArtifactStorage configures where artifacts of the tenant are stored, storage in the server config is used
+	// if it's not set.
+	ArtifactStorage *ArtifactStorage `json:"artifactStorage,omitempty"`
+
+	// LogRetention configures how long logs of the tenant's workflowruns are kept, retention in the server config
+	// is used if it's not set.
+	LogRetention *LogRetention `json:"logRetention,omitempty"`
+}
+
+// LogRetention describes the retention policy of workflowrun logs. Logs are kept forever if both fields are 0.
+type LogRetention struct {
+	// Seconds is how long logs are kept after they were last written.
+	Seconds int64 `json:"seconds"`
+	// MaxWorkflowRuns is the max number of workflowruns to keep logs for each workflow, logs of the oldest
+	// workflowruns are deleted when it's exceeded.
+	MaxWorkflowRuns int `json:"maxWorkflowRuns"`
+}
+
+// LogLine is a line of container logs in a workflowrun.
+type LogLine struct {
+	// Stage that the container belongs to.
+	Stage string `json:"stage"`
+	// Container that produced the line.
+	Container string `json:"container"`
+	// Number is the 1-based line number in logs of the container.
+	Number int `json:"number"`
+	// Time is when the line was received by cyclone server.
+	Time time.Time `json:"time"`
+	// Content of the line.
+	Content string `json:"content"`
+}
+
+// ArtifactStorageType represents the type of artifact storages.
+type ArtifactStorageType string
+
+const (
+	// LocalArtifactStorage stores artifacts on the disk of cyclone server.
+	LocalArtifactStorage ArtifactStorageType = "Local"
+	// S3ArtifactStorage stores artifacts in a S3 compatible object storage.
+	S3ArtifactStorage ArtifactStorageType = "S3"
+)
+
+// ArtifactStorage describes the storage of artifacts produced by workflowruns.
+type ArtifactStorage struct {
+	// Type of the storage, defaults to Local.
+	Type ArtifactStorageType `json:"type"`
+	// S3 configures the object storage, required for S3 type.
+	S3 *S3Storage `json:"s3,omitempty"`
+}
+
+// S3Storage describes a bucket of S3 compatible object storage, such as AWS S3, MinIO and Ceph.
+type S3Storage struct {
+	// Endpoint is URL of the storage service, for example https://s3.us-east-1.amazonaws.com. Buckets are
+	// addressed in path style.
+	Endpoint string `json:"endpoint"`
+	// Region of the bucket, defaults to us-east-1.
+	Region string `json:"region"`
+	// Bucket to store artifacts.
+	Bucket string `json:"bucket"`
+	// Prefix of object keys, artifacts are stored in the bucket root if it's empty.
+	Prefix string `json:"prefix,omitempty"`
+	// AccessKey is the access key ID.
+	AccessKey string `json:"accessKey"`
+	// SecretKey is the secret access key, it can be a secret reference like ${secrets.ns:name/data.key}.
+	SecretKey string `json:"secretKey"`
 }
 
 // PersistentVolumeClaim describes information about pvc belongs to a tenant
@@ -137,6 +204,8 @@ const (
 	SVN = "SVN"
 	// Bitbucket is the Bitbucket scm
 	Bitbucket = "Bitbucket"
+	// Gitea is the Gitea scm, it also works with Gogs
+	Gitea = "Gitea"
 )
 
 // SCMSource represents Source Code Management to manage code.
@@ -239,6 +308,86 @@ type WebhookResponse struct {
 	Message string `json:"message,omitempty"`
 }
 
+// WebhookDryRunResult describes whether a workflow trigger would be fired by an SCM event.
+type WebhookDryRunResult struct {
+	// WorkflowTrigger is name of the workflow trigger
+	WorkflowTrigger string `json:"workflowTrigger"`
+	// Workflow is name of the workflow to run
+	Workflow string `json:"workflow"`
+	// Fired indicates whether the workflow trigger would be fired
+	Fired bool `json:"fired"`
+	// Reason describes why the workflow trigger would be fired or not
+	Reason string `json:"reason,omitempty"`
+}
+
+// WebhookDelivery records an SCM webhook event received by Cyclone and how it's handled.
+type WebhookDelivery struct {
+	// Metadata for the particular object, including name, namespace, labels, etc
+	meta_v1.ObjectMeta `json:"metadata,omitempty"`
+	// Spec contains the received event
+	Spec WebhookDeliverySpec `json:"spec"`
+	// Status contains how the event is handled
+	Status WebhookDeliveryStatus `json:"status"`
+}
+
+// WebhookDeliverySpec describes an SCM webhook event received by Cyclone.
+type WebhookDeliverySpec struct {
+	// Integration is the SCM integration that received the event
+	Integration string `json:"integration,omitempty"`
+	// Event is the SCM event parsed from the payload, in the same format as the SCM event annotation of WorkflowRuns
+	Event json.RawMessage `json:"event"`
+	// Payload is the raw payload of the webhook request
+	Payload string `json:"payload,omitempty"`
+	// PayloadTruncated indicates whether the payload is truncated as it's too large
+	PayloadTruncated bool `json:"payloadTruncated,omitempty"`
+	// ReceivedAt is the time the event is received
+	ReceivedAt meta_v1.Time `json:"receivedAt"`
+	// RedeliveryOf is name of the delivery that this delivery re-delivers
+	RedeliveryOf string `json:"redeliveryOf,omitempty"`
+}
+
+// WebhookDeliveryState is state of webhook deliveries.
+type WebhookDeliveryState string
+
+const (
+	// WebhookDeliveryPending means the delivery is waiting to be handled, or handled with errors and waiting to retry.
+	WebhookDeliveryPending WebhookDeliveryState = "Pending"
+	// WebhookDeliverySucceeded means all matched workflow triggers have been handled.
+	WebhookDeliverySucceeded WebhookDeliveryState = "Succeeded"
+	// WebhookDeliveryFailed means the delivery still has errors after max attempts.
+	WebhookDeliveryFailed WebhookDeliveryState = "Failed"
+)
+
+// WebhookDeliveryStatus describes how a webhook delivery is handled.
+type WebhookDeliveryStatus struct {
+	// State of the delivery
+	State WebhookDeliveryState `json:"state"`
+	// Attempts is the number of attempts to handle the delivery
+	Attempts int `json:"attempts"`
+	// LastAttemptTime is the time of the last attempt
+	LastAttemptTime *meta_v1.Time `json:"lastAttemptTime,omitempty"`
+	// Triggers are results of workflow triggers of the repo
+	Triggers []WebhookDeliveryTrigger `json:"triggers,omitempty"`
+	// Error is the error of the last attempt
+	Error string `json:"error,omitempty"`
+}
+
+// WebhookDeliveryTrigger describes how a workflow trigger handled a webhook delivery.
+type WebhookDeliveryTrigger struct {
+	// WorkflowTrigger is name of the workflow trigger
+	WorkflowTrigger string `json:"workflowTrigger"`
+	// Workflow is name of the workflow to run
+	Workflow string `json:"workflow,omitempty"`
+	// Fired indicates whether the workflow trigger is fired
+	Fired bool `json:"fired"`
+	// Reason describes why the workflow trigger is fired or not
+	Reason string `json:"reason,omitempty"`
+	// WorkflowRun is name of the created WorkflowRun
+	WorkflowRun string `json:"workflowRun,omitempty"`
+	// Error is the error to create the WorkflowRun, it will be retried
+	Error string `json:"error,omitempty"`
+}
+
 // StorageUsage defines usage of PVC storage
 type StorageUsage struct {
 	Total string            `json:"total"`
@@ -355,6 +504,23 @@ type StageArtifact struct {
 	// Files name
 	File              string    `json:"file"`
 	CreationTimestamp time.Time `json:"creationTimestamp"`
+	// Size of the file in bytes
+	Size int64 `json:"size"`
+	// Checksum is the hex encoded SHA-256 of the file, it's empty for artifacts stored before checksums are recorded.
+	Checksum string `json:"checksum,omitempty"`
+	// URL is the pre-signed URL to download the file from the storage directly, it's empty if the storage doesn't
+	// support it.
+	URL string `json:"url,omitempty"`
+}
+
+// CronSchedule describes the schedule of a cron trigger.
+type CronSchedule struct {
+	// Timezone is the timezone of the schedule.
+	Timezone string `json:"timezone"`
+	// LastScheduleTime is the last time the cron trigger was scheduled.
+	LastScheduleTime *meta_v1.Time `json:"lastScheduleTime,omitempty"`
+	// NextFireTimes are the next times the cron trigger will fire, in timezone of the schedule.
+	NextFireTimes []time.Time `json:"nextFireTimes"`
 }
 
 const (
@@ -0,0 +1,185 @@
+package artifact
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/caicloud/nirvana/log"
+	"k8s.io/kubernetes/pkg/volume/util/fs"
+)
+
+// checksumSuffix is suffix of files recording checksums of artifacts, they are hidden files beside the artifacts.
+const checksumSuffix = ".sha256"
+
+// LocalStore stores artifacts in a folder of the local file system.
+type LocalStore struct {
+	home string
+}
+
+// NewLocalStore creates a store rooted at the home folder.
+func NewLocalStore(home string) *LocalStore {
+	return &LocalStore{home: home}
+}
+
+func (s *LocalStore) path(key string) string {
+	return filepath.Join(s.home, filepath.FromSlash(key))
+}
+
+func checksumPath(path string) string {
+	return filepath.Join(filepath.Dir(path), "."+filepath.Base(path)+checksumSuffix)
+}
+
+// Put implements Store.
+func (s *LocalStore) Put(key string, content io.ReadSeeker) (*Object, error) {
+	if err := validateKey(key); err != nil {
+		return nil, err
+	}
+	path := s.path(key)
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return nil, err
+	}
+
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0666)
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		if err := f.Close(); err != nil {
+			log.Errorf("Fail to close file as: %v", err)
+		}
+	}()
+
+	hash := sha256.New()
+	size, err := io.Copy(f, io.TeeReader(content, hash))
+	if err != nil {
+		return nil, err
+	}
+	checksum := hex.EncodeToString(hash.Sum(nil))
+	if err := ioutil.WriteFile(checksumPath(path), []byte(checksum), 0666); err != nil {
+		return nil, err
+	}
+
+	return &Object{Key: key, Size: size, Checksum: checksum, LastModified: time.Now()}, nil
+}
+
+// Get implements Store.
+func (s *LocalStore) Get(key string) (io.ReadCloser, *Object, error) {
+	object, err := s.Stat(key)
+	if err != nil {
+		return nil, nil, err
+	}
+	f, err := os.Open(s.path(key))
+	if err != nil {
+		return nil, nil, err
+	}
+	return f, object, nil
+}
+
+// Stat implements Store.
+func (s *LocalStore) Stat(key string) (*Object, error) {
+	if err := validateKey(key); err != nil {
+		return nil, err
+	}
+	path := s.path(key)
+	info, err := os.Stat(path)
+	if os.IsNotExist(err) || (err == nil && info.IsDir()) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	object := &Object{Key: key, Size: info.Size(), LastModified: info.ModTime()}
+	// Artifacts stored before checksums are recorded have no checksum files.
+	if checksum, err := ioutil.ReadFile(checksumPath(path)); err == nil {
+		object.Checksum = strings.TrimSpace(string(checksum))
+	}
+	return object, nil
+}
+
+// List implements Store.
+func (s *LocalStore) List(prefix string) ([]Object, error) {
+	// Walk from the folder containing the prefix, and filter files by the prefix.
+	root := s.home
+	if i := strings.LastIndex(prefix, "/"); i >= 0 {
+		root = s.path(prefix[:i])
+	}
+	if _, err := os.Stat(root); os.IsNotExist(err) {
+		return nil, nil
+	}
+
+	var objects []Object
+	err := filepath.Walk(root, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if info.IsDir() || strings.HasPrefix(info.Name(), ".") {
+			return nil
+		}
+		rel, err := filepath.Rel(s.home, path)
+		if err != nil {
+			return err
+		}
+		key := filepath.ToSlash(rel)
+		if !strings.HasPrefix(key, prefix) {
+			return nil
+		}
+		objects = append(objects, Object{Key: key, Size: info.Size(), LastModified: info.ModTime()})
+		return nil
+	})
+	return objects, err
+}
+
+// Copy implements Store.
+func (s *LocalStore) Copy(src, dst string) error {
+	in, _, err := s.Get(src)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err := in.Close(); err != nil {
+			log.Errorf("Fail to close file as: %v", err)
+		}
+	}()
+
+	_, err = s.Put(dst, in.(*os.File))
+	return err
+}
+
+// Delete implements Store.
+func (s *LocalStore) Delete(key string) error {
+	if err := validateKey(key); err != nil {
+		return err
+	}
+	path := s.path(key)
+	if err := os.RemoveAll(checksumPath(path)); err != nil {
+		return err
+	}
+	return os.RemoveAll(path)
+}
+
+// PresignedURL implements Store, local artifacts can only be downloaded through cyclone server.
+func (s *LocalStore) PresignedURL(key string, expires time.Duration) (string, error) {
+	return "", nil
+}
+
+// DiskAvailablePercentage returns available space in percentage format of the home folder.
+func (s *LocalStore) DiskAvailablePercentage() (float64, error) {
+	start := time.Now()
+
+	available, capacity, _, _, _, _, err := fs.FsInfo(s.home)
+	if err != nil {
+		return 0, err
+	}
+
+	log.Infof("fsInfo cost time: %s, available: %d, capacity: %d, percentage: %v",
+		time.Since(start), available, capacity, float64(available)/float64(capacity))
+
+	return float64(available) / float64(capacity), nil
+}
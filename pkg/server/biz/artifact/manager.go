@@ -1,34 +1,26 @@
 package artifact
 
 import (
-	"fmt"
-	"os"
-	"path/filepath"
 	"strings"
 	"time"
 
-	"k8s.io/kubernetes/pkg/volume/util/fs"
-
-	"github.com/caicloud/cyclone/pkg/server/common"
 	"github.com/caicloud/nirvana/log"
 )
 
+// StoresGetter gets all stores in use to clean artifacts in them.
+type StoresGetter func() ([]Store, error)
+
 // Manager manages artifacts
 type Manager struct {
-	cleanPeriod     time.Duration
-	artifactHomeDir string
+	cleanPeriod time.Duration
+	stores      StoresGetter
 }
 
-// NewManager Initiates a artifacts manager.
-// If artifactHomeDir not passed, the default '/var/lib/cyclone' will be used.
-func NewManager(artifactHomeDir ...string) *Manager {
-	var home = common.CycloneHome
-	if artifactHomeDir != nil && artifactHomeDir[0] != "" {
-		home = artifactHomeDir[0]
-	}
+// NewManager Initiates a artifacts manager to manage artifacts in stores got by the getter.
+func NewManager(stores StoresGetter) *Manager {
 	m := &Manager{
-		cleanPeriod:     time.Duration(time.Hour),
-		artifactHomeDir: home,
+		cleanPeriod: time.Duration(time.Hour),
+		stores:      stores,
 	}
 
 	return m
@@ -37,13 +29,20 @@ func NewManager(artifactHomeDir ...string) *Manager {
 // CleanPeriodically will clean up artifacts which exceeded retention time periodically.
 // This func will run forever unless panics, you'd better invoke it by a go-routine.
 func (m *Manager) CleanPeriodically(retention time.Duration) {
-	t := time.NewTicker(time.Duration(time.Hour))
+	t := time.NewTicker(m.cleanPeriod)
 	defer t.Stop()
 
 	for ; true; <-t.C {
 		log.Info("Start to scan and clean artifacts")
-		if err := m.scanAndClean(selectArtifact, retention); err != nil {
-			log.Warningf("Clean artifacts error: ", err)
+		stores, err := m.stores()
+		if err != nil {
+			log.Warningf("Get artifact stores error: %v", err)
+			continue
+		}
+		for _, store := range stores {
+			if err := m.scanAndClean(store, selectArtifact, retention); err != nil {
+				log.Warningf("Clean artifacts error: %v", err)
+			}
 		}
 	}
 }
@@ -65,39 +64,27 @@ func selectArtifact(path string) bool {
 	return true
 }
 
-// scanAndClean scans the artifacts folders and finds out exceeded retention time artifacts, and
+// scanAndClean scans objects in the store and finds out exceeded retention time artifacts, and
 // then delete them.
-func (m *Manager) scanAndClean(selectArtifact artifactSelector, retention time.Duration) error {
-	return filepath.Walk(m.artifactHomeDir, func(path string, info os.FileInfo, err error) error {
-		if err != nil {
-			return fmt.Errorf("walk in %s error: %v", path, err)
-		}
+func (m *Manager) scanAndClean(store Store, selectArtifact artifactSelector, retention time.Duration) error {
+	objects, err := store.List("")
+	if err != nil {
+		return err
+	}
 
-		if !selectArtifact(strings.TrimPrefix(path, m.artifactHomeDir)) {
-			return nil
+	for _, object := range objects {
+		if !selectArtifact(object.Key) {
+			continue
 		}
 
-		if time.Now().Before(info.ModTime().Add(retention)) {
-			return nil
+		if time.Now().Before(object.LastModified.Add(retention)) {
+			continue
 		}
 
-		log.Infof("Start to remove artifact: %s", path)
-		return os.RemoveAll(path)
-	})
-}
-
-// GetDiskAvailablePercentage returns available space in percentage format of the artifact home folder.
-func (m *Manager) GetDiskAvailablePercentage() (float64, error) {
-	s := time.Now()
-
-	available, capacity, _, _, _, _, err := fs.FsInfo(m.artifactHomeDir)
-	if err != nil {
-		return 0, err
+		log.Infof("Start to remove artifact: %s", object.Key)
+		if err := store.Delete(object.Key); err != nil {
+			return err
+		}
 	}
-
-	e := time.Now()
-	log.Infof("fsInfo cost time: %s, available: %d, capacity: %d, percentage: %v",
-		e.Sub(s), available, capacity, float64(available)/float64(capacity))
-
-	return float64(available) / float64(capacity), nil
+	return nil
 }
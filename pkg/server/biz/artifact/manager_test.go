@@ -9,8 +9,8 @@ import (
 )
 
 func TestScanAndClean(t *testing.T) {
-	manager := NewManager("testdata")
-	err := manager.scanAndClean(selectArtifact, time.Duration(0))
+	manager := NewManager(nil)
+	err := manager.scanAndClean(NewLocalStore("testdata"), selectArtifact, time.Duration(0))
 	assert.Nil(t, err)
 
 	_, err = os.Stat("testdata/tenant1/project1/wf1/wfr1/artifacts/stage1/artifacts.tar")
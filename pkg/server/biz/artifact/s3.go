@@ -0,0 +1,302 @@
+package artifact
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/caicloud/nirvana/log"
+
+	api "github.com/caicloud/cyclone/pkg/server/apis/v1alpha1"
+)
+
+const (
+	// s3RequestTimeout is timeout of requests to S3, artifacts are transferred in requests so it's long.
+	s3RequestTimeout = 30 * time.Minute
+	// defaultS3Region is used if region of the storage is not set, most S3 compatible storages accept it.
+	defaultS3Region = "us-east-1"
+	// checksumHeader is the user metadata header to record checksums of objects.
+	checksumHeader = "X-Amz-Meta-Sha256"
+)
+
+// S3Store stores artifacts in a bucket of S3 compatible object storage.
+type S3Store struct {
+	client   *http.Client
+	endpoint *url.URL
+	bucket   string
+	prefix   string
+	signer   *signer
+	// now is used to get signing time, it's replaced in tests.
+	now func() time.Time
+}
+
+// NewS3Store creates a store for the S3 storage, buckets are addressed in path style, i.e. {endpoint}/{bucket}/{key}.
+func NewS3Store(client *http.Client, storage *api.S3Storage) (*S3Store, error) {
+	if err := ValidateStorage(&api.ArtifactStorage{Type: api.S3ArtifactStorage, S3: storage}); err != nil {
+		return nil, err
+	}
+	endpoint, err := url.Parse(strings.TrimSuffix(storage.Endpoint, "/"))
+	if err != nil {
+		return nil, fmt.Errorf("invalid S3 endpoint %s: %v", storage.Endpoint, err)
+	}
+	if endpoint.Scheme == "" || endpoint.Host == "" {
+		return nil, fmt.Errorf("invalid S3 endpoint %s: scheme and host are required", storage.Endpoint)
+	}
+
+	region := storage.Region
+	if region == "" {
+		region = defaultS3Region
+	}
+	prefix := strings.Trim(storage.Prefix, "/")
+	if prefix != "" {
+		prefix += "/"
+	}
+
+	return &S3Store{
+		client:   client,
+		endpoint: endpoint,
+		bucket:   storage.Bucket,
+		prefix:   prefix,
+		signer:   &signer{accessKey: storage.AccessKey, secretKey: storage.SecretKey, region: region},
+		now:      time.Now,
+	}, nil
+}
+
+// objectURL returns URL of the object, path of the URL is encoded in the same way as signing.
+func (s *S3Store) objectURL(key string) *url.URL {
+	u := *s.endpoint
+	u.Path = s.endpoint.Path + "/" + s.bucket + "/" + s.prefix + key
+	u.RawPath = uriEncode(u.Path, false)
+	return &u
+}
+
+func (s *S3Store) bucketURL(query url.Values) *url.URL {
+	u := *s.endpoint
+	u.Path = s.endpoint.Path + "/" + s.bucket + "/"
+	u.RawPath = uriEncode(u.Path, false)
+	u.RawQuery = canonicalQuery(query)
+	return &u
+}
+
+// do signs and sends the request, error is returned for responses not in 2xx.
+func (s *S3Store) do(req *http.Request, payloadHash string) (*http.Response, error) {
+	s.signer.sign(req, payloadHash, s.now())
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode/100 == 2 {
+		return resp, nil
+	}
+
+	defer closeBody(resp)
+	if resp.StatusCode == http.StatusNotFound {
+		return nil, ErrNotFound
+	}
+	s3Err := struct {
+		Code    string `xml:"Code"`
+		Message string `xml:"Message"`
+	}{}
+	body, _ := ioutil.ReadAll(resp.Body)
+	if xml.Unmarshal(body, &s3Err) != nil || s3Err.Code == "" {
+		return nil, fmt.Errorf("S3 %s %s error: status %d", req.Method, req.URL.Path, resp.StatusCode)
+	}
+	return nil, fmt.Errorf("S3 %s %s error: %s: %s", req.Method, req.URL.Path, s3Err.Code, s3Err.Message)
+}
+
+// Put implements Store. The content is read twice, once to get the checksum and once to upload.
+func (s *S3Store) Put(key string, content io.ReadSeeker) (*Object, error) {
+	if err := validateKey(key); err != nil {
+		return nil, err
+	}
+
+	hash := sha256.New()
+	size, err := io.Copy(hash, content)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := content.Seek(0, io.SeekStart); err != nil {
+		return nil, err
+	}
+	checksum := hex.EncodeToString(hash.Sum(nil))
+
+	req, err := http.NewRequest(http.MethodPut, s.objectURL(key).String(), ioutil.NopCloser(content))
+	if err != nil {
+		return nil, err
+	}
+	req.ContentLength = size
+	req.Header.Set("Content-Type", "application/octet-stream")
+	req.Header.Set(checksumHeader, checksum)
+	resp, err := s.do(req, checksum)
+	if err != nil {
+		return nil, err
+	}
+	closeBody(resp)
+
+	return &Object{Key: key, Size: size, Checksum: checksum, LastModified: s.now()}, nil
+}
+
+// Get implements Store.
+func (s *S3Store) Get(key string) (io.ReadCloser, *Object, error) {
+	resp, err := s.request(http.MethodGet, key)
+	if err != nil {
+		return nil, nil, err
+	}
+	return resp.Body, objectFromHeader(key, resp), nil
+}
+
+// Stat implements Store.
+func (s *S3Store) Stat(key string) (*Object, error) {
+	resp, err := s.request(http.MethodHead, key)
+	if err != nil {
+		return nil, err
+	}
+	closeBody(resp)
+	return objectFromHeader(key, resp), nil
+}
+
+func (s *S3Store) request(method, key string) (*http.Response, error) {
+	if err := validateKey(key); err != nil {
+		return nil, err
+	}
+	req, err := http.NewRequest(method, s.objectURL(key).String(), nil)
+	if err != nil {
+		return nil, err
+	}
+	return s.do(req, emptyPayloadHash)
+}
+
+func objectFromHeader(key string, resp *http.Response) *Object {
+	object := &Object{
+		Key:      key,
+		Checksum: resp.Header.Get(checksumHeader),
+	}
+	object.Size, _ = strconv.ParseInt(resp.Header.Get("Content-Length"), 10, 64)
+	object.LastModified, _ = http.ParseTime(resp.Header.Get("Last-Modified"))
+	return object
+}
+
+// listResult is the result of ListObjectsV2.
+type listResult struct {
+	IsTruncated           bool   `xml:"IsTruncated"`
+	NextContinuationToken string `xml:"NextContinuationToken"`
+	Contents              []struct {
+		Key          string    `xml:"Key"`
+		Size         int64     `xml:"Size"`
+		LastModified time.Time `xml:"LastModified"`
+	} `xml:"Contents"`
+}
+
+// List implements Store.
+func (s *S3Store) List(prefix string) ([]Object, error) {
+	var objects []Object
+	token := ""
+	for {
+		query := url.Values{}
+		query.Set("list-type", "2")
+		query.Set("prefix", s.prefix+prefix)
+		if token != "" {
+			query.Set("continuation-token", token)
+		}
+		req, err := http.NewRequest(http.MethodGet, s.bucketURL(query).String(), nil)
+		if err != nil {
+			return nil, err
+		}
+		resp, err := s.do(req, emptyPayloadHash)
+		if err != nil {
+			return nil, err
+		}
+
+		result := &listResult{}
+		err = xml.NewDecoder(resp.Body).Decode(result)
+		closeBody(resp)
+		if err != nil {
+			return nil, fmt.Errorf("decode S3 list result error: %v", err)
+		}
+
+		for _, content := range result.Contents {
+			objects = append(objects, Object{
+				Key:          strings.TrimPrefix(content.Key, s.prefix),
+				Size:         content.Size,
+				LastModified: content.LastModified,
+			})
+		}
+		if !result.IsTruncated || result.NextContinuationToken == "" {
+			return objects, nil
+		}
+		token = result.NextContinuationToken
+	}
+}
+
+// Copy implements Store, the object is copied in the storage with its metadata.
+func (s *S3Store) Copy(src, dst string) error {
+	if err := validateKey(src); err != nil {
+		return err
+	}
+	if err := validateKey(dst); err != nil {
+		return err
+	}
+
+	req, err := http.NewRequest(http.MethodPut, s.objectURL(dst).String(), nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("X-Amz-Copy-Source", uriEncode("/"+s.bucket+"/"+s.prefix+src, false))
+	resp, err := s.do(req, emptyPayloadHash)
+	if err != nil {
+		return err
+	}
+	closeBody(resp)
+	return nil
+}
+
+// Delete implements Store.
+func (s *S3Store) Delete(key string) error {
+	if err := validateKey(key); err != nil {
+		return err
+	}
+
+	objects, err := s.List(strings.TrimSuffix(key, "/") + "/")
+	if err != nil {
+		return err
+	}
+	keys := []string{key}
+	for _, object := range objects {
+		keys = append(keys, object.Key)
+	}
+
+	for _, k := range keys {
+		// Deleting objects not exist succeeds in S3.
+		resp, err := s.request(http.MethodDelete, k)
+		if err == ErrNotFound {
+			continue
+		}
+		if err != nil {
+			return err
+		}
+		closeBody(resp)
+	}
+	return nil
+}
+
+// PresignedURL implements Store.
+func (s *S3Store) PresignedURL(key string, expires time.Duration) (string, error) {
+	if err := validateKey(key); err != nil {
+		return "", err
+	}
+	return s.signer.presign(http.MethodGet, s.objectURL(key), s.now(), expires), nil
+}
+
+func closeBody(resp *http.Response) {
+	if err := resp.Body.Close(); err != nil {
+		log.Warningf("Close response body error: %v", err)
+	}
+}
@@ -0,0 +1,100 @@
+package artifact
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	api "github.com/caicloud/cyclone/pkg/server/apis/v1alpha1"
+)
+
+// ErrNotFound is returned when the object of a key doesn't exist in the store.
+var ErrNotFound = errors.New("artifact not found")
+
+// Object describes a file in an artifact store.
+type Object struct {
+	// Key is a slash separated path of the object, artifacts of workflowrun stages are stored with keys in format
+	// of {tenant}/{project}/{workflow}/{workflowrun}/artifacts/{stage}/{file}.
+	Key string
+	// Size of the object in bytes.
+	Size int64
+	// Checksum is the hex encoded SHA-256 of the object, it's empty if it's unknown.
+	Checksum string
+	// LastModified is the time the object was stored.
+	LastModified time.Time
+}
+
+// Store stores artifacts by keys.
+type Store interface {
+	// Put stores the content to the key, replacing the existing object. The stored object with checksum is returned.
+	Put(key string, content io.ReadSeeker) (*Object, error)
+	// Get opens the object of the key, caller should close the returned reader.
+	Get(key string) (io.ReadCloser, *Object, error)
+	// Stat gets the object of the key with checksum.
+	Stat(key string) (*Object, error)
+	// List lists objects whose keys start with the prefix, checksums of them are not loaded.
+	List(prefix string) ([]Object, error)
+	// Copy copies the object of the src key to the dst key.
+	Copy(src, dst string) error
+	// Delete deletes the object of the key, and all objects under the key as a folder.
+	Delete(key string) error
+	// PresignedURL returns an URL to download the object without credentials in the expiration. Empty URL is
+	// returned if the store doesn't support it.
+	PresignedURL(key string, expires time.Duration) (string, error)
+}
+
+// NewStore creates a store from the storage config. Local storage is rooted at the home folder, and the secret key
+// of S3 storage should be already resolved.
+func NewStore(storage *api.ArtifactStorage, home string) (Store, error) {
+	if storage == nil {
+		return NewLocalStore(home), nil
+	}
+
+	switch storage.Type {
+	case "", api.LocalArtifactStorage:
+		return NewLocalStore(home), nil
+	case api.S3ArtifactStorage:
+		return NewS3Store(&http.Client{Timeout: s3RequestTimeout}, storage.S3)
+	default:
+		return nil, fmt.Errorf("artifact storage type %s not supported", storage.Type)
+	}
+}
+
+// ValidateStorage checks whether the storage config is complete.
+func ValidateStorage(storage *api.ArtifactStorage) error {
+	if storage == nil {
+		return nil
+	}
+
+	switch storage.Type {
+	case "", api.LocalArtifactStorage:
+		return nil
+	case api.S3ArtifactStorage:
+		s3 := storage.S3
+		if s3 == nil || s3.Endpoint == "" || s3.Bucket == "" {
+			return fmt.Errorf("endpoint and bucket of S3 storage are required")
+		}
+		if s3.AccessKey == "" || s3.SecretKey == "" {
+			return fmt.Errorf("access key and secret key of S3 storage are required")
+		}
+		return nil
+	default:
+		return fmt.Errorf("artifact storage type %s not supported", storage.Type)
+	}
+}
+
+// validateKey checks the key won't escape from the store.
+func validateKey(key string) error {
+	if key == "" {
+		return fmt.Errorf("artifact key can not be empty")
+	}
+	for _, s := range strings.Split(key, "/") {
+		if s == ".." {
+			return fmt.Errorf("invalid artifact key %s", key)
+		}
+	}
+	return nil
+}
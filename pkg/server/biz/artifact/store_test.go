@@ -0,0 +1,265 @@
+package artifact
+
+import (
+	"encoding/xml"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	api "github.com/caicloud/cyclone/pkg/server/apis/v1alpha1"
+)
+
+// fakeS3 is an in-process S3 stand-in, it verifies signatures of requests and stores objects in memory.
+type fakeS3 struct {
+	signer  *signer
+	lock    sync.Mutex
+	objects map[string]fakeObject
+	// pageSize limits objects in a page of list results.
+	pageSize int
+}
+
+type fakeObject struct {
+	data     []byte
+	checksum string
+	modified time.Time
+}
+
+func newFakeS3() *fakeS3 {
+	return &fakeS3{
+		signer:   &signer{accessKey: "ak", secretKey: "sk", region: defaultS3Region},
+		objects:  make(map[string]fakeObject),
+		pageSize: 2,
+	}
+}
+
+// verify recomputes signature of the request as it's received.
+func (f *fakeS3) verify(r *http.Request) bool {
+	auth := r.Header.Get("Authorization")
+	i := strings.Index(auth, "SignedHeaders=")
+	j := strings.Index(auth, ", Signature=")
+	if i < 0 || j < 0 {
+		return false
+	}
+	signedHeaders := auth[i+len("SignedHeaders=") : j]
+
+	var headers strings.Builder
+	for _, name := range strings.Split(signedHeaders, ";") {
+		value := r.Header.Get(name)
+		if name == "host" {
+			value = r.Host
+		}
+		headers.WriteString(name + ":" + value + "\n")
+	}
+
+	t, err := time.Parse(signTimeFormat, r.Header.Get("X-Amz-Date"))
+	if err != nil {
+		return false
+	}
+	expected := f.signer.signature(t, r.Method, r.URL.EscapedPath(), canonicalQuery(r.URL.Query()), headers.String(),
+		signedHeaders, r.Header.Get("X-Amz-Content-Sha256"))
+	return auth[j+len(", Signature="):] == expected
+}
+
+func (f *fakeS3) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+
+	if !f.verify(r) {
+		w.WriteHeader(http.StatusForbidden)
+		fmt.Fprint(w, "<Error><Code>SignatureDoesNotMatch</Code><Message>signature mismatch</Message></Error>")
+		return
+	}
+
+	key := strings.TrimPrefix(r.URL.Path, "/bucket/")
+	switch {
+	case r.Method == http.MethodGet && key == "":
+		f.list(w, r)
+	case r.Method == http.MethodPut && r.Header.Get("X-Amz-Copy-Source") != "":
+		source, _ := url.PathUnescape(r.Header.Get("X-Amz-Copy-Source"))
+		src, ok := f.objects[strings.TrimPrefix(source, "/bucket/")]
+		if !ok {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		src.modified = time.Now()
+		f.objects[key] = src
+	case r.Method == http.MethodPut:
+		data, _ := ioutil.ReadAll(r.Body)
+		if hashHex(data) != r.Header.Get("X-Amz-Content-Sha256") {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		f.objects[key] = fakeObject{data: data, checksum: r.Header.Get(checksumHeader), modified: time.Now()}
+	case r.Method == http.MethodGet || r.Method == http.MethodHead:
+		object, ok := f.objects[key]
+		if !ok {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		w.Header().Set(checksumHeader, object.checksum)
+		w.Header().Set("Content-Length", fmt.Sprintf("%d", len(object.data)))
+		w.Header().Set("Last-Modified", object.modified.UTC().Format(http.TimeFormat))
+		if r.Method == http.MethodGet {
+			_, _ = w.Write(object.data)
+		}
+	case r.Method == http.MethodDelete:
+		delete(f.objects, key)
+		w.WriteHeader(http.StatusNoContent)
+	default:
+		w.WriteHeader(http.StatusMethodNotAllowed)
+	}
+}
+
+func (f *fakeS3) list(w http.ResponseWriter, r *http.Request) {
+	var keys []string
+	for key := range f.objects {
+		if strings.HasPrefix(key, r.URL.Query().Get("prefix")) && key > r.URL.Query().Get("continuation-token") {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+
+	type content struct {
+		Key          string
+		Size         int
+		LastModified time.Time
+	}
+	result := struct {
+		XMLName               xml.Name `xml:"ListBucketResult"`
+		IsTruncated           bool
+		NextContinuationToken string `xml:",omitempty"`
+		Contents              []content
+	}{}
+	if len(keys) > f.pageSize {
+		keys = keys[:f.pageSize]
+		result.IsTruncated = true
+		result.NextContinuationToken = keys[len(keys)-1]
+	}
+	for _, key := range keys {
+		result.Contents = append(result.Contents, content{key, len(f.objects[key].data), f.objects[key].modified})
+	}
+	_ = xml.NewEncoder(w).Encode(result)
+}
+
+func TestStores(t *testing.T) {
+	dir, err := ioutil.TempDir("", "artifacts")
+	assert.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	fake := newFakeS3()
+	server := httptest.NewServer(fake)
+	defer server.Close()
+	s3, err := NewS3Store(server.Client(), &api.S3Storage{
+		Endpoint:  server.URL,
+		Bucket:    "bucket",
+		Prefix:    "/cyclone/",
+		AccessKey: "ak",
+		SecretKey: "sk",
+	})
+	assert.Nil(t, err)
+
+	for name, store := range map[string]Store{"local": NewLocalStore(dir), "s3": s3} {
+		t.Run(name, func(t *testing.T) {
+			testStore(t, store)
+		})
+	}
+
+	// Objects are stored under the prefix.
+	_, err = s3.Put("t/p/w/r/artifacts/build/a.tar", strings.NewReader("x"))
+	assert.Nil(t, err)
+	_, ok := fake.objects["cyclone/t/p/w/r/artifacts/build/a.tar"]
+	assert.True(t, ok)
+}
+
+func testStore(t *testing.T, store Store) {
+	const key = "t/p/w/r1/artifacts/build/a b.tar"
+	content := "artifact content"
+	checksum := hashHex([]byte(content))
+
+	_, err := store.Stat(key)
+	assert.Equal(t, ErrNotFound, err)
+
+	object, err := store.Put(key, strings.NewReader(content))
+	assert.Nil(t, err)
+	assert.Equal(t, &Object{Key: key, Size: int64(len(content)), Checksum: checksum, LastModified: object.LastModified}, object)
+
+	reader, object, err := store.Get(key)
+	assert.Nil(t, err)
+	data, err := ioutil.ReadAll(reader)
+	assert.Nil(t, err)
+	assert.Nil(t, reader.Close())
+	assert.Equal(t, content, string(data))
+	assert.Equal(t, checksum, object.Checksum)
+	assert.Equal(t, int64(len(content)), object.Size)
+
+	assert.Nil(t, store.Copy(key, "t/p/w/r2/artifacts/build/a b.tar"))
+	for _, k := range []string{"t/p/w/r1/artifacts/test/report.xml", "t/p/w/r1/logs/build_main"} {
+		_, err = store.Put(k, strings.NewReader(k))
+		assert.Nil(t, err)
+	}
+
+	object, err = store.Stat("t/p/w/r2/artifacts/build/a b.tar")
+	assert.Nil(t, err)
+	assert.Equal(t, checksum, object.Checksum)
+
+	objects, err := store.List("t/p/w/r1/artifacts/")
+	assert.Nil(t, err)
+	var keys []string
+	for _, object := range objects {
+		keys = append(keys, object.Key)
+	}
+	sort.Strings(keys)
+	assert.Equal(t, []string{key, "t/p/w/r1/artifacts/test/report.xml"}, keys)
+
+	objects, err = store.List("t/p/w/")
+	assert.Nil(t, err)
+	assert.Len(t, objects, 4)
+
+	assert.Nil(t, store.Delete("t/p/w/r1"))
+	objects, err = store.List("t/")
+	assert.Nil(t, err)
+	assert.Len(t, objects, 1)
+	assert.Equal(t, "t/p/w/r2/artifacts/build/a b.tar", objects[0].Key)
+
+	assert.Nil(t, store.Delete("t/p/w/r2/artifacts/build/a b.tar"))
+	_, _, err = store.Get("t/p/w/r2/artifacts/build/a b.tar")
+	assert.Equal(t, ErrNotFound, err)
+
+	assert.NotNil(t, store.Delete("t/../../etc"))
+}
+
+func TestS3PresignedURL(t *testing.T) {
+	fake := newFakeS3()
+	server := httptest.NewServer(fake)
+	defer server.Close()
+	store, err := NewS3Store(server.Client(), &api.S3Storage{
+		Endpoint:  server.URL,
+		Bucket:    "bucket",
+		AccessKey: "ak",
+		SecretKey: "sk",
+	})
+	assert.Nil(t, err)
+
+	u, err := store.PresignedURL("t/p/w/r/artifacts/build/a.tar", time.Hour)
+	assert.Nil(t, err)
+	assert.True(t, strings.HasPrefix(u, server.URL+"/bucket/t/p/w/r/artifacts/build/a.tar?X-Amz-Algorithm=AWS4-HMAC-SHA256"))
+	assert.Contains(t, u, "X-Amz-Expires=3600")
+	assert.Contains(t, u, "X-Amz-Signature=")
+
+	_, err = store.Put("t/p/w/r/artifacts/build/a.tar", strings.NewReader("x"))
+	assert.Nil(t, err)
+	fake.signer.secretKey = "wrong"
+	_, _, err = store.Get("t/p/w/r/artifacts/build/a.tar")
+	assert.NotNil(t, err)
+	assert.Contains(t, err.Error(), "SignatureDoesNotMatch")
+}
@@ -16,8 +16,10 @@ type Manager interface {
 // GetManager ...
 func GetManager(typ v1alpha1.TriggerType) (Manager, error) {
 	switch typ {
-	case v1alpha1.TriggerTypeSCM, v1alpha1.TriggerTypeWebhook:
+	case v1alpha1.TriggerTypeSCM:
 		return getScmManager(), nil
+	case v1alpha1.TriggerTypeWebhook:
+		return &webhookManager{}, nil
 	}
 
 	return nil, cerr.ErrorUnsupported.Error("trigger type", typ)
@@ -0,0 +1,11 @@
+package hook
+
+import (
+	"github.com/caicloud/cyclone/pkg/apis/cyclone/v1alpha1"
+)
+
+// ImageTriggerURL generates the URL that registries send push events to for Image type WorkflowTriggers, it's
+// shared by all triggers of the DockerRegistry integration.
+func ImageTriggerURL(tenant, integration string) (string, error) {
+	return generateWebhookURL(tenant, v1alpha1.TriggerTypeImage, integration)
+}
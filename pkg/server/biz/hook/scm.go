@@ -44,6 +44,9 @@ func getScmManager() *SCMManager {
 // Register registers SCM webhook if if has not been registered.
 func (*SCMManager) Register(tenant string, wft v1alpha1.WorkflowTrigger) error {
 	var wftName, secretName, repo = wft.Name, wft.Spec.SCM.Secret, wft.Spec.SCM.Repo
+	if IsSCMPolling(&wft) {
+		return nil
+	}
 
 	log.Infof("start to register webhook for %s/%s , %s", secretName, repo, wftName)
 	scmManager.mutex.Lock()
@@ -89,7 +92,7 @@ func createSCMWebhook(scmSource *api.SCMSource, tenant, secret, repo string) err
 		return err
 	}
 
-	webhookURL, err := generateWebhookURL(tenant, secret)
+	webhookURL, err := generateWebhookURL(tenant, v1alpha1.TriggerTypeSCM, secret)
 	if err != nil {
 		return cerr.ErrorUnknownInternal.Error(err)
 	}
@@ -113,7 +116,9 @@ func createSCMWebhook(scmSource *api.SCMSource, tenant, secret, repo string) err
 	return err
 }
 
-func generateWebhookURL(tenant, secret string) (string, error) {
+// generateWebhookURL generates webhook URL from the URL template, 'integration' is name of the integration for SCM
+// webhooks, or name of the WorkflowTrigger for generic webhooks.
+func generateWebhookURL(tenant string, sourceType v1alpha1.TriggerType, integration string) (string, error) {
 	type urlData struct {
 		Tenant      string `json:"Tenant"`
 		SourceType  string `json:"SourceType"`
@@ -126,8 +131,8 @@ func generateWebhookURL(tenant, secret string) (string, error) {
 	var buf bytes.Buffer
 	err = tmpl.Execute(&buf, urlData{
 		Tenant:      tenant,
-		SourceType:  "SCM",
-		Integration: secret,
+		SourceType:  string(sourceType),
+		Integration: integration,
 	})
 	if err != nil {
 		return "", fmt.Errorf("execute template: %w", err)
@@ -138,6 +143,9 @@ func generateWebhookURL(tenant, secret string) (string, error) {
 // Unregister unregisters SCM webhook if if has no other wft using.
 func (o *SCMManager) Unregister(tenant string, wft v1alpha1.WorkflowTrigger) error {
 	var wftName, secretName, repo = wft.Name, wft.Spec.SCM.Secret, wft.Spec.SCM.Repo
+	if IsSCMPolling(&wft) {
+		return nil
+	}
 
 	log.Infof("start to unregister webhook for %s/%s , %s", secretName, repo, wftName)
 	o.mutex.Lock()
@@ -183,7 +191,7 @@ func deleteSCMWebhook(scmSource *api.SCMSource, tenant, secret, repo string) err
 		return err
 	}
 
-	webhookURL, err := generateWebhookURL(tenant, secret)
+	webhookURL, err := generateWebhookURL(tenant, v1alpha1.TriggerTypeSCM, secret)
 	if err != nil {
 		log.Error("Error generating webhook URL: %v", err)
 		return fmt.Errorf("generate webhook URL: %w", err)
@@ -199,7 +207,12 @@ func LabelSCMTrigger(wft *v1alpha1.WorkflowTrigger) {
 	wft.Labels[meta.LabelWftEventSource] = wft.Spec.SCM.Secret
 }
 
-// ListSCMWfts list all related SCM type workflow triggers
+// IsSCMPolling checks whether the SCM trigger polls the repo instead of receiving webhooks.
+func IsSCMPolling(wft *v1alpha1.WorkflowTrigger) bool {
+	return wft.Spec.Type == v1alpha1.TriggerTypeSCM && wft.Spec.SCM.PollInterval != ""
+}
+
+// ListSCMWfts list all related SCM type workflow triggers receiving webhooks, triggers polling the repo are excluded.
 func ListSCMWfts(tenant, repo, integration string) (*v1alpha1.WorkflowTriggerList, error) {
 	labelMap := make(map[string]string)
 
@@ -220,7 +233,7 @@ func ListSCMWfts(tenant, repo, integration string) (*v1alpha1.WorkflowTriggerLis
 	wfts := originWfts.DeepCopy()
 	wfts.Items = make([]v1alpha1.WorkflowTrigger, 0)
 	for _, wft := range originWfts.Items {
-		if wft.Spec.SCM.Repo == repo {
+		if wft.Spec.SCM.Repo == repo && !IsSCMPolling(&wft) {
 			wfts.Items = append(wfts.Items, wft)
 		}
 	}
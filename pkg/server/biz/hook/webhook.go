@@ -0,0 +1,24 @@
+package hook
+
+import (
+	"github.com/caicloud/cyclone/pkg/apis/cyclone/v1alpha1"
+)
+
+// webhookManager manages generic webhooks. Nothing needs to be registered in external systems, as external
+// systems send payloads to the URL of the WorkflowTrigger directly.
+type webhookManager struct{}
+
+// Register implements Manager.
+func (*webhookManager) Register(tenant string, wft v1alpha1.WorkflowTrigger) error {
+	return nil
+}
+
+// Unregister implements Manager.
+func (*webhookManager) Unregister(tenant string, wft v1alpha1.WorkflowTrigger) error {
+	return nil
+}
+
+// WebhookTriggerURL generates the URL that external systems send payloads to for a Webhook type WorkflowTrigger.
+func WebhookTriggerURL(tenant, wft string) (string, error) {
+	return generateWebhookURL(tenant, v1alpha1.TriggerTypeWebhook, wft)
+}
@@ -0,0 +1,90 @@
+package identity
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	// UserHeader is the header that holds the user sending the request.
+	UserHeader = "X-User"
+	// GroupsHeader is the header that holds comma separated groups of the user.
+	GroupsHeader = "X-User-Groups"
+	// TimestampHeader is the header that holds the unix time in seconds when the identity headers are signed.
+	TimestampHeader = "X-User-Timestamp"
+	// SignatureHeader is the header that holds the signature of the identity headers.
+	SignatureHeader = "X-User-Signature"
+
+	// MaxClockSkew is the max difference between the signing time and the server time, signed headers are
+	// rejected out of it so that they can't be replayed long after.
+	MaxClockSkew = 5 * time.Minute
+)
+
+var (
+	// ErrNotConfigured is returned when no secret is configured to verify identities.
+	ErrNotConfigured = errors.New("user identity secret is not configured")
+	// ErrMissing is returned when the request doesn't carry a signed identity.
+	ErrMissing = errors.New("user identity not found in request")
+)
+
+// Identity is the verified identity of the user sending a request.
+type Identity struct {
+	// User name
+	User string
+	// Groups the user belongs to
+	Groups []string
+}
+
+// Sign signs the identity headers of a request with the secret, the signature is hex encoded HMAC-SHA256 digest of
+// '{method}\n{path}\n{user}\n{groups}\n{timestamp}'. Method and path are signed, so that the headers can't be
+// replayed to other requests. It's used by the trusted gateway in front of cyclone server.
+func Sign(secret, method, path, user, groups, timestamp string) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(strings.Join([]string{method, path, user, groups, timestamp}, "\n")))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// FromRequest gets the identity from headers of the request, and verifies it was signed for the method and path of
+// the request by the trusted gateway with the secret at a time not far from now.
+func FromRequest(secret string, request *http.Request, now time.Time) (*Identity, error) {
+	if secret == "" {
+		return nil, ErrNotConfigured
+	}
+
+	header := request.Header
+	user := header.Get(UserHeader)
+	groups := header.Get(GroupsHeader)
+	timestamp := header.Get(TimestampHeader)
+	signature := header.Get(SignatureHeader)
+	if user == "" || timestamp == "" || signature == "" {
+		return nil, ErrMissing
+	}
+
+	expected := Sign(secret, request.Method, request.URL.Path, user, groups, timestamp)
+	if !hmac.Equal([]byte(expected), []byte(strings.ToLower(signature))) {
+		return nil, fmt.Errorf("invalid signature of user %s", user)
+	}
+
+	seconds, err := strconv.ParseInt(timestamp, 10, 64)
+	if err != nil {
+		return nil, fmt.Errorf("invalid timestamp %s: %v", timestamp, err)
+	}
+	if skew := now.Sub(time.Unix(seconds, 0)); skew > MaxClockSkew || skew < -MaxClockSkew {
+		return nil, fmt.Errorf("identity of user %s signed at %s expired", user, time.Unix(seconds, 0).Format(time.RFC3339))
+	}
+
+	identity := &Identity{User: user}
+	for _, g := range strings.Split(groups, ",") {
+		if g = strings.TrimSpace(g); g != "" {
+			identity.Groups = append(identity.Groups, g)
+		}
+	}
+	return identity, nil
+}
@@ -0,0 +1,67 @@
+package identity
+
+import (
+	"net/http"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+const (
+	testMethod = http.MethodPut
+	testPath   = "/apis/v1alpha1/projects/p/workflows/wf/workflowruns/wfr/stages/approval/approve"
+)
+
+func signedRequest(secret, user, groups string, signedAt time.Time) *http.Request {
+	timestamp := strconv.FormatInt(signedAt.Unix(), 10)
+	request, _ := http.NewRequest(testMethod, "http://cyclone-server"+testPath+"?comment=lgtm", nil)
+	request.Header.Set(UserHeader, user)
+	request.Header.Set(GroupsHeader, groups)
+	request.Header.Set(TimestampHeader, timestamp)
+	request.Header.Set(SignatureHeader, Sign(secret, testMethod, testPath, user, groups, timestamp))
+	return request
+}
+
+func TestFromRequest(t *testing.T) {
+	now := time.Unix(100000, 0)
+
+	identity, err := FromRequest("secret", signedRequest("secret", "alice", "dev, ops,", now.Add(-time.Minute)), now)
+	assert.Nil(t, err)
+	assert.Equal(t, &Identity{User: "alice", Groups: []string{"dev", "ops"}}, identity)
+
+	_, err = FromRequest("", signedRequest("secret", "alice", "", now), now)
+	assert.Equal(t, ErrNotConfigured, err)
+
+	// Headers set by clients without signature.
+	request, _ := http.NewRequest(testMethod, testPath, nil)
+	request.Header.Set(UserHeader, "alice")
+	request.Header.Set(GroupsHeader, "admin")
+	_, err = FromRequest("secret", request, now)
+	assert.Equal(t, ErrMissing, err)
+
+	_, err = FromRequest("secret", signedRequest("other", "alice", "", now), now)
+	assert.NotNil(t, err)
+
+	// Groups are changed after signed.
+	request = signedRequest("secret", "alice", "dev", now)
+	request.Header.Set(GroupsHeader, "admin")
+	_, err = FromRequest("secret", request, now)
+	assert.NotNil(t, err)
+
+	// Headers are replayed to another endpoint or with another method.
+	request = signedRequest("secret", "alice", "", now)
+	request.URL.Path = "/apis/v1alpha1/projects/p/workflows/wf/workflowruns/wfr/stages/approval/reject"
+	_, err = FromRequest("secret", request, now)
+	assert.NotNil(t, err)
+	request = signedRequest("secret", "alice", "", now)
+	request.Method = http.MethodDelete
+	_, err = FromRequest("secret", request, now)
+	assert.NotNil(t, err)
+
+	_, err = FromRequest("secret", signedRequest("secret", "alice", "", now.Add(-MaxClockSkew-time.Second)), now)
+	assert.NotNil(t, err)
+	_, err = FromRequest("secret", signedRequest("secret", "alice", "", now.Add(MaxClockSkew+time.Second)), now)
+	assert.NotNil(t, err)
+}
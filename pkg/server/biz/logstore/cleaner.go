@@ -0,0 +1,88 @@
+package logstore
+
+import (
+	"sort"
+	"time"
+
+	"github.com/caicloud/nirvana/log"
+
+	api "github.com/caicloud/cyclone/pkg/server/apis/v1alpha1"
+)
+
+// PolicyGetter gets the log retention policy of the tenant.
+type PolicyGetter func(tenant string) *api.LogRetention
+
+// Cleaner deletes logs exceeding retention policies of tenants.
+type Cleaner struct {
+	cleanPeriod time.Duration
+	store       Store
+	policy      PolicyGetter
+}
+
+// NewCleaner creates a cleaner to clean logs in the store with policies got by the getter.
+func NewCleaner(store Store, policy PolicyGetter) *Cleaner {
+	return &Cleaner{
+		cleanPeriod: time.Hour,
+		store:       store,
+		policy:      policy,
+	}
+}
+
+// CleanPeriodically cleans logs exceeding retention policies periodically.
+// This func will run forever unless panics, you'd better invoke it by a go-routine.
+func (c *Cleaner) CleanPeriodically() {
+	t := time.NewTicker(c.cleanPeriod)
+	defer t.Stop()
+
+	for ; true; <-t.C {
+		log.Info("Start to scan and clean logs")
+		if err := c.Clean(time.Now()); err != nil {
+			log.Warningf("Clean logs error: %v", err)
+		}
+	}
+}
+
+// Clean deletes logs exceeding retention policies at the time. Failures to delete logs of a WorkflowRun are only
+// logged, so that logs of other WorkflowRuns are still cleaned.
+func (c *Cleaner) Clean(now time.Time) error {
+	runs, err := c.store.Runs()
+	if err != nil {
+		return err
+	}
+
+	// Group runs by workflow to find out the oldest runs exceeding max number.
+	workflows := make(map[Run][]RunInfo)
+	for _, run := range runs {
+		key := Run{Tenant: run.Tenant, Project: run.Project, Workflow: run.Workflow}
+		workflows[key] = append(workflows[key], run)
+	}
+
+	policies := make(map[string]*api.LogRetention)
+	for key, runs := range workflows {
+		policy, ok := policies[key.Tenant]
+		if !ok {
+			policy = c.policy(key.Tenant)
+			policies[key.Tenant] = policy
+		}
+		if policy == nil {
+			continue
+		}
+
+		sort.Slice(runs, func(i, j int) bool {
+			return runs[i].LastModified.After(runs[j].LastModified)
+		})
+		for i, run := range runs {
+			expired := policy.Seconds > 0 && now.Sub(run.LastModified) > time.Duration(policy.Seconds)*time.Second
+			exceeded := policy.MaxWorkflowRuns > 0 && i >= policy.MaxWorkflowRuns
+			if !expired && !exceeded {
+				continue
+			}
+
+			log.Infof("Start to remove logs of workflowrun %s/%s/%s/%s", run.Tenant, run.Project, run.Workflow, run.WorkflowRun)
+			if err := c.store.Delete(run.Run); err != nil {
+				log.Warningf("Remove logs of workflowrun %s/%s/%s/%s error: %v", run.Tenant, run.Project, run.Workflow, run.WorkflowRun, err)
+			}
+		}
+	}
+	return nil
+}
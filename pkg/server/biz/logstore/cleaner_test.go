@@ -0,0 +1,72 @@
+package logstore
+
+import (
+	"fmt"
+	"io"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	api "github.com/caicloud/cyclone/pkg/server/apis/v1alpha1"
+)
+
+// fakeStore records deleted runs, deleting the failed run returns error.
+type fakeStore struct {
+	runs    []RunInfo
+	failed  string
+	deleted []string
+}
+
+func (s *fakeStore) Writer(run Run, stage, container string) (io.WriteCloser, error) { return nil, nil }
+func (s *fakeStore) Query(run Run, query *Query) (*Result, error)                    { return nil, nil }
+func (s *fakeStore) Export(run Run, w io.Writer) error                               { return nil }
+func (s *fakeStore) Runs() ([]RunInfo, error)                                        { return s.runs, nil }
+func (s *fakeStore) Delete(run Run) error {
+	if run.WorkflowRun == s.failed {
+		return fmt.Errorf("failed to delete %s", run.WorkflowRun)
+	}
+	s.deleted = append(s.deleted, run.Tenant+"/"+run.Workflow+"/"+run.WorkflowRun)
+	return nil
+}
+
+func TestClean(t *testing.T) {
+	now := time.Unix(10000, 0)
+	runInfo := func(tenant, workflow, wfr string, age time.Duration) RunInfo {
+		return RunInfo{
+			Run:          Run{Tenant: tenant, Project: "p", Workflow: workflow, WorkflowRun: wfr},
+			LastModified: now.Add(-age),
+		}
+	}
+
+	store := &fakeStore{runs: []RunInfo{
+		runInfo("t1", "wf1", "r1", time.Minute),
+		runInfo("t1", "wf1", "r2", 2*time.Hour),
+		runInfo("t1", "wf2", "r3", 3*time.Minute),
+		runInfo("t1", "wf2", "r4", time.Minute),
+		runInfo("t1", "wf2", "r5", 2*time.Minute),
+		runInfo("t2", "wf1", "r6", 2*time.Hour),
+	}}
+	policies := map[string]*api.LogRetention{
+		"t1": {Seconds: 3600, MaxWorkflowRuns: 2},
+	}
+
+	c := NewCleaner(store, func(tenant string) *api.LogRetention { return policies[tenant] })
+	assert.Nil(t, c.Clean(now))
+	assert.ElementsMatch(t, []string{"t1/wf1/r2", "t1/wf2/r3"}, store.deleted)
+}
+
+func TestCleanContinuesOnError(t *testing.T) {
+	now := time.Unix(10000, 0)
+	store := &fakeStore{
+		runs: []RunInfo{
+			{Run: Run{Tenant: "t1", Project: "p", Workflow: "wf1", WorkflowRun: "r1"}, LastModified: now.Add(-2 * time.Hour)},
+			{Run: Run{Tenant: "t1", Project: "p", Workflow: "wf1", WorkflowRun: "r2"}, LastModified: now.Add(-3 * time.Hour)},
+		},
+		failed: "r1",
+	}
+
+	c := NewCleaner(store, func(tenant string) *api.LogRetention { return &api.LogRetention{Seconds: 3600} })
+	assert.Nil(t, c.Clean(now))
+	assert.Equal(t, []string{"t1/wf1/r2"}, store.deleted)
+}
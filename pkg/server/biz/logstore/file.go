@@ -0,0 +1,573 @@
+package logstore
+
+import (
+	"archive/tar"
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/caicloud/cyclone/pkg/common"
+)
+
+const (
+	// logsFolderName is the folder name for logs of a WorkflowRun.
+	logsFolderName = "logs"
+	// indexRecordSize is size of an index record, it's offset of the line in the log file and unix time of the
+	// line in nanoseconds, both in 8 bytes big endian.
+	indexRecordSize = 16
+	// exportTimeFormat is format of timestamps of lines in exported logs.
+	exportTimeFormat = "2006-01-02T15:04:05.000000000Z07:00"
+)
+
+// FileStore stores logs on the disk. Logs of a container are stored in file {home}/{tenant}/{project}/{workflow}/
+// {workflowrun}/logs/{stage}_{container} as they are received, so that they can be streamed by folder readers.
+// Each log file has a hidden index file '.{stage}_{container}.idx' beside it, which records offset and time of
+// each line.
+type FileStore struct {
+	home string
+}
+
+// NewFileStore creates a store rooted at the home folder.
+func NewFileStore(home string) *FileStore {
+	return &FileStore{home: home}
+}
+
+// Folder returns the folder holding logs of the WorkflowRun.
+func (s *FileStore) Folder(run Run) (string, error) {
+	if err := run.Validate(); err != nil {
+		return "", err
+	}
+	return filepath.Join(s.home, run.Tenant, run.Project, run.Workflow, run.WorkflowRun, logsFolderName), nil
+}
+
+func indexPath(logPath string) string {
+	return filepath.Join(filepath.Dir(logPath), "."+filepath.Base(logPath)+".idx")
+}
+
+// lineWriter appends content to a log file, and records an index record when a new line starts. Writers of the
+// same file are shared, so that lines from concurrent streams of the container are indexed correctly.
+type lineWriter struct {
+	lock  sync.Mutex
+	path  string
+	refs  int
+	log   *os.File
+	index *os.File
+	// offset is size of the log file.
+	offset int64
+	// lineStart indicates whether the next byte written starts a new line.
+	lineStart bool
+	// now is used to stamp lines, it's replaced in tests.
+	now func() time.Time
+}
+
+var writers = struct {
+	sync.Mutex
+	m map[string]*lineWriter
+}{m: make(map[string]*lineWriter)}
+
+// Writer implements Store.
+func (s *FileStore) Writer(run Run, stage, container string) (io.WriteCloser, error) {
+	folder, err := s.Folder(run)
+	if err != nil {
+		return nil, err
+	}
+	if stage == "" || container == "" || strings.ContainsAny(stage+container, "/_") {
+		return nil, fmt.Errorf("invalid stage %s or container %s", stage, container)
+	}
+	path := filepath.Join(folder, fmt.Sprintf("%s_%s", stage, container))
+
+	writers.Lock()
+	defer writers.Unlock()
+	if w, ok := writers.m[path]; ok {
+		w.refs++
+		return w, nil
+	}
+
+	w, err := openLineWriter(path)
+	if err != nil {
+		return nil, err
+	}
+	writers.m[path] = w
+	return w, nil
+}
+
+func openLineWriter(path string) (*lineWriter, error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return nil, err
+	}
+	logFile, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE|os.O_APPEND, 0666)
+	if err != nil {
+		return nil, err
+	}
+	indexFile, err := os.OpenFile(indexPath(path), os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0666)
+	if err != nil {
+		logFile.Close()
+		return nil, err
+	}
+
+	w := &lineWriter{path: path, refs: 1, log: logFile, index: indexFile, lineStart: true, now: time.Now}
+	info, err := logFile.Stat()
+	if err != nil {
+		w.closeFiles()
+		return nil, err
+	}
+	w.offset = info.Size()
+	// Continue the last line if it's not ended.
+	if w.offset > 0 {
+		last := make([]byte, 1)
+		if _, err := logFile.ReadAt(last, w.offset-1); err != nil {
+			w.closeFiles()
+			return nil, err
+		}
+		w.lineStart = last[0] == '\n'
+	}
+	return w, nil
+}
+
+// Write appends content to the log file, lines started in the content are stamped with current time.
+func (w *lineWriter) Write(p []byte) (int, error) {
+	w.lock.Lock()
+	defer w.lock.Unlock()
+
+	now := w.now().UnixNano()
+	var records []byte
+	for i, b := range p {
+		if w.lineStart {
+			record := make([]byte, indexRecordSize)
+			binary.BigEndian.PutUint64(record, uint64(w.offset+int64(i)))
+			binary.BigEndian.PutUint64(record[8:], uint64(now))
+			records = append(records, record...)
+		}
+		w.lineStart = b == '\n'
+	}
+
+	// Index is written first, so that lines in the log file are always indexed.
+	if _, err := w.index.Write(records); err != nil {
+		return 0, err
+	}
+	n, err := w.log.Write(p)
+	w.offset += int64(n)
+	return n, err
+}
+
+// Close closes the writer, files are closed when all writers of the same file are closed.
+func (w *lineWriter) Close() error {
+	writers.Lock()
+	defer writers.Unlock()
+
+	w.refs--
+	if w.refs > 0 {
+		return nil
+	}
+	delete(writers.m, w.path)
+	return w.closeFiles()
+}
+
+func (w *lineWriter) closeFiles() error {
+	err := w.log.Close()
+	if indexErr := w.index.Close(); err == nil {
+		err = indexErr
+	}
+	return err
+}
+
+// logFile is a log file of a container.
+type logFile struct {
+	stage     string
+	container string
+	path      string
+}
+
+// logFiles lists log files in the folder, the EOF file and hidden files are skipped.
+func logFiles(folder string) ([]logFile, error) {
+	infos, err := ioutil.ReadDir(folder)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, ErrNotFound
+		}
+		return nil, err
+	}
+
+	var files []logFile
+	for _, info := range infos {
+		name := info.Name()
+		parts := strings.SplitN(name, "_", 2)
+		if info.IsDir() || strings.HasPrefix(name, ".") || len(parts) != 2 || parts[1] == common.FolderEOFFile {
+			continue
+		}
+		files = append(files, logFile{stage: parts[0], container: parts[1], path: filepath.Join(folder, name)})
+	}
+	return files, nil
+}
+
+// lineRef refers to a line in a log file, content of the line is loaded only if it's in the result page.
+type lineRef struct {
+	file   int
+	number int
+	time   time.Time
+	offset int64
+	length int64
+}
+
+// readIndex reads index of the log file. For log files written before indexes are recorded, lines are indexed
+// by scanning the file, and they are stamped with modification time of the file.
+func readIndex(path string, size int64, modTime time.Time) ([]lineRef, error) {
+	data, err := ioutil.ReadFile(indexPath(path))
+	if err != nil && !os.IsNotExist(err) {
+		return nil, err
+	}
+
+	var refs []lineRef
+	if err == nil {
+		for i := 0; i+indexRecordSize <= len(data); i += indexRecordSize {
+			offset := int64(binary.BigEndian.Uint64(data[i:]))
+			// Lines indexed but not written to the log file yet are ignored.
+			if offset >= size {
+				break
+			}
+			refs = append(refs, lineRef{
+				number: len(refs) + 1,
+				offset: offset,
+				time:   time.Unix(0, int64(binary.BigEndian.Uint64(data[i+8:]))),
+			})
+		}
+	} else {
+		f, err := os.Open(path)
+		if err != nil {
+			return nil, err
+		}
+		defer f.Close()
+
+		reader := bufio.NewReader(io.LimitReader(f, size))
+		var offset int64
+		for {
+			line, err := reader.ReadBytes('\n')
+			if len(line) > 0 {
+				refs = append(refs, lineRef{number: len(refs) + 1, offset: offset, time: modTime})
+				offset += int64(len(line))
+			}
+			if err == io.EOF {
+				break
+			}
+			if err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	for i := range refs {
+		end := size
+		if i+1 < len(refs) {
+			end = refs[i+1].offset
+		}
+		refs[i].length = end - refs[i].offset
+	}
+	return refs, nil
+}
+
+// readLine reads content of the line without the line break.
+func readLine(f io.ReaderAt, ref lineRef) (string, error) {
+	buf := make([]byte, ref.length)
+	if _, err := f.ReadAt(buf, ref.offset); err != nil && err != io.EOF {
+		return "", err
+	}
+	return string(bytes.TrimRight(buf, "\r\n")), nil
+}
+
+// selectFile checks whether the log file is selected by the query.
+func selectFile(file logFile, query *Query) bool {
+	if len(query.Stages) > 0 {
+		found := false
+		for _, stage := range query.Stages {
+			if stage == file.stage {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+
+	if query.Container != "" {
+		return file.container == query.Container
+	}
+	for _, exclusion := range query.Exclusions {
+		if exclusion == file.container {
+			return false
+		}
+	}
+	return true
+}
+
+// selectLines selects lines of the log file in the time range and matching the pattern of the query.
+func selectLines(index int, file logFile, query *Query) ([]lineRef, error) {
+	f, err := os.Open(file.path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	info, err := f.Stat()
+	if err != nil {
+		return nil, err
+	}
+
+	refs, err := readIndex(file.path, info.Size(), info.ModTime())
+	if err != nil {
+		return nil, err
+	}
+
+	// Lines are indexed in time order, so lines in the time range are found by binary search.
+	begin, end := 0, len(refs)
+	if !query.Since.IsZero() {
+		begin = sort.Search(len(refs), func(i int) bool { return !refs[i].time.Before(query.Since) })
+	}
+	if !query.Until.IsZero() {
+		end = sort.Search(len(refs), func(i int) bool { return !refs[i].time.Before(query.Until) })
+	}
+	if begin >= end {
+		return nil, nil
+	}
+	refs = refs[begin:end]
+
+	var selected []lineRef
+	for _, ref := range refs {
+		ref.file = index
+		if query.Pattern != nil {
+			content, err := readLine(f, ref)
+			if err != nil {
+				return nil, err
+			}
+			if !query.Pattern.MatchString(content) {
+				continue
+			}
+		}
+		selected = append(selected, ref)
+	}
+	return selected, nil
+}
+
+// Query implements Store.
+func (s *FileStore) Query(run Run, query *Query) (*Result, error) {
+	folder, err := s.Folder(run)
+	if err != nil {
+		return nil, err
+	}
+	files, err := logFiles(folder)
+	if err != nil {
+		return nil, err
+	}
+
+	var refs []lineRef
+	for i, file := range files {
+		if !selectFile(file, query) {
+			continue
+		}
+		selected, err := selectLines(i, file, query)
+		if err != nil {
+			return nil, err
+		}
+		refs = append(refs, selected...)
+	}
+
+	sort.SliceStable(refs, func(i, j int) bool {
+		a, b := refs[i], refs[j]
+		if !a.time.Equal(b.time) {
+			return a.time.Before(b.time)
+		}
+		if files[a.file].stage != files[b.file].stage {
+			return files[a.file].stage < files[b.file].stage
+		}
+		if files[a.file].container != files[b.file].container {
+			return files[a.file].container < files[b.file].container
+		}
+		return a.number < b.number
+	})
+
+	result := &Result{Total: len(refs)}
+	if query.Offset >= len(refs) {
+		return result, nil
+	}
+	refs = refs[query.Offset:]
+	if query.Limit > 0 && query.Limit < len(refs) {
+		refs = refs[:query.Limit]
+	}
+
+	opened := make(map[int]*os.File)
+	defer func() {
+		for _, f := range opened {
+			f.Close()
+		}
+	}()
+	for _, ref := range refs {
+		f, ok := opened[ref.file]
+		if !ok {
+			if f, err = os.Open(files[ref.file].path); err != nil {
+				return nil, err
+			}
+			opened[ref.file] = f
+		}
+		content, err := readLine(f, ref)
+		if err != nil {
+			return nil, err
+		}
+		result.Lines = append(result.Lines, Line{
+			Stage:     files[ref.file].stage,
+			Container: files[ref.file].container,
+			Number:    ref.number,
+			Time:      ref.time,
+			Content:   content,
+		})
+	}
+	return result, nil
+}
+
+// Export implements Store. Logs of each container are written to file {workflowrun}/{stage}/{container}.log in
+// the tar, each line is prefixed with its time.
+func (s *FileStore) Export(run Run, w io.Writer) error {
+	folder, err := s.Folder(run)
+	if err != nil {
+		return err
+	}
+	files, err := logFiles(folder)
+	if err != nil {
+		return err
+	}
+
+	gw := gzip.NewWriter(w)
+	tw := tar.NewWriter(gw)
+	for _, file := range files {
+		if err := exportFile(tw, run.WorkflowRun, file); err != nil {
+			return err
+		}
+	}
+	if err := tw.Close(); err != nil {
+		return err
+	}
+	return gw.Close()
+}
+
+// exportFile writes logs of the container to the tar, lines are exported as they were received. Since timestamps
+// of lines are in fixed length, size of the file in tar is computed from the index, and content is streamed from
+// the log file.
+func exportFile(tw *tar.Writer, workflowrun string, file logFile) error {
+	f, err := os.Open(file.path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	info, err := f.Stat()
+	if err != nil {
+		return err
+	}
+	refs, err := readIndex(file.path, info.Size(), info.ModTime())
+	if err != nil {
+		return err
+	}
+
+	timeSize := int64(len(time.Time{}.Format(exportTimeFormat)))
+	var size int64
+	for _, ref := range refs {
+		size += timeSize + 1 + ref.length
+	}
+	// Only the last line may be not ended, a line break is appended to it.
+	ended := true
+	if n := len(refs); n > 0 {
+		last := make([]byte, 1)
+		if _, err := f.ReadAt(last, refs[n-1].offset+refs[n-1].length-1); err != nil {
+			return err
+		}
+		if ended = last[0] == '\n'; !ended {
+			size++
+		}
+	}
+
+	header := &tar.Header{
+		Name:    fmt.Sprintf("%s/%s/%s.log", workflowrun, file.stage, file.container),
+		Mode:    0644,
+		Size:    size,
+		ModTime: info.ModTime(),
+	}
+	if err := tw.WriteHeader(header); err != nil {
+		return err
+	}
+
+	buf := make([]byte, 32*1024)
+	for _, ref := range refs {
+		if _, err := io.WriteString(tw, ref.time.UTC().Format(exportTimeFormat)+" "); err != nil {
+			return err
+		}
+		if _, err := io.CopyBuffer(tw, io.NewSectionReader(f, ref.offset, ref.length), buf); err != nil {
+			return err
+		}
+	}
+	if !ended {
+		_, err = io.WriteString(tw, "\n")
+	}
+	return err
+}
+
+// Exists checks whether there are logs of the WorkflowRun in the store.
+func (s *FileStore) Exists(run Run) (bool, error) {
+	folder, err := s.Folder(run)
+	if err != nil {
+		return false, err
+	}
+	files, err := logFiles(folder)
+	if err == ErrNotFound {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	return len(files) > 0, nil
+}
+
+// Runs implements Store.
+func (s *FileStore) Runs() ([]RunInfo, error) {
+	folders, err := filepath.Glob(filepath.Join(s.home, "*", "*", "*", "*", logsFolderName))
+	if err != nil {
+		return nil, err
+	}
+
+	var runs []RunInfo
+	for _, folder := range folders {
+		rel, err := filepath.Rel(s.home, folder)
+		if err != nil {
+			continue
+		}
+		parts := strings.Split(filepath.ToSlash(rel), "/")
+		info := RunInfo{Run: Run{Tenant: parts[0], Project: parts[1], Workflow: parts[2], WorkflowRun: parts[3]}}
+
+		infos, err := ioutil.ReadDir(folder)
+		if err != nil {
+			continue
+		}
+		for _, i := range infos {
+			if i.ModTime().After(info.LastModified) {
+				info.LastModified = i.ModTime()
+			}
+		}
+		runs = append(runs, info)
+	}
+	return runs, nil
+}
+
+// Delete implements Store.
+func (s *FileStore) Delete(run Run) error {
+	folder, err := s.Folder(run)
+	if err != nil {
+		return err
+	}
+	return os.RemoveAll(folder)
+}
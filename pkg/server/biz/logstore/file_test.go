@@ -0,0 +1,216 @@
+package logstore
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+var testRun = Run{Tenant: "t", Project: "p", Workflow: "wf", WorkflowRun: "wfr"}
+
+// writeLogs writes chunks to logs of the container, the i-th chunk is stamped with base+i seconds.
+func writeLogs(t *testing.T, s *FileStore, stage, container string, base time.Time, chunks ...string) {
+	w, err := s.Writer(testRun, stage, container)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer w.Close()
+
+	for i, chunk := range chunks {
+		stamp := base.Add(time.Duration(i) * time.Second)
+		w.(*lineWriter).now = func() time.Time { return stamp }
+		if _, err := w.Write([]byte(chunk)); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func newTestStore(t *testing.T) (*FileStore, func()) {
+	home, err := ioutil.TempDir("", "logstore")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return NewFileStore(home), func() { os.RemoveAll(home) }
+}
+
+func TestFileStoreQuery(t *testing.T) {
+	s, cleanup := newTestStore(t)
+	defer cleanup()
+
+	base := time.Unix(1000, 0)
+	// A line split across chunks is stamped when it starts.
+	writeLogs(t, s, "build", "main", base, "compile a\ncomp", "ile b\n", "error: c\n")
+	writeLogs(t, s, "test", "main", base.Add(500*time.Millisecond), "run x\n", "error: y\n")
+	writeLogs(t, s, "build", "csc-co", base, "coordinator\n")
+	// Reopening the writer continues logs of the container.
+	writeLogs(t, s, "test", "main", base.Add(10*time.Second), "done\n")
+
+	folder, _ := s.Folder(testRun)
+	if err := ioutil.WriteFile(filepath.Join(folder, "test___eof__"), nil, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	contents := func(r *Result) []string {
+		var lines []string
+		for _, l := range r.Lines {
+			lines = append(lines, fmt.Sprintf("%s/%s#%d %s", l.Stage, l.Container, l.Number, l.Content))
+		}
+		return lines
+	}
+
+	cases := []struct {
+		name  string
+		query *Query
+		total int
+		lines []string
+	}{
+		{
+			name:  "all",
+			query: &Query{Exclusions: []string{"csc-co"}},
+			total: 6,
+			lines: []string{
+				"build/main#1 compile a", "build/main#2 compile b", "test/main#1 run x",
+				"test/main#2 error: y", "build/main#3 error: c", "test/main#3 done",
+			},
+		},
+		{
+			name:  "excluded container",
+			query: &Query{Container: "csc-co", Exclusions: []string{"csc-co"}},
+			total: 1,
+			lines: []string{"build/csc-co#1 coordinator"},
+		},
+		{
+			name:  "stages and pattern",
+			query: &Query{Stages: []string{"test"}, Pattern: regexp.MustCompile("^error")},
+			total: 1,
+			lines: []string{"test/main#2 error: y"},
+		},
+		{
+			name:  "time range",
+			query: &Query{Container: "main", Since: base.Add(time.Second), Until: base.Add(3 * time.Second)},
+			total: 2,
+			lines: []string{"test/main#2 error: y", "build/main#3 error: c"},
+		},
+		{
+			name:  "pagination",
+			query: &Query{Container: "main", Offset: 4, Limit: 1},
+			total: 6,
+			lines: []string{"build/main#3 error: c"},
+		},
+		{
+			name:  "offset out of range",
+			query: &Query{Container: "main", Offset: 10},
+			total: 6,
+		},
+	}
+	for _, c := range cases {
+		result, err := s.Query(testRun, c.query)
+		if err != nil {
+			t.Fatalf("%s: %v", c.name, err)
+		}
+		assert.Equal(t, c.total, result.Total, c.name)
+		assert.Equal(t, c.lines, contents(result), c.name)
+	}
+
+	result, _ := s.Query(testRun, &Query{Stages: []string{"build"}, Container: "main", Limit: 1})
+	assert.Equal(t, base, result.Lines[0].Time)
+
+	_, err := s.Query(Run{Tenant: "t", Project: "p", Workflow: "wf", WorkflowRun: "none"}, &Query{})
+	assert.Equal(t, ErrNotFound, err)
+}
+
+func TestFileStoreQueryWithoutIndex(t *testing.T) {
+	s, cleanup := newTestStore(t)
+	defer cleanup()
+
+	folder, _ := s.Folder(testRun)
+	if err := os.MkdirAll(folder, 0755); err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(folder, "build_main")
+	if err := ioutil.WriteFile(path, []byte("a\nb\nc"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	modTime := time.Unix(2000, 0)
+	if err := os.Chtimes(path, modTime, modTime); err != nil {
+		t.Fatal(err)
+	}
+
+	result, err := s.Query(testRun, &Query{Pattern: regexp.MustCompile("[bc]")})
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, []Line{
+		{Stage: "build", Container: "main", Number: 2, Time: modTime, Content: "b"},
+		{Stage: "build", Container: "main", Number: 3, Time: modTime, Content: "c"},
+	}, result.Lines)
+}
+
+func TestFileStoreExport(t *testing.T) {
+	s, cleanup := newTestStore(t)
+	defer cleanup()
+
+	base := time.Unix(1000, 0).UTC()
+	writeLogs(t, s, "build", "main", base, "a\n", "b\n")
+	// The last line is not ended.
+	writeLogs(t, s, "test", "main", base, "c\n", "d")
+
+	var buf bytes.Buffer
+	if err := s.Export(testRun, &buf); err != nil {
+		t.Fatal(err)
+	}
+
+	gr, err := gzip.NewReader(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tr := tar.NewReader(gr)
+	files := make(map[string]string)
+	for {
+		header, err := tr.Next()
+		if err != nil {
+			break
+		}
+		data, _ := ioutil.ReadAll(tr)
+		files[header.Name] = string(data)
+	}
+	assert.Equal(t, map[string]string{
+		"wfr/build/main.log": "1970-01-01T00:16:40.000000000Z a\n1970-01-01T00:16:41.000000000Z b\n",
+		"wfr/test/main.log":  "1970-01-01T00:16:40.000000000Z c\n1970-01-01T00:16:41.000000000Z d\n",
+	}, files)
+}
+
+func TestFileStoreRunsAndDelete(t *testing.T) {
+	s, cleanup := newTestStore(t)
+	defer cleanup()
+
+	exists, err := s.Exists(testRun)
+	assert.Nil(t, err)
+	assert.False(t, exists)
+
+	writeLogs(t, s, "build", "main", time.Now(), "a\n")
+	exists, err = s.Exists(testRun)
+	assert.Nil(t, err)
+	assert.True(t, exists)
+
+	runs, err := s.Runs()
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, 1, len(runs))
+	assert.Equal(t, testRun, runs[0].Run)
+	assert.False(t, runs[0].LastModified.IsZero())
+
+	assert.Nil(t, s.Delete(testRun))
+	runs, _ = s.Runs()
+	assert.Equal(t, 0, len(runs))
+}
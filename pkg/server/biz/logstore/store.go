@@ -0,0 +1,91 @@
+package logstore
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"regexp"
+	"time"
+)
+
+// ErrNotFound is returned when there are no logs of the WorkflowRun in the store.
+var ErrNotFound = errors.New("logs not found")
+
+// Run identifies a WorkflowRun whose logs are stored.
+type Run struct {
+	Tenant      string
+	Project     string
+	Workflow    string
+	WorkflowRun string
+}
+
+// Validate checks all fields of the run are set.
+func (r Run) Validate() error {
+	if r.Tenant == "" || r.Project == "" || r.Workflow == "" || r.WorkflowRun == "" {
+		return fmt.Errorf("tenant/project/workflow/workflowrun can not be empty")
+	}
+	return nil
+}
+
+// RunInfo describes logs of a WorkflowRun in the store.
+type RunInfo struct {
+	Run
+	// LastModified is the last time logs of the WorkflowRun were written.
+	LastModified time.Time
+}
+
+// Line is a line of container logs.
+type Line struct {
+	// Stage that the container belongs to.
+	Stage string
+	// Container that produced the line.
+	Container string
+	// Number is the 1-based line number in logs of the container.
+	Number int
+	// Time is when the line was received.
+	Time time.Time
+	// Content of the line without the line break.
+	Content string
+}
+
+// Query selects lines in logs of a WorkflowRun.
+type Query struct {
+	// Stages to search, all stages are searched if it's empty.
+	Stages []string
+	// Container to search, all containers except exclusions are searched if it's empty.
+	Container string
+	// Exclusions are containers not to search unless they are specified by Container.
+	Exclusions []string
+	// Since selects lines received at or after the time if it's not zero.
+	Since time.Time
+	// Until selects lines received before the time if it's not zero.
+	Until time.Time
+	// Pattern selects lines whose content matches it if it's not nil.
+	Pattern *regexp.Regexp
+	// Offset and Limit paginate the selected lines, all lines after the offset are returned if limit is 0.
+	Offset int
+	Limit  int
+}
+
+// Result is the result of a query.
+type Result struct {
+	// Total is the number of selected lines before pagination.
+	Total int
+	// Lines in the page, ordered by time, and then by stage, container and line number.
+	Lines []Line
+}
+
+// Store stores container logs of WorkflowRuns with timestamps of lines.
+type Store interface {
+	// Writer opens a writer to append logs of the container, content is split into lines and each line is
+	// stamped with the time it's written. Caller should close the writer.
+	Writer(run Run, stage, container string) (io.WriteCloser, error)
+	// Query selects lines in logs of the WorkflowRun.
+	Query(run Run, query *Query) (*Result, error)
+	// Export writes logs of all containers in the WorkflowRun to w as a gzipped tar.
+	Export(run Run, w io.Writer) error
+	// Runs lists WorkflowRuns with logs in the store.
+	Runs() ([]RunInfo, error)
+	// Delete deletes logs of the WorkflowRun.
+	Delete(run Run) error
+}
@@ -0,0 +1,70 @@
+package notification
+
+import (
+	"fmt"
+	"net/smtp"
+	"strings"
+
+	"github.com/caicloud/cyclone/pkg/apis/cyclone/v1alpha1"
+	"github.com/caicloud/cyclone/pkg/server/config"
+)
+
+const defaultEmailTemplate = `WorkflowRun: {{ .WorkflowRun.Name }}
+Workflow: {{ .Project }}/{{ .Workflow }}
+Status: {{ .Phase }}
+{{- with .WorkflowRun.Status.Overall.Message }}
+Message: {{ . }}
+{{- end }}
+{{- with .RecordURL }}
+Details: {{ . }}
+{{- end }}
+`
+
+// sendMail sends email, it's a variable so that it can be replaced in tests.
+var sendMail = smtp.SendMail
+
+func init() {
+	if err := RegisterSender(v1alpha1.NotificationTypeEmail, &emailSender{}); err != nil {
+		panic(err)
+	}
+}
+
+// emailSender sends notifications by email through the SMTP server configured in Cyclone server.
+type emailSender struct{}
+
+// Send implements Sender.
+func (s *emailSender) Send(msg *Message, addresses []string, tmpl string) error {
+	conf := config.Config.SMTP
+	if conf.Host == "" {
+		return fmt.Errorf("SMTP server is not configured")
+	}
+
+	if tmpl == "" {
+		tmpl = defaultEmailTemplate
+	}
+	body, err := render(tmpl, msg)
+	if err != nil {
+		return err
+	}
+
+	var auth smtp.Auth
+	if conf.Username != "" {
+		auth = smtp.PlainAuth("", conf.Username, conf.Password, conf.Host)
+	}
+
+	from := conf.From
+	if from == "" {
+		from = conf.Username
+	}
+
+	header := []string{
+		fmt.Sprintf("From: %s", from),
+		fmt.Sprintf("To: %s", strings.Join(addresses, ", ")),
+		fmt.Sprintf("Subject: [Cyclone] %s", summary(msg)),
+		"MIME-Version: 1.0",
+		"Content-Type: text/plain; charset=UTF-8",
+	}
+	content := strings.Join(header, "\r\n") + "\r\n\r\n" + body
+
+	return sendMail(fmt.Sprintf("%s:%d", conf.Host, conf.Port), auth, from, addresses, []byte(content))
+}
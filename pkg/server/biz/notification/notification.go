@@ -0,0 +1,100 @@
+package notification
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"text/template"
+
+	"github.com/caicloud/cyclone/pkg/apis/cyclone/v1alpha1"
+	"github.com/caicloud/cyclone/pkg/util/cerr"
+)
+
+// Message is the notification about a finished WorkflowRun, it's also the data to render notification templates.
+type Message struct {
+	// Tenant of the WorkflowRun
+	Tenant string
+	// Project of the WorkflowRun
+	Project string
+	// Workflow of the WorkflowRun
+	Workflow string
+	// WorkflowRun that finished
+	WorkflowRun *v1alpha1.WorkflowRun
+	// Phase is overall phase of the WorkflowRun
+	Phase v1alpha1.StatusPhase
+	// RecordURL is web URL of the WorkflowRun
+	RecordURL string
+}
+
+// Sender sends notifications to addresses of a receiver.
+type Sender interface {
+	// Send sends the message to the addresses, 'tmpl' is the template to render the notification content,
+	// default content is used if it's empty.
+	Send(msg *Message, addresses []string, tmpl string) error
+}
+
+// senders are registered notification senders, it's initialized here rather than in init() as senders are
+// registered in init() of other files.
+var senders = make(map[v1alpha1.NotificationType]Sender)
+
+// RegisterSender registers notification sender for a notification type.
+func RegisterSender(notificationType v1alpha1.NotificationType, sender Sender) error {
+	if _, ok := senders[notificationType]; ok {
+		return fmt.Errorf("notification sender %s already exists", notificationType)
+	}
+
+	senders[notificationType] = sender
+	return nil
+}
+
+// GetSender gets notification sender of the notification type.
+func GetSender(notificationType v1alpha1.NotificationType) (Sender, error) {
+	sender, ok := senders[notificationType]
+	if !ok {
+		return nil, cerr.ErrorUnsupported.Error("notification type", notificationType)
+	}
+
+	return sender, nil
+}
+
+// ShouldNotify checks whether notifications should be sent for a WorkflowRun finished with the phase according
+// to the policy. Empty policy is regarded as 'Always'.
+func ShouldNotify(policy v1alpha1.NotificationPolicy, phase v1alpha1.StatusPhase) bool {
+	switch policy {
+	case v1alpha1.NotificationPolicySuccess:
+		return phase == v1alpha1.StatusSucceeded
+	case v1alpha1.NotificationPolicyFailure:
+		return phase == v1alpha1.StatusFailed
+	default:
+		return true
+	}
+}
+
+// ReceiverName gets name of the receiver, '<type>-<index>' is used if the name is not set.
+func ReceiverName(receiver *v1alpha1.NotificationReceiver, index int) string {
+	if receiver.Name != "" {
+		return receiver.Name
+	}
+
+	return fmt.Sprintf("%s-%d", strings.ToLower(string(receiver.Type)), index)
+}
+
+// render renders the template with the message.
+func render(tmpl string, msg *Message) (string, error) {
+	t, err := template.New("notification").Parse(tmpl)
+	if err != nil {
+		return "", fmt.Errorf("parse template error: %v", err)
+	}
+
+	var buf bytes.Buffer
+	if err := t.Execute(&buf, msg); err != nil {
+		return "", fmt.Errorf("render template error: %v", err)
+	}
+
+	return buf.String(), nil
+}
+
+// summary is a one-line description of the message.
+func summary(msg *Message) string {
+	return fmt.Sprintf("WorkflowRun %s of workflow %s/%s %s", msg.WorkflowRun.Name, msg.Project, msg.Workflow, msg.Phase)
+}
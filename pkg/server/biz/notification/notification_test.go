@@ -0,0 +1,132 @@
+package notification
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"net/smtp"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/caicloud/cyclone/pkg/apis/cyclone/v1alpha1"
+	"github.com/caicloud/cyclone/pkg/server/config"
+)
+
+func newMessage(phase v1alpha1.StatusPhase) *Message {
+	return &Message{
+		Tenant:   "devops",
+		Project:  "cyclone",
+		Workflow: "ci",
+		WorkflowRun: &v1alpha1.WorkflowRun{
+			ObjectMeta: metav1.ObjectMeta{Name: "ci-run"},
+			Status: v1alpha1.WorkflowRunStatus{
+				Overall: v1alpha1.Status{Phase: phase},
+			},
+		},
+		Phase:     phase,
+		RecordURL: "http://cyclone.dev/runs/ci-run",
+	}
+}
+
+func TestShouldNotify(t *testing.T) {
+	assert.True(t, ShouldNotify("", v1alpha1.StatusFailed))
+	assert.True(t, ShouldNotify(v1alpha1.NotificationPolicyAlways, v1alpha1.StatusCancelled))
+	assert.True(t, ShouldNotify(v1alpha1.NotificationPolicySuccess, v1alpha1.StatusSucceeded))
+	assert.False(t, ShouldNotify(v1alpha1.NotificationPolicySuccess, v1alpha1.StatusFailed))
+	assert.True(t, ShouldNotify(v1alpha1.NotificationPolicyFailure, v1alpha1.StatusFailed))
+	assert.False(t, ShouldNotify(v1alpha1.NotificationPolicyFailure, v1alpha1.StatusSucceeded))
+}
+
+func TestReceiverName(t *testing.T) {
+	assert.Equal(t, "slack-1", ReceiverName(&v1alpha1.NotificationReceiver{Type: v1alpha1.NotificationTypeSlack}, 1))
+	assert.Equal(t, "team", ReceiverName(&v1alpha1.NotificationReceiver{Name: "team", Type: v1alpha1.NotificationTypeSlack}, 1))
+}
+
+func TestGetSender(t *testing.T) {
+	for _, tp := range []v1alpha1.NotificationType{v1alpha1.NotificationTypeEmail, v1alpha1.NotificationTypeSlack, v1alpha1.NotificationTypeWebhook} {
+		_, err := GetSender(tp)
+		assert.Nil(t, err)
+	}
+	_, err := GetSender("Unknown")
+	assert.NotNil(t, err)
+}
+
+func TestWebhookSender(t *testing.T) {
+	var bodies []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		b, _ := ioutil.ReadAll(r.Body)
+		bodies = append(bodies, string(b))
+		if r.URL.Path == "/fail" {
+			w.WriteHeader(http.StatusInternalServerError)
+		}
+	}))
+	defer server.Close()
+
+	sender, _ := GetSender(v1alpha1.NotificationTypeWebhook)
+	msg := newMessage(v1alpha1.StatusSucceeded)
+	assert.Nil(t, sender.Send(msg, []string{server.URL}, `{"run": "{{ .WorkflowRun.Name }}", "phase": "{{ .Phase }}"}`))
+	assert.Equal(t, `{"run": "ci-run", "phase": "Succeeded"}`, bodies[0])
+
+	assert.Nil(t, sender.Send(msg, []string{server.URL}, ""))
+	wfr := &v1alpha1.WorkflowRun{}
+	assert.Nil(t, json.Unmarshal([]byte(bodies[1]), wfr))
+	assert.Equal(t, "ci-run", wfr.Name)
+
+	err := sender.Send(msg, []string{server.URL + "/fail", server.URL}, "")
+	assert.NotNil(t, err)
+	assert.Equal(t, 4, len(bodies))
+}
+
+func TestSlackMessage(t *testing.T) {
+	msg := newMessage(v1alpha1.StatusFailed)
+	msg.WorkflowRun.Status.Overall.Message = "stage build failed"
+	m, err := newSlackMessage(msg, "")
+	assert.Nil(t, err)
+	assert.Equal(t, "WorkflowRun ci-run of workflow cyclone/ci Failed", m.Text)
+	assert.Equal(t, "danger", m.Attachments[0].Color)
+	assert.Equal(t, msg.RecordURL, m.Attachments[0].Link)
+	assert.Equal(t, 3, len(m.Attachments[0].Fields))
+
+	m, err = newSlackMessage(msg, "{{ .Workflow }} is {{ .Phase }}")
+	assert.Nil(t, err)
+	assert.Equal(t, "ci is Failed", m.Text)
+
+	_, err = newSlackMessage(msg, "{{ .Invalid")
+	assert.NotNil(t, err)
+}
+
+func TestEmailSender(t *testing.T) {
+	origin := sendMail
+	defer func() {
+		sendMail = origin
+		config.Config.SMTP = config.SMTPConfig{}
+	}()
+
+	var addr, from string
+	var to []string
+	var content []byte
+	sendMail = func(a string, auth smtp.Auth, f string, t []string, msg []byte) error {
+		addr, from, to, content = a, f, t, msg
+		return nil
+	}
+
+	sender, _ := GetSender(v1alpha1.NotificationTypeEmail)
+	msg := newMessage(v1alpha1.StatusSucceeded)
+	assert.NotNil(t, sender.Send(msg, []string{"dev@cyclone.dev"}, ""))
+
+	config.Config.SMTP = config.SMTPConfig{
+		Host:     "smtp.cyclone.dev",
+		Port:     587,
+		Username: "cyclone@cyclone.dev",
+	}
+	assert.Nil(t, sender.Send(msg, []string{"dev@cyclone.dev"}, ""))
+	assert.Equal(t, "smtp.cyclone.dev:587", addr)
+	assert.Equal(t, "cyclone@cyclone.dev", from)
+	assert.Equal(t, []string{"dev@cyclone.dev"}, to)
+	assert.True(t, strings.Contains(string(content), "Subject: [Cyclone] WorkflowRun ci-run of workflow cyclone/ci Succeeded"))
+	assert.True(t, strings.Contains(string(content), "Details: http://cyclone.dev/runs/ci-run"))
+}
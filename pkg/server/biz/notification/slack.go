@@ -0,0 +1,90 @@
+package notification
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/caicloud/cyclone/pkg/apis/cyclone/v1alpha1"
+)
+
+func init() {
+	if err := RegisterSender(v1alpha1.NotificationTypeSlack, &slackSender{}); err != nil {
+		panic(err)
+	}
+}
+
+// slackSender sends notifications to Slack incoming webhooks.
+type slackSender struct{}
+
+// slackMessage is the message posted to Slack incoming webhook.
+type slackMessage struct {
+	Text        string            `json:"text"`
+	Attachments []slackAttachment `json:"attachments,omitempty"`
+}
+
+type slackAttachment struct {
+	Color  string       `json:"color"`
+	Title  string       `json:"title"`
+	Link   string       `json:"title_link,omitempty"`
+	Fields []slackField `json:"fields"`
+}
+
+type slackField struct {
+	Title string `json:"title"`
+	Value string `json:"value"`
+	Short bool   `json:"short"`
+}
+
+// Send implements Sender. The template customizes text of the Slack message.
+func (s *slackSender) Send(msg *Message, addresses []string, tmpl string) error {
+	message, err := newSlackMessage(msg, tmpl)
+	if err != nil {
+		return err
+	}
+
+	payload, err := json.Marshal(message)
+	if err != nil {
+		return err
+	}
+
+	return postAll(addresses, payload)
+}
+
+func newSlackMessage(msg *Message, tmpl string) (*slackMessage, error) {
+	text := summary(msg)
+	if tmpl != "" {
+		rendered, err := render(tmpl, msg)
+		if err != nil {
+			return nil, err
+		}
+		text = rendered
+	}
+
+	color := "warning"
+	switch msg.Phase {
+	case v1alpha1.StatusSucceeded:
+		color = "good"
+	case v1alpha1.StatusFailed:
+		color = "danger"
+	}
+
+	fields := []slackField{
+		{Title: "Workflow", Value: fmt.Sprintf("%s/%s", msg.Project, msg.Workflow), Short: true},
+		{Title: "Status", Value: string(msg.Phase), Short: true},
+	}
+	if message := msg.WorkflowRun.Status.Overall.Message; message != "" {
+		fields = append(fields, slackField{Title: "Message", Value: message})
+	}
+
+	return &slackMessage{
+		Text: text,
+		Attachments: []slackAttachment{
+			{
+				Color:  color,
+				Title:  msg.WorkflowRun.Name,
+				Link:   msg.RecordURL,
+				Fields: fields,
+			},
+		},
+	}, nil
+}
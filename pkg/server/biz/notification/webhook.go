@@ -0,0 +1,95 @@
+package notification
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+
+	"github.com/caicloud/cyclone/pkg/apis/cyclone/v1alpha1"
+	utilhttp "github.com/caicloud/cyclone/pkg/util/http"
+)
+
+// requestTimeout is timeout of notification requests.
+const requestTimeout = 30 * time.Second
+
+func init() {
+	if err := RegisterSender(v1alpha1.NotificationTypeWebhook, &webhookSender{}); err != nil {
+		panic(err)
+	}
+}
+
+// webhookSender sends notifications by POST requests to webhook URLs.
+type webhookSender struct{}
+
+// Send implements Sender. The WorkflowRun is sent as payload if no template given.
+func (s *webhookSender) Send(msg *Message, addresses []string, tmpl string) error {
+	var payload []byte
+	var err error
+	if tmpl == "" {
+		payload, err = json.Marshal(msg.WorkflowRun)
+	} else {
+		var rendered string
+		rendered, err = render(tmpl, msg)
+		payload = []byte(rendered)
+	}
+	if err != nil {
+		return err
+	}
+
+	return postAll(addresses, payload)
+}
+
+// postAll posts the payload to all URLs, errors of all URLs are aggregated.
+func postAll(urls []string, payload []byte) error {
+	var errs []string
+	for i, address := range urls {
+		if err := post(i, address, payload); err != nil {
+			errs = append(errs, err.Error())
+		}
+	}
+
+	if len(errs) > 0 {
+		return fmt.Errorf("%s", strings.Join(errs, "; "))
+	}
+
+	return nil
+}
+
+// post posts the JSON payload to the URL, 'index' is index of the URL in receiver addresses.
+func post(index int, address string, payload []byte) error {
+	req, err := http.NewRequest(http.MethodPost, address, bytes.NewReader(payload))
+	if err != nil {
+		return fmt.Errorf("invalid URL in address %d", index)
+	}
+	req.Header.Set(utilhttp.HeaderContentType, utilhttp.HeaderContentTypeJSON)
+
+	client := &http.Client{Timeout: requestTimeout}
+	resp, err := client.Do(req)
+	if err != nil {
+		// The URL may contain credentials, so only the host is exposed.
+		if e, ok := err.(*url.Error); ok {
+			err = e.Err
+		}
+		return fmt.Errorf("POST %s error: %v", req.URL.Host, err)
+	}
+
+	defer func() {
+		if err := resp.Body.Close(); err != nil {
+			log.Errorf("Fail to close response body as: %v", err)
+		}
+	}()
+
+	if resp.StatusCode/100 != 2 {
+		body, _ := ioutil.ReadAll(resp.Body)
+		return fmt.Errorf("POST %s status code: %d, body: %s", req.URL.Host, resp.StatusCode, body)
+	}
+
+	return nil
+}
@@ -0,0 +1,255 @@
+package registry
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strings"
+)
+
+// Docker Registry HTTP API V2 docs: https://docs.docker.com/registry/spec/api/ . Token authentication docs:
+// https://docs.docker.com/registry/spec/auth/token/ .
+
+const (
+	// dockerHubRegistry is the API server of Docker Hub, Docker Hub images are usually referred as 'docker.io'.
+	dockerHubRegistry = "registry-1.docker.io"
+
+	// tagsPageSize is the page size to list tags.
+	tagsPageSize = 100
+
+	// maxResponseSize is the max size of response bodies read.
+	maxResponseSize = 10 << 20
+)
+
+// manifestMediaTypes are media types of manifests accepted when getting digests of tags, manifest lists are
+// preferred so that digests of multi-arch images are the same as those pushed.
+var manifestMediaTypes = []string{
+	"application/vnd.docker.distribution.manifest.list.v2+json",
+	"application/vnd.oci.image.index.v1+json",
+	"application/vnd.docker.distribution.manifest.v2+json",
+	"application/vnd.oci.image.manifest.v1+json",
+}
+
+// challengeParamRegexp matches parameters of 'WWW-Authenticate' headers, for example, 'realm="https://auth.docker.io/token"'.
+var challengeParamRegexp = regexp.MustCompile(`(\w+)="([^"]*)"`)
+
+// linkNextRegexp matches the next page in 'Link' headers, for example, '</v2/alpine/tags/list?n=100&last=3.6>; rel="next"'.
+var linkNextRegexp = regexp.MustCompile(`<([^>]+)>;\s*rel="next"`)
+
+// Client manages communication with the Docker Registry HTTP API V2, which is also implemented by Harbor and most
+// other registries.
+type Client struct {
+	// HTTP client used to communicate with the API.
+	client *http.Client
+
+	// Base URL for API requests, for example, 'https://harbor.example.com/'.
+	baseURL *url.URL
+
+	// Username and password used for basic authentication, and to request bearer tokens.
+	username string
+	password string
+
+	// Authorization header got by the last authentication challenge.
+	authorization string
+}
+
+// NewClient returns a registry client for the server, the server can be a host like 'docker.io', or an URL with
+// scheme, https is used if scheme is not specified.
+func NewClient(client *http.Client, server, username, password string) (*Client, error) {
+	host := Host(server)
+	if host == "" {
+		return nil, fmt.Errorf("registry server is empty")
+	}
+	if host == "docker.io" || host == "index.docker.io" {
+		host = dockerHubRegistry
+	}
+	scheme := "https"
+	if strings.HasPrefix(server, "http://") {
+		scheme = "http"
+	}
+	if client == nil {
+		client = http.DefaultClient
+	}
+
+	return &Client{
+		client:   client,
+		baseURL:  &url.URL{Scheme: scheme, Host: host, Path: "/"},
+		username: username,
+		password: password,
+	}, nil
+}
+
+// Host gets host of the registry server, scheme and path are trimmed.
+func Host(server string) string {
+	host := strings.TrimPrefix(strings.TrimPrefix(server, "https://"), "http://")
+	return strings.SplitN(host, "/", 2)[0]
+}
+
+// NormalizeRepository adds the 'library/' prefix to official images of Docker Hub, for example, 'alpine' is
+// normalized to 'library/alpine'.
+func NormalizeRepository(server, repository string) string {
+	switch Host(server) {
+	case "docker.io", "index.docker.io", dockerHubRegistry:
+		if !strings.Contains(repository, "/") {
+			return "library/" + repository
+		}
+	}
+	return repository
+}
+
+// ListTags lists all tags of the repository.
+func (c *Client) ListTags(repository string) ([]string, error) {
+	var tags []string
+	next := fmt.Sprintf("v2/%s/tags/list?n=%d", repository, tagsPageSize)
+	for next != "" {
+		resp, err := c.do(http.MethodGet, next, nil)
+		if err != nil {
+			return nil, err
+		}
+
+		var result struct {
+			Tags []string `json:"tags"`
+		}
+		err = json.NewDecoder(io.LimitReader(resp.Body, maxResponseSize)).Decode(&result)
+		resp.Body.Close()
+		if err != nil {
+			return nil, fmt.Errorf("decode tags of %s error: %v", repository, err)
+		}
+		tags = append(tags, result.Tags...)
+
+		next = ""
+		if match := linkNextRegexp.FindStringSubmatch(resp.Header.Get("Link")); match != nil {
+			next = match[1]
+		}
+	}
+
+	return tags, nil
+}
+
+// GetDigest gets digest of the manifest that the tag refers to.
+func (c *Client) GetDigest(repository, tag string) (string, error) {
+	header := http.Header{}
+	header.Set("Accept", strings.Join(manifestMediaTypes, ", "))
+	resp, err := c.do(http.MethodHead, fmt.Sprintf("v2/%s/manifests/%s", repository, tag), header)
+	if err != nil {
+		return "", err
+	}
+	resp.Body.Close()
+
+	digest := resp.Header.Get("Docker-Content-Digest")
+	if digest == "" {
+		return "", fmt.Errorf("digest of %s:%s not found", repository, tag)
+	}
+	return digest, nil
+}
+
+// do sends an API request, urlStr is relative to the base URL of the client. If the registry responds an
+// authentication challenge, the request is retried with basic authentication or a bearer token requested from the
+// authorization server.
+func (c *Client) do(method, urlStr string, header http.Header) (*http.Response, error) {
+	u, err := c.baseURL.Parse(urlStr)
+	if err != nil {
+		return nil, err
+	}
+
+	send := func() (*http.Response, error) {
+		req, err := http.NewRequest(method, u.String(), nil)
+		if err != nil {
+			return nil, err
+		}
+		for k, v := range header {
+			req.Header[k] = v
+		}
+		if c.authorization != "" {
+			req.Header.Set("Authorization", c.authorization)
+		}
+		return c.client.Do(req)
+	}
+
+	resp, err := send()
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode == http.StatusUnauthorized {
+		challenge := resp.Header.Get("WWW-Authenticate")
+		resp.Body.Close()
+		if c.authorization, err = c.authenticate(challenge); err != nil {
+			return nil, err
+		}
+		if resp, err = send(); err != nil {
+			return nil, err
+		}
+	}
+
+	if resp.StatusCode < http.StatusOK || resp.StatusCode >= http.StatusMultipleChoices {
+		body, _ := ioutil.ReadAll(io.LimitReader(resp.Body, 1024))
+		resp.Body.Close()
+		return nil, fmt.Errorf("%s %s: %d %s", method, u.Path, resp.StatusCode, strings.TrimSpace(string(body)))
+	}
+	return resp, nil
+}
+
+// authenticate gets the Authorization header for the authentication challenge.
+func (c *Client) authenticate(challenge string) (string, error) {
+	scheme := strings.ToLower(strings.SplitN(challenge, " ", 2)[0])
+	switch scheme {
+	case "basic":
+		req, _ := http.NewRequest(http.MethodGet, "/", nil)
+		req.SetBasicAuth(c.username, c.password)
+		return req.Header.Get("Authorization"), nil
+	case "bearer":
+	default:
+		return "", fmt.Errorf("unsupported authentication challenge '%s'", challenge)
+	}
+
+	params := make(map[string]string)
+	for _, match := range challengeParamRegexp.FindAllStringSubmatch(challenge, -1) {
+		params[strings.ToLower(match[1])] = match[2]
+	}
+	realm, err := url.Parse(params["realm"])
+	if err != nil || params["realm"] == "" {
+		return "", fmt.Errorf("invalid realm in authentication challenge '%s'", challenge)
+	}
+	query := realm.Query()
+	for _, key := range []string{"service", "scope"} {
+		if params[key] != "" {
+			query.Set(key, params[key])
+		}
+	}
+	realm.RawQuery = query.Encode()
+
+	req, err := http.NewRequest(http.MethodGet, realm.String(), nil)
+	if err != nil {
+		return "", err
+	}
+	if c.username != "" {
+		req.SetBasicAuth(c.username, c.password)
+	}
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("request token from %s: %d", realm.Host, resp.StatusCode)
+	}
+
+	var token struct {
+		Token       string `json:"token"`
+		AccessToken string `json:"access_token"`
+	}
+	if err := json.NewDecoder(io.LimitReader(resp.Body, maxResponseSize)).Decode(&token); err != nil {
+		return "", fmt.Errorf("decode token error: %v", err)
+	}
+	if token.Token == "" {
+		token.Token = token.AccessToken
+	}
+	if token.Token == "" {
+		return "", fmt.Errorf("empty token from %s", realm.Host)
+	}
+	return "Bearer " + token.Token, nil
+}
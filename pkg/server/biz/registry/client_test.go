@@ -0,0 +1,117 @@
+package registry
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	c_v1alpha1 "github.com/caicloud/cyclone/pkg/apis/cyclone/v1alpha1"
+)
+
+// newTokenAuthServer creates a registry server that requires bearer tokens, which are issued by its '/token'
+// endpoint to user 'admin'.
+func newTokenAuthServer(t *testing.T) *httptest.Server {
+	var server *httptest.Server
+	server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/token" {
+			user, password, ok := r.BasicAuth()
+			if !ok || user != "admin" || password != "secret" {
+				w.WriteHeader(http.StatusUnauthorized)
+				return
+			}
+			assert.Equal(t, "repository:library/alpine:pull", r.URL.Query().Get("scope"))
+			fmt.Fprint(w, `{"token": "abc"}`)
+			return
+		}
+
+		if r.Header.Get("Authorization") != "Bearer abc" {
+			w.Header().Set("WWW-Authenticate", fmt.Sprintf(`Bearer realm="%s/token",service="registry",scope="repository:library/alpine:pull"`, server.URL))
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+
+		switch r.URL.RequestURI() {
+		case "/v2/library/alpine/tags/list?n=100":
+			w.Header().Set("Link", `</v2/library/alpine/tags/list?n=100&last=3.10>; rel="next"`)
+			fmt.Fprint(w, `{"name": "library/alpine", "tags": ["3.9", "3.10"]}`)
+		case "/v2/library/alpine/tags/list?n=100&last=3.10":
+			fmt.Fprint(w, `{"name": "library/alpine", "tags": ["3.11"]}`)
+		case "/v2/library/alpine/manifests/3.11":
+			assert.Equal(t, http.MethodHead, r.Method)
+			assert.Contains(t, r.Header.Get("Accept"), "application/vnd.docker.distribution.manifest.v2+json")
+			w.Header().Set("Docker-Content-Digest", "sha256:e4355b")
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	return server
+}
+
+func TestClient(t *testing.T) {
+	server := newTokenAuthServer(t)
+	defer server.Close()
+
+	c, err := NewClient(nil, server.URL, "admin", "secret")
+	assert.Nil(t, err)
+	tags, err := c.ListTags("library/alpine")
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"3.9", "3.10", "3.11"}, tags)
+
+	digest, err := c.GetDigest("library/alpine", "3.11")
+	assert.Nil(t, err)
+	assert.Equal(t, "sha256:e4355b", digest)
+
+	_, err = c.GetDigest("library/alpine", "3.12")
+	assert.NotNil(t, err)
+
+	c, err = NewClient(nil, server.URL, "admin", "wrong")
+	assert.Nil(t, err)
+	_, err = c.ListTags("library/alpine")
+	assert.NotNil(t, err)
+}
+
+func TestNewClient(t *testing.T) {
+	cases := map[string]string{
+		"docker.io":                      "https://registry-1.docker.io/",
+		"http://registry.example.com":    "http://registry.example.com/",
+		"https://harbor.example.com/":    "https://harbor.example.com/",
+		"harbor.example.com:8443/devops": "https://harbor.example.com:8443/",
+	}
+	for server, expected := range cases {
+		c, err := NewClient(nil, server, "", "")
+		assert.Nil(t, err, server)
+		assert.Equal(t, expected, c.baseURL.String(), server)
+	}
+
+	_, err := NewClient(nil, "", "", "")
+	assert.NotNil(t, err)
+}
+
+func TestMatchTrigger(t *testing.T) {
+	trigger := &c_v1alpha1.ImageTrigger{
+		Repository: "alpine",
+		Tags:       &c_v1alpha1.RefFilter{Include: []string{"3.*"}, Exclude: []string{"*-rc*"}},
+	}
+
+	cases := []struct {
+		server     string
+		repository string
+		tag        string
+		expected   bool
+	}{
+		{"docker.io", "library/alpine", "3.11", true},
+		{"docker.io", "library/alpine", "3.12-rc1", false},
+		{"docker.io", "library/alpine", "edge", false},
+		{"docker.io", "library/busybox", "3.11", false},
+		{"harbor.example.com", "library/alpine", "3.11", false},
+		{"harbor.example.com", "alpine", "3.11", true},
+	}
+	for _, c := range cases {
+		matched, err := MatchTrigger(trigger, c.server, c.repository, c.tag)
+		assert.Nil(t, err)
+		assert.Equal(t, c.expected, matched, "%s/%s:%s", c.server, c.repository, c.tag)
+	}
+}
@@ -0,0 +1,108 @@
+package registry
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+// Docker Registry notification docs: https://docs.docker.com/registry/notifications/ . Harbor webhook docs:
+// https://goharbor.io/docs/main/working-with-projects/project-configuration/configure-webhooks/ .
+
+const (
+	// pushAction is the action of push events sent by Docker Registry.
+	pushAction = "push"
+
+	// harborPushArtifact and harborPushImage are types of push events sent by Harbor 2.x and Harbor 1.x.
+	harborPushArtifact = "PUSH_ARTIFACT"
+	harborPushImage    = "pushImage"
+)
+
+// Event is an image push event.
+type Event struct {
+	// Registry is the host of the registry, for example, 'harbor.example.com'.
+	Registry string `json:"registry"`
+	// Repository is the repository of the image without registry, for example, 'library/alpine'.
+	Repository string `json:"repository"`
+	// Tag is the tag pushed.
+	Tag string `json:"tag"`
+	// Digest is digest of the manifest pushed, for example, 'sha256:...'.
+	Digest string `json:"digest"`
+}
+
+// registryNotification is the envelope of Docker Registry notifications.
+type registryNotification struct {
+	Events []struct {
+		Action string `json:"action"`
+		Target struct {
+			MediaType  string `json:"mediaType"`
+			Digest     string `json:"digest"`
+			Repository string `json:"repository"`
+			Tag        string `json:"tag"`
+		} `json:"target"`
+		Request struct {
+			Host string `json:"host"`
+		} `json:"request"`
+	} `json:"events"`
+}
+
+// harborNotification is the payload of Harbor webhooks.
+type harborNotification struct {
+	Type      string `json:"type"`
+	EventData struct {
+		Resources []struct {
+			Digest      string `json:"digest"`
+			Tag         string `json:"tag"`
+			ResourceURL string `json:"resource_url"`
+		} `json:"resources"`
+		Repository struct {
+			RepoFullName string `json:"repo_full_name"`
+		} `json:"repository"`
+	} `json:"event_data"`
+}
+
+// ParseEvents parses image push events from Docker Registry notifications or Harbor webhooks. Events other than
+// pushes of tagged manifests are ignored.
+func ParseEvents(payload []byte) ([]Event, error) {
+	var registry registryNotification
+	if err := json.Unmarshal(payload, &registry); err != nil {
+		return nil, fmt.Errorf("unmarshal payload error: %v", err)
+	}
+	if len(registry.Events) > 0 {
+		var events []Event
+		for _, e := range registry.Events {
+			// Blobs are also notified when pushing images, they have no tags.
+			if e.Action != pushAction || e.Target.Tag == "" {
+				continue
+			}
+			events = append(events, Event{
+				Registry:   e.Request.Host,
+				Repository: e.Target.Repository,
+				Tag:        e.Target.Tag,
+				Digest:     e.Target.Digest,
+			})
+		}
+		return events, nil
+	}
+
+	var harbor harborNotification
+	if err := json.Unmarshal(payload, &harbor); err != nil {
+		return nil, fmt.Errorf("unmarshal payload error: %v", err)
+	}
+	if harbor.Type != harborPushArtifact && harbor.Type != harborPushImage {
+		return nil, nil
+	}
+	var events []Event
+	for _, r := range harbor.EventData.Resources {
+		if r.Tag == "" {
+			continue
+		}
+		events = append(events, Event{
+			Registry:   strings.SplitN(r.ResourceURL, "/", 2)[0],
+			Repository: harbor.EventData.Repository.RepoFullName,
+			Tag:        r.Tag,
+			Digest:     r.Digest,
+		})
+	}
+	return events, nil
+}
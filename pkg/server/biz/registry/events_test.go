@@ -0,0 +1,37 @@
+package registry
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseEvents(t *testing.T) {
+	pushed := Event{
+		Repository: "library/alpine",
+		Tag:        "3.11",
+		Digest:     "sha256:e4355b66995c96b4b468159fc5c7e3540fcef961189ca13fee877798649f531a",
+	}
+	fromRegistry, fromHarbor := pushed, pushed
+	fromRegistry.Registry = "registry.example.com"
+	fromHarbor.Registry = "harbor.example.com"
+
+	cases := map[string][]Event{
+		"registry_push.json": {fromRegistry},
+		"harbor_push.json":   {fromHarbor},
+		"harbor_pull.json":   nil,
+	}
+
+	for file, expected := range cases {
+		payload, err := ioutil.ReadFile(filepath.Join("testdata", file))
+		assert.Nil(t, err, file)
+		events, err := ParseEvents(payload)
+		assert.Nil(t, err, file)
+		assert.Equal(t, expected, events, file)
+	}
+
+	_, err := ParseEvents([]byte("not json"))
+	assert.NotNil(t, err)
+}
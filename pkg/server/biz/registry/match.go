@@ -0,0 +1,14 @@
+package registry
+
+import (
+	c_v1alpha1 "github.com/caicloud/cyclone/pkg/apis/cyclone/v1alpha1"
+	"github.com/caicloud/cyclone/pkg/server/biz/scm"
+)
+
+// MatchTrigger checks whether the tag of the repository in the registry server fires the image trigger.
+func MatchTrigger(trigger *c_v1alpha1.ImageTrigger, server, repository, tag string) (bool, error) {
+	if NormalizeRepository(server, trigger.Repository) != NormalizeRepository(server, repository) {
+		return false, nil
+	}
+	return scm.MatchRefFilter(trigger.Tags, scm.TagRef(tag))
+}
@@ -72,3 +72,30 @@ func (server *PullRequestsService) GetPullRequest(ctx context.Context, project s
 	resp, err := server.v1Client.Do(req, &pr)
 	return &pr, resp, err
 }
+
+// ListChanges lists files changed by a specific pull request.
+func (server *PullRequestsService) ListChanges(ctx context.Context, project string, repo string, number int, opt *ListOpts) (*FileChanges, *http.Response, error) {
+	u := fmt.Sprintf("rest/api/1.0/projects/%s/repos/%s/pull-requests/%d/changes", project, repo, number)
+	req, err := server.v1Client.NewRequest(http.MethodGet, u, nil, opt)
+	if err != nil {
+		return nil, nil, err
+	}
+	var changes FileChanges
+	resp, err := server.v1Client.Do(req, &changes)
+	return &changes, resp, err
+}
+
+// CommentReq represents the request to comment on a pull request.
+type CommentReq struct {
+	Text string `json:"text"`
+}
+
+// CreateComment comments on a specific pull request.
+func (server *PullRequestsService) CreateComment(ctx context.Context, project string, repo string, number int, input *CommentReq) (*http.Response, error) {
+	u := fmt.Sprintf("rest/api/1.0/projects/%s/repos/%s/pull-requests/%d/comments", project, repo, number)
+	req, err := server.v1Client.NewRequest(http.MethodPost, u, input, nil)
+	if err != nil {
+		return nil, err
+	}
+	return server.v1Client.Do(req, nil)
+}
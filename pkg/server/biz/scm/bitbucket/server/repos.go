@@ -6,8 +6,6 @@ import (
 	"net/http"
 
 	"github.com/caicloud/nirvana/log"
-
-	"github.com/caicloud/cyclone/pkg/server/biz/scm"
 )
 
 // RepositoriesService handles communication with the repository related.
@@ -84,7 +82,7 @@ type Tags struct {
 // PullRequests is a set of PullRequest.
 type PullRequests struct {
 	Pagination
-	Values []scm.PullRequest `json:"values"`
+	Values []PullRequest `json:"values"`
 }
 
 // Files is a set of files' name in a repo.
@@ -93,6 +91,34 @@ type Files struct {
 	Values []string `json:"values"`
 }
 
+// FilePath represents path of a file.
+type FilePath struct {
+	ToString string `json:"toString"`
+}
+
+// FileChange represents change of a file.
+type FileChange struct {
+	Path FilePath `json:"path"`
+	// SrcPath is the original path of moved or copied files.
+	SrcPath *FilePath `json:"srcPath,omitempty"`
+	Type    string    `json:"type"`
+}
+
+// FileChanges is a set of file changes.
+type FileChanges struct {
+	Pagination
+	Values []FileChange `json:"values"`
+}
+
+// CompareOpts represents the options of comparing commits.
+type CompareOpts struct {
+	ListOpts
+	// From is the head commit to compare.
+	From string `url:"from,omitempty" json:"from,omitempty"`
+	// To is the base commit to compare.
+	To string `url:"to,omitempty" json:"to,omitempty"`
+}
+
 // StatusReq represents the options of creating commit status.
 type StatusReq struct {
 	State       string `json:"state"`
@@ -171,6 +197,19 @@ func (server *RepositoriesService) ListPullRequests(ctx context.Context, project
 	return &prs, resp, err
 }
 
+// CompareChanges lists files changed between two commits on the repository.
+func (server *RepositoriesService) CompareChanges(ctx context.Context, project, repo string, opt *CompareOpts) (*FileChanges, *http.Response, error) {
+	u := fmt.Sprintf("rest/api/1.0/projects/%s/repos/%s/compare/changes", project, repo)
+	req, err := server.v1Client.NewRequest(http.MethodGet, u, nil, opt)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var changes FileChanges
+	resp, err := server.v1Client.Do(req, &changes)
+	return &changes, resp, err
+}
+
 // CreateStatus create a commit status.
 func (server *RepositoriesService) CreateStatus(ctx context.Context, commitID string, input *StatusReq) (*http.Response, error) {
 	u := fmt.Sprintf("rest/build-status/1.0/commits/%s", commitID)
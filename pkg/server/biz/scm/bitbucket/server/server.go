@@ -192,6 +192,53 @@ func (b *BitbucketServer) ListTags(repo string) ([]string, error) {
 	return tagNames, nil
 }
 
+// ListRefs lists the branches and tags with their commit SHAs for specified repo.
+func (b *BitbucketServer) ListRefs(repo string) ([]scm.Ref, error) {
+	var projectKey string
+	var err error
+	if projectKey, repo, err = parseRepo(b.scmCfg, repo); err != nil {
+		log.Error(err)
+		return nil, err
+	}
+
+	var refs []scm.Ref
+	opt := ListOpts{}
+	for {
+		branches, resp, err := b.v1Client.Repositories.ListBranches(context.Background(), projectKey, repo, &opt)
+		if err != nil {
+			log.Errorf("Fail to list branches for %s as %v", repo, err)
+			return nil, convertBitBucketError(err, resp)
+		}
+
+		for _, branch := range branches.Values {
+			refs = append(refs, scm.Ref{Name: scm.BranchRef(branch.DisplayID), SHA: branch.LatestCommit})
+		}
+		if branches.NextPage == nil {
+			break
+		}
+		opt.Start = branches.NextPage
+	}
+
+	opt = ListOpts{}
+	for {
+		tags, resp, err := b.v1Client.Repositories.ListTags(context.Background(), projectKey, repo, &opt)
+		if err != nil {
+			log.Errorf("Fail to list tags for %s as %v", repo, err)
+			return nil, convertBitBucketError(err, resp)
+		}
+
+		for _, tag := range tags.Values {
+			refs = append(refs, scm.Ref{Name: scm.TagRef(tag.DisplayID), SHA: tag.LatestCommit})
+		}
+		if tags.NextPage == nil {
+			break
+		}
+		opt.Start = tags.NextPage
+	}
+
+	return refs, nil
+}
+
 // ListPullRequests lists the pull requests for specified repo.
 func (b *BitbucketServer) ListPullRequests(repo, state string) ([]scm.PullRequest, error) {
 	//  Bitbucket pr state: OPEN, DECLINED, MERGED, ALL
@@ -225,7 +272,17 @@ func (b *BitbucketServer) ListPullRequests(repo, state string) ([]scm.PullReques
 			return nil, convertBitBucketError(err, resp)
 		}
 
-		allPRs = append(allPRs, prs.Values...)
+		for _, pr := range prs.Values {
+			allPRs = append(allPRs, scm.PullRequest{
+				ID:           pr.ID,
+				Title:        pr.Title,
+				Description:  pr.Description,
+				State:        pr.State,
+				TargetBranch: pr.ToRef.DisplayID,
+				Ref:          fmt.Sprintf(pullRefTemplate, pr.ID),
+				HeadSHA:      pr.FromRef.LatestCommit,
+			})
+		}
 		if prs.NextPage == nil {
 			break
 		}
@@ -271,41 +328,61 @@ func (b *BitbucketServer) ListDockerfiles(repo string) ([]string, error) {
 
 // CreateStatus generate a new status for repository.
 func (b *BitbucketServer) CreateStatus(status c_v1alpha1.StatusPhase, targetURL, repoURL, commitSha string) error {
-	// BitBucket Server:  SUCCESSFUL, FAILED and INPROGRESS.
-	state := ""
-	description := ""
-
 	switch status {
-	case c_v1alpha1.StatusRunning:
-		state = "INPROGRESS"
-		description = "Cyclone CI is in progress."
-	case c_v1alpha1.StatusSucceeded:
-		state = "SUCCESSFUL"
-		description = "Cyclone CI passed."
-	case c_v1alpha1.StatusFailed:
-		state = "FAILED"
-		description = "Cyclone CI failed."
-	case c_v1alpha1.StatusCancelled:
-		state = "FAILED"
-		description = "Cyclone CI failed."
+	case c_v1alpha1.StatusRunning, c_v1alpha1.StatusSucceeded, c_v1alpha1.StatusFailed, c_v1alpha1.StatusCancelled:
 	default:
 		err := fmt.Errorf("not supported state:%s", status)
 		log.Error(err)
 		return err
 	}
 
-	label := "continuous-integration/cyclone"
+	return b.CreateCommitStatus(repoURL, commitSha, &scm.CommitStatus{
+		Phase:     status,
+		Context:   scm.StatusContext,
+		TargetURL: targetURL,
+	})
+}
+
+// CreateCommitStatus creates the commit status with the given context, the context is used as the key of
+// Bitbucket build statuses.
+func (b *BitbucketServer) CreateCommitStatus(repoURL, commitSha string, status *scm.CommitStatus) error {
+	// BitBucket Server:  SUCCESSFUL, FAILED and INPROGRESS.
+	state := "INPROGRESS"
+	switch status.Phase {
+	case c_v1alpha1.StatusSucceeded, c_v1alpha1.StatusSkipped:
+		state = "SUCCESSFUL"
+	case c_v1alpha1.StatusFailed, c_v1alpha1.StatusCancelled:
+		state = "FAILED"
+	}
+	description := status.Description
+	if description == "" {
+		description = scm.StatusDescription(status.Phase)
+	}
+	label := status.Context
+	if label == "" {
+		label = scm.StatusContext
+	}
+
 	opt := &StatusReq{
 		State:       state,
 		Key:         label,
 		Name:        label,
-		URL:         targetURL,
+		URL:         status.TargetURL,
 		Description: description,
 	}
 	resp, err := b.v1Client.Repositories.CreateStatus(context.Background(), commitSha, opt)
 	return convertBitBucketError(err, resp)
 }
 
+// CreatePullRequestComment comments on the pull request.
+func (b *BitbucketServer) CreatePullRequestComment(repoURL string, number int, body string) error {
+	projectKey, name := scm.ParseRepo(repoURL)
+	resp, err := b.v1Client.PullRequests.CreateComment(context.Background(), projectKey, name, number, &CommentReq{
+		Text: body,
+	})
+	return convertBitBucketError(err, resp)
+}
+
 // GetPullRequestSHA gets latest commit SHA of pull request.
 func (b *BitbucketServer) GetPullRequestSHA(repoURL string, number int) (string, error) {
 	projectKey, name := scm.ParseRepo(repoURL)
@@ -318,6 +395,61 @@ func (b *BitbucketServer) GetPullRequestSHA(repoURL string, number int) (string,
 	return pr.FromRef.LatestCommit, nil
 }
 
+// ListPullRequestFiles lists files changed by the pull request.
+func (b *BitbucketServer) ListPullRequestFiles(repo string, number int) ([]string, error) {
+	projectKey, name := scm.ParseRepo(repo)
+	opt := ListOpts{}
+	files := []string{}
+	for {
+		changes, resp, err := b.v1Client.PullRequests.ListChanges(context.Background(), projectKey, name, number, &opt)
+		if err != nil {
+			log.Errorf("Fail to list changes of pull request %d for %s as %v", number, repo, err)
+			return nil, convertBitBucketError(err, resp)
+		}
+
+		files = appendChangedFiles(files, changes.Values)
+		if changes.NextPage == nil {
+			break
+		}
+		opt.Start = changes.NextPage
+	}
+
+	return files, nil
+}
+
+// CompareCommits lists files changed between the base and head commits.
+func (b *BitbucketServer) CompareCommits(repo, base, head string) ([]string, error) {
+	projectKey, name := scm.ParseRepo(repo)
+	opt := CompareOpts{From: head, To: base}
+	files := []string{}
+	for {
+		changes, resp, err := b.v1Client.Repositories.CompareChanges(context.Background(), projectKey, name, &opt)
+		if err != nil {
+			log.Errorf("Fail to compare commits %s...%s for %s as %v", base, head, repo, err)
+			return nil, convertBitBucketError(err, resp)
+		}
+
+		files = appendChangedFiles(files, changes.Values)
+		if changes.NextPage == nil {
+			break
+		}
+		opt.Start = changes.NextPage
+	}
+
+	return files, nil
+}
+
+// appendChangedFiles appends paths of the changes to the files, both paths are appended for moved or copied files.
+func appendChangedFiles(files []string, changes []FileChange) []string {
+	for _, c := range changes {
+		if c.SrcPath != nil && c.SrcPath.ToString != "" {
+			files = append(files, c.SrcPath.ToString)
+		}
+		files = append(files, c.Path.ToString)
+	}
+	return files
+}
+
 // CreateWebhook creates webhook for specified repo.
 func (b *BitbucketServer) CreateWebhook(repo string, webhook *scm.Webhook) error {
 	if webhook == nil || len(webhook.URL) == 0 || len(webhook.Events) == 0 {
@@ -94,38 +94,43 @@ func ParseEvent(request *http.Request) *scm.EventData {
 				}
 			} else if change.Type == update {
 				return &scm.EventData{
-					Type:   scm.PushEventType,
-					Repo:   fmt.Sprintf("%s/%s", strings.ToLower(payload.Repository.Project.Key), payload.Repository.Slug),
-					Ref:    change.RefID,
-					Branch: change.RefID,
+					Type:      scm.PushEventType,
+					Repo:      fmt.Sprintf("%s/%s", strings.ToLower(payload.Repository.Project.Key), payload.Repository.Slug),
+					Ref:       change.RefID,
+					Branch:    change.RefID,
+					CommitSHA: change.ToHash,
+					Before:    change.FromHash,
 				}
 			}
 		}
 	case PrOpened:
 		return &scm.EventData{
-			Type:      scm.PullRequestEventType,
-			Repo:      fmt.Sprintf("%s/%s", strings.ToLower(payload.PullRequest.ToRef.Repository.Project.Key), payload.PullRequest.ToRef.Repository.Slug),
-			Ref:       fmt.Sprintf(pullRefTemplate, payload.PullRequest.ID),
-			CommitSHA: payload.PullRequest.FromRef.LatestCommit,
-			Branch:    payload.PullRequest.ToRef.DisplayID,
-			CreatedAt: time.Unix(payload.PullRequest.CreatedDate/1000, 0),
+			Type:              scm.PullRequestEventType,
+			Repo:              fmt.Sprintf("%s/%s", strings.ToLower(payload.PullRequest.ToRef.Repository.Project.Key), payload.PullRequest.ToRef.Repository.Slug),
+			Ref:               fmt.Sprintf(pullRefTemplate, payload.PullRequest.ID),
+			CommitSHA:         payload.PullRequest.FromRef.LatestCommit,
+			Branch:            payload.PullRequest.ToRef.DisplayID,
+			PullRequestNumber: payload.PullRequest.ID,
+			CreatedAt:         time.Unix(payload.PullRequest.CreatedDate/1000, 0),
 		}
 	case PrFromRefUpdated, PrModified:
 		return &scm.EventData{
-			Type:      scm.PullRequestEventType,
-			Repo:      fmt.Sprintf("%s/%s", strings.ToLower(payload.PullRequest.ToRef.Repository.Project.Key), payload.PullRequest.ToRef.Repository.Slug),
-			Ref:       fmt.Sprintf(pullRefTemplate, payload.PullRequest.ID),
-			CommitSHA: payload.PullRequest.FromRef.LatestCommit,
-			CreatedAt: time.Unix(payload.PullRequest.UpdatedDate/1000, 0),
+			Type:              scm.PullRequestEventType,
+			Repo:              fmt.Sprintf("%s/%s", strings.ToLower(payload.PullRequest.ToRef.Repository.Project.Key), payload.PullRequest.ToRef.Repository.Slug),
+			Ref:               fmt.Sprintf(pullRefTemplate, payload.PullRequest.ID),
+			CommitSHA:         payload.PullRequest.FromRef.LatestCommit,
+			PullRequestNumber: payload.PullRequest.ID,
+			CreatedAt:         time.Unix(payload.PullRequest.UpdatedDate/1000, 0),
 		}
 	case PrCommentAdded:
 		return &scm.EventData{
-			Type:      scm.PullRequestCommentEventType,
-			Repo:      fmt.Sprintf("%s/%s", strings.ToLower(payload.PullRequest.ToRef.Repository.Project.Key), payload.PullRequest.ToRef.Repository.Slug),
-			Ref:       fmt.Sprintf(pullRefTemplate, payload.PullRequest.ID),
-			Comment:   payload.Comment.Text,
-			CommitSHA: payload.PullRequest.FromRef.LatestCommit,
-			CreatedAt: time.Unix(payload.PullRequest.CreatedDate/1000, 0),
+			Type:              scm.PullRequestCommentEventType,
+			Repo:              fmt.Sprintf("%s/%s", strings.ToLower(payload.PullRequest.ToRef.Repository.Project.Key), payload.PullRequest.ToRef.Repository.Slug),
+			Ref:               fmt.Sprintf(pullRefTemplate, payload.PullRequest.ID),
+			Comment:           payload.Comment.Text,
+			CommitSHA:         payload.PullRequest.FromRef.LatestCommit,
+			PullRequestNumber: payload.PullRequest.ID,
+			CreatedAt:         time.Unix(payload.PullRequest.CreatedDate/1000, 0),
 		}
 	default:
 		log.Warningln("Skip unsupported Bitbucket Server event")
@@ -0,0 +1,358 @@
+package scm
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	c_v1alpha1 "github.com/caicloud/cyclone/pkg/apis/cyclone/v1alpha1"
+)
+
+const (
+	// StatusContext is the context of overall commit statuses of WorkflowRuns, it's also the name of check runs.
+	StatusContext = "continuous-integration/cyclone"
+
+	// ResultKeyTestsTotal is the key of stage results to report the number of tests.
+	ResultKeyTestsTotal = "testsTotal"
+	// ResultKeyTestsFailed is the key of stage results to report the number of failed tests.
+	ResultKeyTestsFailed = "testsFailed"
+	// ResultKeyTestsSkipped is the key of stage results to report the number of skipped tests.
+	ResultKeyTestsSkipped = "testsSkipped"
+	// ResultKeyAnnotation is the key of stage results to report annotations, values are in format of
+	// '<path>:<line>:<level>:<message>'. It can be reported multiple times.
+	ResultKeyAnnotation = "annotation"
+)
+
+// Annotation levels, they are the same as levels of GitHub check run annotations.
+const (
+	AnnotationLevelNotice  = "notice"
+	AnnotationLevelWarning = "warning"
+	AnnotationLevelFailure = "failure"
+)
+
+// StageStatusContext returns the context of commit statuses of the stage.
+func StageStatusContext(stage string) string {
+	return StatusContext + "/" + stage
+}
+
+// CommitStatus represents a commit status to create.
+type CommitStatus struct {
+	// Phase is the phase of the WorkflowRun or stage, it's converted to the state of the SCM.
+	Phase c_v1alpha1.StatusPhase
+	// Context distinguishes statuses of a commit, default is StatusContext.
+	Context string
+	// Description of the status, default description of the phase is used if it's empty.
+	Description string
+	// TargetURL is the link of the status.
+	TargetURL string
+}
+
+// StatusDescription returns the default description of commit statuses for the phase.
+func StatusDescription(phase c_v1alpha1.StatusPhase) string {
+	switch phase {
+	case c_v1alpha1.StatusPending, c_v1alpha1.StatusWaiting:
+		return "Cyclone CI is pending."
+	case c_v1alpha1.StatusRunning:
+		return "Cyclone CI is in progress."
+	case c_v1alpha1.StatusSucceeded:
+		return "Cyclone CI passed."
+	case c_v1alpha1.StatusSkipped:
+		return "Cyclone CI skipped."
+	default:
+		return "Cyclone CI failed."
+	}
+}
+
+// Annotation represents an annotation on a line of a file.
+type Annotation struct {
+	Path    string
+	Line    int
+	Level   string
+	Message string
+}
+
+// ParseAnnotation parses an annotation in format of '<path>:<line>:<level>:<message>'.
+func ParseAnnotation(value string) (*Annotation, error) {
+	parts := strings.SplitN(value, ":", 4)
+	if len(parts) != 4 {
+		return nil, fmt.Errorf("invalid annotation %s, must be in format of '<path>:<line>:<level>:<message>'", value)
+	}
+
+	line, err := strconv.Atoi(parts[1])
+	if err != nil || line <= 0 {
+		return nil, fmt.Errorf("invalid line %s of annotation", parts[1])
+	}
+
+	level := parts[2]
+	switch level {
+	case AnnotationLevelNotice, AnnotationLevelWarning, AnnotationLevelFailure:
+	default:
+		return nil, fmt.Errorf("invalid level %s of annotation, must be one of notice, warning and failure", level)
+	}
+
+	return &Annotation{
+		Path:    strings.TrimPrefix(parts[0], "/"),
+		Line:    line,
+		Level:   level,
+		Message: strings.TrimSpace(parts[3]),
+	}, nil
+}
+
+// TestCounts represents numbers of tests.
+type TestCounts struct {
+	Total   int
+	Failed  int
+	Skipped int
+}
+
+// Passed returns the number of passed tests.
+func (t *TestCounts) Passed() int {
+	return t.Total - t.Failed - t.Skipped
+}
+
+// StageResult represents result of a stage.
+type StageResult struct {
+	Name    string
+	Phase   c_v1alpha1.StatusPhase
+	Message string
+	// Tests is nil if the stage doesn't report tests.
+	Tests       *TestCounts
+	Annotations []Annotation
+	// Artifacts are file names of artifacts produced by the stage.
+	Artifacts []string
+}
+
+// maxStatusDescriptionLength is the max length of commit status descriptions, it's limited by GitHub.
+const maxStatusDescriptionLength = 140
+
+// Description returns the description of the stage commit status, message of the stage is preferred.
+func (r *StageResult) Description() string {
+	if r.Message == "" {
+		return StatusDescription(r.Phase)
+	}
+
+	message := []rune(strings.Replace(r.Message, "\n", " ", -1))
+	if len(message) > maxStatusDescriptionLength {
+		return string(message[:maxStatusDescriptionLength-3]) + "..."
+	}
+	return string(message)
+}
+
+// Summary represents result of a WorkflowRun reported to the SCM.
+type Summary struct {
+	WorkflowRun string
+	Phase       c_v1alpha1.StatusPhase
+	RecordURL   string
+	StartTime   time.Time
+	Stages      []StageResult
+}
+
+// NewSummary creates a summary of the WorkflowRun, artifacts are file names of artifacts keyed by stages.
+// Stages are sorted by their start time.
+func NewSummary(wfr *c_v1alpha1.WorkflowRun, recordURL string, artifacts map[string][]string) *Summary {
+	summary := &Summary{
+		WorkflowRun: wfr.Name,
+		Phase:       wfr.Status.Overall.Phase,
+		RecordURL:   recordURL,
+		StartTime:   wfr.Status.Overall.StartTime.Time,
+	}
+
+	startTimes := make(map[string]time.Time)
+	for name, status := range wfr.Status.Stages {
+		if status == nil {
+			continue
+		}
+
+		result := StageResult{
+			Name:      name,
+			Phase:     status.Status.Phase,
+			Message:   status.Status.Message,
+			Artifacts: artifacts[name],
+		}
+		if result.Phase == "" {
+			result.Phase = c_v1alpha1.StatusPending
+		}
+		for _, kv := range status.Outputs {
+			switch kv.Key {
+			case ResultKeyTestsTotal, ResultKeyTestsFailed, ResultKeyTestsSkipped:
+				n, err := strconv.Atoi(strings.TrimSpace(kv.Value))
+				if err != nil {
+					continue
+				}
+				if result.Tests == nil {
+					result.Tests = &TestCounts{}
+				}
+				switch kv.Key {
+				case ResultKeyTestsTotal:
+					result.Tests.Total = n
+				case ResultKeyTestsFailed:
+					result.Tests.Failed = n
+				case ResultKeyTestsSkipped:
+					result.Tests.Skipped = n
+				}
+			case ResultKeyAnnotation:
+				if a, err := ParseAnnotation(kv.Value); err == nil {
+					result.Annotations = append(result.Annotations, *a)
+				}
+			}
+		}
+
+		startTimes[name] = status.Status.StartTime.Time
+		summary.Stages = append(summary.Stages, result)
+	}
+
+	sort.Slice(summary.Stages, func(i, j int) bool {
+		ti, tj := startTimes[summary.Stages[i].Name], startTimes[summary.Stages[j].Name]
+		if !ti.Equal(tj) {
+			return ti.Before(tj)
+		}
+		return summary.Stages[i].Name < summary.Stages[j].Name
+	})
+
+	return summary
+}
+
+// Tests returns the total numbers of tests of all stages, nil is returned if no stage reports tests.
+func (s *Summary) Tests() *TestCounts {
+	var total *TestCounts
+	for _, stage := range s.Stages {
+		if stage.Tests == nil {
+			continue
+		}
+		if total == nil {
+			total = &TestCounts{}
+		}
+		total.Total += stage.Tests.Total
+		total.Failed += stage.Tests.Failed
+		total.Skipped += stage.Tests.Skipped
+	}
+	return total
+}
+
+// Annotations returns annotations of all stages.
+func (s *Summary) Annotations() []Annotation {
+	var annotations []Annotation
+	for _, stage := range s.Stages {
+		annotations = append(annotations, stage.Annotations...)
+	}
+	return annotations
+}
+
+// Title returns a one line title of the summary, for example, 'Cyclone CI passed: 10 tests, 1 skipped'.
+func (s *Summary) Title() string {
+	title := strings.TrimSuffix(StatusDescription(s.Phase), ".")
+	tests := s.Tests()
+	if tests == nil {
+		return title
+	}
+
+	title = fmt.Sprintf("%s: %d tests", title, tests.Total)
+	if tests.Failed > 0 {
+		title += fmt.Sprintf(", %d failed", tests.Failed)
+	}
+	if tests.Skipped > 0 {
+		title += fmt.Sprintf(", %d skipped", tests.Skipped)
+	}
+	return title
+}
+
+// Markdown renders the summary as markdown, it's used as pull request comments and check run summaries.
+func (s *Summary) Markdown() string {
+	var b strings.Builder
+
+	if s.RecordURL != "" {
+		fmt.Fprintf(&b, "### [%s](%s)\n\n", s.Title(), s.RecordURL)
+	} else {
+		fmt.Fprintf(&b, "### %s\n\n", s.Title())
+	}
+	fmt.Fprintf(&b, "WorkflowRun `%s` is **%s**.\n", s.WorkflowRun, s.Phase)
+
+	if len(s.Stages) > 0 {
+		b.WriteString("\n| Stage | Result | Tests |\n| --- | --- | --- |\n")
+		for _, stage := range s.Stages {
+			tests := "-"
+			if stage.Tests != nil {
+				tests = fmt.Sprintf("%d passed, %d failed, %d skipped", stage.Tests.Passed(), stage.Tests.Failed, stage.Tests.Skipped)
+			}
+			result := string(stage.Phase)
+			if stage.Phase == c_v1alpha1.StatusFailed && stage.Message != "" {
+				result = fmt.Sprintf("%s: %s", result, escapeMarkdownTable(stage.Message))
+			}
+			fmt.Fprintf(&b, "| %s | %s | %s |\n", stage.Name, result, tests)
+		}
+	}
+
+	var artifacts []string
+	for _, stage := range s.Stages {
+		for _, a := range stage.Artifacts {
+			artifacts = append(artifacts, fmt.Sprintf("- `%s/%s`", stage.Name, a))
+		}
+	}
+	if len(artifacts) > 0 {
+		b.WriteString("\n**Artifacts**")
+		if s.RecordURL != "" {
+			fmt.Fprintf(&b, " ([download](%s))", s.RecordURL)
+		}
+		b.WriteString("\n\n")
+		b.WriteString(strings.Join(artifacts, "\n"))
+		b.WriteString("\n")
+	}
+
+	return b.String()
+}
+
+func escapeMarkdownTable(s string) string {
+	s = strings.Replace(s, "\n", " ", -1)
+	return strings.Replace(s, "|", "\\|", -1)
+}
+
+// CheckRun represents a check run to create or update, check runs are identified by name and head SHA.
+type CheckRun struct {
+	Name       string
+	HeadSHA    string
+	Phase      c_v1alpha1.StatusPhase
+	DetailsURL string
+	Title      string
+	Summary    string
+	StartedAt  time.Time
+	// Annotations are only reported when the check run is completed.
+	Annotations []Annotation
+}
+
+// NewCheckRun creates a check run from the summary for the commit.
+func NewCheckRun(summary *Summary, headSHA string) *CheckRun {
+	return &CheckRun{
+		Name:        StatusContext,
+		HeadSHA:     headSHA,
+		Phase:       summary.Phase,
+		DetailsURL:  summary.RecordURL,
+		Title:       summary.Title(),
+		Summary:     summary.Markdown(),
+		StartedAt:   summary.StartTime,
+		Annotations: summary.Annotations(),
+	}
+}
+
+// DeploymentStatus represents status of a deployment of a commit to an environment.
+type DeploymentStatus struct {
+	Environment    string
+	CommitSHA      string
+	Phase          c_v1alpha1.StatusPhase
+	LogURL         string
+	EnvironmentURL string
+}
+
+// CheckRunCreator is implemented by providers supporting check runs, such as GitHub.
+type CheckRunCreator interface {
+	// CreateCheckRun creates the check run, or updates it if a check run with the same name exists for the commit.
+	CreateCheckRun(repo string, run *CheckRun) error
+}
+
+// DeploymentStatusCreator is implemented by providers supporting deployments, such as GitHub.
+type DeploymentStatusCreator interface {
+	// CreateDeploymentStatus creates a deployment of the commit for the environment if it doesn't exist, and
+	// creates the status for it.
+	CreateDeploymentStatus(repo string, status *DeploymentStatus) error
+}
@@ -0,0 +1,100 @@
+package scm
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	c_v1alpha1 "github.com/caicloud/cyclone/pkg/apis/cyclone/v1alpha1"
+)
+
+func TestParseAnnotation(t *testing.T) {
+	a, err := ParseAnnotation("/pkg/foo.go:12:warning:unused variable: x")
+	assert.Nil(t, err)
+	assert.Equal(t, &Annotation{Path: "pkg/foo.go", Line: 12, Level: AnnotationLevelWarning, Message: "unused variable: x"}, a)
+
+	for _, value := range []string{"pkg/foo.go:12:warning", "pkg/foo.go:x:warning:msg", "pkg/foo.go:0:notice:msg", "pkg/foo.go:1:error:msg"} {
+		_, err := ParseAnnotation(value)
+		assert.NotNil(t, err, value)
+	}
+}
+
+func newTestWorkflowRun() *c_v1alpha1.WorkflowRun {
+	start := time.Date(2020, 3, 1, 8, 0, 0, 0, time.UTC)
+	return &c_v1alpha1.WorkflowRun{
+		ObjectMeta: metav1.ObjectMeta{Name: "ci-abcde"},
+		Status: c_v1alpha1.WorkflowRunStatus{
+			Overall: c_v1alpha1.Status{Phase: c_v1alpha1.StatusFailed, StartTime: metav1.NewTime(start)},
+			Stages: map[string]*c_v1alpha1.StageStatus{
+				"test": {
+					Status: c_v1alpha1.Status{Phase: c_v1alpha1.StatusFailed, Message: "exit code 1", StartTime: metav1.NewTime(start.Add(time.Minute))},
+					Outputs: []c_v1alpha1.KeyValue{
+						{Key: ResultKeyTestsTotal, Value: "10"},
+						{Key: ResultKeyTestsFailed, Value: "2"},
+						{Key: ResultKeyTestsSkipped, Value: "1"},
+						{Key: ResultKeyAnnotation, Value: "foo.go:3:failure:TestFoo failed"},
+						{Key: ResultKeyAnnotation, Value: "invalid"},
+					},
+				},
+				"lint": {
+					Status: c_v1alpha1.Status{Phase: c_v1alpha1.StatusSucceeded, StartTime: metav1.NewTime(start.Add(time.Minute))},
+					Outputs: []c_v1alpha1.KeyValue{
+						{Key: ResultKeyAnnotation, Value: "bar.go:7:notice:consider renaming"},
+					},
+				},
+				"build": {
+					Status: c_v1alpha1.Status{Phase: c_v1alpha1.StatusSucceeded, StartTime: metav1.NewTime(start)},
+					Outputs: []c_v1alpha1.KeyValue{
+						{Key: ResultKeyTestsTotal, Value: "N/A"},
+					},
+				},
+			},
+		},
+	}
+}
+
+func TestNewSummary(t *testing.T) {
+	summary := NewSummary(newTestWorkflowRun(), "https://cyclone.example.com/wfr", map[string][]string{
+		"build": {"cyclone.tar"},
+	})
+
+	assert.Equal(t, 3, len(summary.Stages))
+	assert.Equal(t, "build", summary.Stages[0].Name)
+	assert.Equal(t, []string{"cyclone.tar"}, summary.Stages[0].Artifacts)
+	assert.Nil(t, summary.Stages[0].Tests)
+	assert.Equal(t, "lint", summary.Stages[1].Name)
+	assert.Equal(t, "test", summary.Stages[2].Name)
+	assert.Equal(t, &TestCounts{Total: 10, Failed: 2, Skipped: 1}, summary.Stages[2].Tests)
+	assert.Equal(t, 7, summary.Stages[2].Tests.Passed())
+
+	assert.Equal(t, &TestCounts{Total: 10, Failed: 2, Skipped: 1}, summary.Tests())
+	assert.Equal(t, []Annotation{
+		{Path: "bar.go", Line: 7, Level: AnnotationLevelNotice, Message: "consider renaming"},
+		{Path: "foo.go", Line: 3, Level: AnnotationLevelFailure, Message: "TestFoo failed"},
+	}, summary.Annotations())
+	assert.Equal(t, "Cyclone CI failed: 10 tests, 2 failed, 1 skipped", summary.Title())
+
+	markdown := summary.Markdown()
+	assert.True(t, strings.HasPrefix(markdown, "### [Cyclone CI failed: 10 tests, 2 failed, 1 skipped](https://cyclone.example.com/wfr)\n"))
+	assert.Contains(t, markdown, "| test | Failed: exit code 1 | 7 passed, 2 failed, 1 skipped |\n")
+	assert.Contains(t, markdown, "| build | Succeeded | - |\n")
+	assert.Contains(t, markdown, "- `build/cyclone.tar`\n")
+
+	run := NewCheckRun(summary, "6f4b3e0")
+	assert.Equal(t, StatusContext, run.Name)
+	assert.Equal(t, "6f4b3e0", run.HeadSHA)
+	assert.Equal(t, summary.Title(), run.Title)
+	assert.Equal(t, 2, len(run.Annotations))
+}
+
+func TestStageResultDescription(t *testing.T) {
+	assert.Equal(t, "Cyclone CI passed.", (&StageResult{Phase: c_v1alpha1.StatusSucceeded}).Description())
+	assert.Equal(t, "exit code 1", (&StageResult{Phase: c_v1alpha1.StatusFailed, Message: "exit code 1"}).Description())
+
+	description := (&StageResult{Phase: c_v1alpha1.StatusFailed, Message: strings.Repeat("x", 200)}).Description()
+	assert.Equal(t, maxStatusDescriptionLength, len(description))
+	assert.True(t, strings.HasSuffix(description, "..."))
+}
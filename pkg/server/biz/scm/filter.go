@@ -0,0 +1,205 @@
+package scm
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	c_v1alpha1 "github.com/caicloud/cyclone/pkg/apis/cyclone/v1alpha1"
+)
+
+const (
+	// regexPatternPrefix is prefix of regular expression patterns in ref filters.
+	regexPatternPrefix = "regex:"
+
+	branchRefPrefix = "refs/heads/"
+	tagRefPrefix    = "refs/tags/"
+)
+
+// BranchRef gets the full ref of a branch, for example, 'refs/heads/master' for 'master'.
+func BranchRef(branch string) string {
+	if strings.HasPrefix(branch, "refs/") {
+		return branch
+	}
+	return branchRefPrefix + branch
+}
+
+// TagRef gets the full ref of a tag, for example, 'refs/tags/v1.0' for 'v1.0'.
+func TagRef(tag string) string {
+	if strings.HasPrefix(tag, "refs/") {
+		return tag
+	}
+	return tagRefPrefix + tag
+}
+
+// shortRef gets the short name of a full branch or tag ref, other refs are returned as they are.
+func shortRef(ref string) string {
+	for _, prefix := range []string{branchRefPrefix, tagRefPrefix} {
+		if strings.HasPrefix(ref, prefix) {
+			return strings.TrimPrefix(ref, prefix)
+		}
+	}
+	return ref
+}
+
+// MatchRefFilter checks whether the full ref passes the filter, nil filter passes all refs.
+func MatchRefFilter(filter *c_v1alpha1.RefFilter, ref string) (bool, error) {
+	if filter == nil {
+		return true, nil
+	}
+
+	included := len(filter.Include) == 0
+	for _, pattern := range filter.Include {
+		matched, err := matchRefPattern(pattern, ref)
+		if err != nil {
+			return false, err
+		}
+		if matched {
+			included = true
+			break
+		}
+	}
+	if !included {
+		return false, nil
+	}
+
+	for _, pattern := range filter.Exclude {
+		matched, err := matchRefPattern(pattern, ref)
+		if err != nil {
+			return false, err
+		}
+		if matched {
+			return false, nil
+		}
+	}
+
+	return true, nil
+}
+
+// ValidateRefFilter checks whether patterns in the filter are valid.
+func ValidateRefFilter(filter *c_v1alpha1.RefFilter) error {
+	if filter == nil {
+		return nil
+	}
+
+	for _, pattern := range append(append([]string{}, filter.Include...), filter.Exclude...) {
+		if _, err := compileRefPattern(pattern); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// HasPathFilter checks whether the path filter has any patterns.
+func HasPathFilter(filter *c_v1alpha1.PathFilter) bool {
+	return len(filter.Paths) > 0 || len(filter.PathsIgnore) > 0
+}
+
+// MatchPathFilter checks whether any of the changed files passes the filter. Files are regarded as passed if they
+// are unknown, i.e. nil.
+func MatchPathFilter(filter *c_v1alpha1.PathFilter, files []string) (bool, error) {
+	if !HasPathFilter(filter) || files == nil {
+		return true, nil
+	}
+
+	for _, file := range files {
+		file = strings.TrimPrefix(file, "/")
+		included := len(filter.Paths) == 0
+		for _, pattern := range filter.Paths {
+			matched, err := matchPathPattern(pattern, file)
+			if err != nil {
+				return false, err
+			}
+			if matched {
+				included = true
+				break
+			}
+		}
+		if !included {
+			continue
+		}
+
+		ignored := false
+		for _, pattern := range filter.PathsIgnore {
+			matched, err := matchPathPattern(pattern, file)
+			if err != nil {
+				return false, err
+			}
+			if matched {
+				ignored = true
+				break
+			}
+		}
+		if !ignored {
+			return true, nil
+		}
+	}
+
+	return false, nil
+}
+
+// ValidatePathFilter checks whether patterns in the filter are valid.
+func ValidatePathFilter(filter *c_v1alpha1.PathFilter) error {
+	for _, pattern := range append(append([]string{}, filter.Paths...), filter.PathsIgnore...) {
+		if _, err := compileRefPattern(pattern); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// matchPathPattern checks whether the file path matches the pattern. Patterns ending with '/' match all files
+// under the directory.
+func matchPathPattern(pattern, file string) (bool, error) {
+	pattern = strings.TrimPrefix(pattern, "/")
+	if !strings.HasPrefix(pattern, regexPatternPrefix) && strings.HasSuffix(pattern, "/") {
+		pattern += "**"
+	}
+
+	re, err := compileRefPattern(pattern)
+	if err != nil {
+		return false, err
+	}
+
+	return re.MatchString(file), nil
+}
+
+// matchRefPattern checks whether the full ref or its short name matches the pattern.
+func matchRefPattern(pattern, ref string) (bool, error) {
+	re, err := compileRefPattern(pattern)
+	if err != nil {
+		return false, err
+	}
+
+	return re.MatchString(ref) || re.MatchString(shortRef(ref)), nil
+}
+
+// compileRefPattern compiles glob or regex pattern to a regular expression that matches whole strings.
+func compileRefPattern(pattern string) (*regexp.Regexp, error) {
+	if strings.HasPrefix(pattern, regexPatternPrefix) {
+		re, err := regexp.Compile("^(?:" + strings.TrimPrefix(pattern, regexPatternPrefix) + ")$")
+		if err != nil {
+			return nil, fmt.Errorf("invalid pattern %s: %v", pattern, err)
+		}
+		return re, nil
+	}
+
+	var b strings.Builder
+	b.WriteString("^")
+	for i := 0; i < len(pattern); i++ {
+		switch {
+		case strings.HasPrefix(pattern[i:], "**"):
+			b.WriteString(".*")
+			i++
+		case pattern[i] == '*':
+			b.WriteString("[^/]*")
+		case pattern[i] == '?':
+			b.WriteString("[^/]")
+		default:
+			b.WriteString(regexp.QuoteMeta(pattern[i : i+1]))
+		}
+	}
+	b.WriteString("$")
+
+	return regexp.MustCompile(b.String()), nil
+}
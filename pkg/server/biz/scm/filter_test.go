@@ -0,0 +1,188 @@
+package scm
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	c_v1alpha1 "github.com/caicloud/cyclone/pkg/apis/cyclone/v1alpha1"
+)
+
+func TestMatchRefFilter(t *testing.T) {
+	cases := []struct {
+		filter   *c_v1alpha1.RefFilter
+		ref      string
+		expected bool
+	}{
+		{nil, "refs/heads/master", true},
+		{&c_v1alpha1.RefFilter{}, "refs/heads/master", true},
+		{&c_v1alpha1.RefFilter{Include: []string{"master"}}, "refs/heads/master", true},
+		{&c_v1alpha1.RefFilter{Include: []string{"master"}}, "refs/heads/dev/master", false},
+		{&c_v1alpha1.RefFilter{Include: []string{"release/*"}}, "refs/heads/release/1.0", true},
+		{&c_v1alpha1.RefFilter{Include: []string{"release/*"}}, "refs/heads/release/1.0/hotfix", false},
+		{&c_v1alpha1.RefFilter{Include: []string{"feature/**"}}, "refs/heads/feature/foo/bar", true},
+		{&c_v1alpha1.RefFilter{Include: []string{"refs/heads/*"}}, "refs/heads/master", true},
+		{&c_v1alpha1.RefFilter{Include: []string{"refs/heads/*"}}, "refs/tags/v1.0", false},
+		{&c_v1alpha1.RefFilter{Include: []string{"v?.?"}}, "refs/tags/v1.0", true},
+		{&c_v1alpha1.RefFilter{Include: []string{"v?.?"}}, "refs/tags/v1.10", false},
+		{&c_v1alpha1.RefFilter{Include: []string{`regex:v\d+\.\d+`}}, "refs/tags/v1.10", true},
+		{&c_v1alpha1.RefFilter{Include: []string{`regex:v\d+`}}, "refs/tags/v1.10", false},
+		{&c_v1alpha1.RefFilter{Include: []string{"**"}, Exclude: []string{"*-rc*"}}, "refs/tags/v1.0-rc1", false},
+		{&c_v1alpha1.RefFilter{Exclude: []string{"dependabot/**"}}, "refs/heads/dependabot/npm/foo", false},
+		{&c_v1alpha1.RefFilter{Exclude: []string{"dependabot/**"}}, "refs/heads/master", true},
+	}
+
+	for _, c := range cases {
+		matched, err := MatchRefFilter(c.filter, c.ref)
+		assert.Nil(t, err)
+		assert.Equal(t, c.expected, matched, "filter: %v, ref: %s", c.filter, c.ref)
+	}
+
+	_, err := MatchRefFilter(&c_v1alpha1.RefFilter{Include: []string{"regex:("}}, "refs/heads/master")
+	assert.NotNil(t, err)
+	assert.NotNil(t, ValidateRefFilter(&c_v1alpha1.RefFilter{Exclude: []string{"regex:("}}))
+	assert.Nil(t, ValidateRefFilter(&c_v1alpha1.RefFilter{Include: []string{"release/*", "regex:v.*"}}))
+}
+
+func TestMatchTrigger(t *testing.T) {
+	policy := &c_v1alpha1.SCMTriggerPolicy{
+		Push: c_v1alpha1.SCMTriggerPush{
+			Filter: &c_v1alpha1.RefFilter{Include: []string{"release/*"}},
+		},
+		TagRelease: c_v1alpha1.SCMTriggerTagRelease{
+			SCMTriggerBasic: c_v1alpha1.SCMTriggerBasic{Enabled: true},
+			Filter:          &c_v1alpha1.RefFilter{Exclude: []string{"*-rc*"}},
+		},
+		PullRequest: c_v1alpha1.SCMTriggerPullRequest{
+			SCMTriggerBasic: c_v1alpha1.SCMTriggerBasic{Enabled: true},
+			Branches:        []string{"master"},
+		},
+	}
+
+	m, err := MatchTrigger(policy, &EventData{Type: PushEventType, Branch: "refs/heads/release/1.0"})
+	assert.Nil(t, err)
+	assert.True(t, m.Matched)
+	m, _ = MatchTrigger(policy, &EventData{Type: PushEventType, Branch: "refs/heads/master"})
+	assert.False(t, m.Matched)
+
+	policy.Push.Branches = []string{"1.0"}
+	m, _ = MatchTrigger(policy, &EventData{Type: PushEventType, Branch: "refs/heads/release/1.0"})
+	assert.True(t, m.Matched)
+	m, _ = MatchTrigger(policy, &EventData{Type: PushEventType, Branch: "refs/heads/hotfix/1.0"})
+	assert.False(t, m.Matched)
+
+	m, _ = MatchTrigger(policy, &EventData{Type: TagReleaseEventType, Ref: "refs/tags/v1.0"})
+	assert.True(t, m.Matched)
+	assert.Equal(t, "v1.0", m.Tag)
+	m, _ = MatchTrigger(policy, &EventData{Type: TagReleaseEventType, Ref: "refs/tags/v1.0-rc1"})
+	assert.False(t, m.Matched)
+
+	m, _ = MatchTrigger(policy, &EventData{Type: PullRequestEventType, Branch: "master"})
+	assert.True(t, m.Matched)
+	assert.True(t, m.ByPR)
+	m, _ = MatchTrigger(policy, &EventData{Type: PullRequestEventType, Branch: "dev"})
+	assert.False(t, m.Matched)
+
+	policy.Push.Filter = &c_v1alpha1.RefFilter{Include: []string{"regex:["}}
+	_, err = MatchTrigger(policy, &EventData{Type: PushEventType, Branch: "refs/heads/release/1.0"})
+	assert.NotNil(t, err)
+}
+
+func TestMatchPathFilter(t *testing.T) {
+	cases := []struct {
+		filter   *c_v1alpha1.PathFilter
+		files    []string
+		expected bool
+	}{
+		{&c_v1alpha1.PathFilter{}, []string{"README.md"}, true},
+		{&c_v1alpha1.PathFilter{Paths: []string{"services/api/**"}}, nil, true},
+		{&c_v1alpha1.PathFilter{Paths: []string{"services/api/**"}}, []string{}, false},
+		{&c_v1alpha1.PathFilter{Paths: []string{"services/api/**"}}, []string{"services/api/main.go"}, true},
+		{&c_v1alpha1.PathFilter{Paths: []string{"services/api/"}}, []string{"services/api/pkg/server.go"}, true},
+		{&c_v1alpha1.PathFilter{Paths: []string{"/services/api/"}}, []string{"services/web/index.html"}, false},
+		{&c_v1alpha1.PathFilter{Paths: []string{"*.go"}}, []string{"pkg/main.go"}, false},
+		{&c_v1alpha1.PathFilter{Paths: []string{"**.go"}}, []string{"pkg/main.go"}, true},
+		{&c_v1alpha1.PathFilter{Paths: []string{`regex:.*\.go`}}, []string{"pkg/main.go"}, true},
+		{&c_v1alpha1.PathFilter{PathsIgnore: []string{"docs/**", "**.md"}}, []string{"docs/index.md", "README.md"}, false},
+		{&c_v1alpha1.PathFilter{PathsIgnore: []string{"docs/**"}}, []string{"docs/index.md", "main.go"}, true},
+		{&c_v1alpha1.PathFilter{Paths: []string{"services/**"}, PathsIgnore: []string{"**_test.go"}}, []string{"services/api/main_test.go"}, false},
+	}
+
+	for _, c := range cases {
+		matched, err := MatchPathFilter(c.filter, c.files)
+		assert.Nil(t, err)
+		assert.Equal(t, c.expected, matched, "filter: %v, files: %v", c.filter, c.files)
+	}
+
+	_, err := MatchPathFilter(&c_v1alpha1.PathFilter{Paths: []string{"regex:("}}, []string{"main.go"})
+	assert.NotNil(t, err)
+	assert.NotNil(t, ValidatePathFilter(&c_v1alpha1.PathFilter{PathsIgnore: []string{"regex:("}}))
+	assert.Nil(t, ValidatePathFilter(&c_v1alpha1.PathFilter{Paths: []string{"services/**"}}))
+}
+
+func TestMatchTriggerPaths(t *testing.T) {
+	policy := &c_v1alpha1.SCMTriggerPolicy{
+		Push: c_v1alpha1.SCMTriggerPush{
+			Branches:   []string{"master"},
+			PathFilter: c_v1alpha1.PathFilter{Paths: []string{"services/api/**"}},
+		},
+		PullRequest: c_v1alpha1.SCMTriggerPullRequest{
+			SCMTriggerBasic: c_v1alpha1.SCMTriggerBasic{Enabled: true},
+			PathFilter:      c_v1alpha1.PathFilter{PathsIgnore: []string{"docs/**"}},
+		},
+	}
+	assert.True(t, NeedChangedFiles(policy, PushEventType))
+	assert.True(t, NeedChangedFiles(policy, PullRequestEventType))
+	assert.False(t, NeedChangedFiles(policy, TagReleaseEventType))
+
+	m, _ := MatchTrigger(policy, &EventData{Type: PushEventType, Branch: "refs/heads/master", ChangedFiles: []string{"services/api/main.go"}})
+	assert.True(t, m.Matched)
+	m, _ = MatchTrigger(policy, &EventData{Type: PushEventType, Branch: "refs/heads/master", ChangedFiles: []string{"services/web/main.go"}})
+	assert.False(t, m.Matched)
+	m, _ = MatchTrigger(policy, &EventData{Type: PushEventType, Branch: "refs/heads/master"})
+	assert.True(t, m.Matched)
+
+	m, _ = MatchTrigger(policy, &EventData{Type: PullRequestEventType, Branch: "master", ChangedFiles: []string{"docs/index.md"}})
+	assert.False(t, m.Matched)
+	m, _ = MatchTrigger(policy, &EventData{Type: PullRequestEventType, Branch: "master", ChangedFiles: []string{"docs/index.md", "main.go"}})
+	assert.True(t, m.Matched)
+}
+
+type fakeProvider struct {
+	Provider
+	compared [2]string
+	pr       int
+	prFiles  []string
+}
+
+func (p *fakeProvider) CompareCommits(repo, base, head string) ([]string, error) {
+	p.compared = [2]string{base, head}
+	return []string{"main.go"}, nil
+}
+
+func (p *fakeProvider) ListPullRequestFiles(repo string, number int) ([]string, error) {
+	p.pr = number
+	return p.prFiles, nil
+}
+
+func TestPopulateChangedFiles(t *testing.T) {
+	p := &fakeProvider{}
+	data := &EventData{Type: PushEventType, Before: "a", CommitSHA: "b"}
+	assert.Nil(t, PopulateChangedFiles(p, data))
+	assert.Equal(t, [2]string{"a", "b"}, p.compared)
+	assert.Equal(t, []string{"main.go"}, data.ChangedFiles)
+
+	data = &EventData{Type: PushEventType, Before: zeroCommitSHA, CommitSHA: "b"}
+	assert.Nil(t, PopulateChangedFiles(p, data))
+	assert.Nil(t, data.ChangedFiles)
+
+	data = &EventData{Type: PullRequestEventType, PullRequestNumber: 3}
+	assert.Nil(t, PopulateChangedFiles(p, data))
+	assert.Equal(t, 3, p.pr)
+	assert.Equal(t, []string{}, data.ChangedFiles)
+
+	p.prFiles = make([]string, MaxChangedFiles+1)
+	data = &EventData{Type: PullRequestEventType, PullRequestNumber: 3}
+	assert.Nil(t, PopulateChangedFiles(p, data))
+	assert.Nil(t, data.ChangedFiles)
+}
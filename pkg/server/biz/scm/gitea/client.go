@@ -0,0 +1,154 @@
+package gitea
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/caicloud/nirvana/log"
+	"github.com/google/go-querystring/query"
+)
+
+// Gitea API docs: https://try.gitea.io/api/swagger . Gogs implements a subset of the same API, see
+// https://github.com/gogs/docs-api .
+
+// apiPath is the path prefix of Gitea APIs.
+const apiPath = "api/v1/"
+
+// Client manages communication with the Gitea API.
+type Client struct {
+	// HTTP client used to communicate with the API.
+	client *http.Client
+
+	// Base URL for API requests, it's the Gitea server address with the API path, for
+	// example, 'https://gitea.com/api/v1/'.
+	baseURL *url.URL
+
+	// Username and password used for basic authentication, they are used only when
+	// token is empty.
+	username string
+	password string
+
+	// Token used to make authenticated API calls.
+	token string
+
+	// User agent used when communicating with the Gitea API.
+	UserAgent string
+}
+
+// ListOpts specifies the optional parameters to various List methods that support pagination.
+type ListOpts struct {
+	Page  int `url:"page,omitempty"`
+	Limit int `url:"limit,omitempty"`
+}
+
+// NewClient returns a Gitea client for the server, the token is used for authentication if it's
+// not empty, otherwise username and password are used.
+func NewClient(client *http.Client, server, username, password, token string) (*Client, error) {
+	base, err := url.Parse(strings.TrimSuffix(server, "/") + "/" + apiPath)
+	if err != nil {
+		return nil, err
+	}
+	if token == "" && username == "" {
+		return nil, fmt.Errorf("the token or username is required for Gitea")
+	}
+	if client == nil {
+		client = http.DefaultClient
+	}
+
+	return &Client{
+		client:    client,
+		baseURL:   base,
+		username:  username,
+		password:  password,
+		token:     token,
+		UserAgent: "continuous-integration/cyclone",
+	}, nil
+}
+
+// NewRequest creates an API request, urlStr is relative to the base URL of the client.
+func (c *Client) NewRequest(method, urlStr string, body interface{}, opt interface{}) (*http.Request, error) {
+	u, err := c.baseURL.Parse(urlStr)
+	if err != nil {
+		return nil, err
+	}
+
+	if opt != nil {
+		q, err := query.Values(opt)
+		if err != nil {
+			return nil, err
+		}
+		u.RawQuery = q.Encode()
+	}
+
+	var buf io.ReadWriter
+	if body != nil {
+		buf = new(bytes.Buffer)
+		enc := json.NewEncoder(buf)
+		enc.SetEscapeHTML(false)
+		if err := enc.Encode(body); err != nil {
+			return nil, err
+		}
+	}
+
+	req, err := http.NewRequest(method, u.String(), buf)
+	if err != nil {
+		return nil, err
+	}
+
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	if c.UserAgent != "" {
+		req.Header.Set("User-Agent", c.UserAgent)
+	}
+	if c.token != "" {
+		req.Header.Set("Authorization", "token "+c.token)
+	} else {
+		req.SetBasicAuth(c.username, c.password)
+	}
+	return req, nil
+}
+
+// Do sends an API request and decodes the response into v.
+func (c *Client) Do(request *http.Request, v interface{}) (*http.Response, error) {
+	resp, err := c.client.Do(request)
+	if err != nil {
+		return resp, err
+	}
+
+	defer func() {
+		if err := resp.Body.Close(); err != nil {
+			log.Errorf("Fail to close response body as: %v", err)
+		}
+	}()
+
+	if resp.StatusCode/100 != 2 {
+		bodyBytes, _ := ioutil.ReadAll(resp.Body)
+		return resp, fmt.Errorf("status: %v, Body: %s", resp.Status, string(bodyBytes))
+	}
+	if v != nil {
+		err = json.NewDecoder(resp.Body).Decode(v)
+	}
+	return resp, err
+}
+
+// get sends a GET request and decodes the response into v.
+func (c *Client) get(urlStr string, opt interface{}, v interface{}) (*http.Response, error) {
+	req, err := c.NewRequest(http.MethodGet, urlStr, nil, opt)
+	if err != nil {
+		return nil, err
+	}
+	return c.Do(req, v)
+}
+
+// hasNextPage checks whether there are more pages of a list response by the 'Link' header. Gogs
+// doesn't paginate some lists, and it never sets the header, so these lists are got in one page.
+func hasNextPage(resp *http.Response) bool {
+	return resp != nil && strings.Contains(resp.Header.Get("Link"), `rel="next"`)
+}
@@ -0,0 +1,229 @@
+package gitea
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"time"
+
+	"github.com/caicloud/nirvana/log"
+
+	"github.com/caicloud/cyclone/pkg/server/apis/v1alpha1"
+	"github.com/caicloud/cyclone/pkg/server/biz/scm"
+)
+
+const (
+	// EventTypeHeader represents the header key for event type of Gitea.
+	EventTypeHeader = "X-Gitea-Event"
+
+	// GogsEventTypeHeader represents the header key for event type of Gogs, Gitea also sends it.
+	GogsEventTypeHeader = "X-Gogs-Event"
+
+	createEvent       = "create"
+	pushEvent         = "push"
+	pullRequestEvent  = "pull_request"
+	issueCommentEvent = "issue_comment"
+	// pullRequestCommentEvent is used by Gitea to subscribe comments on pull requests, they are sent as issue
+	// comment events.
+	pullRequestCommentEvent = "pull_request_comment"
+
+	tagRefTemplate = "refs/tags/%s"
+	// mergeRefTemplate represents reference template for pull request. Gitea doesn't provide merge refs of pull
+	// requests, so the head ref is merged into the target branch when the code is checked out.
+	mergeRefTemplate = "refs/pull/%d/head:%s"
+
+	zeroCommitSHA = "0000000000000000000000000000000000000000"
+)
+
+// PayloadRepository represents the repository in event payloads.
+type PayloadRepository struct {
+	FullName string `json:"full_name"`
+}
+
+// PayloadCommit represents a commit in push event payloads.
+type PayloadCommit struct {
+	ID       string   `json:"id"`
+	Added    []string `json:"added"`
+	Removed  []string `json:"removed"`
+	Modified []string `json:"modified"`
+}
+
+// CreatePayload represents the payload of create events.
+type CreatePayload struct {
+	Ref        string            `json:"ref"`
+	RefType    string            `json:"ref_type"`
+	Repository PayloadRepository `json:"repository"`
+}
+
+// PushPayload represents the payload of push events.
+type PushPayload struct {
+	Ref     string          `json:"ref"`
+	Before  string          `json:"before"`
+	After   string          `json:"after"`
+	Commits []PayloadCommit `json:"commits"`
+	// TotalCommits is the number of pushed commits, commits in the payload are limited by Gitea. It's not
+	// sent by Gogs.
+	TotalCommits int               `json:"total_commits"`
+	Repository   PayloadRepository `json:"repository"`
+}
+
+// PullRequestPayload represents the payload of pull request events.
+type PullRequestPayload struct {
+	Action      string            `json:"action"`
+	Number      int               `json:"number"`
+	PullRequest PullRequest       `json:"pull_request"`
+	Repository  PayloadRepository `json:"repository"`
+}
+
+// IssueCommentPayload represents the payload of issue comment events.
+type IssueCommentPayload struct {
+	Action string `json:"action"`
+	Issue  struct {
+		Number int    `json:"number"`
+		State  string `json:"state"`
+		// PullRequest is not nil if the issue is a pull request.
+		PullRequest *struct{} `json:"pull_request"`
+	} `json:"issue"`
+	Comment struct {
+		Body      string    `json:"body"`
+		CreatedAt time.Time `json:"created_at"`
+	} `json:"comment"`
+	Repository PayloadRepository `json:"repository"`
+}
+
+// ParseEvent parses data from Gitea and Gogs events.
+func ParseEvent(scmCfg *v1alpha1.SCMSource, request *http.Request) *scm.EventData {
+	eventType := request.Header.Get(EventTypeHeader)
+	if eventType == "" {
+		eventType = request.Header.Get(GogsEventTypeHeader)
+	}
+
+	body, err := ioutil.ReadAll(request.Body)
+	if err != nil {
+		log.Errorln(err)
+		return nil
+	}
+
+	switch eventType {
+	case createEvent:
+		payload := &CreatePayload{}
+		if err := json.Unmarshal(body, payload); err != nil {
+			log.Errorf("Failed to parse Gitea create event as %v", err)
+			return nil
+		}
+		if payload.RefType != "tag" {
+			log.Warningf("Skip unsupported ref type %s of Gitea create event, only support create tag event.", payload.RefType)
+			return nil
+		}
+		return &scm.EventData{
+			Type: scm.TagReleaseEventType,
+			Repo: payload.Repository.FullName,
+			Ref:  fmt.Sprintf(tagRefTemplate, payload.Ref),
+		}
+	case pushEvent:
+		payload := &PushPayload{}
+		if err := json.Unmarshal(body, payload); err != nil {
+			log.Errorf("Failed to parse Gitea push event as %v", err)
+			return nil
+		}
+		if payload.After == zeroCommitSHA {
+			log.Warning("Skip unsupported action 'Branch deleted' of Gitea.")
+			return nil
+		}
+		return &scm.EventData{
+			Type:         scm.PushEventType,
+			Repo:         payload.Repository.FullName,
+			Ref:          payload.Ref,
+			Branch:       payload.Ref,
+			CommitSHA:    payload.After,
+			Before:       payload.Before,
+			ChangedFiles: pushChangedFiles(payload),
+		}
+	case pullRequestEvent:
+		payload := &PullRequestPayload{}
+		if err := json.Unmarshal(body, payload); err != nil {
+			log.Errorf("Failed to parse Gitea pull request event as %v", err)
+			return nil
+		}
+		if payload.Action != "opened" && payload.Action != "synchronized" {
+			log.Warningf("Skip unsupported action %s of Gitea pull request event, only support opened and synchronized action.", payload.Action)
+			return nil
+		}
+		pr := payload.PullRequest
+		return &scm.EventData{
+			Type:              scm.PullRequestEventType,
+			Repo:              payload.Repository.FullName,
+			Ref:               fmt.Sprintf(mergeRefTemplate, pr.Number, pr.Base.Ref),
+			CommitSHA:         pr.Head.Sha,
+			Branch:            pr.Base.Ref,
+			PullRequestNumber: pr.Number,
+			CreatedAt:         pr.UpdatedAt,
+		}
+	case issueCommentEvent:
+		payload := &IssueCommentPayload{}
+		if err := json.Unmarshal(body, payload); err != nil {
+			log.Errorf("Failed to parse Gitea issue comment event as %v", err)
+			return nil
+		}
+		if payload.Issue.PullRequest == nil {
+			log.Warningln("Only handle comments on pull requests.")
+			return nil
+		}
+		if payload.Action != "created" {
+			log.Warningln("Only handle comments when they are created.")
+			return nil
+		}
+		if payload.Issue.State != "open" {
+			log.Warningln("Only handle comments on opened pull requests.")
+			return nil
+		}
+
+		pr, err := getPullRequest(scmCfg, payload.Repository.FullName, payload.Issue.Number)
+		if err != nil {
+			log.Errorf("Failed to get pull request %d: %v", payload.Issue.Number, err)
+			return nil
+		}
+		return &scm.EventData{
+			Type:              scm.PullRequestCommentEventType,
+			Repo:              payload.Repository.FullName,
+			Ref:               fmt.Sprintf(mergeRefTemplate, pr.Number, pr.Base.Ref),
+			Comment:           payload.Comment.Body,
+			CommitSHA:         pr.Head.Sha,
+			PullRequestNumber: pr.Number,
+			CreatedAt:         payload.Comment.CreatedAt,
+		}
+	default:
+		log.Warningf("Skip unsupported Gitea event %s", eventType)
+		return nil
+	}
+}
+
+// pushChangedFiles gets changed files from commits in the push event. Gitea limits the number of commits in push
+// events, so nil is returned if there are more commits, and changed files should be got by comparing commits.
+func pushChangedFiles(payload *PushPayload) []string {
+	if len(payload.Commits) == 0 || payload.TotalCommits > len(payload.Commits) {
+		return nil
+	}
+
+	files := []string{}
+	for _, commit := range payload.Commits {
+		files = append(files, commit.Added...)
+		files = append(files, commit.Removed...)
+		files = append(files, commit.Modified...)
+	}
+	return files
+}
+
+func getPullRequest(scmCfg *v1alpha1.SCMSource, repo string, number int) (*PullRequest, error) {
+	if scmCfg == nil {
+		return nil, fmt.Errorf("SCM config is missing")
+	}
+
+	p, err := NewGitea(scmCfg)
+	if err != nil {
+		return nil, err
+	}
+
+	return p.(*Gitea).getPullRequest(repo, number)
+}
@@ -0,0 +1,117 @@
+package gitea
+
+import (
+	"bytes"
+	"io/ioutil"
+	"net/http"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/caicloud/cyclone/pkg/server/apis/v1alpha1"
+	"github.com/caicloud/cyclone/pkg/server/biz/scm"
+)
+
+func newEventRequest(t *testing.T, header, eventType, file string) *http.Request {
+	data, err := ioutil.ReadFile(filepath.Join("testdata", file))
+	assert.Nil(t, err)
+
+	request, err := http.NewRequest(http.MethodPost, "/webhook", bytes.NewReader(data))
+	assert.Nil(t, err)
+	request.Header.Set(header, eventType)
+	return request
+}
+
+func TestParseEvent(t *testing.T) {
+	server, _ := newFixtureServer(t, map[string]fixture{
+		"GET /api/v1/repos/cyclone/cyclone/pulls/3": {File: "pull.json"},
+	})
+	defer server.Close()
+	scmCfg := &v1alpha1.SCMSource{
+		Type:   v1alpha1.Gitea,
+		Server: server.URL,
+		Token:  "token",
+	}
+
+	cases := map[string]struct {
+		request  *http.Request
+		expected *scm.EventData
+	}{
+		"push": {
+			request: newEventRequest(t, EventTypeHeader, "push", "event_push.json"),
+			expected: &scm.EventData{
+				Type:         scm.PushEventType,
+				Repo:         "cyclone/cyclone",
+				Ref:          "refs/heads/master",
+				Branch:       "refs/heads/master",
+				CommitSHA:    "6f4b3e0c6a0b3a1a4f2e8c1d9b7a5e3c1f0d2b4a",
+				Before:       "b2d1f1c0b1f5a5e1f8b51bfc2a6c8f4e0f5a7c1d",
+				ChangedFiles: []string{"docs/gitea.md", "README.md"},
+			},
+		},
+		"gogs create tag": {
+			request: newEventRequest(t, GogsEventTypeHeader, "create", "event_create.json"),
+			expected: &scm.EventData{
+				Type: scm.TagReleaseEventType,
+				Repo: "cyclone/cyclone",
+				Ref:  "refs/tags/v1.0.0",
+			},
+		},
+		"pull request": {
+			request: newEventRequest(t, EventTypeHeader, "pull_request", "event_pull_request.json"),
+			expected: &scm.EventData{
+				Type:              scm.PullRequestEventType,
+				Repo:              "cyclone/cyclone",
+				Ref:               "refs/pull/3/head:master",
+				Branch:            "master",
+				CommitSHA:         "6f4b3e0c6a0b3a1a4f2e8c1d9b7a5e3c1f0d2b4a",
+				PullRequestNumber: 3,
+				CreatedAt:         time.Date(2020, 3, 1, 8, 0, 0, 0, time.UTC),
+			},
+		},
+		"pull request comment": {
+			request: newEventRequest(t, EventTypeHeader, "issue_comment", "event_issue_comment.json"),
+			expected: &scm.EventData{
+				Type:              scm.PullRequestCommentEventType,
+				Repo:              "cyclone/cyclone",
+				Ref:               "refs/pull/3/head:master",
+				Comment:           "/rerun",
+				CommitSHA:         "6f4b3e0c6a0b3a1a4f2e8c1d9b7a5e3c1f0d2b4a",
+				PullRequestNumber: 3,
+				CreatedAt:         time.Date(2020, 3, 1, 9, 0, 0, 0, time.UTC),
+			},
+		},
+		"unsupported event": {
+			request: newEventRequest(t, EventTypeHeader, "release", "event_create.json"),
+		},
+		"unsupported ref type": {
+			request: newEventRequest(t, EventTypeHeader, "create", "event_push.json"),
+		},
+	}
+
+	for name, c := range cases {
+		data := ParseEvent(scmCfg, c.request)
+		if c.expected == nil {
+			assert.Nil(t, data, name)
+			continue
+		}
+		if assert.NotNil(t, data, name) {
+			data.CreatedAt = data.CreatedAt.UTC()
+			assert.Equal(t, c.expected, data, name)
+		}
+	}
+}
+
+func TestPushChangedFiles(t *testing.T) {
+	commits := []PayloadCommit{
+		{Added: []string{"a"}, Modified: []string{"b"}},
+		{Removed: []string{"c"}},
+	}
+
+	assert.Equal(t, []string{"a", "b", "c"}, pushChangedFiles(&PushPayload{Commits: commits}))
+	assert.Equal(t, []string{"a", "b", "c"}, pushChangedFiles(&PushPayload{Commits: commits, TotalCommits: 2}))
+	assert.Nil(t, pushChangedFiles(&PushPayload{Commits: commits, TotalCommits: 3}))
+	assert.Nil(t, pushChangedFiles(&PushPayload{}))
+}
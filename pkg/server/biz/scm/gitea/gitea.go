@@ -0,0 +1,624 @@
+package gitea
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/caicloud/nirvana/log"
+
+	c_v1alpha1 "github.com/caicloud/cyclone/pkg/apis/cyclone/v1alpha1"
+	"github.com/caicloud/cyclone/pkg/server/apis/v1alpha1"
+	"github.com/caicloud/cyclone/pkg/server/biz/scm"
+	"github.com/caicloud/cyclone/pkg/util/cerr"
+)
+
+const (
+	// tokenName is the name of access tokens generated for Cyclone.
+	tokenName = "cyclone"
+
+	// hookType is the type of webhooks created by Cyclone. Both Gitea and Gogs support hooks of type 'gogs',
+	// and Gitea sends the same payloads for them as for hooks of type 'gitea'.
+	hookType = "gogs"
+
+	// treePerPage is the number of entries per page when getting git trees.
+	treePerPage = 1000
+)
+
+// tokenScopes are scopes of generated access tokens, they are ignored by Gitea before 1.19 and Gogs.
+var tokenScopes = []string{"write:repository", "read:user"}
+
+func init() {
+	if err := scm.RegisterProvider(v1alpha1.Gitea, NewGitea); err != nil {
+		log.Errorln(err)
+	}
+}
+
+// Gitea represents the SCM provider of Gitea, it also works with Gogs.
+type Gitea struct {
+	scmCfg *v1alpha1.SCMSource
+	client *Client
+}
+
+// NewGitea news Gitea client.
+func NewGitea(scmCfg *v1alpha1.SCMSource) (scm.Provider, error) {
+	if scmCfg.Token == "" && scmCfg.Password == "" {
+		return nil, cerr.ErrorParamNotFound.Error("password or token")
+	}
+
+	client, err := NewClient(&http.Client{}, scmCfg.Server, scmCfg.User, scmCfg.Password, scmCfg.Token)
+	if err != nil {
+		log.Errorf("fail to new Gitea client for %s as %v", scmCfg.Server, err)
+		return nil, err
+	}
+
+	return &Gitea{scmCfg: scmCfg, client: client}, nil
+}
+
+// GetToken gets the token by the username and password of SCM config. The token generated before is deleted,
+// as tokens can not be got once they are created.
+func (g *Gitea) GetToken() (string, error) {
+	if len(g.scmCfg.User) == 0 || len(g.scmCfg.Password) == 0 {
+		return "", fmt.Errorf("Gitea username or password is missing")
+	}
+
+	user := url.PathEscape(g.scmCfg.User)
+	opt := ListOpts{Limit: scm.ListOptPerPage}
+	for {
+		var tokens []AccessToken
+		resp, err := g.client.get(fmt.Sprintf("users/%s/tokens", user), &opt, &tokens)
+		if err != nil {
+			return "", convertGiteaError(err, resp)
+		}
+
+		for _, t := range tokens {
+			if t.Name != tokenName {
+				continue
+			}
+
+			req, err := g.client.NewRequest(http.MethodDelete, fmt.Sprintf("users/%s/tokens/%d", user, t.ID), nil, nil)
+			if err != nil {
+				return "", err
+			}
+			if resp, err := g.client.Do(req, nil); err != nil {
+				log.Errorf("Fail to delete the token %s as %v", tokenName, err)
+				return "", convertGiteaError(err, resp)
+			}
+		}
+
+		if !hasNextPage(resp) {
+			break
+		}
+		opt.Page = nextPage(opt.Page)
+	}
+
+	req, err := g.client.NewRequest(http.MethodPost, fmt.Sprintf("users/%s/tokens", user), AccessToken{
+		Name:   tokenName,
+		Scopes: tokenScopes,
+	}, nil)
+	if err != nil {
+		return "", err
+	}
+	token := &AccessToken{}
+	if resp, err := g.client.Do(req, token); err != nil {
+		return "", convertGiteaError(err, resp)
+	}
+
+	return token.Sha1, nil
+}
+
+// CheckToken checks whether the token has the authority of repo by trying ListRepos with the token.
+func (g *Gitea) CheckToken() error {
+	if _, err := g.listReposInner(false); err != nil {
+		return err
+	}
+	return nil
+}
+
+// ListRepos lists the repos by the SCM config.
+func (g *Gitea) ListRepos() ([]scm.Repository, error) {
+	return g.listReposInner(true)
+}
+
+// listReposInner lists the repos by the SCM config,
+// list all repos while the parameter 'listAll' is true,
+// otherwise, list repos by default 'ListOptPerPage' number.
+func (g *Gitea) listReposInner(listAll bool) ([]scm.Repository, error) {
+	opt := ListOpts{Limit: scm.ListOptPerPage}
+	var allRepos []Repository
+	for {
+		var repos []Repository
+		resp, err := g.client.get("user/repos", &opt, &repos)
+		if err != nil {
+			return nil, convertGiteaError(err, resp)
+		}
+
+		allRepos = append(allRepos, repos...)
+		if !hasNextPage(resp) || !listAll {
+			break
+		}
+		opt.Page = nextPage(opt.Page)
+	}
+
+	scmRepos := make([]scm.Repository, len(allRepos))
+	for i, repo := range allRepos {
+		scmRepos[i].Name = repo.FullName
+		scmRepos[i].URL = repo.CloneURL
+	}
+
+	return scmRepos, nil
+}
+
+// ListBranches lists the branches for specified repo.
+func (g *Gitea) ListBranches(repo string) ([]string, error) {
+	owner, name, err := parseRepo(g.scmCfg, repo)
+	if err != nil {
+		log.Error(err)
+		return nil, err
+	}
+
+	opt := ListOpts{Limit: scm.ListOptPerPage}
+	var branchNames []string
+	for {
+		var branches []Branch
+		resp, err := g.client.get(fmt.Sprintf("repos/%s/%s/branches", owner, name), &opt, &branches)
+		if err != nil {
+			log.Errorf("Fail to list branches for %s as %v", repo, err)
+			return nil, convertGiteaError(err, resp)
+		}
+
+		for _, b := range branches {
+			branchNames = append(branchNames, b.Name)
+		}
+		if !hasNextPage(resp) {
+			break
+		}
+		opt.Page = nextPage(opt.Page)
+	}
+
+	return branchNames, nil
+}
+
+// ListTags lists the tags for specified repo.
+func (g *Gitea) ListTags(repo string) ([]string, error) {
+	owner, name, err := parseRepo(g.scmCfg, repo)
+	if err != nil {
+		log.Error(err)
+		return nil, err
+	}
+
+	opt := ListOpts{Limit: scm.ListOptPerPage}
+	var tagNames []string
+	for {
+		var tags []Tag
+		resp, err := g.client.get(fmt.Sprintf("repos/%s/%s/tags", owner, name), &opt, &tags)
+		if err != nil {
+			log.Errorf("Fail to list tags for %s as %v", repo, err)
+			return nil, convertGiteaError(err, resp)
+		}
+
+		for _, t := range tags {
+			tagNames = append(tagNames, t.Name)
+		}
+		if !hasNextPage(resp) {
+			break
+		}
+		opt.Page = nextPage(opt.Page)
+	}
+
+	return tagNames, nil
+}
+
+// ListRefs lists the branches and tags with their commit SHAs for specified repo.
+func (g *Gitea) ListRefs(repo string) ([]scm.Ref, error) {
+	owner, name, err := parseRepo(g.scmCfg, repo)
+	if err != nil {
+		log.Error(err)
+		return nil, err
+	}
+
+	var refs []scm.Ref
+	opt := ListOpts{Limit: scm.ListOptPerPage}
+	for {
+		var branches []Branch
+		resp, err := g.client.get(fmt.Sprintf("repos/%s/%s/branches", owner, name), &opt, &branches)
+		if err != nil {
+			log.Errorf("Fail to list branches for %s as %v", repo, err)
+			return nil, convertGiteaError(err, resp)
+		}
+
+		for _, b := range branches {
+			refs = append(refs, scm.Ref{Name: scm.BranchRef(b.Name), SHA: b.Commit.GetSHA()})
+		}
+		if !hasNextPage(resp) {
+			break
+		}
+		opt.Page = nextPage(opt.Page)
+	}
+
+	opt = ListOpts{Limit: scm.ListOptPerPage}
+	for {
+		var tags []Tag
+		resp, err := g.client.get(fmt.Sprintf("repos/%s/%s/tags", owner, name), &opt, &tags)
+		if err != nil {
+			log.Errorf("Fail to list tags for %s as %v", repo, err)
+			return nil, convertGiteaError(err, resp)
+		}
+
+		for _, t := range tags {
+			refs = append(refs, scm.Ref{Name: scm.TagRef(t.Name), SHA: t.Commit.GetSHA()})
+		}
+		if !hasNextPage(resp) {
+			break
+		}
+		opt.Page = nextPage(opt.Page)
+	}
+
+	return refs, nil
+}
+
+// ListPullRequests lists the pull requests for specified repo.
+func (g *Gitea) ListPullRequests(repo, state string) ([]scm.PullRequest, error) {
+	// Gitea pr state: open, closed, all
+	switch state {
+	case "open", "closed", "all":
+	default:
+		return nil, cerr.ErrorUnsupported.Error("Gitea pull request state", state)
+	}
+
+	owner, name, err := parseRepo(g.scmCfg, repo)
+	if err != nil {
+		log.Error(err)
+		return nil, err
+	}
+
+	opt := PullRequestListOpts{
+		ListOpts: ListOpts{Limit: scm.ListOptPerPage},
+		State:    state,
+	}
+	var allPRs []scm.PullRequest
+	for {
+		var prs []PullRequest
+		resp, err := g.client.get(fmt.Sprintf("repos/%s/%s/pulls", owner, name), &opt, &prs)
+		if err != nil {
+			log.Errorf("Fail to list pull requests for %s as %v", repo, err)
+			return nil, convertGiteaError(err, resp)
+		}
+
+		for _, pr := range prs {
+			allPRs = append(allPRs, scm.PullRequest{
+				ID:           pr.Number,
+				Title:        pr.Title,
+				Description:  pr.Body,
+				State:        pr.State,
+				TargetBranch: pr.Base.Ref,
+				Ref:          fmt.Sprintf(mergeRefTemplate, pr.Number, pr.Base.Ref),
+				HeadSHA:      pr.Head.Sha,
+			})
+		}
+		if !hasNextPage(resp) {
+			break
+		}
+		opt.Page = nextPage(opt.Page)
+	}
+
+	return allPRs, nil
+}
+
+// ListDockerfiles lists the Dockerfiles in the default branch of specified repo.
+func (g *Gitea) ListDockerfiles(repo string) ([]string, error) {
+	owner, name, err := parseRepo(g.scmCfg, repo)
+	if err != nil {
+		log.Error(err)
+		return nil, err
+	}
+
+	r := &Repository{}
+	resp, err := g.client.get(fmt.Sprintf("repos/%s/%s", owner, name), nil, r)
+	if err != nil {
+		log.Errorf("Fail to get repo %s as %v", repo, err)
+		return nil, convertGiteaError(err, resp)
+	}
+
+	opt := TreeOpts{Recursive: true, PerPage: treePerPage}
+	dockerfiles := []string{}
+	for {
+		tree := &Tree{}
+		u := fmt.Sprintf("repos/%s/%s/git/trees/%s", owner, name, url.PathEscape(r.DefaultBranch))
+		resp, err := g.client.get(u, &opt, tree)
+		if err != nil {
+			log.Errorf("Fail to list files for %s as %v", repo, err)
+			return nil, convertGiteaError(err, resp)
+		}
+
+		for _, e := range tree.Entries {
+			if e.Type == "blob" && scm.IsDockerfile(e.Path) {
+				dockerfiles = append(dockerfiles, e.Path)
+			}
+		}
+		if !tree.Truncated {
+			break
+		}
+		opt.Page = nextPage(tree.Page)
+	}
+
+	return dockerfiles, nil
+}
+
+// CreateStatus generate a new status for repository.
+func (g *Gitea) CreateStatus(status c_v1alpha1.StatusPhase, targetURL, repoURL, commitSHA string) error {
+	switch status {
+	case c_v1alpha1.StatusRunning, c_v1alpha1.StatusSucceeded, c_v1alpha1.StatusFailed, c_v1alpha1.StatusCancelled:
+	default:
+		err := fmt.Errorf("not supported state:%s", status)
+		log.Error(err)
+		return err
+	}
+
+	return g.CreateCommitStatus(repoURL, commitSHA, &scm.CommitStatus{
+		Phase:     status,
+		Context:   scm.StatusContext,
+		TargetURL: targetURL,
+	})
+}
+
+// CreateCommitStatus creates the commit status with the given context.
+func (g *Gitea) CreateCommitStatus(repoURL, commitSHA string, status *scm.CommitStatus) error {
+	// Gitea: pending, success, error, failure and warning.
+	state := "pending"
+	switch status.Phase {
+	case c_v1alpha1.StatusSucceeded, c_v1alpha1.StatusSkipped:
+		state = "success"
+	case c_v1alpha1.StatusFailed, c_v1alpha1.StatusCancelled:
+		state = "failure"
+	}
+	description := status.Description
+	if description == "" {
+		description = scm.StatusDescription(status.Phase)
+	}
+	context := status.Context
+	if context == "" {
+		context = scm.StatusContext
+	}
+
+	owner, name := scm.ParseRepo(repoURL)
+	req, err := g.client.NewRequest(http.MethodPost, fmt.Sprintf("repos/%s/%s/statuses/%s", owner, name, commitSHA), &StatusReq{
+		State:       state,
+		TargetURL:   status.TargetURL,
+		Description: description,
+		Context:     context,
+	}, nil)
+	if err != nil {
+		return err
+	}
+
+	resp, err := g.client.Do(req, nil)
+	return convertGiteaError(err, resp)
+}
+
+// CreatePullRequestComment comments on the pull request, pull requests share comments with issues in Gitea.
+func (g *Gitea) CreatePullRequestComment(repo string, number int, body string) error {
+	owner, name := scm.ParseRepo(repo)
+	req, err := g.client.NewRequest(http.MethodPost, fmt.Sprintf("repos/%s/%s/issues/%d/comments", owner, name, number), &CommentReq{
+		Body: body,
+	}, nil)
+	if err != nil {
+		return err
+	}
+
+	resp, err := g.client.Do(req, nil)
+	return convertGiteaError(err, resp)
+}
+
+// GetPullRequestSHA gets latest commit SHA of pull request.
+func (g *Gitea) GetPullRequestSHA(repoURL string, number int) (string, error) {
+	pr, err := g.getPullRequest(repoURL, number)
+	if err != nil {
+		log.Error(err)
+		return "", err
+	}
+
+	return pr.Head.Sha, nil
+}
+
+func (g *Gitea) getPullRequest(repo string, number int) (*PullRequest, error) {
+	owner, name := scm.ParseRepo(repo)
+	pr := &PullRequest{}
+	resp, err := g.client.get(fmt.Sprintf("repos/%s/%s/pulls/%d", owner, name, number), nil, pr)
+	if err != nil {
+		return nil, convertGiteaError(err, resp)
+	}
+
+	return pr, nil
+}
+
+// ListPullRequestFiles lists files changed by the pull request.
+func (g *Gitea) ListPullRequestFiles(repo string, number int) ([]string, error) {
+	owner, name := scm.ParseRepo(repo)
+	opt := ListOpts{Limit: scm.ListOptPerPage}
+	files := []string{}
+	for {
+		var changes []ChangedFile
+		resp, err := g.client.get(fmt.Sprintf("repos/%s/%s/pulls/%d/files", owner, name, number), &opt, &changes)
+		if err != nil {
+			log.Errorf("Fail to list files of pull request %d for %s as %v", number, repo, err)
+			return nil, convertGiteaError(err, resp)
+		}
+
+		for _, c := range changes {
+			if c.PreviousFilename != "" {
+				files = append(files, c.PreviousFilename)
+			}
+			files = append(files, c.Filename)
+		}
+		if !hasNextPage(resp) {
+			break
+		}
+		opt.Page = nextPage(opt.Page)
+	}
+
+	return files, nil
+}
+
+// CompareCommits lists files changed between the base and head commits.
+func (g *Gitea) CompareCommits(repo, base, head string) ([]string, error) {
+	owner, name := scm.ParseRepo(repo)
+	compare := &Compare{}
+	resp, err := g.client.get(fmt.Sprintf("repos/%s/%s/compare/%s...%s", owner, name, base, head), nil, compare)
+	if err != nil {
+		log.Errorf("Fail to compare commits %s...%s for %s as %v", base, head, repo, err)
+		return nil, convertGiteaError(err, resp)
+	}
+
+	files := []string{}
+	for _, c := range compare.Commits {
+		for _, f := range c.Files {
+			files = append(files, f.Filename)
+		}
+	}
+
+	return files, nil
+}
+
+// CreateWebhook creates webhook for specified repo.
+func (g *Gitea) CreateWebhook(repo string, webhook *scm.Webhook) error {
+	if webhook == nil || len(webhook.URL) == 0 || len(webhook.Events) == 0 {
+		return fmt.Errorf("the webhook %v is not correct", webhook)
+	}
+
+	_, err := g.GetWebhook(repo, webhook.URL)
+	if err != nil {
+		if !cerr.ErrorContentNotFound.Derived(err) {
+			return err
+		}
+
+		hook := &Hook{
+			Type: hookType,
+			Config: map[string]string{
+				"url":          webhook.URL,
+				"content_type": "json",
+			},
+			Events: convertToGiteaEvents(webhook.Events),
+			Active: true,
+		}
+		owner, name := scm.ParseRepo(repo)
+		req, err := g.client.NewRequest(http.MethodPost, fmt.Sprintf("repos/%s/%s/hooks", owner, name), hook, nil)
+		if err != nil {
+			return err
+		}
+		resp, err := g.client.Do(req, nil)
+		if err != nil {
+			log.Errorf("Create Webhook error: %v", err)
+		}
+		return convertGiteaError(err, resp)
+	}
+
+	log.Warningf("Webhook already existed: %+v", webhook)
+	return nil
+}
+
+// convertToGiteaEvents converts the defined event types to Gitea event types.
+func convertToGiteaEvents(events []scm.EventType) []string {
+	var ge []string
+	for _, e := range events {
+		switch e {
+		case scm.PullRequestEventType:
+			ge = append(ge, pullRequestEvent)
+		case scm.PullRequestCommentEventType:
+			ge = append(ge, issueCommentEvent, pullRequestCommentEvent)
+		case scm.PushEventType:
+			ge = append(ge, pushEvent)
+		case scm.TagReleaseEventType:
+			ge = append(ge, createEvent)
+		default:
+			log.Errorf("The event type %s is not supported, will be ignored", e)
+		}
+	}
+
+	return ge
+}
+
+// DeleteWebhook deletes webhook from specified repo.
+func (g *Gitea) DeleteWebhook(repo string, webhookURL string) error {
+	hook, err := g.GetWebhook(repo, webhookURL)
+	if err != nil {
+		return err
+	}
+
+	owner, name := scm.ParseRepo(repo)
+	req, err := g.client.NewRequest(http.MethodDelete, fmt.Sprintf("repos/%s/%s/hooks/%d", owner, name, hook.ID), nil, nil)
+	if err != nil {
+		return err
+	}
+	if resp, err := g.client.Do(req, nil); err != nil {
+		log.Errorf("delete hook %d for %s/%s error: %v", hook.ID, owner, name, err)
+		return convertGiteaError(err, resp)
+	}
+	return nil
+}
+
+// GetWebhook gets webhook from specified repo.
+func (g *Gitea) GetWebhook(repo string, webhookURL string) (*Hook, error) {
+	owner, name := scm.ParseRepo(repo)
+	opt := ListOpts{Limit: scm.ListOptPerPage}
+	for {
+		var hooks []Hook
+		resp, err := g.client.get(fmt.Sprintf("repos/%s/%s/hooks", owner, name), &opt, &hooks)
+		if err != nil {
+			return nil, convertGiteaError(err, resp)
+		}
+
+		for _, hook := range hooks {
+			if strings.HasPrefix(hook.Config["url"], webhookURL) {
+				return &hook, nil
+			}
+		}
+		if !hasNextPage(resp) {
+			break
+		}
+		opt.Page = nextPage(opt.Page)
+	}
+
+	return nil, cerr.ErrorContentNotFound.Error(fmt.Sprintf("webhook url %s", webhookURL))
+}
+
+// nextPage returns the page after the current one, pages start from 1 and 0 means the first page.
+func nextPage(page int) int {
+	if page == 0 {
+		page = 1
+	}
+	return page + 1
+}
+
+func parseRepo(scmCfg *v1alpha1.SCMSource, repo string) (string, string, error) {
+	owner := scmCfg.User
+	if strings.Contains(repo, "/") {
+		parts := strings.Split(repo, "/")
+		if len(parts) != 2 {
+			return owner, repo, fmt.Errorf("invalid repo %s, must in format of '{owner}/{repo}'", repo)
+		}
+		owner, repo = parts[0], parts[1]
+	}
+	return owner, repo, nil
+}
+
+func convertGiteaError(err error, resp *http.Response) error {
+	if err == nil {
+		return nil
+	}
+
+	if resp != nil && resp.StatusCode == http.StatusInternalServerError {
+		return cerr.ErrorExternalSystemError.Error("Gitea", err)
+	}
+
+	if resp != nil && resp.StatusCode == http.StatusUnauthorized {
+		return cerr.ErrorExternalAuthorizationFailed.Error(err)
+	}
+
+	if resp != nil && resp.StatusCode == http.StatusForbidden {
+		return cerr.ErrorExternalAuthenticationFailed.Error(err)
+	}
+
+	return cerr.AutoAnalyse(err)
+}
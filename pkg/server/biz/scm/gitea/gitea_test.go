@@ -0,0 +1,319 @@
+package gitea
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	c_v1alpha1 "github.com/caicloud/cyclone/pkg/apis/cyclone/v1alpha1"
+	"github.com/caicloud/cyclone/pkg/server/apis/v1alpha1"
+	"github.com/caicloud/cyclone/pkg/server/biz/scm"
+)
+
+// fixture is a recorded response of Gitea API.
+type fixture struct {
+	// File is the response body in testdata, empty file means no body.
+	File string
+	// Next indicates whether there is a next page.
+	Next bool
+}
+
+// recordedRequest is a request received by the fixture server.
+type recordedRequest struct {
+	Method string
+	URI    string
+	Auth   string
+	Body   []byte
+}
+
+// newFixtureServer creates a server responding recorded fixtures. Keys of routes are '<method> <request URI>',
+// request URIs with the query are matched first, and then those without the query. 404 is responded if no
+// fixture matches.
+func newFixtureServer(t *testing.T, routes map[string]fixture) (*httptest.Server, *[]recordedRequest) {
+	var requests []recordedRequest
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := ioutil.ReadAll(r.Body)
+		requests = append(requests, recordedRequest{
+			Method: r.Method,
+			URI:    r.URL.RequestURI(),
+			Auth:   r.Header.Get("Authorization"),
+			Body:   body,
+		})
+
+		f, ok := routes[r.Method+" "+r.URL.RequestURI()]
+		if !ok {
+			f, ok = routes[r.Method+" "+r.URL.Path]
+		}
+		if !ok {
+			http.NotFound(w, r)
+			return
+		}
+
+		if f.Next {
+			w.Header().Set("Link", fmt.Sprintf(`<%s?page=2>; rel="next"`, r.URL.Path))
+		}
+		if f.File == "" {
+			w.WriteHeader(http.StatusNoContent)
+			return
+		}
+		data, err := ioutil.ReadFile(filepath.Join("testdata", f.File))
+		if err != nil {
+			t.Fatalf("read fixture %s error: %v", f.File, err)
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write(data)
+	}))
+
+	return server, &requests
+}
+
+func newTestGitea(t *testing.T, server *httptest.Server) *Gitea {
+	p, err := NewGitea(&v1alpha1.SCMSource{
+		Type:   v1alpha1.Gitea,
+		Server: server.URL,
+		User:   "cyclone",
+		Token:  "token",
+	})
+	assert.Nil(t, err)
+	return p.(*Gitea)
+}
+
+func TestListRepos(t *testing.T) {
+	server, requests := newFixtureServer(t, map[string]fixture{
+		"GET /api/v1/user/repos?limit=100":        {File: "repos.json", Next: true},
+		"GET /api/v1/user/repos?limit=100&page=2": {File: "repos_page2.json"},
+	})
+	defer server.Close()
+	g := newTestGitea(t, server)
+
+	repos, err := g.ListRepos()
+	assert.Nil(t, err)
+	assert.Equal(t, []scm.Repository{
+		{Name: "cyclone/cyclone", URL: "https://gitea.example.com/cyclone/cyclone.git"},
+		{Name: "cyclone/website", URL: "https://gitea.example.com/cyclone/website.git"},
+	}, repos)
+	assert.Equal(t, 2, len(*requests))
+	assert.Equal(t, "token token", (*requests)[0].Auth)
+
+	assert.Nil(t, g.CheckToken())
+	assert.Equal(t, 3, len(*requests))
+}
+
+func TestListBranchesAndTags(t *testing.T) {
+	server, _ := newFixtureServer(t, map[string]fixture{
+		"GET /api/v1/repos/cyclone/cyclone/branches": {File: "branches.json"},
+		"GET /api/v1/repos/cyclone/cyclone/tags":     {File: "tags.json"},
+	})
+	defer server.Close()
+	g := newTestGitea(t, server)
+
+	branches, err := g.ListBranches("cyclone/cyclone")
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"master", "develop"}, branches)
+
+	// Owner defaults to the user of SCM config.
+	tags, err := g.ListTags("cyclone")
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"v1.0.0"}, tags)
+
+	_, err = g.ListBranches("cyclone/cyclone/cyclone")
+	assert.NotNil(t, err)
+
+	refs, err := g.ListRefs("cyclone/cyclone")
+	assert.Nil(t, err)
+	assert.Equal(t, []scm.Ref{
+		{Name: "refs/heads/master", SHA: "b2d1f1c0b1f5a5e1f8b51bfc2a6c8f4e0f5a7c1d"},
+		{Name: "refs/heads/develop", SHA: "6f4b3e0c6a0b3a1a4f2e8c1d9b7a5e3c1f0d2b4a"},
+		{Name: "refs/tags/v1.0.0", SHA: "b2d1f1c0b1f5a5e1f8b51bfc2a6c8f4e0f5a7c1d"},
+	}, refs)
+}
+
+func TestListPullRequests(t *testing.T) {
+	server, requests := newFixtureServer(t, map[string]fixture{
+		"GET /api/v1/repos/cyclone/cyclone/pulls": {File: "pulls.json"},
+	})
+	defer server.Close()
+	g := newTestGitea(t, server)
+
+	prs, err := g.ListPullRequests("cyclone/cyclone", "open")
+	assert.Nil(t, err)
+	assert.Equal(t, []scm.PullRequest{{
+		ID:           3,
+		Title:        "Add Gitea support",
+		Description:  "Support Gitea as SCM.",
+		State:        "open",
+		TargetBranch: "master",
+		Ref:          "refs/pull/3/head:master",
+		HeadSHA:      "6f4b3e0c6a0b3a1a4f2e8c1d9b7a5e3c1f0d2b4a",
+	}}, prs)
+	assert.Equal(t, "/api/v1/repos/cyclone/cyclone/pulls?limit=100&state=open", (*requests)[0].URI)
+
+	_, err = g.ListPullRequests("cyclone/cyclone", "merged")
+	assert.NotNil(t, err)
+}
+
+func TestListDockerfiles(t *testing.T) {
+	server, _ := newFixtureServer(t, map[string]fixture{
+		"GET /api/v1/repos/cyclone/cyclone":                                                      {File: "repo.json"},
+		"GET /api/v1/repos/cyclone/cyclone/git/trees/master?per_page=1000&recursive=true":        {File: "tree.json"},
+		"GET /api/v1/repos/cyclone/cyclone/git/trees/master?page=2&per_page=1000&recursive=true": {File: "tree_page2.json"},
+	})
+	defer server.Close()
+	g := newTestGitea(t, server)
+
+	dockerfiles, err := g.ListDockerfiles("cyclone/cyclone")
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"build/Dockerfile", "Dockerfile.dev"}, dockerfiles)
+}
+
+func TestCreateStatus(t *testing.T) {
+	server, requests := newFixtureServer(t, map[string]fixture{
+		"POST /api/v1/repos/cyclone/cyclone/statuses/6f4b3e0": {},
+	})
+	defer server.Close()
+	g := newTestGitea(t, server)
+
+	err := g.CreateStatus(c_v1alpha1.StatusSucceeded, "https://cyclone.example.com/wfr", "cyclone/cyclone", "6f4b3e0")
+	assert.Nil(t, err)
+
+	status := &StatusReq{}
+	assert.Nil(t, json.Unmarshal((*requests)[0].Body, status))
+	assert.Equal(t, &StatusReq{
+		State:       "success",
+		TargetURL:   "https://cyclone.example.com/wfr",
+		Description: "Cyclone CI passed.",
+		Context:     scm.StatusContext,
+	}, status)
+
+	assert.NotNil(t, g.CreateStatus(c_v1alpha1.StatusWaiting, "", "cyclone/cyclone", "6f4b3e0"))
+	assert.NotNil(t, g.CreateStatus(c_v1alpha1.StatusFailed, "", "cyclone/cyclone", "unknown"))
+
+	err = g.CreateCommitStatus("cyclone/cyclone", "6f4b3e0", &scm.CommitStatus{
+		Phase:   c_v1alpha1.StatusPending,
+		Context: scm.StageStatusContext("build"),
+	})
+	assert.Nil(t, err)
+	status = &StatusReq{}
+	assert.Nil(t, json.Unmarshal((*requests)[len(*requests)-1].Body, status))
+	assert.Equal(t, &StatusReq{
+		State:       "pending",
+		Description: "Cyclone CI is pending.",
+		Context:     "continuous-integration/cyclone/build",
+	}, status)
+}
+
+func TestCreatePullRequestComment(t *testing.T) {
+	server, requests := newFixtureServer(t, map[string]fixture{
+		"POST /api/v1/repos/cyclone/cyclone/issues/3/comments": {},
+	})
+	defer server.Close()
+	g := newTestGitea(t, server)
+
+	assert.Nil(t, g.CreatePullRequestComment("cyclone/cyclone", 3, "Cyclone CI passed."))
+	comment := &CommentReq{}
+	assert.Nil(t, json.Unmarshal((*requests)[0].Body, comment))
+	assert.Equal(t, "Cyclone CI passed.", comment.Body)
+
+	assert.NotNil(t, g.CreatePullRequestComment("cyclone/cyclone", 4, "Cyclone CI passed."))
+}
+
+func TestPullRequestChanges(t *testing.T) {
+	server, _ := newFixtureServer(t, map[string]fixture{
+		"GET /api/v1/repos/cyclone/cyclone/pulls/3":       {File: "pull.json"},
+		"GET /api/v1/repos/cyclone/cyclone/pulls/3/files": {File: "pull_files.json"},
+		"GET /api/v1/repos/cyclone/cyclone/compare/b2d1f1c0b1f5a5e1f8b51bfc2a6c8f4e0f5a7c1d...6f4b3e0c6a0b3a1a4f2e8c1d9b7a5e3c1f0d2b4a": {File: "compare.json"},
+	})
+	defer server.Close()
+	g := newTestGitea(t, server)
+
+	sha, err := g.GetPullRequestSHA("cyclone/cyclone", 3)
+	assert.Nil(t, err)
+	assert.Equal(t, "6f4b3e0c6a0b3a1a4f2e8c1d9b7a5e3c1f0d2b4a", sha)
+
+	files, err := g.ListPullRequestFiles("cyclone/cyclone", 3)
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"pkg/scm/gitea.go", "docs/old.md", "docs/scm.md"}, files)
+
+	files, err = g.CompareCommits("cyclone/cyclone", "b2d1f1c0b1f5a5e1f8b51bfc2a6c8f4e0f5a7c1d", "6f4b3e0c6a0b3a1a4f2e8c1d9b7a5e3c1f0d2b4a")
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"README.md", "build/Dockerfile"}, files)
+
+	_, err = g.GetPullRequestSHA("cyclone/cyclone", 4)
+	assert.NotNil(t, err)
+}
+
+func TestWebhook(t *testing.T) {
+	server, requests := newFixtureServer(t, map[string]fixture{
+		"GET /api/v1/repos/cyclone/cyclone/hooks":      {File: "hooks.json"},
+		"POST /api/v1/repos/cyclone/cyclone/hooks":     {File: "hooks.json"},
+		"DELETE /api/v1/repos/cyclone/cyclone/hooks/7": {},
+	})
+	defer server.Close()
+	g := newTestGitea(t, server)
+
+	existed := "https://cyclone.example.com/apis/v1alpha1/tenants/devops/webhook?eventType=SCM&integration=gitea"
+	err := g.CreateWebhook("cyclone/cyclone", &scm.Webhook{
+		URL:    existed,
+		Events: []scm.EventType{scm.PushEventType},
+	})
+	assert.Nil(t, err)
+	assert.Equal(t, 1, len(*requests))
+
+	err = g.CreateWebhook("cyclone/cyclone", &scm.Webhook{
+		URL:    "https://cyclone.example.com/apis/v1alpha1/tenants/devops/webhook?eventType=SCM&integration=new",
+		Events: []scm.EventType{scm.PushEventType, scm.TagReleaseEventType, scm.PullRequestEventType, scm.PullRequestCommentEventType},
+	})
+	assert.Nil(t, err)
+	assert.Equal(t, 3, len(*requests))
+	hook := &Hook{}
+	assert.Nil(t, json.Unmarshal((*requests)[2].Body, hook))
+	assert.Equal(t, hookType, hook.Type)
+	assert.Equal(t, "json", hook.Config["content_type"])
+	assert.Equal(t, []string{"push", "create", "pull_request", "issue_comment", "pull_request_comment"}, hook.Events)
+	assert.True(t, hook.Active)
+
+	assert.NotNil(t, g.CreateWebhook("cyclone/cyclone", &scm.Webhook{URL: existed}))
+
+	assert.Nil(t, g.DeleteWebhook("cyclone/cyclone", existed))
+	assert.Equal(t, "DELETE", (*requests)[len(*requests)-1].Method)
+	assert.Equal(t, "/api/v1/repos/cyclone/cyclone/hooks/7", (*requests)[len(*requests)-1].URI)
+
+	assert.NotNil(t, g.DeleteWebhook("cyclone/cyclone", "https://unknown.example.com"))
+}
+
+func TestGetToken(t *testing.T) {
+	server, requests := newFixtureServer(t, map[string]fixture{
+		"GET /api/v1/users/cyclone/tokens":      {File: "tokens.json"},
+		"DELETE /api/v1/users/cyclone/tokens/4": {},
+		"POST /api/v1/users/cyclone/tokens":     {File: "token.json"},
+	})
+	defer server.Close()
+
+	p, err := NewGitea(&v1alpha1.SCMSource{
+		Type:     v1alpha1.Gitea,
+		Server:   server.URL + "/",
+		User:     "cyclone",
+		Password: "password",
+	})
+	assert.Nil(t, err)
+
+	token, err := p.GetToken()
+	assert.Nil(t, err)
+	assert.Equal(t, "9f8e7d6c5b4a39281706f5e4d3c2b1a098765432", token)
+
+	assert.Equal(t, 3, len(*requests))
+	assert.Equal(t, "Basic Y3ljbG9uZTpwYXNzd29yZA==", (*requests)[0].Auth)
+	assert.Equal(t, "/api/v1/users/cyclone/tokens/4", (*requests)[1].URI)
+	created := &AccessToken{}
+	assert.Nil(t, json.Unmarshal((*requests)[2].Body, created))
+	assert.Equal(t, tokenName, created.Name)
+
+	_, err = NewGitea(&v1alpha1.SCMSource{Type: v1alpha1.Gitea, Server: server.URL, User: "cyclone"})
+	assert.NotNil(t, err)
+}
@@ -0,0 +1,141 @@
+package gitea
+
+import (
+	"time"
+)
+
+// Repository contains data of a Gitea repository.
+type Repository struct {
+	ID            int64  `json:"id"`
+	Name          string `json:"name"`
+	FullName      string `json:"full_name"`
+	CloneURL      string `json:"clone_url"`
+	DefaultBranch string `json:"default_branch"`
+}
+
+// Branch contains git branch information.
+type Branch struct {
+	Name   string    `json:"name"`
+	Commit RefCommit `json:"commit"`
+}
+
+// Tag contains git tag information.
+type Tag struct {
+	Name   string    `json:"name"`
+	Commit RefCommit `json:"commit"`
+}
+
+// RefCommit is the commit that a branch or tag points to, its SHA is in 'id' for branches, and in 'sha' for tags.
+type RefCommit struct {
+	ID  string `json:"id"`
+	SHA string `json:"sha"`
+}
+
+// GetSHA gets SHA of the commit.
+func (c RefCommit) GetSHA() string {
+	if c.SHA != "" {
+		return c.SHA
+	}
+	return c.ID
+}
+
+// PRBranch represents the head or base branch of a pull request.
+type PRBranch struct {
+	Ref string `json:"ref"`
+	Sha string `json:"sha"`
+}
+
+// PullRequest contains data of a Gitea pull request.
+type PullRequest struct {
+	ID        int64     `json:"id"`
+	Number    int       `json:"number"`
+	Title     string    `json:"title"`
+	Body      string    `json:"body"`
+	State     string    `json:"state"`
+	Head      PRBranch  `json:"head"`
+	Base      PRBranch  `json:"base"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// ChangedFile represents a file changed by a pull request.
+type ChangedFile struct {
+	Filename string `json:"filename"`
+	// PreviousFilename is the original path of renamed files.
+	PreviousFilename string `json:"previous_filename,omitempty"`
+	Status           string `json:"status"`
+}
+
+// Commit represents a commit in the compare result.
+type Commit struct {
+	SHA   string              `json:"sha"`
+	Files []CommitChangedFile `json:"files"`
+}
+
+// CommitChangedFile represents a file changed by a commit.
+type CommitChangedFile struct {
+	Filename string `json:"filename"`
+	Status   string `json:"status"`
+}
+
+// Compare represents the result of comparing two commits.
+type Compare struct {
+	TotalCommits int      `json:"total_commits"`
+	Commits      []Commit `json:"commits"`
+}
+
+// TreeEntry represents an entry of a git tree.
+type TreeEntry struct {
+	Path string `json:"path"`
+	Type string `json:"type"`
+}
+
+// Tree represents a git tree, large trees are paginated, and Truncated is true if there are more entries.
+type Tree struct {
+	Entries    []TreeEntry `json:"tree"`
+	Truncated  bool        `json:"truncated"`
+	Page       int         `json:"page"`
+	TotalCount int         `json:"total_count"`
+}
+
+// TreeOpts represents the options of getting a git tree.
+type TreeOpts struct {
+	Recursive bool `url:"recursive,omitempty"`
+	Page      int  `url:"page,omitempty"`
+	PerPage   int  `url:"per_page,omitempty"`
+}
+
+// PullRequestListOpts represents the options of listing pull requests.
+type PullRequestListOpts struct {
+	ListOpts
+	State string `url:"state,omitempty"`
+}
+
+// AccessToken represents an access token of a user.
+type AccessToken struct {
+	ID     int64    `json:"id"`
+	Name   string   `json:"name"`
+	Sha1   string   `json:"sha1,omitempty"`
+	Scopes []string `json:"scopes,omitempty"`
+}
+
+// StatusReq represents the options of creating commit status.
+type StatusReq struct {
+	State       string `json:"state"`
+	TargetURL   string `json:"target_url"`
+	Description string `json:"description"`
+	Context     string `json:"context"`
+}
+
+// CommentReq represents the options of creating issue comments.
+type CommentReq struct {
+	Body string `json:"body"`
+}
+
+// Hook represents a webhook of a repository.
+type Hook struct {
+	ID     int64             `json:"id"`
+	Type   string            `json:"type"`
+	Config map[string]string `json:"config"`
+	Events []string          `json:"events"`
+	Active bool              `json:"active"`
+}
@@ -0,0 +1,215 @@
+/*
+Copyright 2020 caicloud authors. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package github
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/caicloud/nirvana/log"
+	"github.com/google/go-github/github"
+
+	c_v1alpha1 "github.com/caicloud/cyclone/pkg/apis/cyclone/v1alpha1"
+	"github.com/caicloud/cyclone/pkg/server/biz/scm"
+)
+
+const (
+	// mediaTypeChecksPreview is the media type of the Checks API.
+	mediaTypeChecksPreview = "application/vnd.github.antiope-preview+json"
+
+	// maxCheckRunAnnotations is the max number of annotations per request of the Checks API.
+	maxCheckRunAnnotations = 50
+)
+
+// checkRunRequest is the request to create or update check runs. It's not the one in go-github, as annotations
+// of the vendored go-github are out of date.
+type checkRunRequest struct {
+	Name        string          `json:"name,omitempty"`
+	HeadSHA     string          `json:"head_sha,omitempty"`
+	DetailsURL  string          `json:"details_url,omitempty"`
+	Status      string          `json:"status"`
+	Conclusion  string          `json:"conclusion,omitempty"`
+	StartedAt   *time.Time      `json:"started_at,omitempty"`
+	CompletedAt *time.Time      `json:"completed_at,omitempty"`
+	Output      *checkRunOutput `json:"output,omitempty"`
+}
+
+type checkRunOutput struct {
+	Title       string               `json:"title"`
+	Summary     string               `json:"summary"`
+	Annotations []checkRunAnnotation `json:"annotations,omitempty"`
+}
+
+type checkRunAnnotation struct {
+	Path            string `json:"path"`
+	StartLine       int    `json:"start_line"`
+	EndLine         int    `json:"end_line"`
+	AnnotationLevel string `json:"annotation_level"`
+	Message         string `json:"message"`
+}
+
+// CreateCheckRun creates the check run, or updates it if a check run with the same name exists for the commit.
+func (g *Github) CreateCheckRun(repo string, run *scm.CheckRun) error {
+	owner, name := scm.ParseRepo(repo)
+
+	checkName := run.Name
+	result, _, err := g.client.Checks.ListCheckRunsForRef(g.ctx, owner, name, run.HeadSHA, &github.ListCheckRunsOptions{
+		CheckName: &checkName,
+	})
+	if err != nil {
+		return convertGithubError(err)
+	}
+
+	body := newCheckRunRequest(run)
+	method, u := "POST", fmt.Sprintf("repos/%s/%s/check-runs", owner, name)
+	if len(result.CheckRuns) > 0 {
+		method, u = "PATCH", fmt.Sprintf("repos/%s/%s/check-runs/%d", owner, name, result.CheckRuns[0].GetID())
+	}
+
+	req, err := g.client.NewRequest(method, u, body)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Accept", mediaTypeChecksPreview)
+
+	if _, err := g.client.Do(g.ctx, req, nil); err != nil {
+		log.Errorf("Fail to %s check run %s for %s/%s@%s: %v", method, run.Name, owner, name, run.HeadSHA, err)
+		return convertGithubError(err)
+	}
+
+	return nil
+}
+
+func newCheckRunRequest(run *scm.CheckRun) *checkRunRequest {
+	req := &checkRunRequest{
+		Name:       run.Name,
+		HeadSHA:    run.HeadSHA,
+		DetailsURL: run.DetailsURL,
+		Output: &checkRunOutput{
+			Title:   run.Title,
+			Summary: run.Summary,
+		},
+	}
+	if !run.StartedAt.IsZero() {
+		startedAt := run.StartedAt.UTC()
+		req.StartedAt = &startedAt
+	}
+
+	switch run.Phase {
+	case c_v1alpha1.StatusPending, c_v1alpha1.StatusWaiting, "":
+		req.Status = "queued"
+		return req
+	case c_v1alpha1.StatusRunning:
+		req.Status = "in_progress"
+		return req
+	case c_v1alpha1.StatusSucceeded:
+		req.Conclusion = "success"
+	case c_v1alpha1.StatusSkipped:
+		req.Conclusion = "neutral"
+	case c_v1alpha1.StatusCancelled:
+		req.Conclusion = "cancelled"
+	default:
+		req.Conclusion = "failure"
+	}
+
+	req.Status = "completed"
+	completedAt := time.Now().UTC()
+	req.CompletedAt = &completedAt
+	for i, a := range run.Annotations {
+		if i >= maxCheckRunAnnotations {
+			break
+		}
+		req.Output.Annotations = append(req.Output.Annotations, checkRunAnnotation{
+			Path:            a.Path,
+			StartLine:       a.Line,
+			EndLine:         a.Line,
+			AnnotationLevel: a.Level,
+			Message:         a.Message,
+		})
+	}
+
+	return req
+}
+
+// CreateDeploymentStatus creates a deployment of the commit for the environment if it doesn't exist, and
+// creates the status for it.
+func (g *Github) CreateDeploymentStatus(repo string, status *scm.DeploymentStatus) error {
+	owner, name := scm.ParseRepo(repo)
+
+	deployments, _, err := g.client.Repositories.ListDeployments(g.ctx, owner, name, &github.DeploymentsListOptions{
+		SHA:         status.CommitSHA,
+		Environment: status.Environment,
+	})
+	if err != nil {
+		return convertGithubError(err)
+	}
+
+	var id int64
+	if len(deployments) > 0 {
+		id = deployments[0].GetID()
+	} else {
+		// Statuses of the commit are created by Cyclone itself, so don't let GitHub check them, and never merge
+		// the default branch into the commit.
+		autoMerge := false
+		description := "Deployed by Cyclone"
+		deployment, _, err := g.client.Repositories.CreateDeployment(g.ctx, owner, name, &github.DeploymentRequest{
+			Ref:              &status.CommitSHA,
+			Environment:      &status.Environment,
+			AutoMerge:        &autoMerge,
+			RequiredContexts: &[]string{},
+			Description:      &description,
+		})
+		if err != nil {
+			log.Errorf("Fail to create deployment for %s/%s@%s: %v", owner, name, status.CommitSHA, err)
+			return convertGithubError(err)
+		}
+		id = deployment.GetID()
+	}
+
+	state := transDeploymentState(status.Phase)
+	description := scm.StatusDescription(status.Phase)
+	req := &github.DeploymentStatusRequest{
+		State:       &state,
+		Description: &description,
+	}
+	if status.LogURL != "" {
+		req.LogURL = &status.LogURL
+	}
+	if status.EnvironmentURL != "" {
+		req.EnvironmentURL = &status.EnvironmentURL
+	}
+	if _, _, err := g.client.Repositories.CreateDeploymentStatus(g.ctx, owner, name, id, req); err != nil {
+		log.Errorf("Fail to create status for deployment %d of %s/%s: %v", id, owner, name, err)
+		return convertGithubError(err)
+	}
+
+	return nil
+}
+
+// transDeploymentState trans c_v1alpha1.StatusPhase to state of Github deployment statuses.
+func transDeploymentState(phase c_v1alpha1.StatusPhase) string {
+	switch phase {
+	case c_v1alpha1.StatusSucceeded:
+		return "success"
+	case c_v1alpha1.StatusFailed:
+		return "failure"
+	case c_v1alpha1.StatusCancelled, c_v1alpha1.StatusSkipped:
+		return "inactive"
+	default:
+		return "pending"
+	}
+}
@@ -0,0 +1,76 @@
+package github
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	c_v1alpha1 "github.com/caicloud/cyclone/pkg/apis/cyclone/v1alpha1"
+	"github.com/caicloud/cyclone/pkg/server/apis/v1alpha1"
+	"github.com/caicloud/cyclone/pkg/server/biz/scm"
+)
+
+func TestCreateCheckRun(t *testing.T) {
+	var checkRunID int64
+	var method string
+	var body checkRunRequest
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.Method == http.MethodGet && r.URL.Path == "/repos/cyclone/cyclone/commits/6f4b3e0/check-runs":
+			assert.Equal(t, scm.StatusContext, r.URL.Query().Get("check_name"))
+			if checkRunID == 0 {
+				fmt.Fprint(w, `{"total_count":0,"check_runs":[]}`)
+			} else {
+				fmt.Fprintf(w, `{"total_count":1,"check_runs":[{"id":%d}]}`, checkRunID)
+			}
+		case r.Method == http.MethodPost && r.URL.Path == "/repos/cyclone/cyclone/check-runs",
+			r.Method == http.MethodPatch && r.URL.Path == fmt.Sprintf("/repos/cyclone/cyclone/check-runs/%d", checkRunID):
+			method = r.Method
+			data, _ := ioutil.ReadAll(r.Body)
+			body = checkRunRequest{}
+			assert.Nil(t, json.Unmarshal(data, &body))
+			fmt.Fprint(w, `{"id":1}`)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+
+	p, err := NewGithub(&v1alpha1.SCMSource{Type: v1alpha1.GitHub, Server: server.URL + "/", User: "cyclone", Token: "token"})
+	assert.Nil(t, err)
+	creator, ok := p.(scm.CheckRunCreator)
+	assert.True(t, ok)
+
+	run := &scm.CheckRun{
+		Name:    scm.StatusContext,
+		HeadSHA: "6f4b3e0",
+		Phase:   c_v1alpha1.StatusRunning,
+		Title:   "Cyclone CI is in progress",
+		Annotations: []scm.Annotation{
+			{Path: "foo.go", Line: 3, Level: scm.AnnotationLevelFailure, Message: "TestFoo failed"},
+		},
+	}
+	assert.Nil(t, creator.CreateCheckRun("cyclone/cyclone", run))
+	assert.Equal(t, http.MethodPost, method)
+	assert.Equal(t, "in_progress", body.Status)
+	assert.Equal(t, "", body.Conclusion)
+	assert.Nil(t, body.Output.Annotations)
+
+	checkRunID = 7
+	run.Phase = c_v1alpha1.StatusFailed
+	assert.Nil(t, creator.CreateCheckRun("cyclone/cyclone", run))
+	assert.Equal(t, http.MethodPatch, method)
+	assert.Equal(t, "completed", body.Status)
+	assert.Equal(t, "failure", body.Conclusion)
+	assert.NotNil(t, body.CompletedAt)
+	assert.Equal(t, []checkRunAnnotation{
+		{Path: "foo.go", StartLine: 3, EndLine: 3, AnnotationLevel: "failure", Message: "TestFoo failed"},
+	}, body.Output.Annotations)
+
+	assert.NotNil(t, creator.CreateCheckRun("cyclone/unknown", run))
+}
@@ -56,6 +56,9 @@ const (
 
 	// publicGithubServer represents the address of public Github.
 	publicGithubServer = "https://github.com"
+
+	// maxPushEventCommits represents the max number of commits in Github push events.
+	maxPushEventCommits = 20
 )
 
 var (
@@ -271,6 +274,58 @@ func (g *Github) ListTags(repo string) ([]string, error) {
 	return tags, nil
 }
 
+// ListRefs lists the branches and tags with their commit SHAs for specified repo.
+func (g *Github) ListRefs(repo string) ([]scm.Ref, error) {
+	opt := &github.ListOptions{
+		PerPage: scm.ListOptPerPage,
+	}
+
+	owner := g.scmCfg.User
+	if strings.Contains(repo, "/") {
+		parts := strings.Split(repo, "/")
+		if len(parts) != 2 {
+			err := fmt.Errorf("invalid repo %s, must in format of '{owner}/{repo}'", repo)
+			log.Error(err.Error())
+			return nil, err
+		}
+		owner, repo = parts[0], parts[1]
+	}
+
+	var refs []scm.Ref
+	for {
+		branches, resp, err := g.client.Repositories.ListBranches(g.ctx, owner, repo, opt)
+		if err != nil {
+			return nil, convertGithubError(err)
+		}
+
+		for _, b := range branches {
+			refs = append(refs, scm.Ref{Name: scm.BranchRef(b.GetName()), SHA: b.GetCommit().GetSHA()})
+		}
+		if resp.NextPage == 0 {
+			break
+		}
+		opt.Page = resp.NextPage
+	}
+
+	opt.Page = 0
+	for {
+		tags, resp, err := g.client.Repositories.ListTags(g.ctx, owner, repo, opt)
+		if err != nil {
+			return nil, convertGithubError(err)
+		}
+
+		for _, t := range tags {
+			refs = append(refs, scm.Ref{Name: scm.TagRef(t.GetName()), SHA: t.GetCommit().GetSHA()})
+		}
+		if resp.NextPage == 0 {
+			break
+		}
+		opt.Page = resp.NextPage
+	}
+
+	return refs, nil
+}
+
 func stringValue(s *string) string {
 	if s == nil {
 		return ""
@@ -324,10 +379,13 @@ func (g *Github) ListPullRequests(repo, state string) ([]scm.PullRequest, error)
 	prs := make([]scm.PullRequest, len(allPRs))
 	for i, pr := range allPRs {
 		prs[i] = scm.PullRequest{
-			ID:          *pr.Number,
-			Title:       stringValue(pr.Title),
-			Description: stringValue(pr.Body),
-			State:       stringValue(pr.State),
+			ID:           *pr.Number,
+			Title:        stringValue(pr.Title),
+			Description:  stringValue(pr.Body),
+			State:        stringValue(pr.State),
+			TargetBranch: pr.GetBase().GetRef(),
+			Ref:          fmt.Sprintf(pullRefTemplate, *pr.Number),
+			HeadSHA:      pr.GetHead().GetSHA(),
 		}
 	}
 
@@ -380,33 +438,37 @@ func (g *Github) ListDockerfiles(repo string) ([]string, error) {
 
 // CreateStatus generate a new status for repository.
 func (g *Github) CreateStatus(status c_v1alpha1.StatusPhase, targetURL, repoURL, commitSHA string) error {
-	// GitHub : error, failure, pending, or success.
-	state := "pending"
-	description := ""
-
 	switch status {
-	case c_v1alpha1.StatusRunning:
-		state = "pending"
-		description = "Cyclone CI is in progress."
-	case c_v1alpha1.StatusSucceeded:
-		state = "success"
-		description = "Cyclone CI passed."
-	case c_v1alpha1.StatusFailed:
-		state = "failure"
-		description = "Cyclone CI failed."
-	case c_v1alpha1.StatusCancelled:
-		state = "failure"
-		description = "Cyclone CI failed."
+	case c_v1alpha1.StatusRunning, c_v1alpha1.StatusSucceeded, c_v1alpha1.StatusFailed, c_v1alpha1.StatusCancelled:
 	default:
 		err := fmt.Errorf("not supported state:%s", status)
 		log.Error(err)
 		return err
 	}
 
+	return g.CreateCommitStatus(repoURL, commitSHA, &scm.CommitStatus{
+		Phase:     status,
+		Context:   scm.StatusContext,
+		TargetURL: targetURL,
+	})
+}
+
+// CreateCommitStatus creates the commit status with the given context.
+func (g *Github) CreateCommitStatus(repoURL, commitSHA string, status *scm.CommitStatus) error {
+	// GitHub : error, failure, pending, or success.
+	state := transStatus(status.Phase)
+	description := status.Description
+	if description == "" {
+		description = scm.StatusDescription(status.Phase)
+	}
+	context := status.Context
+	if context == "" {
+		context = scm.StatusContext
+	}
+
 	owner, repo := scm.ParseRepo(repoURL)
 	email := "cyclone@caicloud.dev"
 	name := "cyclone"
-	context := "continuous-integration/cyclone"
 	creator := github.User{
 		Name:  &name,
 		Email: &email,
@@ -414,7 +476,7 @@ func (g *Github) CreateStatus(status c_v1alpha1.StatusPhase, targetURL, repoURL,
 	repoStatus := &github.RepoStatus{
 		State:       &state,
 		Description: &description,
-		TargetURL:   &targetURL,
+		TargetURL:   &status.TargetURL,
 		Context:     &context,
 		Creator:     &creator,
 	}
@@ -427,6 +489,31 @@ func (g *Github) CreateStatus(status c_v1alpha1.StatusPhase, targetURL, repoURL,
 	})
 }
 
+// transStatus trans c_v1alpha1.StatusPhase to state of Github commit status.
+func transStatus(phase c_v1alpha1.StatusPhase) string {
+	switch phase {
+	case c_v1alpha1.StatusSucceeded, c_v1alpha1.StatusSkipped:
+		return "success"
+	case c_v1alpha1.StatusFailed, c_v1alpha1.StatusCancelled:
+		return "failure"
+	default:
+		return "pending"
+	}
+}
+
+// CreatePullRequestComment comments on the pull request.
+func (g *Github) CreatePullRequestComment(repo string, number int, body string) error {
+	owner, name := scm.ParseRepo(repo)
+	_, _, err := g.client.Issues.CreateComment(g.ctx, owner, name, number, &github.IssueComment{
+		Body: &body,
+	})
+	if err != nil {
+		return convertGithubError(err)
+	}
+
+	return nil
+}
+
 // GetPullRequestSHA gets latest commit SHA of pull request.
 func (g *Github) GetPullRequestSHA(repoURL string, number int) (string, error) {
 	owner, repo := scm.ParseRepo(repoURL)
@@ -439,6 +526,48 @@ func (g *Github) GetPullRequestSHA(repoURL string, number int) (string, error) {
 	return *pr.Head.SHA, nil
 }
 
+// ListPullRequestFiles lists files changed by the pull request.
+func (g *Github) ListPullRequestFiles(repo string, number int) ([]string, error) {
+	owner, name := scm.ParseRepo(repo)
+	opt := &github.ListOptions{
+		PerPage: scm.ListOptPerPage,
+	}
+
+	var files []string
+	for {
+		commitFiles, resp, err := g.client.PullRequests.ListFiles(g.ctx, owner, name, number, opt)
+		if err != nil {
+			return nil, convertGithubError(err)
+		}
+
+		for _, f := range commitFiles {
+			files = append(files, f.GetFilename())
+		}
+		if resp.NextPage == 0 {
+			break
+		}
+		opt.Page = resp.NextPage
+	}
+
+	return files, nil
+}
+
+// CompareCommits lists files changed between the base and head commits.
+func (g *Github) CompareCommits(repo, base, head string) ([]string, error) {
+	owner, name := scm.ParseRepo(repo)
+	comparison, _, err := g.client.Repositories.CompareCommits(g.ctx, owner, name, base, head)
+	if err != nil {
+		return nil, convertGithubError(err)
+	}
+
+	files := make([]string, 0, len(comparison.Files))
+	for _, f := range comparison.Files {
+		files = append(files, f.GetFilename())
+	}
+
+	return files, nil
+}
+
 // newClientByBasicAuth news Github client by basic auth, supports two types: username with password; username
 // with OAuth token.
 // Refer to https://developer.github.com/v3/auth/#basic-authentication
@@ -600,12 +729,13 @@ func ParseEvent(scmCfg *v1alpha1.SCMSource, request *http.Request) *scm.EventDat
 			return nil
 		}
 		return &scm.EventData{
-			Type:      scm.PullRequestEventType,
-			Repo:      *event.Repo.FullName,
-			Ref:       fmt.Sprintf(pullRefTemplate, *event.PullRequest.Number),
-			CommitSHA: commitSHA,
-			Branch:    *event.PullRequest.Base.Ref,
-			CreatedAt: event.GetPullRequest().GetUpdatedAt(),
+			Type:              scm.PullRequestEventType,
+			Repo:              *event.Repo.FullName,
+			Ref:               fmt.Sprintf(pullRefTemplate, *event.PullRequest.Number),
+			CommitSHA:         commitSHA,
+			Branch:            *event.PullRequest.Base.Ref,
+			PullRequestNumber: *event.PullRequest.Number,
+			CreatedAt:         event.GetPullRequest().GetUpdatedAt(),
 		}
 	case *github.IssueCommentEvent:
 		if event.Issue.PullRequestLinks == nil {
@@ -632,12 +762,13 @@ func ParseEvent(scmCfg *v1alpha1.SCMSource, request *http.Request) *scm.EventDat
 		}
 
 		return &scm.EventData{
-			Type:      scm.PullRequestCommentEventType,
-			Repo:      *event.Repo.FullName,
-			Ref:       fmt.Sprintf(pullRefTemplate, issueNumber),
-			Comment:   *event.Comment.Body,
-			CommitSHA: commitSHA,
-			CreatedAt: event.GetComment().GetCreatedAt(),
+			Type:              scm.PullRequestCommentEventType,
+			Repo:              *event.Repo.FullName,
+			Ref:               fmt.Sprintf(pullRefTemplate, issueNumber),
+			Comment:           *event.Comment.Body,
+			CommitSHA:         commitSHA,
+			PullRequestNumber: issueNumber,
+			CreatedAt:         event.GetComment().GetCreatedAt(),
 		}
 	case *github.PushEvent:
 		if event.After != nil && *event.After == "0000000000000000000000000000000000000000" {
@@ -645,10 +776,13 @@ func ParseEvent(scmCfg *v1alpha1.SCMSource, request *http.Request) *scm.EventDat
 			return nil
 		}
 		return &scm.EventData{
-			Type:   scm.PushEventType,
-			Repo:   *event.Repo.FullName,
-			Ref:    *event.Ref,
-			Branch: *event.Ref,
+			Type:         scm.PushEventType,
+			Repo:         *event.Repo.FullName,
+			Ref:          *event.Ref,
+			Branch:       *event.Ref,
+			CommitSHA:    event.GetAfter(),
+			Before:       event.GetBefore(),
+			ChangedFiles: pushChangedFiles(event),
 		}
 	default:
 		log.Warningln("Skip unsupported Github event")
@@ -656,6 +790,22 @@ func ParseEvent(scmCfg *v1alpha1.SCMSource, request *http.Request) *scm.EventDat
 	}
 }
 
+// pushChangedFiles gets changed files from commits in the push event. Github sends at most 20 commits in push
+// events, so nil is returned if there may be more commits, and changed files should be got by comparing commits.
+func pushChangedFiles(event *github.PushEvent) []string {
+	if len(event.Commits) == 0 || len(event.Commits) >= maxPushEventCommits {
+		return nil
+	}
+
+	files := []string{}
+	for _, commit := range event.Commits {
+		files = append(files, commit.Added...)
+		files = append(files, commit.Removed...)
+		files = append(files, commit.Modified...)
+	}
+	return files
+}
+
 // input   : `https://api.github.com/repos/aaa/bbb/statuses/ccc`
 // output  : ccc
 func extractCommitSHA(url string) (string, error) {
@@ -499,10 +499,11 @@ func ParseEvent(request *http.Request) *scm.EventData {
 			Type: scm.PullRequestEventType,
 			Repo: event.Project.PathWithNamespace,
 			// NOTE: v3 ObjectAttributes has `Iid`, but v4 replaces it with `IID`. This has no effect as both of their json field are `iid`.
-			Ref:       fmt.Sprintf(mergeRefTemplate, objectAttributes.Iid, objectAttributes.TargetBranch),
-			CommitSHA: objectAttributes.LastCommit.ID,
-			Branch:    objectAttributes.TargetBranch,
-			CreatedAt: parseTime(objectAttributes.UpdatedAt),
+			Ref:               fmt.Sprintf(mergeRefTemplate, objectAttributes.Iid, objectAttributes.TargetBranch),
+			CommitSHA:         objectAttributes.LastCommit.ID,
+			Branch:            objectAttributes.TargetBranch,
+			PullRequestNumber: objectAttributes.Iid,
+			CreatedAt:         parseTime(objectAttributes.UpdatedAt),
 		}
 	case *MergeCommentEvent:
 		if event.MergeRequest == nil {
@@ -510,12 +511,13 @@ func ParseEvent(request *http.Request) *scm.EventData {
 			return nil
 		}
 		return &scm.EventData{
-			Type:      scm.PullRequestCommentEventType,
-			Repo:      event.Project.PathWithNamespace,
-			Ref:       fmt.Sprintf(mergeRefTemplate, event.MergeRequest.IID, event.MergeRequest.TargetBranch),
-			Comment:   event.ObjectAttributes.Note,
-			CommitSHA: event.MergeRequest.LastCommit.ID,
-			CreatedAt: parseTime(event.ObjectAttributes.CreatedAt),
+			Type:              scm.PullRequestCommentEventType,
+			Repo:              event.Project.PathWithNamespace,
+			Ref:               fmt.Sprintf(mergeRefTemplate, event.MergeRequest.IID, event.MergeRequest.TargetBranch),
+			Comment:           event.ObjectAttributes.Note,
+			CommitSHA:         event.MergeRequest.LastCommit.ID,
+			PullRequestNumber: event.MergeRequest.IID,
+			CreatedAt:         parseTime(event.ObjectAttributes.CreatedAt),
 		}
 	case *v3.PushEvent:
 		if event.After == "0000000000000000000000000000000000000000" {
@@ -523,10 +525,12 @@ func ParseEvent(request *http.Request) *scm.EventData {
 			return nil
 		}
 		return &scm.EventData{
-			Type:   scm.PushEventType,
-			Repo:   event.Project.PathWithNamespace,
-			Ref:    event.Ref,
-			Branch: event.Ref,
+			Type:      scm.PushEventType,
+			Repo:      event.Project.PathWithNamespace,
+			Ref:       event.Ref,
+			Branch:    event.Ref,
+			CommitSHA: event.After,
+			Before:    event.Before,
 		}
 	default:
 		log.Warningln("Skip unsupported Gitlab event")
@@ -534,6 +538,17 @@ func ParseEvent(request *http.Request) *scm.EventData {
 	}
 }
 
+// appendDiffPaths appends paths of a file diff to the files, both paths are appended for renamed files.
+func appendDiffPaths(files []string, oldPath, newPath string) []string {
+	if oldPath != "" && oldPath != newPath {
+		files = append(files, oldPath)
+	}
+	if newPath != "" {
+		files = append(files, newPath)
+	}
+	return files
+}
+
 // transStatus trans api.Status to state and description of gitlab statuses.
 func transStatus(status c_v1alpha1.StatusPhase) (string, string) {
 	// GitLab : pending, running, success, failed, canceled.
@@ -553,6 +568,11 @@ func transStatus(status c_v1alpha1.StatusPhase) (string, string) {
 	case c_v1alpha1.StatusCancelled:
 		state = "canceled"
 		description = "The Cyclone CI build failed."
+	case c_v1alpha1.StatusPending, c_v1alpha1.StatusWaiting:
+		description = "The Cyclone CI build is pending."
+	case c_v1alpha1.StatusSkipped:
+		state = "success"
+		description = "The Cyclone CI build skipped."
 	default:
 		log.Errorf("not supported state:%s", status)
 	}
@@ -23,6 +23,7 @@ import (
 	"io/ioutil"
 	"net/http"
 	"net/url"
+	"strconv"
 	"strings"
 
 	"github.com/caicloud/nirvana/log"
@@ -126,7 +127,58 @@ func (g *V3) ListTags(repo string) ([]string, error) {
 	return tagNames, nil
 }
 
+// ListRefs lists the branches and tags with their commit SHAs for specified repo.
+func (g *V3) ListRefs(repo string) ([]scm.Ref, error) {
+	var refs []scm.Ref
+	for page := 1; page != 0; {
+		branches, resp, err := g.client.Branches.ListBranches(repo, pageOptionV3(page))
+		if err != nil {
+			log.Errorf("Fail to list branches for %s", repo)
+			return nil, convertGitlabError(err, resp)
+		}
+
+		for _, b := range branches {
+			refs = append(refs, scm.Ref{Name: scm.BranchRef(b.Name), SHA: commitIDV3(b.Commit)})
+		}
+		page = resp.NextPage
+	}
+
+	for page := 1; page != 0; {
+		tags, resp, err := g.client.Tags.ListTags(repo, pageOptionV3(page))
+		if err != nil {
+			log.Errorf("Fail to list tags for %s", repo)
+			return nil, convertGitlabError(err, resp)
+		}
+
+		for _, t := range tags {
+			refs = append(refs, scm.Ref{Name: scm.TagRef(t.Name), SHA: commitIDV3(t.Commit)})
+		}
+		page = resp.NextPage
+	}
+
+	return refs, nil
+}
+
+// pageOptionV3 sets the page to list for v3 APIs that don't take list options.
+func pageOptionV3(page int) v3.OptionFunc {
+	return func(req *http.Request) error {
+		query := req.URL.Query()
+		query.Set("page", strconv.Itoa(page))
+		query.Set("per_page", strconv.Itoa(scm.ListOptPerPage))
+		req.URL.RawQuery = query.Encode()
+		return nil
+	}
+}
+
+func commitIDV3(commit *v3.Commit) string {
+	if commit == nil {
+		return ""
+	}
+	return commit.ID
+}
+
 // ListPullRequests lists the merge requests for specified repo.
+// Head commits of merge requests are not returned by v3 API, so HeadSHA is empty.
 func (g *V3) ListPullRequests(repo, state string) ([]scm.PullRequest, error) {
 	// GitLab mr state: opened, closed, locked, merged, all
 	var s string
@@ -161,6 +213,7 @@ func (g *V3) ListPullRequests(repo, state string) ([]scm.PullRequest, error) {
 				Description:  p.Description,
 				State:        p.State,
 				TargetBranch: p.TargetBranch,
+				Ref:          fmt.Sprintf(mergeRefTemplate, p.IID, p.TargetBranch),
 			})
 		}
 		if resp.NextPage == 0 {
@@ -184,26 +237,99 @@ func (g *V3) ListDockerfiles(repo string) ([]string, error) {
 
 // CreateStatus generate a new status for repository.
 func (g *V3) CreateStatus(status c_v1alpha1.StatusPhase, targetURL, repo, commitSha string) error {
-	state, description := transStatus(status)
+	return g.CreateCommitStatus(repo, commitSha, &scm.CommitStatus{
+		Phase:     status,
+		Context:   scm.StatusContext,
+		TargetURL: targetURL,
+	})
+}
+
+// CreateCommitStatus creates the commit status with the given context.
+func (g *V3) CreateCommitStatus(repo, commitSha string, status *scm.CommitStatus) error {
+	state, description := transStatus(status.Phase)
+	if status.Description != "" {
+		description = status.Description
+	}
+	context := status.Context
+	if context == "" {
+		context = scm.StatusContext
+	}
 
-	context := "continuous-integration/cyclone"
 	opt := &v3.SetCommitStatusOptions{
 		State:       v3.BuildState(state),
 		Description: &description,
-		TargetURL:   &targetURL,
+		TargetURL:   &status.TargetURL,
 		Context:     &context,
 	}
 	_, resp, err := g.client.Commits.SetCommitStatus(repo, commitSha, opt)
 	return convertGitlabError(err, resp)
 }
 
+// CreatePullRequestComment comments on the merge request.
+func (g *V3) CreatePullRequestComment(repo string, number int, body string) error {
+	mr, err := g.getMergeRequest(repo, number)
+	if err != nil {
+		return err
+	}
+
+	// Notes API of GitLab v3 uses the ID of merge requests rather than the IID.
+	_, resp, err := g.client.Notes.CreateMergeRequestNote(repo, mr.ID, &v3.CreateMergeRequestNoteOptions{
+		Body: &body,
+	})
+	return convertGitlabError(err, resp)
+}
+
 // GetPullRequestSHA gets latest commit SHA of pull request.
 func (g *V3) GetPullRequestSHA(repo string, number int) (string, error) {
+	mr, err := g.getMergeRequest(repo, number)
+	if err != nil {
+		return "", err
+	}
+
+	return mr.SHA, nil
+}
+
+// ListPullRequestFiles lists files changed by the merge request.
+func (g *V3) ListPullRequestFiles(repo string, number int) ([]string, error) {
+	mr, err := g.getMergeRequest(repo, number)
+	if err != nil {
+		return nil, err
+	}
+
+	// Merge request APIs of Gitlab v3 use ID rather than IID.
+	changes, resp, err := g.client.MergeRequests.GetMergeRequestChanges(repo, mr.ID)
+	if err != nil {
+		return nil, convertGitlabError(err, resp)
+	}
+
+	files := []string{}
+	for _, c := range changes.Changes {
+		files = appendDiffPaths(files, c.OldPath, c.NewPath)
+	}
+	return files, nil
+}
+
+// CompareCommits lists files changed between the base and head commits.
+func (g *V3) CompareCommits(repo, base, head string) ([]string, error) {
+	compare, resp, err := g.client.Repositories.Compare(repo, &v3.CompareOptions{From: &base, To: &head})
+	if err != nil {
+		return nil, convertGitlabError(err, resp)
+	}
+
+	files := []string{}
+	for _, d := range compare.Diffs {
+		files = appendDiffPaths(files, d.OldPath, d.NewPath)
+	}
+	return files, nil
+}
+
+// getMergeRequest gets the merge request by its IID.
+func (g *V3) getMergeRequest(repo string, number int) (*mergeRequestResponse, error) {
 	path := fmt.Sprintf("%s/api/%s/projects/%s/merge_requests?iid=%d",
 		strings.TrimSuffix(g.scmCfg.Server, "/"), v3APIVersion, url.QueryEscape(repo), number)
 	req, err := http.NewRequest(http.MethodGet, path, nil)
 	if err != nil {
-		return "", err
+		return nil, err
 	}
 
 	if len(g.scmCfg.User) == 0 {
@@ -221,7 +347,7 @@ func (g *V3) GetPullRequestSHA(repo string, number int) (string, error) {
 	resp, err := httpClient.Do(req)
 	if err != nil {
 		log.Errorf("Fail to get project merge request as %s", err.Error())
-		return "", err
+		return nil, err
 	}
 
 	defer func() {
@@ -233,23 +359,23 @@ func (g *V3) GetPullRequestSHA(repo string, number int) (string, error) {
 	body, err := ioutil.ReadAll(resp.Body)
 	if err != nil {
 		log.Errorf("Fail to get project merge request as %s", err.Error())
-		return "", err
+		return nil, err
 	}
 
 	if resp.StatusCode/100 == 2 {
 		mr := []mergeRequestResponse{}
 		err := json.Unmarshal(body, &mr)
 		if err != nil {
-			return "", err
+			return nil, err
 		}
 		if len(mr) > 0 {
-			return mr[0].SHA, nil
+			return &mr[0], nil
 		}
-		return "", fmt.Errorf("Merge request %d not found ", number)
+		return nil, fmt.Errorf("Merge request %d not found ", number)
 	}
 
 	err = fmt.Errorf("Fail to get merge request %d as %s ", number, body)
-	return "", err
+	return nil, err
 }
 
 // mergeRequestResponse represents the response of Gitlab merge request API.
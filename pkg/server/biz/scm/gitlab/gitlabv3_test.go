@@ -0,0 +1,46 @@
+package gitlab
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	v3 "gopkg.in/xanzy/go-gitlab.v0"
+
+	"github.com/caicloud/cyclone/pkg/server/biz/scm"
+)
+
+func TestV3ListRefs(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		page, _ := strconv.Atoi(r.URL.Query().Get("page"))
+		switch r.URL.Path {
+		case "/api/v3/projects/group%2Frepo/repository/branches", "/api/v3/projects/group/repo/repository/branches":
+			if page < 2 {
+				w.Header().Set("Link", fmt.Sprintf(`<%s%s?page=2>; rel="next"`, "http://"+r.Host, r.URL.Path))
+				fmt.Fprint(w, `[{"name": "master", "commit": {"id": "a"}}]`)
+				return
+			}
+			fmt.Fprint(w, `[{"name": "dev", "commit": {"id": "b"}}]`)
+		case "/api/v3/projects/group%2Frepo/repository/tags", "/api/v3/projects/group/repo/repository/tags":
+			fmt.Fprint(w, `[{"name": "v1.0", "commit": {"id": "c"}}]`)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+
+	client := v3.NewClient(nil, "token")
+	assert.Nil(t, client.SetBaseURL(server.URL+"/api/v3"))
+	g := &V3{client: client}
+
+	refs, err := g.ListRefs("group/repo")
+	assert.Nil(t, err)
+	assert.Equal(t, []scm.Ref{
+		{Name: "refs/heads/master", SHA: "a"},
+		{Name: "refs/heads/dev", SHA: "b"},
+		{Name: "refs/tags/v1.0", SHA: "c"},
+	}, refs)
+}
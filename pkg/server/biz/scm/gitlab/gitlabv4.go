@@ -144,6 +144,62 @@ func (g *V4) ListTags(repo string) ([]string, error) {
 	return allTags, nil
 }
 
+// ListRefs lists the branches and tags with their commit SHAs for specified repo.
+func (g *V4) ListRefs(repo string) ([]scm.Ref, error) {
+	branchOpts := &v4.ListBranchesOptions{
+		ListOptions: v4.ListOptions{
+			PerPage: scm.ListOptPerPage,
+		},
+	}
+
+	var refs []scm.Ref
+	for {
+		branches, resp, err := g.client.Branches.ListBranches(repo, branchOpts)
+		if err != nil {
+			log.Errorf("Fail to list branches for %s", repo)
+			return nil, convertGitlabError(err, resp)
+		}
+
+		for _, b := range branches {
+			refs = append(refs, scm.Ref{Name: scm.BranchRef(b.Name), SHA: commitIDV4(b.Commit)})
+		}
+		if resp.NextPage == 0 {
+			break
+		}
+		branchOpts.Page = resp.NextPage
+	}
+
+	tagOpts := &v4.ListTagsOptions{
+		ListOptions: v4.ListOptions{
+			PerPage: scm.ListOptPerPage,
+		},
+	}
+	for {
+		tags, resp, err := g.client.Tags.ListTags(repo, tagOpts)
+		if err != nil {
+			log.Errorf("Fail to list tags for %s", repo)
+			return nil, convertGitlabError(err, resp)
+		}
+
+		for _, t := range tags {
+			refs = append(refs, scm.Ref{Name: scm.TagRef(t.Name), SHA: commitIDV4(t.Commit)})
+		}
+		if resp.NextPage == 0 {
+			break
+		}
+		tagOpts.Page = resp.NextPage
+	}
+
+	return refs, nil
+}
+
+func commitIDV4(commit *v4.Commit) string {
+	if commit == nil {
+		return ""
+	}
+	return commit.ID
+}
+
 // ListPullRequests lists the merge requests for specified repo.
 func (g *V4) ListPullRequests(repo, state string) ([]scm.PullRequest, error) {
 	// GitLab mr state: opened, closed, locked, merged, all
@@ -179,6 +235,8 @@ func (g *V4) ListPullRequests(repo, state string) ([]scm.PullRequest, error) {
 				Description:  p.Description,
 				State:        p.State,
 				TargetBranch: p.TargetBranch,
+				Ref:          fmt.Sprintf(mergeRefTemplate, p.IID, p.TargetBranch),
+				HeadSHA:      p.SHA,
 			})
 		}
 		if resp.NextPage == 0 {
@@ -229,19 +287,42 @@ func (g *V4) ListDockerfiles(repo string) ([]string, error) {
 
 // CreateStatus generate a new status for repository.
 func (g *V4) CreateStatus(status c_v1alpha1.StatusPhase, targetURL, repo, commitSha string) error {
-	state, description := transStatus(status)
+	return g.CreateCommitStatus(repo, commitSha, &scm.CommitStatus{
+		Phase:     status,
+		Context:   scm.StatusContext,
+		TargetURL: targetURL,
+	})
+}
+
+// CreateCommitStatus creates the commit status with the given context.
+func (g *V4) CreateCommitStatus(repo, commitSha string, status *scm.CommitStatus) error {
+	state, description := transStatus(status.Phase)
+	if status.Description != "" {
+		description = status.Description
+	}
+	context := status.Context
+	if context == "" {
+		context = scm.StatusContext
+	}
 
-	context := "continuous-integration/cyclone"
 	opt := &v4.SetCommitStatusOptions{
 		State:       v4.BuildStateValue(state),
 		Description: &description,
-		TargetURL:   &targetURL,
+		TargetURL:   &status.TargetURL,
 		Context:     &context,
 	}
 	_, resp, err := g.client.Commits.SetCommitStatus(repo, commitSha, opt)
 	return convertGitlabError(err, resp)
 }
 
+// CreatePullRequestComment comments on the merge request.
+func (g *V4) CreatePullRequestComment(repo string, number int, body string) error {
+	_, resp, err := g.client.Notes.CreateMergeRequestNote(repo, number, &v4.CreateMergeRequestNoteOptions{
+		Body: &body,
+	})
+	return convertGitlabError(err, resp)
+}
+
 // GetPullRequestSHA gets latest commit SHA of pull request.
 func (g *V4) GetPullRequestSHA(repo string, number int) (string, error) {
 	mr, resp, err := g.client.MergeRequests.GetMergeRequest(repo, number, nil)
@@ -252,6 +333,34 @@ func (g *V4) GetPullRequestSHA(repo string, number int) (string, error) {
 	return mr.SHA, nil
 }
 
+// ListPullRequestFiles lists files changed by the merge request.
+func (g *V4) ListPullRequestFiles(repo string, number int) ([]string, error) {
+	mr, resp, err := g.client.MergeRequests.GetMergeRequestChanges(repo, number)
+	if err != nil {
+		return nil, convertGitlabError(err, resp)
+	}
+
+	files := []string{}
+	for _, c := range mr.Changes {
+		files = appendDiffPaths(files, c.OldPath, c.NewPath)
+	}
+	return files, nil
+}
+
+// CompareCommits lists files changed between the base and head commits.
+func (g *V4) CompareCommits(repo, base, head string) ([]string, error) {
+	compare, resp, err := g.client.Repositories.Compare(repo, &v4.CompareOptions{From: &base, To: &head})
+	if err != nil {
+		return nil, convertGitlabError(err, resp)
+	}
+
+	files := []string{}
+	for _, d := range compare.Diffs {
+		files = appendDiffPaths(files, d.OldPath, d.NewPath)
+	}
+	return files, nil
+}
+
 // GetWebhook gets webhook from specified repo.
 func (g *V4) GetWebhook(repo string, webhookURL string) (*v4.ProjectHook, error) {
 	// log.Infof("repo: %s", url.PathEscape(repo))
@@ -0,0 +1,122 @@
+package scm
+
+import (
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	c_v1alpha1 "github.com/caicloud/cyclone/pkg/apis/cyclone/v1alpha1"
+)
+
+// PollRepo gets branches, tags and open pull requests of the repo that the trigger policy needs, and synthesizes
+// events for changes since the last poll, in the same form as those parsed from webhooks. Push events are for new
+// or updated branches, tag release events are for new or moved tags, and pull request events are for new or
+// updated pull requests. Changes of refs or pull requests not got by the last poll, including those of the first
+// poll whose last status is nil, are only recorded in the returned status.
+func PollRepo(provider Provider, repo string, policy *c_v1alpha1.SCMTriggerPolicy, last *c_v1alpha1.SCMTriggerStatus, now time.Time) ([]*EventData, *c_v1alpha1.SCMTriggerStatus, error) {
+	if last == nil {
+		last = &c_v1alpha1.SCMTriggerStatus{}
+	}
+	status := &c_v1alpha1.SCMTriggerStatus{
+		LastPollTime: &metav1.Time{Time: now},
+	}
+	var events []*EventData
+
+	// Both branches and tags are recorded, so that enabling the other policy later won't fire for existing refs.
+	if policy.Push.Enabled || policy.TagRelease.Enabled {
+		refs, err := provider.ListRefs(repo)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		status.Refs = make(map[string]string)
+		for _, ref := range refs {
+			if ref.SHA != "" {
+				status.Refs[ref.Name] = ref.SHA
+			}
+		}
+		if last.Refs != nil {
+			events = append(events, refEvents(repo, last.Refs, status.Refs, now)...)
+		}
+	}
+
+	if policy.PullRequest.Enabled {
+		prs, err := provider.ListPullRequests(repo, PullRequestStateOpen)
+		if err != nil {
+			return nil, nil, err
+		}
+		sort.Slice(prs, func(i, j int) bool { return prs[i].ID < prs[j].ID })
+
+		status.PullRequests = make(map[string]string)
+		for _, pr := range prs {
+			// Updates of pull requests can't be known without head commits.
+			if pr.HeadSHA == "" || pr.Ref == "" {
+				continue
+			}
+			number := strconv.Itoa(pr.ID)
+			status.PullRequests[number] = pr.HeadSHA
+			if last.PullRequests == nil || last.PullRequests[number] == pr.HeadSHA {
+				continue
+			}
+
+			events = append(events, &EventData{
+				Type:              PullRequestEventType,
+				Repo:              repo,
+				Ref:               pr.Ref,
+				Branch:            pr.TargetBranch,
+				CommitSHA:         pr.HeadSHA,
+				PullRequestNumber: pr.ID,
+				CreatedAt:         now,
+			})
+		}
+	}
+
+	return events, status, nil
+}
+
+// refEvents synthesizes push and tag release events for refs changed between the last and current poll.
+func refEvents(repo string, last, current map[string]string, now time.Time) []*EventData {
+	names := make([]string, 0, len(current))
+	for name := range current {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var events []*EventData
+	for _, name := range names {
+		sha := current[name]
+		before, ok := last[name]
+		if ok && before == sha {
+			continue
+		}
+
+		switch {
+		case strings.HasPrefix(name, tagRefPrefix):
+			events = append(events, &EventData{
+				Type:      TagReleaseEventType,
+				Repo:      repo,
+				Ref:       name,
+				CommitSHA: sha,
+				CreatedAt: now,
+			})
+		case strings.HasPrefix(name, branchRefPrefix):
+			// New branches are pushed from the zero commit as in webhooks.
+			if !ok {
+				before = zeroCommitSHA
+			}
+			events = append(events, &EventData{
+				Type:      PushEventType,
+				Repo:      repo,
+				Ref:       name,
+				Branch:    name,
+				CommitSHA: sha,
+				Before:    before,
+				CreatedAt: now,
+			})
+		}
+	}
+	return events
+}
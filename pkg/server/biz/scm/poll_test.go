@@ -0,0 +1,92 @@
+package scm
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	c_v1alpha1 "github.com/caicloud/cyclone/pkg/apis/cyclone/v1alpha1"
+)
+
+type pollProvider struct {
+	Provider
+	refs []Ref
+	prs  []PullRequest
+}
+
+func (p *pollProvider) ListRefs(repo string) ([]Ref, error) {
+	return p.refs, nil
+}
+
+func (p *pollProvider) ListPullRequests(repo, state string) ([]PullRequest, error) {
+	return p.prs, nil
+}
+
+func TestPollRepo(t *testing.T) {
+	now := time.Date(2020, 3, 1, 8, 0, 0, 0, time.UTC)
+	policy := &c_v1alpha1.SCMTriggerPolicy{
+		Push:        c_v1alpha1.SCMTriggerPush{SCMTriggerBasic: c_v1alpha1.SCMTriggerBasic{Enabled: true}},
+		PullRequest: c_v1alpha1.SCMTriggerPullRequest{SCMTriggerBasic: c_v1alpha1.SCMTriggerBasic{Enabled: true}},
+	}
+	p := &pollProvider{
+		refs: []Ref{
+			{Name: "refs/heads/master", SHA: "a1"},
+			{Name: "refs/heads/develop", SHA: "b1"},
+			{Name: "refs/tags/v1.0", SHA: "a1"},
+		},
+		prs: []PullRequest{{ID: 1, TargetBranch: "master", Ref: "refs/pull/1/merge", HeadSHA: "c1"}},
+	}
+
+	// The first poll only records the state.
+	events, status, err := PollRepo(p, "cyclone/cyclone", policy, nil, now)
+	assert.Nil(t, err)
+	assert.Nil(t, events)
+	assert.Equal(t, map[string]string{"refs/heads/master": "a1", "refs/heads/develop": "b1", "refs/tags/v1.0": "a1"}, status.Refs)
+	assert.Equal(t, map[string]string{"1": "c1"}, status.PullRequests)
+	assert.True(t, now.Equal(status.LastPollTime.Time))
+
+	p.refs = []Ref{
+		{Name: "refs/heads/master", SHA: "a2"},
+		{Name: "refs/heads/feature", SHA: "d1"},
+		{Name: "refs/tags/v1.0", SHA: "a1"},
+		{Name: "refs/tags/v1.1", SHA: "a2"},
+	}
+	p.prs = []PullRequest{
+		{ID: 2, TargetBranch: "develop", Ref: "refs/pull/2/merge", HeadSHA: "e1"},
+		{ID: 1, TargetBranch: "master", Ref: "refs/pull/1/merge", HeadSHA: "c1"},
+		{ID: 3, TargetBranch: "master", Ref: "refs/pull/3/merge"},
+	}
+	events, status, err = PollRepo(p, "cyclone/cyclone", policy, status, now)
+	assert.Nil(t, err)
+	assert.Equal(t, []*EventData{
+		{Type: PushEventType, Repo: "cyclone/cyclone", Ref: "refs/heads/feature", Branch: "refs/heads/feature", CommitSHA: "d1", Before: zeroCommitSHA, CreatedAt: now},
+		{Type: PushEventType, Repo: "cyclone/cyclone", Ref: "refs/heads/master", Branch: "refs/heads/master", CommitSHA: "a2", Before: "a1", CreatedAt: now},
+		{Type: TagReleaseEventType, Repo: "cyclone/cyclone", Ref: "refs/tags/v1.1", CommitSHA: "a2", CreatedAt: now},
+		{Type: PullRequestEventType, Repo: "cyclone/cyclone", Ref: "refs/pull/2/merge", Branch: "develop", CommitSHA: "e1", PullRequestNumber: 2, CreatedAt: now},
+	}, events)
+	assert.Equal(t, map[string]string{"1": "c1", "2": "e1"}, status.PullRequests)
+
+	// Pull requests not polled before are only recorded, even if there were no open pull requests.
+	policy.PullRequest.Enabled = false
+	_, status, err = PollRepo(p, "cyclone/cyclone", policy, status, now)
+	assert.Nil(t, err)
+	assert.Nil(t, status.PullRequests)
+	policy.PullRequest.Enabled = true
+	p.prs = nil
+	events, status, err = PollRepo(p, "cyclone/cyclone", policy, status, now)
+	assert.Nil(t, err)
+	assert.Nil(t, events)
+
+	// Empty state is kept through serialization, so that the next pull request fires.
+	data, err := json.Marshal(status)
+	assert.Nil(t, err)
+	status = &c_v1alpha1.SCMTriggerStatus{}
+	assert.Nil(t, json.Unmarshal(data, status))
+	p.prs = []PullRequest{{ID: 4, TargetBranch: "master", Ref: "refs/pull/4/merge", HeadSHA: "f1"}}
+	events, _, err = PollRepo(p, "cyclone/cyclone", policy, status, now)
+	assert.Nil(t, err)
+	assert.Equal(t, 1, len(events))
+	assert.Equal(t, 4, events[0].PullRequestNumber)
+}
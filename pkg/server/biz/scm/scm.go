@@ -62,9 +62,19 @@ type Provider interface {
 	ListTags(repo string) ([]string, error)
 	// ListPullRequests list pull requests of repo, repo format must be {owner}/{repo}.
 	ListPullRequests(repo, state string) ([]PullRequest, error)
+	// ListRefs lists branches and tags of repo with their commit SHAs, repo format must be {owner}/{repo}.
+	ListRefs(repo string) ([]Ref, error)
 	ListDockerfiles(repo string) ([]string, error)
 	CreateStatus(status c_v1alpha1.StatusPhase, targetURL, repoURL, commitSHA string) error
+	// CreateCommitStatus creates the commit status with the given context, repo format must be {owner}/{repo}.
+	CreateCommitStatus(repo, commitSHA string, status *CommitStatus) error
+	// CreatePullRequestComment comments on the pull request, repo format must be {owner}/{repo}.
+	CreatePullRequestComment(repo string, number int, body string) error
 	GetPullRequestSHA(repoURL string, number int) (string, error)
+	// ListPullRequestFiles lists files changed by the pull request, repo format must be {owner}/{repo}.
+	ListPullRequestFiles(repo string, number int) ([]string, error)
+	// CompareCommits lists files changed between the base and head commits, repo format must be {owner}/{repo}.
+	CompareCommits(repo, base, head string) ([]string, error)
 	CheckToken() error
 	CreateWebhook(repo string, webhook *Webhook) error
 	DeleteWebhook(repo string, webhookURL string) error
@@ -116,7 +126,7 @@ func GenerateSCMToken(config *v1alpha1.SCMSource) error {
 	var generatedToken string
 
 	switch scmType {
-	case v1alpha1.GitHub, v1alpha1.GitLab, v1alpha1.Bitbucket:
+	case v1alpha1.GitHub, v1alpha1.GitLab, v1alpha1.Bitbucket, v1alpha1.Gitea:
 		// If username and password is provided, generate the new token.
 		if len(config.User) != 0 && len(config.Password) != 0 {
 			generatedToken, err = provider.GetToken()
@@ -179,11 +189,61 @@ type EventData struct {
 	Branch    string
 	Comment   string
 	CommitSHA string
+	// Before is the commit before push events, it's used to compare changed files.
+	Before string
+	// PullRequestNumber is number of the pull request for pull request and pull request comment events.
+	PullRequestNumber int
 	// CreatedAt is the time this event gets triggered at.
-	CreatedAt    time.Time
+	CreatedAt time.Time
+	// ChangedFiles are files changed by the event, nil means they are unknown.
 	ChangedFiles []string
 }
 
+// zeroCommitSHA is the commit SHA in push events for created or deleted branches.
+const zeroCommitSHA = "0000000000000000000000000000000000000000"
+
+// MaxChangedFiles is the max number of changed files kept in event data. Event data is stored in webhook deliveries
+// and annotations of WorkflowRuns, which are limited in size, so changed files of larger events are left unknown.
+const MaxChangedFiles = 1000
+
+// PopulateChangedFiles gets changed files of push and pull request events from the provider if they are unknown.
+// Changed files of pushes that create branches can't be compared, and they are left unknown, so are those of
+// events with more than MaxChangedFiles changed files.
+func PopulateChangedFiles(provider Provider, data *EventData) error {
+	if data.ChangedFiles != nil {
+		return nil
+	}
+
+	var files []string
+	var err error
+	switch data.Type {
+	case PushEventType:
+		if data.Before == "" || data.Before == zeroCommitSHA || data.CommitSHA == "" {
+			return nil
+		}
+		files, err = provider.CompareCommits(data.Repo, data.Before, data.CommitSHA)
+	case PullRequestEventType:
+		if data.PullRequestNumber == 0 {
+			return nil
+		}
+		files, err = provider.ListPullRequestFiles(data.Repo, data.PullRequestNumber)
+	default:
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	if len(files) > MaxChangedFiles {
+		return nil
+	}
+	if files == nil {
+		files = []string{}
+	}
+	data.ChangedFiles = files
+	return nil
+}
+
 // PullRequest describes pull requests of SCM repositories.
 type PullRequest struct {
 	ID          int    `json:"id"`
@@ -192,4 +252,16 @@ type PullRequest struct {
 	State       string `json:"state"`
 	// TargetBranch used for GitLab to indicate to which branch the merge-request should merge.
 	TargetBranch string `json:"targetBranch"`
+	// Ref is the ref to check out the pull request, it's the same as the ref in pull request events.
+	Ref string `json:"ref,omitempty"`
+	// HeadSHA is the head commit of the pull request.
+	HeadSHA string `json:"headSHA,omitempty"`
+}
+
+// Ref represents a branch or tag of a repo.
+type Ref struct {
+	// Name is the full ref, for example, 'refs/heads/master' or 'refs/tags/v1.0'.
+	Name string `json:"name"`
+	// SHA is the commit SHA that the ref points to.
+	SHA string `json:"sha"`
 }
@@ -85,16 +85,41 @@ func (s *SVN) ListPullRequests(repo, state string) ([]scm.PullRequest, error) {
 	return nil, cerr.ErrorNotImplemented.Error("list svn pull request")
 }
 
+// ListRefs lists branches and tags of repo with their commit SHAs, repo format must be {owner}/{repo}.
+func (s *SVN) ListRefs(repo string) ([]scm.Ref, error) {
+	return nil, cerr.ErrorNotImplemented.Error("list svn refs")
+}
+
 // CreateStatus ...
 func (s *SVN) CreateStatus(status c_v1alpha1.StatusPhase, targetURL, repoURL, commitSha string) error {
 	return cerr.ErrorNotImplemented.Error("create status")
 }
 
+// CreateCommitStatus ...
+func (s *SVN) CreateCommitStatus(repo, commitSha string, status *scm.CommitStatus) error {
+	return cerr.ErrorNotImplemented.Error("create commit status")
+}
+
+// CreatePullRequestComment ...
+func (s *SVN) CreatePullRequestComment(repo string, number int, body string) error {
+	return cerr.ErrorNotImplemented.Error("create pull request comment")
+}
+
 // GetPullRequestSHA ...
 func (s *SVN) GetPullRequestSHA(repoURL string, number int) (string, error) {
 	return "", cerr.ErrorNotImplemented.Error("get pull request SHA")
 }
 
+// ListPullRequestFiles ...
+func (s *SVN) ListPullRequestFiles(repo string, number int) ([]string, error) {
+	return nil, cerr.ErrorNotImplemented.Error("list pull request files")
+}
+
+// CompareCommits ...
+func (s *SVN) CompareCommits(repo, base, head string) ([]string, error) {
+	return nil, cerr.ErrorNotImplemented.Error("compare commits")
+}
+
 // CheckToken ...
 func (s *SVN) CheckToken() error {
 	return nil
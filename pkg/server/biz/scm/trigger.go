@@ -0,0 +1,171 @@
+package scm
+
+import (
+	"fmt"
+	"strings"
+
+	c_v1alpha1 "github.com/caicloud/cyclone/pkg/apis/cyclone/v1alpha1"
+)
+
+// TriggerMatch is the result of matching an SCM event against trigger policies.
+type TriggerMatch struct {
+	// Matched indicates whether the event matches the policies.
+	Matched bool
+	// ByPR indicates whether the event is a pull request or pull request comment event.
+	ByPR bool
+	// Tag is name of the tag for tag release events.
+	Tag string
+	// Reason describes why the event matches or not.
+	Reason string
+}
+
+func matched(reason string, a ...interface{}) *TriggerMatch {
+	return &TriggerMatch{Matched: true, Reason: fmt.Sprintf(reason, a...)}
+}
+
+func notMatched(reason string, a ...interface{}) *TriggerMatch {
+	return &TriggerMatch{Reason: fmt.Sprintf(reason, a...)}
+}
+
+// MatchTrigger matches the SCM event against the trigger policies. Error is returned if patterns in the
+// policies are invalid.
+func MatchTrigger(policy *c_v1alpha1.SCMTriggerPolicy, data *EventData) (*TriggerMatch, error) {
+	switch data.Type {
+	case TagReleaseEventType:
+		if !policy.TagRelease.Enabled {
+			return notMatched("tag release policy is disabled"), nil
+		}
+
+		ref := TagRef(data.Ref)
+		ok, err := MatchRefFilter(policy.TagRelease.Filter, ref)
+		if err != nil {
+			return nil, err
+		}
+		if !ok {
+			return notMatched("tag %s is filtered out", ref), nil
+		}
+
+		tag := data.Ref
+		// If tag contains "/", trim it.
+		if index := strings.LastIndex(tag, "/"); index >= 0 && len(tag) > index+1 {
+			tag = tag[index+1:]
+		}
+		m := matched("tag %s matches", ref)
+		m.Tag = tag
+		return m, nil
+	case PushEventType:
+		push := policy.Push
+		if len(push.Branches) == 0 && push.Filter == nil {
+			return notMatched("no branches configured in push policy"), nil
+		}
+
+		if len(push.Branches) > 0 {
+			trimmedBranch := data.Branch
+			if index := strings.LastIndex(trimmedBranch, "/"); index >= 0 && len(trimmedBranch) > index+1 {
+				trimmedBranch = trimmedBranch[index+1:]
+			}
+			if !contains(push.Branches, trimmedBranch) {
+				return notMatched("branch %s is not in branches of push policy", trimmedBranch), nil
+			}
+		}
+
+		ref := BranchRef(data.Branch)
+		ok, err := MatchRefFilter(push.Filter, ref)
+		if err != nil {
+			return nil, err
+		}
+		if !ok {
+			return notMatched("branch %s is filtered out", ref), nil
+		}
+
+		ok, err = MatchPathFilter(&push.PathFilter, data.ChangedFiles)
+		if err != nil {
+			return nil, err
+		}
+		if !ok {
+			return notMatched("no changed files pass path filter"), nil
+		}
+		return matched("branch %s matches", ref), nil
+	case PullRequestEventType:
+		pr := policy.PullRequest
+		if !pr.Enabled {
+			return notMatched("pull request policy is disabled"), nil
+		}
+
+		// Always trigger if Branches are not specified
+		if len(pr.Branches) > 0 && !contains(pr.Branches, data.Branch) {
+			return notMatched("target branch %s is not in branches of pull request policy", data.Branch), nil
+		}
+
+		ref := BranchRef(data.Branch)
+		ok, err := MatchRefFilter(pr.Filter, ref)
+		if err != nil {
+			return nil, err
+		}
+		if !ok {
+			return notMatched("target branch %s is filtered out", ref), nil
+		}
+
+		ok, err = MatchPathFilter(&pr.PathFilter, data.ChangedFiles)
+		if err != nil {
+			return nil, err
+		}
+		if !ok {
+			return notMatched("no changed files pass path filter"), nil
+		}
+
+		m := matched("target branch %s matches", ref)
+		m.ByPR = true
+		return m, nil
+	case PullRequestCommentEventType:
+		if !contains(policy.PullRequestComment.Comments, data.Comment) {
+			return notMatched("comment is not in comments of pull request comment policy"), nil
+		}
+
+		m := matched("comment matches")
+		m.ByPR = true
+		return m, nil
+	case PostCommitEventType:
+		pc := policy.PostCommit
+		if !pc.Enabled {
+			return notMatched("post commit policy is disabled"), nil
+		}
+
+		// For Backward Compatibility, old version workflowTriggers lack of these field
+		// and can normally trigger workflow
+		if pc.RootURL == "" || pc.WorkflowURL == "" || len(data.ChangedFiles) == 0 {
+			return matched("post commit policy is enabled"), nil
+		}
+
+		for _, file := range data.ChangedFiles {
+			fullPath := pc.RootURL + "/" + file
+			if strings.Contains(fullPath, pc.WorkflowURL) {
+				return matched("changed file %s is in workflow URL", file), nil
+			}
+		}
+		return notMatched("no changed files in workflow URL"), nil
+	}
+
+	return notMatched("unsupported event type %s", data.Type), nil
+}
+
+func contains(items []string, item string) bool {
+	for _, v := range items {
+		if v == item {
+			return true
+		}
+	}
+	return false
+}
+
+// NeedChangedFiles checks whether changed files are needed to match events of the type against the trigger policies.
+func NeedChangedFiles(policy *c_v1alpha1.SCMTriggerPolicy, eventType EventType) bool {
+	switch eventType {
+	case PushEventType:
+		return HasPathFilter(&policy.Push.PathFilter)
+	case PullRequestEventType:
+		return policy.PullRequest.Enabled && HasPathFilter(&policy.PullRequest.PathFilter)
+	default:
+		return false
+	}
+}
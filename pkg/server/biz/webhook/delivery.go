@@ -0,0 +1,224 @@
+package webhook
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+
+	core_v1 "k8s.io/api/core/v1"
+	meta_v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/util/rand"
+
+	"github.com/caicloud/cyclone/pkg/meta"
+	api "github.com/caicloud/cyclone/pkg/server/apis/v1alpha1"
+	"github.com/caicloud/cyclone/pkg/server/common"
+	"github.com/caicloud/cyclone/pkg/util/k8s"
+)
+
+// deliveryNamePrefix is prefix of names of webhook deliveries.
+const deliveryNamePrefix = "webhook-delivery-"
+
+// NewDelivery creates a pending delivery for the SCM event, the payload is truncated if it's larger than
+// 'maxPayloadSize' bytes.
+func NewDelivery(integration string, event interface{}, payload []byte, maxPayloadSize int) (*api.WebhookDelivery, error) {
+	data, err := json.Marshal(event)
+	if err != nil {
+		return nil, fmt.Errorf("marshal event error: %v", err)
+	}
+
+	delivery := &api.WebhookDelivery{
+		ObjectMeta: meta_v1.ObjectMeta{
+			Name: deliveryNamePrefix + rand.String(8),
+		},
+		Spec: api.WebhookDeliverySpec{
+			Integration: integration,
+			Event:       data,
+			ReceivedAt:  meta_v1.Now(),
+		},
+		Status: api.WebhookDeliveryStatus{
+			State: api.WebhookDeliveryPending,
+		},
+	}
+	if len(payload) > maxPayloadSize {
+		payload = payload[:maxPayloadSize]
+		delivery.Spec.PayloadTruncated = true
+	}
+	delivery.Spec.Payload = string(payload)
+
+	return delivery, nil
+}
+
+// Redelivery creates a pending delivery that re-delivers the event of the delivery.
+func Redelivery(delivery *api.WebhookDelivery) *api.WebhookDelivery {
+	return &api.WebhookDelivery{
+		ObjectMeta: meta_v1.ObjectMeta{
+			Name: deliveryNamePrefix + rand.String(8),
+		},
+		Spec: api.WebhookDeliverySpec{
+			Integration:      delivery.Spec.Integration,
+			Event:            delivery.Spec.Event,
+			Payload:          delivery.Spec.Payload,
+			PayloadTruncated: delivery.Spec.PayloadTruncated,
+			ReceivedAt:       meta_v1.Now(),
+			RedeliveryOf:     delivery.Name,
+		},
+		Status: api.WebhookDeliveryStatus{
+			State: api.WebhookDeliveryPending,
+		},
+	}
+}
+
+// ToConfigMap converts a webhook delivery to a ConfigMap.
+func ToConfigMap(delivery *api.WebhookDelivery) (*core_v1.ConfigMap, error) {
+	objectMeta := delivery.ObjectMeta
+	objectMeta.Labels = make(map[string]string)
+	for k, v := range delivery.Labels {
+		objectMeta.Labels[k] = v
+	}
+	objectMeta.Labels[meta.LabelWebhookDeliveryState] = string(delivery.Status.State)
+	if delivery.Spec.Integration != "" {
+		objectMeta.Labels[meta.LabelWebhookDeliveryIntegration] = delivery.Spec.Integration
+	}
+
+	data, err := json.Marshal(struct {
+		Spec   api.WebhookDeliverySpec   `json:"spec"`
+		Status api.WebhookDeliveryStatus `json:"status"`
+	}{delivery.Spec, delivery.Status})
+	if err != nil {
+		return nil, err
+	}
+
+	return &core_v1.ConfigMap{
+		ObjectMeta: objectMeta,
+		Data: map[string]string{
+			common.ConfigMapKeyWebhookDelivery: string(data),
+		},
+	}, nil
+}
+
+// FromConfigMap converts a ConfigMap to a webhook delivery.
+func FromConfigMap(cm *core_v1.ConfigMap) (*api.WebhookDelivery, error) {
+	data, ok := cm.Data[common.ConfigMapKeyWebhookDelivery]
+	if !ok {
+		return nil, fmt.Errorf("ConfigMap %s is not a webhook delivery", cm.Name)
+	}
+
+	delivery := &api.WebhookDelivery{
+		ObjectMeta: cm.ObjectMeta,
+	}
+	content := struct {
+		Spec   *api.WebhookDeliverySpec   `json:"spec"`
+		Status *api.WebhookDeliveryStatus `json:"status"`
+	}{&delivery.Spec, &delivery.Status}
+	if err := json.Unmarshal([]byte(data), &content); err != nil {
+		return nil, err
+	}
+
+	return delivery, nil
+}
+
+// CreateDelivery stores the delivery in the namespace.
+func CreateDelivery(client k8s.Interface, namespace string, delivery *api.WebhookDelivery) (*api.WebhookDelivery, error) {
+	cm, err := ToConfigMap(delivery)
+	if err != nil {
+		return nil, err
+	}
+
+	cm, err = client.CoreV1().ConfigMaps(namespace).Create(context.TODO(), cm, meta_v1.CreateOptions{})
+	if err != nil {
+		return nil, err
+	}
+
+	return FromConfigMap(cm)
+}
+
+// GetDelivery gets the delivery in the namespace.
+func GetDelivery(client k8s.Interface, namespace, name string) (*api.WebhookDelivery, error) {
+	cm, err := client.CoreV1().ConfigMaps(namespace).Get(context.TODO(), name, meta_v1.GetOptions{})
+	if err != nil {
+		return nil, err
+	}
+
+	return FromConfigMap(cm)
+}
+
+// UpdateDelivery updates spec and status of the delivery.
+func UpdateDelivery(client k8s.Interface, delivery *api.WebhookDelivery) (*api.WebhookDelivery, error) {
+	cm, err := ToConfigMap(delivery)
+	if err != nil {
+		return nil, err
+	}
+
+	cm, err = client.CoreV1().ConfigMaps(delivery.Namespace).Update(context.TODO(), cm, meta_v1.UpdateOptions{})
+	if err != nil {
+		return nil, err
+	}
+
+	return FromConfigMap(cm)
+}
+
+// ListDeliveries lists deliveries in the namespace, use meta_v1.NamespaceAll to list in all namespaces. Deliveries
+// are filtered by the integration and the state if they are not empty, and sorted from the newest to the oldest.
+func ListDeliveries(client k8s.Interface, namespace, integration string, state api.WebhookDeliveryState) ([]api.WebhookDelivery, error) {
+	selector := meta.LabelExistsSelector(meta.LabelWebhookDeliveryState)
+	labelMap := make(map[string]string)
+	if integration != "" {
+		labelMap[meta.LabelWebhookDeliveryIntegration] = integration
+	}
+	if state != "" {
+		labelMap[meta.LabelWebhookDeliveryState] = string(state)
+	}
+	if len(labelMap) > 0 {
+		selector = labels.Set(labelMap).String()
+	}
+
+	cms, err := client.CoreV1().ConfigMaps(namespace).List(context.TODO(), meta_v1.ListOptions{
+		LabelSelector: selector,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	deliveries := make([]api.WebhookDelivery, 0, len(cms.Items))
+	for i := range cms.Items {
+		delivery, err := FromConfigMap(&cms.Items[i])
+		if err != nil {
+			continue
+		}
+		deliveries = append(deliveries, *delivery)
+	}
+
+	sort.SliceStable(deliveries, func(i, j int) bool {
+		return deliveries[i].Spec.ReceivedAt.After(deliveries[j].Spec.ReceivedAt.Time)
+	})
+
+	return deliveries, nil
+}
+
+// PruneDeliveries deletes the oldest finished deliveries in the namespace, so that at most 'retention' finished
+// deliveries are kept. Pending deliveries are never deleted.
+func PruneDeliveries(client k8s.Interface, namespace string, retention int) error {
+	deliveries, err := ListDeliveries(client, namespace, "", "")
+	if err != nil {
+		return err
+	}
+
+	kept := 0
+	for _, d := range deliveries {
+		if d.Status.State == api.WebhookDeliveryPending {
+			continue
+		}
+
+		kept++
+		if kept <= retention {
+			continue
+		}
+		if err := client.CoreV1().ConfigMaps(namespace).Delete(context.TODO(), d.Name, meta_v1.DeleteOptions{}); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,87 @@
+package webhook
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	meta_v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	api "github.com/caicloud/cyclone/pkg/server/apis/v1alpha1"
+	"github.com/caicloud/cyclone/pkg/util/k8s/fake"
+)
+
+type event struct {
+	Repo string
+}
+
+func TestNewDelivery(t *testing.T) {
+	delivery, err := NewDelivery("github", &event{Repo: "caicloud/cyclone"}, []byte("0123456789"), 4)
+	assert.Nil(t, err)
+	assert.Equal(t, "github", delivery.Spec.Integration)
+	assert.Equal(t, `{"Repo":"caicloud/cyclone"}`, string(delivery.Spec.Event))
+	assert.Equal(t, "0123", delivery.Spec.Payload)
+	assert.True(t, delivery.Spec.PayloadTruncated)
+	assert.Equal(t, api.WebhookDeliveryPending, delivery.Status.State)
+
+	redelivery := Redelivery(delivery)
+	assert.NotEqual(t, delivery.Name, redelivery.Name)
+	assert.Equal(t, delivery.Name, redelivery.Spec.RedeliveryOf)
+	assert.Equal(t, delivery.Spec.Event, redelivery.Spec.Event)
+	assert.Equal(t, api.WebhookDeliveryPending, redelivery.Status.State)
+}
+
+func TestDeliveryStore(t *testing.T) {
+	client := fake.NewSimpleClientset()
+	ns := "cyclone-devops"
+
+	var names []string
+	for i := 0; i < 4; i++ {
+		d, err := NewDelivery("github", &event{Repo: "caicloud/cyclone"}, []byte("{}"), 1024)
+		assert.Nil(t, err)
+		d.Spec.ReceivedAt = meta_v1.NewTime(time.Now().Add(time.Duration(i) * time.Minute))
+		d, err = CreateDelivery(client, ns, d)
+		assert.Nil(t, err)
+		names = append(names, d.Name)
+	}
+
+	d, err := GetDelivery(client, ns, names[0])
+	assert.Nil(t, err)
+	assert.Equal(t, `{"Repo":"caicloud/cyclone"}`, string(d.Spec.Event))
+	d.Status.State = api.WebhookDeliveryFailed
+	d.Status.Attempts = 5
+	d.Status.Triggers = []api.WebhookDeliveryTrigger{{WorkflowTrigger: "ci", Error: "error"}}
+	_, err = UpdateDelivery(client, d)
+	assert.Nil(t, err)
+
+	deliveries, err := ListDeliveries(client, ns, "", "")
+	assert.Nil(t, err)
+	assert.Equal(t, 4, len(deliveries))
+	assert.Equal(t, names[3], deliveries[0].Name)
+
+	deliveries, err = ListDeliveries(client, meta_v1.NamespaceAll, "github", api.WebhookDeliveryFailed)
+	assert.Nil(t, err)
+	assert.Equal(t, 1, len(deliveries))
+	assert.Equal(t, 5, deliveries[0].Status.Attempts)
+	assert.Equal(t, "ci", deliveries[0].Status.Triggers[0].WorkflowTrigger)
+
+	deliveries, err = ListDeliveries(client, ns, "gitlab", "")
+	assert.Nil(t, err)
+	assert.Equal(t, 0, len(deliveries))
+
+	for _, name := range names[1:3] {
+		d, _ := GetDelivery(client, ns, name)
+		d.Status.State = api.WebhookDeliverySucceeded
+		_, err = UpdateDelivery(client, d)
+		assert.Nil(t, err)
+	}
+
+	// The oldest finished delivery is pruned, and the pending one is kept.
+	assert.Nil(t, PruneDeliveries(client, ns, 2))
+	deliveries, _ = ListDeliveries(client, ns, "", "")
+	assert.Equal(t, 3, len(deliveries))
+	_, err = GetDelivery(client, ns, names[0])
+	assert.NotNil(t, err)
+	_, err = GetDelivery(client, ns, names[3])
+	assert.Nil(t, err)
+}
@@ -0,0 +1,177 @@
+package webhook
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/PaesslerAG/jsonpath"
+
+	"github.com/caicloud/cyclone/pkg/apis/cyclone/v1alpha1"
+)
+
+// DefaultSignatureHeader is the default header to carry signatures of webhook payloads.
+const DefaultSignatureHeader = "X-Cyclone-Signature"
+
+// signaturePrefix is the optional prefix of signatures.
+const signaturePrefix = "sha256="
+
+// Sign signs the payload with the secret, the signature is hex encoded HMAC-SHA256 digest with prefix 'sha256='.
+func Sign(secret string, payload []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(payload)
+	return signaturePrefix + hex.EncodeToString(mac.Sum(nil))
+}
+
+// Verify verifies the signature of the payload, the 'sha256=' prefix of the signature is optional.
+func Verify(secret string, payload []byte, signature string) bool {
+	if secret == "" || signature == "" {
+		return false
+	}
+
+	expected := Sign(secret, payload)
+	if !strings.HasPrefix(signature, signaturePrefix) {
+		signature = signaturePrefix + signature
+	}
+	return hmac.Equal([]byte(expected), []byte(strings.ToLower(signature)))
+}
+
+// Values are values extracted from a webhook payload.
+type Values struct {
+	// Variables are global variables to set, keyed by variable name
+	Variables map[string]string
+	// StageParams are stage parameters to set, keyed by stage name and then parameter name
+	StageParams map[string]map[string]string
+	// Missing are JSONPaths of required values not found in the payload
+	Missing []string
+}
+
+// Extract extracts values from the JSON payload by the rules. Values not found are skipped, and JSONPaths of
+// them are recorded in 'Missing' if they are required.
+func Extract(rules []v1alpha1.WebhookRule, payload []byte) (*Values, error) {
+	var obj interface{}
+	if err := json.Unmarshal(payload, &obj); err != nil {
+		return nil, fmt.Errorf("payload is not valid JSON: %v", err)
+	}
+
+	values := &Values{
+		Variables:   make(map[string]string),
+		StageParams: make(map[string]map[string]string),
+	}
+	for _, rule := range rules {
+		if rule.Variable == "" && (rule.Stage == "" || rule.Parameter == "") {
+			return nil, fmt.Errorf("rule %s should set either variable or stage parameter", rule.JSONPath)
+		}
+
+		eval, err := jsonpath.New(rule.JSONPath)
+		if err != nil {
+			return nil, fmt.Errorf("invalid JSONPath %s: %v", rule.JSONPath, err)
+		}
+
+		v, err := eval(context.Background(), obj)
+		if err != nil || v == nil {
+			if rule.Required {
+				values.Missing = append(values.Missing, rule.JSONPath)
+			}
+			continue
+		}
+
+		value, err := stringify(v)
+		if err != nil {
+			return nil, fmt.Errorf("value of %s error: %v", rule.JSONPath, err)
+		}
+
+		if rule.Variable != "" {
+			values.Variables[rule.Variable] = value
+		}
+		if rule.Stage != "" && rule.Parameter != "" {
+			if _, ok := values.StageParams[rule.Stage]; !ok {
+				values.StageParams[rule.Stage] = make(map[string]string)
+			}
+			values.StageParams[rule.Stage][rule.Parameter] = value
+		}
+	}
+
+	return values, nil
+}
+
+// stringify converts a value in JSON payload to string, non-string values are converted to JSON format.
+func stringify(v interface{}) (string, error) {
+	if s, ok := v.(string); ok {
+		return s, nil
+	}
+
+	b, err := json.Marshal(v)
+	if err != nil {
+		return "", err
+	}
+	return string(b), nil
+}
+
+// Apply sets the values to the WorkflowRun spec, existing variables and parameters with the same name are overridden.
+func (v *Values) Apply(spec *v1alpha1.WorkflowRunSpec) {
+	for _, name := range sortedKeys(v.Variables) {
+		value := v.Variables[name]
+		found := false
+		for i := range spec.GlobalVariables {
+			if spec.GlobalVariables[i].Name == name {
+				spec.GlobalVariables[i].Value = value
+				found = true
+				break
+			}
+		}
+		if !found {
+			spec.GlobalVariables = append(spec.GlobalVariables, v1alpha1.GlobalVariable{Name: name, Value: value})
+		}
+	}
+
+	stages := make([]string, 0, len(v.StageParams))
+	for stage := range v.StageParams {
+		stages = append(stages, stage)
+	}
+	sort.Strings(stages)
+	for _, stage := range stages {
+		params := v.StageParams[stage]
+		index := -1
+		for i := range spec.StageParams {
+			if spec.StageParams[i].Name == stage {
+				index = i
+				break
+			}
+		}
+		if index < 0 {
+			spec.StageParams = append(spec.StageParams, v1alpha1.ParameterConfig{Name: stage})
+			index = len(spec.StageParams) - 1
+		}
+
+		config := &spec.StageParams[index]
+		for _, name := range sortedKeys(params) {
+			value := params[name]
+			found := false
+			for i := range config.Parameters {
+				if config.Parameters[i].Name == name {
+					config.Parameters[i].Value = &value
+					found = true
+					break
+				}
+			}
+			if !found {
+				config.Parameters = append(config.Parameters, v1alpha1.ParameterItem{Name: name, Value: &value})
+			}
+		}
+	}
+}
+
+func sortedKeys(m map[string]string) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,69 @@
+package webhook
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/caicloud/cyclone/pkg/apis/cyclone/v1alpha1"
+)
+
+func TestVerify(t *testing.T) {
+	payload := []byte(`{"tag": "v1.0"}`)
+	signature := Sign("key", payload)
+	assert.True(t, Verify("key", payload, signature))
+	assert.True(t, Verify("key", payload, signature[len("sha256="):]))
+	assert.False(t, Verify("other", payload, signature))
+	assert.False(t, Verify("key", []byte(`{}`), signature))
+	assert.False(t, Verify("", payload, Sign("", payload)))
+	assert.False(t, Verify("key", payload, ""))
+}
+
+func TestExtract(t *testing.T) {
+	payload := []byte(`{"repository": {"name": "cyclone", "tag": "v1.0"}, "size": 12, "labels": ["a", "b"]}`)
+	values, err := Extract([]v1alpha1.WebhookRule{
+		{JSONPath: "$.repository.tag", Variable: "TAG", Stage: "build", Parameter: "tag"},
+		{JSONPath: "$.size", Variable: "SIZE"},
+		{JSONPath: "$.labels", Stage: "build", Parameter: "labels"},
+		{JSONPath: "$.missing", Variable: "MISSING"},
+	}, payload)
+	assert.Nil(t, err)
+	assert.Equal(t, map[string]string{"TAG": "v1.0", "SIZE": "12"}, values.Variables)
+	assert.Equal(t, map[string]map[string]string{"build": {"tag": "v1.0", "labels": `["a","b"]`}}, values.StageParams)
+	assert.Empty(t, values.Missing)
+
+	values, err = Extract([]v1alpha1.WebhookRule{{JSONPath: "$.missing", Variable: "MISSING", Required: true}}, payload)
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"$.missing"}, values.Missing)
+
+	_, err = Extract([]v1alpha1.WebhookRule{{JSONPath: "$.size"}}, payload)
+	assert.NotNil(t, err)
+	_, err = Extract([]v1alpha1.WebhookRule{{JSONPath: "$[", Variable: "V"}}, payload)
+	assert.NotNil(t, err)
+	_, err = Extract(nil, []byte("not json"))
+	assert.NotNil(t, err)
+}
+
+func TestApply(t *testing.T) {
+	old := "old"
+	spec := &v1alpha1.WorkflowRunSpec{
+		GlobalVariables: []v1alpha1.GlobalVariable{{Name: "TAG", Value: "latest"}},
+		StageParams: []v1alpha1.ParameterConfig{
+			{Name: "build", Parameters: []v1alpha1.ParameterItem{{Name: "tag", Value: &old}}},
+		},
+	}
+	values := &Values{
+		Variables:   map[string]string{"TAG": "v1.0", "SIZE": "12"},
+		StageParams: map[string]map[string]string{"build": {"tag": "v1.0", "image": "cyclone"}, "deploy": {"tag": "v1.0"}},
+	}
+	values.Apply(spec)
+
+	assert.Equal(t, []v1alpha1.GlobalVariable{{Name: "TAG", Value: "v1.0"}, {Name: "SIZE", Value: "12"}}, spec.GlobalVariables)
+	assert.Equal(t, 2, len(spec.StageParams))
+	assert.Equal(t, "v1.0", *spec.StageParams[0].Parameters[0].Value)
+	assert.Equal(t, "image", spec.StageParams[0].Parameters[1].Name)
+	assert.Equal(t, "cyclone", *spec.StageParams[0].Parameters[1].Value)
+	assert.Equal(t, "deploy", spec.StageParams[1].Name)
+	assert.Equal(t, "v1.0", *spec.StageParams[1].Parameters[0].Value)
+	assert.Equal(t, "old", old)
+}
@@ -23,6 +23,15 @@ const (
 	// CronTimerTrigger represents the trigger of workflowruns triggered by cron timer.
 	CronTimerTrigger = "cron-timer"
 
+	// GenericWebhookTrigger represents the trigger of workflowruns triggered by generic webhooks.
+	GenericWebhookTrigger = "generic-webhook"
+
+	// WorkflowRunCompletionTrigger represents the trigger of workflowruns triggered by completion of upstream workflowruns.
+	WorkflowRunCompletionTrigger = "workflowrun-completion"
+
+	// ImagePushTrigger represents the trigger of workflowruns triggered by pushes of container images.
+	ImagePushTrigger = "image-push"
+
 	// QuotaCPULimit represents default value of 'limits.cpu'
 	QuotaCPULimit = "2"
 	// QuotaCPURequest represents default value of 'requests.cpu'
@@ -35,6 +44,9 @@ const (
 	// SecretKeyIntegration is the key of the secret dada to indicate its value is about integration information.
 	SecretKeyIntegration = "integration"
 
+	// ConfigMapKeyWebhookDelivery is the key of the ConfigMap data to indicate its value is a webhook delivery.
+	ConfigMapKeyWebhookDelivery = "delivery"
+
 	// CachePrefixPath is the prefix path of acceleration caches
 	CachePrefixPath = "caches"
 
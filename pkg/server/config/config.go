@@ -9,6 +9,7 @@ import (
 	"github.com/caicloud/nirvana/log"
 	core_v1 "k8s.io/api/core/v1"
 
+	api "github.com/caicloud/cyclone/pkg/server/apis/v1alpha1"
 	"github.com/caicloud/cyclone/pkg/server/common"
 )
 
@@ -22,6 +23,10 @@ const (
 	// EnvRecordWebURLTemplate is the key of Environment variable to define template of record url which used in
 	// PR status 'Details' to associate PR with WorkflowRun website.
 	EnvRecordWebURLTemplate = "RECORD_WEB_URL_TEMPLATE"
+
+	// EnvSMTPPassword is the key of Environment variable to define password of the SMTP server, it overrides the
+	// password in config file, so that the password can be kept in a Secret.
+	EnvSMTPPassword = "SMTP_PASSWORD"
 )
 
 // CycloneServerConfig configures Cyclone Server
@@ -65,6 +70,9 @@ type CycloneServerConfig struct {
 	// Notifications represents the config to send notifications after workflowruns finish.
 	Notifications []NotificationEndpoint `json:"notifications"`
 
+	// SMTP configures the SMTP server to send email notifications.
+	SMTP SMTPConfig `json:"smtp"`
+
 	// RecordWebURLTemplate represents the URL template to generate web URLs for workflowruns.
 	RecordWebURLTemplate string `json:"record_web_url_template"`
 
@@ -74,6 +82,30 @@ type CycloneServerConfig struct {
 
 	// Artifact config for artifacts which are managed by cyclone server
 	Artifact ArtifactConfig `json:"artifact"`
+
+	// WebhookDelivery configures the queue to handle SCM webhook deliveries.
+	WebhookDelivery WebhookDeliveryConfig `json:"webhook_delivery"`
+
+	// Log config for workflowrun logs which are stored by cyclone server
+	Log LogConfig `json:"log"`
+
+	// UserIdentity configures how identities of users set by the gateway in front of cyclone server are verified.
+	UserIdentity UserIdentityConfig `json:"user_identity"`
+}
+
+// UserIdentityConfig configures verification of user identities. The gateway in front of cyclone server
+// authenticates users, and signs their identities in request headers with the shared secret.
+type UserIdentityConfig struct {
+	// Secret shared with the gateway to sign identities, requests requiring user identities such as approvals
+	// are rejected if it's not set.
+	Secret string `json:"secret"`
+}
+
+// LogConfig configures workflowrun logs which are stored by cyclone server
+type LogConfig struct {
+	// Retention is the default retention policy of logs, tenants can configure their own policies. Logs are kept
+	// forever if it's not set.
+	Retention api.LogRetention `json:"retention"`
 }
 
 // ArtifactConfig configures artifacts which are managed by cyclone server
@@ -85,6 +117,22 @@ type ArtifactConfig struct {
 	// RetentionDiskProtectionThreshold is a threshold in percentage format, if disk available space is less than this
 	// value, artifacts can not be stored.
 	RetentionDiskProtectionThreshold float64 `json:"retention_disk_protection_threshold"`
+
+	// Storage is the default storage of artifacts, tenants can configure their own storages. Artifacts are stored
+	// on the disk of cyclone server if it's not set.
+	Storage api.ArtifactStorage `json:"storage"`
+}
+
+// WebhookDeliveryConfig configures the queue to handle SCM webhook deliveries.
+type WebhookDeliveryConfig struct {
+	// Workers is the number of workers to handle deliveries.
+	Workers int `json:"workers"`
+	// MaxAttempts is the max number of attempts to handle a delivery, it's marked as failed after that.
+	MaxAttempts int `json:"max_attempts"`
+	// Retention is the max number of finished deliveries kept for each tenant, older ones are deleted.
+	Retention int `json:"retention"`
+	// MaxPayloadSize is the max size of payloads recorded in deliveries in bytes, larger payloads are truncated.
+	MaxPayloadSize int `json:"max_payload_size"`
 }
 
 // ClientSetConfig defines rate limit config for a Kubernetes client
@@ -144,6 +192,25 @@ type NotificationEndpoint struct {
 	URL string `json:"url"`
 }
 
+// SMTPConfig configures the SMTP server to send email notifications.
+type SMTPConfig struct {
+	// Host of the SMTP server
+	Host string `json:"host"`
+	// Port of the SMTP server
+	Port int `json:"port"`
+	// Username to authenticate to the SMTP server, no authentication if it's empty.
+	Username string `json:"username"`
+	// Password to authenticate to the SMTP server, it's overridden by the "SMTP_PASSWORD" environment variable if set.
+	Password string `json:"password"`
+	// From is the sender address of the emails, Username is used if it's empty.
+	From string `json:"from"`
+}
+
+// String implements fmt.Stringer, password is masked so that it won't be logged.
+func (c SMTPConfig) String() string {
+	return fmt.Sprintf("{Host:%s Port:%d Username:%s From:%s}", c.Host, c.Port, c.Username, c.From)
+}
+
 // Config is Workflow Controller config instance
 var Config CycloneServerConfig
 
@@ -226,6 +293,30 @@ func modifier(config *CycloneServerConfig) {
 		log.Warning("artifact RetentionDiskProtectionThreshold not configured, will use default value '0.2'")
 		config.Artifact.RetentionDiskProtectionThreshold = 0.2
 	}
+
+	if config.WebhookDelivery.Workers == 0 {
+		log.Warning("webhook delivery Workers not configured, will use default value '2'")
+		config.WebhookDelivery.Workers = 2
+	}
+
+	if config.WebhookDelivery.MaxAttempts == 0 {
+		log.Warning("webhook delivery MaxAttempts not configured, will use default value '5'")
+		config.WebhookDelivery.MaxAttempts = 5
+	}
+
+	if config.WebhookDelivery.Retention == 0 {
+		log.Warning("webhook delivery Retention not configured, will use default value '100'")
+		config.WebhookDelivery.Retention = 100
+	}
+
+	if config.WebhookDelivery.MaxPayloadSize == 0 {
+		log.Warning("webhook delivery MaxPayloadSize not configured, will use default value '65536'")
+		config.WebhookDelivery.MaxPayloadSize = 64 * 1024
+	}
+
+	if password := os.Getenv(EnvSMTPPassword); password != "" {
+		config.SMTP.Password = password
+	}
 }
 
 // GetRecordWebURLTemplate returns record web URL template. It tries to get the url from "RECORD_WEB_URL_TEMPLATE"
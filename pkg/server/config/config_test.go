@@ -1,6 +1,7 @@
 package config
 
 import (
+	"os"
 	"testing"
 )
 
@@ -44,3 +45,31 @@ func TestValidateNotification(t *testing.T) {
 		}
 	}
 }
+
+func TestModifierSMTPPassword(t *testing.T) {
+	testCases := map[string]struct {
+		env      string
+		password string
+		expected string
+	}{
+		"password from config": {
+			password: "config",
+			expected: "config",
+		},
+		"password from environment variable": {
+			env:      `p"w\d`,
+			password: "config",
+			expected: `p"w\d`,
+		},
+	}
+
+	for d, tc := range testCases {
+		os.Setenv(EnvSMTPPassword, tc.env)
+		config := &CycloneServerConfig{SMTP: SMTPConfig{Password: tc.password}}
+		modifier(config)
+		if config.SMTP.Password != tc.expected {
+			t.Errorf("Test case %s failed: expected password %s, but got %s", d, tc.expected, config.SMTP.Password)
+		}
+	}
+	os.Unsetenv(EnvSMTPPassword)
+}
@@ -0,0 +1,177 @@
+package v1alpha1
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/caicloud/nirvana/log"
+	core_v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	meta_v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/util/retry"
+
+	"github.com/caicloud/cyclone/pkg/meta"
+	api "github.com/caicloud/cyclone/pkg/server/apis/v1alpha1"
+	"github.com/caicloud/cyclone/pkg/server/biz/artifact"
+	"github.com/caicloud/cyclone/pkg/server/biz/tenant"
+	"github.com/caicloud/cyclone/pkg/server/common"
+	"github.com/caicloud/cyclone/pkg/server/config"
+	"github.com/caicloud/cyclone/pkg/server/handler"
+	"github.com/caicloud/cyclone/pkg/util/cerr"
+	"github.com/caicloud/cyclone/pkg/workflow/values/ref"
+)
+
+// presignedURLExpiration is how long pre-signed URLs of artifacts are valid.
+const presignedURLExpiration = time.Hour
+
+const (
+	// artifactStorageSecret is the secret in tenant namespaces that holds the secret key of the S3 artifact storage.
+	artifactStorageSecret = "cyclone-artifact-storage"
+	// artifactStorageSecretKey is the key of the secret key in the artifact storage secret.
+	artifactStorageSecretKey = "secretKey"
+)
+
+// extractArtifactStorageSecret replaces the plain secret key of the tenant's S3 artifact storage with a reference
+// to the artifact storage secret of the tenant, so that the secret key isn't kept in the tenant spec or returned by
+// tenant APIs. The plain secret key is returned to be saved in the secret, it's empty if there is nothing to save.
+func extractArtifactStorageSecret(tenant string, storage *api.ArtifactStorage) string {
+	if storage == nil || storage.S3 == nil || storage.S3.SecretKey == "" {
+		return ""
+	}
+	secretKey := storage.S3.SecretKey
+	if ref.NewSecretRefValue().Parse(secretKey) == nil {
+		return ""
+	}
+
+	storage.S3.SecretKey = fmt.Sprintf("${secrets.%s:%s/data.%s}", common.TenantNamespace(tenant), artifactStorageSecret, artifactStorageSecretKey)
+	return secretKey
+}
+
+// saveArtifactStorageSecret creates or updates the artifact storage secret of the tenant with the secret key.
+func saveArtifactStorageSecret(tenant, secretKey string) error {
+	namespace := common.TenantNamespace(tenant)
+	secret := &core_v1.Secret{
+		ObjectMeta: meta_v1.ObjectMeta{
+			Name:      artifactStorageSecret,
+			Namespace: namespace,
+		},
+		Data: map[string][]byte{artifactStorageSecretKey: []byte(secretKey)},
+	}
+
+	_, err := handler.K8sClient.CoreV1().Secrets(namespace).Create(context.TODO(), secret, meta_v1.CreateOptions{})
+	if errors.IsAlreadyExists(err) {
+		err = retry.RetryOnConflict(retry.DefaultRetry, func() error {
+			origin, err := handler.K8sClient.CoreV1().Secrets(namespace).Get(context.TODO(), artifactStorageSecret, meta_v1.GetOptions{})
+			if err != nil {
+				return err
+			}
+
+			newSecret := origin.DeepCopy()
+			newSecret.Data = secret.Data
+			_, err = handler.K8sClient.CoreV1().Secrets(namespace).Update(context.TODO(), newSecret, meta_v1.UpdateOptions{})
+			return err
+		})
+	}
+	if err != nil {
+		log.Errorf("Save artifact storage secret of tenant %s error: %v", tenant, err)
+		return cerr.ConvertK8sError(err)
+	}
+	return nil
+}
+
+// getArtifactStore gets the store of artifacts of the tenant.
+func getArtifactStore(name string) (artifact.Store, error) {
+	t, err := tenant.Get(handler.K8sClient, name)
+	if err != nil {
+		return nil, err
+	}
+	return newArtifactStore(t.Spec.ArtifactStorage)
+}
+
+// newArtifactStore creates store for the artifact storage of a tenant, storage in the server config is used if the
+// tenant doesn't have one.
+func newArtifactStore(storage *api.ArtifactStorage) (artifact.Store, error) {
+	if storage == nil {
+		storage = &config.Config.Artifact.Storage
+	}
+
+	if storage.Type == api.S3ArtifactStorage && storage.S3 != nil {
+		s3 := *storage.S3
+		secretKey, err := resolveSecret(s3.SecretKey)
+		if err != nil {
+			return nil, fmt.Errorf("resolve secret key of S3 artifact storage error: %v", err)
+		}
+		s3.SecretKey = secretKey
+		storage = &api.ArtifactStorage{Type: storage.Type, S3: &s3}
+	}
+
+	store, err := artifact.NewStore(storage, common.CycloneHome)
+	if err != nil {
+		return nil, cerr.ErrorUnknownInternal.Error(err)
+	}
+	return store, nil
+}
+
+// ListArtifactStores lists artifact stores of all tenants to clean artifacts in them, tenants using the same storage
+// share one store.
+func ListArtifactStores() ([]artifact.Store, error) {
+	namespaces, err := handler.K8sClient.CoreV1().Namespaces().List(context.TODO(), meta_v1.ListOptions{
+		LabelSelector: meta.LabelExistsSelector(meta.LabelTenantName),
+	})
+	if err != nil {
+		return nil, cerr.ConvertK8sError(err)
+	}
+
+	storages := []*api.ArtifactStorage{&config.Config.Artifact.Storage}
+	for _, namespace := range namespaces.Items {
+		t, err := tenant.FromNamespace(&namespace)
+		if err != nil {
+			continue
+		}
+		if t.Spec.ArtifactStorage != nil {
+			storages = append(storages, t.Spec.ArtifactStorage)
+		}
+	}
+
+	var stores []artifact.Store
+	seen := make(map[string]bool)
+	for _, storage := range storages {
+		id := string(api.LocalArtifactStorage)
+		if storage.Type == api.S3ArtifactStorage && storage.S3 != nil {
+			id = strings.Join([]string{storage.S3.Endpoint, storage.S3.Bucket, storage.S3.Prefix}, "/")
+		}
+		if seen[id] {
+			continue
+		}
+		seen[id] = true
+
+		store, err := newArtifactStore(storage)
+		if err != nil {
+			log.Warningf("Create artifact store %s error: %v", id, err)
+			continue
+		}
+		stores = append(stores, store)
+	}
+	return stores, nil
+}
+
+// getArtifactKey gets key of an artifact file in artifact stores.
+func getArtifactKey(tenant, project, workflow, workflowrun, stage, file string) (string, error) {
+	if tenant == "" || project == "" || workflow == "" || workflowrun == "" || stage == "" {
+		return "", fmt.Errorf("tenant/project/workflow/workflowrun/stage can not be empty")
+	}
+	if file == "" || strings.Contains(file, "/") || file == ".." {
+		return "", cerr.ErrorValidationFailed.Error("artifact", fmt.Sprintf("invalid file name %s", file))
+	}
+	return strings.Join([]string{tenant, project, workflow, workflowrun, artifactFolderName, stage, file}, "/"), nil
+}
+
+// getArtifactPrefix gets key prefix of artifacts produced by the stage in artifact stores.
+func getArtifactPrefix(tenant, project, workflow, workflowrun, stage string) (string, error) {
+	if tenant == "" || project == "" || workflow == "" || workflowrun == "" || stage == "" {
+		return "", fmt.Errorf("tenant/project/workflow/workflowrun/stage can not be empty")
+	}
+	return strings.Join([]string{tenant, project, workflow, workflowrun, artifactFolderName, stage, ""}, "/"), nil
+}
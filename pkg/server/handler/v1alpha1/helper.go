@@ -16,6 +16,7 @@ import (
 	"github.com/caicloud/cyclone/pkg/apis/cyclone/v1alpha1"
 	"github.com/caicloud/cyclone/pkg/meta"
 	api "github.com/caicloud/cyclone/pkg/server/apis/v1alpha1"
+	"github.com/caicloud/cyclone/pkg/server/biz/artifact"
 	"github.com/caicloud/cyclone/pkg/server/biz/integration"
 	"github.com/caicloud/cyclone/pkg/server/biz/scm"
 	"github.com/caicloud/cyclone/pkg/server/common"
@@ -28,9 +29,6 @@ import (
 
 const (
 
-	// logsFolderName is the folder name for logs files.
-	logsFolderName = "logs"
-
 	// queryFilterName represents filter by name.
 	queryFilterName = "name"
 	// queryFilterAlias represents filter by alias.
@@ -42,29 +40,8 @@ const (
 	artifactFolderName = "artifacts"
 )
 
-func getLogFilePath(tenant, project, workflow, workflowrun, stage, container string) (string, error) {
-	rf, err := getLogFolder(tenant, project, workflow, workflowrun)
-	if err != nil {
-		return "", err
-	}
-	return strings.Join([]string{rf, fmt.Sprintf("%s_%s", stage, container)}, string(os.PathSeparator)), nil
-}
-
-func getLogFolder(tenant, project, workflow, workflowrun string) (string, error) {
-	if tenant == "" || project == "" || workflow == "" || workflowrun == "" {
-		return "", fmt.Errorf("tenant/project/workflow/workflowrun can not be empty")
-	}
-	return strings.Join([]string{common.CycloneHome, tenant, project, workflow, workflowrun, logsFolderName}, string(os.PathSeparator)), nil
-}
-
-func getArtifactFolder(tenant, project, workflow, workflowrun, stage string) (string, error) {
-	if tenant == "" || project == "" || workflow == "" || workflowrun == "" || stage == "" {
-		return "", fmt.Errorf("tenant/project/workflow/workflowrun/stage can not be empty")
-	}
-	return strings.Join([]string{common.CycloneHome, tenant, project, workflow, workflowrun, artifactFolderName, stage}, string(os.PathSeparator)), nil
-}
-
-// deleteCollections deletes collections in the sub paths of a tenant in the pvc, collections including:
+// deleteCollections deletes collections in the sub paths of a tenant in the pvc and the artifact store, collections
+// including:
 // - logs
 // - artifacts/stage
 // subpaths could be:
@@ -85,6 +62,22 @@ func deleteCollections(tenant string, subpaths ...string) error {
 		return err
 	}
 
+	// remove artifacts in the store, artifacts in local store are already removed with the folder. Errors of the
+	// store are only logged, so that an unreachable store doesn't block deletion of the resources. Artifacts left
+	// in it are cleaned after retention if the storage is still in use, or by lifecycle rules of the bucket.
+	store, err := getArtifactStore(tenant)
+	if err != nil {
+		log.Errorf("get artifact store of tenant %s error:%v", tenant, err)
+		return nil
+	}
+	if _, ok := store.(*artifact.LocalStore); ok {
+		return nil
+	}
+	key := strings.Join(append([]string{tenant}, subpaths...), "/")
+	if err := store.Delete(key); err != nil {
+		log.Errorf("delete artifacts %s error:%v", key, err)
+	}
+
 	return nil
 }
 
@@ -574,3 +567,32 @@ func getSCMEventData(annos map[string]string) (*scm.EventData, error) {
 
 	return event, nil
 }
+
+func setSCMFeedback(annos map[string]string, feedback *v1alpha1.SCMFeedback) (map[string]string, error) {
+	bs, err := json.Marshal(feedback)
+	if err != nil {
+		return nil, err
+	}
+
+	if annos == nil {
+		annos = make(map[string]string)
+	}
+
+	annos[meta.AnnotationWorkflowRunSCMFeedback] = string(bs)
+	return annos, err
+}
+
+// getSCMFeedback gets the SCM feedback config from annotations, nil is returned if it's not set.
+func getSCMFeedback(annos map[string]string) (*v1alpha1.SCMFeedback, error) {
+	feedbackStr, ok := annos[meta.AnnotationWorkflowRunSCMFeedback]
+	if !ok {
+		return nil, nil
+	}
+
+	feedback := &v1alpha1.SCMFeedback{}
+	if err := json.Unmarshal([]byte(feedbackStr), feedback); err != nil {
+		return nil, fmt.Errorf("Failed to parse SCM feedback as %v", err)
+	}
+
+	return feedback, nil
+}
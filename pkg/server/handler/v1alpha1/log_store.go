@@ -0,0 +1,49 @@
+package v1alpha1
+
+import (
+	"strconv"
+	"time"
+
+	"github.com/caicloud/nirvana/log"
+
+	api "github.com/caicloud/cyclone/pkg/server/apis/v1alpha1"
+	"github.com/caicloud/cyclone/pkg/server/biz/logstore"
+	"github.com/caicloud/cyclone/pkg/server/biz/tenant"
+	"github.com/caicloud/cyclone/pkg/server/common"
+	"github.com/caicloud/cyclone/pkg/server/config"
+	"github.com/caicloud/cyclone/pkg/server/handler"
+	wfcommon "github.com/caicloud/cyclone/pkg/workflow/common"
+)
+
+// logStore stores container logs of workflowruns on the disk of cyclone server, log folders of the store are also
+// streamed by folder readers.
+var logStore = logstore.NewFileStore(common.CycloneHome)
+
+// logExclusions are containers whose logs are not searched unless they are specified explicitly.
+var logExclusions = []string{wfcommon.CoordinatorSidecarName, wfcommon.DockerInDockerSidecarName}
+
+// GetLogRetention gets the log retention policy of the tenant, retention in the server config is used if the tenant
+// doesn't have one.
+func GetLogRetention(name string) *api.LogRetention {
+	t, err := tenant.Get(handler.K8sClient, name)
+	if err != nil {
+		log.Warningf("Get tenant %s error: %v", name, err)
+		return nil
+	}
+	if t.Spec.LogRetention != nil {
+		return t.Spec.LogRetention
+	}
+	return &config.Config.Log.Retention
+}
+
+// parseLogTime parses time in unix seconds, zero time is returned if the value is empty.
+func parseLogTime(value string) (time.Time, error) {
+	if value == "" {
+		return time.Time{}, nil
+	}
+	seconds, err := strconv.ParseInt(value, 10, 64)
+	if err != nil {
+		return time.Time{}, err
+	}
+	return time.Unix(seconds, 0), nil
+}
@@ -9,16 +9,19 @@ import (
 	"net/http"
 
 	log "github.com/sirupsen/logrus"
+	core_v1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/client-go/util/retry"
 
 	"github.com/caicloud/cyclone/pkg/apis/cyclone/v1alpha1"
 	"github.com/caicloud/cyclone/pkg/meta"
 	s_v1alpha1 "github.com/caicloud/cyclone/pkg/server/apis/v1alpha1"
+	"github.com/caicloud/cyclone/pkg/server/biz/notification"
 	"github.com/caicloud/cyclone/pkg/server/biz/scm"
 	"github.com/caicloud/cyclone/pkg/server/common"
 	"github.com/caicloud/cyclone/pkg/server/config"
 	"github.com/caicloud/cyclone/pkg/server/handler"
+	"github.com/caicloud/cyclone/pkg/util"
 	utilhttp "github.com/caicloud/cyclone/pkg/util/http"
 	"github.com/caicloud/cyclone/pkg/workflow/values/ref"
 )
@@ -44,24 +47,57 @@ func HandleWorkflowRunNotification(ctx context.Context, wfr *v1alpha1.WorkflowRu
 	return nil, nil
 }
 
-// sendNotifications send notifications to subscribe systems, and record the results in status.
+// sendNotifications send notifications to subscribe systems and receivers configured in the workflow, and
+// record the results in status.
 func sendNotifications(wfr *v1alpha1.WorkflowRun) error {
 	// If already there are notification status, no need to send notifications again.
 	if wfr.Status.Notifications != nil {
 		return nil
 	}
 
+	status := make(map[string]v1alpha1.NotificationStatus)
+	sendEndpointNotifications(wfr, status)
+	sendReceiverNotifications(wfr, status)
+
+	// Update WorkflowRun notification status with retry.
+	err := retry.RetryOnConflict(retry.DefaultRetry, func() error {
+		// Get latest WorkflowRun.
+		latest, err := handler.K8sClient.CycloneV1alpha1().WorkflowRuns(wfr.Namespace).Get(context.TODO(), wfr.Name, metav1.GetOptions{})
+		if err != nil {
+			return err
+		}
+
+		if latest.Status.Notifications == nil {
+			latest.Status.Notifications = status
+			_, err = handler.K8sClient.CycloneV1alpha1().WorkflowRuns(wfr.Namespace).Update(context.TODO(), latest, metav1.UpdateOptions{})
+			return err
+		}
+
+		return nil
+	})
+
+	if err != nil {
+		log.WithField("name", wfr.Name).Error("Update workflowrun notification status error: ", err)
+	}
+
+	return err
+}
+
+// sendEndpointNotifications sends the WorkflowRun to notification endpoints configured in Cyclone server.
+func sendEndpointNotifications(wfr *v1alpha1.WorkflowRun, status map[string]v1alpha1.NotificationStatus) {
+	if len(config.Config.Notifications) == 0 {
+		return
+	}
+
 	// Send notifications with workflowrun.
 	bodyBytes, err := json.Marshal(wfr)
 	if err != nil {
 		log.WithField("wfr", wfr.Name).Error("Failed to marshal workflowrun: ", err)
-		return err
+		return
 	}
-	body := bytes.NewReader(bodyBytes)
 
-	status := make(map[string]v1alpha1.NotificationStatus)
 	for _, endpoint := range config.Config.Notifications {
-		req, err := http.NewRequest(http.MethodPost, endpoint.URL, body)
+		req, err := http.NewRequest(http.MethodPost, endpoint.URL, bytes.NewReader(bodyBytes))
 		if err != nil {
 			err = fmt.Errorf("Failed to new notification request: %v", err)
 			log.WithField("wfr", wfr.Name).Error(err)
@@ -76,59 +112,111 @@ func sendNotifications(wfr *v1alpha1.WorkflowRun) error {
 
 		resp, err := http.DefaultClient.Do(req)
 		if err != nil {
-			s := v1alpha1.NotificationStatus{
-				Result: v1alpha1.NotificationResultFailed,
-			}
-
 			log.WithField("wfr", wfr.Name).Errorf("Failed to send notification for %s: %v", endpoint.Name, err)
-			if resp != nil {
-				defer func() {
-					if err := resp.Body.Close(); err != nil {
-						log.WithField("wfr", wfr.Name).Errorf("Fail to close response body as: %v", err)
-					}
-				}()
-				body, err := ioutil.ReadAll(resp.Body)
-				if err != nil {
-					log.Error(err)
-					s.Message = err.Error()
-				} else {
-					s.Message = fmt.Sprintf("Status code: %d, error: %s", resp.StatusCode, body)
-				}
+			status[endpoint.Name] = v1alpha1.NotificationStatus{
+				Result:  v1alpha1.NotificationResultFailed,
+				Message: err.Error(),
 			}
-
-			status[endpoint.Name] = s
 			continue
 		}
 
-		log.WithField("wfr", wfr.Name).Infof("Status code of notification for %s: %d", endpoint.Name, resp.StatusCode)
-		status[endpoint.Name] = v1alpha1.NotificationStatus{
+		s := v1alpha1.NotificationStatus{
 			Result:  v1alpha1.NotificationResultSucceeded,
 			Message: fmt.Sprintf("Status code: %d", resp.StatusCode),
 		}
+		if resp.StatusCode/100 != 2 {
+			body, err := ioutil.ReadAll(resp.Body)
+			if err != nil {
+				log.Error(err)
+			}
+			s.Result = v1alpha1.NotificationResultFailed
+			s.Message = fmt.Sprintf("Status code: %d, error: %s", resp.StatusCode, body)
+		}
+		if err := resp.Body.Close(); err != nil {
+			log.WithField("wfr", wfr.Name).Errorf("Fail to close response body as: %v", err)
+		}
+
+		log.WithField("wfr", wfr.Name).Infof("Status code of notification for %s: %d", endpoint.Name, resp.StatusCode)
+		status[endpoint.Name] = s
 	}
+}
 
-	// Update WorkflowRun notification status with retry.
-	err = retry.RetryOnConflict(retry.DefaultRetry, func() error {
-		// Get latest WorkflowRun.
-		latest, err := handler.K8sClient.CycloneV1alpha1().WorkflowRuns(wfr.Namespace).Get(context.TODO(), wfr.Name, metav1.GetOptions{})
+// sendReceiverNotifications sends notifications to receivers configured in the workflow according to its
+// notification policy.
+func sendReceiverNotifications(wfr *v1alpha1.WorkflowRun, status map[string]v1alpha1.NotificationStatus) {
+	if wfr.Spec.WorkflowRef == nil {
+		return
+	}
+
+	wf, err := handler.K8sClient.CycloneV1alpha1().Workflows(wfr.Namespace).Get(context.TODO(), wfr.Spec.WorkflowRef.Name, metav1.GetOptions{})
+	if err != nil {
+		log.WithField("wfr", wfr.Name).Error("Failed to get workflow: ", err)
+		return
+	}
+
+	receivers := wf.Spec.Notification.Receivers
+	if len(receivers) == 0 || !notification.ShouldNotify(wf.Spec.Notification.Policy, wfr.Status.Overall.Phase) {
+		return
+	}
+
+	msg := &notification.Message{
+		Tenant:      common.NamespaceTenant(wfr.Namespace),
+		Project:     wfr.Labels[meta.LabelProjectName],
+		Workflow:    wf.Name,
+		WorkflowRun: wfr,
+		Phase:       wfr.Status.Overall.Phase,
+	}
+	if msg.Project != "" {
+		recordURL, err := generateRecordURL(msg.Tenant, msg.Project, msg.Workflow, wfr.Name)
 		if err != nil {
-			return err
+			log.WithField("wfr", wfr.Name).Warning("Failed to generate record URL: ", err)
 		}
+		msg.RecordURL = recordURL
+	}
 
-		if latest.Status.Notifications == nil {
-			latest.Status.Notifications = status
-			_, err = handler.K8sClient.CycloneV1alpha1().WorkflowRuns(wfr.Namespace).Update(context.TODO(), latest, metav1.UpdateOptions{})
-			return err
+	secretGetter := func(ns, name string) (*core_v1.Secret, error) {
+		return handler.K8sClient.CoreV1().Secrets(ns).Get(context.TODO(), name, metav1.GetOptions{})
+	}
+	refProcessor := ref.NewProcessor(wfr, "", secretGetter)
+	for i := range receivers {
+		receiver := &receivers[i]
+		name := notification.ReceiverName(receiver, i)
+		err := sendReceiverNotification(msg, receiver, refProcessor)
+		if err != nil {
+			log.WithField("wfr", wfr.Name).Errorf("Failed to send notification to %s: %v", name, err)
+			status[name] = v1alpha1.NotificationStatus{
+				Result:  v1alpha1.NotificationResultFailed,
+				Message: err.Error(),
+			}
+			continue
 		}
 
-		return nil
-	})
+		status[name] = v1alpha1.NotificationStatus{
+			Result:  v1alpha1.NotificationResultSucceeded,
+			Message: fmt.Sprintf("Sent to %d addresses", len(receiver.Addresses)),
+		}
+	}
+}
 
+func sendReceiverNotification(msg *notification.Message, receiver *v1alpha1.NotificationReceiver, refProcessor *ref.Processor) error {
+	sender, err := notification.GetSender(receiver.Type)
 	if err != nil {
-		log.WithField("name", wfr.Name).Error("Update workflowrun notification status error: ", err)
+		return err
 	}
 
-	return err
+	var addresses []string
+	for _, address := range receiver.Addresses {
+		resolved, err := refProcessor.ResolveRefStringValue(address)
+		if err != nil {
+			return fmt.Errorf("resolve address error: %v", err)
+		}
+		addresses = append(addresses, resolved)
+	}
+	if len(addresses) == 0 {
+		return fmt.Errorf("no addresses")
+	}
+
+	return sender.Send(msg, addresses, receiver.Template)
 }
 
 func updatePullRequestStatus(wfr *v1alpha1.WorkflowRun) error {
@@ -162,7 +250,7 @@ func updatePullRequestStatus(wfr *v1alpha1.WorkflowRun) error {
 		return fmt.Errorf("Fail to get workflow name from labels of workflowrun %s", wfrName)
 	}
 
-	scm, err := getSCMSourceFromWorkflowRun(wfr)
+	scmSource, err := getSCMSourceFromWorkflowRun(wfr)
 	if err != nil {
 		return err
 	}
@@ -177,7 +265,126 @@ func updatePullRequestStatus(wfr *v1alpha1.WorkflowRun) error {
 		return err
 	}
 
-	return createSCMStatus(scm, wfr.Status.Overall.Phase, recordURL, event)
+	feedback, err := getSCMFeedback(wfr.Annotations)
+	if err != nil {
+		return err
+	}
+	if feedback == nil {
+		return createSCMStatus(scmSource, wfr.Status.Overall.Phase, recordURL, event)
+	}
+
+	provider, err := scm.GetSCMProvider(scmSource)
+	if err != nil {
+		return err
+	}
+
+	summary := scm.NewSummary(wfr, recordURL, nil)
+	if feedback.StageStatuses {
+		for _, stage := range feedbackStages(wfr, tenant, wfName) {
+			err := provider.CreateCommitStatus(event.Repo, event.CommitSHA, &scm.CommitStatus{
+				Phase:       stage.Phase,
+				Context:     scm.StageStatusContext(stage.Name),
+				Description: stage.Description(),
+				TargetURL:   recordURL,
+			})
+			if err != nil {
+				log.WithField("wfr", wfrName).Warningf("Failed to create status for stage %s: %v", stage.Name, err)
+			}
+		}
+	}
+
+	if feedback.Deployment != nil {
+		if creator, ok := provider.(scm.DeploymentStatusCreator); ok {
+			err := creator.CreateDeploymentStatus(event.Repo, &scm.DeploymentStatus{
+				Environment:    feedback.Deployment.Environment,
+				CommitSHA:      event.CommitSHA,
+				Phase:          wfr.Status.Overall.Phase,
+				LogURL:         recordURL,
+				EnvironmentURL: feedback.Deployment.URL,
+			})
+			if err != nil {
+				log.WithField("wfr", wfrName).Warningf("Failed to create deployment status: %v", err)
+			}
+		} else {
+			log.WithField("wfr", wfrName).Warningf("Deployment statuses are not supported by %s", scmSource.Type)
+		}
+	}
+
+	// Notifications are sent only once for each workflowrun, so the summary is commented only once as well.
+	if feedback.Comment && util.IsWorkflowRunTerminated(wfr) && wfr.Status.Notifications == nil && event.PullRequestNumber != 0 {
+		artifacts, err := feedbackArtifacts(tenant, project, wfName, wfr)
+		if err != nil {
+			log.WithField("wfr", wfrName).Warningf("Failed to list artifacts: %v", err)
+		}
+		summary = scm.NewSummary(wfr, recordURL, artifacts)
+		if err := provider.CreatePullRequestComment(event.Repo, event.PullRequestNumber, summary.Markdown()); err != nil {
+			log.WithField("wfr", wfrName).Warningf("Failed to comment on pull request %d: %v", event.PullRequestNumber, err)
+		}
+	}
+
+	// Check runs take the place of the overall status, fall back to it if check runs are not supported.
+	if feedback.Checks {
+		if creator, ok := provider.(scm.CheckRunCreator); ok {
+			err := creator.CreateCheckRun(event.Repo, scm.NewCheckRun(summary, event.CommitSHA))
+			if err == nil {
+				return nil
+			}
+			log.WithField("wfr", wfrName).Warningf("Failed to create check run, fall back to commit status: %v", err)
+		} else {
+			log.WithField("wfr", wfrName).Warningf("Check runs are not supported by %s, fall back to commit status", scmSource.Type)
+		}
+	}
+
+	return provider.CreateStatus(wfr.Status.Overall.Phase, recordURL, event.Repo, event.CommitSHA)
+}
+
+// feedbackStages gets results of all stages in the workflow to report their statuses. Stages that are not started
+// are pending, and they are cancelled if the workflowrun has terminated.
+func feedbackStages(wfr *v1alpha1.WorkflowRun, tenant, wfName string) []scm.StageResult {
+	summary := scm.NewSummary(wfr, "", nil)
+	wf, err := handler.K8sClient.CycloneV1alpha1().Workflows(common.TenantNamespace(tenant)).Get(context.TODO(), wfName, metav1.GetOptions{})
+	if err != nil {
+		log.WithField("wfr", wfr.Name).Warningf("Failed to get workflow %s, only report statuses of started stages: %v", wfName, err)
+		return summary.Stages
+	}
+
+	stages := summary.Stages
+	for _, stage := range wf.Spec.Stages {
+		if _, ok := wfr.Status.Stages[stage.Name]; ok {
+			continue
+		}
+
+		result := scm.StageResult{
+			Name:  stage.Name,
+			Phase: v1alpha1.StatusPending,
+		}
+		if util.IsWorkflowRunTerminated(wfr) {
+			result.Phase = v1alpha1.StatusCancelled
+			result.Message = "The stage was not run."
+		}
+		stages = append(stages, result)
+	}
+
+	return stages
+}
+
+// feedbackArtifacts lists file names of artifacts produced by stages of the workflowrun.
+func feedbackArtifacts(tenant, project, wfName string, wfr *v1alpha1.WorkflowRun) (map[string][]string, error) {
+	var stages []string
+	for stage := range wfr.Status.Stages {
+		stages = append(stages, stage)
+	}
+
+	artifacts, err := listStageArtifacts(tenant, project, wfName, wfr.Name, stages)
+	if err != nil {
+		return nil, err
+	}
+
+	files := make(map[string][]string)
+	for _, artifact := range artifacts {
+		files[artifact.Stage] = append(files[artifact.Stage], artifact.File)
+	}
+	return files, nil
 }
 
 func getSCMSourceFromWorkflowRun(wfr *v1alpha1.WorkflowRun) (*s_v1alpha1.SCMSource, error) {
@@ -0,0 +1,140 @@
+package v1alpha1
+
+import (
+	"context"
+	"strconv"
+	"time"
+
+	"github.com/caicloud/nirvana/log"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/client-go/util/retry"
+
+	"github.com/caicloud/cyclone/pkg/apis/cyclone/v1alpha1"
+	"github.com/caicloud/cyclone/pkg/server/biz/hook"
+	"github.com/caicloud/cyclone/pkg/server/biz/scm"
+	"github.com/caicloud/cyclone/pkg/server/common"
+	"github.com/caicloud/cyclone/pkg/server/handler"
+)
+
+// StartSCMTriggerPoller starts to poll repos for SCM triggers in poll mode, the poller runs until the server exits.
+func StartSCMTriggerPoller() {
+	go wait.Until(pollSCMTriggers, pollPeriod, wait.NeverStop)
+}
+
+// pollSCMTriggers polls repos for SCM triggers whose poll interval has elapsed since the last poll.
+func pollSCMTriggers() {
+	wfts, err := handler.K8sClient.CycloneV1alpha1().WorkflowTriggers(metav1.NamespaceAll).List(context.TODO(), metav1.ListOptions{})
+	if err != nil {
+		log.Errorf("Failed to list SCM triggers: %v", err)
+		return
+	}
+
+	now := time.Now()
+	for i := range wfts.Items {
+		wft := &wfts.Items[i]
+		if wft.Spec.Disabled || !hook.IsSCMPolling(wft) {
+			continue
+		}
+		interval, err := time.ParseDuration(wft.Spec.SCM.PollInterval)
+		if err != nil || interval < minPollInterval {
+			log.Warningf("Invalid poll interval of SCM trigger %s/%s: %s", wft.Namespace, wft.Name, wft.Spec.SCM.PollInterval)
+			continue
+		}
+		if status := wft.Status.SCM; status != nil && status.LastPollTime != nil && now.Sub(status.LastPollTime.Time) < interval {
+			continue
+		}
+
+		if err := pollSCMTrigger(wft); err != nil {
+			log.Warningf("Poll repo for SCM trigger %s/%s error: %v", wft.Namespace, wft.Name, err)
+		}
+	}
+}
+
+// pollSCMTrigger gets refs and pull requests of the repo, and delivers events of changes since the last poll to
+// the trigger in the same way as webhooks. The polled status is updated on the observed version of the trigger
+// before delivering events, so that only one of the server replicas polling the trigger at the same time wins and
+// delivers them.
+func pollSCMTrigger(wft *v1alpha1.WorkflowTrigger) error {
+	integration := wft.Spec.SCM.Secret
+	in, err := getIntegration(wft.Namespace, integration)
+	if err != nil {
+		return err
+	}
+	provider, err := scm.GetSCMProvider(in.Spec.SCM)
+	if err != nil {
+		return err
+	}
+
+	events, status, err := scm.PollRepo(provider, wft.Spec.SCM.Repo, &wft.Spec.SCM.SCMTriggerPolicy, wft.Status.SCM, time.Now())
+	if err != nil {
+		return err
+	}
+
+	claimed := wft.DeepCopy()
+	claimed.Status.SCM = status
+	if _, err := handler.K8sClient.CycloneV1alpha1().WorkflowTriggers(wft.Namespace).Update(context.TODO(), claimed, metav1.UpdateOptions{}); err != nil {
+		if errors.IsConflict(err) {
+			log.Infof("SCM trigger %s/%s changed since listed, it's polled by others or in the next period", wft.Namespace, wft.Name)
+			return nil
+		}
+		return err
+	}
+
+	last := wft.Status.SCM
+	if last == nil {
+		last = &v1alpha1.SCMTriggerStatus{}
+	}
+	tenant := common.NamespaceTenant(wft.Namespace)
+	var failed []*scm.EventData
+	for _, data := range events {
+		populateChangedFiles(tenant, integration, []v1alpha1.WorkflowTrigger{*wft}, data)
+		result := deliverToTrigger(tenant, *wft, data, "")
+		if result.Error == "" {
+			if result.Fired {
+				log.Infof("SCM trigger %s/%s fired by polled %s event of %s", wft.Namespace, wft.Name, data.Type, data.Ref)
+			}
+			continue
+		}
+		failed = append(failed, data)
+	}
+	if len(failed) == 0 {
+		return nil
+	}
+
+	// Refs and pull requests failed to trigger keep their previous commits to be retried in the next poll.
+	return retry.RetryOnConflict(retry.DefaultRetry, func() error {
+		latest, err := handler.K8sClient.CycloneV1alpha1().WorkflowTriggers(wft.Namespace).Get(context.TODO(), wft.Name, metav1.GetOptions{})
+		if err != nil {
+			return err
+		}
+		if latest.Status.SCM == nil {
+			return nil
+		}
+
+		toUpdate := latest.DeepCopy()
+		for _, data := range failed {
+			switch data.Type {
+			case scm.PullRequestEventType:
+				restoreCommit(toUpdate.Status.SCM.PullRequests, last.PullRequests, strconv.Itoa(data.PullRequestNumber))
+			default:
+				restoreCommit(toUpdate.Status.SCM.Refs, last.Refs, data.Ref)
+			}
+		}
+		_, err = handler.K8sClient.CycloneV1alpha1().WorkflowTriggers(wft.Namespace).Update(context.TODO(), toUpdate, metav1.UpdateOptions{})
+		return err
+	})
+}
+
+// restoreCommit sets commit of the key in current back to the one in last, or removes it if it's not in last.
+func restoreCommit(current, last map[string]string, key string) {
+	if current == nil {
+		return
+	}
+	if sha, ok := last[key]; ok {
+		current[key] = sha
+		return
+	}
+	delete(current, key)
+}
@@ -11,6 +11,7 @@ import (
 	"github.com/caicloud/cyclone/pkg/common"
 	"github.com/caicloud/cyclone/pkg/meta"
 	api "github.com/caicloud/cyclone/pkg/server/apis/v1alpha1"
+	"github.com/caicloud/cyclone/pkg/server/biz/artifact"
 	"github.com/caicloud/cyclone/pkg/server/biz/integration/cluster"
 	"github.com/caicloud/cyclone/pkg/server/biz/pvc"
 	"github.com/caicloud/cyclone/pkg/server/biz/tenant"
@@ -80,6 +81,10 @@ func GetTenant(ctx context.Context, name string) (*api.Tenant, error) {
 
 // UpdateTenant updates information for a specific tenant
 func UpdateTenant(ctx context.Context, name string, newTenant *api.Tenant) (*api.Tenant, error) {
+	if err := artifact.ValidateStorage(newTenant.Spec.ArtifactStorage); err != nil {
+		return nil, cerr.ErrorValidationFailed.Error("artifact storage", err)
+	}
+
 	// Get old tenant
 	t, err := tenant.Get(handler.K8sClient, name)
 	if err != nil {
@@ -159,6 +164,13 @@ func UpdateTenant(ctx context.Context, name string, newTenant *api.Tenant) (*api
 		}
 	}
 
+	// Save secret key of the artifact storage in the secret
+	if secretKey := extractArtifactStorageSecret(name, newTenant.Spec.ArtifactStorage); secretKey != "" {
+		if err := saveArtifactStorageSecret(name, secretKey); err != nil {
+			return nil, err
+		}
+	}
+
 	// Update namespace
 	err = tenant.UpdateNamespace(handler.K8sClient, newTenant)
 	if err != nil {
@@ -280,12 +292,24 @@ func createControlClusterIntegration(tenant string) error {
 }
 
 func createTenant(t *api.Tenant) error {
+	if err := artifact.ValidateStorage(t.Spec.ArtifactStorage); err != nil {
+		return cerr.ErrorValidationFailed.Error("artifact storage", err)
+	}
+
 	// create namespace
+	secretKey := extractArtifactStorageSecret(t.Name, t.Spec.ArtifactStorage)
 	err := tenant.CreateNamespace(handler.K8sClient, t)
 	if err != nil {
 		return err
 	}
 
+	// save secret key of the artifact storage in the secret referred by the tenant
+	if secretKey != "" {
+		if err := saveArtifactStorageSecret(t.Name, secretKey); err != nil {
+			return err
+		}
+	}
+
 	// create cluster integration for control cluster
 	err = createControlClusterIntegration(t.Name)
 	if err != nil {
@@ -0,0 +1,43 @@
+package v1alpha1
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	api "github.com/caicloud/cyclone/pkg/server/apis/v1alpha1"
+	"github.com/caicloud/cyclone/pkg/server/common"
+	"github.com/caicloud/cyclone/pkg/server/handler"
+	"github.com/caicloud/cyclone/pkg/util/k8s/fake"
+)
+
+func TestArtifactStorageSecret(t *testing.T) {
+	namespace := common.TenantNamespace(testTenant)
+	handler.Init(fake.NewSimpleClientset(&corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: namespace}}))
+
+	storage := &api.ArtifactStorage{
+		Type: api.S3ArtifactStorage,
+		S3:   &api.S3Storage{Endpoint: "https://s3.example.com", Bucket: "artifacts", AccessKey: "ak", SecretKey: "sk"},
+	}
+	secretKey := extractArtifactStorageSecret(testTenant, storage)
+	assert.Equal(t, "sk", secretKey)
+	assert.Equal(t, "${secrets.cyclone-devops:cyclone-artifact-storage/data.secretKey}", storage.S3.SecretKey)
+
+	// References are kept as they are.
+	assert.Equal(t, "", extractArtifactStorageSecret(testTenant, storage))
+	assert.Equal(t, "", extractArtifactStorageSecret(testTenant, nil))
+
+	assert.Nil(t, saveArtifactStorageSecret(testTenant, secretKey))
+	resolved, err := resolveSecret(storage.S3.SecretKey)
+	assert.Nil(t, err)
+	assert.Equal(t, "sk", resolved)
+
+	// The secret is updated with a new secret key.
+	assert.Nil(t, saveArtifactStorageSecret(testTenant, "new-sk"))
+	secret, err := handler.K8sClient.CoreV1().Secrets(namespace).Get(context.TODO(), artifactStorageSecret, metav1.GetOptions{})
+	assert.Nil(t, err)
+	assert.Equal(t, "new-sk", string(secret.Data[artifactStorageSecretKey]))
+}
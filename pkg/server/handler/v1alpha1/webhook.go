@@ -1,13 +1,19 @@
 package v1alpha1
 
 import (
+	"bytes"
 	"context"
+	"crypto/sha256"
+	"encoding/hex"
 	"fmt"
+	"io/ioutil"
+	"net/http"
 	"strings"
 	"time"
 
 	"github.com/caicloud/nirvana/log"
 	"github.com/caicloud/nirvana/service"
+	"k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/util/rand"
 
@@ -18,11 +24,15 @@ import (
 	"github.com/caicloud/cyclone/pkg/server/biz/hook"
 	"github.com/caicloud/cyclone/pkg/server/biz/scm"
 	"github.com/caicloud/cyclone/pkg/server/biz/scm/bitbucket"
+	"github.com/caicloud/cyclone/pkg/server/biz/scm/gitea"
 	"github.com/caicloud/cyclone/pkg/server/biz/scm/github"
 	"github.com/caicloud/cyclone/pkg/server/biz/scm/gitlab"
 	"github.com/caicloud/cyclone/pkg/server/biz/scm/svn"
+	"github.com/caicloud/cyclone/pkg/server/biz/webhook"
 	"github.com/caicloud/cyclone/pkg/server/common"
+	"github.com/caicloud/cyclone/pkg/server/config"
 	"github.com/caicloud/cyclone/pkg/server/handler"
+	"github.com/caicloud/cyclone/pkg/server/types"
 	"github.com/caicloud/cyclone/pkg/util"
 	"github.com/caicloud/cyclone/pkg/util/cerr"
 )
@@ -30,67 +40,70 @@ import (
 const (
 	succeededMsg = "Successfully triggered"
 
+	receivedMsg = "Successfully received"
+
 	ignoredMsg = "Is ignored"
 )
 
-type task struct {
-	Tenant    string
-	Trigger   v1alpha1.WorkflowTrigger
-	EventData *scm.EventData
-}
-
-var taskQueue = make(chan task, 10)
-
-func init() {
-	go webhookWorker(taskQueue)
-}
-
-func webhookWorker(tasks <-chan task) {
-	for t := range tasks {
-		err := createWorkflowRun(t.Tenant, t.Trigger, t.EventData)
-		if err != nil {
-			log.Errorf("wft %s create workflow run error:%v", t.Trigger.Name, err)
-		}
-	}
-}
-
 func newWebhookResponse(msg string) api.WebhookResponse {
 	return api.WebhookResponse{
 		Message: msg,
 	}
 }
 
-// HandleWebhook handles webhooks from integrated systems.
+// HandleWebhook handles webhooks from integrated systems. SCM events are recorded as webhook deliveries and handled
+// by delivery workers asynchronously. For generic webhooks (eventType 'Webhook'), 'integration' is name of the
+// WorkflowTrigger to trigger. For image push events (eventType 'Image'), 'integration' is name of the DockerRegistry
+// integration.
 func HandleWebhook(ctx context.Context, tenant, eventType, integration string) (api.WebhookResponse, error) {
-	if eventType != string(v1alpha1.TriggerTypeSCM) {
-		err := fmt.Errorf("eventType %s unsupported, support SCM for now", eventType)
+	switch eventType {
+	case string(v1alpha1.TriggerTypeSCM):
+	case string(v1alpha1.TriggerTypeWebhook):
+		return handleGenericWebhook(ctx, tenant, integration)
+	case string(v1alpha1.TriggerTypeImage):
+		return handleImageWebhook(ctx, tenant, integration)
+	default:
+		err := fmt.Errorf("eventType %s unsupported, support SCM, Webhook and Image for now", eventType)
+		return newWebhookResponse(err.Error()), err
+	}
+
+	return receiveSCMWebhook(tenant, integration, service.HTTPContextFrom(ctx).Request())
+}
+
+// receiveSCMWebhook parses the SCM event from the request and records it as a webhook delivery.
+func receiveSCMWebhook(tenant, integration string, request *http.Request) (api.WebhookResponse, error) {
+	// Payload is read here to be recorded in the delivery, the body is restored for event parsers.
+	payload, err := readWebhookPayload(request.Body)
+	if err != nil {
 		return newWebhookResponse(err.Error()), err
 	}
-	request := service.HTTPContextFrom(ctx).Request()
+	request.Body = ioutil.NopCloser(bytes.NewReader(payload))
 
 	var data *scm.EventData
 
-	if request.Header.Get(github.EventTypeHeader) != "" {
+	// Gitea also sends the GitHub event header, so it's checked first.
+	switch {
+	case request.Header.Get(gitea.EventTypeHeader) != "" || request.Header.Get(gitea.GogsEventTypeHeader) != "":
+		in, err := getIntegration(common.TenantNamespace(tenant), integration)
+		if err != nil {
+			return newWebhookResponse(err.Error()), err
+		}
+		data = gitea.ParseEvent(in.Spec.SCM, request)
+	case request.Header.Get(github.EventTypeHeader) != "":
 		in, err := getIntegration(common.TenantNamespace(tenant), integration)
 		if err != nil {
 			return newWebhookResponse(err.Error()), err
 		}
 		data = github.ParseEvent(in.Spec.SCM, request)
-	}
-
-	if request.Header.Get(gitlab.EventTypeHeader) != "" {
+	case request.Header.Get(gitlab.EventTypeHeader) != "":
 		data = gitlab.ParseEvent(request)
-	}
-
-	if request.Header.Get(bitbucket.EventTypeHeader) != "" {
+	case request.Header.Get(bitbucket.EventTypeHeader) != "":
 		in, err := getIntegration(common.TenantNamespace(tenant), integration)
 		if err != nil {
 			return newWebhookResponse(err.Error()), err
 		}
 		data = bitbucket.ParseEvent(in.Spec.SCM, request)
-	}
-
-	if request.Header.Get(svn.EventTypeHeader) != "" {
+	case request.Header.Get(svn.EventTypeHeader) != "":
 		data = svn.ParseEvent(request)
 	}
 
@@ -100,26 +113,117 @@ func HandleWebhook(ctx context.Context, tenant, eventType, integration string) (
 	// convert the time to UTC timezone
 	data.CreatedAt = data.CreatedAt.UTC()
 
-	wfts, err := hook.ListSCMWfts(tenant, data.Repo, integration)
+	// The event is persisted before responding, so that it won't be lost even if the server restarts.
+	delivery, err := webhook.NewDelivery(integration, data, payload, config.Config.WebhookDelivery.MaxPayloadSize)
 	if err != nil {
 		return newWebhookResponse(err.Error()), err
 	}
+	delivery, err = webhook.CreateDelivery(handler.K8sClient, common.TenantNamespace(tenant), delivery)
+	if err != nil {
+		log.Errorf("Failed to create webhook delivery for repo %s: %v", data.Repo, err)
+		return newWebhookResponse(err.Error()), cerr.ConvertK8sError(err)
+	}
+	enqueueDelivery(delivery)
 
-	triggeredWfts := make([]string, 0)
-	for _, wft := range wfts.Items {
-		log.Infof("Trigger workflow trigger %s", wft.Name)
-		taskQueue <- task{
-			Tenant:    tenant,
-			Trigger:   wft,
-			EventData: data,
+	return newWebhookResponse(fmt.Sprintf("%s: %s", receivedMsg, delivery.Name)), nil
+}
+
+// populateChangedFiles gets changed files of the event from the SCM provider if any of the workflow triggers has
+// path filters for it. Changed files are left unknown on errors, so that the path filters pass.
+func populateChangedFiles(tenant, integration string, wfts []v1alpha1.WorkflowTrigger, data *scm.EventData) {
+	if data.ChangedFiles != nil {
+		return
+	}
+
+	needed := false
+	for _, wft := range wfts {
+		if scm.NeedChangedFiles(&wft.Spec.SCM.SCMTriggerPolicy, data.Type) {
+			needed = true
+			break
+		}
+	}
+	if !needed {
+		return
+	}
+
+	in, err := getIntegration(common.TenantNamespace(tenant), integration)
+	if err != nil {
+		log.Warningf("Failed to get integration %s to get changed files: %v", integration, err)
+		return
+	}
+
+	provider, err := scm.GetSCMProvider(in.Spec.SCM)
+	if err != nil {
+		log.Warningf("Failed to get SCM provider to get changed files: %v", err)
+		return
+	}
+
+	if err := scm.PopulateChangedFiles(provider, data); err != nil {
+		log.Warningf("Failed to get changed files of %s event in repo %s: %v", data.Type, data.Repo, err)
+	}
+}
+
+// DryRunWebhook reports which SCM workflow triggers of the repo would be fired by an event of the ref, without
+// creating any WorkflowRuns. For pull request events, 'ref' is the target branch. If 'eventType' is empty, it's
+// regarded as tag release event for tag refs, and push event for others. 'files' are comma separated changed files
+// to match path filters, and path filters pass if it's empty.
+func DryRunWebhook(ctx context.Context, tenant, repo, ref, eventType, integration, files string) (*types.ListResponse, error) {
+	if repo == "" {
+		return nil, cerr.ErrorURLParamNotFound.Error("repo")
+	}
+	if ref == "" {
+		return nil, cerr.ErrorURLParamNotFound.Error("ref")
+	}
+
+	data := &scm.EventData{
+		Type: scm.EventType(eventType),
+		Repo: repo,
+	}
+	if data.Type == "" {
+		data.Type = scm.PushEventType
+		if strings.HasPrefix(ref, "refs/tags/") {
+			data.Type = scm.TagReleaseEventType
 		}
-		triggeredWfts = append(triggeredWfts, wft.Name)
 	}
-	if len(triggeredWfts) > 0 {
-		return newWebhookResponse(fmt.Sprintf("%s: %s", succeededMsg, triggeredWfts)), nil
+	switch data.Type {
+	case scm.PushEventType:
+		data.Ref = scm.BranchRef(ref)
+		data.Branch = data.Ref
+	case scm.TagReleaseEventType:
+		data.Ref = scm.TagRef(ref)
+	case scm.PullRequestEventType:
+		data.Ref = scm.BranchRef(ref)
+		data.Branch = strings.TrimPrefix(data.Ref, "refs/heads/")
+	default:
+		return nil, cerr.ErrorUnsupported.Error("event type", eventType)
+	}
+	if files != "" {
+		data.ChangedFiles = strings.Split(files, ",")
+	}
+
+	wfts, err := hook.ListSCMWfts(tenant, repo, integration)
+	if err != nil {
+		return nil, cerr.ConvertK8sError(err)
+	}
+
+	results := make([]api.WebhookDryRunResult, 0, len(wfts.Items))
+	for _, wft := range wfts.Items {
+		result := api.WebhookDryRunResult{WorkflowTrigger: wft.Name}
+		if wft.Spec.WorkflowRef != nil {
+			result.Workflow = wft.Spec.WorkflowRef.Name
+		}
+
+		match, err := scm.MatchTrigger(&wft.Spec.SCM.SCMTriggerPolicy, data)
+		if err != nil {
+			result.Reason = err.Error()
+		} else {
+			result.Fired = match.Matched
+			result.Reason = match.Reason
+		}
+		results = append(results, result)
 	}
 
-	return newWebhookResponse(ignoredMsg), nil
+	return types.NewListResponse(len(results), results), nil
 }
 
 func sanitizeRef(ref string) string {
@@ -137,7 +241,22 @@ func sanitizeRef(ref string) string {
 	return string(ret)
 }
 
-func createWorkflowRun(tenant string, wft v1alpha1.WorkflowTrigger, data *scm.EventData) error {
+// eventWorkflowRunName generates name of the WorkflowRun created by the workflow trigger for the SCM event with the
+// ID, so that retries of the same event get the same name. Random name is generated if the event has no ID.
+func eventWorkflowRunName(wfName, wftName, eventID string) string {
+	if eventID == "" {
+		return fmt.Sprintf("%s-%s", wfName, rand.String(5))
+	}
+
+	sum := sha256.Sum256([]byte(eventID + "/" + wftName))
+	return fmt.Sprintf("%s-%s", wfName, hex.EncodeToString(sum[:])[:8])
+}
+
+// createWorkflowRun creates WorkflowRun for the SCM event that matches the workflow trigger, it returns name of the
+// created WorkflowRun, or empty name if there is already a WorkflowRun for a newer update of the pull request.
+// WorkflowRuns of events with ID are created idempotently, the one created by previous attempts is regarded as
+// created.
+func createWorkflowRun(tenant string, wft v1alpha1.WorkflowTrigger, data *scm.EventData, match *scm.TriggerMatch, eventID string) (string, error) {
 	ns := wft.Namespace
 	var err error
 	var project string
@@ -145,93 +264,16 @@ func createWorkflowRun(tenant string, wft v1alpha1.WorkflowTrigger, data *scm.Ev
 		project = wft.Labels[meta.LabelProjectName]
 	}
 	if project == "" {
-		return fmt.Errorf("failed to get project from workflowtrigger labels")
+		return "", fmt.Errorf("failed to get project from workflowtrigger labels")
 	}
 
-	wfName := wft.Spec.WorkflowRef.Name
-	if wfName == "" {
-		return fmt.Errorf("workflow reference of workflowtrigger is empty")
-	}
-
-	triggeredByPR := false
-	trigger := false
-	var tag string
-	st := wft.Spec.SCM
-	switch data.Type {
-	case scm.TagReleaseEventType:
-		if st.TagRelease.Enabled {
-			trigger = true
-			tag = data.Ref
-			// If tag contains "/", trim it.
-			if index := strings.LastIndex(tag, "/"); index >= 0 && len(tag) > index+1 {
-				tag = tag[index+1:]
-			}
-		}
-	case scm.PushEventType:
-		trimmedBranch := data.Branch
-		if index := strings.LastIndex(trimmedBranch, "/"); index >= 0 && len(trimmedBranch) > index+1 {
-			trimmedBranch = trimmedBranch[index+1:]
-		}
-		for _, branch := range st.Push.Branches {
-			if branch == trimmedBranch {
-				trigger = true
-				break
-			}
-		}
-	case scm.PullRequestEventType:
-		log.Infof("target branch name: %s", data.Branch)
-		if !st.PullRequest.Enabled {
-			break
-		}
-
-		// Always trigger if Branches are not specified
-		if len(st.PullRequest.Branches) == 0 {
-			trigger = true
-			triggeredByPR = true
-			break
-		}
-
-		for _, branch := range st.PullRequest.Branches {
-			if branch == data.Branch {
-				trigger = true
-				triggeredByPR = true
-				break
-			}
-		}
-	case scm.PullRequestCommentEventType:
-		for _, comment := range st.PullRequestComment.Comments {
-			if comment == data.Comment {
-				trigger = true
-				triggeredByPR = true
-				break
-			}
-		}
-	case scm.PostCommitEventType:
-		if !st.PostCommit.Enabled {
-			break
-		}
-
-		// For Backward Compatibility, old version workflowTriggers lack of these field
-		// and can normally trigger workflow
-		if st.PostCommit.RootURL == "" || st.PostCommit.WorkflowURL == "" ||
-			data.ChangedFiles == nil || len(data.ChangedFiles) == 0 {
-			trigger = true
-			break
-		}
-
-		for _, file := range data.ChangedFiles {
-			fullPath := st.PostCommit.RootURL + "/" + file
-			if strings.Contains(fullPath, st.PostCommit.WorkflowURL) {
-				trigger = true
-				break
-			}
-		}
-
+	if wft.Spec.WorkflowRef == nil || wft.Spec.WorkflowRef.Name == "" {
+		return "", fmt.Errorf("workflow reference of workflowtrigger is empty")
 	}
+	wfName := wft.Spec.WorkflowRef.Name
+	name := eventWorkflowRunName(wfName, wft.Name, eventID)
 
-	if !trigger {
-		return nil
-	}
+	triggeredByPR, tag := match.ByPR, match.Tag
 
 	cycloneClient := handler.K8sClient.CycloneV1alpha1()
 	ctx := context.TODO()
@@ -252,7 +294,7 @@ func createWorkflowRun(tenant string, wft v1alpha1.WorkflowTrigger, data *scm.Ev
 				project, wfName, data.Type)
 		} else if !data.CreatedAt.IsZero() {
 			for _, item := range wfrs.Items {
-				if len(item.Annotations) == 0 {
+				if len(item.Annotations) == 0 || item.Name == name {
 					continue
 				}
 				evtType := scm.EventType(item.Annotations[meta.AnnotationWorkflowRunTrigger])
@@ -286,13 +328,11 @@ func createWorkflowRun(tenant string, wft v1alpha1.WorkflowTrigger, data *scm.Ev
 	}
 
 	if skipCurrent {
-		return nil
+		return "", nil
 	}
 
 	log.Infof("Trigger wft %s with event data: %v", wft.Name, data)
 
-	name := fmt.Sprintf("%s-%s", wfName, rand.String(5))
-
 	// Create workflowrun.
 	wfr := &v1alpha1.WorkflowRun{
 		ObjectMeta: metav1.ObjectMeta{
@@ -301,6 +341,7 @@ func createWorkflowRun(tenant string, wft v1alpha1.WorkflowTrigger, data *scm.Ev
 				meta.AnnotationWorkflowRunPRUpdatedAt: data.CreatedAt.Format(time.RFC3339),
 				meta.AnnotationWorkflowRunTrigger:     string(data.Type),
 				meta.AnnotationAlias:                  name,
+				meta.AnnotationWorkflowRunSCMEventID:  eventID,
 			},
 			Labels: map[string]string{
 				meta.LabelProjectName:             project,
@@ -316,7 +357,14 @@ func createWorkflowRun(tenant string, wft v1alpha1.WorkflowTrigger, data *scm.Ev
 
 	wfr.Annotations, err = setSCMEventData(wfr.Annotations, data)
 	if err != nil {
-		return err
+		return "", err
+	}
+
+	if wft.Spec.SCM.Feedback != nil {
+		wfr.Annotations, err = setSCMFeedback(wfr.Annotations, wft.Spec.SCM.Feedback)
+		if err != nil {
+			return "", err
+		}
 	}
 
 	// Set "Tag" and "SCM_REVISION" for all resource configs.
@@ -344,7 +392,14 @@ func createWorkflowRun(tenant string, wft v1alpha1.WorkflowTrigger, data *scm.Ev
 	accelerator.NewAccelerator(tenant, project, wfr).Accelerate()
 	_, err = cycloneClient.WorkflowRuns(ns).Create(ctx, wfr, metav1.CreateOptions{})
 	if err != nil {
-		return cerr.ConvertK8sError(err)
+		if eventID != "" && errors.IsAlreadyExists(err) {
+			existing, getErr := cycloneClient.WorkflowRuns(ns).Get(ctx, name, metav1.GetOptions{})
+			if getErr == nil && existing.Annotations[meta.AnnotationWorkflowRunSCMEventID] == eventID {
+				log.Infof("WorkflowRun %s/%s for SCM event %s already created", ns, name, eventID)
+				return name, nil
+			}
+		}
+		return "", cerr.ConvertK8sError(err)
 	}
 
 	go func(wfrCopy *v1alpha1.WorkflowRun) {
@@ -357,7 +412,7 @@ func createWorkflowRun(tenant string, wft v1alpha1.WorkflowTrigger, data *scm.Ev
 	}(wfr.DeepCopy())
 
 	if !triggeredByPR {
-		return nil
+		return name, nil
 	}
 
 	log.Infof("Trying to cancel %d previous builds for PR %s. repo=%s", len(currentWfrs), data.Ref, data.Repo)
@@ -369,5 +424,5 @@ func createWorkflowRun(tenant string, wft v1alpha1.WorkflowTrigger, data *scm.Ev
 		}
 	}
 
-	return nil
+	return name, nil
 }
@@ -0,0 +1,266 @@
+package v1alpha1
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/caicloud/nirvana/log"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/client-go/tools/cache"
+	"k8s.io/client-go/util/workqueue"
+
+	"github.com/caicloud/cyclone/pkg/apis/cyclone/v1alpha1"
+	api "github.com/caicloud/cyclone/pkg/server/apis/v1alpha1"
+	"github.com/caicloud/cyclone/pkg/server/biz/hook"
+	"github.com/caicloud/cyclone/pkg/server/biz/scm"
+	"github.com/caicloud/cyclone/pkg/server/biz/webhook"
+	"github.com/caicloud/cyclone/pkg/server/common"
+	"github.com/caicloud/cyclone/pkg/server/config"
+	"github.com/caicloud/cyclone/pkg/server/handler"
+	"github.com/caicloud/cyclone/pkg/server/types"
+	"github.com/caicloud/cyclone/pkg/util/cerr"
+)
+
+const (
+	// deliveryRetryBaseDelay is the delay before the first retry of a failed delivery, it doubles on each failure.
+	deliveryRetryBaseDelay = 5 * time.Second
+	// deliveryRetryMaxDelay is the max delay between retries of a failed delivery.
+	deliveryRetryMaxDelay = 5 * time.Minute
+)
+
+// deliveryQueue is the queue of webhook deliveries to handle, keys are '<namespace>/<name>' of deliveries. Failed
+// deliveries are retried with exponential backoff, so that SCM providers and the API server are not hammered.
+var deliveryQueue = workqueue.NewNamedRateLimitingQueue(
+	workqueue.NewItemExponentialFailureRateLimiter(deliveryRetryBaseDelay, deliveryRetryMaxDelay), "webhook-delivery")
+
+// StartWebhookDeliveryWorkers starts workers to handle webhook deliveries. Pending deliveries, for example, those
+// received before the server restarted, are enqueued again.
+func StartWebhookDeliveryWorkers() {
+	for i := 0; i < config.Config.WebhookDelivery.Workers; i++ {
+		go wait.Until(runDeliveryWorker, time.Second, wait.NeverStop)
+	}
+
+	deliveries, err := webhook.ListDeliveries(handler.K8sClient, metav1.NamespaceAll, "", api.WebhookDeliveryPending)
+	if err != nil {
+		log.Errorf("Failed to list pending webhook deliveries: %v", err)
+		return
+	}
+	for i := range deliveries {
+		enqueueDelivery(&deliveries[i])
+	}
+	log.Infof("%d pending webhook deliveries enqueued", len(deliveries))
+}
+
+func enqueueDelivery(delivery *api.WebhookDelivery) {
+	deliveryQueue.Add(delivery.Namespace + "/" + delivery.Name)
+}
+
+func runDeliveryWorker() {
+	for processNextDelivery() {
+	}
+}
+
+func processNextDelivery() bool {
+	key, quit := deliveryQueue.Get()
+	if quit {
+		return false
+	}
+	defer deliveryQueue.Done(key)
+
+	namespace, name, err := cache.SplitMetaNamespaceKey(key.(string))
+	if err != nil {
+		deliveryQueue.Forget(key)
+		return true
+	}
+
+	if err := handleDelivery(namespace, name); err != nil {
+		log.Warningf("Handle webhook delivery %s error, will retry: %v", key, err)
+		deliveryQueue.AddRateLimited(key)
+		return true
+	}
+
+	deliveryQueue.Forget(key)
+	return true
+}
+
+// handleDelivery handles a pending webhook delivery. Workflow triggers that have been handled in previous attempts
+// are skipped, so that WorkflowRuns are not created twice. Error is returned if the delivery should be retried.
+func handleDelivery(namespace, name string) error {
+	delivery, err := webhook.GetDelivery(handler.K8sClient, namespace, name)
+	if err != nil {
+		if errors.IsNotFound(err) {
+			return nil
+		}
+		return err
+	}
+	if delivery.Status.State != api.WebhookDeliveryPending {
+		return nil
+	}
+
+	now := metav1.Now()
+	delivery.Status.Attempts++
+	delivery.Status.LastAttemptTime = &now
+	delivery.Status.Error = ""
+
+	data := &scm.EventData{}
+	deliverErr := json.Unmarshal(delivery.Spec.Event, data)
+	if deliverErr != nil {
+		// Malformed events won't be fixed by retries.
+		delivery.Status.Attempts = config.Config.WebhookDelivery.MaxAttempts
+	} else {
+		deliverErr = deliver(common.NamespaceTenant(namespace), delivery, data)
+	}
+
+	switch {
+	case deliverErr == nil:
+		delivery.Status.State = api.WebhookDeliverySucceeded
+	case delivery.Status.Attempts >= config.Config.WebhookDelivery.MaxAttempts:
+		delivery.Status.State = api.WebhookDeliveryFailed
+		delivery.Status.Error = deliverErr.Error()
+	default:
+		delivery.Status.Error = deliverErr.Error()
+	}
+
+	if _, err := webhook.UpdateDelivery(handler.K8sClient, delivery); err != nil {
+		return err
+	}
+
+	if delivery.Status.State == api.WebhookDeliveryPending {
+		return deliverErr
+	}
+
+	if err := webhook.PruneDeliveries(handler.K8sClient, namespace, config.Config.WebhookDelivery.Retention); err != nil {
+		log.Warningf("Failed to prune webhook deliveries in %s: %v", namespace, err)
+	}
+	return nil
+}
+
+// deliver delivers the SCM event to workflow triggers of the repo, and records results in status of the delivery.
+func deliver(tenant string, delivery *api.WebhookDelivery, data *scm.EventData) error {
+	wfts, err := hook.ListSCMWfts(tenant, data.Repo, delivery.Spec.Integration)
+	if err != nil {
+		return err
+	}
+
+	if data.ChangedFiles == nil {
+		populateChangedFiles(tenant, delivery.Spec.Integration, wfts.Items, data)
+		if data.ChangedFiles != nil {
+			if event, err := json.Marshal(data); err == nil {
+				delivery.Spec.Event = event
+			}
+		}
+	}
+
+	handled := make(map[string]api.WebhookDeliveryTrigger)
+	for _, t := range delivery.Status.Triggers {
+		if t.Error == "" {
+			handled[t.WorkflowTrigger] = t
+		}
+	}
+
+	var failed []string
+	triggers := make([]api.WebhookDeliveryTrigger, 0, len(wfts.Items))
+	for _, wft := range wfts.Items {
+		result, ok := handled[wft.Name]
+		if !ok {
+			result = deliverToTrigger(tenant, wft, data, delivery.Name)
+		}
+		if result.Error != "" {
+			failed = append(failed, wft.Name)
+		}
+		triggers = append(triggers, result)
+	}
+	delivery.Status.Triggers = triggers
+
+	if len(failed) > 0 {
+		return fmt.Errorf("failed to create WorkflowRuns for workflow triggers %v", failed)
+	}
+	return nil
+}
+
+// deliverToTrigger creates WorkflowRun if the SCM event matches the workflow trigger. Events with the same ID are
+// delivered at most once to the trigger, the ID can be empty if the event is not retried.
+func deliverToTrigger(tenant string, wft v1alpha1.WorkflowTrigger, data *scm.EventData, eventID string) api.WebhookDeliveryTrigger {
+	result := api.WebhookDeliveryTrigger{WorkflowTrigger: wft.Name}
+	if wft.Spec.WorkflowRef != nil {
+		result.Workflow = wft.Spec.WorkflowRef.Name
+	}
+
+	match, err := scm.MatchTrigger(&wft.Spec.SCM.SCMTriggerPolicy, data)
+	if err != nil {
+		// Invalid patterns won't be fixed by retries, so it's not regarded as an error.
+		result.Reason = err.Error()
+		return result
+	}
+	result.Fired, result.Reason = match.Matched, match.Reason
+	if !match.Matched {
+		log.Infof("Skip wft %s: %s", wft.Name, match.Reason)
+		return result
+	}
+
+	wfr, err := createWorkflowRun(tenant, wft, data, match, eventID)
+	if err != nil {
+		log.Errorf("wft %s create workflow run error: %v", wft.Name, err)
+		result.Error = err.Error()
+		return result
+	}
+	if wfr == "" {
+		result.Reason = "a WorkflowRun for a newer update of the pull request exists"
+	}
+	result.WorkflowRun = wfr
+
+	return result
+}
+
+// ListWebhookDeliveries lists webhook deliveries of the tenant from the newest to the oldest, they can be filtered
+// by the SCM integration and the state.
+func ListWebhookDeliveries(ctx context.Context, tenant, integration, state string, query *types.QueryParams) (*types.ListResponse, error) {
+	deliveries, err := webhook.ListDeliveries(handler.K8sClient, common.TenantNamespace(tenant), integration, api.WebhookDeliveryState(state))
+	if err != nil {
+		log.Errorf("List webhook deliveries of tenant %s error: %v", tenant, err)
+		return nil, cerr.ConvertK8sError(err)
+	}
+
+	size := uint64(len(deliveries))
+	if query.Start >= size {
+		return types.NewListResponse(int(size), []api.WebhookDelivery{}), nil
+	}
+
+	end := query.Start + query.Limit
+	if end > size {
+		end = size
+	}
+
+	return types.NewListResponse(int(size), deliveries[query.Start:end]), nil
+}
+
+// GetWebhookDelivery gets a webhook delivery of the tenant.
+func GetWebhookDelivery(ctx context.Context, tenant, name string) (*api.WebhookDelivery, error) {
+	delivery, err := webhook.GetDelivery(handler.K8sClient, common.TenantNamespace(tenant), name)
+	if err != nil {
+		return nil, cerr.ConvertK8sError(err)
+	}
+
+	return delivery, nil
+}
+
+// RedeliverWebhookDelivery re-delivers the SCM event of a webhook delivery as a new delivery.
+func RedeliverWebhookDelivery(ctx context.Context, tenant, name string) (*api.WebhookDelivery, error) {
+	namespace := common.TenantNamespace(tenant)
+	delivery, err := webhook.GetDelivery(handler.K8sClient, namespace, name)
+	if err != nil {
+		return nil, cerr.ConvertK8sError(err)
+	}
+
+	redelivery, err := webhook.CreateDelivery(handler.K8sClient, namespace, webhook.Redelivery(delivery))
+	if err != nil {
+		return nil, cerr.ConvertK8sError(err)
+	}
+	enqueueDelivery(redelivery)
+
+	return redelivery, nil
+}
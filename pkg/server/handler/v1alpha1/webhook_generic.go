@@ -0,0 +1,153 @@
+package v1alpha1
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"strings"
+
+	"github.com/caicloud/nirvana/log"
+	"github.com/caicloud/nirvana/service"
+	core_v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/rand"
+
+	"github.com/caicloud/cyclone/pkg/apis/cyclone/v1alpha1"
+	"github.com/caicloud/cyclone/pkg/meta"
+	api "github.com/caicloud/cyclone/pkg/server/apis/v1alpha1"
+	"github.com/caicloud/cyclone/pkg/server/biz/accelerator"
+	"github.com/caicloud/cyclone/pkg/server/biz/webhook"
+	"github.com/caicloud/cyclone/pkg/server/common"
+	"github.com/caicloud/cyclone/pkg/server/handler"
+	"github.com/caicloud/cyclone/pkg/util/cerr"
+	"github.com/caicloud/cyclone/pkg/workflow/values/ref"
+)
+
+// maxWebhookPayloadSize is the max size of payloads accepted by webhooks.
+const maxWebhookPayloadSize = 5 << 20
+
+// readWebhookPayload reads payload of a webhook request, payloads larger than maxWebhookPayloadSize are rejected
+// instead of being truncated.
+func readWebhookPayload(body io.Reader) ([]byte, error) {
+	payload, err := ioutil.ReadAll(io.LimitReader(body, maxWebhookPayloadSize+1))
+	if err != nil {
+		return nil, cerr.ErrorUnknownInternal.Error(err)
+	}
+	if len(payload) > maxWebhookPayloadSize {
+		return nil, cerr.ErrorPayloadTooLarge.Error(maxWebhookPayloadSize)
+	}
+	return payload, nil
+}
+
+// handleGenericWebhook handles payloads sent to a Webhook type WorkflowTrigger. Signature of the payload is verified
+// with secret of the trigger, and values extracted from the payload are used to create the WorkflowRun.
+func handleGenericWebhook(ctx context.Context, tenant, trigger string) (api.WebhookResponse, error) {
+	if trigger == "" {
+		err := cerr.ErrorURLParamNotFound.Error("integration")
+		return newWebhookResponse(err.Error()), err
+	}
+
+	wft, err := handler.K8sClient.CycloneV1alpha1().WorkflowTriggers(common.TenantNamespace(tenant)).Get(context.TODO(), trigger, metav1.GetOptions{})
+	if err != nil {
+		err = cerr.ConvertK8sError(err)
+		return newWebhookResponse(err.Error()), err
+	}
+	if wft.Spec.Type != v1alpha1.TriggerTypeWebhook {
+		err := cerr.ErrorUnsupported.Error("trigger type", wft.Spec.Type)
+		return newWebhookResponse(err.Error()), err
+	}
+
+	request := service.HTTPContextFrom(ctx).Request()
+	payload, err := readWebhookPayload(request.Body)
+	if err != nil {
+		return newWebhookResponse(err.Error()), err
+	}
+
+	secret, err := resolveSecret(wft.Spec.Webhook.Secret)
+	if err != nil {
+		log.Errorf("Resolve secret of workflowtrigger %s error: %v", wft.Name, err)
+		err = cerr.ErrorAuthenticationFailed.Error()
+		return newWebhookResponse(err.Error()), err
+	}
+	header := wft.Spec.Webhook.SignatureHeader
+	if header == "" {
+		header = webhook.DefaultSignatureHeader
+	}
+	if !webhook.Verify(secret, payload, request.Header.Get(header)) {
+		err := cerr.ErrorAuthenticationFailed.Error()
+		return newWebhookResponse(err.Error()), err
+	}
+
+	if wft.Spec.Disabled {
+		return newWebhookResponse(ignoredMsg), nil
+	}
+
+	values, err := webhook.Extract(wft.Spec.Webhook.Rules, payload)
+	if err != nil {
+		err = cerr.ErrorValidationFailed.Error("payload", err)
+		return newWebhookResponse(err.Error()), err
+	}
+	if len(values.Missing) > 0 {
+		return newWebhookResponse(fmt.Sprintf("%s: %s not found", ignoredMsg, strings.Join(values.Missing, ", "))), nil
+	}
+
+	wfr, err := createGenericWebhookWorkflowRun(tenant, wft, values)
+	if err != nil {
+		log.Errorf("wft %s create workflow run error: %v", wft.Name, err)
+		return newWebhookResponse(err.Error()), err
+	}
+
+	return newWebhookResponse(fmt.Sprintf("%s: %s", succeededMsg, wfr.Name)), nil
+}
+
+// resolveSecret resolves secret values such as secrets of webhook triggers, it can be a secret reference.
+func resolveSecret(secret string) (string, error) {
+	if secret == "" {
+		return "", fmt.Errorf("secret not set")
+	}
+
+	secretGetter := func(ns, name string) (*core_v1.Secret, error) {
+		return handler.K8sClient.CoreV1().Secrets(ns).Get(context.TODO(), name, metav1.GetOptions{})
+	}
+	return ref.NewProcessor(&v1alpha1.WorkflowRun{}, "", secretGetter).ResolveRefStringValue(secret)
+}
+
+// createGenericWebhookWorkflowRun creates WorkflowRun for the generic webhook trigger with values extracted from payload.
+func createGenericWebhookWorkflowRun(tenant string, wft *v1alpha1.WorkflowTrigger, values *webhook.Values) (*v1alpha1.WorkflowRun, error) {
+	project := wft.Labels[meta.LabelProjectName]
+	if project == "" {
+		return nil, fmt.Errorf("failed to get project from workflowtrigger labels")
+	}
+	if wft.Spec.WorkflowRef == nil || wft.Spec.WorkflowRef.Name == "" {
+		return nil, fmt.Errorf("workflow reference of workflowtrigger is empty")
+	}
+	wfName := wft.Spec.WorkflowRef.Name
+
+	name := fmt.Sprintf("%s-%s", wfName, rand.String(5))
+	wfr := &v1alpha1.WorkflowRun{
+		ObjectMeta: metav1.ObjectMeta{
+			Name: name,
+			Annotations: map[string]string{
+				meta.AnnotationWorkflowRunTrigger: common.GenericWebhookTrigger,
+				meta.AnnotationAlias:              name,
+			},
+			Labels: map[string]string{
+				meta.LabelProjectName:             project,
+				meta.LabelWorkflowName:            wfName,
+				meta.LabelWorkflowRunAcceleration: wft.Labels[meta.LabelWorkflowRunAcceleration],
+			},
+		},
+		Spec: *wft.Spec.WorkflowRunSpec.DeepCopy(),
+	}
+	values.Apply(&wfr.Spec)
+
+	log.Infof("Trigger wft %s by generic webhook", wft.Name)
+	accelerator.NewAccelerator(tenant, project, wfr).Accelerate()
+	created, err := handler.K8sClient.CycloneV1alpha1().WorkflowRuns(wft.Namespace).Create(context.TODO(), wfr, metav1.CreateOptions{})
+	if err != nil {
+		return nil, cerr.ConvertK8sError(err)
+	}
+
+	return created, nil
+}
@@ -0,0 +1,20 @@
+package v1alpha1
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/caicloud/cyclone/pkg/util/cerr"
+)
+
+func TestReadWebhookPayload(t *testing.T) {
+	payload, err := readWebhookPayload(bytes.NewReader(make([]byte, maxWebhookPayloadSize)))
+	assert.Nil(t, err)
+	assert.Equal(t, maxWebhookPayloadSize, len(payload))
+
+	_, err = readWebhookPayload(bytes.NewReader(make([]byte, maxWebhookPayloadSize+1)))
+	assert.NotNil(t, err)
+	assert.True(t, cerr.ErrorPayloadTooLarge.Derived(err))
+}
@@ -0,0 +1,475 @@
+package v1alpha1
+
+import (
+	"context"
+	"crypto/subtle"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/caicloud/nirvana/log"
+	"github.com/caicloud/nirvana/service"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/rand"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/client-go/util/retry"
+
+	"github.com/caicloud/cyclone/pkg/apis/cyclone/v1alpha1"
+	"github.com/caicloud/cyclone/pkg/meta"
+	api "github.com/caicloud/cyclone/pkg/server/apis/v1alpha1"
+	"github.com/caicloud/cyclone/pkg/server/biz/accelerator"
+	"github.com/caicloud/cyclone/pkg/server/biz/registry"
+	"github.com/caicloud/cyclone/pkg/server/biz/webhook"
+	"github.com/caicloud/cyclone/pkg/server/common"
+	"github.com/caicloud/cyclone/pkg/server/handler"
+	"github.com/caicloud/cyclone/pkg/util/cerr"
+)
+
+const (
+	// pollPeriod is the period to check whether image and SCM triggers need to poll.
+	pollPeriod = time.Minute
+
+	// minPollInterval is the min poll interval of image and SCM triggers.
+	minPollInterval = time.Minute
+
+	// maxPolledTags is the max number of tags whose digests are got in one poll, it also limits the number of seen
+	// tags recorded in status of image triggers.
+	maxPolledTags = 100
+
+	// registryRequestTimeout is the timeout of requests to registries.
+	registryRequestTimeout = 30 * time.Second
+
+	// imageVariablePrefix is prefix of global variables passed from image push events.
+	imageVariablePrefix = "image."
+)
+
+// handleImageWebhook handles image push events sent by registries of the DockerRegistry integration. Image triggers
+// of the integration whose secret matches the Authorization header are fired by events that match them.
+func handleImageWebhook(ctx context.Context, tenant, integration string) (api.WebhookResponse, error) {
+	if integration == "" {
+		err := cerr.ErrorURLParamNotFound.Error("integration")
+		return newWebhookResponse(err.Error()), err
+	}
+
+	request := service.HTTPContextFrom(ctx).Request()
+	return deliverImageWebhook(tenant, integration, request.Header.Get("Authorization"), request.Body)
+}
+
+// deliverImageWebhook delivers image push events in the payload to image triggers of the DockerRegistry integration
+// that the Authorization header is authenticated by.
+func deliverImageWebhook(tenant, integration, authorization string, body io.Reader) (api.WebhookResponse, error) {
+	in, err := getIntegration(common.TenantNamespace(tenant), integration)
+	if err != nil {
+		return newWebhookResponse(err.Error()), err
+	}
+	if in.Spec.Type != api.DockerRegistry || in.Spec.DockerRegistry == nil {
+		err := cerr.ErrorUnsupported.Error("integration type", in.Spec.Type)
+		return newWebhookResponse(err.Error()), err
+	}
+
+	payload, err := readWebhookPayload(body)
+	if err != nil {
+		return newWebhookResponse(err.Error()), err
+	}
+	events, err := registry.ParseEvents(payload)
+	if err != nil {
+		err = cerr.ErrorValidationFailed.Error("payload", err)
+		return newWebhookResponse(err.Error()), err
+	}
+
+	wfts, err := listImageTriggers(common.TenantNamespace(tenant), integration)
+	if err != nil {
+		return newWebhookResponse(err.Error()), err
+	}
+	var authenticated []v1alpha1.WorkflowTrigger
+	for _, wft := range wfts {
+		if verifyImageWebhook(&wft, authorization) {
+			authenticated = append(authenticated, wft)
+		}
+	}
+	if len(wfts) > 0 && len(authenticated) == 0 {
+		err := cerr.ErrorAuthenticationFailed.Error()
+		return newWebhookResponse(err.Error()), err
+	}
+
+	var triggered []string
+	for _, event := range events {
+		for i := range authenticated {
+			wft := &authenticated[i]
+			if wft.Spec.Disabled {
+				continue
+			}
+			matched, err := registry.MatchTrigger(&wft.Spec.Image, in.Spec.DockerRegistry.Server, event.Repository, event.Tag)
+			if err != nil {
+				log.Warningf("Match image trigger %s error: %v", wft.Name, err)
+				continue
+			}
+			if !matched {
+				continue
+			}
+
+			wfr, err := fireImageTrigger(tenant, wft, in.Spec.DockerRegistry.Server, event)
+			if err != nil {
+				log.Errorf("wft %s create workflow run error: %v", wft.Name, err)
+				continue
+			}
+			if wfr != nil {
+				triggered = append(triggered, wfr.Name)
+			}
+		}
+	}
+
+	if len(triggered) == 0 {
+		return newWebhookResponse(ignoredMsg), nil
+	}
+	return newWebhookResponse(fmt.Sprintf("%s: %s", succeededMsg, strings.Join(triggered, ", "))), nil
+}
+
+// listImageTriggers lists Image type WorkflowTriggers of the DockerRegistry integration in the namespace, triggers
+// of all integrations are listed if integration is empty.
+func listImageTriggers(namespace, integration string) ([]v1alpha1.WorkflowTrigger, error) {
+	wfts, err := handler.K8sClient.CycloneV1alpha1().WorkflowTriggers(namespace).List(context.TODO(), metav1.ListOptions{})
+	if err != nil {
+		return nil, cerr.ConvertK8sError(err)
+	}
+
+	var items []v1alpha1.WorkflowTrigger
+	for _, wft := range wfts.Items {
+		if wft.Spec.Type != v1alpha1.TriggerTypeImage {
+			continue
+		}
+		if integration != "" && wft.Spec.Image.Integration != integration {
+			continue
+		}
+		items = append(items, wft)
+	}
+	return items, nil
+}
+
+// verifyImageWebhook checks whether the Authorization header matches secret of the image trigger, triggers without
+// secret don't accept webhooks.
+func verifyImageWebhook(wft *v1alpha1.WorkflowTrigger, authorization string) bool {
+	if wft.Spec.Image.Secret == "" {
+		return false
+	}
+	secret, err := resolveSecret(wft.Spec.Image.Secret)
+	if err != nil {
+		log.Errorf("Resolve secret of workflowtrigger %s error: %v", wft.Name, err)
+		return false
+	}
+
+	authorization = strings.TrimPrefix(authorization, "Bearer ")
+	return subtle.ConstantTimeCompare([]byte(secret), []byte(authorization)) == 1
+}
+
+// fireImageTrigger creates a WorkflowRun for the image push event, it returns nil if the digest of the tag has
+// been seen already. The digest is recorded in status of the trigger before the WorkflowRun is created, so that
+// the same push notified by both webhooks and polling only triggers once.
+func fireImageTrigger(tenant string, wft *v1alpha1.WorkflowTrigger, server string, event registry.Event) (*v1alpha1.WorkflowRun, error) {
+	var previous string
+	seen := false
+	err := retry.RetryOnConflict(retry.DefaultRetry, func() error {
+		latest, err := handler.K8sClient.CycloneV1alpha1().WorkflowTriggers(wft.Namespace).Get(context.TODO(), wft.Name, metav1.GetOptions{})
+		if err != nil {
+			return err
+		}
+		if latest.Status.Image != nil && event.Digest != "" && latest.Status.Image.Tags[event.Tag] == event.Digest {
+			seen = true
+			return nil
+		}
+
+		toUpdate := latest.DeepCopy()
+		if toUpdate.Status.Image == nil {
+			toUpdate.Status.Image = &v1alpha1.ImageTriggerStatus{}
+		}
+		if toUpdate.Status.Image.Tags == nil {
+			toUpdate.Status.Image.Tags = make(map[string]string)
+		}
+		previous = toUpdate.Status.Image.Tags[event.Tag]
+		toUpdate.Status.Image.Tags[event.Tag] = event.Digest
+		pruneImageTags(&toUpdate.Spec.Image, server, toUpdate.Status.Image.Tags, event.Tag)
+		toUpdate.Status.Count++
+		_, err = handler.K8sClient.CycloneV1alpha1().WorkflowTriggers(wft.Namespace).Update(context.TODO(), toUpdate, metav1.UpdateOptions{})
+		return err
+	})
+	if err != nil {
+		return nil, cerr.ConvertK8sError(err)
+	}
+	if seen {
+		return nil, nil
+	}
+
+	wfr, err := createImageWorkflowRun(tenant, wft, server, event)
+	if err != nil {
+		// Restore the digest so that the push can be triggered again.
+		restoreErr := retry.RetryOnConflict(retry.DefaultRetry, func() error {
+			latest, err := handler.K8sClient.CycloneV1alpha1().WorkflowTriggers(wft.Namespace).Get(context.TODO(), wft.Name, metav1.GetOptions{})
+			if err != nil {
+				return err
+			}
+			if latest.Status.Image == nil || latest.Status.Image.Tags[event.Tag] != event.Digest {
+				return nil
+			}
+
+			toUpdate := latest.DeepCopy()
+			if previous == "" {
+				delete(toUpdate.Status.Image.Tags, event.Tag)
+			} else {
+				toUpdate.Status.Image.Tags[event.Tag] = previous
+			}
+			toUpdate.Status.Count--
+			_, err = handler.K8sClient.CycloneV1alpha1().WorkflowTriggers(wft.Namespace).Update(context.TODO(), toUpdate, metav1.UpdateOptions{})
+			return err
+		})
+		if restoreErr != nil {
+			log.Warningf("Restore digest of %s:%s in workflowtrigger %s error: %v", event.Repository, event.Tag, wft.Name, restoreErr)
+		}
+		return nil, err
+	}
+
+	return wfr, nil
+}
+
+// pruneImageTags drops seen tags that no longer match the image trigger, and drops more tags in name order if there
+// are still more than maxPolledTags, except the tag to keep. Seen tags of triggers only receiving webhooks are not
+// replaced by polls, they would grow endlessly otherwise. Dropped tags fire the trigger again when pushed.
+func pruneImageTags(trigger *v1alpha1.ImageTrigger, server string, tags map[string]string, keep string) {
+	var names []string
+	for tag := range tags {
+		if tag == keep {
+			continue
+		}
+		if matched, err := registry.MatchTrigger(trigger, server, trigger.Repository, tag); err != nil || !matched {
+			delete(tags, tag)
+			continue
+		}
+		names = append(names, tag)
+	}
+
+	sort.Strings(names)
+	for _, tag := range names {
+		if len(tags) <= maxPolledTags {
+			break
+		}
+		delete(tags, tag)
+	}
+}
+
+// createImageWorkflowRun creates WorkflowRun for the image trigger with the pushed image bound to the image resource
+// and passed as global variables.
+func createImageWorkflowRun(tenant string, wft *v1alpha1.WorkflowTrigger, server string, event registry.Event) (*v1alpha1.WorkflowRun, error) {
+	project := wft.Labels[meta.LabelProjectName]
+	if project == "" {
+		return nil, fmt.Errorf("failed to get project from workflowtrigger labels")
+	}
+	if wft.Spec.WorkflowRef == nil || wft.Spec.WorkflowRef.Name == "" {
+		return nil, fmt.Errorf("workflow reference of workflowtrigger is empty")
+	}
+	wfName := wft.Spec.WorkflowRef.Name
+
+	name := fmt.Sprintf("%s-%s", wfName, rand.String(5))
+	wfr := &v1alpha1.WorkflowRun{
+		ObjectMeta: metav1.ObjectMeta{
+			Name: name,
+			Annotations: map[string]string{
+				meta.AnnotationWorkflowRunTrigger: common.ImagePushTrigger,
+				meta.AnnotationAlias:              name,
+			},
+			Labels: map[string]string{
+				meta.LabelProjectName:             project,
+				meta.LabelWorkflowName:            wfName,
+				meta.LabelWorkflowRunAcceleration: wft.Labels[meta.LabelWorkflowRunAcceleration],
+			},
+		},
+		Spec: *wft.Spec.WorkflowRunSpec.DeepCopy(),
+	}
+	applyImageEvent(&wfr.Spec, wft.Spec.Image.Resource, server, event)
+
+	log.Infof("Trigger wft %s by image %s:%s", wft.Name, event.Repository, event.Tag)
+	accelerator.NewAccelerator(tenant, project, wfr).Accelerate()
+	created, err := handler.K8sClient.CycloneV1alpha1().WorkflowRuns(wft.Namespace).Create(context.TODO(), wfr, metav1.CreateOptions{})
+	if err != nil {
+		return nil, cerr.ConvertK8sError(err)
+	}
+
+	return created, nil
+}
+
+// applyImageEvent binds repository, tag and digest of the pushed image to parameters of the image resource, and sets
+// them as global variables.
+func applyImageEvent(spec *v1alpha1.WorkflowRunSpec, resource, server string, event registry.Event) {
+	values := &webhook.Values{
+		Variables: map[string]string{
+			imageVariablePrefix + "registry":   registry.Host(server),
+			imageVariablePrefix + "repository": event.Repository,
+			imageVariablePrefix + "tag":        event.Tag,
+			imageVariablePrefix + "digest":     event.Digest,
+		},
+	}
+	values.Apply(spec)
+
+	if resource == "" {
+		return
+	}
+	index := -1
+	for i := range spec.ResourceParams {
+		if spec.ResourceParams[i].Name == resource {
+			index = i
+			break
+		}
+	}
+	if index < 0 {
+		spec.ResourceParams = append(spec.ResourceParams, v1alpha1.ParameterConfig{Name: resource})
+		index = len(spec.ResourceParams) - 1
+	}
+
+	config := &spec.ResourceParams[index]
+	for _, p := range []struct{ name, value string }{
+		{"REPOSITORY", event.Repository},
+		{"TAG", event.Tag},
+		{"DIGEST", event.Digest},
+	} {
+		value := p.value
+		found := false
+		for i := range config.Parameters {
+			if config.Parameters[i].Name == p.name {
+				config.Parameters[i].Value = &value
+				found = true
+				break
+			}
+		}
+		if !found {
+			config.Parameters = append(config.Parameters, v1alpha1.ParameterItem{Name: p.name, Value: &value})
+		}
+	}
+}
+
+// StartImageTriggerPoller starts polling tags for image triggers with poll interval set.
+func StartImageTriggerPoller() {
+	go wait.Until(pollImageTriggers, pollPeriod, wait.NeverStop)
+}
+
+// pollImageTriggers polls tags for image triggers whose poll interval has elapsed since the last poll.
+func pollImageTriggers() {
+	wfts, err := listImageTriggers(metav1.NamespaceAll, "")
+	if err != nil {
+		log.Errorf("Failed to list image triggers: %v", err)
+		return
+	}
+
+	now := time.Now()
+	for i := range wfts {
+		wft := &wfts[i]
+		if wft.Spec.Disabled || wft.Spec.Image.PollInterval == "" {
+			continue
+		}
+		interval, err := time.ParseDuration(wft.Spec.Image.PollInterval)
+		if err != nil || interval < minPollInterval {
+			log.Warningf("Invalid poll interval of image trigger %s/%s: %s", wft.Namespace, wft.Name, wft.Spec.Image.PollInterval)
+			continue
+		}
+		if status := wft.Status.Image; status != nil && status.LastPollTime != nil && now.Sub(status.LastPollTime.Time) < interval {
+			continue
+		}
+
+		if err := pollImageTrigger(wft); err != nil {
+			log.Warningf("Poll tags for image trigger %s/%s error: %v", wft.Namespace, wft.Name, err)
+		}
+	}
+}
+
+// pollImageTrigger gets digests of tags matching the image trigger, and fires the trigger for tags whose digests
+// changed. Tags found by the first poll are only recorded, they don't fire the trigger.
+func pollImageTrigger(wft *v1alpha1.WorkflowTrigger) error {
+	in, err := getIntegration(wft.Namespace, wft.Spec.Image.Integration)
+	if err != nil {
+		return err
+	}
+	if in.Spec.DockerRegistry == nil {
+		return fmt.Errorf("integration %s is not a docker registry", wft.Spec.Image.Integration)
+	}
+	server := in.Spec.DockerRegistry.Server
+	client, err := registry.NewClient(&http.Client{Timeout: registryRequestTimeout}, server,
+		in.Spec.DockerRegistry.User, in.Spec.DockerRegistry.Password)
+	if err != nil {
+		return err
+	}
+
+	repository := registry.NormalizeRepository(server, wft.Spec.Image.Repository)
+	tags, err := client.ListTags(repository)
+	if err != nil {
+		return err
+	}
+	digests := make(map[string]string)
+	for _, tag := range tags {
+		matched, err := registry.MatchTrigger(&wft.Spec.Image, server, repository, tag)
+		if err != nil {
+			return err
+		}
+		if !matched {
+			continue
+		}
+		if len(digests) >= maxPolledTags {
+			log.Warningf("Image trigger %s/%s matches more than %d tags, others are ignored", wft.Namespace, wft.Name, maxPolledTags)
+			break
+		}
+		digest, err := client.GetDigest(repository, tag)
+		if err != nil {
+			return err
+		}
+		digests[tag] = digest
+	}
+
+	firstPoll := wft.Status.Image == nil || wft.Status.Image.LastPollTime == nil
+	failed := make(map[string]bool)
+	if !firstPoll {
+		tenant := common.NamespaceTenant(wft.Namespace)
+		for tag, digest := range digests {
+			if wft.Status.Image.Tags[tag] == digest {
+				continue
+			}
+			event := registry.Event{Registry: registry.Host(server), Repository: repository, Tag: tag, Digest: digest}
+			wfr, err := fireImageTrigger(tenant, wft, server, event)
+			if err != nil {
+				log.Errorf("wft %s create workflow run error: %v", wft.Name, err)
+				failed[tag] = true
+				continue
+			}
+			if wfr != nil {
+				log.Infof("Image trigger %s/%s fired by polled tag %s, workflowrun %s created", wft.Namespace, wft.Name, tag, wfr.Name)
+			}
+		}
+	}
+
+	return retry.RetryOnConflict(retry.DefaultRetry, func() error {
+		latest, err := handler.K8sClient.CycloneV1alpha1().WorkflowTriggers(wft.Namespace).Get(context.TODO(), wft.Name, metav1.GetOptions{})
+		if err != nil {
+			return err
+		}
+
+		toUpdate := latest.DeepCopy()
+		if toUpdate.Status.Image == nil {
+			toUpdate.Status.Image = &v1alpha1.ImageTriggerStatus{}
+		}
+		// Tags no longer matched are dropped, and tags failed to trigger keep their previous digests to be retried
+		// in the next poll.
+		tags := make(map[string]string)
+		for tag, digest := range digests {
+			if failed[tag] {
+				digest = toUpdate.Status.Image.Tags[tag]
+				if digest == "" {
+					continue
+				}
+			}
+			tags[tag] = digest
+		}
+		toUpdate.Status.Image.Tags = tags
+		now := metav1.Now()
+		toUpdate.Status.Image.LastPollTime = &now
+		_, err = handler.K8sClient.CycloneV1alpha1().WorkflowTriggers(wft.Namespace).Update(context.TODO(), toUpdate, metav1.UpdateOptions{})
+		return err
+	})
+}
@@ -0,0 +1,156 @@
+package v1alpha1
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/caicloud/cyclone/pkg/apis/cyclone/v1alpha1"
+	"github.com/caicloud/cyclone/pkg/meta"
+	api "github.com/caicloud/cyclone/pkg/server/apis/v1alpha1"
+	"github.com/caicloud/cyclone/pkg/server/biz/integration"
+	"github.com/caicloud/cyclone/pkg/server/biz/registry"
+	"github.com/caicloud/cyclone/pkg/server/common"
+	"github.com/caicloud/cyclone/pkg/server/handler"
+	"github.com/caicloud/cyclone/pkg/util/cerr"
+	"github.com/caicloud/cyclone/pkg/util/k8s/fake"
+)
+
+const testTenant = "devops"
+
+func newTestIntegration(t *testing.T, name string, spec api.IntegrationSpec) *corev1.Secret {
+	secret, err := integration.ToSecret(testTenant, &api.Integration{
+		ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: common.TenantNamespace(testTenant)},
+		Spec:       spec,
+	})
+	assert.Nil(t, err)
+	return secret
+}
+
+func newTestImageTrigger() *v1alpha1.WorkflowTrigger {
+	return &v1alpha1.WorkflowTrigger{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "deploy-on-push",
+			Namespace: common.TenantNamespace(testTenant),
+			Labels:    map[string]string{meta.LabelProjectName: "p"},
+		},
+		Spec: v1alpha1.WorkflowTriggerSpec{
+			Type: v1alpha1.TriggerTypeImage,
+			Image: v1alpha1.ImageTrigger{
+				Integration: "harbor",
+				Repository:  "library/app",
+				Secret:      "token",
+				Resource:    "app-image",
+			},
+			WorkflowRunSpec: v1alpha1.WorkflowRunSpec{
+				WorkflowRef: &corev1.ObjectReference{Name: "deploy"},
+			},
+		},
+	}
+}
+
+func initImageWebhookTest(t *testing.T) {
+	handler.Init(fake.NewSimpleClientset(
+		newTestIntegration(t, "harbor", api.IntegrationSpec{
+			Type: api.DockerRegistry,
+			IntegrationSource: api.IntegrationSource{
+				DockerRegistry: &api.DockerRegistrySource{Server: "harbor.example.com"},
+			},
+		}),
+		newTestIntegration(t, "github", api.IntegrationSpec{Type: api.SCM}),
+		newTestImageTrigger(),
+	))
+}
+
+func registryPushPayload(repository, tag, digest string) []byte {
+	return []byte(fmt.Sprintf(`{"events": [{"action": "push", "target": {"repository": "%s", "tag": "%s", "digest": "%s"},
+		"request": {"host": "harbor.example.com"}}]}`, repository, tag, digest))
+}
+
+func TestDeliverImageWebhook(t *testing.T) {
+	initImageWebhookTest(t)
+
+	_, err := deliverImageWebhook(testTenant, "harbor", "Bearer other", bytes.NewReader(registryPushPayload("library/app", "v1", "sha256:1")))
+	assert.True(t, cerr.ErrorAuthenticationFailed.Derived(err))
+
+	_, err = deliverImageWebhook(testTenant, "github", "Bearer token", bytes.NewReader(registryPushPayload("library/app", "v1", "sha256:1")))
+	assert.True(t, cerr.ErrorUnsupported.Derived(err))
+
+	_, err = deliverImageWebhook(testTenant, "harbor", "Bearer token", bytes.NewReader(make([]byte, maxWebhookPayloadSize+1)))
+	assert.True(t, cerr.ErrorPayloadTooLarge.Derived(err))
+
+	resp, err := deliverImageWebhook(testTenant, "harbor", "Bearer token", bytes.NewReader(registryPushPayload("library/other", "v1", "sha256:1")))
+	assert.Nil(t, err)
+	assert.Equal(t, ignoredMsg, resp.Message)
+
+	resp, err = deliverImageWebhook(testTenant, "harbor", "Bearer token", bytes.NewReader(registryPushPayload("library/app", "v1", "sha256:1")))
+	assert.Nil(t, err)
+	assert.True(t, strings.HasPrefix(resp.Message, succeededMsg))
+
+	// The same push is triggered only once.
+	resp, err = deliverImageWebhook(testTenant, "harbor", "Bearer token", bytes.NewReader(registryPushPayload("library/app", "v1", "sha256:1")))
+	assert.Nil(t, err)
+	assert.Equal(t, ignoredMsg, resp.Message)
+
+	namespace := common.TenantNamespace(testTenant)
+	wfrs, err := handler.K8sClient.CycloneV1alpha1().WorkflowRuns(namespace).List(context.TODO(), metav1.ListOptions{})
+	assert.Nil(t, err)
+	assert.Equal(t, 1, len(wfrs.Items))
+	wfr := wfrs.Items[0]
+	assert.Equal(t, common.ImagePushTrigger, wfr.Annotations[meta.AnnotationWorkflowRunTrigger])
+	assert.Contains(t, wfr.Spec.GlobalVariables, v1alpha1.GlobalVariable{Name: "image.tag", Value: "v1"})
+
+	wft, err := handler.K8sClient.CycloneV1alpha1().WorkflowTriggers(namespace).Get(context.TODO(), "deploy-on-push", metav1.GetOptions{})
+	assert.Nil(t, err)
+	assert.Equal(t, map[string]string{"v1": "sha256:1"}, wft.Status.Image.Tags)
+	assert.Equal(t, 1, wft.Status.Count)
+}
+
+func TestApplyImageEvent(t *testing.T) {
+	spec := &v1alpha1.WorkflowRunSpec{}
+	event := registry.Event{Repository: "library/app", Tag: "v1", Digest: "sha256:1"}
+	applyImageEvent(spec, "app-image", "https://harbor.example.com", event)
+
+	assert.Equal(t, 1, len(spec.ResourceParams))
+	assert.Equal(t, "app-image", spec.ResourceParams[0].Name)
+	params := make(map[string]string)
+	for _, p := range spec.ResourceParams[0].Parameters {
+		params[p.Name] = *p.Value
+	}
+	assert.Equal(t, map[string]string{"REPOSITORY": "library/app", "TAG": "v1", "DIGEST": "sha256:1"}, params)
+	assert.Contains(t, spec.GlobalVariables, v1alpha1.GlobalVariable{Name: "image.registry", Value: "harbor.example.com"})
+}
+
+func TestPruneImageTags(t *testing.T) {
+	trigger := &v1alpha1.ImageTrigger{Repository: "library/app", Tags: &v1alpha1.RefFilter{Include: []string{"v*"}}}
+	tags := map[string]string{"latest": "sha256:0"}
+	for i := 0; i < maxPolledTags+10; i++ {
+		tags[fmt.Sprintf("v%03d", i)] = "sha256:1"
+	}
+	pruneImageTags(trigger, "harbor.example.com", tags, "v000")
+
+	assert.Equal(t, maxPolledTags, len(tags))
+	assert.Contains(t, tags, "v000")
+	assert.NotContains(t, tags, "latest")
+	assert.NotContains(t, tags, "v001")
+	assert.Contains(t, tags, fmt.Sprintf("v%03d", maxPolledTags+9))
+}
+
+func TestValidateImageTrigger(t *testing.T) {
+	initImageWebhookTest(t)
+
+	wft := newTestImageTrigger()
+	assert.Nil(t, validateImageTrigger(testTenant, wft))
+
+	wft.Spec.Image.Integration = "github"
+	assert.True(t, cerr.ErrorValidationFailed.Derived(validateImageTrigger(testTenant, wft)))
+
+	wft.Spec.Image.Integration = "missing"
+	assert.NotNil(t, validateImageTrigger(testTenant, wft))
+}
@@ -0,0 +1,63 @@
+package v1alpha1
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/caicloud/cyclone/pkg/apis/cyclone/v1alpha1"
+	"github.com/caicloud/cyclone/pkg/meta"
+	"github.com/caicloud/cyclone/pkg/server/biz/scm"
+	"github.com/caicloud/cyclone/pkg/server/biz/scm/gitlab"
+	"github.com/caicloud/cyclone/pkg/server/handler"
+	"github.com/caicloud/cyclone/pkg/util/cerr"
+	"github.com/caicloud/cyclone/pkg/util/k8s/fake"
+)
+
+func TestCreateWorkflowRunIdempotent(t *testing.T) {
+	handler.Init(fake.NewSimpleClientset())
+
+	wft := v1alpha1.WorkflowTrigger{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "wft",
+			Namespace: "cyclone-system",
+			Labels:    map[string]string{meta.LabelProjectName: "p"},
+		},
+		Spec: v1alpha1.WorkflowTriggerSpec{
+			WorkflowRunSpec: v1alpha1.WorkflowRunSpec{
+				WorkflowRef: &corev1.ObjectReference{Name: "wf"},
+			},
+		},
+	}
+	data := &scm.EventData{Type: scm.PushEventType, Repo: "r", Ref: "refs/heads/master"}
+	match := &scm.TriggerMatch{Matched: true}
+
+	name, err := createWorkflowRun("system", wft, data, match, "delivery-1")
+	assert.Nil(t, err)
+	retried, err := createWorkflowRun("system", wft, data, match, "delivery-1")
+	assert.Nil(t, err)
+	assert.Equal(t, name, retried)
+
+	other, err := createWorkflowRun("system", wft, data, match, "delivery-2")
+	assert.Nil(t, err)
+	assert.NotEqual(t, name, other)
+
+	wfrs, err := handler.K8sClient.CycloneV1alpha1().WorkflowRuns("cyclone-system").List(context.TODO(), metav1.ListOptions{})
+	assert.Nil(t, err)
+	assert.Equal(t, 2, len(wfrs.Items))
+}
+
+func TestReceiveSCMWebhookPayloadTooLarge(t *testing.T) {
+	handler.Init(fake.NewSimpleClientset())
+
+	request := httptest.NewRequest(http.MethodPost, "/apis/v1alpha1/tenants/system/webhook", bytes.NewReader(make([]byte, maxWebhookPayloadSize+1)))
+	request.Header.Set(gitlab.EventTypeHeader, "Push Hook")
+	_, err := receiveSCMWebhook("system", "gitlab", request)
+	assert.True(t, cerr.ErrorPayloadTooLarge.Derived(err))
+}
@@ -2,11 +2,11 @@ package v1alpha1
 
 import (
 	"context"
+	"crypto/subtle"
 	"fmt"
 	"io"
-	"io/ioutil"
-	"os"
 	"reflect"
+	"regexp"
 	"sort"
 	"strings"
 	"time"
@@ -14,8 +14,10 @@ import (
 	"github.com/caicloud/nirvana/log"
 	"github.com/gorilla/websocket"
 	core_v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	k8s_types "k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/rand"
 	"k8s.io/client-go/util/retry"
 
 	"github.com/caicloud/cyclone/pkg/apis/cyclone/v1alpha1"
@@ -23,6 +25,8 @@ import (
 	api "github.com/caicloud/cyclone/pkg/server/apis/v1alpha1"
 	"github.com/caicloud/cyclone/pkg/server/biz/accelerator"
 	"github.com/caicloud/cyclone/pkg/server/biz/artifact"
+	"github.com/caicloud/cyclone/pkg/server/biz/identity"
+	"github.com/caicloud/cyclone/pkg/server/biz/logstore"
 	"github.com/caicloud/cyclone/pkg/server/biz/stream"
 	"github.com/caicloud/cyclone/pkg/server/biz/utils"
 	"github.com/caicloud/cyclone/pkg/server/common"
@@ -33,15 +37,18 @@ import (
 	"github.com/caicloud/cyclone/pkg/util"
 	"github.com/caicloud/cyclone/pkg/util/cerr"
 	contextutil "github.com/caicloud/cyclone/pkg/util/context"
-	fileutil "github.com/caicloud/cyclone/pkg/util/file"
 	httputil "github.com/caicloud/cyclone/pkg/util/http"
 	websocketutil "github.com/caicloud/cyclone/pkg/util/websocket"
 	wfcommon "github.com/caicloud/cyclone/pkg/workflow/common"
+	"github.com/caicloud/cyclone/pkg/workflow/workload/delegation"
 )
 
 const (
 	// stageArtifactFormFileKey is the form file key to receive stage artifact
 	stageArtifactFormFileKey = "file"
+	// delegationLogName is name of the log file that stores logs reported by delegation service, it's used
+	// as the container name of the stage logs.
+	delegationLogName = "delegation"
 )
 
 // CreateWorkflowRun ...
@@ -72,8 +79,15 @@ func workflowRunCreationDryRun(project, workflow, tenant string, wfr *v1alpha1.W
 		return nil, err
 	}
 
-	artifactManager := artifact.NewManager()
-	go artifactManager.CleanPeriodically(config.Config.Artifact.RetentionSeconds * time.Second)
+	// Only artifacts stored on the disk of cyclone server are limited by available space.
+	store, err := getArtifactStore(tenant)
+	if err != nil {
+		return nil, err
+	}
+	local, ok := store.(*artifact.LocalStore)
+	if !ok {
+		return nil, nil
+	}
 
 	for _, stage := range wf.Spec.Stages {
 		stg, err := handler.K8sClient.CycloneV1alpha1().Stages(common.TenantNamespace(tenant)).Get(context.TODO(), stage.Name, metav1.GetOptions{})
@@ -88,7 +102,7 @@ func workflowRunCreationDryRun(project, workflow, tenant string, wfr *v1alpha1.W
 		// Check disk space for output http resources
 		for _, resource := range stg.Spec.Pod.Outputs.Resources {
 			if resource.Type == v1alpha1.HTTPResourceType {
-				percentage, err := artifactManager.GetDiskAvailablePercentage()
+				percentage, err := local.DiskAvailablePercentage()
 				if err != nil {
 					log.Errorf("Get disk available space error: %v", err)
 					return nil, err
@@ -327,6 +341,290 @@ func ResumeWorkflowRun(ctx context.Context, project, workflow, workflowrun, tena
 	return wfr, cerr.ConvertK8sError(err)
 }
 
+// ApproveStage approves an approval stage of a WorkflowRun, the WorkflowRun would continue to run after approved.
+func ApproveStage(ctx context.Context, project, workflow, workflowrun, stage, tenant, comment string) (*v1alpha1.WorkflowRun, error) {
+	return decideApproval(ctx, tenant, workflowrun, stage, comment, true)
+}
+
+// RejectStage rejects an approval stage of a WorkflowRun, the stage would fail after rejected.
+func RejectStage(ctx context.Context, project, workflow, workflowrun, stage, tenant, comment string) (*v1alpha1.WorkflowRun, error) {
+	return decideApproval(ctx, tenant, workflowrun, stage, comment, false)
+}
+
+// decideApproval records decision of the approver in status of the approval stage, and resumes the WorkflowRun
+// if it's waiting. The approver is the user whose identity is signed by the gateway in request headers.
+func decideApproval(ctx context.Context, tenant, workflowrun, stage, comment string, approved bool) (*v1alpha1.WorkflowRun, error) {
+	approver, err := identity.FromRequest(config.Config.UserIdentity.Secret, contextutil.GetHTTPRequest(ctx), time.Now())
+	if err != nil {
+		log.Warningf("Get approver of stage %s in workflowrun %s error: %v", stage, workflowrun, err)
+		if err == identity.ErrNotConfigured || err == identity.ErrMissing {
+			return nil, cerr.ErrorAuthorizationRequired.Error()
+		}
+		return nil, cerr.ErrorAuthorizationFailed.Error()
+	}
+	user := approver.User
+
+	var result *v1alpha1.WorkflowRun
+	var validationErr error
+	err = retry.RetryOnConflict(retry.DefaultRetry, func() error {
+		wfr, err := handler.K8sClient.CycloneV1alpha1().WorkflowRuns(common.TenantNamespace(tenant)).Get(context.TODO(), workflowrun, metav1.GetOptions{})
+		if err != nil {
+			return err
+		}
+
+		status, ok := wfr.Status.Stages[stage]
+		if !ok || status.Approval == nil {
+			validationErr = cerr.ErrorValidationFailed.Error("stage", fmt.Sprintf("stage %s is not an approval stage", stage))
+			return nil
+		}
+		if status.Status.Phase != v1alpha1.StatusWaiting || status.Approval.Approver != "" {
+			validationErr = cerr.ErrorValidationFailed.Error("stage", fmt.Sprintf("stage %s is not waiting for approval", stage))
+			return nil
+		}
+		if !isApprover(status.Approval, approver) {
+			validationErr = cerr.ErrorApprovalNotAllowed.Error(user, stage)
+			return nil
+		}
+
+		now := metav1.Now()
+		status.Approval.Approver = user
+		status.Approval.Approved = approved
+		status.Approval.Time = &now
+		status.Approval.Comment = comment
+
+		phase, reason, message := v1alpha1.StatusSucceeded, v1alpha1.ReasonApproved, fmt.Sprintf("Approved by %s", user)
+		if !approved {
+			phase, reason, message = v1alpha1.StatusFailed, v1alpha1.ReasonRejected, fmt.Sprintf("Rejected by %s", user)
+		}
+		status.Status.Phase = phase
+		status.Status.Reason = reason
+		status.Status.Message = message
+		status.Status.LastTransitionTime = now
+
+		// Resume the WorkflowRun so that workflow controller would continue to process it.
+		if wfr.Status.Overall.Phase == v1alpha1.StatusWaiting {
+			wfr.Status.Overall.Phase = v1alpha1.StatusRunning
+			wfr.Status.Overall.LastTransitionTime = now
+		}
+
+		result, err = handler.K8sClient.CycloneV1alpha1().WorkflowRuns(wfr.Namespace).Update(context.TODO(), wfr, metav1.UpdateOptions{})
+		return err
+	})
+	if err != nil {
+		log.Errorf("Decide approval of stage %s in workflowrun %s error: %v", stage, workflowrun, err)
+		return nil, cerr.ConvertK8sError(err)
+	}
+	if validationErr != nil {
+		return nil, validationErr
+	}
+
+	return result, nil
+}
+
+// isApprover checks whether the user is allowed to approve the stage, the user should be in the approval
+// users, or belong to one of the approval groups. Anyone can approve if neither users nor groups are set.
+func isApprover(approval *v1alpha1.ApprovalStatus, approver *identity.Identity) bool {
+	if len(approval.Users) == 0 && len(approval.Groups) == 0 {
+		return true
+	}
+
+	for _, u := range approval.Users {
+		if u == approver.User {
+			return true
+		}
+	}
+
+	for _, g := range approver.Groups {
+		for _, ag := range approval.Groups {
+			if ag == g {
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
+// RerunWorkflowRun re-runs a terminated WorkflowRun from its failed stages. A new WorkflowRun is created with
+// status and artifacts of succeeded stages inherited, and it would start from stages that haven't succeeded.
+// If data of the original WorkflowRun in PV hasn't been cleaned, the new WorkflowRun would share it.
+func RerunWorkflowRun(ctx context.Context, project, workflow, workflowrun, tenant string) (*v1alpha1.WorkflowRun, error) {
+	origin, err := handler.K8sClient.CycloneV1alpha1().WorkflowRuns(common.TenantNamespace(tenant)).Get(context.TODO(), workflowrun, metav1.GetOptions{})
+	if err != nil {
+		return nil, cerr.ConvertK8sError(err)
+	}
+
+	if !util.IsWorkflowRunTerminated(origin) {
+		return nil, cerr.ErrorValidationFailed.Error("workflowrun", fmt.Sprintf("workflowrun %s is not terminated", workflowrun))
+	}
+
+	rerunStages := workflowRunRerunStages(origin)
+	if len(rerunStages) == 0 {
+		return nil, cerr.ErrorValidationFailed.Error("workflowrun", fmt.Sprintf("no stages to re-run in workflowrun %s", workflowrun))
+	}
+
+	wfr := rerunWorkflowRun(origin, rerunStages)
+
+	modifiers := []CreationModifier{GenerateNameModifier, InjectProjectLabelModifier, InjectWorkflowLabelModifier, InjectWorkflowOwnerRefModifier}
+	for _, modifier := range modifiers {
+		err := modifier(tenant, project, workflow, wfr)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	// Artifacts of inherited stages are copied before the workflowrun is created, so that they are available once
+	// stages to re-run start. Copies are deleted if the workflowrun fails to be created.
+	name := wfr.Name
+	cleanup := func() {
+		if err := deleteCollections(tenant, project, workflow, name); err != nil {
+			log.Warningf("Delete copied artifacts of workflowrun %s error: %v", name, err)
+		}
+	}
+	for stage := range wfr.Status.Stages {
+		if err := copyStageArtifacts(tenant, project, workflow, origin.Name, name, stage); err != nil {
+			log.Errorf("Copy artifacts of stage %s from workflowrun %s error: %v", stage, origin.Name, err)
+			cleanup()
+			return nil, cerr.ErrorUnknownInternal.Error(err)
+		}
+	}
+
+	wfr, err = handler.K8sClient.CycloneV1alpha1().WorkflowRuns(common.TenantNamespace(tenant)).Create(context.TODO(), wfr, metav1.CreateOptions{})
+	if err != nil {
+		cleanup()
+		return nil, cerr.ConvertK8sError(err)
+	}
+	return wfr, nil
+}
+
+// rerunWorkflowRun creates a WorkflowRun to re-run stages of the origin one. Status of other stages are inherited
+// from the origin, and stages to re-run without dependencies in them are started.
+func rerunWorkflowRun(origin *v1alpha1.WorkflowRun, rerunStages map[string]bool) *v1alpha1.WorkflowRun {
+	wfr := &v1alpha1.WorkflowRun{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:        fmt.Sprintf("%s-rerun-%s", origin.Name, rand.String(5)),
+			Labels:      make(map[string]string),
+			Annotations: make(map[string]string),
+		},
+		Spec: *origin.Spec.DeepCopy(),
+		Status: v1alpha1.WorkflowRunStatus{
+			Stages: make(map[string]*v1alpha1.StageStatus),
+		},
+	}
+	for k, v := range origin.Labels {
+		if k != meta.LabelWorkflowRunNotificationSent {
+			wfr.Labels[k] = v
+		}
+	}
+	for k, v := range origin.Annotations {
+		if k != meta.AnnotationAlias {
+			wfr.Annotations[k] = v
+		}
+	}
+	wfr.Annotations[meta.AnnotationWorkflowRunRerunFrom] = origin.Name
+	if origin.Status.Cleaned {
+		delete(wfr.Labels, meta.LabelWorkflowRunWorkspace)
+	} else {
+		wfr.Labels[meta.LabelWorkflowRunWorkspace] = wfcommon.WorkspaceName(origin)
+	}
+
+	wfr.Spec.StartStages = nil
+	for stage, status := range origin.Status.Stages {
+		// Instances of matrix stage are inherited or re-run together with the matrix stage.
+		logical := stage
+		if status.Parent != "" {
+			logical = status.Parent
+		}
+
+		if !rerunStages[logical] {
+			inherited := status.DeepCopy()
+			inherited.Pod = nil
+			inherited.Attempts = nil
+			inherited.Status.Reason = v1alpha1.ReasonRerun
+			inherited.Status.Message = fmt.Sprintf("Inherited from workflowrun %s", origin.Name)
+			wfr.Status.Stages[stage] = inherited
+			continue
+		}
+		if status.Parent != "" {
+			continue
+		}
+
+		start := true
+		for _, d := range status.Depends {
+			if rerunStages[d] {
+				start = false
+				break
+			}
+		}
+		if start {
+			wfr.Spec.StartStages = append(wfr.Spec.StartStages, stage)
+		}
+	}
+	sort.Strings(wfr.Spec.StartStages)
+
+	return wfr
+}
+
+// workflowRunRerunStages gets stages that need to be re-run in a WorkflowRun, they are stages haven't
+// succeeded or skipped, and stages depend on them directly or indirectly.
+func workflowRunRerunStages(wfr *v1alpha1.WorkflowRun) map[string]bool {
+	stages := make(map[string]bool)
+	for stage, status := range wfr.Status.Stages {
+		if status.Parent != "" {
+			continue
+		}
+		if status.Status.Phase != v1alpha1.StatusSucceeded && status.Status.Phase != v1alpha1.StatusSkipped {
+			stages[stage] = true
+		}
+	}
+
+	for changed := true; changed; {
+		changed = false
+		for stage, status := range wfr.Status.Stages {
+			if stages[stage] || status.Parent != "" {
+				continue
+			}
+			for _, d := range status.Depends {
+				if stages[d] {
+					stages[stage] = true
+					changed = true
+					break
+				}
+			}
+		}
+	}
+
+	return stages
+}
+
+// copyStageArtifacts copies artifacts of a stage received from one workflowrun to another.
+func copyStageArtifacts(tenant, project, workflow, from, to, stage string) error {
+	store, err := getArtifactStore(tenant)
+	if err != nil {
+		return err
+	}
+	src, err := getArtifactPrefix(tenant, project, workflow, from, stage)
+	if err != nil {
+		return err
+	}
+	dst, err := getArtifactPrefix(tenant, project, workflow, to, stage)
+	if err != nil {
+		return err
+	}
+
+	objects, err := store.List(src)
+	if err != nil {
+		return err
+	}
+	for _, object := range objects {
+		if err := store.Copy(object.Key, dst+strings.TrimPrefix(object.Key, src)); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
 // ReceiveContainerLogStream receives real-time log of container within workflowrun stage.
 func ReceiveContainerLogStream(ctx context.Context, workflowrun, namespace, stage, container string) error {
 	// get workflowrun
@@ -375,28 +673,10 @@ func ReceiveContainerLogStream(ctx context.Context, workflowrun, namespace, stag
 // receiveContainerLogStream receives the log stream for
 // one stage of the workflowrun, and stores it into log files.
 func receiveContainerLogStream(tenant, project, workflow, workflowrun, stage, container string, ws *websocket.Conn) error {
-	logFolder, err := getLogFolder(tenant, project, workflow, workflowrun)
-	if err != nil {
-		log.Errorf("get log folder failed: %v", err)
-		return err
-	}
-
-	// create log folders.
-	fileutil.CreateDirectory(logFolder)
-
-	logFilePath, err := getLogFilePath(tenant, project, workflow, workflowrun, stage, container)
-	if err != nil {
-		log.Errorf("get log path failed: %v", err)
-		return err
-	}
-
-	if fileutil.Exists(logFilePath) {
-		log.Infof("log file %s already exists, append logs", logFilePath)
-	}
-
-	file, err := os.OpenFile(logFilePath, os.O_RDWR|os.O_CREATE|os.O_APPEND, 0666)
+	run := logstore.Run{Tenant: tenant, Project: project, Workflow: workflow, WorkflowRun: workflowrun}
+	file, err := logStore.Writer(run, stage, container)
 	if err != nil {
-		log.Errorf("fail to open the log file %s as %v", logFilePath, err)
+		log.Errorf("fail to open the log writer for %s/%s/%s/%s as %v", tenant, workflowrun, stage, container, err)
 		return err
 	}
 
@@ -479,7 +759,7 @@ func GetContainerLogStream(ctx context.Context, project, workflow, workflowrun,
 
 // getContainerLogStream watches the log files and sends the content to the log stream.
 func getContainerLogStream(tenant, project, workflow, workflowrun, stage string, ws *websocket.Conn) error {
-	logFolder, err := getLogFolder(tenant, project, workflow, workflowrun)
+	logFolder, err := logStore.Folder(logstore.Run{Tenant: tenant, Project: project, Workflow: workflow, WorkflowRun: workflowrun})
 	if err != nil {
 		return err
 	}
@@ -541,7 +821,7 @@ func GetContainerLogs(ctx context.Context, project, workflow, workflowrun, tenan
 		headers["Content-Disposition"] = fmt.Sprintf("attachment; filename=%s", logFileName)
 	}
 
-	logFolder, _ := getLogFolder(tenant, project, workflow, workflowrun)
+	logFolder, _ := logStore.Folder(logstore.Run{Tenant: tenant, Project: project, Workflow: workflow, WorkflowRun: workflowrun})
 	prefix := fmt.Sprintf("%s_", stage)
 	exclusions := []string{fmt.Sprintf("%s_%s", stage, wfcommon.CoordinatorSidecarName), fmt.Sprintf("%s_%s", stage, wfcommon.DockerInDockerSidecarName)}
 	folderReader := stream.NewFolderReader(logFolder, prefix, exclusions, 0, nil)
@@ -549,30 +829,168 @@ func GetContainerLogs(ctx context.Context, project, workflow, workflowrun, tenan
 	return folderReader, headers, nil
 }
 
-// ReceiveArtifacts receives artifacts produced by workflowrun stage.
-func ReceiveArtifacts(ctx context.Context, workflowrun, namespace, stage string) error {
-	artifactManager := artifact.NewManager()
-	percentage, err := artifactManager.GetDiskAvailablePercentage()
+// SearchLogs searches logs of containers in the workflowrun, lines are selected by stages, container, time range
+// and pattern, and ordered by the time they were received. Logs of the coordinator and docker-in-docker sidecars
+// are searched only if they are specified by container.
+func SearchLogs(ctx context.Context, project, workflow, workflowrun, tenant, stages, container, start, end, pattern string,
+	query *types.QueryParams) (*types.ListResponse, error) {
+	q := &logstore.Query{
+		Container:  container,
+		Exclusions: logExclusions,
+		Offset:     int(query.Start),
+		Limit:      int(query.Limit),
+	}
+	if stages != "" {
+		q.Stages = strings.Split(stages, ",")
+	}
+
+	var err error
+	if q.Since, err = parseLogTime(start); err != nil {
+		return nil, cerr.ErrorValidationFailed.Error(httputil.StartTimeQueryParameter, err)
+	}
+	if q.Until, err = parseLogTime(end); err != nil {
+		return nil, cerr.ErrorValidationFailed.Error(httputil.EndTimeQueryParameter, err)
+	}
+	if pattern != "" {
+		if q.Pattern, err = regexp.Compile(pattern); err != nil {
+			return nil, cerr.ErrorValidationFailed.Error(httputil.PatternQueryParameter, err)
+		}
+	}
+
+	run := logstore.Run{Tenant: tenant, Project: project, Workflow: workflow, WorkflowRun: workflowrun}
+	result, err := logStore.Query(run, q)
+	if err == logstore.ErrNotFound {
+		return types.NewListResponse(0, []api.LogLine{}), nil
+	}
 	if err != nil {
-		log.Errorf("Get disk available space error: %v", err)
-		return err
+		log.Errorf("Search logs of workflowrun %s error: %v", workflowrun, err)
+		return nil, cerr.ErrorUnknownInternal.Error(err)
 	}
 
-	if percentage < config.Config.Artifact.RetentionDiskProtectionThreshold {
-		err = cerr.ErrorNoSpaceLeftForHTTPResource.Error(namespace, workflowrun, stage, percentage)
-		log.Error(err)
-		return nil
+	lines := make([]api.LogLine, 0, len(result.Lines))
+	for _, line := range result.Lines {
+		lines = append(lines, api.LogLine{
+			Stage:     line.Stage,
+			Container: line.Container,
+			Number:    line.Number,
+			Time:      line.Time,
+			Content:   line.Content,
+		})
 	}
+	return types.NewListResponse(result.Total, lines), nil
+}
 
-	// get workflowrun
-	wfr, err := handler.K8sClient.CycloneV1alpha1().WorkflowRuns(namespace).Get(context.TODO(), workflowrun, metav1.GetOptions{})
+// ExportLogs exports logs of all containers in the workflowrun as a gzipped tar archive.
+func ExportLogs(ctx context.Context, project, workflow, workflowrun, tenant string) (io.ReadCloser, map[string]string, error) {
+	run := logstore.Run{Tenant: tenant, Project: project, Workflow: workflow, WorkflowRun: workflowrun}
+	exists, err := logStore.Exists(run)
 	if err != nil {
-		log.Errorf("get wfr %s/%s error %s", namespace, workflowrun, err)
+		return nil, nil, cerr.ErrorUnknownInternal.Error(err)
+	}
+	if !exists {
+		return nil, nil, cerr.ErrorContentNotFound.Error(fmt.Sprintf("logs of workflowrun %s", workflowrun))
+	}
+
+	reader, writer := io.Pipe()
+	go func() {
+		writer.CloseWithError(logStore.Export(run, writer))
+	}()
+
+	headers := make(map[string]string)
+	headers[httputil.HeaderContentType] = "application/gzip"
+	headers["Content-Disposition"] = fmt.Sprintf("attachment; filename=%s-logs.tar.gz", workflowrun)
+	return reader, headers, nil
+}
+
+// ReportDelegation receives status of a delegated stage reported by delegation service. The report should be
+// authenticated by the callback token sent in the delegation request.
+func ReportDelegation(ctx context.Context, workflowrun, namespace, stage string, report *delegation.Report) error {
+	if err := report.Validate(); err != nil {
+		return cerr.ErrorValidationFailed.Error("report", err)
+	}
+
+	request := contextutil.GetHTTPRequest(ctx)
+	token := strings.TrimPrefix(request.Header.Get("Authorization"), "Bearer ")
+
+	var validationErr error
+	var wfr *v1alpha1.WorkflowRun
+	err := retry.RetryOnConflict(retry.DefaultRetry, func() error {
+		var err error
+		wfr, err = handler.K8sClient.CycloneV1alpha1().WorkflowRuns(namespace).Get(context.TODO(), workflowrun, metav1.GetOptions{})
+		if err != nil {
+			return err
+		}
+
+		status, ok := wfr.Status.Stages[stage]
+		if !ok || status.Delegation == nil || !delegation.VerifyToken(token, status.Delegation.TokenDigest) {
+			validationErr = cerr.ErrorAuthorizationFailed.Error()
+			return nil
+		}
+		if status.Status.Phase != v1alpha1.StatusWaiting && status.Status.Phase != v1alpha1.StatusRunning {
+			validationErr = cerr.ErrorValidationFailed.Error("stage", fmt.Sprintf("stage %s is %s", stage, status.Status.Phase))
+			return nil
+		}
+
+		now := metav1.Now()
+		status.Delegation.LastReportTime = &now
+		status.Outputs = mergeOutputs(status.Outputs, report.Outputs)
+		status.Status.Phase = report.Phase
+		status.Status.Reason = v1alpha1.ReasonDelegationReported
+		status.Status.Message = report.Message
+		status.Status.LastTransitionTime = now
+		if status.Status.StartTime.IsZero() {
+			status.Status.StartTime = now
+		}
+
+		// Resume the WorkflowRun so that workflow controller would continue to process it.
+		if wfr.Status.Overall.Phase == v1alpha1.StatusWaiting {
+			wfr.Status.Overall.Phase = v1alpha1.StatusRunning
+			wfr.Status.Overall.LastTransitionTime = now
+		}
+
+		_, err = handler.K8sClient.CycloneV1alpha1().WorkflowRuns(namespace).Update(context.TODO(), wfr, metav1.UpdateOptions{})
 		return err
+	})
+	if err != nil {
+		log.Errorf("Report delegation of stage %s in workflowrun %s/%s error: %v", stage, namespace, workflowrun, err)
+		return cerr.ConvertK8sError(err)
+	}
+	if validationErr != nil {
+		return validationErr
 	}
 
-	// get tenant, project, workflow from workflowrun
-	tenant := common.NamespaceTenant(namespace)
+	if len(report.Logs) > 0 {
+		if err := appendDelegationLogs(wfr, stage, report.Logs); err != nil {
+			log.Errorf("Append logs of stage %s in workflowrun %s/%s error: %v", stage, namespace, workflowrun, err)
+			return cerr.ErrorUnknownInternal.Error(err)
+		}
+	}
+
+	return nil
+}
+
+// mergeOutputs merges reported outputs into existing ones, values of existing keys are overridden.
+func mergeOutputs(outputs []v1alpha1.KeyValue, reported []v1alpha1.KeyValue) []v1alpha1.KeyValue {
+	for _, r := range reported {
+		found := false
+		for i := range outputs {
+			if outputs[i].Key == r.Key {
+				outputs[i].Value = r.Value
+				found = true
+				break
+			}
+		}
+		if !found {
+			outputs = append(outputs, r)
+		}
+	}
+
+	return outputs
+}
+
+// appendDelegationLogs appends log lines reported by delegation service to logs of the stage.
+func appendDelegationLogs(wfr *v1alpha1.WorkflowRun, stage string, lines []string) error {
+	tenant := common.NamespaceTenant(wfr.Namespace)
 	var project, workflow string
 	if wfr.Labels != nil {
 		project = wfr.Labels[meta.LabelProjectName]
@@ -582,11 +1000,9 @@ func ReceiveArtifacts(ctx context.Context, workflowrun, namespace, stage string)
 		return fmt.Errorf("failed to get project or workflow from workflowrun labels")
 	}
 
-	request := contextutil.GetHTTPRequest(ctx)
-
-	file, fileHeader, err := request.FormFile(stageArtifactFormFileKey)
+	run := logstore.Run{Tenant: tenant, Project: project, Workflow: workflow, WorkflowRun: wfr.Name}
+	file, err := logStore.Writer(run, stage, delegationLogName)
 	if err != nil {
-		log.Infof("Form file by key %s error: %v", stageArtifactFormFileKey, err)
 		return err
 	}
 
@@ -596,94 +1012,223 @@ func ReceiveArtifacts(ctx context.Context, workflowrun, namespace, stage string)
 		}
 	}()
 
-	folder, err := getArtifactFolder(tenant, project, workflow, workflowrun, stage)
+	for _, line := range lines {
+		if _, err := file.Write([]byte(line + "\n")); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// ReceiveArtifacts receives artifacts produced by workflowrun stage. The request should be authenticated by the
+// artifact token of the workflowrun, which is injected to the coordinator by workflow controller.
+func ReceiveArtifacts(ctx context.Context, workflowrun, namespace, stage string) error {
+	request := contextutil.GetHTTPRequest(ctx)
+	token := strings.TrimPrefix(request.Header.Get("Authorization"), "Bearer ")
+	if err := verifyArtifactToken(namespace, workflowrun, token); err != nil {
+		return err
+	}
+
+	// get tenant, project, workflow from workflowrun
+	tenant := common.NamespaceTenant(namespace)
+	store, err := getArtifactStore(tenant)
 	if err != nil {
-		log.Errorf("Get workflowrun %s artifact folder failed: %v", workflowrun, err)
+		log.Errorf("Get artifact store of tenant %s error: %v", tenant, err)
 		return err
 	}
 
-	// create artifact folders.
-	fileutil.CreateDirectory(folder)
-	// copy file
-	f, err := os.OpenFile(fmt.Sprintf("%s/%s", folder, fileHeader.Filename), os.O_WRONLY|os.O_CREATE, 0666)
+	// Only artifacts stored on the disk of cyclone server are limited by available space.
+	if local, ok := store.(*artifact.LocalStore); ok {
+		percentage, err := local.DiskAvailablePercentage()
+		if err != nil {
+			log.Errorf("Get disk available space error: %v", err)
+			return err
+		}
+
+		if percentage < config.Config.Artifact.RetentionDiskProtectionThreshold {
+			err = cerr.ErrorNoSpaceLeftForHTTPResource.Error(namespace, workflowrun, stage, percentage)
+			log.Error(err)
+			return nil
+		}
+	}
+
+	project, workflow, err := getWorkflowRunOwners(namespace, workflowrun)
 	if err != nil {
 		return err
 	}
 
+	file, fileHeader, err := request.FormFile(stageArtifactFormFileKey)
+	if err != nil {
+		log.Infof("Form file by key %s error: %v", stageArtifactFormFileKey, err)
+		return err
+	}
+
 	defer func() {
-		if err := f.Close(); err != nil {
+		if err := file.Close(); err != nil {
 			log.Errorf("Fail to close file as: %v", err)
 		}
 	}()
 
-	_, err = io.Copy(f, file)
+	key, err := getArtifactKey(tenant, project, workflow, workflowrun, stage, fileHeader.Filename)
 	if err != nil {
-		log.Infof("copy artifact %s error: %v", fileHeader.Filename, err)
+		log.Errorf("Get workflowrun %s artifact key failed: %v", workflowrun, err)
+		return err
 	}
 
-	return err
+	object, err := store.Put(key, file)
+	if err != nil {
+		log.Infof("store artifact %s error: %v", fileHeader.Filename, err)
+		return err
+	}
+	log.Infof("Artifact %s stored, size: %d, sha256: %s", key, object.Size, object.Checksum)
+
+	return nil
+}
+
+// getWorkflowRunOwners gets project and workflow of the workflowrun from its labels.
+func getWorkflowRunOwners(namespace, workflowrun string) (string, string, error) {
+	wfr, err := handler.K8sClient.CycloneV1alpha1().WorkflowRuns(namespace).Get(context.TODO(), workflowrun, metav1.GetOptions{})
+	if err != nil {
+		log.Errorf("get wfr %s/%s error %s", namespace, workflowrun, err)
+		return "", "", cerr.ConvertK8sError(err)
+	}
+
+	var project, workflow string
+	if wfr.Labels != nil {
+		project = wfr.Labels[meta.LabelProjectName]
+		workflow = wfr.Labels[meta.LabelWorkflowName]
+	}
+	if project == "" || workflow == "" {
+		return "", "", fmt.Errorf("failed to get project or workflow from workflowrun labels")
+	}
+	return project, workflow, nil
+}
+
+// DownloadStageArtifact downloads artifacts uploaded by workflowrun stage, it's used by stages to get input
+// artifacts when there is no PVC to share them. The request should be authenticated by the artifact token of
+// the workflowrun, which is injected to the downloading containers by workflow controller.
+func DownloadStageArtifact(ctx context.Context, workflowrun, artifactName, namespace, stage string) (io.ReadCloser, map[string]string, error) {
+	request := contextutil.GetHTTPRequest(ctx)
+	token := strings.TrimPrefix(request.Header.Get("Authorization"), "Bearer ")
+	if err := verifyArtifactToken(namespace, workflowrun, token); err != nil {
+		return nil, nil, err
+	}
+
+	project, workflow, err := getWorkflowRunOwners(namespace, workflowrun)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return DownloadArtifact(ctx, project, workflow, workflowrun, artifactName, common.NamespaceTenant(namespace), stage)
+}
+
+// verifyArtifactToken checks whether the token matches the one kept in the artifact token secret of the workflowrun.
+func verifyArtifactToken(namespace, workflowrun, token string) error {
+	secret, err := handler.K8sClient.CoreV1().Secrets(namespace).Get(context.TODO(), wfcommon.ArtifactTokenSecretName(workflowrun), metav1.GetOptions{})
+	if err != nil {
+		if errors.IsNotFound(err) {
+			return cerr.ErrorAuthorizationFailed.Error()
+		}
+		log.Errorf("Get artifact token of workflowrun %s/%s error: %v", namespace, workflowrun, err)
+		return cerr.ConvertK8sError(err)
+	}
+
+	expected := secret.Data[wfcommon.ArtifactTokenSecretKey]
+	if token == "" || len(expected) == 0 || subtle.ConstantTimeCompare([]byte(token), expected) != 1 {
+		return cerr.ErrorAuthorizationFailed.Error()
+	}
+	return nil
 }
 
 // ListArtifacts handles the request to list artifacts produced in a workflowRun.
 func ListArtifacts(ctx context.Context, project, workflow, workflowrun, tenant string) (*types.ListResponse, error) {
-	var artifacts []api.StageArtifact
-
 	wf, err := handler.K8sClient.CycloneV1alpha1().Workflows(common.TenantNamespace(tenant)).Get(context.TODO(), workflow, metav1.GetOptions{})
 	if err != nil {
 		return nil, err
 	}
 
+	var stages []string
 	for _, stage := range wf.Spec.Stages {
-		artifactFolder, _ := getArtifactFolder(tenant, project, workflow, workflowrun, stage.Name)
-		artifactFolderInfo, err := os.Stat(artifactFolder)
-		if os.IsNotExist(err) {
-			continue
-		}
-		if !artifactFolderInfo.IsDir() {
-			continue
+		stages = append(stages, stage.Name)
+	}
+
+	artifacts, err := listStageArtifacts(tenant, project, workflow, workflowrun, stages)
+	if err != nil {
+		return nil, err
+	}
+
+	return types.NewListResponse(len(artifacts), artifacts), nil
+}
+
+// listStageArtifacts lists artifacts produced by the stages in a workflowRun.
+func listStageArtifacts(tenant, project, workflow, workflowrun string, stages []string) ([]api.StageArtifact, error) {
+	store, err := getArtifactStore(tenant)
+	if err != nil {
+		return nil, err
+	}
+
+	var artifacts []api.StageArtifact
+	for _, stage := range stages {
+		prefix, err := getArtifactPrefix(tenant, project, workflow, workflowrun, stage)
+		if err != nil {
+			return nil, err
 		}
 
-		files, err := ioutil.ReadDir(artifactFolder)
+		objects, err := store.List(prefix)
 		if err != nil {
-			log.Infof("Readdir %s error:%v", artifactFolder, err)
+			log.Infof("List artifacts %s error:%v", prefix, err)
 			return nil, err
 		}
 
-		for _, file := range files {
-			// Only support display files, since we can not collect folders, all files will be compressed to a tar file.
-			if file.IsDir() {
-				continue
+		for _, object := range objects {
+			// Checksums are not loaded in listing.
+			if stat, err := store.Stat(object.Key); err == nil {
+				object = *stat
 			}
-			artifact := api.StageArtifact{
-				Stage:             stage.Name,
-				File:              file.Name(),
-				CreationTimestamp: file.ModTime(),
+			url, err := store.PresignedURL(object.Key, presignedURLExpiration)
+			if err != nil {
+				log.Warningf("Presign URL for artifact %s error: %v", object.Key, err)
 			}
 
-			log.Info(artifact)
-			artifacts = append(artifacts, artifact)
+			artifacts = append(artifacts, api.StageArtifact{
+				Stage:             stage,
+				File:              strings.TrimPrefix(object.Key, prefix),
+				CreationTimestamp: object.LastModified,
+				Size:              object.Size,
+				Checksum:          object.Checksum,
+				URL:               url,
+			})
 		}
 	}
 
-	return types.NewListResponse(len(artifacts), artifacts), nil
+	return artifacts, nil
 }
 
 // DownloadArtifact handles the request to download a artifact of a stage produced by a workflowRun.
-func DownloadArtifact(ctx context.Context, project, workflow, workflowrun, artifact, tenant, stage string) (io.ReadCloser, map[string]string, error) {
+func DownloadArtifact(ctx context.Context, project, workflow, workflowrun, artifactName, tenant, stage string) (io.ReadCloser, map[string]string, error) {
 	headers := make(map[string]string)
-	headers["Content-Disposition"] = fmt.Sprintf("attachment; filename=%s", artifact)
+	headers["Content-Disposition"] = fmt.Sprintf("attachment; filename=%s", artifactName)
 
-	artifactFolder, err := getArtifactFolder(tenant, project, workflow, workflowrun, stage)
+	key, err := getArtifactKey(tenant, project, workflow, workflowrun, stage, artifactName)
+	if err != nil {
+		return nil, nil, err
+	}
+	store, err := getArtifactStore(tenant)
 	if err != nil {
 		return nil, nil, err
 	}
 
-	artifactFilePath := artifactFolder + "/" + artifact
-
-	artifactFile, err := os.Open(artifactFilePath)
+	artifactFile, object, err := store.Get(key)
+	if err == artifact.ErrNotFound {
+		return nil, nil, cerr.ErrorContentNotFound.Error(key)
+	}
 	if err != nil {
 		return nil, nil, err
 	}
+	if object.Checksum != "" {
+		headers["Digest"] = "SHA-256=" + object.Checksum
+	}
 
 	go func() {
 		for range ctx.Done() {
@@ -697,12 +1242,15 @@ func DownloadArtifact(ctx context.Context, project, workflow, workflowrun, artif
 }
 
 // DeleteArtifact handles the request to delete a artifact of a stage produced by a workflowRun.
-func DeleteArtifact(ctx context.Context, project, workflow, workflowrun, artifact, tenant, stage string) error {
-	artifactFolder, err := getArtifactFolder(tenant, project, workflow, workflowrun, stage)
+func DeleteArtifact(ctx context.Context, project, workflow, workflowrun, artifactName, tenant, stage string) error {
+	key, err := getArtifactKey(tenant, project, workflow, workflowrun, stage, artifactName)
+	if err != nil {
+		return err
+	}
+	store, err := getArtifactStore(tenant)
 	if err != nil {
 		return err
 	}
 
-	artifactFilePath := artifactFolder + "/" + artifact
-	return os.RemoveAll(artifactFilePath)
+	return store.Delete(key)
 }
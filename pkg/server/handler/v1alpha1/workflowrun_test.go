@@ -0,0 +1,272 @@
+package v1alpha1
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"context"
+	"io/ioutil"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/caicloud/cyclone/pkg/apis/cyclone/v1alpha1"
+	"github.com/caicloud/cyclone/pkg/meta"
+	api "github.com/caicloud/cyclone/pkg/server/apis/v1alpha1"
+	"github.com/caicloud/cyclone/pkg/server/biz/logstore"
+	"github.com/caicloud/cyclone/pkg/server/handler"
+	"github.com/caicloud/cyclone/pkg/server/types"
+	"github.com/caicloud/cyclone/pkg/util/cerr"
+	"github.com/caicloud/cyclone/pkg/util/k8s/fake"
+	wfcommon "github.com/caicloud/cyclone/pkg/workflow/common"
+)
+
+func stageStatus(phase v1alpha1.StatusPhase, depends ...string) *v1alpha1.StageStatus {
+	return &v1alpha1.StageStatus{
+		Status:  v1alpha1.Status{Phase: phase},
+		Depends: depends,
+	}
+}
+
+func TestWorkflowRunRerunStages(t *testing.T) {
+	cases := []struct {
+		name     string
+		stages   map[string]*v1alpha1.StageStatus
+		expected map[string]bool
+	}{
+		{
+			name: "all succeeded",
+			stages: map[string]*v1alpha1.StageStatus{
+				"a": stageStatus(v1alpha1.StatusSucceeded),
+				"b": stageStatus(v1alpha1.StatusSkipped, "a"),
+			},
+			expected: map[string]bool{},
+		},
+		{
+			name: "failed stage and its dependents",
+			stages: map[string]*v1alpha1.StageStatus{
+				"a": stageStatus(v1alpha1.StatusSucceeded),
+				"b": stageStatus(v1alpha1.StatusFailed, "a"),
+				"c": stageStatus(v1alpha1.StatusCancelled, "b"),
+				"d": stageStatus(v1alpha1.StatusSucceeded, "a"),
+			},
+			expected: map[string]bool{"b": true, "c": true},
+		},
+		{
+			name: "indirect dependents",
+			stages: map[string]*v1alpha1.StageStatus{
+				"a": stageStatus(v1alpha1.StatusFailed),
+				"b": stageStatus(v1alpha1.StatusSucceeded, "a"),
+				"c": stageStatus(v1alpha1.StatusSucceeded, "b"),
+				"d": stageStatus(v1alpha1.StatusSucceeded),
+			},
+			expected: map[string]bool{"a": true, "b": true, "c": true},
+		},
+		{
+			name: "matrix instances are counted in the matrix stage",
+			stages: map[string]*v1alpha1.StageStatus{
+				"m":   stageStatus(v1alpha1.StatusFailed),
+				"m-0": {Status: v1alpha1.Status{Phase: v1alpha1.StatusSucceeded}, Parent: "m"},
+				"m-1": {Status: v1alpha1.Status{Phase: v1alpha1.StatusFailed}, Parent: "m"},
+				"n":   stageStatus(v1alpha1.StatusPending, "m"),
+			},
+			expected: map[string]bool{"m": true, "n": true},
+		},
+	}
+
+	for _, c := range cases {
+		wfr := &v1alpha1.WorkflowRun{Status: v1alpha1.WorkflowRunStatus{Stages: c.stages}}
+		assert.Equal(t, c.expected, workflowRunRerunStages(wfr), c.name)
+	}
+}
+
+func TestRerunWorkflowRun(t *testing.T) {
+	origin := &v1alpha1.WorkflowRun{
+		ObjectMeta: metav1.ObjectMeta{
+			Name: "wfr",
+			Labels: map[string]string{
+				meta.LabelProjectName:                 "p",
+				meta.LabelWorkflowRunNotificationSent: "true",
+			},
+			Annotations: map[string]string{
+				meta.AnnotationAlias: "alias",
+			},
+		},
+		Spec: v1alpha1.WorkflowRunSpec{
+			WorkflowRef: &corev1.ObjectReference{Name: "wf"},
+			StartStages: []string{"a"},
+		},
+		Status: v1alpha1.WorkflowRunStatus{
+			Stages: map[string]*v1alpha1.StageStatus{
+				"a": {
+					Status:   v1alpha1.Status{Phase: v1alpha1.StatusSucceeded, Reason: "PodSucceed"},
+					Pod:      &v1alpha1.PodInfo{Name: "a-pod"},
+					Attempts: []v1alpha1.StageAttempt{{}},
+					Outputs:  []v1alpha1.KeyValue{{Key: "k", Value: "v"}},
+				},
+				"b": {
+					Status:  v1alpha1.Status{Phase: v1alpha1.StatusFailed},
+					Pod:     &v1alpha1.PodInfo{Name: "b-pod"},
+					Depends: []string{"a"},
+				},
+				"c": {
+					Status:  v1alpha1.Status{Phase: v1alpha1.StatusCancelled},
+					Depends: []string{"b"},
+				},
+				"m": {
+					Status: v1alpha1.Status{Phase: v1alpha1.StatusFailed},
+				},
+				"m-0": {
+					Status: v1alpha1.Status{Phase: v1alpha1.StatusFailed},
+					Parent: "m",
+				},
+			},
+		},
+	}
+
+	wfr := rerunWorkflowRun(origin, workflowRunRerunStages(origin))
+
+	assert.True(t, strings.HasPrefix(wfr.Name, "wfr-rerun-"))
+	assert.Equal(t, map[string]string{meta.LabelProjectName: "p", meta.LabelWorkflowRunWorkspace: "wfr"}, wfr.Labels)
+	assert.Equal(t, map[string]string{meta.AnnotationWorkflowRunRerunFrom: "wfr"}, wfr.Annotations)
+
+	// Stages to re-run are started from the ones without re-run dependencies.
+	assert.Equal(t, []string{"b", "m"}, wfr.Spec.StartStages)
+
+	// Succeeded stages are inherited without their pods and attempts.
+	assert.Equal(t, 1, len(wfr.Status.Stages))
+	inherited := wfr.Status.Stages["a"]
+	assert.Equal(t, v1alpha1.StatusSucceeded, inherited.Status.Phase)
+	assert.Equal(t, v1alpha1.ReasonRerun, inherited.Status.Reason)
+	assert.Nil(t, inherited.Pod)
+	assert.Nil(t, inherited.Attempts)
+	assert.Equal(t, []v1alpha1.KeyValue{{Key: "k", Value: "v"}}, inherited.Outputs)
+
+	// Origin is not changed.
+	assert.Equal(t, "a-pod", origin.Status.Stages["a"].Pod.Name)
+	assert.Equal(t, []string{"a"}, origin.Spec.StartStages)
+}
+
+func TestVerifyArtifactToken(t *testing.T) {
+	handler.Init(fake.NewSimpleClientset(&corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Name: wfcommon.ArtifactTokenSecretName("wfr"), Namespace: "cyclone-devops"},
+		Data:       map[string][]byte{wfcommon.ArtifactTokenSecretKey: []byte("token")},
+	}))
+
+	assert.Nil(t, verifyArtifactToken("cyclone-devops", "wfr", "token"))
+	assert.True(t, cerr.ErrorAuthorizationFailed.Derived(verifyArtifactToken("cyclone-devops", "wfr", "")))
+	assert.True(t, cerr.ErrorAuthorizationFailed.Derived(verifyArtifactToken("cyclone-devops", "wfr", "other")))
+	// Tokens of one workflowrun can't be used to download artifacts of others.
+	assert.True(t, cerr.ErrorAuthorizationFailed.Derived(verifyArtifactToken("cyclone-devops", "another-wfr", "token")))
+}
+
+// useTestLogStore replaces the log store with one in a temporary folder, logs are written to containers of the
+// workflowrun 'devops/p/wf/wfr'.
+func useTestLogStore(t *testing.T, logs map[string]string) func() {
+	home, err := ioutil.TempDir("", "logs")
+	if err != nil {
+		t.Fatal(err)
+	}
+	origin := logStore
+	logStore = logstore.NewFileStore(home)
+
+	run := logstore.Run{Tenant: testTenant, Project: "p", Workflow: "wf", WorkflowRun: "wfr"}
+	for key, content := range logs {
+		parts := strings.Split(key, "/")
+		w, err := logStore.Writer(run, parts[0], parts[1])
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := w.Write([]byte(content)); err != nil {
+			t.Fatal(err)
+		}
+		w.Close()
+	}
+
+	return func() {
+		logStore = origin
+		os.RemoveAll(home)
+	}
+}
+
+func TestSearchLogs(t *testing.T) {
+	defer useTestLogStore(t, map[string]string{
+		"build/main":   "compile\nerror: a\n",
+		"build/csc-co": "error: coordinator\n",
+		"test/main":    "error: b\n",
+	})()
+	query := &types.QueryParams{Limit: 10}
+	search := func(stages, container, start, pattern string) ([]api.LogLine, error) {
+		resp, err := SearchLogs(context.TODO(), "p", "wf", "wfr", testTenant, stages, container, start, "", pattern, query)
+		if err != nil {
+			return nil, err
+		}
+		return resp.Items.([]api.LogLine), nil
+	}
+	contents := func(lines []api.LogLine) []string {
+		var result []string
+		for _, l := range lines {
+			result = append(result, l.Stage+"/"+l.Container+": "+l.Content)
+		}
+		return result
+	}
+
+	// Logs of coordinator are excluded unless specified.
+	lines, err := search("", "", "", "error")
+	assert.Nil(t, err)
+	assert.ElementsMatch(t, []string{"build/main: error: a", "test/main: error: b"}, contents(lines))
+
+	lines, err = search("build", "", "", "")
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"build/main: compile", "build/main: error: a"}, contents(lines))
+
+	lines, err = search("", "csc-co", "", "")
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"build/csc-co: error: coordinator"}, contents(lines))
+
+	_, err = search("", "", "", "(")
+	assert.True(t, cerr.ErrorValidationFailed.Derived(err))
+	_, err = search("", "", "yesterday", "")
+	assert.True(t, cerr.ErrorValidationFailed.Derived(err))
+
+	// There are no logs of the workflowrun.
+	resp, err := SearchLogs(context.TODO(), "p", "wf", "other", testTenant, "", "", "", "", "", query)
+	assert.Nil(t, err)
+	assert.Equal(t, 0, resp.Metadata.Total)
+}
+
+func TestExportLogs(t *testing.T) {
+	defer useTestLogStore(t, map[string]string{
+		"build/main": "compile\n",
+		"test/main":  "ok\n",
+	})()
+
+	_, _, err := ExportLogs(context.TODO(), "p", "wf", "other", testTenant)
+	assert.True(t, cerr.ErrorContentNotFound.Derived(err))
+
+	reader, headers, err := ExportLogs(context.TODO(), "p", "wf", "wfr", testTenant)
+	assert.Nil(t, err)
+	defer reader.Close()
+	assert.Equal(t, "attachment; filename=wfr-logs.tar.gz", headers["Content-Disposition"])
+
+	gr, err := gzip.NewReader(reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tr := tar.NewReader(gr)
+	files := make(map[string]string)
+	for {
+		header, err := tr.Next()
+		if err != nil {
+			break
+		}
+		data, _ := ioutil.ReadAll(tr)
+		files[header.Name] = string(data)
+	}
+	assert.Equal(t, 2, len(files))
+	assert.True(t, strings.HasSuffix(files["wfr/build/main.log"], " compile\n"))
+	assert.True(t, strings.HasSuffix(files["wfr/test/main.log"], " ok\n"))
+}
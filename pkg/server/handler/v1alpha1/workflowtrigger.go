@@ -2,7 +2,9 @@ package v1alpha1
 
 import (
 	"context"
+	"fmt"
 	"sort"
+	"time"
 
 	"github.com/caicloud/nirvana/log"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
@@ -10,15 +12,21 @@ import (
 
 	"github.com/caicloud/cyclone/pkg/apis/cyclone/v1alpha1"
 	"github.com/caicloud/cyclone/pkg/meta"
+	api "github.com/caicloud/cyclone/pkg/server/apis/v1alpha1"
 	"github.com/caicloud/cyclone/pkg/server/biz/hook"
+	"github.com/caicloud/cyclone/pkg/server/biz/scm"
 	"github.com/caicloud/cyclone/pkg/server/biz/utils"
 	"github.com/caicloud/cyclone/pkg/server/common"
 	"github.com/caicloud/cyclone/pkg/server/handler"
 	"github.com/caicloud/cyclone/pkg/server/handler/v1alpha1/sorter"
 	"github.com/caicloud/cyclone/pkg/server/types"
 	"github.com/caicloud/cyclone/pkg/util/cerr"
+	"github.com/caicloud/cyclone/pkg/workflow/workflowtrigger"
 )
 
+// maxCronScheduleCount is the max number of next fire times returned for a cron trigger.
+const maxCronScheduleCount = 100
+
 // CreateWorkflowTrigger ...
 func CreateWorkflowTrigger(ctx context.Context, tenant, project, workflow string, wft *v1alpha1.WorkflowTrigger) (*v1alpha1.WorkflowTrigger, error) {
 	modifiers := []CreationModifier{
@@ -38,6 +46,22 @@ func CreateWorkflowTrigger(ctx context.Context, tenant, project, workflow string
 		wft.Spec.WorkflowRef = workflowReference(tenant, workflow)
 	}
 
+	if err := validateSCMFilters(wft); err != nil {
+		return nil, err
+	}
+
+	if err := validateCronTrigger(wft); err != nil {
+		return nil, err
+	}
+
+	if err := validateWorkflowRunTrigger(wft); err != nil {
+		return nil, err
+	}
+
+	if err := validateImageTrigger(tenant, wft); err != nil {
+		return nil, err
+	}
+
 	if wft.Spec.Type == v1alpha1.TriggerTypeWebhook || wft.Spec.Type == v1alpha1.TriggerTypeSCM {
 		hookManager, err := hook.GetManager(wft.Spec.Type)
 		if err != nil {
@@ -50,10 +74,146 @@ func CreateWorkflowTrigger(ctx context.Context, tenant, project, workflow string
 		}
 	}
 
+	if err := setWebhookURL(tenant, wft); err != nil {
+		return nil, err
+	}
+
 	hook.LabelSCMTrigger(wft)
 	return handler.K8sClient.CycloneV1alpha1().WorkflowTriggers(common.TenantNamespace(tenant)).Create(context.TODO(), wft, metav1.CreateOptions{})
 }
 
+// validateSCMFilters checks patterns of branch, tag and path filters in SCM trigger policies, the feedback config
+// and the poll interval of SCM triggers.
+func validateSCMFilters(wft *v1alpha1.WorkflowTrigger) error {
+	if wft.Spec.Type != v1alpha1.TriggerTypeSCM {
+		return nil
+	}
+
+	policy := wft.Spec.SCM.SCMTriggerPolicy
+	for _, filter := range []*v1alpha1.RefFilter{policy.Push.Filter, policy.TagRelease.Filter, policy.PullRequest.Filter} {
+		if err := scm.ValidateRefFilter(filter); err != nil {
+			return cerr.ErrorValidationFailed.Error("ref filter", err)
+		}
+	}
+	for _, filter := range []*v1alpha1.PathFilter{&policy.Push.PathFilter, &policy.PullRequest.PathFilter} {
+		if err := scm.ValidatePathFilter(filter); err != nil {
+			return cerr.ErrorValidationFailed.Error("path filter", err)
+		}
+	}
+
+	feedback := wft.Spec.SCM.Feedback
+	if feedback != nil && feedback.Deployment != nil && feedback.Deployment.Environment == "" {
+		return cerr.ErrorValidationFailed.Error("SCM feedback", "environment of deployment is required")
+	}
+
+	if wft.Spec.SCM.PollInterval != "" {
+		interval, err := time.ParseDuration(wft.Spec.SCM.PollInterval)
+		if err != nil {
+			return cerr.ErrorValidationFailed.Error("SCM poll interval", err)
+		}
+		if interval < minPollInterval {
+			return cerr.ErrorValidationFailed.Error("SCM poll interval", fmt.Sprintf("must be at least %v", minPollInterval))
+		}
+	}
+	return nil
+}
+
+// validateCronTrigger checks schedule, timezone, starting deadline and concurrency policy of Cron triggers.
+func validateCronTrigger(wft *v1alpha1.WorkflowTrigger) error {
+	if wft.Spec.Type != v1alpha1.TriggerTypeCron {
+		return nil
+	}
+
+	if _, err := workflowtrigger.ParseSchedule(&wft.Spec.Cron); err != nil {
+		return cerr.ErrorValidationFailed.Error("cron trigger", err)
+	}
+	return nil
+}
+
+// validateWorkflowRunTrigger checks upstream workflow and phases of WorkflowRun completion triggers.
+func validateWorkflowRunTrigger(wft *v1alpha1.WorkflowTrigger) error {
+	if wft.Spec.Type != v1alpha1.TriggerTypeWorkflowRun {
+		return nil
+	}
+
+	trigger := wft.Spec.WorkflowRun
+	if trigger.Workflow == "" {
+		return cerr.ErrorValidationFailed.Error("workflowrun trigger", "upstream workflow is required")
+	}
+	if wft.Spec.WorkflowRef != nil && wft.Spec.WorkflowRef.Name == trigger.Workflow {
+		return cerr.ErrorValidationFailed.Error("workflowrun trigger", "workflow can't be triggered by itself")
+	}
+	for _, phase := range trigger.Phases {
+		switch phase {
+		case v1alpha1.StatusSucceeded, v1alpha1.StatusFailed, v1alpha1.StatusCancelled:
+		default:
+			return cerr.ErrorValidationFailed.Error("workflowrun trigger", fmt.Sprintf("invalid phase %s, must be one of Succeeded, Failed and Cancelled", phase))
+		}
+	}
+	return nil
+}
+
+// validateImageTrigger checks integration, repository, tag filter and poll interval of Image triggers, the
+// integration must be an existing DockerRegistry integration of the tenant.
+func validateImageTrigger(tenant string, wft *v1alpha1.WorkflowTrigger) error {
+	if wft.Spec.Type != v1alpha1.TriggerTypeImage {
+		return nil
+	}
+
+	trigger := wft.Spec.Image
+	if trigger.Integration == "" {
+		return cerr.ErrorValidationFailed.Error("image trigger", "integration is required")
+	}
+	in, err := getIntegration(common.TenantNamespace(tenant), trigger.Integration)
+	if err != nil {
+		return err
+	}
+	if in.Spec.Type != api.DockerRegistry || in.Spec.DockerRegistry == nil {
+		return cerr.ErrorValidationFailed.Error("image trigger", fmt.Sprintf("integration %s is not a docker registry", trigger.Integration))
+	}
+	if trigger.Repository == "" {
+		return cerr.ErrorValidationFailed.Error("image trigger", "repository is required")
+	}
+	if err := scm.ValidateRefFilter(trigger.Tags); err != nil {
+		return cerr.ErrorValidationFailed.Error("image trigger", err)
+	}
+	if trigger.PollInterval == "" {
+		if trigger.Secret == "" {
+			return cerr.ErrorValidationFailed.Error("image trigger", "either secret or poll interval is required")
+		}
+		return nil
+	}
+	interval, err := time.ParseDuration(trigger.PollInterval)
+	if err != nil {
+		return cerr.ErrorValidationFailed.Error("image trigger", err)
+	}
+	if interval < minPollInterval {
+		return cerr.ErrorValidationFailed.Error("image trigger", fmt.Sprintf("poll interval must be at least %v", minPollInterval))
+	}
+	return nil
+}
+
+// setWebhookURL sets URL of Webhook and Image type WorkflowTriggers in status, external systems send payloads to
+// the URL.
+func setWebhookURL(tenant string, wft *v1alpha1.WorkflowTrigger) error {
+	var url string
+	var err error
+	switch wft.Spec.Type {
+	case v1alpha1.TriggerTypeWebhook:
+		url, err = hook.WebhookTriggerURL(tenant, wft.Name)
+	case v1alpha1.TriggerTypeImage:
+		url, err = hook.ImageTriggerURL(tenant, wft.Spec.Image.Integration)
+	default:
+		wft.Status.WebhookURL = ""
+		return nil
+	}
+	if err != nil {
+		return cerr.ErrorUnknownInternal.Error(err)
+	}
+	wft.Status.WebhookURL = url
+	return nil
+}
+
 // ListWorkflowTriggers ...
 func ListWorkflowTriggers(ctx context.Context, tenant, project, workflow string, query *types.QueryParams) (*types.ListResponse, error) {
 	workflowTriggers, err := handler.K8sClient.CycloneV1alpha1().WorkflowTriggers(common.TenantNamespace(tenant)).List(context.TODO(), metav1.ListOptions{
@@ -89,6 +249,35 @@ func GetWorkflowTrigger(ctx context.Context, tenant, project, workflow, workflow
 	return wft, cerr.ConvertK8sError(err)
 }
 
+// GetWorkflowTriggerSchedule gets the last schedule time and next count fire times of a Cron type workflowtrigger.
+func GetWorkflowTriggerSchedule(ctx context.Context, tenant, project, workflow, wftName string, count int) (*api.CronSchedule, error) {
+	wft, err := handler.K8sClient.CycloneV1alpha1().WorkflowTriggers(common.TenantNamespace(tenant)).Get(context.TODO(), wftName, metav1.GetOptions{})
+	if err != nil {
+		return nil, cerr.ConvertK8sError(err)
+	}
+
+	if wft.Spec.Type != v1alpha1.TriggerTypeCron {
+		return nil, cerr.ErrorValidationFailed.Error("workflowtrigger type", "only Cron type workflowtriggers have schedules")
+	}
+	if count <= 0 || count > maxCronScheduleCount {
+		return nil, cerr.ErrorValidationFailed.Error("count", fmt.Sprintf("must be in range [1, %d]", maxCronScheduleCount))
+	}
+
+	schedule, err := workflowtrigger.ParseSchedule(&wft.Spec.Cron)
+	if err != nil {
+		return nil, cerr.ErrorValidationFailed.Error("cron trigger", err)
+	}
+
+	result := &api.CronSchedule{
+		Timezone:      schedule.Location.String(),
+		NextFireTimes: schedule.NextN(time.Now(), count),
+	}
+	if wft.Status.Cron != nil {
+		result.LastScheduleTime = wft.Status.Cron.LastScheduleTime
+	}
+	return result, nil
+}
+
 // UpdateWorkflowTrigger ...
 func UpdateWorkflowTrigger(ctx context.Context, tenant, project, workflow, workflowtrigger string, wft *v1alpha1.WorkflowTrigger) (*v1alpha1.WorkflowTrigger, error) {
 	modifiers := []CreationModifier{WorkflowTrggerSVNModifier}
@@ -99,6 +288,22 @@ func UpdateWorkflowTrigger(ctx context.Context, tenant, project, workflow, workf
 		}
 	}
 
+	if err := validateSCMFilters(wft); err != nil {
+		return nil, err
+	}
+
+	if err := validateCronTrigger(wft); err != nil {
+		return nil, err
+	}
+
+	if err := validateWorkflowRunTrigger(wft); err != nil {
+		return nil, err
+	}
+
+	if err := validateImageTrigger(tenant, wft); err != nil {
+		return nil, err
+	}
+
 	err := retry.RetryOnConflict(retry.DefaultRetry, func() error {
 		origin, err := handler.K8sClient.CycloneV1alpha1().WorkflowTriggers(common.TenantNamespace(tenant)).Get(context.TODO(), workflowtrigger, metav1.GetOptions{})
 		if err != nil {
@@ -111,6 +316,16 @@ func UpdateWorkflowTrigger(ctx context.Context, tenant, project, workflow, workf
 		if newWft.Spec.WorkflowRef == nil {
 			newWft.Spec.WorkflowRef = workflowReference(tenant, workflow)
 		}
+		// Seen tags belong to the previous repository, tags of the new one are recorded by the next poll.
+		if newWft.Spec.Type != v1alpha1.TriggerTypeImage || newWft.Spec.Image.Integration != origin.Spec.Image.Integration ||
+			newWft.Spec.Image.Repository != origin.Spec.Image.Repository {
+			newWft.Status.Image = nil
+		}
+		// So are refs and pull requests of SCM triggers polling the repo.
+		if !hook.IsSCMPolling(newWft) || newWft.Spec.SCM.Secret != origin.Spec.SCM.Secret ||
+			newWft.Spec.SCM.Repo != origin.Spec.SCM.Repo {
+			newWft.Status.SCM = nil
+		}
 
 		// Handle trigger type change and repo change when SCM type.
 		// Do not care about the change of secret.
@@ -121,9 +336,10 @@ func UpdateWorkflowTrigger(ctx context.Context, tenant, project, workflow, workf
 			// Need to unregister old SCM webhook only when:
 			// * new trigger is not SCM type
 			// * repo of new trigger is different from old
+			// * poll mode of the trigger is switched
 			if newSpec.Type != v1alpha1.TriggerTypeSCM {
 				unregisterOld = true
-			} else if oldSpec.SCM.Repo != newSpec.SCM.Repo {
+			} else if oldSpec.SCM.Repo != newSpec.SCM.Repo || hook.IsSCMPolling(origin) != hook.IsSCMPolling(newWft) {
 				unregisterOld = true
 				registerNew = true
 			}
@@ -131,26 +347,30 @@ func UpdateWorkflowTrigger(ctx context.Context, tenant, project, workflow, workf
 			registerNew = true
 		}
 
-		var hookManager hook.Manager
-		if registerNew || unregisterOld {
-			hookManager, err = hook.GetManager(wft.Spec.Type)
+		if unregisterOld {
+			hookManager, err := hook.GetManager(oldSpec.Type)
 			if err != nil {
 				return err
 			}
-		}
-
-		if unregisterOld {
 			if err = hookManager.Unregister(tenant, *origin); err != nil {
 				return err
 			}
 		}
 
 		if registerNew {
+			hookManager, err := hook.GetManager(newSpec.Type)
+			if err != nil {
+				return err
+			}
 			if err = hookManager.Register(tenant, *newWft); err != nil {
 				return err
 			}
 		}
 
+		if err = setWebhookURL(tenant, newWft); err != nil {
+			return err
+		}
+
 		hook.LabelSCMTrigger(newWft)
 		_, err = handler.K8sClient.CycloneV1alpha1().WorkflowTriggers(common.TenantNamespace(tenant)).Update(context.TODO(), newWft, metav1.UpdateOptions{})
 		return err
@@ -180,9 +400,8 @@ func DeleteWorkflowTrigger(ctx context.Context, tenant, project, workflow, workf
 		if err = hookManager.Unregister(tenant, *wft); err != nil {
 			return err
 		}
-
-		err = handler.K8sClient.CycloneV1alpha1().WorkflowTriggers(common.TenantNamespace(tenant)).Delete(context.TODO(), workflowtrigger, metav1.DeleteOptions{})
 	}
 
+	err = handler.K8sClient.CycloneV1alpha1().WorkflowTriggers(common.TenantNamespace(tenant)).Delete(context.TODO(), workflowtrigger, metav1.DeleteOptions{})
 	return cerr.ConvertK8sError(err)
 }
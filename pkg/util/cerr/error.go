@@ -64,6 +64,10 @@ var (
 	ErrorQuotaExceeded = nerror.Forbidden.Build(ReasonRequest, "${resource} quota exceeded")
 	// ErrorClusterNotClosed defines error that represents some operations are forbidden while cluster is not closed
 	ErrorClusterNotClosed = nerror.Forbidden.Build(ReasonRequest, "should close cluster integration ${integration} firstly")
+	// ErrorPayloadTooLarge defines error that the request payload exceeds the max size.
+	ErrorPayloadTooLarge = nerror.RequestEntityTooLarge.Build(ReasonRequest, "payload exceeds the max size ${size} bytes")
+	// ErrorApprovalNotAllowed defines error that the user is not an approver of the stage.
+	ErrorApprovalNotAllowed = nerror.Forbidden.Build(ReasonRequest, "user ${user} is not allowed to approve stage ${stage}")
 	// ErrorAlreadyExist defines conflict error.
 	ErrorAlreadyExist = nerror.Conflict.Build(ReasonRequest, "conflict: ${resource} already exist")
 
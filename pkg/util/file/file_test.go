@@ -17,6 +17,12 @@ limitations under the License.
 package file
 
 import (
+	"archive/tar"
+	"bytes"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
 	"testing"
 )
 
@@ -65,3 +71,56 @@ func TestFileExists(t *testing.T) {
 		}
 	}
 }
+
+func TestTar(t *testing.T) {
+	dir, err := ioutil.TempDir("", "tar")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	if err := os.MkdirAll(filepath.Join(dir, "bin", "lib"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	files := map[string]string{
+		"bin/app":      "app",
+		"bin/lib/a.so": "lib",
+	}
+	for name, content := range files {
+		if err := ioutil.WriteFile(filepath.Join(dir, name), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	buf := &bytes.Buffer{}
+	if err := Tar(buf, dir); err != nil {
+		t.Fatal(err)
+	}
+
+	entries := make(map[string]string)
+	tr := tar.NewReader(buf)
+	for {
+		header, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		content, err := ioutil.ReadAll(tr)
+		if err != nil {
+			t.Fatal(err)
+		}
+		entries[header.Name] = string(content)
+	}
+
+	expected := map[string]string{"bin": "", "bin/lib": "", "bin/app": "app", "bin/lib/a.so": "lib"}
+	if len(entries) != len(expected) {
+		t.Errorf("Expected entries %v, but got %v", expected, entries)
+	}
+	for name, content := range expected {
+		if c, ok := entries[name]; !ok || c != content {
+			t.Errorf("Expected entry %s with content %q, but got %q", name, content, c)
+		}
+	}
+}
@@ -56,12 +56,18 @@ const (
 	// WorkflowTriggerNamePathParameterName represents the name of the path parameter for workflowtrigger name.
 	WorkflowTriggerNamePathParameterName = "workflowtrigger"
 
+	// WebhookDeliveryNamePathParameterName represents the name of the path parameter for webhook delivery name.
+	WebhookDeliveryNamePathParameterName = "webhookdelivery"
+
 	// NamespaceQueryParameter represents namespace query parameter.
 	NamespaceQueryParameter = "namespace"
 
 	// StageNameQueryParameter represents the query param stage name.
 	StageNameQueryParameter = "stage"
 
+	// CommentQueryParameter represents the query param comment.
+	CommentQueryParameter = "comment"
+
 	// LabelQueryParameter represents the label query.
 	LabelQueryParameter = "label"
 
@@ -108,6 +114,15 @@ const (
 	// EndTimeQueryParameter represents the query param end time.
 	EndTimeQueryParameter string = "endTime"
 
+	// StagesQueryParameter represents the query param of comma separated stage names.
+	StagesQueryParameter string = "stages"
+
+	// PatternQueryParameter represents the query param of a regular expression pattern.
+	PatternQueryParameter string = "pattern"
+
+	// CountQueryParameter represents the query param count.
+	CountQueryParameter string = "count"
+
 	// OperationQueryParameter ...
 	OperationQueryParameter string = "operation"
 
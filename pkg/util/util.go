@@ -12,7 +12,8 @@ func IsWorkflowRunTerminated(wfr *v1alpha1.WorkflowRun) bool {
 func IsPhaseTerminated(phase v1alpha1.StatusPhase) bool {
 	if phase == v1alpha1.StatusSucceeded ||
 		phase == v1alpha1.StatusFailed ||
-		phase == v1alpha1.StatusCancelled {
+		phase == v1alpha1.StatusCancelled ||
+		phase == v1alpha1.StatusSkipped {
 		return true
 	}
 
@@ -26,7 +26,11 @@ const (
 	EnvWorkflowrunName = "WORKFLOWRUN_NAME"
 	// EnvStageName is an environment which represents stage name.
 	EnvStageName = "STAGE_NAME"
-	// EnvWorkloadContainerName is an environment which represents the workload container name.
+	// EnvWorkloadContainerNames is an environment which represents names of workload containers except service
+	// containers, separated by comma.
+	EnvWorkloadContainerNames = "WORKLOAD_CONTAINER_NAMES"
+	// EnvWorkloadContainerName is an environment which represents the workload container name, it's replaced by
+	// EnvWorkloadContainerNames, and only read from pods created by workflow controller of old versions.
 	EnvWorkloadContainerName = "WORKLOAD_CONTAINER_NAME"
 	// EnvNamespace is an environment which represents namespace of workflow execution context.
 	EnvNamespace = "NAMESPACE"
@@ -34,6 +38,16 @@ const (
 	EnvCycloneServerAddr = "CYCLONE_SERVER_ADDR"
 	// EnvLogCollectorURL is URL to send logs
 	EnvLogCollectorURL = "LOG_COLLECTOR_URL"
+	// EnvUploadArtifacts is an environment which indicates coordinator to upload output artifacts to cyclone
+	// server, it's set when there is no PVC to share artifacts between stages.
+	EnvUploadArtifacts = "UPLOAD_ARTIFACTS"
+	// EnvArtifactURL is an environment which represents URL to download an input artifact.
+	EnvArtifactURL = "ARTIFACT_URL"
+	// EnvArtifactDir is an environment which represents directory to unpack an input artifact.
+	EnvArtifactDir = "ARTIFACT_DIR"
+	// EnvArtifactToken is an environment which represents token to download an input artifact, or to upload output
+	// artifacts by coordinator.
+	EnvArtifactToken = "ARTIFACT_TOKEN"
 
 	// DefaultCycloneServerAddr defines default Cyclone Server address
 	DefaultCycloneServerAddr = "cyclone-server"
@@ -47,6 +61,9 @@ const (
 	// WorkloadSidecarPrefix defines workload sidecar container name prefix.
 	WorkloadSidecarPrefix = "wsc-"
 
+	// ServiceContainerPrefix defines name prefix of containers created for services of the stage.
+	ServiceContainerPrefix = "svc-"
+
 	// CoordinatorSidecarName defines name of coordinator container.
 	CoordinatorSidecarName = CycloneSidecarPrefix + "co"
 
@@ -70,6 +87,8 @@ const (
 	ResourcePullCommand = "pull"
 	// ResourcePushCommand indicates push resource
 	ResourcePushCommand = "push"
+	// HTTPResourceURLKey is the parameter key of the URL of HTTP resources.
+	HTTPResourceURLKey = "URL"
 
 	// CoordinatorResolverPath ...
 	CoordinatorResolverPath = "/workspace/resolvers"
@@ -81,6 +100,17 @@ const (
 	CoordinatorArtifactsPath = "/workspace/artifacts"
 	// CoordinatorResultsPath is the directory that contains __result__ files written by other containers
 	CoordinatorResultsPath = "/workspace/results"
+	// CoordinatorServicesReadyPath is the file created by coordinator when all services of the stage are ready,
+	// workload containers are held until it's created.
+	CoordinatorServicesReadyPath = "/workspace/services-ready"
+
+	// UpstreamArtifactSourcePrefix is prefix of sources of artifacts from the upstream WorkflowRun.
+	UpstreamArtifactSourcePrefix = "upstream"
+	// InputArtifactsPath is path of the input artifacts emptyDir volume in artifact downloader containers, each
+	// artifact is unpacked to a sub directory named by the artifact.
+	InputArtifactsPath = "/workspace/input-artifacts"
+	// ArtifactTokenSecretKey is the key of the token in the artifact token secret of a WorkflowRun.
+	ArtifactTokenSecretKey = "token"
 
 	// ToolboxPath is path of cyclone tools in containers
 	ToolboxPath = "/usr/bin/cyclone-toolbox"
@@ -93,6 +123,9 @@ const (
 	DefaultPvVolumeName = "default-pv"
 	// ToolsVolume is name of the volume to inject cyclone tools to containers.
 	ToolsVolume = "toolbox-volume"
+	// InputArtifactsVolume is name of the emptyDir volume holding input artifacts downloaded from cyclone server,
+	// it's used when there is no PVC.
+	InputArtifactsVolume = "input-artifacts-volume"
 	// CoordinatorSidecarVolumeName is name of the emptyDir volume shared between coordinator and
 	// sidecar containers, e.g. image resolvers. Coordinator would notify resolvers that workload
 	// containers have finished their work, so that resource resolvers can push resources.
@@ -108,6 +141,8 @@ const (
 	ContainerStateTerminated ContainerState = "Terminated"
 	// ContainerStateInitialized represents container is Running or Stopped, not Init or Creating.
 	ContainerStateInitialized ContainerState = "Initialized"
+	// ContainerStateReady represents container is running and has passed its readiness probe.
+	ContainerStateReady ContainerState = "Ready"
 
 	// ResultFileDir contains the file `__result__` to hold execution result of a container that need to be synced to
 	// WorkflowRun status. Each line of the result should be in format: <key>:<value>
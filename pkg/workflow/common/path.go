@@ -1,6 +1,11 @@
 package common
 
-import "fmt"
+import (
+	"fmt"
+
+	"github.com/caicloud/cyclone/pkg/apis/cyclone/v1alpha1"
+	"github.com/caicloud/cyclone/pkg/meta"
+)
 
 const (
 	// StageMountPath is path that we will mount PV on in container.
@@ -15,6 +20,16 @@ func WorkflowRunsPath() string {
 	return "workflowruns"
 }
 
+// WorkspaceName gets name of the workspace (data folder under WorkflowRunsPath in PV) used by the WorkflowRun.
+// A re-run WorkflowRun shares workspace with the WorkflowRun it re-runs from, otherwise WorkflowRun name is used.
+func WorkspaceName(wfr *v1alpha1.WorkflowRun) string {
+	if ws, ok := wfr.Labels[meta.LabelWorkflowRunWorkspace]; ok && len(ws) > 0 {
+		return ws
+	}
+
+	return wfr.Name
+}
+
 // StagePath gets the path of a stage in PV
 func StagePath(wfr, stage string) string {
 	return fmt.Sprintf("workflowruns/%s/stages/%s", wfr, stage)
@@ -30,6 +45,18 @@ func ArtifactPath(wfr, stage, artifact string) string {
 	return fmt.Sprintf("workflowruns/%s/stages/%s/artifacts/%s", wfr, stage, artifact)
 }
 
+// ArtifactArchiveName gets name of the tar file that packs an output artifact, it's used to upload the artifact
+// to cyclone server when there is no PVC.
+func ArtifactArchiveName(artifact string) string {
+	return artifact + ".tar"
+}
+
+// ArtifactTokenSecretName gets name of the secret holding the token to download artifacts of the WorkflowRun
+// from cyclone server.
+func ArtifactTokenSecretName(wfr string) string {
+	return fmt.Sprintf("%s-artifact-token", wfr)
+}
+
 // ResourcePath gets the path of a resource in PV
 func ResourcePath(wfr, resource string) string {
 	return fmt.Sprintf("workflowruns/%s/resources/%s", wfr, resource)
@@ -17,6 +17,10 @@ func OnlyWorkload(name string) bool {
 		return false
 	}
 
+	if strings.HasPrefix(name, ServiceContainerPrefix) {
+		return false
+	}
+
 	return true
 }
 
@@ -36,6 +40,16 @@ func NonWorkloadSidecar(name string) bool {
 	return !strings.HasPrefix(name, WorkloadSidecarPrefix)
 }
 
+// NonServiceContainer selects all containers except containers created for services of the stage.
+func NonServiceContainer(name string) bool {
+	return !strings.HasPrefix(name, ServiceContainerPrefix)
+}
+
+// OnlyServiceContainer selects only containers created for services of the stage.
+func OnlyServiceContainer(name string) bool {
+	return strings.HasPrefix(name, ServiceContainerPrefix)
+}
+
 // NonCoordinator selects all containers except coordinator.
 func NonCoordinator(name string) bool {
 	return name != CoordinatorSidecarName
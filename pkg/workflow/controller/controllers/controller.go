@@ -25,7 +25,9 @@ type Controller struct {
 	clientSet     k8s.Interface
 	queue         workqueue.RateLimitingInterface
 	informer      cache.SharedIndexInformer
-	eventHandler  handlers.Interface
+	// cacheInformers are informers of other resources that the event handler reads from caches.
+	cacheInformers []cache.SharedIndexInformer
+	eventHandler   handlers.Interface
 }
 
 // Run ...
@@ -35,6 +37,9 @@ func (c *Controller) Run(threadiness int, stopCh <-chan struct{}) {
 	log.WithField("name", c.name).WithField("threadiness", threadiness).Info("Start controller.")
 
 	go c.informer.Run(stopCh)
+	for _, informer := range c.cacheInformers {
+		go informer.Run(stopCh)
+	}
 
 	if !cache.WaitForCacheSync(stopCh, c.HasSynced) {
 		utilruntime.HandleError(fmt.Errorf("timeout to sync caches"))
@@ -52,6 +57,11 @@ func (c *Controller) Run(threadiness int, stopCh <-chan struct{}) {
 
 // HasSynced ...
 func (c *Controller) HasSynced() bool {
+	for _, informer := range c.cacheInformers {
+		if !informer.HasSynced() {
+			return false
+		}
+	}
 	return c.informer.HasSynced()
 }
 
@@ -50,11 +50,23 @@ func NewWorkflowRunController(client k8s.Interface) *Controller {
 		},
 	})
 
+	// WorkflowRun completion triggers are read from cache when WorkflowRuns terminate.
+	wftFactory := informers.NewSharedInformerFactoryWithOptions(
+		client,
+		controller.Config.ResyncPeriodSeconds*time.Second,
+		informers.WithTweakListOptions(func(options *metav1.ListOptions) {
+			options.LabelSelector = meta.WorkflowTriggerSelector()
+		}),
+	)
+	wftInformer := wftFactory.Cyclone().V1alpha1().WorkflowTriggers()
+
 	return &Controller{
-		name:         "WorkflowRun Controller",
-		clientSet:    client,
-		informer:     informer,
-		queue:        queue,
-		eventHandler: handlers.NewHandler(client, controller.Config.GC.Enabled, controller.Config.Limits.MaxWorkflowRuns, controller.Config.Parallelism),
+		name:           "WorkflowRun Controller",
+		clientSet:      client,
+		informer:       informer,
+		cacheInformers: []cache.SharedIndexInformer{wftInformer.Informer()},
+		queue:          queue,
+		eventHandler: handlers.NewHandler(client, wftInformer.Lister(), controller.Config.GC.Enabled,
+			controller.Config.Limits.MaxWorkflowRuns, controller.Config.Parallelism),
 	}
 }
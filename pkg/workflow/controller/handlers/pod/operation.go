@@ -4,6 +4,7 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"strings"
 	"time"
 
 	log "github.com/sirupsen/logrus"
@@ -81,12 +82,11 @@ func (p *Operator) OnDelete() error {
 		return err
 	}
 	status, ok := wfr.Status.Stages[p.stage]
+	if ok && p.isPreviousAttempt(status) {
+		return nil
+	}
 	if !ok || status.Status.Phase == v1alpha1.StatusRunning {
-		operator.UpdateStageStatus(p.stage, &v1alpha1.Status{
-			Phase:              v1alpha1.StatusFailed,
-			LastTransitionTime: metav1.Time{Time: time.Now()},
-			Reason:             "PodDeleted",
-		})
+		p.failStage(operator, "PodDeleted", "")
 	}
 
 	return operator.Update()
@@ -123,20 +123,30 @@ func (p *Operator) OnUpdated() error {
 	}
 
 	status, ok := wfr.Status.Stages[p.stage]
+	if ok && p.isPreviousAttempt(status) {
+		log.WithField("wfr", wfr.Name).WithField("stg", p.stage).WithField("pod", p.pod.Name).Debug("Pod of previous attempt, skip it")
+		return nil
+	}
+
+	// Sync exit codes of workload containers to WorkflowRun status
+	wfrOperator.UpdateStageContainers(p.stage, p.workloadContainerStatuses())
 
 	switch p.pod.Status.Phase {
 	case corev1.PodFailed:
-		if !ok || status.Status.Phase != v1alpha1.StatusFailed {
-			log.WithField("wfr", wfr.Name).
-				WithField("stg", p.stage).
-				WithField("status", v1alpha1.StatusFailed).
-				Info("To update stage status")
-			wfrOperator.UpdateStageStatus(p.stage, &v1alpha1.Status{
-				Phase:              v1alpha1.StatusFailed,
-				LastTransitionTime: metav1.Time{Time: time.Now()},
-				Reason:             "PodFailed",
-			})
+		if ok && status.Status.Phase == v1alpha1.StatusFailed {
+			break
+		}
+		// Service containers are killed by coordinator when workload containers finish, which fails the pod even
+		// if the stage succeeds. So once coordinator terminates, its exit code decides the stage status.
+		if p.coordinatorTerminated() != nil {
+			p.DetermineStatus(wfrOperator)
+			break
 		}
+		log.WithField("wfr", wfr.Name).
+			WithField("stg", p.stage).
+			WithField("status", v1alpha1.StatusFailed).
+			Info("To update stage status")
+		p.failStage(wfrOperator, "PodFailed", p.pod.Status.Message)
 	case corev1.PodSucceeded:
 		if !ok || status.Status.Phase != v1alpha1.StatusSucceeded {
 			log.WithField("wfr", wfr.Name).
@@ -181,18 +191,10 @@ func (p *Operator) DetermineStatus(wfrOperator workflowrun.Operator) {
 	}
 
 	// Check coordinator container's status, if it's terminated, we regard the pod completed.
-	var terminatedCoordinatorState *corev1.ContainerStateTerminated
-	for _, containerStatus := range p.pod.Status.ContainerStatuses {
-		if containerStatus.Name == common.CoordinatorSidecarName {
-			if containerStatus.State.Terminated == nil {
-				log.WithField("container", containerStatus.Name).Debug("Coordinator not terminated")
-				return
-			}
-
-			terminatedCoordinatorState = containerStatus.State.Terminated
-			// There is only one coordinator container in each pod.
-			break
-		}
+	terminatedCoordinatorState := p.coordinatorTerminated()
+	if terminatedCoordinatorState == nil {
+		log.WithField("pod", p.pod.Name).Debug("Coordinator not terminated")
+		return
 	}
 
 	// Now the workload containers and coordinator container have all been finished. We then:
@@ -213,12 +215,9 @@ func (p *Operator) DetermineStatus(wfrOperator workflowrun.Operator) {
 			WithField("stg", p.stage).
 			WithField("status", v1alpha1.StatusFailed).
 			Info("To update stage status")
-		wfrOperator.UpdateStageStatus(p.stage, &v1alpha1.Status{
-			Phase:              v1alpha1.StatusFailed,
-			LastTransitionTime: metav1.Time{Time: time.Now()},
-			Reason:             terminatedCoordinatorState.Reason,
-			Message:            terminatedCoordinatorState.Message,
-		})
+		if p.failStage(wfrOperator, terminatedCoordinatorState.Reason, terminatedCoordinatorState.Message) {
+			return
+		}
 	}
 
 	// The workload and coordinator containers have all been finished, but maybe some others are still Running,
@@ -231,3 +230,119 @@ func (p *Operator) DetermineStatus(wfrOperator workflowrun.Operator) {
 		}
 	}
 }
+
+// coordinatorTerminated gets the terminated state of coordinator container, nil is returned if it hasn't terminated.
+func (p *Operator) coordinatorTerminated() *corev1.ContainerStateTerminated {
+	for _, containerStatus := range p.pod.Status.ContainerStatuses {
+		// There is only one coordinator container in each pod.
+		if containerStatus.Name == common.CoordinatorSidecarName {
+			return containerStatus.State.Terminated
+		}
+	}
+
+	return nil
+}
+
+// isPreviousAttempt checks whether the pod belongs to a previous failed attempt of a retried stage.
+func (p *Operator) isPreviousAttempt(status *v1alpha1.StageStatus) bool {
+	for _, a := range status.Attempts {
+		if a.Pod == p.pod.Name {
+			return true
+		}
+	}
+
+	return false
+}
+
+// failStage marks the stage as failed, unless it would be retried according to its retry policy. When
+// the stage is to be retried, the failed pod would be deleted, and true is returned.
+func (p *Operator) failStage(wfrOperator workflowrun.Operator, reason, message string) bool {
+	if wfrOperator.RetryStage(p.stage, p.attempt(reason, message)) {
+		if err := p.clusterClient.CoreV1().Pods(p.pod.Namespace).Delete(context.TODO(), p.pod.Name, metav1.DeleteOptions{}); err != nil && !errors.IsNotFound(err) {
+			log.WithField("ns", p.pod.Namespace).WithField("pod", p.pod.Name).Warn("Delete pod of failed attempt error: ", err)
+		}
+		return true
+	}
+
+	wfrOperator.UpdateStageStatus(p.stage, &v1alpha1.Status{
+		Phase:              v1alpha1.StatusFailed,
+		LastTransitionTime: metav1.Time{Time: time.Now()},
+		Reason:             reason,
+		Message:            message,
+	})
+	return false
+}
+
+// attempt builds a failed stage attempt from the pod. Reason of the pod (for example 'Evicted') or reason of
+// the terminated workload container (for example 'OOMKilled') takes precedence over the given reason.
+func (p *Operator) attempt(reason, message string) *v1alpha1.StageAttempt {
+	attempt := &v1alpha1.StageAttempt{
+		Pod:        p.pod.Name,
+		Reason:     reason,
+		Message:    message,
+		FinishTime: metav1.Time{Time: time.Now()},
+	}
+	if p.pod.Status.StartTime != nil {
+		attempt.StartTime = *p.pod.Status.StartTime
+	}
+
+	// Take the first failed workload container if there are multiple ones, service containers are excluded.
+	var terminated *corev1.ContainerStateTerminated
+	for _, c := range p.workloadContainerStatuses() {
+		if c.Service {
+			continue
+		}
+		for _, cs := range p.pod.Status.ContainerStatuses {
+			if cs.Name == c.Name && cs.State.Terminated != nil && (terminated == nil || terminated.ExitCode == 0) {
+				terminated = cs.State.Terminated
+			}
+		}
+	}
+	if terminated != nil {
+		exitCode := terminated.ExitCode
+		attempt.ExitCode = &exitCode
+		if len(terminated.Reason) > 0 {
+			attempt.Reason = terminated.Reason
+		}
+	}
+
+	if len(p.pod.Status.Reason) > 0 {
+		attempt.Reason = p.pod.Status.Reason
+	}
+
+	return attempt
+}
+
+// workloadContainerStatuses gets status of workload containers from the pod, in the order of containers in pod spec.
+// Containers created for services of the stage are excluded, workload containers marked as services are told from
+// the pod annotation.
+func (p *Operator) workloadContainerStatuses() []v1alpha1.WorkloadContainerStatus {
+	services := make(map[string]bool)
+	if v, ok := p.pod.Annotations[meta.AnnotationServiceContainers]; ok && v != "" {
+		for _, name := range strings.Split(v, ",") {
+			services[name] = true
+		}
+	}
+
+	var statuses []v1alpha1.WorkloadContainerStatus
+	for _, c := range p.pod.Spec.Containers {
+		if !common.OnlyWorkload(c.Name) {
+			continue
+		}
+
+		status := v1alpha1.WorkloadContainerStatus{
+			Name:    c.Name,
+			Service: services[c.Name],
+		}
+		for _, cs := range p.pod.Status.ContainerStatuses {
+			if cs.Name == c.Name && cs.State.Terminated != nil {
+				exitCode := cs.State.Terminated.ExitCode
+				status.ExitCode = &exitCode
+				status.Reason = cs.State.Terminated.Reason
+			}
+		}
+		statuses = append(statuses, status)
+	}
+
+	return statuses
+}
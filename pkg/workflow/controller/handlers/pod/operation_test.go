@@ -0,0 +1,216 @@
+package pod
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/caicloud/cyclone/pkg/apis/cyclone/v1alpha1"
+	"github.com/caicloud/cyclone/pkg/meta"
+	"github.com/caicloud/cyclone/pkg/util/k8s/fake"
+	"github.com/caicloud/cyclone/pkg/workflow/common"
+)
+
+// terminated builds status of a terminated container.
+func terminated(name string, exitCode int32) corev1.ContainerStatus {
+	return corev1.ContainerStatus{
+		Name: name,
+		State: corev1.ContainerState{
+			Terminated: &corev1.ContainerStateTerminated{
+				ExitCode:  exitCode,
+				StartedAt: metav1.Now(),
+			},
+		},
+	}
+}
+
+// newTestOperator creates a pod operator for stage 'test' of WorkflowRun 'wfr', the stage is running and can be
+// retried once.
+func newTestOperator(t *testing.T, pod *corev1.Pod) (*Operator, *fake.Clientset) {
+	wf := &v1alpha1.Workflow{
+		ObjectMeta: metav1.ObjectMeta{Name: "wf", Namespace: "default"},
+		Spec: v1alpha1.WorkflowSpec{
+			Stages: []v1alpha1.StageItem{
+				{Name: "test", Retry: &v1alpha1.RetryPolicy{Limit: 1}},
+			},
+		},
+	}
+	wfr := &v1alpha1.WorkflowRun{
+		ObjectMeta: metav1.ObjectMeta{Name: "wfr", Namespace: "default"},
+		Spec: v1alpha1.WorkflowRunSpec{
+			WorkflowRef: &corev1.ObjectReference{Name: "wf"},
+		},
+		Status: v1alpha1.WorkflowRunStatus{
+			Overall: v1alpha1.Status{Phase: v1alpha1.StatusRunning},
+			Stages: map[string]*v1alpha1.StageStatus{
+				"test": {
+					Status: v1alpha1.Status{Phase: v1alpha1.StatusRunning},
+					Pod:    &v1alpha1.PodInfo{Name: pod.Name, Namespace: pod.Namespace},
+				},
+			},
+		},
+	}
+	client := fake.NewSimpleClientset(wf, wfr)
+
+	pod.Annotations[meta.AnnotationWorkflowRunName] = "wfr"
+	pod.Annotations[meta.AnnotationStageName] = "test"
+	pod.Annotations[meta.AnnotationMetaNamespace] = "default"
+	operator, err := NewOperator(client, client, pod)
+	assert.Nil(t, err)
+	return operator, client
+}
+
+// stageStatus gets status of stage 'test' from WorkflowRun 'wfr'.
+func stageStatus(t *testing.T, client *fake.Clientset) *v1alpha1.StageStatus {
+	wfr, err := client.CycloneV1alpha1().WorkflowRuns("default").Get(context.TODO(), "wfr", metav1.GetOptions{})
+	assert.Nil(t, err)
+	return wfr.Status.Stages["test"]
+}
+
+func TestOnUpdatedServiceKilled(t *testing.T) {
+	testCases := map[string]struct {
+		coordinator int32
+		expected    v1alpha1.StatusPhase
+		attempts    int
+	}{
+		"service killed, workload exit 0": {
+			coordinator: 0,
+			expected:    v1alpha1.StatusSucceeded,
+		},
+		"service killed, workload failed": {
+			coordinator: 1,
+			expected:    v1alpha1.StatusPending,
+			attempts:    1,
+		},
+	}
+
+	for d, tc := range testCases {
+		pod := &corev1.Pod{
+			ObjectMeta: metav1.ObjectMeta{Name: "wf-test-pod", Namespace: "default", Annotations: map[string]string{}},
+			Spec: corev1.PodSpec{
+				Containers: []corev1.Container{{Name: "svc-db"}, {Name: common.CoordinatorSidecarName}, {Name: "test"}},
+			},
+			Status: corev1.PodStatus{
+				// Killed services make the pod Failed.
+				Phase: corev1.PodFailed,
+				ContainerStatuses: []corev1.ContainerStatus{
+					terminated("svc-db", 137),
+					terminated(common.CoordinatorSidecarName, tc.coordinator),
+					terminated("test", tc.coordinator),
+				},
+			},
+		}
+		operator, client := newTestOperator(t, pod)
+		assert.Nil(t, operator.OnUpdated(), d)
+
+		// Failed stage is retried, exit code of the attempt is taken from the workload container.
+		status := stageStatus(t, client)
+		assert.Equal(t, tc.expected, status.Status.Phase, d)
+		assert.Equal(t, tc.attempts, len(status.Attempts), d)
+		for _, a := range status.Attempts {
+			assert.Equal(t, int32(1), *a.ExitCode, d)
+		}
+		// Services aren't recorded as workload containers.
+		assert.Equal(t, 1, len(status.Containers), d)
+		assert.Equal(t, "test", status.Containers[0].Name, d)
+	}
+}
+
+func TestOnUpdatedPodFailedBeforeCoordinatorTerminated(t *testing.T) {
+	pod := &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{Name: "wf-test-pod", Namespace: "default", Annotations: map[string]string{}},
+		Spec: corev1.PodSpec{
+			Containers: []corev1.Container{{Name: common.CoordinatorSidecarName}, {Name: "test"}},
+		},
+		Status: corev1.PodStatus{
+			Phase:  corev1.PodFailed,
+			Reason: "Evicted",
+		},
+	}
+	operator, client := newTestOperator(t, pod)
+	assert.Nil(t, operator.OnUpdated())
+
+	// The stage is retried for the eviction.
+	status := stageStatus(t, client)
+	assert.Equal(t, v1alpha1.StatusPending, status.Status.Phase)
+	assert.Equal(t, 1, len(status.Attempts))
+	assert.Equal(t, "Evicted", status.Attempts[0].Reason)
+}
+
+func TestOnUpdatedServiceContainerKilled(t *testing.T) {
+	pod := &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:        "wf-test-pod",
+			Namespace:   "default",
+			Annotations: map[string]string{meta.AnnotationServiceContainers: "app"},
+		},
+		Spec: corev1.PodSpec{
+			Containers: []corev1.Container{{Name: common.CoordinatorSidecarName}, {Name: "app"}, {Name: "test"}},
+		},
+		Status: corev1.PodStatus{
+			// Killed service containers make the pod Failed.
+			Phase: corev1.PodFailed,
+			ContainerStatuses: []corev1.ContainerStatus{
+				terminated(common.CoordinatorSidecarName, 0),
+				terminated("app", 137),
+				terminated("test", 0),
+			},
+		},
+	}
+	operator, client := newTestOperator(t, pod)
+	assert.Nil(t, operator.OnUpdated())
+
+	status := stageStatus(t, client)
+	assert.Equal(t, v1alpha1.StatusSucceeded, status.Status.Phase)
+	assert.Empty(t, status.Attempts)
+	assert.Equal(t, 2, len(status.Containers))
+	assert.Equal(t, "app", status.Containers[0].Name)
+	assert.True(t, status.Containers[0].Service)
+	assert.Equal(t, int32(137), *status.Containers[0].ExitCode)
+	assert.Equal(t, "test", status.Containers[1].Name)
+	assert.False(t, status.Containers[1].Service)
+}
+
+func TestAttempt(t *testing.T) {
+	pod := &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:        "wf-test-pod",
+			Annotations: map[string]string{meta.AnnotationServiceContainers: "app"},
+		},
+		Spec: corev1.PodSpec{
+			Containers: []corev1.Container{
+				{Name: "svc-db"}, {Name: common.CoordinatorSidecarName}, {Name: "app"}, {Name: "build"}, {Name: "lint"}, {Name: "test"},
+			},
+		},
+		Status: corev1.PodStatus{
+			ContainerStatuses: []corev1.ContainerStatus{
+				terminated("svc-db", 137),
+				terminated(common.CoordinatorSidecarName, 1),
+				terminated("app", 137),
+				terminated("build", 0),
+				terminated("lint", 2),
+				terminated("test", 3),
+			},
+		},
+	}
+	pod.Status.ContainerStatuses[4].State.Terminated.Reason = "Error"
+	operator := &Operator{pod: pod}
+
+	// The first failed workload container is taken, services and service containers are excluded.
+	attempt := operator.attempt("CoordinatorFailed", "message")
+	assert.Equal(t, "wf-test-pod", attempt.Pod)
+	assert.Equal(t, int32(2), *attempt.ExitCode)
+	assert.Equal(t, "Error", attempt.Reason)
+	assert.Equal(t, "message", attempt.Message)
+
+	// Exit code is 0 if all workload containers succeeded, and reason of the pod takes precedence.
+	pod.Status.ContainerStatuses[4] = terminated("lint", 0)
+	pod.Status.ContainerStatuses[5] = terminated("test", 0)
+	pod.Status.Reason = "Evicted"
+	attempt = operator.attempt("CoordinatorFailed", "")
+	assert.Equal(t, int32(0), *attempt.ExitCode)
+	assert.Equal(t, "Evicted", attempt.Reason)
+}
@@ -0,0 +1,275 @@
+package workflowrun
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+
+	log "github.com/sirupsen/logrus"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/util/validation"
+	"k8s.io/client-go/util/retry"
+
+	"github.com/caicloud/cyclone/pkg/apis/cyclone/v1alpha1"
+	ccommon "github.com/caicloud/cyclone/pkg/common"
+	"github.com/caicloud/cyclone/pkg/meta"
+	svrcommon "github.com/caicloud/cyclone/pkg/server/common"
+	"github.com/caicloud/cyclone/pkg/workflow/common"
+	"github.com/caicloud/cyclone/pkg/workflow/workflowrun"
+)
+
+const (
+	// upstreamVariablePrefix is prefix of global variables passed from upstream WorkflowRuns.
+	upstreamVariablePrefix = "upstream."
+
+	// maxUpstreamChainLength is the max number of WorkflowRuns in a chain of WorkflowRun completion triggers.
+	maxUpstreamChainLength = 20
+)
+
+// triggerDownstream creates WorkflowRuns for the WorkflowRun completion triggers fired by a terminated WorkflowRun.
+// It's performed only once for each WorkflowRun, which is marked by an annotation after all downstream WorkflowRuns
+// are created. Errors are returned to retry, downstream WorkflowRuns created in previous attempts are not created
+// again as their names are generated from the upstream WorkflowRun.
+func (h *Handler) triggerDownstream(wfr *v1alpha1.WorkflowRun) error {
+	if wfr.Annotations[meta.AnnotationWorkflowRunDownstreamTriggered] == meta.LabelValueTrue {
+		return nil
+	}
+
+	// Get latest WorkflowRun, the one from cache may have been processed already.
+	latest, err := h.Client.CycloneV1alpha1().WorkflowRuns(wfr.Namespace).Get(context.TODO(), wfr.Name, metav1.GetOptions{})
+	if err != nil {
+		return err
+	}
+	if latest.Annotations[meta.AnnotationWorkflowRunDownstreamTriggered] == meta.LabelValueTrue {
+		return nil
+	}
+
+	wfts, err := h.WorkflowTriggerLister.WorkflowTriggers(wfr.Namespace).List(labels.Everything())
+	if err != nil {
+		return err
+	}
+	var failed []string
+	for _, wft := range wfts {
+		if !MatchWorkflowRunTrigger(wft, latest) {
+			continue
+		}
+
+		downstream, err := h.createDownstream(wft, latest)
+		if err != nil {
+			log.WithField("wfr", wfr.Name).WithField("wft", wft.Name).Warn("Create downstream WorkflowRun error: ", err)
+			failed = append(failed, wft.Name)
+			continue
+		}
+		log.WithField("wfr", wfr.Name).WithField("wft", wft.Name).Infof("Downstream WorkflowRun %s created", downstream.Name)
+	}
+	if len(failed) > 0 {
+		return fmt.Errorf("failed to create downstream WorkflowRuns for workflow triggers %v", failed)
+	}
+
+	return retry.RetryOnConflict(retry.DefaultRetry, func() error {
+		latest, err := h.Client.CycloneV1alpha1().WorkflowRuns(wfr.Namespace).Get(context.TODO(), wfr.Name, metav1.GetOptions{})
+		if err != nil {
+			return err
+		}
+
+		toUpdate := latest.DeepCopy()
+		if toUpdate.Annotations == nil {
+			toUpdate.Annotations = make(map[string]string)
+		}
+		toUpdate.Annotations[meta.AnnotationWorkflowRunDownstreamTriggered] = meta.LabelValueTrue
+		_, err = h.Client.CycloneV1alpha1().WorkflowRuns(wfr.Namespace).Update(context.TODO(), toUpdate, metav1.UpdateOptions{})
+		return err
+	})
+}
+
+// createDownstream creates a WorkflowRun for the WorkflowRun completion trigger, and increases the triggered count
+// in status of the trigger. If the WorkflowRun has been created for the upstream WorkflowRun, it's returned as created.
+func (h *Handler) createDownstream(wft *v1alpha1.WorkflowTrigger, upstream *v1alpha1.WorkflowRun) (*v1alpha1.WorkflowRun, error) {
+	wfr := NewDownstreamWorkflowRun(wft, upstream)
+	created, err := h.Client.CycloneV1alpha1().WorkflowRuns(wft.Namespace).Create(context.TODO(), wfr, metav1.CreateOptions{})
+	if errors.IsAlreadyExists(err) {
+		existing, getErr := h.Client.CycloneV1alpha1().WorkflowRuns(wft.Namespace).Get(context.TODO(), wfr.Name, metav1.GetOptions{})
+		if getErr != nil {
+			return nil, getErr
+		}
+		if existing.Annotations[meta.AnnotationWorkflowRunUpstream] != upstream.Name {
+			return nil, err
+		}
+		return existing, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	err = retry.RetryOnConflict(retry.DefaultRetry, func() error {
+		latest, err := h.Client.CycloneV1alpha1().WorkflowTriggers(wft.Namespace).Get(context.TODO(), wft.Name, metav1.GetOptions{})
+		if err != nil {
+			return err
+		}
+
+		toUpdate := latest.DeepCopy()
+		toUpdate.Status.Count++
+		_, err = h.Client.CycloneV1alpha1().WorkflowTriggers(wft.Namespace).Update(context.TODO(), toUpdate, metav1.UpdateOptions{})
+		return err
+	})
+	if err != nil {
+		log.WithField("wft", wft.Name).Warn("Update triggered count error: ", err)
+	}
+
+	return created, nil
+}
+
+// MatchWorkflowRunTrigger checks whether a terminated WorkflowRun fires the WorkflowRun completion trigger.
+func MatchWorkflowRunTrigger(wft *v1alpha1.WorkflowTrigger, upstream *v1alpha1.WorkflowRun) bool {
+	if wft.Spec.Type != v1alpha1.TriggerTypeWorkflowRun || wft.Spec.Disabled || wft.Spec.WorkflowRef == nil {
+		return false
+	}
+
+	trigger := wft.Spec.WorkflowRun
+	if upstream.Spec.WorkflowRef == nil || trigger.Workflow != upstream.Spec.WorkflowRef.Name {
+		return false
+	}
+
+	project := trigger.Project
+	if project == "" {
+		project = wft.Labels[meta.LabelProjectName]
+	}
+	if project != "" && project != upstream.Labels[meta.LabelProjectName] {
+		return false
+	}
+
+	// A workflow triggering itself, directly or through other workflows, would run endlessly.
+	chain := upstreamChain(upstream)
+	if len(chain) > maxUpstreamChainLength {
+		return false
+	}
+	downstream := chainWorkflow(wft.Labels[meta.LabelProjectName], wft.Spec.WorkflowRef.Name)
+	for _, w := range chain {
+		if w == downstream {
+			return false
+		}
+	}
+
+	phases := trigger.Phases
+	if len(phases) == 0 {
+		phases = []v1alpha1.StatusPhase{v1alpha1.StatusSucceeded}
+	}
+	for _, phase := range phases {
+		if phase == upstream.Status.Overall.Phase {
+			return true
+		}
+	}
+	return false
+}
+
+// upstreamChain gets workflows of the WorkflowRun and its upstream WorkflowRuns in format '<project>/<workflow>',
+// from the first upstream to the WorkflowRun itself.
+func upstreamChain(wfr *v1alpha1.WorkflowRun) []string {
+	var chain []string
+	if value := wfr.Annotations[meta.AnnotationWorkflowRunUpstreamChain]; value != "" {
+		chain = strings.Split(value, ",")
+	}
+	return append(chain, chainWorkflow(wfr.Labels[meta.LabelProjectName], wfr.Spec.WorkflowRef.Name))
+}
+
+func chainWorkflow(project, workflow string) string {
+	return project + "/" + workflow
+}
+
+// NewDownstreamWorkflowRun creates a WorkflowRun from the WorkflowRun completion trigger, with outputs of the
+// upstream WorkflowRun passed as global variables. Its name is generated from the trigger and the upstream
+// WorkflowRun, so that the trigger creates only one WorkflowRun for the upstream WorkflowRun.
+func NewDownstreamWorkflowRun(wft *v1alpha1.WorkflowTrigger, upstream *v1alpha1.WorkflowRun) *v1alpha1.WorkflowRun {
+	sum := sha256.Sum256([]byte(fmt.Sprintf("%s/%s/%s", upstream.Name, upstream.UID, wft.Name)))
+	name := fmt.Sprintf("%s-%s", wft.Name, hex.EncodeToString(sum[:])[:8])
+	wfr := &v1alpha1.WorkflowRun{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      name,
+			Namespace: wft.Namespace,
+			Annotations: map[string]string{
+				meta.AnnotationAlias:                    name,
+				meta.AnnotationWorkflowRunTrigger:       svrcommon.WorkflowRunCompletionTrigger,
+				meta.AnnotationWorkflowRunUpstream:      upstream.Name,
+				meta.AnnotationWorkflowRunUpstreamChain: strings.Join(upstreamChain(upstream), ","),
+			},
+			Labels: map[string]string{
+				meta.LabelWorkflowName: wft.Spec.WorkflowRef.Name,
+			},
+		},
+		Spec: *wft.Spec.WorkflowRunSpec.DeepCopy(),
+	}
+
+	if project, ok := wft.Labels[meta.LabelProjectName]; ok {
+		wfr.Labels[meta.LabelProjectName] = project
+	}
+	if errs := validation.IsValidLabelValue(wft.Name); len(errs) == 0 {
+		wfr.Labels[meta.LabelWorkflowRunTrigger] = wft.Name
+	}
+
+	// If controller instance name is set, add label to the WorkflowRun created.
+	if instance := os.Getenv(ccommon.ControllerInstanceEnvName); len(instance) != 0 {
+		wfr.Labels[meta.LabelControllerInstance] = instance
+	}
+
+	// Artifacts of the upstream WorkflowRun can only be read if they are in the same PV.
+	workspace := common.WorkspaceName(upstream)
+	if sameExecutionContext(workflowrun.GetExecutionContext(upstream), workflowrun.GetExecutionContext(wfr)) &&
+		len(validation.IsValidLabelValue(workspace)) == 0 {
+		wfr.Labels[meta.LabelWorkflowRunUpstreamWorkspace] = workspace
+	}
+
+	for _, variable := range upstreamVariables(upstream) {
+		setGlobalVariable(wfr, variable)
+	}
+
+	return wfr
+}
+
+// sameExecutionContext checks whether two execution contexts share the same PV.
+func sameExecutionContext(a, b *v1alpha1.ExecutionContext) bool {
+	return a.PVC != "" && a.Cluster == b.Cluster && a.Namespace == b.Namespace && a.PVC == b.PVC
+}
+
+// upstreamVariables gets global variables passed from the upstream WorkflowRun, they are metadata of the upstream
+// WorkflowRun, and outputs of its stages in format 'upstream.stages.<stage>.outputs.<key>'.
+func upstreamVariables(upstream *v1alpha1.WorkflowRun) []v1alpha1.GlobalVariable {
+	variables := []v1alpha1.GlobalVariable{
+		{Name: upstreamVariablePrefix + "workflowrun", Value: upstream.Name},
+		{Name: upstreamVariablePrefix + "workflow", Value: upstream.Spec.WorkflowRef.Name},
+		{Name: upstreamVariablePrefix + "project", Value: upstream.Labels[meta.LabelProjectName]},
+		{Name: upstreamVariablePrefix + "phase", Value: string(upstream.Status.Overall.Phase)},
+	}
+
+	var stages []string
+	for stage := range upstream.Status.Stages {
+		stages = append(stages, stage)
+	}
+	sort.Strings(stages)
+	for _, stage := range stages {
+		for _, output := range upstream.Status.Stages[stage].Outputs {
+			variables = append(variables, v1alpha1.GlobalVariable{
+				Name:  fmt.Sprintf("%sstages.%s.outputs.%s", upstreamVariablePrefix, stage, output.Key),
+				Value: output.Value,
+			})
+		}
+	}
+
+	return variables
+}
+
+// setGlobalVariable sets a global variable of the WorkflowRun, it overrides the one with the same name.
+func setGlobalVariable(wfr *v1alpha1.WorkflowRun, variable v1alpha1.GlobalVariable) {
+	for i, v := range wfr.Spec.GlobalVariables {
+		if v.Name == variable.Name {
+			wfr.Spec.GlobalVariables[i].Value = variable.Value
+			return
+		}
+	}
+	wfr.Spec.GlobalVariables = append(wfr.Spec.GlobalVariables, variable)
+}
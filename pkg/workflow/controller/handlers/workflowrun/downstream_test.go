@@ -0,0 +1,213 @@
+package workflowrun
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/tools/cache"
+
+	"github.com/caicloud/cyclone/pkg/apis/cyclone/v1alpha1"
+	listers "github.com/caicloud/cyclone/pkg/k8s/listers/cyclone/v1alpha1"
+	"github.com/caicloud/cyclone/pkg/meta"
+	svrcommon "github.com/caicloud/cyclone/pkg/server/common"
+	"github.com/caicloud/cyclone/pkg/util/k8s/fake"
+)
+
+func newTestUpstream(phase v1alpha1.StatusPhase) *v1alpha1.WorkflowRun {
+	return &v1alpha1.WorkflowRun{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "build-abcde",
+			Namespace: "cyclone-devops",
+			Labels:    map[string]string{meta.LabelProjectName: "cyclone"},
+		},
+		Spec: v1alpha1.WorkflowRunSpec{
+			WorkflowRef: &corev1.ObjectReference{Name: "build"},
+		},
+		Status: v1alpha1.WorkflowRunStatus{
+			Overall: v1alpha1.Status{Phase: phase},
+			Stages: map[string]*v1alpha1.StageStatus{
+				"image":   {Outputs: []v1alpha1.KeyValue{{Key: "IMAGE", Value: "cyclone:v1.0"}}},
+				"compile": {Outputs: []v1alpha1.KeyValue{{Key: "VERSION", Value: "v1.0"}}},
+			},
+		},
+	}
+}
+
+func newTestCompletionTrigger() *v1alpha1.WorkflowTrigger {
+	return &v1alpha1.WorkflowTrigger{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "deploy-after-build",
+			Namespace: "cyclone-devops",
+			Labels:    map[string]string{meta.LabelProjectName: "cyclone"},
+		},
+		Spec: v1alpha1.WorkflowTriggerSpec{
+			Type:        v1alpha1.TriggerTypeWorkflowRun,
+			WorkflowRun: v1alpha1.WorkflowRunTrigger{Workflow: "build"},
+			WorkflowRunSpec: v1alpha1.WorkflowRunSpec{
+				WorkflowRef: &corev1.ObjectReference{Name: "deploy"},
+				ExecutionContext: &v1alpha1.ExecutionContext{
+					Cluster:   "control-cluster",
+					Namespace: "cyclone-devops-workload",
+					PVC:       "cyclone-pvc",
+				},
+				GlobalVariables: []v1alpha1.GlobalVariable{
+					{Name: "ENV", Value: "staging"},
+					{Name: "upstream.phase", Value: "unknown"},
+				},
+			},
+		},
+	}
+}
+
+func TestMatchWorkflowRunTrigger(t *testing.T) {
+	cases := map[string]struct {
+		modify   func(wft *v1alpha1.WorkflowTrigger, upstream *v1alpha1.WorkflowRun)
+		expected bool
+	}{
+		"match": {
+			modify:   func(wft *v1alpha1.WorkflowTrigger, upstream *v1alpha1.WorkflowRun) {},
+			expected: true,
+		},
+		"failed by default": {
+			modify: func(wft *v1alpha1.WorkflowTrigger, upstream *v1alpha1.WorkflowRun) {
+				upstream.Status.Overall.Phase = v1alpha1.StatusFailed
+			},
+			expected: false,
+		},
+		"failed phase": {
+			modify: func(wft *v1alpha1.WorkflowTrigger, upstream *v1alpha1.WorkflowRun) {
+				upstream.Status.Overall.Phase = v1alpha1.StatusFailed
+				wft.Spec.WorkflowRun.Phases = []v1alpha1.StatusPhase{v1alpha1.StatusFailed, v1alpha1.StatusCancelled}
+			},
+			expected: true,
+		},
+		"other workflow": {
+			modify: func(wft *v1alpha1.WorkflowTrigger, upstream *v1alpha1.WorkflowRun) {
+				upstream.Spec.WorkflowRef.Name = "test"
+			},
+			expected: false,
+		},
+		"other project": {
+			modify: func(wft *v1alpha1.WorkflowTrigger, upstream *v1alpha1.WorkflowRun) {
+				upstream.Labels[meta.LabelProjectName] = "other"
+			},
+			expected: false,
+		},
+		"across projects": {
+			modify: func(wft *v1alpha1.WorkflowTrigger, upstream *v1alpha1.WorkflowRun) {
+				upstream.Labels[meta.LabelProjectName] = "other"
+				wft.Spec.WorkflowRun.Project = "other"
+			},
+			expected: true,
+		},
+		"disabled": {
+			modify: func(wft *v1alpha1.WorkflowTrigger, upstream *v1alpha1.WorkflowRun) {
+				wft.Spec.Disabled = true
+			},
+			expected: false,
+		},
+		"self": {
+			modify: func(wft *v1alpha1.WorkflowTrigger, upstream *v1alpha1.WorkflowRun) {
+				wft.Spec.WorkflowRef.Name = "build"
+			},
+			expected: false,
+		},
+		"cycle": {
+			modify: func(wft *v1alpha1.WorkflowTrigger, upstream *v1alpha1.WorkflowRun) {
+				upstream.Annotations = map[string]string{meta.AnnotationWorkflowRunUpstreamChain: "cyclone/deploy,cyclone/test"}
+			},
+			expected: false,
+		},
+		"chain": {
+			modify: func(wft *v1alpha1.WorkflowTrigger, upstream *v1alpha1.WorkflowRun) {
+				upstream.Annotations = map[string]string{meta.AnnotationWorkflowRunUpstreamChain: "other/deploy,cyclone/test"}
+			},
+			expected: true,
+		},
+		"other type": {
+			modify: func(wft *v1alpha1.WorkflowTrigger, upstream *v1alpha1.WorkflowRun) {
+				wft.Spec.Type = v1alpha1.TriggerTypeCron
+			},
+			expected: false,
+		},
+	}
+
+	for name, c := range cases {
+		wft := newTestCompletionTrigger()
+		upstream := newTestUpstream(v1alpha1.StatusSucceeded)
+		c.modify(wft, upstream)
+		assert.Equal(t, c.expected, MatchWorkflowRunTrigger(wft, upstream), name)
+	}
+}
+
+func TestNewDownstreamWorkflowRun(t *testing.T) {
+	upstream := newTestUpstream(v1alpha1.StatusSucceeded)
+	upstream.Spec.ExecutionContext = newTestCompletionTrigger().Spec.ExecutionContext
+	wfr := NewDownstreamWorkflowRun(newTestCompletionTrigger(), upstream)
+
+	assert.Equal(t, "deploy", wfr.Spec.WorkflowRef.Name)
+	assert.Equal(t, "cyclone-devops", wfr.Namespace)
+	assert.Equal(t, "cyclone", wfr.Labels[meta.LabelProjectName])
+	assert.Equal(t, "deploy", wfr.Labels[meta.LabelWorkflowName])
+	assert.Equal(t, "deploy-after-build", wfr.Labels[meta.LabelWorkflowRunTrigger])
+	assert.Equal(t, "build-abcde", wfr.Labels[meta.LabelWorkflowRunUpstreamWorkspace])
+	assert.Equal(t, "build-abcde", wfr.Annotations[meta.AnnotationWorkflowRunUpstream])
+	assert.Equal(t, svrcommon.WorkflowRunCompletionTrigger, wfr.Annotations[meta.AnnotationWorkflowRunTrigger])
+	assert.Equal(t, "cyclone/build", wfr.Annotations[meta.AnnotationWorkflowRunUpstreamChain])
+	assert.Equal(t, wfr.Name, NewDownstreamWorkflowRun(newTestCompletionTrigger(), upstream).Name)
+	assert.Equal(t, []v1alpha1.GlobalVariable{
+		{Name: "ENV", Value: "staging"},
+		{Name: "upstream.phase", Value: "Succeeded"},
+		{Name: "upstream.workflowrun", Value: "build-abcde"},
+		{Name: "upstream.workflow", Value: "build"},
+		{Name: "upstream.project", Value: "cyclone"},
+		{Name: "upstream.stages.compile.outputs.VERSION", Value: "v1.0"},
+		{Name: "upstream.stages.image.outputs.IMAGE", Value: "cyclone:v1.0"},
+	}, wfr.Spec.GlobalVariables)
+
+	// Artifacts can't be read from other execution contexts.
+	upstream.Spec.ExecutionContext = nil
+	wfr = NewDownstreamWorkflowRun(newTestCompletionTrigger(), upstream)
+	_, ok := wfr.Labels[meta.LabelWorkflowRunUpstreamWorkspace]
+	assert.False(t, ok)
+}
+
+func newTestWorkflowTriggerLister(wfts ...*v1alpha1.WorkflowTrigger) listers.WorkflowTriggerLister {
+	indexer := cache.NewIndexer(cache.MetaNamespaceKeyFunc, cache.Indexers{cache.NamespaceIndex: cache.MetaNamespaceIndexFunc})
+	for _, wft := range wfts {
+		_ = indexer.Add(wft)
+	}
+	return listers.NewWorkflowTriggerLister(indexer)
+}
+
+func TestTriggerDownstream(t *testing.T) {
+	upstream := newTestUpstream(v1alpha1.StatusSucceeded)
+	wft := newTestCompletionTrigger()
+	client := fake.NewSimpleClientset(upstream, wft)
+	h := &Handler{Client: client, WorkflowTriggerLister: newTestWorkflowTriggerLister(wft)}
+
+	// Downstream WorkflowRun created before the upstream WorkflowRun is marked is not created again.
+	created, err := h.createDownstream(wft, upstream)
+	assert.Nil(t, err)
+	assert.Nil(t, h.triggerDownstream(upstream))
+	// It's triggered only once.
+	assert.Nil(t, h.triggerDownstream(upstream))
+
+	wfrs, err := client.CycloneV1alpha1().WorkflowRuns(upstream.Namespace).List(context.TODO(), metav1.ListOptions{
+		LabelSelector: meta.TriggeredWorkflowRunSelector(wft.Name),
+	})
+	assert.Nil(t, err)
+	assert.Equal(t, 1, len(wfrs.Items))
+	assert.Equal(t, created.Name, wfrs.Items[0].Name)
+
+	latest, err := client.CycloneV1alpha1().WorkflowRuns(upstream.Namespace).Get(context.TODO(), upstream.Name, metav1.GetOptions{})
+	assert.Nil(t, err)
+	assert.Equal(t, meta.LabelValueTrue, latest.Annotations[meta.AnnotationWorkflowRunDownstreamTriggered])
+
+	latestWft, err := client.CycloneV1alpha1().WorkflowTriggers(wft.Namespace).Get(context.TODO(), wft.Name, metav1.GetOptions{})
+	assert.Nil(t, err)
+	assert.Equal(t, 1, latestWft.Status.Count)
+}
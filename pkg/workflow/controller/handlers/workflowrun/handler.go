@@ -9,12 +9,14 @@ import (
 	"time"
 
 	log "github.com/sirupsen/logrus"
+	"k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/util/sets"
 	"k8s.io/client-go/tools/cache"
 	"k8s.io/client-go/util/retry"
 
 	"github.com/caicloud/cyclone/pkg/apis/cyclone/v1alpha1"
+	listers "github.com/caicloud/cyclone/pkg/k8s/listers/cyclone/v1alpha1"
 	"github.com/caicloud/cyclone/pkg/meta"
 	"github.com/caicloud/cyclone/pkg/util"
 	utilhttp "github.com/caicloud/cyclone/pkg/util/http"
@@ -33,6 +35,7 @@ type Handler struct {
 	LimitedQueues         *workflowrun.LimitedQueues
 	ParallelismController workflowrun.ParallelismController
 	Informer              cache.SharedIndexInformer
+	WorkflowTriggerLister listers.WorkflowTriggerLister
 }
 
 // Ensure *Handler has implemented handlers.Interface interface.
@@ -44,13 +47,14 @@ const (
 )
 
 // NewHandler ...
-func NewHandler(client k8s.Interface, gcEnable bool, maxWorkflowRuns int, parallelism *controller.ParallelismConfig) *Handler {
+func NewHandler(client k8s.Interface, wftLister listers.WorkflowTriggerLister, gcEnable bool, maxWorkflowRuns int, parallelism *controller.ParallelismConfig) *Handler {
 	return &Handler{
 		Client:                client,
 		TimeoutProcessor:      workflowrun.NewTimeoutProcessor(client),
 		GCProcessor:           workflowrun.NewGCProcessor(client, gcEnable),
 		LimitedQueues:         workflowrun.NewLimitedQueues(client, maxWorkflowRuns),
 		ParallelismController: workflowrun.NewParallelismController(parallelism),
+		WorkflowTriggerLister: wftLister,
 	}
 }
 
@@ -78,6 +82,18 @@ func (h *Handler) Reconcile(obj interface{}) (res controller.Result, err error)
 	// If the WorkflowRun has not yet be started to execute, check the parallelism constraints to determine
 	// whether to execute it.
 	if originWfr.Status.Overall.Phase == "" {
+		groupAction, err := h.attemptInGroup(originWfr)
+		if err != nil {
+			return res, err
+		}
+		switch groupAction {
+		case workflowrun.AttemptActionQueued:
+			log.WithField("wfr", originWfr.Name).Infof("WorkflowRun in the same concurrency group is running, stay pending in queue, will retry in %d seconds", controller.Config.ResyncPeriodSeconds)
+			return res, fmt.Errorf("waiting for WorkflowRun in the same concurrency group")
+		case workflowrun.AttemptActionSkipped, workflowrun.AttemptActionFailed:
+			return res, nil
+		}
+
 		log.WithField("wfr", originWfr.Name).Info("Attempt to run WorkflowRun")
 		attemptAction := h.ParallelismController.AttemptNew(originWfr.Namespace, originWfr.Spec.WorkflowRef.Name, originWfr.Name)
 		switch attemptAction {
@@ -110,6 +126,12 @@ func (h *Handler) Reconcile(obj interface{}) (res controller.Result, err error)
 		if err != nil {
 			log.WithField("wfr", originWfr.Name).Warn("send notification failed", err)
 		}
+
+		// Fire WorkflowRun completion triggers of downstream workflows.
+		if err := h.triggerDownstream(originWfr); err != nil {
+			log.WithField("wfr", originWfr.Name).Warn("Trigger downstream WorkflowRuns error: ", err)
+			return res, err
+		}
 		return res, nil
 	}
 
@@ -148,6 +170,63 @@ func (h *Handler) Reconcile(obj interface{}) (res controller.Result, err error)
 	return res, nil
 }
 
+// attemptInGroup checks the concurrency group of a WorkflowRun that has not yet been started. WorkflowRuns superseded
+// by it are cancelled, and it will be cancelled itself if it's skipped, or failed if its concurrency group is invalid.
+func (h *Handler) attemptInGroup(wfr *v1alpha1.WorkflowRun) (workflowrun.AttemptAction, error) {
+	var wf *v1alpha1.Workflow
+	if wfr.Spec.Concurrency == nil {
+		f, err := h.Client.CycloneV1alpha1().Workflows(wfr.Namespace).Get(context.TODO(), wfr.Spec.WorkflowRef.Name, metav1.GetOptions{})
+		if err != nil && !errors.IsNotFound(err) {
+			log.WithField("wfr", wfr.Name).Error("Get workflow error: ", err)
+			return "", err
+		}
+		if err == nil {
+			wf = f
+		}
+	}
+
+	group, err := workflowrun.ResolveConcurrencyGroup(wf, wfr)
+	if err != nil {
+		log.WithField("wfr", wfr.Name).Warn("Resolve concurrency group error: ", err)
+		if err := h.SetStatus(wfr.Namespace, wfr.Name, &v1alpha1.Status{
+			Phase:              v1alpha1.StatusFailed,
+			Reason:             "InvalidConcurrencyGroup",
+			Message:            err.Error(),
+			LastTransitionTime: metav1.Time{Time: time.Now()},
+		}); err != nil {
+			return "", err
+		}
+		return workflowrun.AttemptActionFailed, nil
+	}
+
+	action, superseded := h.ParallelismController.AttemptInGroup(wfr.Namespace, wfr.Name, group)
+	for _, name := range superseded {
+		log.WithField("wfr", name).Infof("Cancel WorkflowRun superseded by %s in concurrency group %s", wfr.Name, group.Name)
+		if err := h.cancel(wfr.Namespace, name, &v1alpha1.Status{
+			Phase:              v1alpha1.StatusCancelled,
+			Reason:             v1alpha1.ReasonAutoCancelPreviousBuild,
+			Message:            fmt.Sprintf("Superseded by WorkflowRun %s", wfr.Name),
+			LastTransitionTime: metav1.Time{Time: time.Now()},
+		}); err != nil {
+			log.WithField("wfr", name).Warn("Cancel superseded WorkflowRun error: ", err)
+		}
+	}
+
+	if action == workflowrun.AttemptActionSkipped {
+		log.WithField("wfr", wfr.Name).Infof("Skip WorkflowRun as there is WorkflowRun running in concurrency group %s", group.Name)
+		if err := h.SetStatus(wfr.Namespace, wfr.Name, &v1alpha1.Status{
+			Phase:              v1alpha1.StatusCancelled,
+			Reason:             v1alpha1.ReasonConcurrencySkipped,
+			Message:            fmt.Sprintf("WorkflowRun is running in concurrency group %s", group.Name),
+			LastTransitionTime: metav1.Time{Time: time.Now()},
+		}); err != nil {
+			return "", err
+		}
+	}
+
+	return action, nil
+}
+
 // finalize handles the case when a WorkflowRun get deleted.
 // It will perform GC immediately for this WorkflowRun.
 func (h *Handler) finalize(wfr *v1alpha1.WorkflowRun) error {
@@ -206,7 +285,7 @@ func (h *Handler) HandleFinalizer(obj interface{}) error {
 
 	// Mark the WorkflowRun terminated in ParallelismController
 	defer func() {
-		h.ParallelismController.MarkFinished(originWfr.Namespace, originWfr.Name, originWfr.Spec.WorkflowRef.Name)
+		h.ParallelismController.MarkFinished(originWfr.Namespace, originWfr.Spec.WorkflowRef.Name, originWfr.Name)
 	}()
 
 	// Handler finalizer
@@ -309,6 +388,28 @@ func (h *Handler) SetStatus(ns, wfr string, status *v1alpha1.Status) error {
 	})
 }
 
+// cancel sets overall status of a WorkflowRun that is not terminated yet.
+func (h *Handler) cancel(ns, wfr string, status *v1alpha1.Status) error {
+	return retry.RetryOnConflict(retry.DefaultRetry, func() error {
+		latest, err := h.Client.CycloneV1alpha1().WorkflowRuns(ns).Get(context.TODO(), wfr, metav1.GetOptions{})
+		if err != nil {
+			if errors.IsNotFound(err) {
+				return nil
+			}
+			return err
+		}
+
+		if util.IsWorkflowRunTerminated(latest) {
+			return nil
+		}
+
+		toUpdate := latest.DeepCopy()
+		toUpdate.Status.Overall = *status
+		_, err = h.Client.CycloneV1alpha1().WorkflowRuns(ns).Update(context.TODO(), toUpdate, metav1.UpdateOptions{})
+		return err
+	})
+}
+
 // validate workflow run
 func validate(wfr *v1alpha1.WorkflowRun) bool {
 	// check workflowRef can not be nil
@@ -4,14 +4,17 @@ import (
 	"context"
 	"fmt"
 	"os"
-	"strings"
+	"reflect"
 	"sync"
+	"time"
 
 	"github.com/robfig/cron"
 	log "github.com/sirupsen/logrus"
 	errors2 "k8s.io/apimachinery/pkg/api/errors"
 	meta_v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/util/rand"
+	"k8s.io/apimachinery/pkg/util/validation"
+	"k8s.io/client-go/util/retry"
 
 	"github.com/caicloud/cyclone/pkg/apis/cyclone/v1alpha1"
 	ccommon "github.com/caicloud/cyclone/pkg/common"
@@ -19,6 +22,8 @@ import (
 	"github.com/caicloud/cyclone/pkg/meta"
 	"github.com/caicloud/cyclone/pkg/server/biz/accelerator"
 	"github.com/caicloud/cyclone/pkg/server/common"
+	"github.com/caicloud/cyclone/pkg/util"
+	"github.com/caicloud/cyclone/pkg/workflow/workflowtrigger"
 )
 
 const (
@@ -36,6 +41,12 @@ type CronTrigger struct {
 	WorkflowTriggerName string
 	WorkflowRun         *v1alpha1.WorkflowRun
 	Manage              *CronTriggerManager
+	ConcurrencyPolicy   v1alpha1.CronConcurrencyPolicy
+	// spec and labels of the workflow trigger, they are used to check whether the trigger needs to be recreated.
+	spec   v1alpha1.WorkflowTriggerSpec
+	labels map[string]string
+	// mutex makes sure WorkflowRuns are created one by one, so that the concurrency policy works.
+	mutex sync.Mutex
 }
 
 // CronTriggerManager represents manager for cron triggers.
@@ -94,6 +105,39 @@ func (t *CronTrigger) getKeyFromTrigger() string {
 
 // Run triggers the workflows.
 func (t *CronTrigger) Run() {
+	t.schedule(time.Now())
+}
+
+// schedule creates a WorkflowRun scheduled at the time according to the concurrency policy, and records the
+// result in status of the workflow trigger.
+func (t *CronTrigger) schedule(scheduledTime time.Time) {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+
+	skipped, err := t.handleConcurrency()
+	if err != nil {
+		log.WithField("wft", t.WorkflowTriggerName).Warn("Handle concurrency policy error: ", err)
+	}
+	if skipped {
+		log.WithField("wft", t.WorkflowTriggerName).Info("Skip schedule as there are WorkflowRuns still running")
+		t.updateStatus(scheduledTime, func(status *v1alpha1.CronTriggerStatus) {
+			status.SkipCount++
+		})
+		return
+	}
+
+	succeeded := t.createWorkflowRun()
+	t.updateStatus(scheduledTime, func(status *v1alpha1.CronTriggerStatus) {
+		if succeeded {
+			status.SuccCount++
+		} else {
+			status.FailCount++
+		}
+	})
+}
+
+// createWorkflowRun creates a WorkflowRun from the template, it returns whether the WorkflowRun is created.
+func (t *CronTrigger) createWorkflowRun() bool {
 	for {
 		t.WorkflowRun.Name = fmt.Sprintf("%s-%s", t.WorkflowTriggerName, rand.String(5))
 		t.WorkflowRun.Annotations[meta.AnnotationAlias] = t.WorkflowRun.Name
@@ -104,20 +148,117 @@ func (t *CronTrigger) Run() {
 			} else {
 				t.FailCount++
 				log.Warnf("can not create WorkflowRun: %s", err)
-				break
+				return false
 			}
 		} else {
 			t.SuccCount++
-			break
+			return true
 		}
 	}
 }
 
+// handleConcurrency handles WorkflowRuns created by the trigger that are still running, it returns whether the
+// new WorkflowRun should be skipped.
+func (t *CronTrigger) handleConcurrency() (bool, error) {
+	if t.ConcurrencyPolicy != v1alpha1.CronConcurrencyForbid && t.ConcurrencyPolicy != v1alpha1.CronConcurrencyReplace {
+		return false, nil
+	}
+
+	wfrs, err := t.Manage.Client.CycloneV1alpha1().WorkflowRuns(t.Namespace).List(context.TODO(), meta_v1.ListOptions{
+		LabelSelector: meta.TriggeredWorkflowRunSelector(t.WorkflowTriggerName),
+	})
+	if err != nil {
+		return false, err
+	}
+
+	var active []string
+	for _, wfr := range wfrs.Items {
+		if !util.IsWorkflowRunTerminated(&wfr) {
+			active = append(active, wfr.Name)
+		}
+	}
+	if len(active) == 0 {
+		return false, nil
+	}
+
+	if t.ConcurrencyPolicy == v1alpha1.CronConcurrencyForbid {
+		return true, nil
+	}
+
+	for _, name := range active {
+		log.WithField("wfr", name).Infof("Cancel WorkflowRun replaced by new schedule of %s", t.WorkflowTriggerName)
+		if err := t.cancel(name); err != nil {
+			log.WithField("wfr", name).Warn("Cancel replaced WorkflowRun error: ", err)
+		}
+	}
+	return false, nil
+}
+
+// cancel cancels a WorkflowRun that is not terminated yet.
+func (t *CronTrigger) cancel(wfr string) error {
+	client := t.Manage.Client.CycloneV1alpha1().WorkflowRuns(t.Namespace)
+	return retry.RetryOnConflict(retry.DefaultRetry, func() error {
+		latest, err := client.Get(context.TODO(), wfr, meta_v1.GetOptions{})
+		if err != nil {
+			if errors2.IsNotFound(err) {
+				return nil
+			}
+			return err
+		}
+
+		if util.IsWorkflowRunTerminated(latest) {
+			return nil
+		}
+
+		toUpdate := latest.DeepCopy()
+		toUpdate.Status.Overall = v1alpha1.Status{
+			Phase:              v1alpha1.StatusCancelled,
+			Reason:             v1alpha1.ReasonReplacedByCronSchedule,
+			Message:            fmt.Sprintf("Replaced by new schedule of WorkflowTrigger %s", t.WorkflowTriggerName),
+			LastTransitionTime: meta_v1.Time{Time: time.Now()},
+		}
+		_, err = client.Update(context.TODO(), toUpdate, meta_v1.UpdateOptions{})
+		return err
+	})
+}
+
+// updateStatus records the schedule in status of the workflow trigger.
+func (t *CronTrigger) updateStatus(scheduledTime time.Time, update func(status *v1alpha1.CronTriggerStatus)) {
+	client := t.Manage.Client.CycloneV1alpha1().WorkflowTriggers(t.Namespace)
+	err := retry.RetryOnConflict(retry.DefaultRetry, func() error {
+		latest, err := client.Get(context.TODO(), t.WorkflowTriggerName, meta_v1.GetOptions{})
+		if err != nil {
+			return err
+		}
+
+		toUpdate := latest.DeepCopy()
+		if toUpdate.Status.Cron == nil {
+			toUpdate.Status.Cron = &v1alpha1.CronTriggerStatus{}
+		}
+		lastScheduleTime := meta_v1.NewTime(scheduledTime)
+		toUpdate.Status.Cron.LastScheduleTime = &lastScheduleTime
+		update(toUpdate.Status.Cron)
+		toUpdate.Status.Count = toUpdate.Status.Cron.SuccCount
+		_, err = client.Update(context.TODO(), toUpdate, meta_v1.UpdateOptions{})
+		return err
+	})
+	if err != nil {
+		log.WithField("wft", t.WorkflowTriggerName).Warn("Update status error: ", err)
+	}
+}
+
 // CreateCron creates a cron trigger from workflow trigger, and add it to cron trigger manager.
 func (m *CronTriggerManager) CreateCron(wft *v1alpha1.WorkflowTrigger) {
 	ct := &CronTrigger{
 		Namespace:           wft.Namespace,
 		WorkflowTriggerName: wft.Name,
+		ConcurrencyPolicy:   wft.Spec.Cron.ConcurrencyPolicy,
+		spec:                *wft.Spec.DeepCopy(),
+		labels:              wft.Labels,
+	}
+	if wft.Status.Cron != nil {
+		ct.SuccCount = wft.Status.Cron.SuccCount
+		ct.FailCount = wft.Status.Cron.FailCount
 	}
 
 	wfr := &v1alpha1.WorkflowRun{
@@ -132,6 +273,13 @@ func (m *CronTriggerManager) CreateCron(wft *v1alpha1.WorkflowTrigger) {
 		Spec: wft.Spec.WorkflowRunSpec,
 	}
 
+	// Label WorkflowRuns with the trigger to find running ones for the concurrency policy.
+	if errs := validation.IsValidLabelValue(wft.Name); len(errs) == 0 {
+		wfr.ObjectMeta.Labels[meta.LabelWorkflowRunTrigger] = wft.Name
+	} else if ct.ConcurrencyPolicy == v1alpha1.CronConcurrencyForbid || ct.ConcurrencyPolicy == v1alpha1.CronConcurrencyReplace {
+		log.Warningf("concurrency policy of workflowtrigger %s will not work as its name is not a valid label value", wft.Name)
+	}
+
 	// If controller instance name is set, add label to the pod created.
 	if instance := os.Getenv(ccommon.ControllerInstanceEnvName); len(instance) != 0 {
 		wfr.ObjectMeta.Labels[meta.LabelControllerInstance] = instance
@@ -161,22 +309,13 @@ func (m *CronTriggerManager) CreateCron(wft *v1alpha1.WorkflowTrigger) {
 
 	ct.WorkflowRun = wfr
 
-	var schedule cron.Schedule
-	var err error
-	schedExpr := wft.Spec.Cron.Schedule
-	parts := strings.Fields(schedExpr)
-	if len(parts) == 5 {
-		schedule, err = cron.ParseStandard(schedExpr)
-	} else {
-		schedule, err = cron.Parse(schedExpr)
-	}
-
+	schedule, err := workflowtrigger.ParseSchedule(&wft.Spec.Cron)
 	if err != nil {
-		log.Errorf("can not parse cron expression %s: %s", schedExpr, err)
+		log.Errorf("can not parse schedule of workflowtrigger %s: %s", wft.Name, err)
 		return
 	}
 
-	c := cron.New()
+	c := cron.NewWithLocation(schedule.Location)
 	c.Schedule(schedule, ct)
 
 	ct.Cron = c
@@ -186,11 +325,30 @@ func (m *CronTriggerManager) CreateCron(wft *v1alpha1.WorkflowTrigger) {
 	if !wft.Spec.Disabled {
 		ct.Cron.Start()
 		ct.IsRunning = true
+
+		// Catch up the missed schedule, for example, when the controller was down. The last schedule time
+		// defaults to creation time of the trigger.
+		last := wft.CreationTimestamp.Time
+		if wft.Status.Cron != nil && wft.Status.Cron.LastScheduleTime != nil {
+			last = wft.Status.Cron.LastScheduleTime.Time
+		}
+		if missed := schedule.Missed(last, time.Now()); !missed.IsZero() {
+			log.Infof("catch up missed schedule at %s of workflowtrigger %s", missed, wft.Name)
+			go ct.schedule(missed)
+		}
 	}
 }
 
-// UpdateCron updates cron trigger based on workflow trigger.
+// UpdateCron updates cron trigger based on workflow trigger. The cron trigger is recreated only if spec or labels
+// of the workflow trigger changed, as status updates also get here.
 func (m *CronTriggerManager) UpdateCron(wft *v1alpha1.WorkflowTrigger) {
+	m.mutex.Lock()
+	ct, ok := m.CronTriggerMap[getKeyFromWorkflowTrigger(wft)]
+	m.mutex.Unlock()
+	if ok && reflect.DeepEqual(ct.spec, wft.Spec) && reflect.DeepEqual(ct.labels, wft.Labels) {
+		return
+	}
+
 	m.DeleteCron(wft)
 	m.CreateCron(wft)
 }
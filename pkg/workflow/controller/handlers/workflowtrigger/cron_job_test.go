@@ -0,0 +1,138 @@
+package workflowtrigger
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	corev1 "k8s.io/api/core/v1"
+	meta_v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/caicloud/cyclone/pkg/apis/cyclone/v1alpha1"
+	"github.com/caicloud/cyclone/pkg/k8s/clientset/fake"
+	"github.com/caicloud/cyclone/pkg/meta"
+)
+
+func newTestWorkflowRun(name string, phase v1alpha1.StatusPhase) *v1alpha1.WorkflowRun {
+	return &v1alpha1.WorkflowRun{
+		ObjectMeta: meta_v1.ObjectMeta{
+			Name:      name,
+			Namespace: "cyclone-system",
+			Labels:    map[string]string{meta.LabelWorkflowRunTrigger: "nightly"},
+		},
+		Status: v1alpha1.WorkflowRunStatus{
+			Overall: v1alpha1.Status{Phase: phase},
+		},
+	}
+}
+
+func TestSchedule(t *testing.T) {
+	wft := &v1alpha1.WorkflowTrigger{
+		ObjectMeta: meta_v1.ObjectMeta{Name: "nightly", Namespace: "cyclone-system"},
+		Spec: v1alpha1.WorkflowTriggerSpec{
+			Type: v1alpha1.TriggerTypeCron,
+			Cron: v1alpha1.CronTrigger{Schedule: "0 0 * * *"},
+			WorkflowRunSpec: v1alpha1.WorkflowRunSpec{
+				WorkflowRef: &corev1.ObjectReference{Name: "ci"},
+			},
+		},
+	}
+
+	cases := map[string]struct {
+		policy      v1alpha1.CronConcurrencyPolicy
+		status      v1alpha1.CronTriggerStatus
+		runningWfr  v1alpha1.StatusPhase
+		runningWfrs int
+	}{
+		"allow": {
+			policy:      v1alpha1.CronConcurrencyAllow,
+			status:      v1alpha1.CronTriggerStatus{SuccCount: 1},
+			runningWfr:  v1alpha1.StatusRunning,
+			runningWfrs: 2,
+		},
+		"forbid": {
+			policy:      v1alpha1.CronConcurrencyForbid,
+			status:      v1alpha1.CronTriggerStatus{SkipCount: 1},
+			runningWfr:  v1alpha1.StatusRunning,
+			runningWfrs: 1,
+		},
+		"replace": {
+			policy:      v1alpha1.CronConcurrencyReplace,
+			status:      v1alpha1.CronTriggerStatus{SuccCount: 1},
+			runningWfr:  v1alpha1.StatusCancelled,
+			runningWfrs: 1,
+		},
+	}
+
+	for name, c := range cases {
+		wft := wft.DeepCopy()
+		wft.Spec.Cron.ConcurrencyPolicy = c.policy
+		client := fake.NewSimpleClientset(wft, newTestWorkflowRun("nightly-running", v1alpha1.StatusRunning),
+			newTestWorkflowRun("nightly-done", v1alpha1.StatusSucceeded))
+		m := NewTriggerManager(client)
+		m.CreateCron(wft)
+		ct := m.CronTriggerMap[getKeyFromWorkflowTrigger(wft)]
+		assert.NotNil(t, ct, name)
+		ct.Cron.Stop()
+
+		scheduled := time.Date(2020, 3, 1, 0, 0, 0, 0, time.UTC)
+		ct.schedule(scheduled)
+
+		latest, err := client.CycloneV1alpha1().WorkflowTriggers(wft.Namespace).Get(context.TODO(), wft.Name, meta_v1.GetOptions{})
+		assert.Nil(t, err, name)
+		if assert.NotNil(t, latest.Status.Cron, name) {
+			assert.True(t, scheduled.Equal(latest.Status.Cron.LastScheduleTime.Time), name)
+			latest.Status.Cron.LastScheduleTime = nil
+			assert.Equal(t, c.status, *latest.Status.Cron, name)
+		}
+
+		running, err := client.CycloneV1alpha1().WorkflowRuns(wft.Namespace).Get(context.TODO(), "nightly-running", meta_v1.GetOptions{})
+		assert.Nil(t, err, name)
+		assert.Equal(t, c.runningWfr, running.Status.Overall.Phase, name)
+
+		wfrs, err := client.CycloneV1alpha1().WorkflowRuns(wft.Namespace).List(context.TODO(), meta_v1.ListOptions{
+			LabelSelector: meta.TriggeredWorkflowRunSelector(wft.Name),
+		})
+		assert.Nil(t, err, name)
+		var active int
+		for _, wfr := range wfrs.Items {
+			if wfr.Status.Overall.Phase == "" || wfr.Status.Overall.Phase == v1alpha1.StatusRunning {
+				active++
+			}
+		}
+		assert.Equal(t, c.runningWfrs, active, name)
+	}
+}
+
+func TestUpdateCron(t *testing.T) {
+	wft := &v1alpha1.WorkflowTrigger{
+		ObjectMeta: meta_v1.ObjectMeta{Name: "nightly", Namespace: "cyclone-system"},
+		Spec: v1alpha1.WorkflowTriggerSpec{
+			Type: v1alpha1.TriggerTypeCron,
+			Cron: v1alpha1.CronTrigger{Schedule: "0 0 * * *", Timezone: "UTC"},
+			WorkflowRunSpec: v1alpha1.WorkflowRunSpec{
+				WorkflowRef: &corev1.ObjectReference{Name: "ci"},
+			},
+		},
+	}
+	m := NewTriggerManager(fake.NewSimpleClientset(wft))
+	m.UpdateCron(wft)
+	ct := m.CronTriggerMap[getKeyFromWorkflowTrigger(wft)]
+	assert.NotNil(t, ct)
+	assert.Equal(t, time.UTC, ct.Cron.Location())
+
+	// Status changes don't recreate the cron trigger.
+	updated := wft.DeepCopy()
+	updated.Status.Cron = &v1alpha1.CronTriggerStatus{SuccCount: 1}
+	m.UpdateCron(updated)
+	assert.True(t, ct == m.CronTriggerMap[getKeyFromWorkflowTrigger(wft)])
+
+	updated.Spec.Cron.Schedule = "0 1 * * *"
+	m.UpdateCron(updated)
+	assert.False(t, ct == m.CronTriggerMap[getKeyFromWorkflowTrigger(wft)])
+	assert.False(t, ct.IsRunning)
+
+	m.DeleteCron(updated)
+	assert.Equal(t, 0, len(m.CronTriggerMap))
+}
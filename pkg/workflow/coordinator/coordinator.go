@@ -4,6 +4,7 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"io"
 	"io/ioutil"
 	"os"
 	"path"
@@ -17,6 +18,7 @@ import (
 	fileutil "github.com/caicloud/cyclone/pkg/util/file"
 	"github.com/caicloud/cyclone/pkg/util/k8s"
 	"github.com/caicloud/cyclone/pkg/workflow/common"
+	"github.com/caicloud/cyclone/pkg/workflow/coordinator/cycloneserver"
 	"github.com/caicloud/cyclone/pkg/workflow/coordinator/k8sapi"
 )
 
@@ -24,15 +26,20 @@ import (
 // will be used in workflow sidecar named coordinator.
 type Coordinator struct {
 	runtimeExec RuntimeExecutor
-	// workloadContainer represents name of the workload container.
-	workloadContainer string
+	// workloadContainers represents names of workload containers except service containers.
+	workloadContainers []string
+	// serviceContainers represents names of workload containers marked as services, they are killed with
+	// containers created for services of the stage when other workload containers finish.
+	serviceContainers []string
 	// Stage related to this pod.
 	Stage *v1alpha1.Stage
 	// Wfr represents the WorkflowRun which triggered this pod.
 	Wfr *v1alpha1.WorkflowRun
 	// OutputResources represents output resources the related stage configured.
 	OutputResources []*v1alpha1.Resource
-	ctx             context.Context
+	// serverClient is the client to upload artifacts to cyclone server.
+	serverClient cycloneserver.Client
+	ctx          context.Context
 }
 
 // RuntimeExecutor is an interface defined some methods
@@ -46,6 +53,8 @@ type RuntimeExecutor interface {
 	MarkLogEOF(workflowrun, stage string, close <-chan struct{}) error
 	// CopyFromContainer copy a file or directory from container:path to dst.
 	CopyFromContainer(container, path, dst string) error
+	// KillContainer kills a running container.
+	KillContainer(container string) error
 	// GetPod get the stage related pod.
 	GetPod() (*core_v1.Pod, error)
 	// SetResults set results (key-values) to the pod, workflow controller would sync this result
@@ -91,13 +100,24 @@ func NewCoordinator(ctx context.Context, client k8s.Interface) (*Coordinator, er
 		return nil, fmt.Errorf("unmarshal output resources info error %s", err)
 	}
 
+	workloadContainers := getWorkloadContainers()
+	if len(workloadContainers) == 0 {
+		return nil, fmt.Errorf("get workload containers from env failed")
+	}
+	var serviceContainers []string
+	if stage.Spec.Pod != nil {
+		serviceContainers = stage.Spec.Pod.ServiceContainers
+	}
+
 	return &Coordinator{
-		runtimeExec:       k8sapi.NewK8sapiExecutor(client, wfr.Namespace, getNamespace(), getPodName(), getCycloneServerAddr()),
-		workloadContainer: getWorkloadContainer(),
-		Stage:             stage,
-		Wfr:               wfr,
-		OutputResources:   rscs,
-		ctx:               ctx,
+		runtimeExec:        k8sapi.NewK8sapiExecutor(client, wfr.Namespace, getNamespace(), getPodName(), getCycloneServerAddr()),
+		workloadContainers: workloadContainers,
+		serviceContainers:  serviceContainers,
+		Stage:              stage,
+		Wfr:                wfr,
+		OutputResources:    rscs,
+		serverClient:       cycloneserver.NewClient(getCycloneServerAddr()),
+		ctx:                ctx,
 	}, nil
 }
 
@@ -134,15 +154,107 @@ func (co *Coordinator) WaitRunning() error {
 	return err
 }
 
-// WaitWorkloadTerminate waits all workload containers to be Terminated status.
+// WaitServicesReady waits containers created for services of the stage to be ready, and then creates the ready file
+// to release workload containers held by postStart hook of coordinator.
+//
+// If services fail to be ready, workload containers are still released but killed once they start. They can't be
+// kept from starting, kubelet starts the next containers even if postStart hook of coordinator fails.
+func (co *Coordinator) WaitServicesReady() error {
+	if co.Stage.Spec.Pod == nil || len(co.Stage.Spec.Pod.Services) == 0 {
+		return nil
+	}
+
+	if err := co.runtimeExec.WaitContainers(common.ContainerStateReady, common.OnlyServiceContainer); err != nil {
+		log.Errorf("Wait services to be ready error: %v", err)
+		if releaseWorkload() == nil {
+			co.abortWorkload()
+		}
+		return err
+	}
+
+	return releaseWorkload()
+}
+
+// releaseWorkload creates the ready file to release workload containers held by postStart hook of coordinator.
+func releaseWorkload() error {
+	f, err := os.Create(common.CoordinatorServicesReadyPath)
+	if err != nil {
+		log.WithField("file", common.CoordinatorServicesReadyPath).Error("Create services ready file error: ", err)
+		return err
+	}
+	return f.Close()
+}
+
+// abortWorkload waits workload containers to start and kills them, it's used when they can't work as expected,
+// for example services they depend on fail.
+func (co *Coordinator) abortWorkload() {
+	if err := co.runtimeExec.WaitContainers(common.ContainerStateInitialized, common.OnlyWorkload); err != nil {
+		log.Errorf("Wait workload containers to start error: %v", err)
+		return
+	}
+
+	if err := co.killContainers(common.OnlyWorkload); err != nil {
+		log.Errorf("Kill workload containers error: %v", err)
+	}
+}
+
+// WaitWorkloadTerminate waits all workload containers except service containers to be Terminated status.
 func (co *Coordinator) WaitWorkloadTerminate() error {
-	err := co.runtimeExec.WaitContainers(common.ContainerStateTerminated, common.OnlyWorkload)
+	err := co.runtimeExec.WaitContainers(common.ContainerStateTerminated, common.OnlyWorkload, co.nonService)
 	if err != nil {
 		log.Errorf("Wait containers to completion error: %v", err)
 	}
 	return err
 }
 
+// StopServices kills service containers that are still running, including containers created for services of
+// the stage and workload containers marked as services. It's called when other workload containers finish, so that
+// service containers won't block the stage.
+func (co *Coordinator) StopServices() error {
+	return co.killContainers(co.isService)
+}
+
+// killContainers kills selected containers that are still running.
+func (co *Coordinator) killContainers(selector common.ContainerSelector) error {
+	pod, err := co.runtimeExec.GetPod()
+	if err != nil {
+		return err
+	}
+
+	for _, cs := range pod.Status.ContainerStatuses {
+		if !selector(cs.Name) || cs.State.Terminated != nil {
+			continue
+		}
+
+		log.WithField("container", cs.Name).Info("Kill container")
+		if err := co.runtimeExec.KillContainer(refineContainerID(cs.ContainerID)); err != nil {
+			log.Errorf("Kill container %s failed: %v", cs.Name, err)
+			return err
+		}
+	}
+
+	return nil
+}
+
+// isService selects service containers, including containers created for services of the stage and workload
+// containers marked as services.
+func (co *Coordinator) isService(name string) bool {
+	if common.OnlyServiceContainer(name) {
+		return true
+	}
+	for _, s := range co.serviceContainers {
+		if s == name {
+			return true
+		}
+	}
+	return false
+}
+
+// nonService selects all containers except service containers.
+func (co *Coordinator) nonService(name string) bool {
+	return !co.isService(name)
+}
+
 // WaitAllOthersTerminate waits all containers except for
 // the coordinator container itself to become Terminated status.
 func (co *Coordinator) WaitAllOthersTerminate() error {
@@ -154,9 +266,9 @@ func (co *Coordinator) WaitAllOthersTerminate() error {
 	return err
 }
 
-// StageSuccess checks if the workload and resolver containers are succeeded.
+// StageSuccess checks if the workload and resolver containers are succeeded, service containers are excluded.
 func (co *Coordinator) StageSuccess() bool {
-	ws, err := co.GetExitCodes(common.NonCoordinator, common.NonWorkloadSidecar)
+	ws, err := co.GetExitCodes(common.NonCoordinator, common.NonWorkloadSidecar, co.nonService)
 	if err != nil {
 		log.Errorf("Get Exit Codes failed: %v", err)
 		return false
@@ -173,9 +285,9 @@ func (co *Coordinator) StageSuccess() bool {
 	return true
 }
 
-// WorkLoadSuccess checks if the workload containers are succeeded.
+// WorkLoadSuccess checks if all workload containers except service containers are succeeded.
 func (co *Coordinator) WorkLoadSuccess() bool {
-	ws, err := co.GetExitCodes(common.OnlyWorkload)
+	ws, err := co.GetExitCodes(common.OnlyWorkload, co.nonService)
 	if err != nil {
 		log.Errorf("Get Exit Codes failed: %v", err)
 		return false
@@ -215,7 +327,7 @@ func (co *Coordinator) GetExitCodes(selectors ...common.ContainerSelector) (map[
 	return ws, nil
 }
 
-// CollectArtifacts collects workload artifacts.
+// CollectArtifacts collects workload artifacts, each artifact is copied from the workload container that produces it.
 func (co *Coordinator) CollectArtifacts() error {
 	if co.Stage.Spec.Pod == nil {
 		return fmt.Errorf("get stage output artifacts failed, stage pod nil")
@@ -236,17 +348,49 @@ func (co *Coordinator) CollectArtifacts() error {
 		dst := path.Join(common.CoordinatorArtifactsPath, artifact.Name)
 		fileutil.CreateDirectory(dst)
 
-		id, err := co.getContainerID(co.workloadContainer)
+		container := artifact.Container
+		if container == "" {
+			container = co.workloadContainers[0]
+		}
+		id, err := co.getContainerID(container)
 		if err != nil {
-			log.Errorf("get container %s's id failed: %v", co.workloadContainer, err)
+			log.Errorf("get container %s's id failed: %v", container, err)
 			return err
 		}
 
 		err = co.runtimeExec.CopyFromContainer(id, artifact.Path, dst)
 		if err != nil {
-			log.Errorf("Copy container %s artifact %s failed: %v", co.workloadContainer, artifact.Name, err)
+			log.Errorf("Copy container %s artifact %s failed: %v", container, artifact.Name, err)
+			return err
+		}
+	}
+
+	return nil
+}
+
+// UploadArtifacts packs each output artifact collected by CollectArtifacts into a tar file, and uploads it to
+// cyclone server. It's only needed when there is no PVC to share artifacts between stages, then downstream
+// stages download artifacts from cyclone server.
+func (co *Coordinator) UploadArtifacts() error {
+	if os.Getenv(common.EnvUploadArtifacts) != "true" {
+		return nil
+	}
+
+	token := os.Getenv(common.EnvArtifactToken)
+	for _, artifact := range co.Stage.Spec.Pod.Outputs.Artifacts {
+		file := common.ArtifactArchiveName(artifact.Name)
+		reader, writer := io.Pipe()
+		go func(dir string) {
+			writer.CloseWithError(fileutil.Tar(writer, dir))
+		}(path.Join(common.CoordinatorArtifactsPath, artifact.Name))
+
+		err := co.serverClient.UploadArtifact(co.Wfr.Namespace, co.Wfr.Name, co.Stage.Name, token, file, reader)
+		reader.Close()
+		if err != nil {
+			log.Errorf("Upload artifact %s failed: %v", artifact.Name, err)
 			return err
 		}
+		log.WithField("artifact", artifact.Name).Info("artifact uploaded")
 	}
 
 	return nil
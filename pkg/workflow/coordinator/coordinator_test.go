@@ -0,0 +1,145 @@
+package coordinator
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	core_v1 "k8s.io/api/core/v1"
+
+	"github.com/caicloud/cyclone/pkg/apis/cyclone/v1alpha1"
+	"github.com/caicloud/cyclone/pkg/workflow/common"
+)
+
+// fakeExecutor is a RuntimeExecutor with a static pod, killed containers are recorded.
+type fakeExecutor struct {
+	pod    *core_v1.Pod
+	killed []string
+}
+
+func (e *fakeExecutor) WaitContainers(state common.ContainerState, selectors ...common.ContainerSelector) error {
+	return nil
+}
+
+func (e *fakeExecutor) CollectLog(container, workflowrun, stage string, close <-chan struct{}) error {
+	return nil
+}
+
+func (e *fakeExecutor) MarkLogEOF(workflowrun, stage string, close <-chan struct{}) error {
+	return nil
+}
+
+func (e *fakeExecutor) CopyFromContainer(container, path, dst string) error {
+	return nil
+}
+
+func (e *fakeExecutor) KillContainer(container string) error {
+	e.killed = append(e.killed, container)
+	return nil
+}
+
+func (e *fakeExecutor) GetPod() (*core_v1.Pod, error) {
+	return e.pod, nil
+}
+
+func (e *fakeExecutor) SetResults(values []v1alpha1.KeyValue) error {
+	return nil
+}
+
+// containerStatus builds status of a container, it's running if exit code is nil.
+func containerStatus(name string, exitCode *int32) core_v1.ContainerStatus {
+	status := core_v1.ContainerStatus{
+		Name:        name,
+		ContainerID: "docker://" + name,
+		State: core_v1.ContainerState{
+			Running: &core_v1.ContainerStateRunning{},
+		},
+	}
+	if exitCode != nil {
+		status.State = core_v1.ContainerState{
+			Terminated: &core_v1.ContainerStateTerminated{ExitCode: *exitCode},
+		}
+	}
+	return status
+}
+
+func exitCode(code int32) *int32 {
+	return &code
+}
+
+// newTestCoordinator creates a coordinator of a stage with workload containers 'build' and 'test', workload
+// container 'app' marked as a service, and service 'db'.
+func newTestCoordinator(statuses map[string]*int32) (*Coordinator, *fakeExecutor) {
+	pod := &core_v1.Pod{}
+	for _, name := range []string{"svc-db", common.CoordinatorSidecarName, "app", "build", "test", "wsc-proxy", "csc-out1"} {
+		pod.Spec.Containers = append(pod.Spec.Containers, core_v1.Container{Name: name})
+		pod.Status.ContainerStatuses = append(pod.Status.ContainerStatuses, containerStatus(name, statuses[name]))
+	}
+	executor := &fakeExecutor{pod: pod}
+	return &Coordinator{
+		runtimeExec:        executor,
+		workloadContainers: []string{"build", "test"},
+		serviceContainers:  []string{"app"},
+		Stage: &v1alpha1.Stage{
+			Spec: v1alpha1.StageSpec{
+				Pod: &v1alpha1.PodWorkload{
+					ServiceContainers: []string{"app"},
+				},
+			},
+		},
+	}, executor
+}
+
+func TestWorkloadAndStageSuccess(t *testing.T) {
+	testCases := map[string]struct {
+		statuses map[string]*int32
+		workload bool
+		stage    bool
+	}{
+		"all succeeded, services killed": {
+			statuses: map[string]*int32{
+				"svc-db": exitCode(137), "app": exitCode(137), "build": exitCode(0), "test": exitCode(0),
+				"wsc-proxy": exitCode(0), "csc-out1": exitCode(0),
+			},
+			workload: true,
+			stage:    true,
+		},
+		"one of workload containers failed": {
+			statuses: map[string]*int32{
+				"svc-db": exitCode(137), "app": exitCode(137), "build": exitCode(0), "test": exitCode(1),
+				"wsc-proxy": exitCode(0), "csc-out1": exitCode(0),
+			},
+			workload: false,
+			stage:    false,
+		},
+		"output resolver failed": {
+			statuses: map[string]*int32{
+				"svc-db": exitCode(137), "app": exitCode(137), "build": exitCode(0), "test": exitCode(0),
+				"wsc-proxy": exitCode(0), "csc-out1": exitCode(2),
+			},
+			workload: true,
+			stage:    false,
+		},
+		"workload sidecar failed": {
+			statuses: map[string]*int32{
+				"svc-db": exitCode(137), "app": exitCode(137), "build": exitCode(0), "test": exitCode(0),
+				"wsc-proxy": exitCode(1), "csc-out1": exitCode(0),
+			},
+			workload: true,
+			stage:    true,
+		},
+	}
+
+	for d, tc := range testCases {
+		co, _ := newTestCoordinator(tc.statuses)
+		assert.Equal(t, tc.workload, co.WorkLoadSuccess(), d)
+		assert.Equal(t, tc.stage, co.StageSuccess(), d)
+	}
+}
+
+func TestStopServices(t *testing.T) {
+	co, executor := newTestCoordinator(map[string]*int32{
+		"build": exitCode(0), "test": exitCode(0), "csc-out1": exitCode(0),
+	})
+	assert.Nil(t, co.StopServices())
+	assert.Equal(t, []string{"svc-db", "app"}, executor.killed)
+}
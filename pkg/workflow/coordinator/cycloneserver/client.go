@@ -3,6 +3,8 @@ package cycloneserver
 import (
 	"fmt"
 	"io"
+	"io/ioutil"
+	"mime/multipart"
 	"net/http"
 	"net/url"
 	"strings"
@@ -14,11 +16,17 @@ const (
 	cycloneAPIVersion = "/apis/v1alpha1"
 
 	apiPathForLogStream = "/workflowruns/%s/streamlogs"
+
+	apiPathForArtifacts = "/workflowruns/%s/artifacts"
+
+	// artifactFormFileKey is the form file key to upload artifacts
+	artifactFormFileKey = "file"
 )
 
 // Client ...
 type Client interface {
 	PushLogStream(ns, workflowrun, stage, container string, reader io.Reader, close <-chan struct{}) error
+	UploadArtifact(ns, workflowrun, stage, token, file string, reader io.Reader) error
 }
 
 type client struct {
@@ -53,3 +61,42 @@ func (c *client) PushLogStream(ns, workflowrun, stage, container string, reader
 
 	return websocketutil.SendStream(requestURL.String(), reader, close)
 }
+
+// UploadArtifact uploads the content from reader as an artifact file of the stage, the request is authenticated
+// by the artifact token of the workflowrun.
+func (c *client) UploadArtifact(ns, workflowrun, stage, token, file string, reader io.Reader) error {
+	requestURL := fmt.Sprintf("%s%s?namespace=%s&stage=%s", c.baseURL, cycloneAPIVersion+fmt.Sprintf(apiPathForArtifacts, workflowrun),
+		url.QueryEscape(ns), url.QueryEscape(stage))
+
+	// Stream the multipart body, artifacts may be too large to be buffered.
+	body, writer := io.Pipe()
+	form := multipart.NewWriter(writer)
+	go func() {
+		part, err := form.CreateFormFile(artifactFormFileKey, file)
+		if err == nil {
+			_, err = io.Copy(part, reader)
+		}
+		if err == nil {
+			err = form.Close()
+		}
+		writer.CloseWithError(err)
+	}()
+
+	req, err := http.NewRequest(http.MethodPost, requestURL, body)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", form.FormDataContentType())
+	req.Header.Set("Authorization", "Bearer "+token)
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode/100 != 2 {
+		message, _ := ioutil.ReadAll(resp.Body)
+		return fmt.Errorf("upload artifact %s error, status code: %d, message: %s", file, resp.StatusCode, message)
+	}
+	return nil
+}
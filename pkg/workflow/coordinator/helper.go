@@ -11,8 +11,20 @@ func getPodName() string {
 	return os.Getenv(common.EnvStagePodName)
 }
 
-func getWorkloadContainer() string {
-	return os.Getenv(common.EnvWorkloadContainerName)
+func getWorkloadContainers() []string {
+	value, ok := os.LookupEnv(common.EnvWorkloadContainerNames)
+	if !ok {
+		// Pods created by workflow controller of old versions have only one workload container.
+		value = os.Getenv(common.EnvWorkloadContainerName)
+	}
+
+	var names []string
+	for _, name := range strings.Split(value, ",") {
+		if name != "" {
+			names = append(names, name)
+		}
+	}
+	return names
 }
 
 func getCycloneServerAddr() string {
@@ -75,13 +75,21 @@ func (k *Executor) WaitContainers(expectState common.ContainerState, selectors .
 					log.WithField("container", c.Name).WithField("expected", expectState).Debugf("Container not expected status")
 					reachGoals = false
 				}
+			case common.ContainerStateReady:
+				if s != nil && s.State.Terminated != nil {
+					return fmt.Errorf("container %s terminated before ready", c.Name)
+				}
+				if s == nil || !s.Ready {
+					log.WithField("container", c.Name).WithField("expected", expectState).Debugf("Container not in expected status")
+					reachGoals = false
+				}
 			case common.ContainerStateInitialized:
 				if s == nil || (s.State.Running == nil && s.State.Terminated == nil) {
 					log.WithField("container", c.Name).WithField("expected", expectState).Debugf("Container not in expected status")
 					reachGoals = false
 				}
 			default:
-				return fmt.Errorf("Unsupported state: %s, Only support: %s, %s", expectState, common.ContainerStateTerminated, common.ContainerStateInitialized)
+				return fmt.Errorf("Unsupported state: %s, Only support: %s, %s, %s", expectState, common.ContainerStateTerminated, common.ContainerStateInitialized, common.ContainerStateReady)
 			}
 		}
 
@@ -147,6 +155,25 @@ func (k *Executor) CopyFromContainer(container, path, dst string) error {
 	return nil
 }
 
+// KillContainer kills a running container. It's not an error if the container has stopped.
+func (k *Executor) KillContainer(container string) error {
+	args := []string{"kill", container}
+
+	cmd := exec.Command("docker", args...)
+	log.WithField("args", args).Info()
+	ret, err := cmd.CombinedOutput()
+	if err != nil {
+		// The container may exit after its status is got.
+		if strings.Contains(string(ret), "is not running") {
+			log.WithField("container", container).Info("container is not running")
+			return nil
+		}
+		return fmt.Errorf("%s, error: %v", string(ret), err)
+	}
+
+	return nil
+}
+
 // SetResults sets execution results (key-values) to the pod, workflow controller will sync this result to WorkflowRun status.
 func (k *Executor) SetResults(values []v1alpha1.KeyValue) error {
 	return retry.RetryOnConflict(retry.DefaultRetry, func() error {
@@ -15,15 +15,17 @@ type Processor struct {
 	wfr              *v1alpha1.WorkflowRun
 	secretRefValue   *SecretRefValue
 	variableRefValue *VariableRefValue
+	stageRefValue    *StageRefValue
 	secretGetter     SecretGetter
 }
 
-// NewProcessor creates a processor object
-func NewProcessor(wfr *v1alpha1.WorkflowRun, getter SecretGetter) *Processor {
+// NewProcessor creates a processor object, 'stage' is the stage whose values would be resolved.
+func NewProcessor(wfr *v1alpha1.WorkflowRun, stage string, getter SecretGetter) *Processor {
 	return &Processor{
 		wfr:              wfr,
 		secretRefValue:   NewSecretRefValue(),
 		variableRefValue: NewVariableRefValue(wfr),
+		stageRefValue:    NewStageRefValue(wfr, stage),
 		secretGetter:     getter,
 	}
 }
@@ -47,8 +49,12 @@ func (p *Processor) ResolveRefStringValue(ref string) (string, error) {
 		if err != nil {
 			return ref, err
 		}
+	} else if err = p.stageRefValue.Parse(ref); err == nil {
+		value, err = p.stageRefValue.Resolve()
+		if err != nil {
+			return ref, err
+		}
 	} else {
-		// TODO(ChenDe): implement ${stages.<stage>.outputs.<key>} type ref value
 		return ref, nil
 	}
 
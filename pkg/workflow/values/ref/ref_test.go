@@ -54,6 +54,19 @@ func (suite *RefSuite) SetupSuite() {
 				},
 			},
 		},
+		Status: v1alpha1.WorkflowRunStatus{
+			Stages: map[string]*v1alpha1.StageStatus{
+				"build": {
+					Outputs: []v1alpha1.KeyValue{{
+						Key:   "DIGEST",
+						Value: "sha256:1dc7a7d",
+					}},
+				},
+				"deploy": {
+					Depends: []string{"build"},
+				},
+			},
+		},
 	}
 }
 
@@ -63,7 +76,7 @@ func (suite *RefSuite) TestResolveRefStringValue() {
 	secretGetter := func(ns, name string) (*corev1.Secret, error) {
 		return suite.client.CoreV1().Secrets(ns).Get(context.TODO(), name, metav1.GetOptions{})
 	}
-	processor := NewProcessor(suite.wfr, secretGetter)
+	processor := NewProcessor(suite.wfr, "deploy", secretGetter)
 
 	v, err := processor.ResolveRefStringValue("")
 	assert.Nil(err)
@@ -106,6 +119,14 @@ func (suite *RefSuite) TestResolveRefStringValue() {
 	assert.NotNil(v)
 	assert.Nil(err)
 	assert.Equal("cyclone", v)
+
+	v, err = processor.ResolveRefStringValue("${stages.build.outputs.DIGEST}")
+	assert.Nil(err)
+	assert.Equal("sha256:1dc7a7d", v)
+
+	v, err = processor.ResolveRefStringValue("${stages.deploy.outputs.DIGEST}")
+	assert.NotNil(v)
+	assert.Error(err)
 }
 
 func TestRefSuite(t *testing.T) {
@@ -1,18 +1,123 @@
 package ref
 
-/*
- Need to implement
-*/
+import (
+	"fmt"
+	"regexp"
+	"strings"
 
-/*
-import "regexp"
+	"github.com/caicloud/cyclone/pkg/apis/cyclone/v1alpha1"
+)
 
 var (
-	stageRegexpString = `^\${stages.([-_\w]+).outputs.([-_\w]+)}$`
+	stageRegexpString = `^\${stages.([-_\w]+).outputs.([-_\w\.]+)}$`
 	stageRegexp       = regexp.MustCompile(stageRegexpString)
 )
 
 const (
 	stageTypeRefFormat = `${stages.<stage>.outputs.<key>}`
 )
-*/
+
+// StageRefValue represents a key-value output of an upstream stage. Outputs are collected by the coordinator
+// and recorded in wfr status.stages.<stage>.outputs.
+type StageRefValue struct {
+	wfr *v1alpha1.WorkflowRun
+	// current is the stage that refers to the value, the referred stage must be its ancestor.
+	current string
+	// Stage is name of the stage that produces the output
+	Stage string
+	// Key of the output
+	Key string
+}
+
+// NewStageRefValue create a stage reference value. 'stage' is the stage that consumes the value.
+func NewStageRefValue(wfr *v1alpha1.WorkflowRun, stage string) *StageRefValue {
+	return &StageRefValue{
+		wfr:     wfr,
+		current: stage,
+	}
+}
+
+// Parse parses a given ref. The reference value specifies an output key of a stage. Format of the reference is:
+// ${stages.<stage>.outputs.<key>}
+//
+// For example, in wfr status:
+// {
+//   "kind": "WorkflowRun",
+//   ...
+//   "status": {
+//     "stages": {
+//       "build": {
+//         "outputs": [
+//           {
+//             "key": "DIGEST",
+//             "value": "sha256:1dc7a7d..."
+//           }
+//         ]
+//       }
+//     }
+//   }
+// }
+// ${stages.build.outputs.DIGEST}  --> sha256:1dc7a7d...
+func (r *StageRefValue) Parse(ref string) error {
+	trimed := strings.TrimSpace(ref)
+	results := stageRegexp.FindStringSubmatch(trimed)
+	if len(results) < 3 {
+		return fmt.Errorf("stage type ref must be specified as %s, but got '%s'", stageTypeRefFormat, ref)
+	}
+
+	r.Stage = results[1]
+	r.Key = results[2]
+	return nil
+}
+
+// Resolve resolves the stage ref and get the real value. The referred stage must be an ancestor of
+// the current stage, otherwise it's not guaranteed to have finished when the current stage runs.
+func (r *StageRefValue) Resolve() (string, error) {
+	if r.wfr == nil {
+		return "", fmt.Errorf("wfr is nil")
+	}
+
+	if !IsAncestor(r.wfr, r.Stage, r.current) {
+		return "", fmt.Errorf("stage '%s' is not an ancestor of stage '%s', its outputs can't be referred", r.Stage, r.current)
+	}
+
+	status, ok := r.wfr.Status.Stages[r.Stage]
+	if !ok || status == nil {
+		return "", fmt.Errorf("status of stage %s not found in wfr", r.Stage)
+	}
+
+	for _, output := range status.Outputs {
+		if output.Key == r.Key {
+			return output.Value, nil
+		}
+	}
+
+	return "", fmt.Errorf("not found output %s of stage %s in wfr", r.Key, r.Stage)
+}
+
+// IsAncestor checks whether stage 'ancestor' is a direct or indirect dependency of stage 'stage'. The stage
+// topology recorded in wfr status is used.
+func IsAncestor(wfr *v1alpha1.WorkflowRun, ancestor, stage string) bool {
+	visited := make(map[string]bool)
+	queue := []string{stage}
+	for len(queue) > 0 {
+		current := queue[0]
+		queue = queue[1:]
+
+		status, ok := wfr.Status.Stages[current]
+		if !ok || status == nil {
+			continue
+		}
+		for _, d := range status.Depends {
+			if d == ancestor {
+				return true
+			}
+			if !visited[d] {
+				visited[d] = true
+				queue = append(queue, d)
+			}
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,103 @@
+package ref
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/suite"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/caicloud/cyclone/pkg/apis/cyclone/v1alpha1"
+)
+
+type StageSuite struct {
+	suite.Suite
+	wfr *v1alpha1.WorkflowRun
+}
+
+func (suite *StageSuite) SetupSuite() {
+	suite.wfr = &v1alpha1.WorkflowRun{
+		ObjectMeta: metav1.ObjectMeta{
+			Name: "test-wfr",
+		},
+		Status: v1alpha1.WorkflowRunStatus{
+			Stages: map[string]*v1alpha1.StageStatus{
+				"build": {
+					Outputs: []v1alpha1.KeyValue{{
+						Key:   "DIGEST",
+						Value: "sha256:1dc7a7d",
+					}},
+				},
+				"test": {
+					Depends: []string{"build"},
+				},
+				"lint": {},
+				"deploy": {
+					Depends: []string{"test", "lint"},
+				},
+			},
+		},
+	}
+}
+
+func (suite *StageSuite) TestParse() {
+	assert := assert.New(suite.T())
+	refValue := NewStageRefValue(suite.wfr, "deploy")
+	assert.Error(refValue.Parse(""))
+	assert.Error(refValue.Parse("stages."))
+	assert.Error(refValue.Parse("${stages.build}"))
+	assert.Error(refValue.Parse("${stages.build.outputs}"))
+	assert.Error(refValue.Parse("${stages.build.inputs.DIGEST}"))
+
+	assert.Nil(refValue.Parse("${stages.build.outputs.DIGEST}"))
+	assert.Equal("build", refValue.Stage)
+	assert.Equal("DIGEST", refValue.Key)
+}
+
+func (suite *StageSuite) TestResolve() {
+	assert := assert.New(suite.T())
+	refValue := NewStageRefValue(suite.wfr, "deploy")
+	assert.Nil(refValue.Parse("${stages.build.outputs.DIGEST}"))
+	v, err := refValue.Resolve()
+	assert.Nil(err)
+	assert.Equal("sha256:1dc7a7d", v)
+
+	assert.Nil(refValue.Parse("${stages.build.outputs.TAG}"))
+	v, err = refValue.Resolve()
+	assert.Empty(v)
+	assert.Error(err)
+
+	// 'lint' is not an ancestor of 'test'
+	refValue = NewStageRefValue(suite.wfr, "test")
+	assert.Nil(refValue.Parse("${stages.lint.outputs.DIGEST}"))
+	v, err = refValue.Resolve()
+	assert.Empty(v)
+	assert.Error(err)
+
+	// Stage can't refer to its own outputs
+	refValue = NewStageRefValue(suite.wfr, "build")
+	assert.Nil(refValue.Parse("${stages.build.outputs.DIGEST}"))
+	v, err = refValue.Resolve()
+	assert.Empty(v)
+	assert.Error(err)
+
+	refValueNil := NewStageRefValue(nil, "deploy")
+	assert.Nil(refValueNil.Parse("${stages.build.outputs.DIGEST}"))
+	v, err = refValueNil.Resolve()
+	assert.Empty(v)
+	assert.Error(err)
+}
+
+func (suite *StageSuite) TestIsAncestor() {
+	assert := assert.New(suite.T())
+	assert.True(IsAncestor(suite.wfr, "build", "test"))
+	assert.True(IsAncestor(suite.wfr, "build", "deploy"))
+	assert.True(IsAncestor(suite.wfr, "lint", "deploy"))
+	assert.False(IsAncestor(suite.wfr, "deploy", "build"))
+	assert.False(IsAncestor(suite.wfr, "lint", "test"))
+	assert.False(IsAncestor(suite.wfr, "build", "build"))
+}
+
+func TestStageSuite(t *testing.T) {
+	suite.Run(t, new(StageSuite))
+}
@@ -0,0 +1,102 @@
+package workflowrun
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"text/template"
+
+	"github.com/caicloud/cyclone/pkg/apis/cyclone/v1alpha1"
+	"github.com/caicloud/cyclone/pkg/meta"
+)
+
+// concurrencyData is data to render concurrency group templates.
+type concurrencyData struct {
+	// Namespace of the WorkflowRun
+	Namespace string
+	// Project of the WorkflowRun
+	Project string
+	// Workflow of the WorkflowRun
+	Workflow string
+	// WorkflowRun name
+	WorkflowRun string
+	// Trigger is the trigger event type of the WorkflowRun, it's empty for manually created WorkflowRuns
+	Trigger string
+	// Event is the SCM event data that triggered the WorkflowRun, for example, 'Repo', 'Ref', 'Branch'
+	Event map[string]string
+	// Variables are global variables of the WorkflowRun
+	Variables map[string]string
+}
+
+// ResolveConcurrencyGroup resolves concurrency group of the WorkflowRun, concurrency config in the WorkflowRun
+// overrides that in the Workflow. It returns nil if concurrency is not configured or the rendered group is empty.
+func ResolveConcurrencyGroup(wf *v1alpha1.Workflow, wfr *v1alpha1.WorkflowRun) (*ConcurrencyGroup, error) {
+	concurrency := wfr.Spec.Concurrency
+	if concurrency == nil && wf != nil {
+		concurrency = wf.Spec.Concurrency
+	}
+	if concurrency == nil || concurrency.Group == "" {
+		return nil, nil
+	}
+
+	switch concurrency.Mode {
+	case "", v1alpha1.ConcurrencyQueue, v1alpha1.ConcurrencyCancelInProgress, v1alpha1.ConcurrencySkipIfRunning:
+	default:
+		return nil, fmt.Errorf("unsupported concurrency mode %s", concurrency.Mode)
+	}
+
+	t, err := template.New("concurrency").Option("missingkey=zero").Parse(concurrency.Group)
+	if err != nil {
+		return nil, fmt.Errorf("parse concurrency group error: %v", err)
+	}
+
+	var buf bytes.Buffer
+	if err := t.Execute(&buf, newConcurrencyData(wfr)); err != nil {
+		return nil, fmt.Errorf("render concurrency group error: %v", err)
+	}
+
+	name := strings.TrimSpace(buf.String())
+	if name == "" {
+		return nil, nil
+	}
+
+	mode := concurrency.Mode
+	if mode == "" {
+		mode = v1alpha1.ConcurrencyQueue
+	}
+
+	return &ConcurrencyGroup{Name: name, Mode: mode}, nil
+}
+
+func newConcurrencyData(wfr *v1alpha1.WorkflowRun) *concurrencyData {
+	data := &concurrencyData{
+		Namespace:   wfr.Namespace,
+		Project:     wfr.Labels[meta.LabelProjectName],
+		WorkflowRun: wfr.Name,
+		Trigger:     wfr.Annotations[meta.AnnotationWorkflowRunTrigger],
+		Event:       make(map[string]string),
+		Variables:   make(map[string]string),
+	}
+	if wfr.Spec.WorkflowRef != nil {
+		data.Workflow = wfr.Spec.WorkflowRef.Name
+	}
+
+	// Only string fields of the SCM event are exposed, such as repo, ref and branch.
+	if s, ok := wfr.Annotations[meta.AnnotationWorkflowRunSCMEvent]; ok {
+		event := make(map[string]interface{})
+		if err := json.Unmarshal([]byte(s), &event); err == nil {
+			for k, v := range event {
+				if str, ok := v.(string); ok {
+					data.Event[k] = str
+				}
+			}
+		}
+	}
+
+	for _, v := range wfr.Spec.GlobalVariables {
+		data.Variables[v.Name] = v.Value
+	}
+
+	return data
+}
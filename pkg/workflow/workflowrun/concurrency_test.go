@@ -0,0 +1,63 @@
+package workflowrun
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/caicloud/cyclone/pkg/apis/cyclone/v1alpha1"
+	"github.com/caicloud/cyclone/pkg/meta"
+)
+
+func TestResolveConcurrencyGroup(t *testing.T) {
+	wf := &v1alpha1.Workflow{
+		Spec: v1alpha1.WorkflowSpec{
+			Concurrency: &v1alpha1.Concurrency{
+				Group: "{{ .Workflow }}-{{ .Event.Repo }}-{{ .Event.Branch }}",
+			},
+		},
+	}
+	wfr := &v1alpha1.WorkflowRun{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "wfr",
+			Namespace: "ns",
+			Annotations: map[string]string{
+				meta.AnnotationWorkflowRunSCMEvent: `{"Type":"Push","Repo":"caicloud/cyclone","Branch":"master","ChangedFiles":["a"]}`,
+			},
+		},
+		Spec: v1alpha1.WorkflowRunSpec{
+			WorkflowRef: &corev1.ObjectReference{Name: "wf"},
+		},
+	}
+
+	group, err := ResolveConcurrencyGroup(nil, wfr)
+	assert.Nil(t, err)
+	assert.Nil(t, group)
+
+	group, err = ResolveConcurrencyGroup(wf, wfr)
+	assert.Nil(t, err)
+	assert.Equal(t, &ConcurrencyGroup{Name: "wf-caicloud/cyclone-master", Mode: v1alpha1.ConcurrencyQueue}, group)
+
+	wfr.Spec.Concurrency = &v1alpha1.Concurrency{
+		Group: "{{ .Variables.PR }}",
+		Mode:  v1alpha1.ConcurrencyCancelInProgress,
+	}
+	group, err = ResolveConcurrencyGroup(wf, wfr)
+	assert.Nil(t, err)
+	assert.Nil(t, group)
+
+	wfr.Spec.GlobalVariables = []v1alpha1.GlobalVariable{{Name: "PR", Value: "12"}}
+	group, err = ResolveConcurrencyGroup(wf, wfr)
+	assert.Nil(t, err)
+	assert.Equal(t, &ConcurrencyGroup{Name: "12", Mode: v1alpha1.ConcurrencyCancelInProgress}, group)
+
+	wfr.Spec.Concurrency.Mode = "unknown"
+	_, err = ResolveConcurrencyGroup(wf, wfr)
+	assert.NotNil(t, err)
+
+	wfr.Spec.Concurrency = &v1alpha1.Concurrency{Group: "{{ .Invalid"}
+	_, err = ResolveConcurrencyGroup(wf, wfr)
+	assert.NotNil(t, err)
+}
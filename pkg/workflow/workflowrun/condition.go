@@ -0,0 +1,106 @@
+package workflowrun
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/PaesslerAG/gval"
+	log "github.com/sirupsen/logrus"
+
+	"github.com/caicloud/cyclone/pkg/apis/cyclone/v1alpha1"
+	"github.com/caicloud/cyclone/pkg/meta"
+)
+
+const (
+	// scmTagRefPrefix is prefix of git tag references, for example, 'refs/tags/v1.0'
+	scmTagRefPrefix = "refs/tags/"
+	// scmTagReleaseEventType is the event type of tag release in SCM event data.
+	scmTagReleaseEventType = "scm-tag-release"
+)
+
+// scmEventData is SCM event data that stored in WorkflowRun annotations, only fields needed by
+// condition evaluation are decoded here.
+type scmEventData struct {
+	Type      string
+	Repo      string
+	Ref       string
+	Branch    string
+	Comment   string
+	CommitSHA string
+}
+
+// EvaluateCondition evaluates the 'when' condition of a stage against the WorkflowRun. Parameters
+// can be used in the expression are:
+// - variables.<name>: global variables of the WorkflowRun
+// - scm.type, scm.repo, scm.ref, scm.branch, scm.tag, scm.comment, scm.commitSHA: SCM event data
+//   that triggered the WorkflowRun, they are empty strings if the WorkflowRun isn't triggered by SCM
+// - stages.<stage>.phase, stages.<stage>.outputs.<key>: status and outputs of stages
+//
+// Empty condition is always evaluated to true.
+func EvaluateCondition(wfr *v1alpha1.WorkflowRun, when string) (bool, error) {
+	if len(strings.TrimSpace(when)) == 0 {
+		return true, nil
+	}
+
+	value, err := gval.Evaluate(when, conditionParameters(wfr), gval.Full())
+	if err != nil {
+		return false, fmt.Errorf("evaluate condition '%s' error: %v", when, err)
+	}
+
+	result, ok := value.(bool)
+	if !ok {
+		return false, fmt.Errorf("condition '%s' should be evaluated to a boolean value, but got: %v", when, value)
+	}
+
+	return result, nil
+}
+
+// conditionParameters builds parameters from WorkflowRun used to evaluate stage conditions.
+func conditionParameters(wfr *v1alpha1.WorkflowRun) map[string]interface{} {
+	variables := make(map[string]interface{})
+	for _, v := range wfr.Spec.GlobalVariables {
+		variables[v.Name] = v.Value
+	}
+
+	event := &scmEventData{}
+	if data, ok := wfr.Annotations[meta.AnnotationWorkflowRunSCMEvent]; ok {
+		if err := json.Unmarshal([]byte(data), event); err != nil {
+			log.WithField("wfr", wfr.Name).Warn("Unmarshal SCM event data error: ", err)
+		}
+	}
+	var tag string
+	if event.Type == scmTagReleaseEventType {
+		tag = strings.TrimPrefix(event.Ref, scmTagRefPrefix)
+	}
+	scm := map[string]interface{}{
+		"type":      event.Type,
+		"repo":      event.Repo,
+		"ref":       event.Ref,
+		"branch":    event.Branch,
+		"tag":       tag,
+		"comment":   event.Comment,
+		"commitSHA": event.CommitSHA,
+	}
+
+	stages := make(map[string]interface{})
+	for name, status := range wfr.Status.Stages {
+		if status == nil {
+			continue
+		}
+		outputs := make(map[string]interface{})
+		for _, kv := range status.Outputs {
+			outputs[kv.Key] = kv.Value
+		}
+		stages[name] = map[string]interface{}{
+			"phase":   string(status.Status.Phase),
+			"outputs": outputs,
+		}
+	}
+
+	return map[string]interface{}{
+		"variables": variables,
+		"scm":       scm,
+		"stages":    stages,
+	}
+}
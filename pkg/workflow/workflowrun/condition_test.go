@@ -0,0 +1,93 @@
+package workflowrun
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/caicloud/cyclone/pkg/apis/cyclone/v1alpha1"
+	"github.com/caicloud/cyclone/pkg/meta"
+)
+
+func TestEvaluateCondition(t *testing.T) {
+	wfr := &v1alpha1.WorkflowRun{
+		ObjectMeta: metav1.ObjectMeta{
+			Name: "wfr",
+			Annotations: map[string]string{
+				meta.AnnotationWorkflowRunSCMEvent: `{"Type":"scm-tag-release","Repo":"caicloud/cyclone","Ref":"refs/tags/v1.0.0"}`,
+			},
+		},
+		Spec: v1alpha1.WorkflowRunSpec{
+			GlobalVariables: []v1alpha1.GlobalVariable{
+				{
+					Name:  "ENV",
+					Value: "prod",
+				},
+			},
+		},
+		Status: v1alpha1.WorkflowRunStatus{
+			Stages: map[string]*v1alpha1.StageStatus{
+				"build": {
+					Status: v1alpha1.Status{Phase: v1alpha1.StatusSucceeded},
+					Outputs: []v1alpha1.KeyValue{
+						{
+							Key:   "changed",
+							Value: "true",
+						},
+					},
+				},
+			},
+		},
+	}
+
+	testCases := map[string]struct {
+		when     string
+		expected bool
+		err      bool
+	}{
+		"empty": {
+			when:     "",
+			expected: true,
+		},
+		"tag": {
+			when:     `scm.type == "scm-tag-release" && scm.tag =~ "^v[0-9]+"`,
+			expected: true,
+		},
+		"branch": {
+			when:     `scm.branch == "master"`,
+			expected: false,
+		},
+		"variable": {
+			when:     `variables.ENV == "prod"`,
+			expected: true,
+		},
+		"stage outputs": {
+			when:     `stages.build.phase == "Succeeded" && stages.build.outputs.changed == "true"`,
+			expected: true,
+		},
+		"not boolean": {
+			when: `variables.ENV`,
+			err:  true,
+		},
+		"invalid": {
+			when: `variables.ENV ==`,
+			err:  true,
+		},
+	}
+
+	for d, tc := range testCases {
+		result, err := EvaluateCondition(wfr, tc.when)
+		if tc.err {
+			assert.Error(t, err, d)
+			continue
+		}
+		assert.Nil(t, err, d)
+		assert.Equal(t, tc.expected, result, d)
+	}
+
+	// WorkflowRun not triggered by SCM
+	result, err := EvaluateCondition(&v1alpha1.WorkflowRun{}, `scm.tag != ""`)
+	assert.Nil(t, err)
+	assert.False(t, result)
+}
@@ -0,0 +1,130 @@
+package workflowrun
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/caicloud/cyclone/pkg/apis/cyclone/v1alpha1"
+	"github.com/caicloud/cyclone/pkg/util"
+)
+
+// MatrixInstanceName generates name of an instance expanded from a matrix stage.
+func MatrixInstanceName(stage string, index int) string {
+	return fmt.Sprintf("%s--%d", stage, index)
+}
+
+// ExpandMatrix expands matrix axes into combinations of axis values, each combination is a map from axis
+// name to value. Combinations are ordered by axis names and order of values, so that the expansion is
+// stable. Axes without values are ignored, and nil is returned if there are no axes.
+func ExpandMatrix(matrix map[string][]string) []map[string]string {
+	var axes []string
+	for axis, values := range matrix {
+		if len(values) > 0 {
+			axes = append(axes, axis)
+		}
+	}
+	if len(axes) == 0 {
+		return nil
+	}
+	sort.Strings(axes)
+
+	combinations := []map[string]string{{}}
+	for _, axis := range axes {
+		var expanded []map[string]string
+		for _, c := range combinations {
+			for _, v := range matrix[axis] {
+				combination := make(map[string]string, len(c)+1)
+				for k, cv := range c {
+					combination[k] = cv
+				}
+				combination[axis] = v
+				expanded = append(expanded, combination)
+			}
+		}
+		combinations = expanded
+	}
+
+	return combinations
+}
+
+// parentStage gets the stage in workflow that a stage status belongs to. For instances of matrix stage,
+// the matrix stage is returned, otherwise it's the stage itself.
+func parentStage(wfr *v1alpha1.WorkflowRun, stage string) string {
+	if status, ok := wfr.Status.Stages[stage]; ok && status.Parent != "" {
+		return status.Parent
+	}
+
+	return stage
+}
+
+// MatrixStatus aggregates status of a matrix stage from its instances. The matrix stage is terminated only
+// when all instances are terminated, and it fails if any instance failed.
+func MatrixStatus(stages map[string]*v1alpha1.StageStatus, stage string) *v1alpha1.Status {
+	aggregated := &v1alpha1.Status{}
+	status, ok := stages[stage]
+	if !ok {
+		return aggregated
+	}
+
+	var running, waiting, pending, succeeded int
+	var failed, cancelled []string
+	for _, instance := range status.Instances {
+		s, ok := stages[instance]
+		if !ok {
+			pending++
+			continue
+		}
+
+		if !s.Status.StartTime.IsZero() && (aggregated.StartTime.IsZero() || s.Status.StartTime.Before(&aggregated.StartTime)) {
+			aggregated.StartTime = s.Status.StartTime
+		}
+		if aggregated.LastTransitionTime.Before(&s.Status.LastTransitionTime) {
+			aggregated.LastTransitionTime = s.Status.LastTransitionTime
+		}
+
+		switch s.Status.Phase {
+		case v1alpha1.StatusRunning:
+			running++
+		case v1alpha1.StatusWaiting:
+			waiting++
+		case v1alpha1.StatusSucceeded:
+			succeeded++
+		case v1alpha1.StatusFailed:
+			failed = append(failed, instance)
+		case v1alpha1.StatusCancelled:
+			cancelled = append(cancelled, instance)
+		case v1alpha1.StatusSkipped:
+		default:
+			pending++
+		}
+	}
+
+	switch {
+	case running > 0:
+		aggregated.Phase = v1alpha1.StatusRunning
+	case waiting > 0:
+		aggregated.Phase = v1alpha1.StatusWaiting
+	case pending == len(status.Instances):
+		aggregated.Phase = v1alpha1.StatusPending
+	case pending > 0:
+		aggregated.Phase = v1alpha1.StatusRunning
+	case len(failed) > 0:
+		aggregated.Phase = v1alpha1.StatusFailed
+		aggregated.Message = fmt.Sprintf("Instances failed: %s", strings.Join(failed, ", "))
+	case len(cancelled) > 0:
+		aggregated.Phase = v1alpha1.StatusCancelled
+		aggregated.Message = fmt.Sprintf("Instances cancelled: %s", strings.Join(cancelled, ", "))
+	case succeeded == 0:
+		aggregated.Phase = v1alpha1.StatusSkipped
+	default:
+		aggregated.Phase = v1alpha1.StatusSucceeded
+	}
+
+	// Keep reason of the stage if it has already been terminated with the same phase, for example, cancelled by GC.
+	if util.IsPhaseTerminated(status.Status.Phase) && status.Status.Phase == aggregated.Phase {
+		aggregated.Reason = status.Status.Reason
+	}
+
+	return aggregated
+}
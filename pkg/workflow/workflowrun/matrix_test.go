@@ -0,0 +1,104 @@
+package workflowrun
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/caicloud/cyclone/pkg/apis/cyclone/v1alpha1"
+)
+
+func TestExpandMatrix(t *testing.T) {
+	assert.Nil(t, ExpandMatrix(nil))
+	assert.Nil(t, ExpandMatrix(map[string][]string{"GOARCH": {}}))
+	assert.Equal(t, []map[string]string{
+		{"GOARCH": "amd64", "GO_VERSION": "1.13"},
+		{"GOARCH": "amd64", "GO_VERSION": "1.14"},
+		{"GOARCH": "arm64", "GO_VERSION": "1.13"},
+		{"GOARCH": "arm64", "GO_VERSION": "1.14"},
+	}, ExpandMatrix(map[string][]string{
+		"GO_VERSION": {"1.13", "1.14"},
+		"GOARCH":     {"amd64", "arm64"},
+		"EMPTY":      {},
+	}))
+}
+
+func TestMatrixInstanceName(t *testing.T) {
+	assert.Equal(t, "build--0", MatrixInstanceName("build", 0))
+}
+
+func TestMatrixStatus(t *testing.T) {
+	stages := func(phases ...v1alpha1.StatusPhase) map[string]*v1alpha1.StageStatus {
+		result := map[string]*v1alpha1.StageStatus{
+			"build": {},
+		}
+		for i, phase := range phases {
+			name := MatrixInstanceName("build", i)
+			result["build"].Instances = append(result["build"].Instances, name)
+			result[name] = &v1alpha1.StageStatus{
+				Status: v1alpha1.Status{Phase: phase},
+				Parent: "build",
+			}
+		}
+		return result
+	}
+
+	cases := []struct {
+		phases   []v1alpha1.StatusPhase
+		expected v1alpha1.StatusPhase
+	}{
+		{[]v1alpha1.StatusPhase{v1alpha1.StatusPending, v1alpha1.StatusPending}, v1alpha1.StatusPending},
+		{[]v1alpha1.StatusPhase{v1alpha1.StatusPending, v1alpha1.StatusSucceeded}, v1alpha1.StatusRunning},
+		{[]v1alpha1.StatusPhase{v1alpha1.StatusRunning, v1alpha1.StatusFailed}, v1alpha1.StatusRunning},
+		{[]v1alpha1.StatusPhase{v1alpha1.StatusWaiting, v1alpha1.StatusSucceeded}, v1alpha1.StatusWaiting},
+		{[]v1alpha1.StatusPhase{v1alpha1.StatusFailed, v1alpha1.StatusSucceeded}, v1alpha1.StatusFailed},
+		{[]v1alpha1.StatusPhase{v1alpha1.StatusCancelled, v1alpha1.StatusSucceeded}, v1alpha1.StatusCancelled},
+		{[]v1alpha1.StatusPhase{v1alpha1.StatusSkipped, v1alpha1.StatusSkipped}, v1alpha1.StatusSkipped},
+		{[]v1alpha1.StatusPhase{v1alpha1.StatusSkipped, v1alpha1.StatusSucceeded}, v1alpha1.StatusSucceeded},
+	}
+	for _, c := range cases {
+		assert.Equal(t, c.expected, MatrixStatus(stages(c.phases...), "build").Phase, "%v", c.phases)
+	}
+
+	failed := MatrixStatus(stages(v1alpha1.StatusFailed, v1alpha1.StatusSucceeded), "build")
+	assert.Equal(t, "Instances failed: build--0", failed.Message)
+}
+
+func TestMatrixStages(t *testing.T) {
+	wf := &v1alpha1.Workflow{
+		Spec: v1alpha1.WorkflowSpec{
+			Stages: []v1alpha1.StageItem{
+				{
+					Name: "build",
+					Matrix: map[string][]string{
+						"GOARCH": {"amd64", "arm64"},
+					},
+				},
+				{
+					Name:    "release",
+					Depends: []string{"build"},
+				},
+			},
+		},
+	}
+	wfr := &v1alpha1.WorkflowRun{}
+	o := &operator{
+		wf:  wf,
+		wfr: wfr,
+	}
+	o.InitStagesStatus()
+	assert.Equal(t, []string{"build--0", "build--1"}, wfr.Status.Stages["build"].Instances)
+	assert.Equal(t, map[string]string{"GOARCH": "arm64"}, wfr.Status.Stages["build--1"].Matrix)
+	assert.Equal(t, "build", wfr.Status.Stages["build--1"].Parent)
+	assert.Equal(t, []string{"build--0", "build--1"}, NextStages(wf, wfr))
+
+	o.UpdateStageStatus("build--0", &v1alpha1.Status{Phase: v1alpha1.StatusSucceeded})
+	assert.Equal(t, v1alpha1.StatusRunning, wfr.Status.Stages["build"].Status.Phase)
+	assert.Equal(t, []string{"build--1"}, NextStages(wf, wfr))
+
+	o.UpdateStageStatus("build--1", &v1alpha1.Status{Phase: v1alpha1.StatusSucceeded})
+	assert.Equal(t, v1alpha1.StatusSucceeded, wfr.Status.Stages["build"].Status.Phase)
+	assert.Equal(t, []string{"release"}, NextStages(wf, wfr))
+	assert.Equal(t, "build", parentStage(wfr, "build--1"))
+	assert.Equal(t, "release", parentStage(wfr, "release"))
+}
@@ -42,6 +42,15 @@ type Operator interface {
 	UpdateStagePodInfo(stage string, podInfo *v1alpha1.PodInfo)
 	// Update stage outputs, they are key-value results from stage execution
 	UpdateStageOutputs(stage string, keyValues []v1alpha1.KeyValue)
+	// Update status of workload containers in the stage pod.
+	UpdateStageContainers(stage string, containers []v1alpha1.WorkloadContainerStatus)
+	// Update approval status of an approval stage.
+	UpdateStageApproval(stage string, approval *v1alpha1.ApprovalStatus)
+	// Update delegation status of a delegated stage.
+	UpdateStageDelegation(stage string, delegation *v1alpha1.DelegationStatus)
+	// Record a failed attempt of the stage and reset the stage to be retried if its retry policy allows.
+	// It returns true if the stage would be retried.
+	RetryStage(stage string, attempt *v1alpha1.StageAttempt) bool
 	// Decide overall status of the WorkflowRun from stage status.
 	OverallStatus() (*v1alpha1.Status, error)
 	// Garbage collection on the WorkflowRun based on GC policy configured
@@ -133,21 +142,57 @@ func (o *operator) GetRecorder() record.EventRecorder {
 }
 
 // InitStagesStatus initializes all missing stages' status to pending, and record workflow topology at this time to workflowRun status.
+// If start stages are specified in the WorkflowRun, stages that neither are start stages nor depend on them are skipped.
 func (o *operator) InitStagesStatus() {
 	if o.wfr.Status.Stages == nil {
 		o.wfr.Status.Stages = make(map[string]*v1alpha1.StageStatus)
 	}
 
+	started := StartedStages(o.wf, o.wfr.Spec.StartStages)
 	for _, stg := range o.wf.Spec.Stages {
-		if _, ok := o.wfr.Status.Stages[stg.Name]; !ok {
+		if _, ok := o.wfr.Status.Stages[stg.Name]; ok {
+			continue
+		}
+
+		if started != nil && !started[stg.Name] {
 			o.wfr.Status.Stages[stg.Name] = &v1alpha1.StageStatus{
+				Status: v1alpha1.Status{
+					Phase:              v1alpha1.StatusSkipped,
+					Reason:             v1alpha1.ReasonNotStartStage,
+					LastTransitionTime: metav1.Time{Time: time.Now()},
+				},
+				Depends: stg.Depends,
+				Trivial: stg.Trivial,
+			}
+			continue
+		}
+
+		status := &v1alpha1.StageStatus{
+			Status: v1alpha1.Status{
+				Phase: v1alpha1.StatusPending,
+			},
+			Depends: stg.Depends,
+			Trivial: stg.Trivial,
+			Timeout: StageTimeout(o.wfr, &stg),
+		}
+
+		// Expand matrix stage into instances, each instance has its own status.
+		for i, values := range ExpandMatrix(stg.Matrix) {
+			instance := MatrixInstanceName(stg.Name, i)
+			status.Instances = append(status.Instances, instance)
+			o.wfr.Status.Stages[instance] = &v1alpha1.StageStatus{
 				Status: v1alpha1.Status{
 					Phase: v1alpha1.StatusPending,
 				},
 				Depends: stg.Depends,
 				Trivial: stg.Trivial,
+				Timeout: status.Timeout,
+				Parent:  stg.Name,
+				Matrix:  values,
 			}
 		}
+
+		o.wfr.Status.Stages[stg.Name] = status
 	}
 }
 
@@ -185,17 +230,48 @@ func (o *operator) Update() error {
 				continue
 			}
 
-			combined.Status.Stages[stage].Status = *resolveStatus(&s.Status, &status.Status)
-			if s.Pod == nil {
+			// If more attempts recorded, the stage is retried, status and pod would be reset for the new attempt.
+			if len(status.Attempts) > len(s.Attempts) {
+				combined.Status.Stages[stage].Status = status.Status
+				combined.Status.Stages[stage].Attempts = status.Attempts
+			} else {
+				combined.Status.Stages[stage].Status = *resolveStatus(&s.Status, &status.Status)
+			}
+			if s.Pod == nil || (status.Pod != nil && len(status.Attempts) >= len(s.Attempts) && s.Status.Reason == v1alpha1.ReasonRetrying) {
 				combined.Status.Stages[stage].Pod = status.Pod
 			}
 			if len(s.Outputs) == 0 {
 				combined.Status.Stages[stage].Outputs = status.Outputs
 			}
+			if len(status.Containers) > 0 {
+				combined.Status.Stages[stage].Containers = status.Containers
+			}
 			if len(s.Depends) == 0 {
 				combined.Status.Stages[stage].Depends = status.Depends
 			}
+			if len(s.Timeout) == 0 {
+				combined.Status.Stages[stage].Timeout = status.Timeout
+			}
 			combined.Status.Stages[stage].Trivial = status.Trivial
+			if len(s.Instances) == 0 {
+				combined.Status.Stages[stage].Instances = status.Instances
+			}
+			// Approval decided by approvers should never be overridden.
+			if s.Approval == nil || (len(s.Approval.Approver) == 0 && status.Approval != nil && len(status.Approval.Approver) > 0) {
+				combined.Status.Stages[stage].Approval = status.Approval
+			}
+			// Delegation status is reset with a new callback token when the stage is delegated again.
+			if s.Delegation == nil || (status.Delegation != nil && status.Delegation.TokenDigest != s.Delegation.TokenDigest &&
+				len(status.Attempts) >= len(s.Attempts) && s.Status.Phase != v1alpha1.StatusWaiting && s.Status.Phase != v1alpha1.StatusRunning) {
+				combined.Status.Stages[stage].Delegation = status.Delegation
+			}
+		}
+
+		// Status of matrix stages are aggregated from the combined status of their instances.
+		for stage, status := range combined.Status.Stages {
+			if len(status.Instances) > 0 {
+				status.Status = *MatrixStatus(combined.Status.Stages, stage)
+			}
 		}
 
 		// Update global variables to resolved values
@@ -239,6 +315,13 @@ func (o *operator) UpdateStageStatus(stage string, status *v1alpha1.Status) {
 			o.wfr.Status.Stages[stage].Status.StartTime = originStatus.StartTime
 		}
 	}
+
+	// If it's an instance of matrix stage, update status of the matrix stage too.
+	if parent := o.wfr.Status.Stages[stage].Parent; parent != "" {
+		if s, ok := o.wfr.Status.Stages[parent]; ok {
+			s.Status = *MatrixStatus(o.wfr.Status.Stages, parent)
+		}
+	}
 }
 
 // UpdateStagePodInfo updates stage pod information to WorkflowRun.
@@ -279,6 +362,61 @@ func (o *operator) UpdateStageOutputs(stage string, keyValues []v1alpha1.KeyValu
 	o.wfr.Status.Stages[stage].Outputs = keyValues
 }
 
+// UpdateStageContainers updates status of workload containers in the stage pod, they are synced from the pod.
+func (o *operator) UpdateStageContainers(stage string, containers []v1alpha1.WorkloadContainerStatus) {
+	if len(containers) == 0 {
+		return
+	}
+
+	if o.wfr.Status.Stages == nil {
+		o.wfr.Status.Stages = make(map[string]*v1alpha1.StageStatus)
+	}
+
+	if _, ok := o.wfr.Status.Stages[stage]; !ok {
+		o.wfr.Status.Stages[stage] = &v1alpha1.StageStatus{
+			Status: v1alpha1.Status{
+				Phase: v1alpha1.StatusRunning,
+			},
+		}
+	}
+
+	o.wfr.Status.Stages[stage].Containers = containers
+}
+
+// UpdateStageApproval updates approval status of an approval stage to WorkflowRun.
+func (o *operator) UpdateStageApproval(stage string, approval *v1alpha1.ApprovalStatus) {
+	if o.wfr.Status.Stages == nil {
+		o.wfr.Status.Stages = make(map[string]*v1alpha1.StageStatus)
+	}
+
+	if _, ok := o.wfr.Status.Stages[stage]; !ok {
+		o.wfr.Status.Stages[stage] = &v1alpha1.StageStatus{
+			Status: v1alpha1.Status{
+				Phase: v1alpha1.StatusPending,
+			},
+		}
+	}
+
+	o.wfr.Status.Stages[stage].Approval = approval
+}
+
+// UpdateStageDelegation updates delegation status of a delegated stage to WorkflowRun.
+func (o *operator) UpdateStageDelegation(stage string, delegation *v1alpha1.DelegationStatus) {
+	if o.wfr.Status.Stages == nil {
+		o.wfr.Status.Stages = make(map[string]*v1alpha1.StageStatus)
+	}
+
+	if _, ok := o.wfr.Status.Stages[stage]; !ok {
+		o.wfr.Status.Stages[stage] = &v1alpha1.StageStatus{
+			Status: v1alpha1.Status{
+				Phase: v1alpha1.StatusPending,
+			},
+		}
+	}
+
+	o.wfr.Status.Stages[stage].Delegation = delegation
+}
+
 // OverallStatus calculates the overall status of the WorkflowRun. When a stage has its status
 // changed, the change will be updated in WorkflowRun stage status, but the overall status is
 // not calculated. So when we observed a WorkflowRun updated, we need to calculate its overall
@@ -296,6 +434,11 @@ func (o *operator) OverallStatus() (*v1alpha1.Status, error) {
 
 	var running, waiting, pending, err bool
 	for stage, status := range o.wfr.Status.Stages {
+		// Instances of matrix stage are counted in status of the matrix stage.
+		if status.Parent != "" {
+			continue
+		}
+
 		switch status.Status.Phase {
 		case v1alpha1.StatusRunning:
 			running = true
@@ -306,6 +449,7 @@ func (o *operator) OverallStatus() (*v1alpha1.Status, error) {
 		case v1alpha1.StatusPending:
 			pending = true
 		case v1alpha1.StatusSucceeded:
+		case v1alpha1.StatusSkipped:
 		case v1alpha1.StatusCancelled:
 			err = err || !IsTrivial(o.wf, stage)
 		default:
@@ -374,7 +518,9 @@ func isExceededQuotaError(err error) bool {
 func (o *operator) Reconcile() (controller.Result, error) {
 	var res controller.Result
 
-	if o.wfr.Status.Stages == nil {
+	// Stages status may be partially provided when the WorkflowRun created, for example, re-run from
+	// another WorkflowRun, so ensure status of all stages are initialized.
+	if o.wfr.Status.Overall.Phase == "" || o.wfr.Status.Stages == nil {
 		o.InitStagesStatus()
 	}
 
@@ -392,6 +538,13 @@ func (o *operator) Reconcile() (controller.Result, error) {
 	}
 	o.wfr.Status.Overall = *overall
 
+	// If there are stages waiting to be retried, requeue the WorkflowRun to run them when backoff expired.
+	if after := nextRetryAfter(o.wf, o.wfr); after > 0 {
+		requeue := true
+		res.Requeue = &requeue
+		res.RequeueAfter = after
+	}
+
 	// Return if no stages need to run.
 	if len(nextStages) == 0 {
 		err = o.Update()
@@ -405,15 +558,19 @@ func (o *operator) Reconcile() (controller.Result, error) {
 	var retryStageNames []string
 	// Create pod to run stages.
 	for _, stage := range nextStages {
+		if !o.conditionMet(stage) {
+			continue
+		}
+
 		log.WithField("stg", stage).Info("Start to run stage")
 
-		stg, err := o.client.CycloneV1alpha1().Stages(o.wfr.Namespace).Get(context.TODO(), stage, metav1.GetOptions{})
+		stg, err := o.client.CycloneV1alpha1().Stages(o.wfr.Namespace).Get(context.TODO(), parentStage(o.wfr, stage), metav1.GetOptions{})
 		if err != nil {
 			log.WithField("stg", stage).Error("Get stage error: ", err)
 			continue
 		}
 
-		err = NewWorkloadProcessor(o.clusterClient, o.client, o.wf, o.wfr, stg, o).Process()
+		err = NewWorkloadProcessor(o.clusterClient, o.client, o.wf, o.wfr, stg, stage, o).Process()
 		if err != nil {
 			if isExceededQuotaError(err) {
 				retryStageNames = append(retryStageNames, stage)
@@ -457,6 +614,43 @@ func (o *operator) Reconcile() (controller.Result, error) {
 	return res, nil
 }
 
+// conditionMet evaluates 'when' condition of the stage, and returns whether the stage should be executed.
+// If the condition is not met, the stage would be marked as skipped, and if the condition can't be evaluated,
+// the stage would be marked as failed.
+func (o *operator) conditionMet(stage string) bool {
+	item := GetStageItem(o.wf, parentStage(o.wfr, stage))
+	if item == nil {
+		return true
+	}
+
+	met, err := EvaluateCondition(o.wfr, item.When)
+	if err != nil {
+		log.WithField("wfr", o.wfr.Name).WithField("stg", stage).Warn("Evaluate stage condition error: ", err)
+		o.recorder.Eventf(o.wfr, corev1.EventTypeWarning, v1alpha1.ReasonInvalidCondition, "Evaluate condition of stage '%s' error: %v", stage, err)
+		o.UpdateStageStatus(stage, &v1alpha1.Status{
+			Phase:              v1alpha1.StatusFailed,
+			Reason:             v1alpha1.ReasonInvalidCondition,
+			Message:            err.Error(),
+			LastTransitionTime: metav1.Time{Time: time.Now()},
+			StartTime:          metav1.Time{Time: time.Now()},
+		})
+		return false
+	}
+
+	if !met {
+		log.WithField("wfr", o.wfr.Name).WithField("stg", stage).WithField("when", item.When).Info("Stage condition not met, skip it")
+		o.UpdateStageStatus(stage, &v1alpha1.Status{
+			Phase:              v1alpha1.StatusSkipped,
+			Reason:             v1alpha1.ReasonConditionNotMet,
+			Message:            fmt.Sprintf("Condition '%s' not met", item.When),
+			LastTransitionTime: metav1.Time{Time: time.Now()},
+			StartTime:          metav1.Time{Time: time.Now()},
+		})
+	}
+
+	return met
+}
+
 // Garbage collection of WorkflowRun. When it's terminated, we will cleanup the pods created by it.
 // - 'lastTry' indicates whether this is the last try to perform GC on this WorkflowRun object,
 // if set to true, the WorkflowRun would be marked as cleaned regardless whether the GC succeeded or not.
@@ -543,8 +737,16 @@ func (o *operator) GC(lastTry, wfrDeletion bool) error {
 	// Get execution context of the WorkflowRun, namespace and PVC are defined in the context.
 	executionContext := GetExecutionContext(o.wfr)
 
-	// Create a gc pod to clean data on PV if PVC is configured.
-	if executionContext.PVC != "" {
+	// Create a gc pod to clean data on PV if PVC is configured, data shared with other WorkflowRuns would
+	// be cleaned by the last one of them. So is the workspace of the upstream WorkflowRun whose artifacts are
+	// used by this one.
+	var workspaces []string
+	for _, workspace := range []string{common.WorkspaceName(o.wfr), o.wfr.Labels[meta.LabelWorkflowRunUpstreamWorkspace]} {
+		if workspace != "" && !o.workspaceInUse(workspace) {
+			workspaces = append(workspaces, common.GCDataPath+"/"+workspace)
+		}
+	}
+	if executionContext.PVC != "" && len(workspaces) > 0 {
 		gcPod := &corev1.Pod{
 			ObjectMeta: metav1.ObjectMeta{
 				Name:      GCPodName(o.wfr.Name),
@@ -566,7 +768,7 @@ func (o *operator) GC(lastTry, wfrDeletion bool) error {
 					{
 						Name:    common.GCContainerName,
 						Image:   controller.Config.Images[controller.GCImage],
-						Command: []string{"rm", "-rf", common.GCDataPath + "/" + o.wfr.Name},
+						Command: append([]string{"rm", "-rf"}, workspaces...),
 						VolumeMounts: []corev1.VolumeMount{
 							{
 								Name:      common.DefaultPvVolumeName,
@@ -609,6 +811,12 @@ func (o *operator) GC(lastTry, wfrDeletion bool) error {
 		}
 	}
 
+	// Copy of the artifact token secret in the execution namespace is kept until the WorkflowRun is deleted, as
+	// downstream WorkflowRuns may still download its artifacts.
+	if wfrDeletion {
+		o.deleteArtifactTokenCopy(executionContext.Namespace)
+	}
+
 	if !wfrDeletion {
 		o.recorder.Event(o.wfr, corev1.EventTypeNormal, "GC", "GC is performed succeed.")
 
@@ -622,6 +830,55 @@ func (o *operator) GC(lastTry, wfrDeletion bool) error {
 	return nil
 }
 
+// deleteArtifactTokenCopy deletes the artifact token secret of the WorkflowRun copied to the execution namespace.
+// The secret owned by the WorkflowRun isn't a copy, it's deleted by Kubernetes garbage collector.
+func (o *operator) deleteArtifactTokenCopy(namespace string) {
+	name := common.ArtifactTokenSecretName(o.wfr.Name)
+	secret, err := o.clusterClient.CoreV1().Secrets(namespace).Get(context.TODO(), name, metav1.GetOptions{})
+	if err != nil {
+		if !errors.IsNotFound(err) {
+			log.WithField("wfr", o.wfr.Name).Warn("Get artifact token secret error: ", err)
+		}
+		return
+	}
+	if len(secret.OwnerReferences) > 0 {
+		return
+	}
+	err = o.clusterClient.CoreV1().Secrets(namespace).Delete(context.TODO(), name, metav1.DeleteOptions{})
+	if err != nil && !errors.IsNotFound(err) {
+		log.WithField("wfr", o.wfr.Name).Warn("Delete artifact token secret error: ", err)
+	}
+}
+
+// workspaceInUse checks whether a workspace is still used by WorkflowRuns other than this one that haven't been
+// cleaned, for example, a WorkflowRun re-run from it, or a WorkflowRun triggered by it that reads its artifacts.
+func (o *operator) workspaceInUse(workspace string) bool {
+	for _, key := range []string{meta.LabelWorkflowRunWorkspace, meta.LabelWorkflowRunUpstreamWorkspace} {
+		wfrs, err := o.client.CycloneV1alpha1().WorkflowRuns(o.wfr.Namespace).List(context.TODO(), metav1.ListOptions{
+			LabelSelector: key + "=" + workspace,
+		})
+		if err != nil {
+			log.WithField("wfr", o.wfr.Name).Warn("List WorkflowRuns sharing workspace error: ", err)
+			return false
+		}
+		for _, wfr := range wfrs.Items {
+			if wfr.Name != o.wfr.Name && wfr.DeletionTimestamp.IsZero() && !wfr.Status.Cleaned {
+				return true
+			}
+		}
+	}
+
+	// The workspace is owned by another WorkflowRun, check whether it's cleaned.
+	if workspace != o.wfr.Name {
+		wfr, err := o.client.CycloneV1alpha1().WorkflowRuns(o.wfr.Namespace).Get(context.TODO(), workspace, metav1.GetOptions{})
+		if err == nil && wfr.DeletionTimestamp.IsZero() && !wfr.Status.Cleaned {
+			return true
+		}
+	}
+
+	return false
+}
+
 // ResolveGlobalVariables will resolve global variables in workflowrun For example, generate final value for generation
 // type value defined in workflow. For example, $(random:5) --> 'axyps'
 func (o *operator) ResolveGlobalVariables() {
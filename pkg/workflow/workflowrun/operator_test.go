@@ -1,16 +1,71 @@
 package workflowrun
 
 import (
+	"context"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 
 	"github.com/caicloud/cyclone/pkg/apis/cyclone/v1alpha1"
 	"github.com/caicloud/cyclone/pkg/util/k8s/fake"
+	"github.com/caicloud/cyclone/pkg/workflow/common"
 )
 
+func TestInitStagesStatus(t *testing.T) {
+	wf := &v1alpha1.Workflow{
+		Spec: v1alpha1.WorkflowSpec{
+			Stages: []v1alpha1.StageItem{
+				{
+					Name: "A",
+				},
+				{
+					Name:    "B",
+					Depends: []string{"A"},
+				},
+				{
+					Name:    "C",
+					Depends: []string{"B"},
+				},
+				{
+					Name: "D",
+				},
+			},
+		},
+	}
+	wfr := &v1alpha1.WorkflowRun{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "test",
+			Namespace: "default",
+		},
+		Spec: v1alpha1.WorkflowRunSpec{
+			StartStages: []string{"B"},
+		},
+		Status: v1alpha1.WorkflowRunStatus{
+			Stages: map[string]*v1alpha1.StageStatus{
+				"A": {
+					Status: v1alpha1.Status{Phase: v1alpha1.StatusSucceeded, Reason: v1alpha1.ReasonRerun},
+				},
+			},
+		},
+	}
+	o := &operator{
+		wf:  wf,
+		wfr: wfr,
+	}
+	o.InitStagesStatus()
+	assert.Equal(t, v1alpha1.StatusSucceeded, wfr.Status.Stages["A"].Status.Phase)
+	assert.Equal(t, v1alpha1.StatusPending, wfr.Status.Stages["B"].Status.Phase)
+	assert.Equal(t, []string{"A"}, wfr.Status.Stages["B"].Depends)
+	assert.Equal(t, v1alpha1.StatusPending, wfr.Status.Stages["C"].Status.Phase)
+	assert.Equal(t, v1alpha1.StatusSkipped, wfr.Status.Stages["D"].Status.Phase)
+	assert.Equal(t, v1alpha1.ReasonNotStartStage, wfr.Status.Stages["D"].Status.Reason)
+	assert.Equal(t, []string{"B"}, NextStages(wf, wfr))
+}
+
 func TestOverallStatus(t *testing.T) {
 	client := fake.NewSimpleClientset()
 	recorder := new(MockedRecorder)
@@ -143,3 +198,73 @@ func TestOverallStatus(t *testing.T) {
 	overall, _ = o.OverallStatus()
 	assert.Equal(t, v1alpha1.StatusRunning, overall.Phase)
 }
+
+func TestDeleteArtifactTokenCopy(t *testing.T) {
+	client := fake.NewSimpleClientset(
+		&corev1.Secret{ObjectMeta: metav1.ObjectMeta{Name: common.ArtifactTokenSecretName("test"), Namespace: "worker"}},
+		&corev1.Secret{ObjectMeta: metav1.ObjectMeta{
+			Name:            common.ArtifactTokenSecretName("test"),
+			Namespace:       "default",
+			OwnerReferences: []metav1.OwnerReference{{Name: "test"}},
+		}},
+	)
+	o := &operator{
+		clusterClient: client,
+		wfr:           &v1alpha1.WorkflowRun{ObjectMeta: metav1.ObjectMeta{Name: "test", Namespace: "default"}},
+	}
+
+	o.deleteArtifactTokenCopy("worker")
+	_, err := client.CoreV1().Secrets("worker").Get(context.TODO(), common.ArtifactTokenSecretName("test"), metav1.GetOptions{})
+	assert.True(t, errors.IsNotFound(err))
+
+	// The secret owned by the WorkflowRun is left to Kubernetes garbage collector.
+	o.deleteArtifactTokenCopy("default")
+	_, err = client.CoreV1().Secrets("default").Get(context.TODO(), common.ArtifactTokenSecretName("test"), metav1.GetOptions{})
+	assert.Nil(t, err)
+}
+
+func TestUpdateStageContainers(t *testing.T) {
+	exitCode := func(code int32) *int32 {
+		return &code
+	}
+	latest := &v1alpha1.WorkflowRun{
+		ObjectMeta: metav1.ObjectMeta{Name: "test", Namespace: "default"},
+		Status: v1alpha1.WorkflowRunStatus{
+			Stages: map[string]*v1alpha1.StageStatus{
+				"A": {
+					Status:     v1alpha1.Status{Phase: v1alpha1.StatusRunning},
+					Containers: []v1alpha1.WorkloadContainerStatus{{Name: "build"}, {Name: "test"}},
+				},
+				"B": {
+					Status:     v1alpha1.Status{Phase: v1alpha1.StatusRunning},
+					Containers: []v1alpha1.WorkloadContainerStatus{{Name: "test", ExitCode: exitCode(0)}},
+				},
+			},
+		},
+	}
+	client := fake.NewSimpleClientset(latest)
+	o := &operator{
+		client: client,
+		wf:     &v1alpha1.Workflow{ObjectMeta: metav1.ObjectMeta{Name: "wf"}},
+		wfr:    latest.DeepCopy(),
+	}
+
+	o.UpdateStageContainers("A", []v1alpha1.WorkloadContainerStatus{
+		{Name: "build", ExitCode: exitCode(1), Reason: "Error"},
+		{Name: "test"},
+		{Name: "app", Service: true, ExitCode: exitCode(137)},
+	})
+	// Empty container statuses don't override the recorded ones, for example, when containers of the stage are
+	// recorded after the operator loads the WorkflowRun.
+	o.wfr.Status.Stages["B"].Containers = nil
+	o.UpdateStageContainers("B", nil)
+	o.UpdateStageContainers("C", []v1alpha1.WorkloadContainerStatus{{Name: "test"}})
+	assert.Nil(t, o.Update())
+
+	updated, err := client.CycloneV1alpha1().WorkflowRuns("default").Get(context.TODO(), "test", metav1.GetOptions{})
+	assert.Nil(t, err)
+	assert.Equal(t, o.wfr.Status.Stages["A"].Containers, updated.Status.Stages["A"].Containers)
+	assert.Equal(t, latest.Status.Stages["B"].Containers, updated.Status.Stages["B"].Containers)
+	assert.Equal(t, v1alpha1.StatusRunning, updated.Status.Stages["C"].Status.Phase)
+	assert.Equal(t, []v1alpha1.WorkloadContainerStatus{{Name: "test"}}, updated.Status.Stages["C"].Containers)
+}
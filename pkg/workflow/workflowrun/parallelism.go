@@ -1,10 +1,12 @@
 package workflowrun
 
 import (
+	"sort"
 	"sync"
 
 	log "github.com/sirupsen/logrus"
 
+	"github.com/caicloud/cyclone/pkg/apis/cyclone/v1alpha1"
 	"github.com/caicloud/cyclone/pkg/workflow/controller"
 )
 
@@ -18,12 +20,25 @@ const (
 	AttemptActionQueued AttemptAction = "Queued"
 	// AttemptActionFailed represents the WorkflowRun will fail directly due to queue full
 	AttemptActionFailed AttemptAction = "Failed"
+	// AttemptActionSkipped represents the WorkflowRun is skipped as there is WorkflowRun running in its concurrency group
+	AttemptActionSkipped AttemptAction = "Skipped"
 )
 
+// ConcurrencyGroup is the resolved concurrency group of a WorkflowRun
+type ConcurrencyGroup struct {
+	// Name of the group rendered from the group template
+	Name string
+	// Mode to handle new WorkflowRun in the group
+	Mode v1alpha1.ConcurrencyMode
+}
+
 // ParallelismController is an interface to manage parallelism of WorkflowRun executions
 type ParallelismController interface {
 	// AttemptNew tries to run a new WorkflowRun, and returns the corresponding action.
 	AttemptNew(ns, wf, wfr string) AttemptAction
+	// AttemptInGroup tries to run a new WorkflowRun in its concurrency group, and returns the corresponding
+	// action together with WorkflowRuns in the group that are superseded and should be cancelled.
+	AttemptInGroup(ns, wfr string, group *ConcurrencyGroup) (AttemptAction, []string)
 	// MarkFinished mark a WorkflowRun execution finished
 	MarkFinished(ns, wf, wfr string)
 }
@@ -35,6 +50,13 @@ type wfStatus struct {
 	runningWfrMap map[string]struct{}
 }
 
+type groupStatus struct {
+	// WorkflowRuns that are running in the group
+	running map[string]struct{}
+	// WorkflowRuns that are waiting in the group, in the order they arrived
+	waiting []string
+}
+
 type overallStatus struct {
 	// How many WorkflowRun are running in total
 	total int64
@@ -46,15 +68,21 @@ type parallelismController struct {
 	config  *controller.ParallelismConfig
 	wfMap   map[string]*wfStatus
 	overall overallStatus
-	lock    *sync.Mutex
+	// groups are concurrency groups keyed by '<namespace>/<group>'
+	groups map[string]*groupStatus
+	// wfrGroups maps '<namespace>/<workflowrun>' to key of the group the WorkflowRun is in
+	wfrGroups map[string]string
+	lock      *sync.Mutex
 }
 
 // NewParallelismController creates a ParallelismController
 func NewParallelismController(parallelismConfig *controller.ParallelismConfig) ParallelismController {
 	return &parallelismController{
-		config: parallelismConfig,
-		wfMap:  make(map[string]*wfStatus),
-		lock:   &sync.Mutex{},
+		config:    parallelismConfig,
+		wfMap:     make(map[string]*wfStatus),
+		groups:    make(map[string]*groupStatus),
+		wfrGroups: make(map[string]string),
+		lock:      &sync.Mutex{},
 	}
 }
 
@@ -159,17 +187,115 @@ func (c *parallelismController) AttemptNew(ns, wf, wfr string) AttemptAction {
 	return AttemptActionStart
 }
 
+// AttemptInGroup tries to run a new WorkflowRun in its concurrency group. Different to AttemptNew, it works
+// even if no parallelism constraint configured, as concurrency groups are configured in workflows.
+func (c *parallelismController) AttemptInGroup(ns, wfr string, group *ConcurrencyGroup) (AttemptAction, []string) {
+	if group == nil || group.Name == "" {
+		return AttemptActionStart, nil
+	}
+	log.Infof("Attempt to run '%s' in concurrency group '%s'", wfr, group.Name)
+
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	key := ns + "/" + group.Name
+	g, ok := c.groups[key]
+	if !ok {
+		g = &groupStatus{running: make(map[string]struct{})}
+		c.groups[key] = g
+	}
+
+	// If the WorkflowRun is already running in the group, return action directly
+	if _, ok := g.running[wfr]; ok {
+		return AttemptActionStart, nil
+	}
+
+	switch group.Mode {
+	case v1alpha1.ConcurrencyCancelInProgress:
+		var superseded []string
+		for r := range g.running {
+			superseded = append(superseded, r)
+		}
+		sort.Strings(superseded)
+		for _, r := range g.waiting {
+			if r != wfr {
+				superseded = append(superseded, r)
+			}
+		}
+		for _, r := range superseded {
+			delete(c.wfrGroups, ns+"/"+r)
+		}
+
+		g.running = map[string]struct{}{wfr: {}}
+		g.waiting = nil
+		c.wfrGroups[ns+"/"+wfr] = key
+		return AttemptActionStart, superseded
+	case v1alpha1.ConcurrencySkipIfRunning:
+		if len(g.running) > 0 {
+			return AttemptActionSkipped, nil
+		}
+	default:
+		// WorkflowRuns in the group run one by one in the order they arrived.
+		if len(g.running) > 0 || (len(g.waiting) > 0 && g.waiting[0] != wfr) {
+			if indexOf(g.waiting, wfr) < 0 {
+				g.waiting = append(g.waiting, wfr)
+				c.wfrGroups[ns+"/"+wfr] = key
+			}
+			return AttemptActionQueued, nil
+		}
+		if len(g.waiting) > 0 {
+			g.waiting = g.waiting[1:]
+		}
+	}
+
+	g.running[wfr] = struct{}{}
+	c.wfrGroups[ns+"/"+wfr] = key
+	return AttemptActionStart, nil
+}
+
+// leaveGroup removes a WorkflowRun from its concurrency group, caller should hold the lock.
+func (c *parallelismController) leaveGroup(ns, wfr string) {
+	key, ok := c.wfrGroups[ns+"/"+wfr]
+	if !ok {
+		return
+	}
+	delete(c.wfrGroups, ns+"/"+wfr)
+
+	g, ok := c.groups[key]
+	if !ok {
+		return
+	}
+	delete(g.running, wfr)
+	if i := indexOf(g.waiting, wfr); i >= 0 {
+		g.waiting = append(g.waiting[:i], g.waiting[i+1:]...)
+	}
+	if len(g.running) == 0 && len(g.waiting) == 0 {
+		delete(c.groups, key)
+	}
+}
+
+func indexOf(items []string, item string) int {
+	for i, v := range items {
+		if v == item {
+			return i
+		}
+	}
+	return -1
+}
+
 // MarkFinished mark a WorkflowRun execution finished
 func (c *parallelismController) MarkFinished(ns, wf, wfr string) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	c.leaveGroup(ns, wfr)
+
 	// If no parallelism constraint configured, no action to take
 	if c.config == nil {
 		return
 	}
 	log.Infof("To mark '%s' finished", wfr)
 
-	c.lock.Lock()
-	defer c.lock.Unlock()
-
 	if m, ok := c.wfMap[wf]; ok {
 		if _, ook := m.runningWfrMap[wfr]; ook {
 			c.overall.total--
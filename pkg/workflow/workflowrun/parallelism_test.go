@@ -5,6 +5,7 @@ import (
 
 	"github.com/stretchr/testify/assert"
 
+	"github.com/caicloud/cyclone/pkg/apis/cyclone/v1alpha1"
 	"github.com/caicloud/cyclone/pkg/workflow/controller"
 )
 
@@ -98,3 +99,49 @@ func TestMarkFinished(t *testing.T) {
 	assert.Equal(t, AttemptActionStart, pc.AttemptNew("ns1", "wf1", "wfr4"))
 	assert.Equal(t, AttemptActionQueued, pc.AttemptNew("ns1", "wf1", "wfr5"))
 }
+
+func TestAttemptInGroup(t *testing.T) {
+	pc := NewParallelismController(nil)
+	action, superseded := pc.AttemptInGroup("ns1", "wfr0", nil)
+	assert.Equal(t, AttemptActionStart, action)
+	assert.Nil(t, superseded)
+
+	queue := &ConcurrencyGroup{Name: "repo-master", Mode: v1alpha1.ConcurrencyQueue}
+	action, superseded = pc.AttemptInGroup("ns1", "wfr1", queue)
+	assert.Equal(t, AttemptActionStart, action)
+	assert.Nil(t, superseded)
+	action, _ = pc.AttemptInGroup("ns1", "wfr2", queue)
+	assert.Equal(t, AttemptActionQueued, action)
+	action, _ = pc.AttemptInGroup("ns1", "wfr3", queue)
+	assert.Equal(t, AttemptActionQueued, action)
+	action, _ = pc.AttemptInGroup("ns2", "wfr4", queue)
+	assert.Equal(t, AttemptActionStart, action)
+	pc.MarkFinished("ns1", "wf1", "wfr1")
+	action, _ = pc.AttemptInGroup("ns1", "wfr3", queue)
+	assert.Equal(t, AttemptActionQueued, action)
+	action, _ = pc.AttemptInGroup("ns1", "wfr2", queue)
+	assert.Equal(t, AttemptActionStart, action)
+	pc.MarkFinished("ns1", "wf1", "wfr2")
+	action, _ = pc.AttemptInGroup("ns1", "wfr3", queue)
+	assert.Equal(t, AttemptActionStart, action)
+
+	skip := &ConcurrencyGroup{Name: "repo-dev", Mode: v1alpha1.ConcurrencySkipIfRunning}
+	action, _ = pc.AttemptInGroup("ns1", "wfr5", skip)
+	assert.Equal(t, AttemptActionStart, action)
+	action, _ = pc.AttemptInGroup("ns1", "wfr6", skip)
+	assert.Equal(t, AttemptActionSkipped, action)
+	pc.MarkFinished("ns1", "wf1", "wfr5")
+	action, _ = pc.AttemptInGroup("ns1", "wfr7", skip)
+	assert.Equal(t, AttemptActionStart, action)
+
+	cancel := &ConcurrencyGroup{Name: "pr-1", Mode: v1alpha1.ConcurrencyCancelInProgress}
+	action, superseded = pc.AttemptInGroup("ns1", "wfr8", cancel)
+	assert.Equal(t, AttemptActionStart, action)
+	assert.Nil(t, superseded)
+	action, superseded = pc.AttemptInGroup("ns1", "wfr9", cancel)
+	assert.Equal(t, AttemptActionStart, action)
+	assert.Equal(t, []string{"wfr8"}, superseded)
+	action, superseded = pc.AttemptInGroup("ns1", "wfr9", cancel)
+	assert.Equal(t, AttemptActionStart, action)
+	assert.Nil(t, superseded)
+}
@@ -0,0 +1,147 @@
+package workflowrun
+
+import (
+	"fmt"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/caicloud/cyclone/pkg/apis/cyclone/v1alpha1"
+	"github.com/caicloud/cyclone/pkg/util"
+)
+
+const (
+	// defaultRetryBackoff is the default duration to wait before the first retry of a stage.
+	defaultRetryBackoff = 10 * time.Second
+	// defaultRetryMaxBackoff is the default maximum duration to wait between two retries of a stage.
+	defaultRetryMaxBackoff = 5 * time.Minute
+)
+
+// RetryStage records a failed attempt of the stage, and resets the stage to pending if it can be retried
+// according to its retry policy. It returns true if the stage would be retried, in this case, caller
+// should not mark the stage as failed. Stages without retry policy are never retried.
+func (o *operator) RetryStage(stage string, attempt *v1alpha1.StageAttempt) bool {
+	if o.wf == nil || util.IsWorkflowRunTerminated(o.wfr) {
+		return false
+	}
+	item := GetStageItem(o.wf, parentStage(o.wfr, stage))
+	if item == nil || item.Retry == nil {
+		return false
+	}
+
+	status, ok := o.wfr.Status.Stages[stage]
+	if !ok {
+		return false
+	}
+
+	// The attempt has already been recorded, for example, the same pod observed failed more than once.
+	for _, a := range status.Attempts {
+		if a.Pod == attempt.Pod {
+			return status.Status.Phase == v1alpha1.StatusPending && status.Status.Reason == v1alpha1.ReasonRetrying
+		}
+	}
+
+	status.Attempts = append(status.Attempts, *attempt)
+	retries := len(status.Attempts)
+	if retries > item.Retry.Limit || !RetryPolicyMatch(item.Retry, attempt) {
+		return false
+	}
+
+	backoff := RetryBackoff(item.Retry, retries)
+	log.WithField("wfr", o.wfr.Name).
+		WithField("stg", stage).
+		WithField("attempt", retries).
+		WithField("backoff", backoff).
+		Info("Stage failed, retry it")
+	o.recorder.Eventf(o.wfr, corev1.EventTypeWarning, v1alpha1.ReasonRetrying, "Stage '%s' failed (%s), retry %d/%d after %s", stage, attempt.Reason, retries, item.Retry.Limit, backoff)
+	o.UpdateStageStatus(stage, &v1alpha1.Status{
+		Phase:              v1alpha1.StatusPending,
+		Reason:             v1alpha1.ReasonRetrying,
+		Message:            fmt.Sprintf("Retry %d/%d, last attempt failed: %s %s", retries, item.Retry.Limit, attempt.Reason, attempt.Message),
+		LastTransitionTime: metav1.Time{Time: time.Now()},
+	})
+
+	return true
+}
+
+// RetryPolicyMatch checks whether a failed attempt matches the retry policy. If neither exit codes nor
+// reasons are specified in the policy, all failures are matched.
+func RetryPolicyMatch(policy *v1alpha1.RetryPolicy, attempt *v1alpha1.StageAttempt) bool {
+	if len(policy.ExitCodes) == 0 && len(policy.Reasons) == 0 {
+		return true
+	}
+
+	if attempt.ExitCode != nil {
+		for _, c := range policy.ExitCodes {
+			if c == *attempt.ExitCode {
+				return true
+			}
+		}
+	}
+
+	for _, r := range policy.Reasons {
+		if r == attempt.Reason {
+			return true
+		}
+	}
+
+	return false
+}
+
+// RetryBackoff calculates the duration to wait before the given retry (starts from 1).
+func RetryBackoff(policy *v1alpha1.RetryPolicy, retry int) time.Duration {
+	backoff, err := ParseTime(policy.Backoff)
+	if err != nil || backoff <= 0 {
+		backoff = defaultRetryBackoff
+	}
+	maxBackoff, err := ParseTime(policy.MaxBackoff)
+	if err != nil || maxBackoff <= 0 {
+		maxBackoff = defaultRetryMaxBackoff
+	}
+
+	for i := 1; i < retry && backoff < maxBackoff; i++ {
+		backoff *= 2
+	}
+	if backoff > maxBackoff {
+		backoff = maxBackoff
+	}
+
+	return backoff
+}
+
+// retryWaitTime returns how long a stage still needs to wait before it can be retried. 0 is returned
+// if the stage is not waiting for retry, or it's already time to retry.
+func retryWaitTime(wf *v1alpha1.Workflow, wfr *v1alpha1.WorkflowRun, stage string) time.Duration {
+	status, ok := wfr.Status.Stages[stage]
+	if !ok || status.Status.Phase != v1alpha1.StatusPending || status.Status.Reason != v1alpha1.ReasonRetrying {
+		return 0
+	}
+	item := GetStageItem(wf, parentStage(wfr, stage))
+	if item == nil || item.Retry == nil || len(status.Attempts) == 0 {
+		return 0
+	}
+
+	last := status.Attempts[len(status.Attempts)-1]
+	retryTime := last.FinishTime.Add(RetryBackoff(item.Retry, len(status.Attempts)))
+	if wait := time.Until(retryTime); wait > 0 {
+		return wait
+	}
+
+	return 0
+}
+
+// nextRetryAfter returns the shortest duration to wait for stages that are waiting to be retried,
+// 0 is returned if no stages are waiting.
+func nextRetryAfter(wf *v1alpha1.Workflow, wfr *v1alpha1.WorkflowRun) time.Duration {
+	var after time.Duration
+	for stage := range wfr.Status.Stages {
+		wait := retryWaitTime(wf, wfr, stage)
+		if wait > 0 && (after == 0 || wait < after) {
+			after = wait
+		}
+	}
+
+	return after
+}
@@ -0,0 +1,108 @@
+package workflowrun
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/caicloud/cyclone/pkg/apis/cyclone/v1alpha1"
+	"github.com/caicloud/cyclone/pkg/util/k8s/fake"
+)
+
+func TestRetryPolicyMatch(t *testing.T) {
+	exitCode := int32(137)
+	attempt := &v1alpha1.StageAttempt{
+		Pod:      "pod",
+		ExitCode: &exitCode,
+		Reason:   "OOMKilled",
+	}
+
+	assert.True(t, RetryPolicyMatch(&v1alpha1.RetryPolicy{}, attempt))
+	assert.True(t, RetryPolicyMatch(&v1alpha1.RetryPolicy{ExitCodes: []int32{1, 137}}, attempt))
+	assert.True(t, RetryPolicyMatch(&v1alpha1.RetryPolicy{Reasons: []string{"Evicted", "OOMKilled"}}, attempt))
+	assert.False(t, RetryPolicyMatch(&v1alpha1.RetryPolicy{ExitCodes: []int32{1}}, attempt))
+	assert.False(t, RetryPolicyMatch(&v1alpha1.RetryPolicy{Reasons: []string{"Evicted"}}, attempt))
+	assert.False(t, RetryPolicyMatch(&v1alpha1.RetryPolicy{ExitCodes: []int32{1}}, &v1alpha1.StageAttempt{Reason: "PodDeleted"}))
+}
+
+func TestRetryBackoff(t *testing.T) {
+	assert.Equal(t, defaultRetryBackoff, RetryBackoff(&v1alpha1.RetryPolicy{}, 1))
+	assert.Equal(t, 2*defaultRetryBackoff, RetryBackoff(&v1alpha1.RetryPolicy{}, 2))
+
+	policy := &v1alpha1.RetryPolicy{
+		Backoff:    "30s",
+		MaxBackoff: "1m",
+	}
+	assert.Equal(t, 30*time.Second, RetryBackoff(policy, 1))
+	assert.Equal(t, time.Minute, RetryBackoff(policy, 2))
+	assert.Equal(t, time.Minute, RetryBackoff(policy, 5))
+}
+
+func TestRetryStage(t *testing.T) {
+	wf := &v1alpha1.Workflow{
+		Spec: v1alpha1.WorkflowSpec{
+			Stages: []v1alpha1.StageItem{
+				{
+					Name: "A",
+				},
+				{
+					Name: "B",
+					Retry: &v1alpha1.RetryPolicy{
+						Limit:   1,
+						Backoff: "1h",
+						Reasons: []string{"Evicted"},
+					},
+				},
+			},
+		},
+	}
+	wfr := &v1alpha1.WorkflowRun{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "test",
+			Namespace: "default",
+		},
+		Status: v1alpha1.WorkflowRunStatus{
+			Stages: map[string]*v1alpha1.StageStatus{
+				"A": {
+					Status: v1alpha1.Status{Phase: v1alpha1.StatusRunning},
+				},
+				"B": {
+					Status: v1alpha1.Status{Phase: v1alpha1.StatusRunning},
+				},
+			},
+		},
+	}
+	o := &operator{
+		client:   fake.NewSimpleClientset(),
+		recorder: new(MockedRecorder),
+		wf:       wf,
+		wfr:      wfr,
+	}
+
+	// Stage without retry policy
+	assert.False(t, o.RetryStage("A", &v1alpha1.StageAttempt{Pod: "a-1", Reason: "Evicted"}))
+	assert.Empty(t, wfr.Status.Stages["A"].Attempts)
+
+	// Reason not matched
+	assert.False(t, o.RetryStage("B", &v1alpha1.StageAttempt{Pod: "b-1", Reason: "Error"}))
+	assert.Equal(t, 1, len(wfr.Status.Stages["B"].Attempts))
+
+	wfr.Status.Stages["B"].Attempts = nil
+	assert.True(t, o.RetryStage("B", &v1alpha1.StageAttempt{Pod: "b-1", Reason: "Evicted", FinishTime: metav1.Now()}))
+	assert.Equal(t, v1alpha1.StatusPending, wfr.Status.Stages["B"].Status.Phase)
+	assert.Equal(t, v1alpha1.ReasonRetrying, wfr.Status.Stages["B"].Status.Reason)
+	// Same pod observed again
+	assert.True(t, o.RetryStage("B", &v1alpha1.StageAttempt{Pod: "b-1", Reason: "Evicted"}))
+	assert.Equal(t, 1, len(wfr.Status.Stages["B"].Attempts))
+
+	// Stage is waiting for backoff, it should not be started.
+	assert.NotContains(t, NextStages(wf, wfr), "B")
+	assert.True(t, nextRetryAfter(wf, wfr) > 0)
+
+	// Retry limit exceeded
+	wfr.Status.Stages["B"].Status.Phase = v1alpha1.StatusRunning
+	assert.False(t, o.RetryStage("B", &v1alpha1.StageAttempt{Pod: "b-2", Reason: "Evicted"}))
+	assert.Equal(t, 2, len(wfr.Status.Stages["B"].Attempts))
+}
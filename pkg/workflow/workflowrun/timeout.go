@@ -6,6 +6,7 @@ import (
 	"regexp"
 	"strconv"
 	"strings"
+	"sync"
 	"time"
 
 	log "github.com/sirupsen/logrus"
@@ -62,26 +63,81 @@ func newWorkflowRunItem(wfr *v1alpha1.WorkflowRun) *workflowRunItem {
 	}
 }
 
-// TimeoutProcessor manages timeout of WorkflowRun.
+// stageItem keeps track of a running stage pod that has timeout configured.
+type stageItem struct {
+	workflowRunItem
+	// Name of the stage
+	stage string
+	// Name of the pod running the stage
+	pod string
+}
+
+// newStageItem creates item for a running stage, timeout is counted from the time the stage started running, so
+// that it's not reset when the controller restarts.
+func newStageItem(wfr *v1alpha1.WorkflowRun, stage, pod string, started time.Time, timeout time.Duration) *stageItem {
+	if started.IsZero() {
+		started = time.Now()
+	}
+	return &stageItem{
+		workflowRunItem: workflowRunItem{
+			name:       wfr.Name,
+			namespace:  wfr.Namespace,
+			expireTime: started.Add(timeout),
+		},
+		stage: stage,
+		pod:   pod,
+	}
+}
+
+func (i *stageItem) String() string {
+	return fmt.Sprintf("%s:%s:%s:%s", i.namespace, i.name, i.stage, i.pod)
+}
+
+// StageTimeout resolves timeout of a stage, timeout set in WorkflowRun stage parameters overrides the one
+// defined in Workflow.
+func StageTimeout(wfr *v1alpha1.WorkflowRun, stage *v1alpha1.StageItem) string {
+	for _, p := range wfr.Spec.StageParams {
+		if p.Name == stage.Name && len(p.Timeout) > 0 {
+			return p.Timeout
+		}
+	}
+
+	return stage.Timeout
+}
+
+// TimeoutProcessor manages timeout of WorkflowRun and its stages.
 type TimeoutProcessor struct {
 	client   k8s.Interface
 	recorder record.EventRecorder
-	items    map[string]*workflowRunItem
+	// lock guards items below, they are added from the informer and processed by the ticker.
+	lock          sync.Mutex
+	items         map[string]*workflowRunItem
+	stageItems    map[string]*stageItem
+	deadlineItems map[string]*stageItem
 }
 
 // NewTimeoutProcessor creates a timeout manager and run it.
 func NewTimeoutProcessor(client k8s.Interface) *TimeoutProcessor {
 	manager := &TimeoutProcessor{
-		client:   client,
-		recorder: common.GetEventRecorder(client, common.EventSourceWfrController),
-		items:    make(map[string]*workflowRunItem),
+		client:        client,
+		recorder:      common.GetEventRecorder(client, common.EventSourceWfrController),
+		items:         make(map[string]*workflowRunItem),
+		stageItems:    make(map[string]*stageItem),
+		deadlineItems: make(map[string]*stageItem),
 	}
 	go manager.Run(time.Second * 5)
 	return manager
 }
 
-// AddIfNotExist adds a WorkflowRun to the timeout manager if it is not exist.
+// AddIfNotExist adds a WorkflowRun to the timeout manager if it is not exist. Running stages of the
+// WorkflowRun with timeout configured and stages waiting with deadline are also added.
 func (m *TimeoutProcessor) AddIfNotExist(wfr *v1alpha1.WorkflowRun) error {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	m.addStagesIfNotExist(wfr)
+	m.addDeadlinesIfNotExist(wfr)
+
 	item := newWorkflowRunItem(wfr)
 	key := item.String()
 	if _, ok := m.items[key]; ok {
@@ -98,28 +154,234 @@ func (m *TimeoutProcessor) AddIfNotExist(wfr *v1alpha1.WorkflowRun) error {
 	return nil
 }
 
+// addStagesIfNotExist adds running stages of the WorkflowRun that have timeout configured. Each stage pod is
+// tracked separately, so that a retried stage gets its timeout reset.
+func (m *TimeoutProcessor) addStagesIfNotExist(wfr *v1alpha1.WorkflowRun) {
+	for stage, status := range wfr.Status.Stages {
+		if status.Status.Phase != v1alpha1.StatusRunning || status.Pod == nil || len(status.Timeout) == 0 {
+			continue
+		}
+
+		timeout, err := ParseTime(status.Timeout)
+		if err != nil {
+			log.WithField("wfr", wfr.Name).WithField("stg", stage).Warnf("Invalid stage timeout value '%s', error: %v", status.Timeout, err)
+			continue
+		}
+
+		item := newStageItem(wfr, stage, status.Pod.Name, status.Status.LastTransitionTime.Time, timeout)
+		if _, ok := m.stageItems[item.String()]; ok {
+			continue
+		}
+		m.stageItems[item.String()] = item
+	}
+}
+
+// stageDeadline gets deadline of a stage that is waiting for approval or delegation report, nil is returned
+// if the stage has no deadline or it's not waiting anymore. Reason to fail the stage when deadline exceeded is
+// also returned.
+func stageDeadline(status *v1alpha1.StageStatus) (*metav1.Time, string) {
+	phase := status.Status.Phase
+	if status.Approval != nil && phase == v1alpha1.StatusWaiting && len(status.Approval.Approver) == 0 {
+		return status.Approval.Deadline, v1alpha1.ReasonApprovalTimeout
+	}
+	if status.Delegation != nil && (phase == v1alpha1.StatusWaiting || phase == v1alpha1.StatusRunning) {
+		return status.Delegation.Deadline, v1alpha1.ReasonDelegationTimeout
+	}
+
+	return nil, ""
+}
+
+// addDeadlinesIfNotExist adds stages of the WorkflowRun that are waiting for approval or delegation report
+// with a deadline.
+func (m *TimeoutProcessor) addDeadlinesIfNotExist(wfr *v1alpha1.WorkflowRun) {
+	for stage, status := range wfr.Status.Stages {
+		deadline, _ := stageDeadline(status)
+		if deadline == nil {
+			continue
+		}
+
+		item := &stageItem{
+			workflowRunItem: workflowRunItem{
+				name:       wfr.Name,
+				namespace:  wfr.Namespace,
+				expireTime: deadline.Time,
+			},
+			stage: stage,
+		}
+		if existing, ok := m.deadlineItems[item.String()]; ok && existing.expireTime.Equal(item.expireTime) {
+			continue
+		}
+		m.deadlineItems[item.String()] = item
+	}
+}
+
+// removeItem stops tracking timeout of the WorkflowRun.
+func (m *TimeoutProcessor) removeItem(key string) {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	delete(m.items, key)
+}
+
+// removeStageItem stops tracking timeout or deadline of the stage.
+func (m *TimeoutProcessor) removeStageItem(items map[string]*stageItem, key string) {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	delete(items, key)
+}
+
 // Run will check timeout of managed WorkflowRun and process items that have expired their time.
 func (m *TimeoutProcessor) Run(interval time.Duration) {
 	ticker := time.NewTicker(interval)
 	for range ticker.C {
+		m.processStages()
+		m.processDeadlines()
 		m.process()
 	}
 }
 
+// processStages kills stage pods that have expired their time, and marks the stages failed. Trivial stages
+// failed in this way would not fail the WorkflowRun, so the rest of the workflow can continue.
+func (m *TimeoutProcessor) processStages() {
+	var expired []*stageItem
+	m.lock.Lock()
+	for _, v := range m.stageItems {
+		if v.expireTime.Before(time.Now()) {
+			expired = append(expired, v)
+		}
+	}
+	m.lock.Unlock()
+
+	for _, i := range expired {
+		wfr, err := m.client.CycloneV1alpha1().WorkflowRuns(i.namespace).Get(context.TODO(), i.name, metav1.GetOptions{})
+		if err != nil {
+			if errors.IsNotFound(err) {
+				m.removeStageItem(m.stageItems, i.String())
+			} else {
+				log.WithField("wfr", i.name).Error("Get WorkflowRun error: ", err)
+			}
+			continue
+		}
+
+		// The stage pod has already finished, or replaced by a new one due to retry.
+		status, ok := wfr.Status.Stages[i.stage]
+		if !ok || status.Status.Phase != v1alpha1.StatusRunning || status.Pod == nil || status.Pod.Name != i.pod {
+			m.removeStageItem(m.stageItems, i.String())
+			continue
+		}
+
+		clusterClient := common.GetExecutionClusterClient(wfr)
+		if clusterClient == nil {
+			log.WithField("wfr", wfr.Name).Error("Execution cluster client not found")
+			continue
+		}
+
+		log.WithField("wfr", wfr.Name).WithField("stg", i.stage).WithField("pod", i.pod).Info("Start to process expired stage")
+		m.recorder.Eventf(wfr, corev1.EventTypeWarning, v1alpha1.ReasonStageTimeout, "Stage '%s' execution timeout", i.stage)
+
+		// Update stage status before deleting the pod, so that the pod deletion won't be regarded as failure.
+		operator := operator{
+			clusterClient: clusterClient,
+			client:        m.client,
+			wfr:           wfr,
+		}
+		operator.UpdateStageStatus(i.stage, &v1alpha1.Status{
+			Phase:              v1alpha1.StatusFailed,
+			Reason:             v1alpha1.ReasonStageTimeout,
+			Message:            fmt.Sprintf("Stage execution exceeded timeout %s", status.Timeout),
+			LastTransitionTime: metav1.Time{Time: time.Now()},
+		})
+		if err = operator.Update(); err != nil {
+			log.WithField("wfr", wfr.Name).WithField("stg", i.stage).Error("Update WorkflowRun status error: ", err)
+			continue
+		}
+
+		err = clusterClient.CoreV1().Pods(status.Pod.Namespace).Delete(context.TODO(), status.Pod.Name, metav1.DeleteOptions{})
+		if err != nil && !errors.IsNotFound(err) {
+			log.WithField("wfr", wfr.Name).WithField("pod", status.Pod.Name).Error("Delete pod error: ", err)
+		}
+
+		m.removeStageItem(m.stageItems, i.String())
+	}
+}
+
+// processDeadlines fails stages that are not approved, rejected or reported finished before their deadline,
+// and resumes the WorkflowRun so that the rest of the workflow can be processed.
+func (m *TimeoutProcessor) processDeadlines() {
+	var expired []*stageItem
+	m.lock.Lock()
+	for _, v := range m.deadlineItems {
+		if v.expireTime.Before(time.Now()) {
+			expired = append(expired, v)
+		}
+	}
+	m.lock.Unlock()
+
+	for _, i := range expired {
+		wfr, err := m.client.CycloneV1alpha1().WorkflowRuns(i.namespace).Get(context.TODO(), i.name, metav1.GetOptions{})
+		if err != nil {
+			if errors.IsNotFound(err) {
+				m.removeStageItem(m.deadlineItems, i.String())
+			} else {
+				log.WithField("wfr", i.name).Error("Get WorkflowRun error: ", err)
+			}
+			continue
+		}
+
+		// The stage has already finished, or the deadline has been reset.
+		status, ok := wfr.Status.Stages[i.stage]
+		if !ok {
+			m.removeStageItem(m.deadlineItems, i.String())
+			continue
+		}
+		deadline, reason := stageDeadline(status)
+		if deadline == nil || !deadline.Time.Equal(i.expireTime) {
+			m.removeStageItem(m.deadlineItems, i.String())
+			continue
+		}
+
+		log.WithField("wfr", wfr.Name).WithField("stg", i.stage).Info("Start to process expired stage deadline")
+		m.recorder.Eventf(wfr, corev1.EventTypeWarning, reason, "Stage '%s' deadline exceeded", i.stage)
+
+		operator := operator{
+			clusterClient: common.GetExecutionClusterClient(wfr),
+			client:        m.client,
+			wfr:           wfr,
+		}
+		operator.UpdateStageStatus(i.stage, &v1alpha1.Status{
+			Phase:              v1alpha1.StatusFailed,
+			Reason:             reason,
+			Message:            fmt.Sprintf("Stage was not finished before deadline %s", deadline.Format(time.RFC3339)),
+			LastTransitionTime: metav1.Time{Time: time.Now()},
+		})
+		if wfr.Status.Overall.Phase == v1alpha1.StatusWaiting {
+			wfr.Status.Overall.Phase = v1alpha1.StatusRunning
+			wfr.Status.Overall.LastTransitionTime = metav1.Time{Time: time.Now()}
+		}
+		if err = operator.Update(); err != nil {
+			log.WithField("wfr", wfr.Name).WithField("stg", i.stage).Error("Update WorkflowRun status error: ", err)
+			continue
+		}
+
+		m.removeStageItem(m.deadlineItems, i.String())
+	}
+}
+
 func (m *TimeoutProcessor) process() {
 	var expired []*workflowRunItem
+	m.lock.Lock()
 	for _, v := range m.items {
 		if v.expireTime.Before(time.Now()) {
 			expired = append(expired, v)
 		}
 	}
+	m.lock.Unlock()
 
 	for _, i := range expired {
 		log.WithField("wfr", i.name).WithField("namespace", i.namespace).Info("Start to process expired WorkflowRun")
 		wfr, err := m.client.CycloneV1alpha1().WorkflowRuns(i.namespace).Get(context.TODO(), i.name, metav1.GetOptions{})
 		if err != nil {
 			if errors.IsNotFound(err) {
-				delete(m.items, i.String())
+				m.removeItem(i.String())
 			} else {
 				log.WithField("wfr", wfr.Name).Error("Get WorkflowRun error: ", err)
 			}
@@ -165,7 +427,7 @@ func (m *TimeoutProcessor) process() {
 			}
 		}
 
-		delete(m.items, i.String())
+		m.removeItem(i.String())
 		m.recorder.Event(wfr, corev1.EventTypeWarning, "Timeout", "Stages stopped due to timeout")
 	}
 }
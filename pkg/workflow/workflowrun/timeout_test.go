@@ -1,17 +1,21 @@
 package workflowrun
 
 import (
+	"context"
 	"testing"
 	"time"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
 	"github.com/stretchr/testify/suite"
+	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
 
 	"github.com/caicloud/cyclone/pkg/apis/cyclone/v1alpha1"
+	"github.com/caicloud/cyclone/pkg/common"
 	"github.com/caicloud/cyclone/pkg/util/k8s/fake"
+	"github.com/caicloud/cyclone/pkg/workflow/controller/store"
 )
 
 func TestParseTime(t *testing.T) {
@@ -112,6 +116,24 @@ func TestNewWorkflowRunItem(t *testing.T) {
 	}
 }
 
+func TestNewStageItem(t *testing.T) {
+	wfr := &v1alpha1.WorkflowRun{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "test",
+			Namespace: "default",
+		},
+	}
+
+	// Timeout is counted from the time the stage started, so a restarted controller doesn't reset it.
+	started := time.Now().Add(-time.Hour)
+	item := newStageItem(wfr, "stg1", "stg1-pod", started, time.Minute*30)
+	assert.Equal(t, started.Add(time.Minute*30), item.expireTime)
+	assert.Equal(t, "default:test:stg1:stg1-pod", item.String())
+
+	item = newStageItem(wfr, "stg1", "stg1-pod", time.Time{}, time.Minute*30)
+	assert.InDelta(t, time.Now().Add(time.Minute*30).Unix(), item.expireTime.Unix(), 1)
+}
+
 type MockedRecorder struct {
 	mock.Mock
 }
@@ -132,12 +154,17 @@ type TimeoutProcessorSuite struct {
 
 func (suite *TimeoutProcessorSuite) SetupTest() {
 	client := fake.NewSimpleClientset()
+	store.ControllerRegistry[common.ControlClusterName] = &store.ClusterController{
+		Client: client,
+	}
 	recorder := new(MockedRecorder)
 	recorder.On("Event", mock.Anything).Return()
 	suite.processor = &TimeoutProcessor{
-		client:   client,
-		recorder: recorder,
-		items:    make(map[string]*workflowRunItem),
+		client:        client,
+		recorder:      recorder,
+		items:         make(map[string]*workflowRunItem),
+		stageItems:    make(map[string]*stageItem),
+		deadlineItems: make(map[string]*stageItem),
 	}
 }
 
@@ -213,6 +240,141 @@ func (suite *TimeoutProcessorSuite) TestProcess() {
 	suite.Nil(suite.processor.items["default:test1"])
 }
 
+func (suite *TimeoutProcessorSuite) TestProcessStages() {
+	wfr := &v1alpha1.WorkflowRun{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "test1",
+			Namespace: "default",
+		},
+		Spec: v1alpha1.WorkflowRunSpec{
+			WorkflowRef: &corev1.ObjectReference{
+				Name: "wf",
+			},
+			Timeout: "1h",
+		},
+		Status: v1alpha1.WorkflowRunStatus{
+			Stages: map[string]*v1alpha1.StageStatus{
+				"stg1": {
+					Status:  v1alpha1.Status{Phase: v1alpha1.StatusRunning},
+					Timeout: "1s",
+					Pod: &v1alpha1.PodInfo{
+						Name:      "stg1-pod",
+						Namespace: "default",
+					},
+				},
+				"stg2": {
+					Status:  v1alpha1.Status{Phase: v1alpha1.StatusRunning},
+					Timeout: "1h",
+					Pod: &v1alpha1.PodInfo{
+						Name:      "stg2-pod",
+						Namespace: "default",
+					},
+				},
+				"stg3": {
+					Status: v1alpha1.Status{Phase: v1alpha1.StatusRunning},
+					Pod: &v1alpha1.PodInfo{
+						Name:      "stg3-pod",
+						Namespace: "default",
+					},
+				},
+			},
+		},
+	}
+	_, err := suite.processor.client.CycloneV1alpha1().WorkflowRuns("default").Create(context.TODO(), wfr, metav1.CreateOptions{})
+	suite.Nil(err)
+
+	suite.Nil(suite.processor.AddIfNotExist(wfr))
+	suite.Equal(2, len(suite.processor.stageItems))
+	suite.NotNil(suite.processor.stageItems["default:test1:stg1:stg1-pod"])
+
+	time.Sleep(time.Second)
+	suite.processor.processStages()
+	suite.Equal(1, len(suite.processor.stageItems))
+	suite.Nil(suite.processor.stageItems["default:test1:stg1:stg1-pod"])
+
+	latest, err := suite.processor.client.CycloneV1alpha1().WorkflowRuns("default").Get(context.TODO(), "test1", metav1.GetOptions{})
+	suite.Nil(err)
+	suite.Equal(v1alpha1.StatusFailed, latest.Status.Stages["stg1"].Status.Phase)
+	suite.Equal(v1alpha1.ReasonStageTimeout, latest.Status.Stages["stg1"].Status.Reason)
+	suite.Equal(v1alpha1.StatusRunning, latest.Status.Stages["stg2"].Status.Phase)
+}
+
+func (suite *TimeoutProcessorSuite) TestProcessDeadlines() {
+	expired := metav1.NewTime(time.Now().Add(-time.Second))
+	deadline := metav1.NewTime(time.Now().Add(time.Hour))
+	wfr := &v1alpha1.WorkflowRun{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "test1",
+			Namespace: "default",
+		},
+		Spec: v1alpha1.WorkflowRunSpec{
+			WorkflowRef: &corev1.ObjectReference{
+				Name: "wf",
+			},
+			Timeout: "1h",
+		},
+		Status: v1alpha1.WorkflowRunStatus{
+			Overall: v1alpha1.Status{Phase: v1alpha1.StatusWaiting},
+			Stages: map[string]*v1alpha1.StageStatus{
+				"stg1": {
+					Status:   v1alpha1.Status{Phase: v1alpha1.StatusWaiting},
+					Approval: &v1alpha1.ApprovalStatus{Deadline: &expired},
+				},
+				"stg2": {
+					Status:   v1alpha1.Status{Phase: v1alpha1.StatusWaiting},
+					Approval: &v1alpha1.ApprovalStatus{Deadline: &deadline},
+				},
+				"stg3": {
+					Status:   v1alpha1.Status{Phase: v1alpha1.StatusWaiting},
+					Approval: &v1alpha1.ApprovalStatus{},
+				},
+				"stg4": {
+					Status:     v1alpha1.Status{Phase: v1alpha1.StatusRunning},
+					Delegation: &v1alpha1.DelegationStatus{Deadline: &expired},
+				},
+			},
+		},
+	}
+	_, err := suite.processor.client.CycloneV1alpha1().WorkflowRuns("default").Create(context.TODO(), wfr, metav1.CreateOptions{})
+	suite.Nil(err)
+
+	suite.Nil(suite.processor.AddIfNotExist(wfr))
+	suite.Equal(3, len(suite.processor.deadlineItems))
+
+	suite.processor.processDeadlines()
+	suite.Equal(1, len(suite.processor.deadlineItems))
+	suite.NotNil(suite.processor.deadlineItems["default:test1:stg2:"])
+
+	latest, err := suite.processor.client.CycloneV1alpha1().WorkflowRuns("default").Get(context.TODO(), "test1", metav1.GetOptions{})
+	suite.Nil(err)
+	suite.Equal(v1alpha1.StatusFailed, latest.Status.Stages["stg1"].Status.Phase)
+	suite.Equal(v1alpha1.ReasonApprovalTimeout, latest.Status.Stages["stg1"].Status.Reason)
+	suite.Equal(v1alpha1.StatusWaiting, latest.Status.Stages["stg2"].Status.Phase)
+	suite.Equal(v1alpha1.StatusFailed, latest.Status.Stages["stg4"].Status.Phase)
+	suite.Equal(v1alpha1.ReasonDelegationTimeout, latest.Status.Stages["stg4"].Status.Reason)
+	suite.Equal(v1alpha1.StatusRunning, latest.Status.Overall.Phase)
+}
+
+func TestStageTimeout(t *testing.T) {
+	stage := &v1alpha1.StageItem{
+		Name:    "stg1",
+		Timeout: "10m",
+	}
+	wfr := &v1alpha1.WorkflowRun{}
+	assert.Equal(t, "10m", StageTimeout(wfr, stage))
+
+	wfr.Spec.StageParams = []v1alpha1.ParameterConfig{
+		{
+			Name: "stg2",
+		},
+		{
+			Name:    "stg1",
+			Timeout: "1h",
+		},
+	}
+	assert.Equal(t, "1h", StageTimeout(wfr, stage))
+}
+
 func TestTimeoutProcessorSuite(t *testing.T) {
 	suite.Run(t, new(TimeoutProcessorSuite))
 }
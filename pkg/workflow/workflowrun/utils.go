@@ -37,28 +37,41 @@ func resolveStatus(latest, update *v1alpha1.Status) *v1alpha1.Status {
 }
 
 // NextStages determine next stages that can be started to execute. It returns
-// stages that are not started yet but have all depended stages finished.
+// stages that are not started yet but have all depended stages finished. For matrix
+// stages, instances that are not started yet are returned instead.
 func NextStages(wf *v1alpha1.Workflow, wfr *v1alpha1.WorkflowRun) []string {
 	var nextStages []string
 	for _, stage := range wf.Spec.Stages {
+		s, ok := wfr.Status.Stages[stage.Name]
+
+		// Instances of matrix stage are started individually, they may be retried separately.
+		if ok && len(s.Instances) > 0 {
+			if util.IsPhaseTerminated(s.Status.Phase) || !dependsFinished(wf, wfr, &stage) {
+				continue
+			}
+			for _, instance := range s.Instances {
+				if is, ok := wfr.Status.Stages[instance]; ok && is.Status.Phase != v1alpha1.StatusPending {
+					continue
+				}
+				if retryWaitTime(wf, wfr, instance) > 0 {
+					continue
+				}
+				nextStages = append(nextStages, instance)
+			}
+			continue
+		}
+
 		// If this stage already have status set and not pending, it means it's already been started, skip it.
-		if s, ok := wfr.Status.Stages[stage.Name]; ok && s.Status.Phase != v1alpha1.StatusPending {
+		if ok && s.Status.Phase != v1alpha1.StatusPending {
 			continue
 		}
 
-		// All depended stages must have been successfully finished, otherwise this
-		// stage would be skipped.
-		safeToRun := true
-		for _, d := range stage.Depends {
-			status, ok := wfr.Status.Stages[d]
-			if !(ok && (status.Status.Phase == v1alpha1.StatusSucceeded || (status.Status.Phase == v1alpha1.StatusFailed && IsTrivial(wf, d)) ||
-				(status.Status.Phase == v1alpha1.StatusCancelled && IsTrivial(wf, d)))) {
-				safeToRun = false
-				break
-			}
+		// If this stage is waiting to be retried, skip it until the retry backoff expired.
+		if retryWaitTime(wf, wfr, stage.Name) > 0 {
+			continue
 		}
 
-		if safeToRun {
+		if dependsFinished(wf, wfr, &stage) {
 			nextStages = append(nextStages, stage.Name)
 		}
 	}
@@ -66,6 +79,65 @@ func NextStages(wf *v1alpha1.Workflow, wfr *v1alpha1.WorkflowRun) []string {
 	return nextStages
 }
 
+// dependsFinished checks whether all depended stages of a stage have been successfully finished or skipped,
+// failure of trivial stages is tolerated.
+func dependsFinished(wf *v1alpha1.Workflow, wfr *v1alpha1.WorkflowRun, stage *v1alpha1.StageItem) bool {
+	for _, d := range stage.Depends {
+		status, ok := wfr.Status.Stages[d]
+		if !(ok && (status.Status.Phase == v1alpha1.StatusSucceeded || status.Status.Phase == v1alpha1.StatusSkipped ||
+			(status.Status.Phase == v1alpha1.StatusFailed && IsTrivial(wf, d)) ||
+			(status.Status.Phase == v1alpha1.StatusCancelled && IsTrivial(wf, d)))) {
+			return false
+		}
+	}
+
+	return true
+}
+
+// StartedStages returns stages that would be executed when the WorkflowRun starts from the given start
+// stages, they are start stages and stages depend on them directly or indirectly. nil is returned if no
+// start stages given, which means all stages would be executed.
+func StartedStages(wf *v1alpha1.Workflow, startStages []string) map[string]bool {
+	if len(startStages) == 0 {
+		return nil
+	}
+
+	started := make(map[string]bool)
+	for _, s := range startStages {
+		started[s] = true
+	}
+
+	// Propagate to dependent stages until no more stages found, stages in a workflow are DAG.
+	for changed := true; changed; {
+		changed = false
+		for _, stage := range wf.Spec.Stages {
+			if started[stage.Name] {
+				continue
+			}
+			for _, d := range stage.Depends {
+				if started[d] {
+					started[stage.Name] = true
+					changed = true
+					break
+				}
+			}
+		}
+	}
+
+	return started
+}
+
+// GetStageItem returns the stage item with the given name in a workflow, nil is returned if not found.
+func GetStageItem(wf *v1alpha1.Workflow, stage string) *v1alpha1.StageItem {
+	for i := range wf.Spec.Stages {
+		if wf.Spec.Stages[i].Name == stage {
+			return &wf.Spec.Stages[i]
+		}
+	}
+
+	return nil
+}
+
 // IsTrivial returns whether a stage is trivial in a workflow
 func IsTrivial(wf *v1alpha1.Workflow, stage string) bool {
 	for _, s := range wf.Spec.Stages {
@@ -177,6 +177,67 @@ func TestNextStages(t *testing.T) {
 	expected = []string{"C"}
 	nexts = NextStages(wf, wfr)
 	assert.Equal(t, expected, nexts)
+
+	wfr = &v1alpha1.WorkflowRun{
+		Status: v1alpha1.WorkflowRunStatus{
+			Stages: map[string]*v1alpha1.StageStatus{
+				"A": {
+					Status: v1alpha1.Status{Phase: v1alpha1.StatusSkipped},
+				},
+			},
+		},
+	}
+	expected = []string{"B", "C"}
+	nexts = NextStages(wf, wfr)
+	assert.Equal(t, expected, nexts)
+}
+
+func TestGetStageItem(t *testing.T) {
+	wf := &v1alpha1.Workflow{
+		Spec: v1alpha1.WorkflowSpec{
+			Stages: []v1alpha1.StageItem{
+				{
+					Name: "A",
+				},
+				{
+					Name: "B",
+					When: "scm.tag != \"\"",
+				},
+			},
+		},
+	}
+	assert.Equal(t, "scm.tag != \"\"", GetStageItem(wf, "B").When)
+	assert.Nil(t, GetStageItem(wf, "C"))
+}
+
+func TestStartedStages(t *testing.T) {
+	wf := &v1alpha1.Workflow{
+		Spec: v1alpha1.WorkflowSpec{
+			Stages: []v1alpha1.StageItem{
+				{
+					Name: "A",
+				},
+				{
+					Name:    "B",
+					Depends: []string{"A"},
+				},
+				{
+					Name:    "C",
+					Depends: []string{"D"},
+				},
+				{
+					Name:    "D",
+					Depends: []string{"B"},
+				},
+				{
+					Name: "E",
+				},
+			},
+		},
+	}
+	assert.Nil(t, StartedStages(wf, nil))
+	assert.Equal(t, map[string]bool{"B": true, "C": true, "D": true}, StartedStages(wf, []string{"B"}))
+	assert.Equal(t, map[string]bool{"C": true, "E": true}, StartedStages(wf, []string{"C", "E"}))
 }
 
 func TestStaticStatus(t *testing.T) {
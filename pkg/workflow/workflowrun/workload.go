@@ -11,33 +11,38 @@ import (
 	"k8s.io/client-go/kubernetes"
 
 	"github.com/caicloud/cyclone/pkg/apis/cyclone/v1alpha1"
-	"github.com/caicloud/cyclone/pkg/util"
 	"github.com/caicloud/cyclone/pkg/util/k8s"
+	"github.com/caicloud/cyclone/pkg/workflow/controller"
+	"github.com/caicloud/cyclone/pkg/workflow/values/ref"
 	"github.com/caicloud/cyclone/pkg/workflow/workload/delegation"
 	"github.com/caicloud/cyclone/pkg/workflow/workload/pod"
 )
 
-// WorkloadProcessor processes stage workload. There are kinds of workload supported: pod, delegation.
+// WorkloadProcessor processes stage workload. There are kinds of workload supported: pod, delegation, approval.
 // With pod, Cyclone would create a pod to run the stage. With delegation, Cyclone would send
-// a POST request to the given URL in the workload spec.
+// a POST request to the given URL in the workload spec. With approval, the stage would wait
+// for approvers to approve or reject it.
 type WorkloadProcessor struct {
 	clusterClient   kubernetes.Interface
 	client          k8s.Interface
 	wf              *v1alpha1.Workflow
 	wfr             *v1alpha1.WorkflowRun
 	stg             *v1alpha1.Stage
+	instance        string
 	wfrOper         Operator
 	podEventWatcher PodEventWatcher
 }
 
-// NewWorkloadProcessor ...
-func NewWorkloadProcessor(clusterClient kubernetes.Interface, client k8s.Interface, wf *v1alpha1.Workflow, wfr *v1alpha1.WorkflowRun, stage *v1alpha1.Stage, wfrOperator Operator) *WorkloadProcessor {
+// NewWorkloadProcessor creates a workload processor, 'instance' is name of the stage instance to run, it's
+// the stage name itself for normal stages, and instance name for instances expanded from matrix stages.
+func NewWorkloadProcessor(clusterClient kubernetes.Interface, client k8s.Interface, wf *v1alpha1.Workflow, wfr *v1alpha1.WorkflowRun, stage *v1alpha1.Stage, instance string, wfrOperator Operator) *WorkloadProcessor {
 	return &WorkloadProcessor{
 		client:          client,
 		clusterClient:   clusterClient,
 		wf:              wf,
 		wfr:             wfr,
 		stg:             stage,
+		instance:        instance,
 		wfrOper:         wfrOperator,
 		podEventWatcher: newPodEventWatcher(clusterClient, client, wfr.Namespace, wfr.Name),
 	}
@@ -45,11 +50,17 @@ func NewWorkloadProcessor(clusterClient kubernetes.Interface, client k8s.Interfa
 
 // Process processes the stage according to workload type.
 func (p *WorkloadProcessor) Process() error {
-	if p.stg.Spec.Pod != nil && p.stg.Spec.Delegation != nil {
-		return fmt.Errorf("exact 1 workload (pod or delegation) expected in stage '%s/%s', but got both", p.stg.Namespace, p.stg.Name)
+	var workloads int
+	for _, w := range []bool{p.stg.Spec.Pod != nil, p.stg.Spec.Delegation != nil, p.stg.Spec.Approval != nil} {
+		if w {
+			workloads++
+		}
+	}
+	if workloads > 1 {
+		return fmt.Errorf("exact 1 workload (pod, delegation or approval) expected in stage '%s/%s', but got %d", p.stg.Namespace, p.stg.Name, workloads)
 	}
-	if p.stg.Spec.Pod == nil && p.stg.Spec.Delegation == nil {
-		return fmt.Errorf("exact 1 workload (pod or delegation) expected in stage '%s/%s', but got none", p.stg.Namespace, p.stg.Name)
+	if workloads == 0 {
+		return fmt.Errorf("exact 1 workload (pod, delegation or approval) expected in stage '%s/%s', but got none", p.stg.Namespace, p.stg.Name)
 	}
 
 	if p.stg.Spec.Pod != nil {
@@ -57,18 +68,36 @@ func (p *WorkloadProcessor) Process() error {
 	}
 
 	if p.stg.Spec.Delegation != nil {
+		if p.instance != p.stg.Name {
+			err := fmt.Errorf("matrix is not supported for delegation workload in stage '%s/%s'", p.stg.Namespace, p.stg.Name)
+			p.wfrOper.UpdateStageStatus(p.instance, &v1alpha1.Status{
+				Phase:              v1alpha1.StatusFailed,
+				Reason:             "DelegationFailure",
+				LastTransitionTime: metav1.Time{Time: time.Now()},
+				Message:            err.Error(),
+			})
+			return err
+		}
 		return p.processDelegation()
 	}
 
+	if p.stg.Spec.Approval != nil {
+		return p.processApproval()
+	}
+
 	return nil
 }
 
 func (p *WorkloadProcessor) processPod() error {
 	// Generate pod for this stage.
-	po, err := pod.NewBuilder(p.client, p.wf, p.wfr, p.stg).Build()
+	builder := pod.NewBuilder(p.client, p.clusterClient, p.wf, p.wfr, p.stg)
+	if p.instance != p.stg.Name {
+		builder = pod.NewInstanceBuilder(p.client, p.clusterClient, p.wf, p.wfr, p.stg, p.instance)
+	}
+	po, err := builder.Build()
 	if err != nil {
-		p.wfrOper.GetRecorder().Eventf(p.wfr, corev1.EventTypeWarning, "GeneratePodSpecError", "Generate pod for stage '%s' error: %v", p.stg.Name, err)
-		p.wfrOper.UpdateStageStatus(p.stg.Name, &v1alpha1.Status{
+		p.wfrOper.GetRecorder().Eventf(p.wfr, corev1.EventTypeWarning, "GeneratePodSpecError", "Generate pod for stage '%s' error: %v", p.instance, err)
+		p.wfrOper.UpdateStageStatus(p.instance, &v1alpha1.Status{
 			Phase:              v1alpha1.StatusFailed,
 			Reason:             "GeneratePodError",
 			LastTransitionTime: metav1.Time{Time: time.Now()},
@@ -76,18 +105,18 @@ func (p *WorkloadProcessor) processPod() error {
 		})
 		return fmt.Errorf("create pod manifest: %w", err)
 	}
-	log.WithField("stg", p.stg.Name).Debug("Pod manifest created")
+	log.WithField("stg", p.instance).Debug("Pod manifest created")
 
 	po, err = p.clusterClient.CoreV1().Pods(pod.GetExecutionContext(p.wfr).Namespace).Create(context.TODO(), po, metav1.CreateOptions{})
 	if err != nil {
-		p.wfrOper.GetRecorder().Eventf(p.wfr, corev1.EventTypeWarning, "StagePodCreated", "Create pod for stage '%s' error: %v", p.stg.Name, err)
+		p.wfrOper.GetRecorder().Eventf(p.wfr, corev1.EventTypeWarning, "StagePodCreated", "Create pod for stage '%s' error: %v", p.instance, err)
 		var phase v1alpha1.StatusPhase
 		if isExceededQuotaError(err) {
 			phase = v1alpha1.StatusPending
 		} else {
 			phase = v1alpha1.StatusFailed
 		}
-		p.wfrOper.UpdateStageStatus(p.stg.Name, &v1alpha1.Status{
+		p.wfrOper.UpdateStageStatus(p.instance, &v1alpha1.Status{
 			Phase:              phase,
 			Reason:             "CreatePodError",
 			LastTransitionTime: metav1.Time{Time: time.Now()},
@@ -96,18 +125,18 @@ func (p *WorkloadProcessor) processPod() error {
 		return fmt.Errorf("create pod: %w", err)
 	}
 
-	log.WithField("wfr", p.wfr.Name).WithField("stg", p.stg.Name).WithField("pod", po.Name).Debug("Create pod for stage succeeded")
-	p.wfrOper.GetRecorder().Eventf(p.wfr, corev1.EventTypeNormal, "StagePodCreated", "Create pod for stage '%s' succeeded", p.stg.Name)
+	log.WithField("wfr", p.wfr.Name).WithField("stg", p.instance).WithField("pod", po.Name).Debug("Create pod for stage succeeded")
+	p.wfrOper.GetRecorder().Eventf(p.wfr, corev1.EventTypeNormal, "StagePodCreated", "Create pod for stage '%s' succeeded", p.instance)
 
-	go p.podEventWatcher.Work(p.stg.Name, po.Namespace, po.Name)
+	go p.podEventWatcher.Work(p.instance, po.Namespace, po.Name)
 
-	p.wfrOper.UpdateStageStatus(p.stg.Name, &v1alpha1.Status{
+	p.wfrOper.UpdateStageStatus(p.instance, &v1alpha1.Status{
 		Phase:              v1alpha1.StatusRunning,
 		LastTransitionTime: metav1.Time{Time: time.Now()},
 		Reason:             "StagePodCreated",
 	})
 
-	p.wfrOper.UpdateStagePodInfo(p.stg.Name, &v1alpha1.PodInfo{
+	p.wfrOper.UpdateStagePodInfo(p.instance, &v1alpha1.PodInfo{
 		Name:      po.Name,
 		Namespace: po.Namespace,
 	})
@@ -116,42 +145,122 @@ func (p *WorkloadProcessor) processPod() error {
 }
 
 func (p *WorkloadProcessor) processDelegation() error {
-	err := delegation.Delegate(&delegation.Request{
+	spec := p.stg.Spec.Delegation
+	callback, status, err := p.delegationCallback()
+	if err != nil {
+		return p.delegationFailed(err)
+	}
+
+	var secret string
+	if spec.Auth != nil {
+		secretGetter := func(ns, name string) (*corev1.Secret, error) {
+			return p.client.CoreV1().Secrets(ns).Get(context.TODO(), name, metav1.GetOptions{})
+		}
+		secret, err = ref.NewProcessor(p.wfr, p.stg.Name, secretGetter).ResolveRefStringValue(spec.Auth.Secret)
+		if err != nil {
+			return p.delegationFailed(fmt.Errorf("resolve delegation auth secret error: %v", err))
+		}
+	}
+
+	// Persist the callback token digest and mark the stage waiting before sending the request, so that
+	// reports from the delegation service can be verified even if it responds immediately.
+	p.wfrOper.UpdateStageDelegation(p.instance, status)
+	p.wfrOper.UpdateStageStatus(p.instance, &v1alpha1.Status{
+		Phase:              v1alpha1.StatusWaiting,
+		Reason:             v1alpha1.ReasonWaitingDelegation,
+		LastTransitionTime: metav1.Time{Time: time.Now()},
+		StartTime:          metav1.Time{Time: time.Now()},
+	})
+	if err := p.wfrOper.Update(); err != nil {
+		log.WithField("wfr", p.wfr.Name).WithField("stg", p.instance).Error("Update WorkflowRun status error: ", err)
+		return err
+	}
+
+	err = delegation.Delegate(&delegation.Request{
 		Stage:       p.stg,
 		Workflow:    p.wf,
 		WorkflowRun: p.wfr,
-	})
-
+		Callback:    callback,
+	}, secret)
 	if err != nil {
-		p.wfrOper.GetRecorder().Eventf(p.wfr, corev1.EventTypeWarning, "DelegationFailure", "Delegate stage %s to %s error: %v", p.stg.Name, p.stg.Spec.Delegation.URL, err)
-		p.wfrOper.UpdateStageStatus(p.stg.Name, &v1alpha1.Status{
-			Phase:              v1alpha1.StatusFailed,
-			Reason:             "DelegationFailure",
-			LastTransitionTime: metav1.Time{Time: time.Now()},
-			Message:            fmt.Sprintf("Delegate error: %v", err),
-		})
-		return err
+		return p.delegationFailed(err)
 	}
 
-	p.wfrOper.GetRecorder().Eventf(p.wfr, corev1.EventTypeNormal, "DelegationSucceed", "Delegate stage %s to %s succeeded", p.stg.Name, p.stg.Spec.Delegation.URL)
+	p.wfrOper.GetRecorder().Eventf(p.wfr, corev1.EventTypeNormal, "DelegationSucceed", "Delegate stage %s to %s succeeded", p.stg.Name, spec.URL)
+	return nil
+}
 
-	// If the task has already been processed by the above RESTful API task, we should not update the status of the task to Waiting
-	latestWfr, err := p.client.CycloneV1alpha1().WorkflowRuns(p.wfr.Namespace).Get(context.TODO(), p.wfr.Name, metav1.GetOptions{})
+// delegationCallback generates callback for the delegation service to report stage status, and delegation
+// status with digest of the callback token.
+func (p *WorkloadProcessor) delegationCallback() (*delegation.Callback, *v1alpha1.DelegationStatus, error) {
+	token, err := delegation.NewToken()
 	if err != nil {
-		log.WithField("wfr", p.wfr.Name).Error("Get latest wfr failed")
-		return err
+		return nil, nil, fmt.Errorf("generate callback token error: %v", err)
+	}
+
+	callback := &delegation.Callback{
+		URL: fmt.Sprintf("%s/apis/v1alpha1/workflowruns/%s/delegationreports?namespace=%s&stage=%s",
+			controller.Config.CycloneServerAddr, p.wfr.Name, p.wfr.Namespace, p.instance),
+		Token: token,
+	}
+	status := &v1alpha1.DelegationStatus{
+		TokenDigest: delegation.TokenDigest(token),
+	}
+
+	if timeout := p.stg.Spec.Delegation.Timeout; len(timeout) > 0 {
+		d, err := ParseTime(timeout)
+		if err != nil {
+			return nil, nil, fmt.Errorf("invalid delegation timeout '%s': %v", timeout, err)
+		}
+		deadline := metav1.NewTime(time.Now().Add(d))
+		status.Deadline = &deadline
+		callback.Deadline = &deadline.Time
+	}
+
+	return callback, status, nil
+}
+
+// delegationFailed marks the delegated stage failed.
+func (p *WorkloadProcessor) delegationFailed(err error) error {
+	p.wfrOper.GetRecorder().Eventf(p.wfr, corev1.EventTypeWarning, "DelegationFailure", "Delegate stage %s to %s error: %v", p.stg.Name, p.stg.Spec.Delegation.URL, err)
+	p.wfrOper.UpdateStageStatus(p.instance, &v1alpha1.Status{
+		Phase:              v1alpha1.StatusFailed,
+		Reason:             "DelegationFailure",
+		LastTransitionTime: metav1.Time{Time: time.Now()},
+		Message:            fmt.Sprintf("Delegate error: %v", err),
+	})
+	return err
+}
+
+func (p *WorkloadProcessor) processApproval() error {
+	approval := p.stg.Spec.Approval
+	status := &v1alpha1.ApprovalStatus{
+		Users:   approval.Users,
+		Groups:  approval.Groups,
+		Message: approval.Message,
 	}
-	// The task maybe has been processed by the delegation task and status not needed to be set to Waiting
-	if stg, ok := latestWfr.Status.Stages[p.stg.Name]; ok {
-		if util.IsPhaseTerminated(stg.Status.Phase) {
-			return nil
+	if len(approval.Timeout) > 0 {
+		timeout, err := ParseTime(approval.Timeout)
+		if err != nil {
+			p.wfrOper.UpdateStageStatus(p.instance, &v1alpha1.Status{
+				Phase:              v1alpha1.StatusFailed,
+				Reason:             "InvalidApprovalTimeout",
+				LastTransitionTime: metav1.Time{Time: time.Now()},
+				Message:            fmt.Sprintf("Invalid approval timeout '%s': %v", approval.Timeout, err),
+			})
+			return fmt.Errorf("invalid approval timeout '%s' in stage '%s': %v", approval.Timeout, p.stg.Name, err)
 		}
+		deadline := metav1.NewTime(time.Now().Add(timeout))
+		status.Deadline = &deadline
 	}
 
-	p.wfrOper.UpdateStageStatus(p.stg.Name, &v1alpha1.Status{
+	p.wfrOper.GetRecorder().Eventf(p.wfr, corev1.EventTypeNormal, v1alpha1.ReasonWaitingApproval, "Stage '%s' is waiting for approval", p.instance)
+	p.wfrOper.UpdateStageApproval(p.instance, status)
+	p.wfrOper.UpdateStageStatus(p.instance, &v1alpha1.Status{
 		Phase:              v1alpha1.StatusWaiting,
+		Reason:             v1alpha1.ReasonWaitingApproval,
 		LastTransitionTime: metav1.Time{Time: time.Now()},
-		Reason:             "DelegationSucceed",
+		StartTime:          metav1.Time{Time: time.Now()},
 	})
 
 	return nil
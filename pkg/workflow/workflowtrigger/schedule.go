@@ -0,0 +1,102 @@
+package workflowtrigger
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/robfig/cron"
+
+	"github.com/caicloud/cyclone/pkg/apis/cyclone/v1alpha1"
+)
+
+// Schedule is the schedule of a cron trigger in its timezone, it implements cron.Schedule.
+type Schedule struct {
+	schedule cron.Schedule
+	// Location is the timezone of the schedule.
+	Location *time.Location
+	// StartingDeadline is the deadline for starting missed schedules, zero means missed schedules are not
+	// caught up.
+	StartingDeadline time.Duration
+}
+
+// ParseSchedule parses the schedule of a cron trigger. Both standard 5 fields expressions and 6 fields expressions
+// with seconds are supported.
+func ParseSchedule(trigger *v1alpha1.CronTrigger) (*Schedule, error) {
+	var schedule cron.Schedule
+	var err error
+	parts := strings.Fields(trigger.Schedule)
+	if len(parts) == 5 {
+		schedule, err = cron.ParseStandard(trigger.Schedule)
+	} else {
+		schedule, err = cron.Parse(trigger.Schedule)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("invalid cron expression %s: %v", trigger.Schedule, err)
+	}
+
+	location := time.Local
+	if trigger.Timezone != "" {
+		location, err = time.LoadLocation(trigger.Timezone)
+		if err != nil {
+			return nil, fmt.Errorf("invalid timezone %s: %v", trigger.Timezone, err)
+		}
+	}
+
+	var deadline time.Duration
+	if trigger.StartingDeadlineSeconds != nil {
+		if *trigger.StartingDeadlineSeconds < 0 {
+			return nil, fmt.Errorf("starting deadline seconds %d must not be negative", *trigger.StartingDeadlineSeconds)
+		}
+		deadline = time.Duration(*trigger.StartingDeadlineSeconds) * time.Second
+	}
+
+	switch trigger.ConcurrencyPolicy {
+	case "", v1alpha1.CronConcurrencyAllow, v1alpha1.CronConcurrencyForbid, v1alpha1.CronConcurrencyReplace:
+	default:
+		return nil, fmt.Errorf("invalid concurrency policy %s, must be one of Allow, Forbid and Replace", trigger.ConcurrencyPolicy)
+	}
+
+	return &Schedule{
+		schedule:         schedule,
+		Location:         location,
+		StartingDeadline: deadline,
+	}, nil
+}
+
+// Next returns the next fire time after t in timezone of the schedule, zero time is returned if there is none.
+func (s *Schedule) Next(t time.Time) time.Time {
+	return s.schedule.Next(t.In(s.Location))
+}
+
+// NextN returns at most n next fire times after t.
+func (s *Schedule) NextN(t time.Time, n int) []time.Time {
+	var times []time.Time
+	for i := 0; i < n; i++ {
+		t = s.Next(t)
+		if t.IsZero() {
+			break
+		}
+		times = append(times, t)
+	}
+	return times
+}
+
+// Missed returns the latest fire time that is missed after the last schedule time and within the starting deadline,
+// zero time is returned if there is no such fire time.
+func (s *Schedule) Missed(last, now time.Time) time.Time {
+	if s.StartingDeadline == 0 {
+		return time.Time{}
+	}
+
+	// Fire times before the deadline can't be started anyway, so start from it to bound the iterations.
+	if earliest := now.Add(-s.StartingDeadline); last.Before(earliest) {
+		last = earliest.Add(-time.Second)
+	}
+
+	var missed time.Time
+	for t := s.Next(last); !t.IsZero() && !t.After(now); t = s.Next(t) {
+		missed = t
+	}
+	return missed
+}
@@ -0,0 +1,76 @@
+package workflowtrigger
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/caicloud/cyclone/pkg/apis/cyclone/v1alpha1"
+)
+
+func int64Ptr(i int64) *int64 {
+	return &i
+}
+
+func TestParseSchedule(t *testing.T) {
+	cases := map[string]struct {
+		trigger v1alpha1.CronTrigger
+		valid   bool
+	}{
+		"standard":            {v1alpha1.CronTrigger{Schedule: "0 8 * * *"}, true},
+		"with seconds":        {v1alpha1.CronTrigger{Schedule: "0 0 8 * * *"}, true},
+		"descriptor":          {v1alpha1.CronTrigger{Schedule: "@daily"}, true},
+		"timezone":            {v1alpha1.CronTrigger{Schedule: "0 8 * * *", Timezone: "Asia/Shanghai"}, true},
+		"policy":              {v1alpha1.CronTrigger{Schedule: "0 8 * * *", ConcurrencyPolicy: v1alpha1.CronConcurrencyReplace}, true},
+		"invalid schedule":    {v1alpha1.CronTrigger{Schedule: "0 25 * * *"}, false},
+		"invalid timezone":    {v1alpha1.CronTrigger{Schedule: "0 8 * * *", Timezone: "Mars/Olympus"}, false},
+		"negative deadline":   {v1alpha1.CronTrigger{Schedule: "0 8 * * *", StartingDeadlineSeconds: int64Ptr(-1)}, false},
+		"invalid concurrency": {v1alpha1.CronTrigger{Schedule: "0 8 * * *", ConcurrencyPolicy: "Queue"}, false},
+	}
+
+	for name, c := range cases {
+		_, err := ParseSchedule(&c.trigger)
+		assert.Equal(t, c.valid, err == nil, name)
+	}
+}
+
+func TestNextN(t *testing.T) {
+	s, err := ParseSchedule(&v1alpha1.CronTrigger{Schedule: "0 8 * * *", Timezone: "Asia/Shanghai"})
+	assert.Nil(t, err)
+
+	now := time.Date(2020, 3, 1, 0, 30, 0, 0, time.UTC)
+	times := s.NextN(now, 2)
+	assert.Equal(t, 2, len(times))
+	// 8:00 in Shanghai is 0:00 in UTC.
+	assert.True(t, times[0].Equal(time.Date(2020, 3, 2, 0, 0, 0, 0, time.UTC)))
+	assert.True(t, times[1].Equal(time.Date(2020, 3, 3, 0, 0, 0, 0, time.UTC)))
+	assert.Equal(t, "Asia/Shanghai", times[0].Location().String())
+}
+
+func TestMissed(t *testing.T) {
+	s, err := ParseSchedule(&v1alpha1.CronTrigger{Schedule: "0 * * * *", Timezone: "UTC", StartingDeadlineSeconds: int64Ptr(600)})
+	assert.Nil(t, err)
+
+	now := time.Date(2020, 3, 1, 10, 5, 0, 0, time.UTC)
+	cases := []struct {
+		last     time.Time
+		expected time.Time
+	}{
+		// Fired at 10:00 already.
+		{time.Date(2020, 3, 1, 10, 0, 0, 0, time.UTC), time.Time{}},
+		// 10:00 is missed and within the deadline, 9:00 is not caught up.
+		{time.Date(2020, 3, 1, 8, 0, 0, 0, time.UTC), time.Date(2020, 3, 1, 10, 0, 0, 0, time.UTC)},
+		{time.Date(2019, 3, 1, 8, 0, 0, 0, time.UTC), time.Date(2020, 3, 1, 10, 0, 0, 0, time.UTC)},
+	}
+	for _, c := range cases {
+		assert.True(t, c.expected.Equal(s.Missed(c.last, now)), c.last.String())
+	}
+
+	// 10:00 is out of the deadline.
+	assert.True(t, s.Missed(time.Date(2020, 3, 1, 8, 0, 0, 0, time.UTC), now.Add(10*time.Minute)).IsZero())
+
+	s, err = ParseSchedule(&v1alpha1.CronTrigger{Schedule: "0 * * * *"})
+	assert.Nil(t, err)
+	assert.True(t, s.Missed(time.Date(2020, 3, 1, 8, 0, 0, 0, time.UTC), now).IsZero())
+}
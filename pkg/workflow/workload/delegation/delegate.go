@@ -2,11 +2,17 @@ package delegation
 
 import (
 	"bytes"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
 	"encoding/json"
 	"errors"
 	"fmt"
 	"io/ioutil"
 	"net/http"
+	"time"
 
 	log "github.com/sirupsen/logrus"
 
@@ -14,24 +20,122 @@ import (
 	httputil "github.com/caicloud/cyclone/pkg/util/http"
 )
 
+const (
+	// SignatureHeaderName is name of the header that holds HMAC signature of the request body.
+	SignatureHeaderName = "X-Cyclone-Signature"
+
+	// requestTimeout is timeout of delegation request.
+	requestTimeout = 30 * time.Second
+)
+
 // Request is request sent to delegation service.
 type Request struct {
 	Stage       *v1alpha1.Stage       `json:"stage"`
 	Workflow    *v1alpha1.Workflow    `json:"workflow"`
 	WorkflowRun *v1alpha1.WorkflowRun `json:"workflowrun"`
+	// Callback tells delegation service how to report stage status back to Cyclone.
+	Callback *Callback `json:"callback,omitempty"`
+}
+
+// Callback describes how delegation service reports stage status. Delegation service should POST
+// a Report to the URL, with the token in 'Authorization' header as a bearer token.
+type Callback struct {
+	// URL to report stage status.
+	URL string `json:"url"`
+	// Token to authenticate the report, it's only valid for this stage execution.
+	Token string `json:"token"`
+	// Deadline is the time before which the stage should be reported finished, nil means no deadline.
+	Deadline *time.Time `json:"deadline,omitempty"`
+}
+
+// Report is stage status reported by delegation service.
+type Report struct {
+	// Phase of the stage, it can be 'Running', 'Succeeded' or 'Failed'.
+	Phase v1alpha1.StatusPhase `json:"phase"`
+	// Message describes the status, for example, why the stage failed.
+	Message string `json:"message,omitempty"`
+	// Outputs of the stage, they can be referred by following stages.
+	Outputs []v1alpha1.KeyValue `json:"outputs,omitempty"`
+	// Logs are log lines of the stage, they are appended to the stage logs.
+	Logs []string `json:"logs,omitempty"`
+}
+
+// Validate validates the report.
+func (r *Report) Validate() error {
+	switch r.Phase {
+	case v1alpha1.StatusRunning, v1alpha1.StatusSucceeded, v1alpha1.StatusFailed:
+		return nil
+	default:
+		return fmt.Errorf("phase should be one of %s, %s and %s, but got '%s'",
+			v1alpha1.StatusRunning, v1alpha1.StatusSucceeded, v1alpha1.StatusFailed, r.Phase)
+	}
+}
+
+// NewToken generates a random callback token.
+func NewToken() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+
+	return hex.EncodeToString(b), nil
 }
 
-// Delegate sends request to delegation service.
-func Delegate(request *Request) error {
+// TokenDigest computes digest of the callback token, only the digest is recorded in WorkflowRun status,
+// so that the token can't be got from the WorkflowRun.
+func TokenDigest(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+// VerifyToken checks whether the token matches the digest.
+func VerifyToken(token, digest string) bool {
+	if token == "" || digest == "" {
+		return false
+	}
+
+	return subtle.ConstantTimeCompare([]byte(TokenDigest(token)), []byte(digest)) == 1
+}
+
+// Sign computes HMAC-SHA256 signature of the body, in format 'sha256=<hex>'.
+func Sign(key string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(key))
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// Delegate sends request to delegation service, 'secret' is the resolved secret of the delegation auth.
+func Delegate(request *Request, secret string) error {
 	delegation := request.Stage.Spec.Delegation
 	log.WithField("stg", request.Stage.Name).Info("Delegate stage to: ", delegation.URL)
 
-	raw, err := json.Marshal(request)
+	// Auth of the stage holds the secret to sign the request, it must not be sent to the delegation service.
+	sanitized := *request
+	sanitized.Stage = request.Stage.DeepCopy()
+	sanitized.Stage.Spec.Delegation.Auth = nil
+	raw, err := json.Marshal(&sanitized)
 	if err != nil {
 		return fmt.Errorf("marshal request error: %v", err)
 	}
 
-	resp, err := http.DefaultClient.Post(httputil.EnsureProtocolScheme(delegation.URL), "application/json", bytes.NewReader(raw))
+	req, err := http.NewRequest(http.MethodPost, httputil.EnsureProtocolScheme(delegation.URL), bytes.NewReader(raw))
+	if err != nil {
+		return fmt.Errorf("new request error: %v", err)
+	}
+	req.Header.Set(httputil.HeaderContentType, httputil.HeaderContentTypeJSON)
+	if delegation.Auth != nil {
+		switch delegation.Auth.Type {
+		case v1alpha1.DelegationAuthHMAC:
+			req.Header.Set(SignatureHeaderName, Sign(secret, raw))
+		case v1alpha1.DelegationAuthBearer:
+			req.Header.Set("Authorization", "Bearer "+secret)
+		default:
+			return fmt.Errorf("unsupported delegation auth type '%s'", delegation.Auth.Type)
+		}
+	}
+
+	client := &http.Client{Timeout: requestTimeout}
+	resp, err := client.Do(req)
 	if err != nil {
 		return fmt.Errorf("POST %s error: %v", delegation.URL, err)
 	}
@@ -0,0 +1,79 @@
+package delegation
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/caicloud/cyclone/pkg/apis/cyclone/v1alpha1"
+)
+
+func TestToken(t *testing.T) {
+	token, err := NewToken()
+	assert.Nil(t, err)
+	assert.Equal(t, 64, len(token))
+
+	digest := TokenDigest(token)
+	assert.True(t, VerifyToken(token, digest))
+	assert.False(t, VerifyToken(token+"x", digest))
+	assert.False(t, VerifyToken("", ""))
+}
+
+func TestReportValidate(t *testing.T) {
+	assert.Nil(t, (&Report{Phase: v1alpha1.StatusRunning}).Validate())
+	assert.Nil(t, (&Report{Phase: v1alpha1.StatusSucceeded}).Validate())
+	assert.Nil(t, (&Report{Phase: v1alpha1.StatusFailed}).Validate())
+	assert.NotNil(t, (&Report{Phase: v1alpha1.StatusWaiting}).Validate())
+	assert.NotNil(t, (&Report{}).Validate())
+}
+
+func TestDelegate(t *testing.T) {
+	var header http.Header
+	var body []byte
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		header = r.Header
+		body, _ = ioutil.ReadAll(r.Body)
+	}))
+	defer server.Close()
+
+	stage := &v1alpha1.Stage{
+		Spec: v1alpha1.StageSpec{
+			Delegation: &v1alpha1.DelegationWorkload{
+				URL: server.URL,
+				Auth: &v1alpha1.DelegationAuth{
+					Type:   v1alpha1.DelegationAuthHMAC,
+					Secret: "${secrets.ns:secret/data.key}",
+				},
+			},
+		},
+	}
+	request := &Request{
+		Stage:    stage,
+		Callback: &Callback{URL: "http://cyclone-server", Token: "token"},
+	}
+	assert.Nil(t, Delegate(request, "key"))
+	assert.Equal(t, Sign("key", body), header.Get(SignatureHeaderName))
+	received := &Request{}
+	assert.Nil(t, json.Unmarshal(body, received))
+	assert.Equal(t, "token", received.Callback.Token)
+	assert.Nil(t, received.Stage.Spec.Delegation.Auth)
+	assert.NotContains(t, string(body), "secrets.ns")
+	assert.NotNil(t, stage.Spec.Delegation.Auth)
+
+	stage.Spec.Delegation.Auth.Type = v1alpha1.DelegationAuthBearer
+	assert.Nil(t, Delegate(request, "key"))
+	assert.Equal(t, "Bearer key", header.Get("Authorization"))
+	assert.Equal(t, "", header.Get(SignatureHeaderName))
+
+	// Plain text secret is not sent either.
+	stage.Spec.Delegation.Auth.Secret = "plain-secret"
+	assert.Nil(t, Delegate(request, "plain-secret"))
+	assert.NotContains(t, string(body), "plain-secret")
+
+	stage.Spec.Delegation.Auth.Type = "Unknown"
+	assert.NotNil(t, Delegate(request, "key"))
+}
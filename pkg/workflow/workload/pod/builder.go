@@ -2,10 +2,13 @@ package pod
 
 import (
 	"context"
+	"crypto/rand"
+	"encoding/hex"
 	"encoding/json"
 	"fmt"
 	"os"
 	"path/filepath"
+	"reflect"
 	"regexp"
 	"strconv"
 	"strings"
@@ -13,8 +16,10 @@ import (
 	"github.com/cbroglie/mustache"
 	log "github.com/sirupsen/logrus"
 	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
 	"k8s.io/apimachinery/pkg/api/resource"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
 
 	"github.com/caicloud/cyclone/pkg/apis/cyclone/v1alpha1"
 	ccommon "github.com/caicloud/cyclone/pkg/common"
@@ -29,6 +34,7 @@ import (
 // Builder is builder used to build pod for stage
 type Builder struct {
 	client           k8s.Interface
+	clusterClient    kubernetes.Interface
 	wf               *v1alpha1.Workflow
 	wfr              *v1alpha1.WorkflowRun
 	stg              *v1alpha1.Stage
@@ -41,13 +47,14 @@ type Builder struct {
 	refProcessor     *ref.Processor
 }
 
-// NewBuilder creates a new pod builder.
-func NewBuilder(client k8s.Interface, wf *v1alpha1.Workflow, wfr *v1alpha1.WorkflowRun, stg *v1alpha1.Stage) *Builder {
+// NewBuilder creates a new pod builder, 'clusterClient' is client of the execution cluster where the pod runs.
+func NewBuilder(client k8s.Interface, clusterClient kubernetes.Interface, wf *v1alpha1.Workflow, wfr *v1alpha1.WorkflowRun, stg *v1alpha1.Stage) *Builder {
 	secretGetter := func(ns, name string) (*corev1.Secret, error) {
 		return client.CoreV1().Secrets(ns).Get(context.TODO(), name, metav1.GetOptions{})
 	}
 	return &Builder{
 		client:           client,
+		clusterClient:    clusterClient,
 		wf:               wf,
 		wfr:              wfr,
 		stage:            stg.Name,
@@ -55,10 +62,18 @@ func NewBuilder(client k8s.Interface, wf *v1alpha1.Workflow, wfr *v1alpha1.Workf
 		pod:              &corev1.Pod{},
 		pvcVolumes:       make(map[string]string),
 		executionContext: GetExecutionContext(wfr),
-		refProcessor:     ref.NewProcessor(wfr, secretGetter),
+		refProcessor:     ref.NewProcessor(wfr, stg.Name, secretGetter),
 	}
 }
 
+// NewInstanceBuilder creates a new pod builder for an instance of matrix stage. Values of matrix axes
+// recorded in the instance status are injected as arguments of the stage.
+func NewInstanceBuilder(client k8s.Interface, clusterClient kubernetes.Interface, wf *v1alpha1.Workflow, wfr *v1alpha1.WorkflowRun, stg *v1alpha1.Stage, instance string) *Builder {
+	builder := NewBuilder(client, clusterClient, wf, wfr, stg)
+	builder.stage = instance
+	return builder
+}
+
 var (
 	zeroQuantity = resource.MustParse("0")
 )
@@ -69,16 +84,10 @@ func (m *Builder) Prepare() error {
 		return fmt.Errorf("pod must be defined in stage spec, stage: %s", m.stage)
 	}
 
-	// Only one workload container supported, others should be sidecar marked by special
+	// At least one workload container other than service containers is required, sidecars are marked by special
 	// container name prefix.
-	var workloadContainers int
-	for _, c := range m.stg.Spec.Pod.Spec.Containers {
-		if !strings.HasPrefix(c.Name, common.WorkloadSidecarPrefix) {
-			workloadContainers++
-		}
-	}
-	if workloadContainers != 1 {
-		return fmt.Errorf("only one workload containers supported, others should be sidecars, stage: %s", m.stage)
+	if err := m.validateWorkloadContainers(); err != nil {
+		return err
 	}
 
 	labels := map[string]string{
@@ -103,6 +112,9 @@ func (m *Builder) Prepare() error {
 			annotations[k] = v
 		}
 	}
+	if len(m.stg.Spec.Pod.ServiceContainers) > 0 {
+		annotations[meta.AnnotationServiceContainers] = strings.Join(m.stg.Spec.Pod.ServiceContainers, ",")
+	}
 	m.pod.ObjectMeta = metav1.ObjectMeta{
 		Name:        Name(m.wf.Name, m.stage),
 		Namespace:   m.executionContext.Namespace,
@@ -118,11 +130,63 @@ func (m *Builder) Prepare() error {
 	return nil
 }
 
+// validateWorkloadContainers checks service containers and containers of output artifacts are workload containers,
+// and there is at least one workload container that is not a service. Names of workload containers can't have the
+// prefix reserved for services of the stage.
+func (m *Builder) validateWorkloadContainers() error {
+	workloads := make(map[string]bool)
+	for _, c := range m.stg.Spec.Pod.Spec.Containers {
+		if strings.HasPrefix(c.Name, common.WorkloadSidecarPrefix) {
+			continue
+		}
+		if strings.HasPrefix(c.Name, common.ServiceContainerPrefix) {
+			return fmt.Errorf("container name prefix '%s' is reserved for services, container: %s, stage: %s",
+				common.ServiceContainerPrefix, c.Name, m.stage)
+		}
+		workloads[c.Name] = true
+	}
+
+	for _, name := range m.stg.Spec.Pod.ServiceContainers {
+		if _, ok := workloads[name]; !ok {
+			return fmt.Errorf("service container %s is not a workload container, stage: %s", name, m.stage)
+		}
+		workloads[name] = false
+	}
+
+	for _, artifact := range m.stg.Spec.Pod.Outputs.Artifacts {
+		if artifact.Container != "" && !workloads[artifact.Container] {
+			return fmt.Errorf("container %s of output artifact %s is not a workload container or is a service, stage: %s",
+				artifact.Container, artifact.Name, m.stage)
+		}
+	}
+
+	if len(m.workloadContainers()) == 0 {
+		return fmt.Errorf("at least one workload container is required, others should be sidecars or services, stage: %s", m.stage)
+	}
+	return nil
+}
+
+// workloadContainers returns names of workload containers except sidecars and service containers.
+func (m *Builder) workloadContainers() []string {
+	services := make(map[string]bool)
+	for _, name := range m.stg.Spec.Pod.ServiceContainers {
+		services[name] = true
+	}
+
+	var names []string
+	for _, c := range m.stg.Spec.Pod.Spec.Containers {
+		if !strings.HasPrefix(c.Name, common.WorkloadSidecarPrefix) && !services[c.Name] {
+			names = append(names, c.Name)
+		}
+	}
+	return names
+}
+
 // ResolveArguments ...
 func (m *Builder) ResolveArguments() error {
 	parameters := make(map[string]string)
 	for _, s := range m.wfr.Spec.StageParams {
-		if s.Name == m.stage {
+		if s.Name == m.stg.Name {
 			for _, p := range s.Parameters {
 				if p.Value != nil {
 					parameters[p.Name] = *p.Value
@@ -130,6 +194,11 @@ func (m *Builder) ResolveArguments() error {
 			}
 		}
 	}
+	if status, ok := m.wfr.Status.Stages[m.stage]; ok {
+		for k, v := range status.Matrix {
+			parameters[k] = v
+		}
+	}
 	for _, a := range m.stg.Spec.Pod.Inputs.Arguments {
 		if _, ok := parameters[a.Name]; !ok {
 			if a.Value == nil {
@@ -191,6 +260,32 @@ func (m *Builder) ResolveArguments() error {
 	return nil
 }
 
+// ResolveServices adds containers for services of the stage. They are placed before workload containers so
+// that they are started first, and workload containers are held until all services are ready, see AddCoordinator.
+func (m *Builder) ResolveServices() error {
+	if len(m.rendered.Pod.Services) == 0 {
+		return nil
+	}
+
+	names := make(map[string]bool)
+	var services []corev1.Container
+	for _, s := range m.rendered.Pod.Services {
+		if s.Name == "" {
+			return fmt.Errorf("name of service is required, stage: %s", m.stage)
+		}
+		if names[s.Name] {
+			return fmt.Errorf("duplicated service %s, stage: %s", s.Name, m.stage)
+		}
+		names[s.Name] = true
+
+		s.Name = ServiceContainerName(s.Name)
+		services = append(services, s)
+	}
+	m.pod.Spec.Containers = append(services, m.pod.Spec.Containers...)
+
+	return nil
+}
+
 // EnsureContainerNames ensures all containers have name set.
 func (m *Builder) EnsureContainerNames() error {
 	if m.stg.Spec.Pod == nil {
@@ -388,7 +483,7 @@ func (m *Builder) ResolveInputResources() error {
 		volumeName := common.DefaultPvVolumeName
 
 		// Sub-path in the PVC to hold resource data
-		subPath := common.ResourcePath(m.wfr.Name, r.Name)
+		subPath := common.ResourcePath(common.WorkspaceName(m.wfr), r.Name)
 
 		// If persistent is set in the resource spec, create a volume for the persistent PVC
 		// specified. Then resource would be pulled in the PVC. If persistent is not set, resource
@@ -439,9 +534,18 @@ func (m *Builder) ResolveInputResources() error {
 		envs = append(envs, corev1.EnvVar{
 			Name: common.EnvLogCollectorURL,
 			Value: fmt.Sprintf("%s/apis/v1alpha1/workflowruns/%s/streamlogs?namespace=%s&stage=%s&container=%s",
-				controller.Config.CycloneServerAddr, m.wfr.Name, m.wfr.Namespace, m.stg.Name, InputContainerName(index+1)),
+				controller.Config.CycloneServerAddr, m.wfr.Name, m.wfr.Namespace, m.stage, InputContainerName(index+1)),
 		})
 
+		// HTTP resources pushing to cyclone server upload artifacts of the WorkflowRun, which are authenticated
+		// by the artifact token. The token isn't passed to other servers.
+		if resource.Spec.Type == v1alpha1.HTTPResourceType && uploadsArtifacts(envs) {
+			if err := m.ensureArtifactToken(m.wfr.Name); err != nil {
+				return err
+			}
+			envs = append(envs, artifactTokenEnv(m.wfr.Name))
+		}
+
 		// Get resource resolver for the given resource type. If the resource has resolver set, use it directly,
 		// otherwise get resolver from registered resource types.
 		resolver, err := common.GetResourceResolver(m.client, resource)
@@ -659,12 +763,9 @@ func (m *Builder) ResolveOutputResources() error {
 	return nil
 }
 
-// ResolveInputArtifacts mount each input artifact from PVC.
+// ResolveInputArtifacts mount each input artifact from PVC. If there is no PVC, input artifacts are downloaded
+// from cyclone server by init containers, and mounted from an emptyDir volume.
 func (m *Builder) ResolveInputArtifacts() error {
-	if m.executionContext.PVC == "" && len(m.stg.Spec.Pod.Inputs.Artifacts) > 0 {
-		return fmt.Errorf("artifacts not supported when no PVC provided, but %d input artifacts found", len(m.stg.Spec.Pod.Inputs.Artifacts))
-	}
-
 	// Bind input artifacts to workload containers.
 	// First find StageItem from Workflow spec, we will get artifacts binding info from it.
 	var wfStage *v1alpha1.StageItem
@@ -680,7 +781,7 @@ func (m *Builder) ResolveInputArtifacts() error {
 	}
 
 	// For each input artifact, mount data from PVC.
-	for _, artifact := range m.stg.Spec.Pod.Inputs.Artifacts {
+	for index, artifact := range m.stg.Spec.Pod.Inputs.Artifacts {
 		// Get source of this input artifact from Workflow StageItem
 		// It has format: <stage name>/<artifact name>
 		var source string
@@ -696,26 +797,37 @@ func (m *Builder) ResolveInputArtifacts() error {
 				Error("Input artifact not bind in workflow")
 			return fmt.Errorf("input artifact %s not binded in workflow %s", m.stg.Name, m.wf.Name)
 		}
-		parts := strings.Split(source, "/")
+		workspace, parts, err := m.artifactSource(source)
+		if err != nil {
+			return err
+		}
 		log.WithField("source", source).
 			WithField("artifact", artifact.Name).
 			Info("To mount artifact")
 
-		// Mount artifacts to each workload container.
-		var containers []corev1.Container
-		for _, c := range m.pod.Spec.Containers {
-			fileName, err := m.ArtifactFileName(parts[0], parts[1])
-			if err != nil {
+		fileName, err := m.ArtifactFileName(parts[0], parts[1])
+		if err != nil {
+			return err
+		}
+		mount := corev1.VolumeMount{
+			Name:      common.DefaultPvVolumeName,
+			MountPath: artifact.Path,
+			SubPath:   common.ArtifactPath(workspace, parts[0], parts[1]) + "/" + fileName,
+		}
+		if m.executionContext.PVC == "" {
+			if err := m.downloadInputArtifact(index, artifact.Name, workspace, parts); err != nil {
 				return err
 			}
+			mount.Name = common.InputArtifactsVolume
+			mount.SubPath = artifact.Name + "/" + fileName
+		}
 
+		// Mount artifacts to each workload container.
+		var containers []corev1.Container
+		for _, c := range m.pod.Spec.Containers {
 			// Mount artifacts only to workload containers, with sidecars excluded.
 			if common.OnlyWorkload(c.Name) {
-				c.VolumeMounts = append(c.VolumeMounts, corev1.VolumeMount{
-					Name:      common.DefaultPvVolumeName,
-					MountPath: artifact.Path,
-					SubPath:   common.ArtifactPath(m.wfr.Name, parts[0], parts[1]) + "/" + fileName,
-				})
+				c.VolumeMounts = append(c.VolumeMounts, mount)
 			}
 			containers = append(containers, c)
 		}
@@ -725,6 +837,206 @@ func (m *Builder) ResolveInputArtifacts() error {
 	return nil
 }
 
+// downloadInputArtifact adds an init container to download the input artifact from cyclone server and unpack it
+// to the input artifacts volume. Output artifacts are uploaded to cyclone server by coordinator as tar files when
+// there is no PVC, see Coordinator.UploadArtifacts.
+func (m *Builder) downloadInputArtifact(index int, name, workflowrun string, parts []string) error {
+	image, ok := controller.Config.Images[controller.CoordinatorImage]
+	if !ok {
+		return fmt.Errorf("no coordinator image configured to download artifacts, the image key is '%s'", controller.CoordinatorImage)
+	}
+
+	if err := m.ensureArtifactToken(workflowrun); err != nil {
+		return err
+	}
+
+	created := false
+	for _, v := range m.pod.Spec.Volumes {
+		if v.Name == common.InputArtifactsVolume {
+			created = true
+			break
+		}
+	}
+	if !created {
+		m.CreateEmptyDirVolume(common.InputArtifactsVolume)
+	}
+
+	container := corev1.Container{
+		Name:    InputArtifactContainerName(index),
+		Image:   image,
+		Command: []string{"sh", "-c"},
+		Args: []string{fmt.Sprintf(`curl -fsS -H "Authorization: Bearer $%s" -o /tmp/artifact.tar "$%s" && mkdir -p "$%s" && tar -xf /tmp/artifact.tar -C "$%s"`,
+			common.EnvArtifactToken, common.EnvArtifactURL, common.EnvArtifactDir, common.EnvArtifactDir)},
+		Env: []corev1.EnvVar{
+			artifactTokenEnv(workflowrun),
+			{
+				Name: common.EnvArtifactURL,
+				Value: fmt.Sprintf("%s/apis/v1alpha1/workflowruns/%s/artifacts/%s?namespace=%s&stage=%s",
+					controller.Config.CycloneServerAddr, workflowrun, common.ArtifactArchiveName(parts[1]), m.wfr.Namespace, parts[0]),
+			},
+			{
+				Name:  common.EnvArtifactDir,
+				Value: common.InputArtifactsPath + "/" + name,
+			},
+		},
+		VolumeMounts: []corev1.VolumeMount{
+			{
+				Name:      common.InputArtifactsVolume,
+				MountPath: common.InputArtifactsPath,
+			},
+		},
+		ImagePullPolicy: controller.ImagePullPolicy(),
+	}
+	m.pod.Spec.InitContainers = append(m.pod.Spec.InitContainers, container)
+
+	return nil
+}
+
+// ensureArtifactToken ensures the secret holding the token to access artifacts of the WorkflowRun in cyclone
+// server exists. The secret is kept in the namespace of the WorkflowRun and owned by it, cyclone server verifies
+// tokens against it. It's created for the current WorkflowRun if not exist, for the upstream WorkflowRun, it should
+// have been created when its artifacts were uploaded. Pods get the token from the secret, so it's copied to the
+// execution namespace if pods run elsewhere, see artifactTokenSecretCopy.
+func (m *Builder) ensureArtifactToken(workflowrun string) error {
+	secret, err := m.artifactTokenSecret(workflowrun)
+	if err != nil {
+		return err
+	}
+
+	secrets := m.clusterClient.CoreV1().Secrets(m.executionContext.Namespace)
+	_, err = secrets.Get(context.TODO(), secret.Name, metav1.GetOptions{})
+	if err == nil {
+		return nil
+	}
+	if !errors.IsNotFound(err) {
+		return fmt.Errorf("get artifact token of workflowrun %s in execution namespace %s error: %v", workflowrun, m.executionContext.Namespace, err)
+	}
+	_, err = secrets.Create(context.TODO(), artifactTokenSecretCopy(secret), metav1.CreateOptions{})
+	if err != nil && !errors.IsAlreadyExists(err) {
+		return fmt.Errorf("copy artifact token of workflowrun %s to execution namespace %s error: %v", workflowrun, m.executionContext.Namespace, err)
+	}
+	return nil
+}
+
+// artifactTokenSecret gets the artifact token secret of the WorkflowRun in its namespace, the secret is created
+// for the current WorkflowRun if not exist.
+func (m *Builder) artifactTokenSecret(workflowrun string) (*corev1.Secret, error) {
+	secrets := m.client.CoreV1().Secrets(m.wfr.Namespace)
+	secret, err := secrets.Get(context.TODO(), common.ArtifactTokenSecretName(workflowrun), metav1.GetOptions{})
+	if err == nil {
+		return secret, nil
+	}
+	if !errors.IsNotFound(err) || workflowrun != m.wfr.Name {
+		return nil, fmt.Errorf("get artifact token of workflowrun %s error: %v", workflowrun, err)
+	}
+
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return nil, fmt.Errorf("generate artifact token error: %v", err)
+	}
+	secret = &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			Name: common.ArtifactTokenSecretName(workflowrun),
+			Labels: map[string]string{
+				meta.LabelWorkflowRunName: workflowrun,
+			},
+			OwnerReferences: []metav1.OwnerReference{
+				{
+					APIVersion: v1alpha1.APIVersion,
+					Kind:       reflect.TypeOf(v1alpha1.WorkflowRun{}).Name(),
+					Name:       m.wfr.Name,
+					UID:        m.wfr.UID,
+				},
+			},
+		},
+		Data: map[string][]byte{
+			common.ArtifactTokenSecretKey: []byte(hex.EncodeToString(b)),
+		},
+	}
+	created, err := secrets.Create(context.TODO(), secret, metav1.CreateOptions{})
+	if errors.IsAlreadyExists(err) {
+		// Created by the pod of another stage at the same time.
+		return m.artifactTokenSecret(workflowrun)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("create artifact token of workflowrun %s error: %v", workflowrun, err)
+	}
+	return created, nil
+}
+
+// artifactTokenSecretCopy makes a copy of the artifact token secret for the execution namespace. The copy has
+// no owner, as owners can't be in another namespace or cluster, it's deleted when the WorkflowRun is deleted.
+func artifactTokenSecretCopy(secret *corev1.Secret) *corev1.Secret {
+	return &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:   secret.Name,
+			Labels: secret.Labels,
+		},
+		Data: secret.Data,
+	}
+}
+
+// uploadsArtifacts checks whether the URL of a HTTP resource is the API of cyclone server to upload artifacts.
+func uploadsArtifacts(envs []corev1.EnvVar) bool {
+	prefix := strings.TrimRight(controller.Config.CycloneServerAddr, "/") + "/apis/v1alpha1/workflowruns/"
+	for _, env := range envs {
+		if env.Name == common.HTTPResourceURLKey {
+			return controller.Config.CycloneServerAddr != "" && strings.HasPrefix(env.Value, prefix)
+		}
+	}
+	return false
+}
+
+// artifactTokenEnv gets the environment variable of the artifact token of the WorkflowRun, the token is read from
+// the artifact token secret.
+func artifactTokenEnv(workflowrun string) corev1.EnvVar {
+	return corev1.EnvVar{
+		Name: common.EnvArtifactToken,
+		ValueFrom: &corev1.EnvVarSource{
+			SecretKeyRef: &corev1.SecretKeySelector{
+				LocalObjectReference: corev1.LocalObjectReference{
+					Name: common.ArtifactTokenSecretName(workflowrun),
+				},
+				Key: common.ArtifactTokenSecretKey,
+			},
+		},
+	}
+}
+
+// artifactSource parses source of an input artifact, it returns the workspace where the artifact is stored and
+// names of the stage and the artifact. Source of artifacts from the upstream WorkflowRun that triggered this one
+// has format 'upstream/<stage name>/<artifact name>', otherwise it's '<stage name>/<artifact name>'. If there is
+// no PVC, artifacts are stored in cyclone server, and name of the WorkflowRun that owns them is returned instead
+// of the workspace.
+func (m *Builder) artifactSource(source string) (string, []string, error) {
+	parts := strings.Split(source, "/")
+	if m.executionContext.PVC == "" {
+		if len(parts) == 3 && parts[0] == common.UpstreamArtifactSourcePrefix {
+			upstream, ok := m.wfr.Annotations[meta.AnnotationWorkflowRunUpstream]
+			if !ok || upstream == "" {
+				return "", nil, fmt.Errorf("artifact %s not available, the workflowrun is not triggered by an upstream workflowrun", source)
+			}
+			return upstream, parts[1:], nil
+		}
+		if len(parts) == 2 {
+			return m.wfr.Name, parts, nil
+		}
+	}
+
+	if len(parts) == 3 && parts[0] == common.UpstreamArtifactSourcePrefix {
+		workspace, ok := m.wfr.Labels[meta.LabelWorkflowRunUpstreamWorkspace]
+		if !ok || workspace == "" {
+			return "", nil, fmt.Errorf("artifact %s not available, the workflowrun is not triggered by an upstream workflowrun in the same execution context", source)
+		}
+		return workspace, parts[1:], nil
+	}
+
+	if len(parts) != 2 {
+		return "", nil, fmt.Errorf("invalid artifact source %s, it should be <stage>/<artifact> or %s/<stage>/<artifact>", source, common.UpstreamArtifactSourcePrefix)
+	}
+	return common.WorkspaceName(m.wfr), parts, nil
+}
+
 // AddVolumeMounts add common PVC  to workload containers
 func (m *Builder) AddVolumeMounts() error {
 	if m.executionContext.PVC != "" {
@@ -733,7 +1045,7 @@ func (m *Builder) AddVolumeMounts() error {
 			c.VolumeMounts = append(c.VolumeMounts, corev1.VolumeMount{
 				Name:      common.DefaultPvVolumeName,
 				MountPath: common.StageMountPath,
-				SubPath:   common.StagePath(m.wfr.Name, m.stg.Name),
+				SubPath:   common.StagePath(common.WorkspaceName(m.wfr), m.stage),
 			})
 			containers = append(containers, c)
 		}
@@ -781,17 +1093,12 @@ func (m *Builder) MountPresetVolumes() error {
 	return nil
 }
 
-// AddCoordinator adds coordinator container as sidecar to pod. Coordinator is used
-// to collect logs, artifacts and notify resource resolvers to push resources.
+// AddCoordinator adds coordinator container as sidecar to pod. Coordinator is used to wait workload
+// containers, collect logs, artifacts and notify resource resolvers to push resources.
 func (m *Builder) AddCoordinator() error {
-	// Get workload container name, for the moment, we support only one workload container.
-	var workloadContainer string
-	for _, c := range m.stg.Spec.Pod.Spec.Containers {
-		workloadContainer = c.Name
-		break
-	}
-
+	// Stage name is replaced with instance name for matrix stage, so that logs are collected per instance.
 	newStg := m.stg.DeepCopy()
+	newStg.Name = m.stage
 	newStg.Spec = *m.rendered
 	stgInfo, err := json.Marshal(newStg)
 	if err != nil {
@@ -824,8 +1131,8 @@ func (m *Builder) AddCoordinator() error {
 				Value: m.executionContext.Namespace,
 			},
 			{
-				Name:  common.EnvWorkloadContainerName,
-				Value: workloadContainer,
+				Name:  common.EnvWorkloadContainerNames,
+				Value: strings.Join(m.workloadContainers(), ","),
 			},
 			{
 				Name:  common.EnvCycloneServerAddr,
@@ -857,12 +1164,34 @@ func (m *Builder) AddCoordinator() error {
 		},
 		ImagePullPolicy: controller.ImagePullPolicy(),
 	}
+	if len(m.rendered.Pod.Services) > 0 {
+		// Kubelet starts containers in order, and won't start the next container until postStart hook of the
+		// current one completes. So workload containers after coordinator are held until coordinator finds all
+		// services ready and creates the ready file.
+		coordinator.Lifecycle = &corev1.Lifecycle{
+			PostStart: &corev1.Handler{
+				Exec: &corev1.ExecAction{
+					Command: []string{"sh", "-c", fmt.Sprintf("until [ -f %s ]; do sleep 1; done", common.CoordinatorServicesReadyPath)},
+				},
+			},
+		}
+	}
 	if m.executionContext.PVC != "" {
 		coordinator.VolumeMounts = append(coordinator.VolumeMounts, corev1.VolumeMount{
 			Name:      common.DefaultPvVolumeName,
 			MountPath: common.CoordinatorWorkspacePath + "artifacts",
-			SubPath:   common.ArtifactsPath(m.wfr.Name, m.stage),
+			SubPath:   common.ArtifactsPath(common.WorkspaceName(m.wfr), m.stage),
 		})
+	} else if len(m.stg.Spec.Pod.Outputs.Artifacts) > 0 {
+		// Without PVC, output artifacts are uploaded to cyclone server for downstream stages with the token of
+		// the WorkflowRun, it's created beforehand, so that it's available to downstream WorkflowRuns.
+		if err := m.ensureArtifactToken(m.wfr.Name); err != nil {
+			return err
+		}
+		coordinator.Env = append(coordinator.Env, corev1.EnvVar{
+			Name:  common.EnvUploadArtifacts,
+			Value: "true",
+		}, artifactTokenEnv(m.wfr.Name))
 	}
 
 	// modify the containers in-place
@@ -875,7 +1204,11 @@ func (m *Builder) AddCoordinator() error {
 		})
 	}
 
-	m.pod.Spec.Containers = append(m.pod.Spec.Containers, coordinator)
+	// Coordinator is placed right after service containers, or appended if there are no services.
+	services := len(m.rendered.Pod.Services)
+	containers := append([]corev1.Container{}, m.pod.Spec.Containers[:services]...)
+	containers = append(containers, coordinator)
+	m.pod.Spec.Containers = append(containers, m.pod.Spec.Containers[services:]...)
 
 	return nil
 }
@@ -1093,6 +1426,11 @@ func (m *Builder) Build() (*corev1.Pod, error) {
 		return nil, err
 	}
 
+	err = m.ResolveServices()
+	if err != nil {
+		return nil, err
+	}
+
 	err = m.CreateVolumes()
 	if err != nil {
 		return nil, err
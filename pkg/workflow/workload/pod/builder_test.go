@@ -393,7 +393,7 @@ func getStage(client clientset.Interface, stage string) *v1alpha1.Stage {
 }
 
 func (suite *PodBuilderSuite) TestPrepare() {
-	builder := NewBuilder(suite.client, wf, wfr, getStage(suite.client, "simple"))
+	builder := NewBuilder(suite.client, suite.client, wf, wfr, getStage(suite.client, "simple"))
 	err := builder.Prepare()
 	assert.Nil(suite.T(), err)
 	assert.NotEmpty(suite.T(), builder.pod.Name)
@@ -402,14 +402,77 @@ func (suite *PodBuilderSuite) TestPrepare() {
 	assert.Equal(suite.T(), "wfr", builder.pod.Annotations[meta.AnnotationWorkflowRunName])
 }
 
+func (suite *PodBuilderSuite) TestPrepareWorkloadContainers() {
+	stg := getStage(suite.client, "stage1").DeepCopy()
+	stg.Spec.Pod.Spec.Containers = append(stg.Spec.Pod.Spec.Containers,
+		corev1.Container{Name: "test"}, corev1.Container{Name: "app"})
+	stg.Spec.Pod.ServiceContainers = []string{"app"}
+	stg.Spec.Pod.Outputs.Artifacts[0].Container = "test"
+	builder := NewBuilder(suite.client, suite.client, wf, wfr, stg)
+	assert.Nil(suite.T(), builder.Prepare())
+	assert.Equal(suite.T(), []string{"c1", "test"}, builder.workloadContainers())
+	assert.Equal(suite.T(), "app", builder.pod.Annotations[meta.AnnotationServiceContainers])
+
+	// Artifacts can't be produced by service containers or sidecars.
+	stg.Spec.Pod.Outputs.Artifacts[0].Container = "app"
+	assert.Error(suite.T(), NewBuilder(suite.client, suite.client, wf, wfr, stg).Prepare())
+	stg.Spec.Pod.Outputs.Artifacts[0].Container = "wsc-c2"
+	assert.Error(suite.T(), NewBuilder(suite.client, suite.client, wf, wfr, stg).Prepare())
+	stg.Spec.Pod.Outputs.Artifacts[0].Container = ""
+
+	// Service containers must be workload containers.
+	stg.Spec.Pod.ServiceContainers = []string{"app", "wsc-c2"}
+	assert.Error(suite.T(), NewBuilder(suite.client, suite.client, wf, wfr, stg).Prepare())
+
+	// At least one workload container is not a service.
+	stg.Spec.Pod.ServiceContainers = []string{"c1", "test", "app"}
+	assert.Error(suite.T(), NewBuilder(suite.client, suite.client, wf, wfr, stg).Prepare())
+	stg.Spec.Pod.ServiceContainers = []string{"app"}
+
+	// Name prefix of services is reserved.
+	stg.Spec.Pod.Spec.Containers = append(stg.Spec.Pod.Spec.Containers, corev1.Container{Name: "svc-db"})
+	assert.Error(suite.T(), NewBuilder(suite.client, suite.client, wf, wfr, stg).Prepare())
+}
+
+func (suite *PodBuilderSuite) TestResolveServices() {
+	stg := getStage(suite.client, "stage1").DeepCopy()
+	stg.Spec.Pod.Services = []corev1.Container{
+		{
+			Name:  "postgres",
+			Image: "postgres:12",
+		},
+	}
+	builder := NewBuilder(suite.client, suite.client, wf, wfr, stg)
+	assert.Nil(suite.T(), builder.Prepare())
+	assert.Nil(suite.T(), builder.ResolveArguments())
+	assert.Nil(suite.T(), builder.ResolveServices())
+	assert.Nil(suite.T(), builder.AddCoordinator())
+
+	// Services are started first, and coordinator holds workload containers until services are ready.
+	var names []string
+	for _, c := range builder.pod.Spec.Containers {
+		names = append(names, c.Name)
+	}
+	assert.Equal(suite.T(), []string{"svc-postgres", common.CoordinatorSidecarName, "c1", "wsc-c2"}, names)
+	assert.NotNil(suite.T(), builder.pod.Spec.Containers[1].Lifecycle)
+	assert.Contains(suite.T(), builder.pod.Spec.Containers[1].Lifecycle.PostStart.Exec.Command[2], common.CoordinatorServicesReadyPath)
+	assert.False(suite.T(), common.OnlyWorkload(names[0]))
+
+	stg.Spec.Pod.Services = append(stg.Spec.Pod.Services, corev1.Container{Name: "postgres"})
+	builder = NewBuilder(suite.client, suite.client, wf, wfr, stg)
+	assert.Nil(suite.T(), builder.Prepare())
+	assert.Nil(suite.T(), builder.ResolveArguments())
+	assert.Error(suite.T(), builder.ResolveServices())
+}
+
 func (suite *PodBuilderSuite) TestResolveArguments() {
-	builder := NewBuilder(suite.client, wf, wfr, getStage(suite.client, "unresolvable-argument"))
+	builder := NewBuilder(suite.client, suite.client, wf, wfr, getStage(suite.client, "unresolvable-argument"))
 	err := builder.Prepare()
 	assert.Nil(suite.T(), err)
 	err = builder.ResolveArguments()
 	assert.Error(suite.T(), err)
 
-	builder = NewBuilder(suite.client, wf, wfr, getStage(suite.client, "stage1"))
+	builder = NewBuilder(suite.client, suite.client, wf, wfr, getStage(suite.client, "stage1"))
 	err = builder.Prepare()
 	assert.Nil(suite.T(), err)
 	err = builder.ResolveArguments()
@@ -419,8 +482,25 @@ func (suite *PodBuilderSuite) TestResolveArguments() {
 	assert.Equal(suite.T(), corev1.RestartPolicyNever, builder.pod.Spec.RestartPolicy)
 }
 
+func (suite *PodBuilderSuite) TestMatrixInstance() {
+	instanceWfr := wfr.DeepCopy()
+	instanceWfr.Status.Stages = map[string]*v1alpha1.StageStatus{
+		"unresolvable-argument--0": {
+			Parent: "unresolvable-argument",
+			Matrix: map[string]string{"undefined-arg": "busybox:1.0"},
+		},
+	}
+	builder := NewInstanceBuilder(suite.client, suite.client, wf, instanceWfr, getStage(suite.client, "unresolvable-argument"), "unresolvable-argument--0")
+	err := builder.Prepare()
+	assert.Nil(suite.T(), err)
+	assert.Equal(suite.T(), "unresolvable-argument--0", builder.pod.Annotations[meta.AnnotationStageName])
+	err = builder.ResolveArguments()
+	assert.Nil(suite.T(), err)
+	assert.Equal(suite.T(), "busybox:1.0", builder.pod.Spec.Containers[0].Image)
+}
+
 func (suite *PodBuilderSuite) TestCreateVolumes() {
-	builder := NewBuilder(suite.client, wf, wfr, getStage(suite.client, "stage1"))
+	builder := NewBuilder(suite.client, suite.client, wf, wfr, getStage(suite.client, "stage1"))
 	err := builder.Prepare()
 	assert.Nil(suite.T(), err)
 	err = builder.ResolveArguments()
@@ -441,7 +521,7 @@ func (suite *PodBuilderSuite) TestCreateVolumes() {
 }
 
 func (suite *PodBuilderSuite) TestCreatePVCVolume() {
-	builder := NewBuilder(suite.client, wf, wfr, getStage(suite.client, "stage1"))
+	builder := NewBuilder(suite.client, suite.client, wf, wfr, getStage(suite.client, "stage1"))
 	assert.Equal(suite.T(), "v1", builder.CreatePVCVolume("v1", "pvc1"))
 	assert.Equal(suite.T(), "v1", builder.CreatePVCVolume("v2", "pvc1"))
 }
@@ -456,7 +536,7 @@ func (suite *PodBuilderSuite) TestResolveInputResources() {
 		controller.Config = controller.WorkflowControllerConfig{}
 	}()
 
-	builder := NewBuilder(suite.client, wf, wfr, getStage(suite.client, "stage1"))
+	builder := NewBuilder(suite.client, suite.client, wf, wfr, getStage(suite.client, "stage1"))
 	assert.Nil(suite.T(), builder.Prepare())
 	assert.Nil(suite.T(), builder.ResolveInputResources())
 	initContainer := builder.pod.Spec.InitContainers[1]
@@ -469,7 +549,7 @@ func (suite *PodBuilderSuite) TestResolveInputResources() {
 	}
 	assert.Contains(suite.T(), envs, "p1")
 
-	builder = NewBuilder(suite.client, wf, wfr, getStage(suite.client, "stage2"))
+	builder = NewBuilder(suite.client, suite.client, wf, wfr, getStage(suite.client, "stage2"))
 	assert.Nil(suite.T(), builder.Prepare())
 	assert.Nil(suite.T(), builder.ResolveArguments())
 	assert.Nil(suite.T(), builder.ResolveInputResources())
@@ -491,7 +571,7 @@ func (suite *PodBuilderSuite) TestResolveInputResources() {
 }
 
 func (suite *PodBuilderSuite) TestResolveOutputResources() {
-	builder := NewBuilder(suite.client, wf, wfr, getStage(suite.client, "stage2"))
+	builder := NewBuilder(suite.client, suite.client, wf, wfr, getStage(suite.client, "stage2"))
 	assert.Nil(suite.T(), builder.Prepare())
 	assert.Nil(suite.T(), builder.ResolveArguments())
 	assert.Nil(suite.T(), builder.ResolveOutputResources())
@@ -532,16 +612,62 @@ func (suite *PodBuilderSuite) TestResolveInputArtifacts() {
 		Images: map[string]string{
 			controller.ToolboxImage: "cyclone-toolbox:v0.1",
 		},
+		CycloneServerAddr: "http://cyclone-server:7099",
 	}
 	defer func() {
 		controller.Config = controller.WorkflowControllerConfig{}
 	}()
 
-	builder := NewBuilder(suite.client, wf, wfr, getStage(suite.client, "stage2"))
+	// Without PVC, artifacts are downloaded from cyclone server, the coordinator image is required.
+	builder := NewBuilder(suite.client, suite.client, wf, wfr, getStage(suite.client, "stage2"))
 	assert.Nil(suite.T(), builder.Prepare())
 	assert.Nil(suite.T(), builder.ResolveArguments())
 	assert.Error(suite.T(), builder.ResolveInputArtifacts())
 
+	controller.Config.Images[controller.CoordinatorImage] = "cyclone-workflow-coordinator:v0.1"
+	builder = NewBuilder(suite.client, suite.client, wf, wfr, getStage(suite.client, "stage2"))
+	assert.Nil(suite.T(), builder.Prepare())
+	assert.Nil(suite.T(), builder.ResolveArguments())
+	assert.Nil(suite.T(), builder.ResolveInputArtifacts())
+	assert.Len(suite.T(), builder.pod.Spec.InitContainers, 1)
+	initContainer := builder.pod.Spec.InitContainers[0]
+	assert.Equal(suite.T(), InputArtifactContainerName(0), initContainer.Name)
+	assert.Contains(suite.T(), initContainer.Env, corev1.EnvVar{
+		Name:  common.EnvArtifactURL,
+		Value: "http://cyclone-server:7099/apis/v1alpha1/workflowruns/wfr/artifacts/art1.tar?namespace=&stage=stage1",
+	})
+	// Token to download the artifacts is kept in the secret of the WorkflowRun.
+	secret, err := suite.client.CoreV1().Secrets(wfr.Namespace).Get(context.TODO(), common.ArtifactTokenSecretName("wfr"), metav1.GetOptions{})
+	assert.Nil(suite.T(), err)
+	assert.Len(suite.T(), secret.Data[common.ArtifactTokenSecretKey], 64)
+	assert.Len(suite.T(), secret.OwnerReferences, 1)
+	assert.Contains(suite.T(), initContainer.Env, artifactTokenEnv("wfr"))
+	assert.Equal(suite.T(), common.ArtifactTokenSecretName("wfr"), artifactTokenEnv("wfr").ValueFrom.SecretKeyRef.Name)
+	for _, c := range builder.pod.Spec.Containers {
+		if !common.OnlyWorkload(c.Name) {
+			continue
+		}
+		assert.Contains(suite.T(), c.VolumeMounts, corev1.VolumeMount{
+			Name:      common.InputArtifactsVolume,
+			MountPath: "/tmp/art1",
+			SubPath:   "art1/artifact.tar",
+		})
+	}
+
+	// The secret is copied to the execution namespace where the pod runs.
+	builder = NewBuilder(suite.client, suite.client, wf, wfr, getStage(suite.client, "stage2"))
+	builder.executionContext = &v1alpha1.ExecutionContext{Namespace: "cyclone-worker"}
+	assert.Nil(suite.T(), builder.Prepare())
+	assert.Nil(suite.T(), builder.ResolveArguments())
+	assert.Nil(suite.T(), builder.ResolveInputArtifacts())
+	copied, err := suite.client.CoreV1().Secrets("cyclone-worker").Get(context.TODO(), common.ArtifactTokenSecretName("wfr"), metav1.GetOptions{})
+	assert.Nil(suite.T(), err)
+	assert.Equal(suite.T(), secret.Data, copied.Data)
+	assert.Empty(suite.T(), copied.OwnerReferences)
+
+	builder = NewBuilder(suite.client, suite.client, wf, wfr, getStage(suite.client, "stage2"))
+	assert.Nil(suite.T(), builder.Prepare())
+	assert.Nil(suite.T(), builder.ResolveArguments())
 	builder.executionContext.PVC = "pvc1"
 	assert.Nil(suite.T(), builder.ResolveInputArtifacts())
 
@@ -552,6 +678,42 @@ func (suite *PodBuilderSuite) TestResolveInputArtifacts() {
 			SubPath:   common.ArtifactPath("wfr", "stage1", "art1") + "/artifact.tar",
 		})
 	}
+
+	// Artifacts from the upstream WorkflowRun.
+	downstreamWf := wf.DeepCopy()
+	downstreamWf.Spec.Stages[1].Artifacts[0].Source = "upstream/stage1/art1"
+	downstreamWfr := wfr.DeepCopy()
+
+	// Without PVC, artifact token of the upstream WorkflowRun is required.
+	downstreamWfr.Name = "downstream-wfr"
+	downstreamWfr.Annotations = map[string]string{meta.AnnotationWorkflowRunUpstream: "upstream-wfr"}
+	builder = NewBuilder(suite.client, suite.client, downstreamWf, downstreamWfr, getStage(suite.client, "stage2"))
+	assert.Nil(suite.T(), builder.Prepare())
+	assert.Nil(suite.T(), builder.ResolveArguments())
+	assert.Error(suite.T(), builder.ResolveInputArtifacts())
+	downstreamWfr = wfr.DeepCopy()
+	builder = NewBuilder(suite.client, suite.client, downstreamWf, downstreamWfr, getStage(suite.client, "stage2"))
+	builder.executionContext.PVC = "pvc1"
+	assert.Nil(suite.T(), builder.Prepare())
+	assert.Nil(suite.T(), builder.ResolveArguments())
+	assert.Error(suite.T(), builder.ResolveInputArtifacts())
+
+	downstreamWfr.Labels[meta.LabelWorkflowRunUpstreamWorkspace] = "upstream-wfr"
+	builder = NewBuilder(suite.client, suite.client, downstreamWf, downstreamWfr, getStage(suite.client, "stage2"))
+	builder.executionContext.PVC = "pvc1"
+	assert.Nil(suite.T(), builder.Prepare())
+	assert.Nil(suite.T(), builder.ResolveArguments())
+	assert.Nil(suite.T(), builder.ResolveInputArtifacts())
+	for _, c := range builder.pod.Spec.Containers {
+		if !common.OnlyWorkload(c.Name) {
+			continue
+		}
+		assert.Contains(suite.T(), c.VolumeMounts, corev1.VolumeMount{
+			Name:      common.DefaultPvVolumeName,
+			MountPath: "/tmp/art1",
+			SubPath:   common.ArtifactPath("upstream-wfr", "stage1", "art1") + "/artifact.tar",
+		})
+	}
 }
 
 func (suite *PodBuilderSuite) TestApplyResourceRequirements() {
@@ -575,7 +737,7 @@ func (suite *PodBuilderSuite) TestApplyResourceRequirements() {
 		controller.Config = controller.WorkflowControllerConfig{}
 	}()
 
-	builder := NewBuilder(suite.client, wf, wfr, getStage(suite.client, "stage1"))
+	builder := NewBuilder(suite.client, suite.client, wf, wfr, getStage(suite.client, "stage1"))
 	assert.Nil(suite.T(), builder.Prepare())
 	assert.Nil(suite.T(), builder.ResolveArguments())
 	assert.Nil(suite.T(), builder.ResolveInputResources())
@@ -615,13 +777,13 @@ func (suite *PodBuilderSuite) TestApplyResourceRequirements() {
 }
 
 func (suite *PodBuilderSuite) TestArtifactFileName() {
-	builder := NewBuilder(suite.client, wf, wfr, getStage(suite.client, "stage2"))
+	builder := NewBuilder(suite.client, suite.client, wf, wfr, getStage(suite.client, "stage2"))
 	name, _ := builder.ArtifactFileName("stage1", "art1")
 	assert.Equal(suite.T(), "artifact.tar", name)
 }
 
 func (suite *PodBuilderSuite) TestAddCommonVolumes() {
-	builder := NewBuilder(suite.client, wf, wfr, getStage(suite.client, "stage2"))
+	builder := NewBuilder(suite.client, suite.client, wf, wfr, getStage(suite.client, "stage2"))
 	assert.Nil(suite.T(), builder.Prepare())
 	assert.Nil(suite.T(), builder.ResolveArguments())
 	assert.Nil(suite.T(), builder.CreateVolumes())
@@ -643,7 +805,7 @@ func (suite *PodBuilderSuite) TestAddCommonVolumes() {
 }
 
 func (suite *PodBuilderSuite) TestInjectEnvs() {
-	builder := NewBuilder(suite.client, wf, wfr, getStage(suite.client, "simple"))
+	builder := NewBuilder(suite.client, suite.client, wf, wfr, getStage(suite.client, "simple"))
 	err := builder.Prepare()
 	assert.Nil(suite.T(), err)
 	err = builder.ResolveArguments()
@@ -680,7 +842,7 @@ func checkSubMap(t *testing.T, parent, child map[string]string) {
 }
 
 func (suite *PodBuilderSuite) TestAdditionalPodMetadata() {
-	builder := NewBuilder(suite.client, wf, wfr, getStage(suite.client, "simple-with-pod-meta"))
+	builder := NewBuilder(suite.client, suite.client, wf, wfr, getStage(suite.client, "simple-with-pod-meta"))
 	err := builder.Prepare()
 	assert.NoError(suite.T(), err)
 	checkSubMap(suite.T(), builder.pod.Labels, map[string]string{"l1": "v1"})
@@ -853,3 +1015,33 @@ func TestApplyResourceRequirements(t *testing.T) {
 		}
 	}
 }
+
+func TestUploadsArtifacts(t *testing.T) {
+	controller.Config = controller.WorkflowControllerConfig{CycloneServerAddr: "http://cyclone-server:7099"}
+	defer func() {
+		controller.Config = controller.WorkflowControllerConfig{}
+	}()
+
+	testCases := map[string]struct {
+		url      string
+		expected bool
+	}{
+		"cyclone server": {
+			url:      "http://cyclone-server:7099/apis/v1alpha1/workflowruns/${WORKFLOWRUN_NAME}/artifacts?namespace=ns&stage=s1",
+			expected: true,
+		},
+		"other server": {
+			url:      "http://example.com/apis/v1alpha1/workflowruns/wfr/artifacts",
+			expected: false,
+		},
+		"other server with cyclone server in path": {
+			url:      "http://example.com/http://cyclone-server:7099/apis/v1alpha1/workflowruns/wfr/artifacts",
+			expected: false,
+		},
+	}
+	for d, tc := range testCases {
+		envs := []corev1.EnvVar{{Name: common.HTTPResourceURLKey, Value: tc.url}}
+		assert.Equal(t, tc.expected, uploadsArtifacts(envs), d)
+	}
+	assert.False(t, uploadsArtifacts(nil))
+}
@@ -21,6 +21,16 @@ func InputContainerName(index int) string {
 	return fmt.Sprintf("i%d", index)
 }
 
+// InputArtifactContainerName generates a container name for the init container downloading an input artifact
+func InputArtifactContainerName(index int) string {
+	return fmt.Sprintf("ia%d", index)
+}
+
+// ServiceContainerName generates a container name for a service of the stage
+func ServiceContainerName(name string) string {
+	return common.ServiceContainerPrefix + name
+}
+
 // OutputContainerName generates a container name for output resolver container
 func OutputContainerName(index int) string {
 	return fmt.Sprintf("%so%d", common.CycloneSidecarPrefix, index)